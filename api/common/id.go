@@ -19,6 +19,7 @@ package common
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"strconv"
@@ -104,9 +105,13 @@ func GetPosition(ctx context.Context) (p Position) {
 	return val.(Position)
 }
 
+// ErrResourceExhausted indicates a request exceeds a limit protecting the node.
+var ErrResourceExhausted = errors.New("resource exhausted")
+
 // Error wraps a error msg.
 type Error struct {
-	msg string
+	cause error
+	msg   string
 }
 
 // NewError returns a new Error.
@@ -114,11 +119,21 @@ func NewError(tpl string, args ...any) Error {
 	return Error{msg: fmt.Sprintf(tpl, args...)}
 }
 
+// NewErrorWithCause returns a new Error caused by cause, which errors.Is can match through Cause.
+func NewErrorWithCause(cause error, tpl string, args ...any) Error {
+	return Error{cause: cause, msg: fmt.Sprintf(tpl, args...)}
+}
+
 // Msg shows the string msg.
 func (e Error) Msg() string {
 	return e.msg
 }
 
+// Cause returns the error causing the Error, nil if it's unknown.
+func (e Error) Cause() error {
+	return e.cause
+}
+
 // Node contains the node id and address.
 type Node struct {
 	NodeID      string
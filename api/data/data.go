@@ -28,11 +28,12 @@ import (
 
 // TopicMap is the map of topic name to topic.
 var TopicMap = map[string]bus.Topic{
-	TopicStreamWrite.String():  TopicStreamWrite,
-	TopicStreamQuery.String():  TopicStreamQuery,
-	TopicMeasureWrite.String(): TopicMeasureWrite,
-	TopicMeasureQuery.String(): TopicMeasureQuery,
-	TopicTopNQuery.String():    TopicTopNQuery,
+	TopicStreamWrite.String():             TopicStreamWrite,
+	TopicStreamQuery.String():             TopicStreamQuery,
+	TopicMeasureWrite.String():            TopicMeasureWrite,
+	TopicMeasureConditionalWrite.String(): TopicMeasureConditionalWrite,
+	TopicMeasureQuery.String():            TopicMeasureQuery,
+	TopicTopNQuery.String():               TopicTopNQuery,
 }
 
 // TopicRequestMap is the map of topic name to request message.
@@ -47,6 +48,9 @@ var TopicRequestMap = map[bus.Topic]func() proto.Message{
 	TopicMeasureWrite: func() proto.Message {
 		return &measurev1.InternalWriteRequest{}
 	},
+	TopicMeasureConditionalWrite: func() proto.Message {
+		return &measurev1.InternalWriteRequest{}
+	},
 	TopicMeasureQuery: func() proto.Message {
 		return &measurev1.QueryRequest{}
 	},
@@ -61,6 +65,9 @@ var TopicResponseMap = map[bus.Topic]func() proto.Message{
 	TopicStreamQuery: func() proto.Message {
 		return &streamv1.QueryResponse{}
 	},
+	TopicMeasureConditionalWrite: func() proto.Message {
+		return &measurev1.WriteResponse{}
+	},
 	TopicMeasureQuery: func() proto.Message {
 		return &measurev1.QueryResponse{}
 	},
@@ -31,6 +31,15 @@ var MeasureWriteKindVersion = common.KindVersion{
 // TopicMeasureWrite is the measure write topic.
 var TopicMeasureWrite = bus.UniTopic(MeasureWriteKindVersion.String())
 
+// MeasureConditionalWriteKindVersion is the version tag of measure conditional write kind.
+var MeasureConditionalWriteKindVersion = common.KindVersion{
+	Version: "v1",
+	Kind:    "measure-conditional-write",
+}
+
+// TopicMeasureConditionalWrite is the measure conditional write topic, it replies whether the condition holds.
+var TopicMeasureConditionalWrite = bus.BiTopic(MeasureConditionalWriteKindVersion.String())
+
 // MeasureQueryKindVersion is the version tag of measure query kind.
 var MeasureQueryKindVersion = common.KindVersion{
 	Version: "v1",
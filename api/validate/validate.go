@@ -20,11 +20,19 @@ package validate
 
 import (
 	"errors"
+	"fmt"
 
 	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
 	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
 )
 
+// MaxMeasureBlockSize is the largest number of data points in a measure block.
+const MaxMeasureBlockSize = 8 * 1024
+
+// StreamSchemaRevisionTagFamily is the tag family reserved for stamping the stream elements with their schema revision.
+const StreamSchemaRevisionTagFamily = "_schema"
+
 // GroupForStreamOrMeasure validates the provided Group object for Stream or Measure.
 // It checks for nil values, empty strings, and unspecified enum values.
 func GroupForStreamOrMeasure(group *commonv1.Group) error {
@@ -64,6 +72,17 @@ func GroupForStreamOrMeasure(group *commonv1.Group) error {
 	if group.ResourceOpts.Ttl.Unit == commonv1.IntervalRule_UNIT_UNSPECIFIED {
 		return errors.New("group ttl unit is unspecified")
 	}
+	return BlockSize(group.ResourceOpts)
+}
+
+// BlockSize validates the block size of the provided ResourceOpts if it's set.
+func BlockSize(opts *commonv1.ResourceOpts) error {
+	if opts == nil || opts.BlockSize == nil {
+		return nil
+	}
+	if bs := *opts.BlockSize; bs == 0 || bs > MaxMeasureBlockSize {
+		return fmt.Errorf("group blockSize %d is out of (0, %d]", bs, MaxMeasureBlockSize)
+	}
 	return nil
 }
 
@@ -91,6 +110,11 @@ func Stream(stream *databasev1.Stream) error {
 	if len(stream.Entity.TagNames) == 0 {
 		return errors.New("stream entity tag names is empty")
 	}
+	for _, tf := range stream.TagFamilies {
+		if tf.GetName() == StreamSchemaRevisionTagFamily {
+			return fmt.Errorf("stream tag family %s is reserved", StreamSchemaRevisionTagFamily)
+		}
+	}
 	return tagFamily(stream.TagFamilies)
 }
 
@@ -132,9 +156,40 @@ func Measure(measure *databasev1.Measure) error {
 	if len(measure.TagFamilies) == 0 {
 		return errors.New("measure tag families is empty")
 	}
+	if err := rollup(measure); err != nil {
+		return err
+	}
 	return tagFamily(measure.TagFamilies)
 }
 
+func rollup(measure *databasev1.Measure) error {
+	r := measure.Rollup
+	if r == nil {
+		return nil
+	}
+	if r.SourceMeasure.GetName() == "" {
+		return errors.New("rollup source measure is empty")
+	}
+	if r.SourceMeasure.GetName() == measure.Metadata.Name {
+		return errors.New("rollup source measure is the measure itself")
+	}
+	if measure.Interval == "" {
+		return errors.New("rollup interval is empty")
+	}
+	if len(r.Fields) == 0 {
+		return errors.New("rollup fields is empty")
+	}
+	for i := range r.Fields {
+		if r.Fields[i].Name == "" {
+			return errors.New("rollup field name is empty")
+		}
+		if r.Fields[i].Function == modelv1.AggregationFunction_AGGREGATION_FUNCTION_UNSPECIFIED {
+			return errors.New("rollup function is unspecified")
+		}
+	}
+	return nil
+}
+
 func tagFamily(tagFamilies []*databasev1.TagFamilySpec) error {
 	for i := range tagFamilies {
 		if tagFamilies[i].Name == "" {
@@ -20,6 +20,7 @@ package dquery
 
 import (
 	"context"
+	"sync/atomic"
 
 	"go.uber.org/multierr"
 
@@ -105,8 +106,13 @@ var _ executor.DistributedExecutionContext = (*distributedContext)(nil)
 type distributedContext struct {
 	bus.Broadcaster
 	timeRange *modelv1.TimeRange
+	bytesRead atomic.Uint64
 }
 
 func (dc *distributedContext) TimeRange() *modelv1.TimeRange {
 	return dc.timeRange
 }
+
+func (dc *distributedContext) AddBytesRead(n uint64) {
+	dc.bytesRead.Add(n)
+}
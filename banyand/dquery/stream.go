@@ -18,7 +18,6 @@
 package dquery
 
 import (
-	"context"
 	"time"
 
 	"github.com/apache/skywalking-banyandb/api/common"
@@ -27,6 +26,7 @@ import (
 	"github.com/apache/skywalking-banyandb/banyand/stream"
 	"github.com/apache/skywalking-banyandb/pkg/bus"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
 	"github.com/apache/skywalking-banyandb/pkg/query/executor"
 	logical_stream "github.com/apache/skywalking-banyandb/pkg/query/logical/stream"
 )
@@ -76,17 +76,27 @@ func (p *streamQueryProcessor) Rev(message bus.Message) (resp bus.Message) {
 	if p.log.Debug().Enabled() {
 		p.log.Debug().Str("plan", plan.String()).Msg("query plan")
 	}
-	entities, err := plan.(executor.StreamExecutable).Execute(executor.WithDistributedExecutionContext(context.Background(), &distributedContext{
+	dc := &distributedContext{
 		Broadcaster: p.broadcaster,
 		timeRange:   queryCriteria.TimeRange,
-	}))
+	}
+	entities, err := plan.(executor.StreamExecutable).Execute(executor.WithDistributedExecutionContext(message.Context(), dc))
 	if err != nil {
 		p.log.Error().Err(err).RawJSON("req", logger.Proto(queryCriteria)).Msg("fail to execute the query plan")
-		resp = bus.NewMessage(bus.MessageID(now), common.NewError("execute the query plan for stream %s: %v", meta.GetName(), err))
+		resp = bus.NewMessage(bus.MessageID(now), common.NewErrorWithCause(err, "execute the query plan for stream %s: %v", meta.GetName(), err))
 		return
 	}
 
-	resp = bus.NewMessage(bus.MessageID(now), &streamv1.QueryResponse{Elements: entities})
+	nextPageToken, err := logical_stream.NextPageToken(queryCriteria, entities)
+	if err != nil {
+		resp = bus.NewMessage(bus.MessageID(now), common.NewError("fail to build the next page token for stream %s: %v", meta.GetName(), err))
+		return
+	}
+	r := &streamv1.QueryResponse{Elements: entities, BytesRead: dc.bytesRead.Load(), NextPageToken: nextPageToken}
+	if queryCriteria.GetChecksum() {
+		r.Checksum = pbv1.StreamQueryChecksum(entities)
+	}
+	resp = bus.NewMessage(bus.MessageID(now), r)
 
 	return
 }
@@ -18,12 +18,14 @@
 package dquery
 
 import (
+	"context"
 	"time"
 
 	"go.uber.org/multierr"
 
 	"github.com/apache/skywalking-banyandb/api/common"
 	"github.com/apache/skywalking-banyandb/api/data"
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
 	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
 	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
 	"github.com/apache/skywalking-banyandb/banyand/query"
@@ -56,6 +58,25 @@ func (t *topNQueryProcessor) Rev(message bus.Message) (resp bus.Message) {
 	agg := request.Agg
 	request.Agg = modelv1.AggregationFunction_AGGREGATION_FUNCTION_UNSPECIFIED
 	now := bus.MessageID(request.TimeRange.Begin.Nanos)
+	var rollup *query.TopNRollup
+	if interval := request.GetRollupInterval(); interval != nil {
+		topNSchema, err := t.metaService.TopNAggregationRegistry().GetTopNAggregation(context.TODO(), &commonv1.Metadata{
+			Name:  request.GetName(),
+			Group: request.GetGroups()[0],
+		})
+		if err != nil {
+			resp = bus.NewMessage(now, common.NewError("fail to get topn %s: %v", request.GetName(), err))
+			return
+		}
+		request.Agg = agg
+		if agg, err = query.TopNRollupFunction(request, topNSchema); err != nil {
+			resp = bus.NewMessage(now, common.NewError("fail to roll up topn %s: %v", request.GetName(), err))
+			return
+		}
+		// The data nodes roll up their own entries, and their buckets are combined here.
+		request.Agg = agg
+		rollup = query.NewTopNRollup(interval.AsDuration(), request.GetTopN(), query.RollupPartialFunction(agg), request.GetFieldValueSort())
+	}
 	ff, err := t.broadcaster.Broadcast(defaultTopNQueryTimeout, data.TopicTopNQuery, bus.NewMessage(now, request))
 	if err != nil {
 		resp = bus.NewMessage(now, common.NewError("execute the query %s: %v", request.GetName(), err))
@@ -76,6 +97,12 @@ func (t *topNQueryProcessor) Rev(message bus.Message) (resp bus.Message) {
 			topNResp := d.(*measurev1.TopNResponse)
 			for _, l := range topNResp.Lists {
 				for _, tn := range l.Items {
+					if rollup != nil {
+						if putErr := rollup.Put(l.Timestamp.AsTime(), tn.Entity, tn.Value.GetInt().GetValue()); putErr != nil {
+							allErr = multierr.Append(allErr, putErr)
+						}
+						continue
+					}
 					if tags == nil {
 						tags = make([]string, 0, len(tn.Entity))
 						for _, e := range tn.Entity {
@@ -95,6 +122,10 @@ func (t *topNQueryProcessor) Rev(message bus.Message) (resp bus.Message) {
 		resp = bus.NewMessage(now, common.NewError("execute the query %s: %v", request.GetName(), allErr))
 		return
 	}
+	if rollup != nil {
+		resp = bus.NewMessage(now, &measurev1.TopNResponse{Lists: rollup.Val()})
+		return
+	}
 	if tags == nil {
 		resp = bus.NewMessage(now, &measurev1.TopNResponse{})
 		return
@@ -0,0 +1,376 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package storage
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+)
+
+// CachePolicy decides which entry a full cache evicts.
+type CachePolicy int
+
+// Available CachePolicies.
+const (
+	// CachePolicyLRU evicts the least recently used entry.
+	CachePolicyLRU CachePolicy = iota
+	// CachePolicyLFU evicts the least frequently used entry, the least recently used one among the ties.
+	CachePolicyLFU
+	// CachePolicySLRU admits the new entries to a probationary segment, and promotes the ones hit again to
+	// a protected segment. A burst of entries used once only evicts the other probationary ones.
+	CachePolicySLRU
+)
+
+var cachePolicyNames = map[CachePolicy]string{
+	CachePolicyLRU:  "lru",
+	CachePolicyLFU:  "lfu",
+	CachePolicySLRU: "slru",
+}
+
+// ParseCachePolicy parses the name of a CachePolicy.
+func ParseCachePolicy(name string) (CachePolicy, error) {
+	for p, n := range cachePolicyNames {
+		if n == name {
+			return p, nil
+		}
+	}
+	return CachePolicyLRU, fmt.Errorf("unknown cache policy %q, it should be one of lru, lfu and slru", name)
+}
+
+func (p CachePolicy) String() string {
+	return cachePolicyNames[p]
+}
+
+// newPolicyCache returns a cache of the policy. Its keys are listed in the order they'd be evicted.
+func newPolicyCache[K comparable, V any](policy CachePolicy, size int) (simplelru.LRUCache[K, V], error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("the size of a cache must be positive, got %d", size)
+	}
+	switch policy {
+	case CachePolicyLFU:
+		return &lfuCache[K, V]{size: size, items: make(map[K]*lfuEntry[K, V])}, nil
+	case CachePolicySLRU:
+		return newSLRUCache[K, V](size)
+	default:
+		return simplelru.NewLRU[K, V](size, nil)
+	}
+}
+
+var _ simplelru.LRUCache[string, int] = (*lfuCache[string, int])(nil)
+
+type lfuEntry[K comparable, V any] struct {
+	key   K
+	value V
+	freq  uint64
+	// tick is when the entry is used last.
+	tick  uint64
+	index int
+}
+
+// lfuCache is a min-heap of the entries by their frequencies and last uses.
+type lfuCache[K comparable, V any] struct {
+	items   map[K]*lfuEntry[K, V]
+	entries []*lfuEntry[K, V]
+	size    int
+	tick    uint64
+}
+
+func (c *lfuCache[K, V]) Len() int {
+	return len(c.entries)
+}
+
+func (c *lfuCache[K, V]) Less(i, j int) bool {
+	return lfuLess(c.entries[i], c.entries[j])
+}
+
+func lfuLess[K comparable, V any](a, b *lfuEntry[K, V]) bool {
+	if a.freq != b.freq {
+		return a.freq < b.freq
+	}
+	return a.tick < b.tick
+}
+
+func (c *lfuCache[K, V]) Swap(i, j int) {
+	c.entries[i], c.entries[j] = c.entries[j], c.entries[i]
+	c.entries[i].index = i
+	c.entries[j].index = j
+}
+
+func (c *lfuCache[K, V]) Push(x any) {
+	e := x.(*lfuEntry[K, V])
+	e.index = len(c.entries)
+	c.entries = append(c.entries, e)
+}
+
+func (c *lfuCache[K, V]) Pop() any {
+	n := len(c.entries)
+	e := c.entries[n-1]
+	c.entries[n-1] = nil
+	c.entries = c.entries[:n-1]
+	return e
+}
+
+func (c *lfuCache[K, V]) touch(e *lfuEntry[K, V]) {
+	c.tick++
+	e.freq++
+	e.tick = c.tick
+	heap.Fix(c, e.index)
+}
+
+func (c *lfuCache[K, V]) Add(key K, value V) bool {
+	if e, ok := c.items[key]; ok {
+		e.value = value
+		c.touch(e)
+		return false
+	}
+	evicted := false
+	if len(c.entries) >= c.size {
+		c.RemoveOldest()
+		evicted = true
+	}
+	c.tick++
+	e := &lfuEntry[K, V]{key: key, value: value, freq: 1, tick: c.tick}
+	heap.Push(c, e)
+	c.items[key] = e
+	return evicted
+}
+
+func (c *lfuCache[K, V]) Get(key K) (value V, ok bool) {
+	e, ok := c.items[key]
+	if !ok {
+		return value, false
+	}
+	c.touch(e)
+	return e.value, true
+}
+
+func (c *lfuCache[K, V]) Contains(key K) bool {
+	_, ok := c.items[key]
+	return ok
+}
+
+func (c *lfuCache[K, V]) Peek(key K) (value V, ok bool) {
+	e, ok := c.items[key]
+	if !ok {
+		return value, false
+	}
+	return e.value, true
+}
+
+func (c *lfuCache[K, V]) Remove(key K) bool {
+	e, ok := c.items[key]
+	if !ok {
+		return false
+	}
+	heap.Remove(c, e.index)
+	delete(c.items, key)
+	return true
+}
+
+func (c *lfuCache[K, V]) RemoveOldest() (key K, value V, ok bool) {
+	if len(c.entries) == 0 {
+		return key, value, false
+	}
+	e := heap.Pop(c).(*lfuEntry[K, V])
+	delete(c.items, e.key)
+	return e.key, e.value, true
+}
+
+func (c *lfuCache[K, V]) GetOldest() (key K, value V, ok bool) {
+	if len(c.entries) == 0 {
+		return key, value, false
+	}
+	return c.entries[0].key, c.entries[0].value, true
+}
+
+func (c *lfuCache[K, V]) sorted() []*lfuEntry[K, V] {
+	sorted := make([]*lfuEntry[K, V], len(c.entries))
+	copy(sorted, c.entries)
+	sort.Slice(sorted, func(i, j int) bool {
+		return lfuLess(sorted[i], sorted[j])
+	})
+	return sorted
+}
+
+func (c *lfuCache[K, V]) Keys() []K {
+	sorted := c.sorted()
+	keys := make([]K, len(sorted))
+	for i := range sorted {
+		keys[i] = sorted[i].key
+	}
+	return keys
+}
+
+func (c *lfuCache[K, V]) Values() []V {
+	sorted := c.sorted()
+	values := make([]V, len(sorted))
+	for i := range sorted {
+		values[i] = sorted[i].value
+	}
+	return values
+}
+
+func (c *lfuCache[K, V]) Purge() {
+	c.items = make(map[K]*lfuEntry[K, V])
+	c.entries = nil
+}
+
+func (c *lfuCache[K, V]) Resize(size int) int {
+	evicted := 0
+	for len(c.entries) > size {
+		c.RemoveOldest()
+		evicted++
+	}
+	c.size = size
+	return evicted
+}
+
+var _ simplelru.LRUCache[string, int] = (*slruCache[string, int])(nil)
+
+// slruProtectedRatio is the share of the protected segment in a segmented LRU cache.
+const slruProtectedRatio = 0.8
+
+// slruCache is a segmented LRU cache. The segments are plain LRU caches never filled up by themselves,
+// the cache moves the entries between them to keep their sizes.
+type slruCache[K comparable, V any] struct {
+	probation     *simplelru.LRU[K, V]
+	protected     *simplelru.LRU[K, V]
+	size          int
+	protectedSize int
+}
+
+func newSLRUCache[K comparable, V any](size int) (*slruCache[K, V], error) {
+	probation, err := simplelru.NewLRU[K, V](size+1, nil)
+	if err != nil {
+		return nil, err
+	}
+	protected, err := simplelru.NewLRU[K, V](size+1, nil)
+	if err != nil {
+		return nil, err
+	}
+	c := &slruCache[K, V]{probation: probation, protected: protected}
+	c.Resize(size)
+	return c, nil
+}
+
+func (c *slruCache[K, V]) Add(key K, value V) bool {
+	if c.protected.Contains(key) {
+		c.protected.Add(key, value)
+		return false
+	}
+	if c.probation.Contains(key) {
+		c.probation.Add(key, value)
+		return false
+	}
+	c.probation.Add(key, value)
+	return c.fit() > 0
+}
+
+// Get promotes a probationary entry to the protected segment, which demotes the protected entry used least recently.
+func (c *slruCache[K, V]) Get(key K) (value V, ok bool) {
+	if value, ok = c.protected.Get(key); ok {
+		return value, true
+	}
+	if value, ok = c.probation.Peek(key); !ok {
+		return value, false
+	}
+	if c.protectedSize == 0 {
+		c.probation.Get(key)
+		return value, true
+	}
+	c.probation.Remove(key)
+	c.protected.Add(key, value)
+	if c.protected.Len() > c.protectedSize {
+		k, v, _ := c.protected.RemoveOldest()
+		c.probation.Add(k, v)
+	}
+	return value, true
+}
+
+func (c *slruCache[K, V]) Contains(key K) bool {
+	return c.protected.Contains(key) || c.probation.Contains(key)
+}
+
+func (c *slruCache[K, V]) Peek(key K) (value V, ok bool) {
+	if value, ok = c.protected.Peek(key); ok {
+		return value, true
+	}
+	return c.probation.Peek(key)
+}
+
+func (c *slruCache[K, V]) Remove(key K) bool {
+	return c.protected.Remove(key) || c.probation.Remove(key)
+}
+
+func (c *slruCache[K, V]) RemoveOldest() (key K, value V, ok bool) {
+	if key, value, ok = c.probation.RemoveOldest(); ok {
+		return key, value, true
+	}
+	return c.protected.RemoveOldest()
+}
+
+func (c *slruCache[K, V]) GetOldest() (key K, value V, ok bool) {
+	if key, value, ok = c.probation.GetOldest(); ok {
+		return key, value, true
+	}
+	return c.protected.GetOldest()
+}
+
+func (c *slruCache[K, V]) Keys() []K {
+	return append(c.probation.Keys(), c.protected.Keys()...)
+}
+
+func (c *slruCache[K, V]) Values() []V {
+	return append(c.probation.Values(), c.protected.Values()...)
+}
+
+func (c *slruCache[K, V]) Len() int {
+	return c.probation.Len() + c.protected.Len()
+}
+
+func (c *slruCache[K, V]) Purge() {
+	c.probation.Purge()
+	c.protected.Purge()
+}
+
+func (c *slruCache[K, V]) Resize(size int) int {
+	c.size = size
+	c.protectedSize = int(float64(size) * slruProtectedRatio)
+	for c.protected.Len() > c.protectedSize {
+		k, v, _ := c.protected.RemoveOldest()
+		c.probation.Add(k, v)
+	}
+	evicted := c.fit()
+	// The segments have fit in the size, so they never evict anything by themselves.
+	c.probation.Resize(size + 1)
+	c.protected.Resize(size + 1)
+	return evicted
+}
+
+// fit evicts the least recently used probationary entries until the cache fits, and returns how many are evicted.
+func (c *slruCache[K, V]) fit() int {
+	evicted := 0
+	for c.Len() > c.size {
+		c.RemoveOldest()
+		evicted++
+	}
+	return evicted
+}
@@ -0,0 +1,125 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package storage
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+)
+
+var cachePolicies = []CachePolicy{CachePolicyLRU, CachePolicyLFU, CachePolicySLRU}
+
+func TestParseCachePolicy(t *testing.T) {
+	for _, p := range cachePolicies {
+		parsed, err := ParseCachePolicy(p.String())
+		require.NoError(t, err)
+		assert.Equal(t, p, parsed)
+	}
+	_, err := ParseCachePolicy("fifo")
+	assert.Error(t, err)
+}
+
+func TestCachePolicyScan(t *testing.T) {
+	// A hot key is hit a few times, then a scan over more keys than the cache holds passes by.
+	tests := []struct {
+		policy   CachePolicy
+		keepsHot bool
+	}{
+		{policy: CachePolicyLRU},
+		{policy: CachePolicyLFU, keepsHot: true},
+		{policy: CachePolicySLRU, keepsHot: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			c, err := newPolicyCache[string, int](tt.policy, 10)
+			require.NoError(t, err)
+			c.Add("hot", 0)
+			for i := 0; i < 3; i++ {
+				_, ok := c.Get("hot")
+				require.True(t, ok)
+			}
+			for i := 0; i < 20; i++ {
+				c.Add(fmt.Sprintf("scan-%d", i), i)
+				assert.LessOrEqual(t, c.Len(), 10)
+			}
+			assert.Equal(t, tt.keepsHot, c.Contains("hot"))
+			assert.Len(t, c.Keys(), c.Len())
+			k, _, ok := c.GetOldest()
+			require.True(t, ok)
+			assert.Equal(t, c.Keys()[0], k, "the keys are listed in the order they would be evicted")
+			assert.Equal(t, 5, c.Resize(5))
+			assert.Equal(t, 5, c.Len())
+		})
+	}
+}
+
+func TestSeriesListCacheStatsWithPolicies(t *testing.T) {
+	for _, p := range cachePolicies {
+		t.Run(p.String(), func(t *testing.T) {
+			c := newSeriesListCache(2, 0, p)
+			list := pbv1.SeriesList{{ID: common.SeriesID(1)}}
+			_, gen, ok := c.get("a")
+			require.False(t, ok)
+			c.put("a", gen, nil, list)
+			_, _, ok = c.get("a")
+			require.True(t, ok)
+			assert.Equal(t, SeriesCacheStats{Hits: 1, Misses: 1}, c.stats())
+			assert.Equal(t, 0.5, c.stats().HitRatio())
+		})
+	}
+}
+
+// BenchmarkCachePolicyZipf reports the hit ratio of every policy under a skewed access pattern,
+// which repeatedly scans over cold keys.
+func BenchmarkCachePolicyZipf(b *testing.B) {
+	const (
+		size    = 1000
+		keySpan = 100000
+	)
+	for _, p := range cachePolicies {
+		b.Run(p.String(), func(b *testing.B) {
+			c, err := newPolicyCache[uint64, struct{}](p, size)
+			require.NoError(b, err)
+			r := rand.New(rand.NewSource(1))
+			zipf := rand.NewZipf(r, 1.1, 1, keySpan-1)
+			var hits, scan uint64
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				key := zipf.Uint64()
+				if i%4 == 0 {
+					// The cold keys are out of the span of the zipfian ones.
+					scan++
+					key = keySpan + scan
+				}
+				if _, ok := c.Get(key); ok {
+					hits++
+					continue
+				}
+				c.Add(key, struct{}{})
+			}
+			b.ReportMetric(float64(hits)/float64(b.N), "hit-ratio")
+		})
+	}
+}
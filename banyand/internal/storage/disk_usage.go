@@ -0,0 +1,123 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package storage
+
+import (
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+// usedDiskPercent reports the used percent of the disk holding the path.
+var usedDiskPercent = func(path string) (float64, error) {
+	usage, err := disk.Usage(path)
+	if err != nil {
+		return 0, err
+	}
+	return usage.UsedPercent, nil
+}
+
+// oldestReclaimable returns the oldest segment the disk pressure is allowed to delete, it's nil if there isn't any.
+// The latest floor segments and the ones not ended by now, which are being written, are retained.
+// The returned segment is referred, the caller releases it.
+func (sc *segmentController[T, O]) oldestReclaimable(now time.Time, floor int) *segment[T] {
+	ss := sc.segments()
+	for i := 1; i < len(ss); i++ {
+		ss[i].DecRef()
+	}
+	if len(ss) == 0 {
+		return nil
+	}
+	if len(ss) <= max(floor, 1) || !ss[0].Before(now) {
+		ss[0].DecRef()
+		return nil
+	}
+	return ss[0]
+}
+
+// reclaim deletes the segments of the oldest time range from all the shards if the usage of the disk exceeds maxPercent,
+// regardless of the TTL. A deleted segment only frees the disk once the queries holding it are done,
+// so the usage measured right after a deletion is stale. At most one time range is deleted per call,
+// and the next call measures the usage again.
+func (d *database[T, O]) reclaim(now time.Time, maxPercent float64, floor int) (removed *timestamp.TimeRange, err error) {
+	shardList := d.sLst.Load()
+	if shardList == nil {
+		return nil, nil
+	}
+	type reclaimable struct {
+		sc *segmentController[T, O]
+		s  *segment[T]
+	}
+	var oldest []reclaimable
+	defer func() {
+		for _, r := range oldest {
+			r.s.DecRef()
+		}
+	}()
+	for _, shard := range *shardList {
+		s := shard.segmentController.oldestReclaimable(now, floor)
+		if s == nil {
+			continue
+		}
+		switch {
+		case len(oldest) == 0 || s.Start.Equal(oldest[0].s.Start):
+			oldest = append(oldest, reclaimable{sc: shard.segmentController, s: s})
+		case s.Start.Before(oldest[0].s.Start):
+			for _, r := range oldest {
+				r.s.DecRef()
+			}
+			oldest = append(oldest[:0], reclaimable{sc: shard.segmentController, s: s})
+		default:
+			s.DecRef()
+		}
+	}
+	if len(oldest) == 0 {
+		return nil, nil
+	}
+	used, err := usedDiskPercent(d.location)
+	if err != nil || used <= maxPercent {
+		return nil, err
+	}
+	for _, r := range oldest {
+		r.sc.deleteSegment(r.s)
+	}
+	tr := oldest[0].s.TimeRange
+	d.logger.Info().Stringer("time_range", tr).Int("shards", len(oldest)).
+		Float64("disk_used_percent", used).Msg("reclaimed the oldest segments under the disk pressure")
+	return &tr, nil
+}
+
+func (rc *retentionTask[T, O]) reclaim(now time.Time, l *logger.Logger) bool {
+	select {
+	case rc.running <- struct{}{}:
+	default:
+		return true
+	}
+	defer func() {
+		<-rc.running
+	}()
+
+	opts := rc.database.opts
+	if _, err := rc.database.reclaim(now, float64(opts.MaxDiskUsagePercent), opts.MinRetainedSegments); err != nil {
+		l.Error().Err(err).Msg("failed to reclaim the segments under the disk pressure")
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package storage
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+func TestReclaimUnderDiskPressure(t *testing.T) {
+	tests := []struct {
+		name string
+		// usage is the reported disk usage per check, the last one is reported once they run out.
+		usage []float64
+		// now is the time since the start of the first segment.
+		now   time.Duration
+		floor int
+		want  []int
+	}{
+		{name: "no pressure", usage: []float64{80}, now: 73 * time.Hour, floor: 1},
+		{name: "until the usage drops", usage: []float64{95, 91, 60}, now: 73 * time.Hour, floor: 1, want: []int{0, 1}},
+		{name: "retain the floor", usage: []float64{99}, now: 73 * time.Hour, floor: 2, want: []int{0, 1}},
+		{name: "retain the writable segment", usage: []float64{99}, now: 49 * time.Hour, floor: 1, want: []int{0, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checks := 0
+			origin := usedDiskPercent
+			usedDiskPercent = func(string) (float64, error) {
+				used := tt.usage[min(checks, len(tt.usage)-1)]
+				checks++
+				return used, nil
+			}
+			defer func() { usedDiskPercent = origin }()
+
+			db, c, segCtrl, dfFn := setUpDB(t)
+			defer dfFn()
+			start := c.Now()
+			// The second shard starts a day later, its segments are deleted along with the first shard's of the same days.
+			for i := 1; i < 4; i++ {
+				for _, shardID := range []common.ShardID{0, 1} {
+					tsTable, err := db.CreateTSTableIfNotExist(shardID, start.AddDate(0, 0, i))
+					require.NoError(t, err)
+					tsTable.DecRef()
+				}
+			}
+			// Every tick reclaims one time range at most.
+			var removed []timestamp.TimeRange
+			for tick := 0; tick < 5; tick++ {
+				tr, err := db.reclaim(start.Add(tt.now), 90, tt.floor)
+				require.NoError(t, err)
+				if tr == nil {
+					break
+				}
+				removed = append(removed, *tr)
+			}
+			var want []timestamp.TimeRange
+			for _, day := range tt.want {
+				want = append(want, timestamp.NewSectionTimeRange(start.AddDate(0, 0, day), start.AddDate(0, 0, day+1)))
+			}
+			assert.Equal(t, want, removed)
+			segments := func(sc *segmentController[*MockTSTable, any]) int {
+				ss := sc.segments()
+				for i := range ss {
+					ss[i].DecRef()
+				}
+				return len(ss)
+			}
+			second, ok := db.getShard(1)
+			require.True(t, ok)
+			assert.Equal(t, 4-len(tt.want), segments(segCtrl))
+			removedFromSecond := len(tt.want)
+			if slices.Contains(tt.want, 0) {
+				removedFromSecond--
+			}
+			assert.Equal(t, 3-removedFromSecond, segments(second.segmentController))
+		})
+	}
+}
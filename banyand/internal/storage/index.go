@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"path"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -49,11 +50,37 @@ func (d *database[T, O]) Lookup(ctx context.Context, series []*pbv1.Series) (pbv
 	return d.indexController.searchPrimary(ctx, series)
 }
 
+func (d *database[T, O]) LookupByID(ctx context.Context, ids []common.SeriesID) (pbv1.SeriesList, error) {
+	return d.indexController.searchByID(ctx, ids)
+}
+
+func (d *database[T, O]) PersistSeriesIndex(ctx context.Context) error {
+	return d.indexController.persist(ctx)
+}
+
+func (d *database[T, O]) SeriesCacheStats() SeriesCacheStats {
+	return d.indexController.cacheStats()
+}
+
+func (d *database[T, O]) SeriesCacheEntries() []SeriesCacheEntry {
+	return d.indexController.cacheEntries()
+}
+
+func (d *database[T, O]) EvictSeries(series *pbv1.Series) (int, error) {
+	if err := series.Marshal(); err != nil {
+		return 0, errors.WithMessagef(err, "failed to marshal the series of %s", series.Subject)
+	}
+	return d.indexController.evictSeries(series.ID), nil
+}
+
 type seriesIndex struct {
 	startTime time.Time
 	store     index.SeriesStore
 	l         *logger.Logger
+	cache     *seriesListCache
 	path      string
+	group     string
+	maxSeries int
 }
 
 func newSeriesIndex(ctx context.Context, path string, startTime time.Time, flushTimeoutSeconds int64) (*seriesIndex, error) {
@@ -83,6 +110,9 @@ func (s *seriesIndex) Write(docs index.Documents) error {
 		return err
 	}
 	<-applied
+	if s.cache != nil {
+		s.cache.invalidate(docs)
+	}
 	return nil
 }
 
@@ -97,17 +127,74 @@ func (s *seriesIndex) searchPrimary(ctx context.Context, series []*pbv1.Series)
 			return nil, err
 		}
 	}
-	ss, err := s.store.Search(ctx, seriesMatchers)
+	var cacheKey string
+	var generation uint64
+	if s.cache != nil {
+		cacheKey = seriesListCacheKey(seriesMatchers)
+		var sl pbv1.SeriesList
+		var ok bool
+		if sl, generation, ok = s.cache.get(cacheKey); ok {
+			if err := s.checkSeriesLimit(ctx, len(sl)); err != nil {
+				return nil, err
+			}
+			return sl, nil
+		}
+	}
+	// The search stops right after the limit, as the series beyond it are enough to reject the query.
+	limit := maxSeriesOf(ctx, s.maxSeries)
+	if limit > 0 {
+		limit++
+	}
+	ss, err := s.store.Search(ctx, seriesMatchers, limit)
 	if err != nil {
 		return nil, err
 	}
+	if err = s.checkSeriesLimit(ctx, len(ss)); err != nil {
+		return nil, err
+	}
 	result, err := convertIndexSeriesToSeriesList(ss)
 	if err != nil {
 		return nil, errors.WithMessagef(err, "failed to convert index series to series list, matchers: %v, matched: %d", seriesMatchers, len(ss))
 	}
+	if s.cache != nil {
+		s.cache.put(cacheKey, generation, seriesMatchers, result)
+	}
 	return result, nil
 }
 
+func (s *seriesIndex) searchByID(ctx context.Context, ids []common.SeriesID) (pbv1.SeriesList, error) {
+	ss, err := s.store.SearchByID(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	result, err := convertIndexSeriesToSeriesList(ss)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to convert index series to series list, ids: %v", ids)
+	}
+	return result, nil
+}
+
+func (s *seriesIndex) cacheStats() SeriesCacheStats {
+	if s.cache == nil {
+		return SeriesCacheStats{}
+	}
+	return s.cache.stats()
+}
+
+func (s *seriesIndex) cacheEntries() []SeriesCacheEntry {
+	if s.cache == nil {
+		return nil
+	}
+	return s.cache.entries()
+}
+
+func (s *seriesIndex) evictSeries(id common.SeriesID) int {
+	if s.cache == nil {
+		return 0
+	}
+	return s.cache.evict(id)
+}
+
 var emptySeriesMatcher = index.SeriesMatcher{}
 
 func convertEntityValuesToSeriesMatcher(series *pbv1.Series) (index.SeriesMatcher, error) {
@@ -248,6 +335,10 @@ func appendSeriesList(dest, src pbv1.SeriesList, target common.SeriesID) pbv1.Se
 	return dest
 }
 
+func (s *seriesIndex) persist(ctx context.Context) error {
+	return s.store.Persist(ctx)
+}
+
 func (s *seriesIndex) Close() error {
 	return s.store.Close()
 }
@@ -258,6 +349,7 @@ type seriesIndexController[T TSTable, O any] struct {
 	standby         *seriesIndex
 	l               *logger.Logger
 	location        string
+	group           string
 	opts            TSDBOpts[T, O]
 	standbyLiveTime time.Duration
 	sync.RWMutex
@@ -282,6 +374,7 @@ func newSeriesIndexController[T TSTable, O any](
 		clock:           clock,
 		standbyLiveTime: standbyLiveTime,
 		location:        filepath.Clean(opts.Location),
+		group:           common.GetPosition(ctx).Database,
 		l:               l,
 	}
 	idxName, err := sic.loadIdx()
@@ -307,6 +400,9 @@ func newSeriesIndexController[T TSTable, O any](
 	default:
 		return nil, errors.New("unexpected series index count")
 	}
+	if opts.SeriesCacheWarmup {
+		sic.warmup(ctx)
+	}
 	return sic, nil
 }
 
@@ -357,7 +453,14 @@ func (sic *seriesIndexController[T, O]) openIdx(ctx context.Context, name string
 			return nil, err
 		}
 
-		return newSeriesIndex(ctx, p, sic.opts.TTL.Unit.standard(time.Unix(0, t)), sic.opts.SeriesIndexFlushTimeoutSeconds)
+		si, err := newSeriesIndex(ctx, p, sic.opts.TTL.Unit.standard(time.Unix(0, t)), sic.opts.SeriesIndexFlushTimeoutSeconds)
+		if err != nil {
+			return nil, err
+		}
+		si.cache = newSeriesListCache(sic.opts.SeriesCacheSize, sic.opts.SeriesCacheTTL, sic.opts.SeriesCachePolicy)
+		si.maxSeries = sic.opts.MaxSeriesPerQuery
+		si.group = sic.group
+		return si, nil
 	}
 	return nil, errors.New("unexpected series index name")
 }
@@ -448,6 +551,31 @@ func (sic *seriesIndexController[T, O]) searchPrimary(ctx context.Context, serie
 	return sic.standby.searchPrimary(ctx, series)
 }
 
+// searchByID looks the IDs up in the hot index, and the ones absent from it in the standby index.
+func (sic *seriesIndexController[T, O]) searchByID(ctx context.Context, ids []common.SeriesID) (pbv1.SeriesList, error) {
+	sic.RLock()
+	defer sic.RUnlock()
+
+	sl, err := sic.hot.searchByID(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	if len(sl) == len(ids) || sic.standby == nil {
+		return sl, nil
+	}
+	absent := make([]common.SeriesID, 0, len(ids)-len(sl))
+	for _, id := range ids {
+		if !slices.ContainsFunc(sl, func(s *pbv1.Series) bool { return s.ID == id }) {
+			absent = append(absent, id)
+		}
+	}
+	standby, err := sic.standby.searchByID(ctx, absent)
+	if err != nil {
+		return nil, err
+	}
+	return append(sl, standby...), nil
+}
+
 func (sic *seriesIndexController[T, O]) Search(ctx context.Context, series []*pbv1.Series,
 	filter index.Filter, order *pbv1.OrderBy, preloadSize int,
 ) (pbv1.SeriesList, error) {
@@ -464,7 +592,51 @@ func (sic *seriesIndexController[T, O]) Search(ctx context.Context, series []*pb
 	return sic.standby.Search(ctx, series, filter, order, preloadSize)
 }
 
+func (sic *seriesIndexController[T, O]) persist(ctx context.Context) error {
+	sic.RLock()
+	defer sic.RUnlock()
+	if sic.standby != nil {
+		return multierr.Combine(sic.hot.persist(ctx), sic.standby.persist(ctx))
+	}
+	return sic.hot.persist(ctx)
+}
+
+func (sic *seriesIndexController[T, O]) cacheStats() SeriesCacheStats {
+	sic.RLock()
+	defer sic.RUnlock()
+	stats := sic.hot.cacheStats()
+	if sic.standby != nil {
+		stats.add(sic.standby.cacheStats())
+	}
+	return stats
+}
+
+func (sic *seriesIndexController[T, O]) cacheEntries() []SeriesCacheEntry {
+	sic.RLock()
+	defer sic.RUnlock()
+	entries := sic.hot.cacheEntries()
+	if sic.standby != nil {
+		entries = append(entries, sic.standby.cacheEntries()...)
+	}
+	return entries
+}
+
+func (sic *seriesIndexController[T, O]) evictSeries(id common.SeriesID) int {
+	sic.RLock()
+	defer sic.RUnlock()
+	n := sic.hot.evictSeries(id)
+	if sic.standby != nil {
+		n += sic.standby.evictSeries(id)
+	}
+	return n
+}
+
 func (sic *seriesIndexController[T, O]) Close() error {
+	if sic.opts.SeriesCacheWarmup {
+		if err := sic.snapshotHotSeries(); err != nil {
+			sic.l.Warn().Err(err).Msg("failed to persist the hot series")
+		}
+	}
 	sic.Lock()
 	defer sic.Unlock()
 	if sic.standby != nil {
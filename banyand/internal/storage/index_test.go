@@ -28,9 +28,11 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/apache/skywalking-banyandb/api/common"
 	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
 	"github.com/apache/skywalking-banyandb/pkg/index"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/meter"
 	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
 	"github.com/apache/skywalking-banyandb/pkg/test"
 	"github.com/apache/skywalking-banyandb/pkg/test/flags"
@@ -177,6 +179,177 @@ func TestSeriesIndex_Primary(t *testing.T) {
 	}
 }
 
+func TestSeriesIndex_ListCache(t *testing.T) {
+	ctx := context.Background()
+	path, fn := setUp(require.New(t))
+	si, err := newSeriesIndex(ctx, path, time.Now(), 0)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, si.Close())
+		fn()
+	}()
+	si.cache = newSeriesListCache(DefaultSeriesCacheSize, DefaultSeriesCacheTTL, CachePolicyLRU)
+	newDoc := func(svc, instance string) index.Document {
+		series := testSeriesPool.Generate()
+		defer testSeriesPool.Release(series)
+		series.Subject = "service_instance_latency"
+		series.EntityValues = []*modelv1.TagValue{
+			{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: svc}}},
+			{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: instance}}},
+		}
+		require.NoError(t, series.Marshal())
+		return index.Document{
+			DocID:        uint64(series.ID),
+			EntityValues: append([]byte(nil), series.Buffer...),
+		}
+	}
+	list := func() pbv1.SeriesList {
+		seriesQuery := testSeriesPool.Generate()
+		defer testSeriesPool.Release(seriesQuery)
+		seriesQuery.Subject = "service_instance_latency"
+		seriesQuery.EntityValues = []*modelv1.TagValue{
+			{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "svc_1"}}},
+			pbv1.AnyTagValue,
+		}
+		sl, errSearch := si.searchPrimary(ctx, []*pbv1.Series{seriesQuery})
+		require.NoError(t, errSearch)
+		return sl
+	}
+	require.NoError(t, si.Write(index.Documents{newDoc("svc_1", "instance_1"), newDoc("svc_2", "instance_1")}))
+
+	require.Len(t, list(), 1)
+	assert.Equal(t, SeriesCacheStats{Misses: 1}, si.cacheStats())
+	require.Len(t, list(), 1)
+	assert.Equal(t, SeriesCacheStats{Hits: 1, Misses: 1}, si.cacheStats())
+
+	// Rewriting known series and writing unrelated ones keep the entry.
+	require.NoError(t, si.Write(index.Documents{newDoc("svc_1", "instance_1"), newDoc("svc_3", "instance_1")}))
+	require.Len(t, list(), 1)
+	assert.Equal(t, SeriesCacheStats{Hits: 2, Misses: 1}, si.cacheStats())
+
+	// A new series under the cached path invalidates the entry.
+	require.NoError(t, si.Write(index.Documents{newDoc("svc_1", "instance_2")}))
+	require.Len(t, list(), 2)
+	assert.Equal(t, SeriesCacheStats{Hits: 2, Misses: 2}, si.cacheStats())
+	assert.InDelta(t, 0.5, si.cacheStats().HitRatio(), 0.001)
+}
+
+func TestSeriesIndex_ListCacheEntries(t *testing.T) {
+	ctx := context.Background()
+	path, fn := setUp(require.New(t))
+	si, err := newSeriesIndex(ctx, path, time.Now(), 0)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, si.Close())
+		fn()
+	}()
+	si.cache = newSeriesListCache(DefaultSeriesCacheSize, DefaultSeriesCacheTTL, CachePolicyLRU)
+	newSeries := func(svc string, instance *modelv1.TagValue) *pbv1.Series {
+		return &pbv1.Series{
+			Subject: "service_instance_latency",
+			EntityValues: []*modelv1.TagValue{
+				{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: svc}}},
+				instance,
+			},
+		}
+	}
+	instance1 := &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "instance_1"}}}
+	var docs index.Documents
+	var ids []common.SeriesID
+	for _, svc := range []string{"svc_1", "svc_2"} {
+		series := newSeries(svc, instance1)
+		require.NoError(t, series.Marshal())
+		ids = append(ids, series.ID)
+		docs = append(docs, index.Document{DocID: uint64(series.ID), EntityValues: series.Buffer})
+	}
+	require.NoError(t, si.Write(docs))
+	for _, svc := range []string{"svc_1", "svc_2"} {
+		sl, errSearch := si.searchPrimary(ctx, []*pbv1.Series{newSeries(svc, pbv1.AnyTagValue)})
+		require.NoError(t, errSearch)
+		require.Len(t, sl, 1)
+	}
+
+	entries := si.cacheEntries()
+	require.Len(t, entries, 2)
+	for i, e := range entries {
+		assert.Equal(t, []common.SeriesID{ids[i]}, e.SeriesIDs)
+		assert.Positive(t, e.SizeBytes)
+		assert.False(t, e.LastAccess.Before(e.CreatedAt))
+	}
+
+	assert.Equal(t, 1, si.evictSeries(ids[0]))
+	assert.Equal(t, 0, si.evictSeries(ids[0]), "the series is no longer cached")
+	entries = si.cacheEntries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, []common.SeriesID{ids[1]}, entries[0].SeriesIDs)
+}
+
+func TestSeriesIndex_MaxSeries(t *testing.T) {
+	ctx := context.Background()
+	path, fn := setUp(require.New(t))
+	si, err := newSeriesIndex(ctx, path, time.Now(), 0)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, si.Close())
+		fn()
+	}()
+	const limit = 100
+	si.maxSeries = limit
+	si.group = "sw_metric"
+	exceeded := &recordingCounter{values: make(map[string]float64)}
+	origin := seriesLimitExceeded
+	seriesLimitExceeded = func() meter.Counter { return exceeded }
+	defer func() { seriesLimitExceeded = origin }()
+	si.cache = newSeriesListCache(DefaultSeriesCacheSize, DefaultSeriesCacheTTL, CachePolicyLRU)
+	// The high-cardinality service "svc_1" has one series more than the limit.
+	var docs index.Documents
+	for i := 0; i <= limit; i++ {
+		for _, svc := range []string{"svc_1", fmt.Sprintf("svc_2_%d", i)} {
+			series := testSeriesPool.Generate()
+			series.Subject = "service_instance_latency"
+			series.EntityValues = []*modelv1.TagValue{
+				{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: svc}}},
+				{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: fmt.Sprintf("instance_%d", i)}}},
+			}
+			require.NoError(t, series.Marshal())
+			docs = append(docs, index.Document{
+				DocID:        uint64(series.ID),
+				EntityValues: append([]byte(nil), series.Buffer...),
+			})
+			testSeriesPool.Release(series)
+		}
+	}
+	require.NoError(t, si.Write(docs))
+	search := func(ctx context.Context, svc *modelv1.TagValue) (pbv1.SeriesList, error) {
+		return si.Search(ctx, []*pbv1.Series{{
+			Subject:      "service_instance_latency",
+			EntityValues: []*modelv1.TagValue{svc, pbv1.AnyTagValue},
+		}}, nil, nil, 0)
+	}
+	svc1 := &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "svc_1"}}}
+
+	_, err = search(ctx, svc1)
+	require.ErrorIs(t, err, ErrTooManySeries)
+	require.ErrorIs(t, err, common.ErrResourceExhausted)
+	_, err = search(ctx, pbv1.AnyTagValue)
+	require.ErrorIs(t, err, ErrTooManySeries)
+	sl, err := search(ctx, &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "svc_2_1"}}})
+	require.NoError(t, err)
+	assert.Len(t, sl, 1)
+
+	// A privileged caller raises the limit, and a cached list is still guarded.
+	sl, err = search(WithMaxSeries(ctx, limit+1), svc1)
+	require.NoError(t, err)
+	assert.Len(t, sl, limit+1)
+	_, err = search(ctx, svc1)
+	require.ErrorIs(t, err, ErrTooManySeries)
+	require.ErrorIs(t, err, common.ErrResourceExhausted)
+	sl, err = search(WithMaxSeries(ctx, 0), pbv1.AnyTagValue)
+	require.NoError(t, err)
+	assert.Len(t, sl, 2*(limit+1))
+	assert.Equal(t, float64(3), exceeded.value("sw_metric"))
+}
+
 func setUp(t *require.Assertions) (tempDir string, deferFunc func()) {
 	t.NoError(logger.Init(logger.Logging{
 		Env:   "dev",
@@ -237,3 +410,66 @@ func TestSeriesIndexController(t *testing.T) {
 		require.NoError(t, sic.Close())
 	})
 }
+
+func TestSeriesIndexController_Warmup(t *testing.T) {
+	ctx := context.Background()
+	tmpDir, fn := setUp(require.New(t))
+	defer fn()
+	opts := TSDBOpts[TSTable, any]{
+		Location:          tmpDir,
+		TTL:               IntervalRule{Unit: DAY, Num: 3},
+		SeriesCacheSize:   DefaultSeriesCacheSize,
+		SeriesCacheTTL:    DefaultSeriesCacheTTL,
+		SeriesCacheWarmup: true,
+	}
+	newSeries := func(svc string, instance *modelv1.TagValue) *pbv1.Series {
+		return &pbv1.Series{
+			Subject: "service_instance_latency",
+			EntityValues: []*modelv1.TagValue{
+				{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: svc}}},
+				instance,
+			},
+		}
+	}
+	instance1 := &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "instance_1"}}}
+
+	sic, err := newSeriesIndexController(ctx, opts)
+	require.NoError(t, err)
+	var docs index.Documents
+	var ids []common.SeriesID
+	for _, svc := range []string{"svc_1", "svc_2", "svc_3"} {
+		series := newSeries(svc, instance1)
+		require.NoError(t, series.Marshal())
+		ids = append(ids, series.ID)
+		docs = append(docs, index.Document{DocID: uint64(series.ID), EntityValues: series.Buffer})
+	}
+	require.NoError(t, sic.Write(docs))
+	// svc_1 and svc_2 are hot, svc_3 is never queried.
+	for _, svc := range []string{"svc_1", "svc_2"} {
+		sl, errSearch := sic.searchPrimary(ctx, []*pbv1.Series{newSeries(svc, pbv1.AnyTagValue)})
+		require.NoError(t, errSearch)
+		require.Len(t, sl, 1)
+	}
+	require.NoError(t, sic.Close())
+
+	sic, err = newSeriesIndexController(ctx, opts)
+	require.NoError(t, err)
+	entries := sic.cacheEntries()
+	require.Len(t, entries, 2)
+	for i, e := range entries {
+		assert.Equal(t, []common.SeriesID{ids[i]}, e.SeriesIDs)
+	}
+	assert.Equal(t, SeriesCacheStats{}, sic.cacheStats(), "the cache is warmed up without a query")
+	sl, err := sic.searchPrimary(ctx, []*pbv1.Series{newSeries("svc_1", pbv1.AnyTagValue)})
+	require.NoError(t, err)
+	require.Len(t, sl, 1)
+	assert.Equal(t, SeriesCacheStats{Hits: 1}, sic.cacheStats())
+	require.NoError(t, sic.Close())
+
+	// The persisted hot series are ignored without the warmup.
+	opts.SeriesCacheWarmup = false
+	sic, err = newSeriesIndexController(ctx, opts)
+	require.NoError(t, err)
+	assert.Empty(t, sic.cacheEntries())
+	require.NoError(t, sic.Close())
+}
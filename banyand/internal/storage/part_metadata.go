@@ -0,0 +1,124 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package storage
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+
+	"github.com/pkg/errors"
+
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+const (
+	// PartMetadataVersionBinary leads the metadata encoded by MarshalPartMetadata without a version of the module's own.
+	// The legacy metadata is JSON, which leads with '{'.
+	PartMetadataVersionBinary byte = 1
+	// PartMetadataBinarySize is the size of the version, the header and the checksum of the binary metadata.
+	PartMetadataBinarySize = 1 + 6*8 + 4
+)
+
+var (
+	// ErrPartMetadataChecksumMismatch denotes the metadata of a part doesn't match its checksum.
+	ErrPartMetadataChecksumMismatch = errors.New("metadata checksum mismatch")
+	partMetadataChecksumTable       = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// PartMetadataHeader holds the metadata fields shared by the stream and measure parts.
+type PartMetadataHeader struct {
+	CompressedSizeBytes   uint64
+	UncompressedSizeBytes uint64
+	TotalCount            uint64
+	BlocksCount           uint64
+	MinTimestamp          int64
+	MaxTimestamp          int64
+}
+
+// MarshalPartMetadata appends the version, the little-endian fields of the header, the tail appended by appendTail
+// if it isn't nil, and the CRC-32C checksum of them all to dst.
+func MarshalPartMetadata(dst []byte, version byte, h PartMetadataHeader, appendTail func(dst []byte) []byte) []byte {
+	start := len(dst)
+	dst = append(dst, version)
+	dst = binary.LittleEndian.AppendUint64(dst, h.CompressedSizeBytes)
+	dst = binary.LittleEndian.AppendUint64(dst, h.UncompressedSizeBytes)
+	dst = binary.LittleEndian.AppendUint64(dst, h.TotalCount)
+	dst = binary.LittleEndian.AppendUint64(dst, h.BlocksCount)
+	dst = binary.LittleEndian.AppendUint64(dst, uint64(h.MinTimestamp))
+	dst = binary.LittleEndian.AppendUint64(dst, uint64(h.MaxTimestamp))
+	if appendTail != nil {
+		dst = appendTail(dst)
+	}
+	return binary.LittleEndian.AppendUint32(dst, crc32.Checksum(dst[start:], partMetadataChecksumTable))
+}
+
+// UnmarshalPartMetadata verifies the checksum of the metadata encoded by MarshalPartMetadata and decodes its header.
+// It returns the version and the tail, which are left to the caller to validate.
+func UnmarshalPartMetadata(src []byte, h *PartMetadataHeader) (byte, []byte, error) {
+	if len(src) < PartMetadataBinarySize {
+		return 0, nil, errors.Errorf("unexpected metadata size; got %d; want at least %d", len(src), PartMetadataBinarySize)
+	}
+	payload := src[:len(src)-4]
+	want := binary.LittleEndian.Uint32(src[len(payload):])
+	if got := crc32.Checksum(payload, partMetadataChecksumTable); got != want {
+		return 0, nil, errors.Wrapf(ErrPartMetadataChecksumMismatch, "got %08x; want %08x", got, want)
+	}
+	version := payload[0]
+	src = payload[1:]
+	h.CompressedSizeBytes = binary.LittleEndian.Uint64(src)
+	src = src[8:]
+	h.UncompressedSizeBytes = binary.LittleEndian.Uint64(src)
+	src = src[8:]
+	h.TotalCount = binary.LittleEndian.Uint64(src)
+	src = src[8:]
+	h.BlocksCount = binary.LittleEndian.Uint64(src)
+	src = src[8:]
+	h.MinTimestamp = int64(binary.LittleEndian.Uint64(src))
+	src = src[8:]
+	h.MaxTimestamp = int64(binary.LittleEndian.Uint64(src))
+	return version, src[8:], nil
+}
+
+// PartMetadataJSONChecksum computes the CRC-32C checksum of the legacy JSON encoding of pm,
+// taking its checksum field, which checksum points to, as zero.
+func PartMetadataJSONChecksum(pm any, checksum *uint32) uint32 {
+	saved := *checksum
+	*checksum = 0
+	data, err := json.Marshal(pm)
+	*checksum = saved
+	if err != nil {
+		logger.Panicf("cannot marshal metadata: %s", err)
+	}
+	return crc32.Checksum(data, partMetadataChecksumTable)
+}
+
+// UnmarshalPartMetadataJSON decodes the legacy JSON metadata into pm, whose checksum field checksum points to.
+// The metadata written before the checksum was recorded has zero, and isn't verified.
+func UnmarshalPartMetadataJSON(data []byte, pm any, checksum *uint32) error {
+	if err := json.Unmarshal(data, pm); err != nil {
+		return err
+	}
+	if *checksum == 0 {
+		return nil
+	}
+	if got := PartMetadataJSONChecksum(pm, checksum); got != *checksum {
+		return errors.Wrapf(ErrPartMetadataChecksumMismatch, "got %08x; want %08x", got, *checksum)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package storage
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testPartMetadataHeader = PartMetadataHeader{
+	CompressedSizeBytes:   100,
+	UncompressedSizeBytes: 200,
+	TotalCount:            42,
+	BlocksCount:           3,
+	MinTimestamp:          -1,
+	MaxTimestamp:          10,
+}
+
+type legacyPartMetadata struct {
+	TotalCount   uint64 `json:"totalCount"`
+	MinTimestamp int64  `json:"minTimestamp"`
+	Checksum     uint32 `json:"checksum,omitempty"`
+}
+
+func marshalLegacyPartMetadata(tb testing.TB, pm legacyPartMetadata) []byte {
+	pm.Checksum = PartMetadataJSONChecksum(&pm, &pm.Checksum)
+	data, err := json.Marshal(&pm)
+	require.NoError(tb, err)
+	return data
+}
+
+func TestPartMetadataBinary(t *testing.T) {
+	appendTail := func(dst []byte) []byte { return binary.LittleEndian.AppendUint64(dst, 1000) }
+	metadata := MarshalPartMetadata(nil, 2, testPartMetadataHeader, appendTail)
+	require.Len(t, metadata, PartMetadataBinarySize+8)
+
+	var h PartMetadataHeader
+	version, tail, err := UnmarshalPartMetadata(metadata, &h)
+	require.NoError(t, err)
+	assert.Equal(t, byte(2), version)
+	assert.Equal(t, testPartMetadataHeader, h)
+	assert.Equal(t, uint64(1000), binary.LittleEndian.Uint64(tail))
+
+	version, tail, err = UnmarshalPartMetadata(MarshalPartMetadata(nil, PartMetadataVersionBinary, testPartMetadataHeader, nil), &h)
+	require.NoError(t, err)
+	assert.Equal(t, PartMetadataVersionBinary, version)
+	assert.Empty(t, tail)
+
+	// Corrupt the total count.
+	metadata[1+2*8]++
+	_, _, err = UnmarshalPartMetadata(metadata, &h)
+	assert.ErrorIs(t, err, ErrPartMetadataChecksumMismatch)
+	_, _, err = UnmarshalPartMetadata(metadata[:PartMetadataBinarySize-1], &h)
+	assert.Error(t, err)
+}
+
+func TestPartMetadataJSON(t *testing.T) {
+	written := legacyPartMetadata{TotalCount: 42, MinTimestamp: -1}
+	tests := []struct {
+		name     string
+		metadata []byte
+		wantErr  error
+	}{
+		{name: "with a checksum", metadata: marshalLegacyPartMetadata(t, written)},
+		{name: "without a checksum", metadata: []byte(`{"totalCount":42,"minTimestamp":-1}`)},
+		{
+			// The corrupted JSON is still valid.
+			name:     "corrupted",
+			metadata: []byte(strings.Replace(string(marshalLegacyPartMetadata(t, written)), `"totalCount":42`, `"totalCount":52`, 1)),
+			wantErr:  ErrPartMetadataChecksumMismatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pm legacyPartMetadata
+			err := UnmarshalPartMetadataJSON(tt.metadata, &pm, &pm.Checksum)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			pm.Checksum = 0
+			assert.Equal(t, written, pm)
+		})
+	}
+}
+
+func BenchmarkPartMetadata(b *testing.B) {
+	b.Run("marshal binary", func(b *testing.B) {
+		b.ReportAllocs()
+		var dst []byte
+		for i := 0; i < b.N; i++ {
+			dst = MarshalPartMetadata(dst[:0], PartMetadataVersionBinary, testPartMetadataHeader, nil)
+		}
+	})
+	b.Run("marshal json", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			marshalLegacyPartMetadata(b, legacyPartMetadata{TotalCount: 42})
+		}
+	})
+	b.Run("unmarshal binary", func(b *testing.B) {
+		b.ReportAllocs()
+		metadata := MarshalPartMetadata(nil, PartMetadataVersionBinary, testPartMetadataHeader, nil)
+		var h PartMetadataHeader
+		for i := 0; i < b.N; i++ {
+			if _, _, err := UnmarshalPartMetadata(metadata, &h); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("unmarshal json", func(b *testing.B) {
+		b.ReportAllocs()
+		metadata := marshalLegacyPartMetadata(b, legacyPartMetadata{TotalCount: 42})
+		var pm legacyPartMetadata
+		for i := 0; i < b.N; i++ {
+			if err := UnmarshalPartMetadataJSON(metadata, &pm, &pm.Checksum); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
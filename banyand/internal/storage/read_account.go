@@ -0,0 +1,95 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package storage
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+)
+
+// ErrTooManyBytesRead indicates a query reads more bytes from disk than its limit.
+var ErrTooManyBytesRead = errors.WithMessage(common.ErrResourceExhausted, "too many bytes read")
+
+// ReadAccount accumulates the bytes a query reads from disk.
+// A nil ReadAccount accounts nothing.
+type ReadAccount struct {
+	err   error
+	read  atomic.Uint64
+	limit uint64
+	mu    sync.Mutex
+}
+
+// NewReadAccount returns a ReadAccount aborting the query once it reads more than limit bytes.
+// Zero means no limit.
+func NewReadAccount(limit uint64) *ReadAccount {
+	return &ReadAccount{limit: limit}
+}
+
+// Charge adds n bytes to the account. It returns ErrTooManyBytesRead once the account exceeds its limit,
+// and keeps returning it afterwards.
+func (a *ReadAccount) Charge(n uint64) error {
+	if a == nil {
+		return nil
+	}
+	read := a.read.Add(n)
+	if a.limit == 0 || read <= a.limit {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.err == nil {
+		a.err = errors.WithMessagef(ErrTooManyBytesRead, "the query reads more than %d bytes", a.limit)
+	}
+	return a.err
+}
+
+// BytesRead returns the bytes read so far.
+func (a *ReadAccount) BytesRead() uint64 {
+	if a == nil {
+		return 0
+	}
+	return a.read.Load()
+}
+
+// Err returns ErrTooManyBytesRead if the account exceeded its limit.
+func (a *ReadAccount) Err() error {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.err
+}
+
+type readAccountContextKey struct{}
+
+// WithReadAccount attaches the account to the query context.
+func WithReadAccount(ctx context.Context, a *ReadAccount) context.Context {
+	return context.WithValue(ctx, readAccountContextKey{}, a)
+}
+
+// ReadAccountFrom returns the account attached to the query context, or nil if there isn't one.
+func ReadAccountFrom(ctx context.Context) *ReadAccount {
+	a, _ := ctx.Value(readAccountContextKey{}).(*ReadAccount)
+	return a
+}
@@ -0,0 +1,60 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package storage
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sync"
+
+	"github.com/apache/skywalking-banyandb/banyand/observability"
+	"github.com/apache/skywalking-banyandb/pkg/meter"
+)
+
+// retentionCounters count the segments the retention deletes and the disk space it reclaims per group,
+// along with the writes beyond the retention window by their outcomes.
+// The time ranges of the segments aren't labeled to bound the cardinality.
+type retentionCounters struct {
+	segmentsDeleted      meter.Counter
+	bytesReclaimed       meter.Counter
+	outOfRetentionWrites meter.Counter
+}
+
+var retentionMetrics = sync.OnceValue(func() *retentionCounters {
+	providers := observability.NewMeterProviders(observability.RootScope.SubScope("storage"))
+	return &retentionCounters{
+		segmentsDeleted:      observability.NewCounter(providers, "segments_deleted_total", "group"),
+		bytesReclaimed:       observability.NewCounter(providers, "bytes_reclaimed_total", "group"),
+		outOfRetentionWrites: observability.NewCounter(providers, "out_of_retention_writes", "group", "outcome"),
+	}
+})
+
+// dirSize returns the total size of the regular files under the path.
+func dirSize(path string) int64 {
+	var size int64
+	_ = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		if info, errInfo := d.Info(); errInfo == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,127 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package storage
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+)
+
+// ErrOutOfRetention is returned when a write is rejected for its timestamp beyond the retention window.
+var ErrOutOfRetention = errors.New("timestamp is out of the retention window")
+
+// OutOfRetentionPolicy decides how to handle a write beyond the retention window.
+type OutOfRetentionPolicy int
+
+// Available OutOfRetentionPolicies.
+const (
+	OutOfRetentionAccept OutOfRetentionPolicy = iota
+	OutOfRetentionReject
+	OutOfRetentionClamp
+)
+
+// ToOutOfRetentionPolicy converts a commonv1.OutOfRetentionPolicy to OutOfRetentionPolicy.
+func ToOutOfRetentionPolicy(p commonv1.OutOfRetentionPolicy) OutOfRetentionPolicy {
+	switch p {
+	case commonv1.OutOfRetentionPolicy_OUT_OF_RETENTION_POLICY_REJECT:
+		return OutOfRetentionReject
+	case commonv1.OutOfRetentionPolicy_OUT_OF_RETENTION_POLICY_CLAMP:
+		return OutOfRetentionClamp
+	default:
+		return OutOfRetentionAccept
+	}
+}
+
+// RetentionWindow is the range of the timestamps a group admits, and the policy applied to the ones beyond it.
+// The window spans from the ttl before now to the future window after now, the future window defaults to the segment interval.
+type RetentionWindow struct {
+	TTL             IntervalRule
+	FutureWindow    IntervalRule
+	SegmentInterval IntervalRule
+	Policy          OutOfRetentionPolicy
+}
+
+// NewRetentionWindow returns the retention window of a group's resource options.
+func NewRetentionWindow(opts *commonv1.ResourceOpts) RetentionWindow {
+	w := RetentionWindow{
+		TTL:             MustToIntervalRule(opts.GetTtl()),
+		SegmentInterval: MustToIntervalRule(opts.GetSegmentInterval()),
+		Policy:          ToOutOfRetentionPolicy(opts.GetOutOfRetentionPolicy()),
+	}
+	if fw := opts.GetFutureWindow(); fw != nil {
+		w.FutureWindow = MustToIntervalRule(fw)
+	}
+	return w
+}
+
+// Admit applies the out-of-retention policy to the timestamp of a write at now.
+// It returns the timestamp to write and whether the original one is out of the window.
+func (w RetentionWindow) Admit(now, ts time.Time) (time.Time, bool, error) {
+	lower := now.Add(-w.TTL.estimatedDuration()).Truncate(time.Millisecond)
+	futureWindow := w.FutureWindow
+	if futureWindow.Num == 0 {
+		futureWindow = w.SegmentInterval
+	}
+	upper := now.Add(futureWindow.estimatedDuration()).Truncate(time.Millisecond)
+	var bound time.Time
+	switch {
+	case ts.Before(lower):
+		bound = lower
+	case ts.After(upper):
+		bound = upper
+	default:
+		return ts, false, nil
+	}
+	switch w.Policy {
+	case OutOfRetentionReject:
+		return ts, true, errors.WithMessagef(ErrOutOfRetention, "%s is not in [%s, %s]",
+			ts.Format(time.RFC3339Nano), lower.Format(time.RFC3339Nano), upper.Format(time.RFC3339Nano))
+	case OutOfRetentionClamp:
+		return bound, true, nil
+	default:
+		return ts, true, nil
+	}
+}
+
+// AdmitTimestamp applies the out-of-retention policy to the timestamp of a write.
+// It returns the timestamp to write and whether the original one is out of the window.
+// The writes beyond the window are counted by whether they are accepted, clamped or rejected.
+func (d *database[T, O]) AdmitTimestamp(ts time.Time) (time.Time, bool, error) {
+	w := RetentionWindow{
+		TTL:             d.opts.TTL,
+		FutureWindow:    d.opts.FutureWindow,
+		SegmentInterval: d.opts.SegmentInterval,
+		Policy:          d.opts.OutOfRetentionPolicy,
+	}
+	admitted, outOfRetention, err := w.Admit(d.clock.Now(), ts)
+	if !outOfRetention {
+		return ts, false, nil
+	}
+	switch {
+	case err != nil:
+		retentionMetrics().outOfRetentionWrites.Inc(1, d.p.Database, "rejected")
+	case !admitted.Equal(ts):
+		retentionMetrics().outOfRetentionWrites.Inc(1, d.p.Database, "clamped")
+	default:
+		retentionMetrics().outOfRetentionWrites.Inc(1, d.p.Database, "accepted")
+	}
+	return admitted, true, err
+}
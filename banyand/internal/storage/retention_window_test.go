@@ -0,0 +1,81 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/pkg/test"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+func TestAdmitTimestamp(t *testing.T) {
+	now, err := time.ParseInLocation("2006-01-02 15:04:05", "2024-05-01 12:00:00", time.Local)
+	require.NoError(t, err)
+	lower := now.AddDate(0, 0, -3)
+	upper := now.AddDate(0, 0, 1)
+	farPast := now.AddDate(0, 0, -30)
+	farFuture := now.AddDate(0, 0, 30)
+	tests := []struct {
+		ts                 time.Time
+		want               time.Time
+		name               string
+		policy             OutOfRetentionPolicy
+		wantOutOfRetention bool
+		wantErr            bool
+	}{
+		{name: "in the window", policy: OutOfRetentionReject, ts: now, want: now},
+		{name: "accept a far past write", policy: OutOfRetentionAccept, ts: farPast, want: farPast, wantOutOfRetention: true},
+		{name: "accept a far future write", policy: OutOfRetentionAccept, ts: farFuture, want: farFuture, wantOutOfRetention: true},
+		{name: "reject a far past write", policy: OutOfRetentionReject, ts: farPast, wantOutOfRetention: true, wantErr: true},
+		{name: "reject a far future write", policy: OutOfRetentionReject, ts: farFuture, wantOutOfRetention: true, wantErr: true},
+		{name: "clamp a far past write", policy: OutOfRetentionClamp, ts: farPast, want: lower, wantOutOfRetention: true},
+		{name: "clamp a far future write", policy: OutOfRetentionClamp, ts: farFuture, want: upper, wantOutOfRetention: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, defFn := test.Space(require.New(t))
+			defer defFn()
+			mc := timestamp.NewMockClock()
+			mc.Set(now)
+			tsdb, err := OpenTSDB(timestamp.SetClock(context.Background(), mc), TSDBOpts[*MockTSTable, any]{
+				Location:             dir,
+				SegmentInterval:      IntervalRule{Unit: DAY, Num: 1},
+				TTL:                  IntervalRule{Unit: DAY, Num: 3},
+				ShardNum:             1,
+				TSTableCreator:       MockTSTableCreator,
+				OutOfRetentionPolicy: tt.policy,
+			})
+			require.NoError(t, err)
+			defer tsdb.Close()
+			got, outOfRetention, err := tsdb.AdmitTimestamp(tt.ts)
+			assert.Equal(t, tt.wantOutOfRetention, outOfRetention)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrOutOfRetention)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tt.want.Equal(got), "want %s, got %s", tt.want, got)
+		})
+	}
+}
@@ -20,9 +20,11 @@ package storage
 import (
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/robfig/cron/v3"
 
 	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
 )
 
 var (
@@ -31,6 +33,33 @@ var (
 	timeEventSnapDuration = (10 * time.Minute).Nanoseconds()
 )
 
+// Status is the rotation state of a database.
+type Status struct {
+	// WritableRange is the time range of the newest segment, which is zero before any segment is created.
+	WritableRange timestamp.TimeRange
+	// Rotating is true while the segment following the newest one is being created.
+	// The writes into the new segment may be rejected until it's created.
+	Rotating bool
+}
+
+func (d *database[T, O]) Status() Status {
+	status := Status{Rotating: d.creatingSegment.Load()}
+	shardsRef := d.sLst.Load()
+	if shardsRef == nil {
+		return status
+	}
+	for _, s := range *shardsRef {
+		ss := s.segmentController.segments()
+		if len(ss) > 0 && ss[len(ss)-1].Start.After(status.WritableRange.Start) {
+			status.WritableRange = ss[len(ss)-1].TimeRange
+		}
+		for i := range ss {
+			ss[i].DecRef()
+		}
+	}
+	return status
+}
+
 func (d *database[T, O]) Tick(ts int64) {
 	if (ts - timeEventSnapDuration) < d.latestTickTime.Load() {
 		return
@@ -42,6 +71,41 @@ func (d *database[T, O]) Tick(ts int64) {
 	}
 }
 
+func (d *database[T, O]) ForceRotate() error {
+	shardsRef := d.sLst.Load()
+	if shardsRef == nil {
+		return nil
+	}
+	now := d.clock.Now()
+	d.creatingSegment.Store(true)
+	defer d.creatingSegment.Store(false)
+	for _, s := range *shardsRef {
+		seg, err := s.segmentController.rotate(now)
+		if err != nil {
+			return errors.WithMessagef(err, "failed to rotate the segment of shard %d", s.id)
+		}
+		d.logger.Info().Stringer("segment", seg).Time("segment_start", seg.Start).Msg("force to rotate the segment")
+		seg.DecRef()
+	}
+	return nil
+}
+
+func (d *database[T, O]) DeleteExpiredSegments(deadline time.Time, dryRun bool) ([]timestamp.TimeRange, error) {
+	shardsRef := d.sLst.Load()
+	if shardsRef == nil {
+		return nil, nil
+	}
+	var removed []timestamp.TimeRange
+	for _, s := range *shardsRef {
+		ranges, err := s.segmentController.remove(deadline, dryRun)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to remove the expired segments of shard %d", s.id)
+		}
+		removed = append(removed, ranges...)
+	}
+	return removed, nil
+}
+
 func (d *database[T, O]) startRotationTask() error {
 	rt := newRetentionTask(d, d.opts.TTL)
 	go func(rt *retentionTask[T, O]) {
@@ -74,16 +138,27 @@ func (d *database[T, O]) startRotationTask() error {
 							return
 						}
 						d.logger.Info().Time("segment_start", s.segmentController.segmentSize.nextTime(t)).Time("event_time", t).Msg("create new segment")
-						_, err := s.segmentController.create(s.segmentController.segmentSize.nextTime(t))
+						d.creatingSegment.Store(true)
+						seg, err := s.segmentController.create(s.segmentController.segmentSize.nextTime(t))
+						d.creatingSegment.Store(false)
 						if err != nil {
 							d.logger.Error().Err(err).Msgf("failed to create new segment.")
+							return
 						}
+						seg.DecRef()
 					}(s)
 				}
 			}(ts)
 		}
 	}(rt)
-	return d.scheduler.Register("retention", rt.option, rt.expr, rt.run)
+	if err := d.scheduler.Register("retention", rt.option, rt.expr, rt.run); err != nil {
+		return err
+	}
+	if d.opts.MaxDiskUsagePercent <= 0 {
+		return nil
+	}
+	// Check the disk usage every minute.
+	return d.scheduler.Register("disk-usage", cron.Minute|cron.Hour, "* *", rt.reclaim)
 }
 
 type retentionTask[T TSTable, O any] struct {
@@ -95,11 +170,15 @@ type retentionTask[T TSTable, O any] struct {
 }
 
 func newRetentionTask[T TSTable, O any](database *database[T, O], ttl IntervalRule) *retentionTask[T, O] {
+	// Remove the expired data daily, or hourly if the TTL is in hours.
+	expr := "5 0"
+	if ttl.Unit == HOUR {
+		expr = "5 *"
+	}
 	return &retentionTask[T, O]{
 		database: database,
 		option:   cron.Minute | cron.Hour,
-		// Remove data which is
-		expr:     "5 0",
+		expr:     expr,
 		duration: ttl.estimatedDuration(),
 		running:  make(chan struct{}, 1),
 	}
@@ -122,7 +201,7 @@ func (rc *retentionTask[T, O]) run(now time.Time, l *logger.Logger) bool {
 	deadline := now.Add(-rc.duration)
 
 	for _, shard := range *shardList {
-		if err := shard.segmentController.remove(deadline); err != nil {
+		if _, err := shard.segmentController.remove(deadline, false); err != nil {
 			l.Error().Err(err)
 		}
 	}
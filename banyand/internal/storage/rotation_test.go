@@ -19,6 +19,9 @@ package storage
 
 import (
 	"context"
+	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -57,6 +60,95 @@ func TestForwardRotation(t *testing.T) {
 	})
 }
 
+func TestForceRotation(t *testing.T) {
+	t.Run("seal the current segment and start a new one", func(t *testing.T) {
+		tsdb, c, segCtrl, dfFn := setUpDB(t)
+		defer dfFn()
+		start := c.Now()
+		rotateAt := start.Add(10*time.Hour + 30*time.Minute)
+		c.Set(rotateAt)
+		require.NoError(t, tsdb.ForceRotate())
+
+		ss := segCtrl.segments()
+		defer func() {
+			for i := range ss {
+				ss[i].DecRef()
+			}
+		}()
+		require.Len(t, ss, 2)
+		sealed, latest := ss[0], ss[1]
+		assert.Equal(t, start, sealed.Start)
+		assert.Equal(t, rotateAt, sealed.GetTimeRange().End)
+		assert.Equal(t, rotateAt, latest.Start)
+		assert.Equal(t, start.Add(24*time.Hour), latest.End, "the rotated segment ends at the next interval boundary")
+		suffix := segCtrl.Format(rotateAt)
+		assert.Equal(t, "20240501103000", suffix)
+		parsed, err := segCtrl.Parse(suffix)
+		require.NoError(t, err)
+		assert.Equal(t, rotateAt, parsed)
+
+		after, err := tsdb.CreateTSTableIfNotExist(0, rotateAt.Add(time.Minute))
+		require.NoError(t, err)
+		defer after.DecRef()
+		assert.Equal(t, rotateAt, after.GetTimeRange().Start)
+		before, err := tsdb.CreateTSTableIfNotExist(0, rotateAt.Add(-time.Minute))
+		require.NoError(t, err)
+		defer before.DecRef()
+		assert.Equal(t, start, before.GetTimeRange().Start)
+
+		require.NoError(t, tsdb.ForceRotate())
+		assert.Len(t, segCtrl.segments(), 2, "rotating twice at the same time creates no more segment")
+
+		// The point is later than a whole interval after the rotation, but earlier than the next interval boundary.
+		ts := start.Add(35 * time.Hour)
+		next, err := tsdb.CreateTSTableIfNotExist(0, ts)
+		require.NoError(t, err)
+		defer next.DecRef()
+		assert.True(t, next.GetTimeRange().Contains(ts.UnixNano()), "the segment %s should accept %s", next.GetTimeRange(), ts)
+		tables := tsdb.SelectTSTables(timestamp.NewInclusiveTimeRange(ts, ts))
+		defer func() {
+			for i := range tables {
+				tables[i].DecRef()
+			}
+		}()
+		require.Len(t, tables, 1)
+		assert.True(t, tables[0].GetTimeRange().Contains(ts.UnixNano()), "the query should read the segment written to")
+	})
+
+	t.Run("rotate under concurrent writes", func(t *testing.T) {
+		tsdb, c, segCtrl, dfFn := setUpDB(t)
+		defer dfFn()
+		rotateAt := c.Now().Add(time.Hour + time.Second)
+		c.Set(rotateAt)
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					tt, err := tsdb.CreateTSTableIfNotExist(0, rotateAt.Add(time.Duration(j)*time.Second))
+					if !assert.NoError(t, err) {
+						return
+					}
+					tt.DecRef()
+				}
+			}()
+		}
+		require.NoError(t, tsdb.ForceRotate())
+		wg.Wait()
+
+		tt, err := tsdb.CreateTSTableIfNotExist(0, rotateAt)
+		require.NoError(t, err)
+		defer tt.DecRef()
+		assert.Equal(t, rotateAt, tt.GetTimeRange().Start)
+		ss := segCtrl.segments()
+		for i := range ss {
+			ss[i].DecRef()
+		}
+		assert.Len(t, ss, 2)
+	})
+}
+
 func TestRetention(t *testing.T) {
 	t.Run("delete the segment and index when the TTL is up", func(t *testing.T) {
 		tsdb, c, segCtrl, dfFn := setUpDB(t)
@@ -92,6 +184,63 @@ func TestRetention(t *testing.T) {
 		}, flags.EventuallyTimeout, time.Millisecond, "wait for the index to be updated")
 	})
 
+	t.Run("notify the deleted segments", func(t *testing.T) {
+		tsdb, c, segCtrl, dfFn := setUpDB(t)
+		defer dfFn()
+		var mu sync.Mutex
+		var deleted []timestamp.TimeRange
+		tsdb.OnSegmentDeleted(func(_ string, tr timestamp.TimeRange) {
+			mu.Lock()
+			defer mu.Unlock()
+			deleted = append(deleted, tr)
+		})
+		deletedRanges := func() []timestamp.TimeRange {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]timestamp.TimeRange(nil), deleted...)
+		}
+		start := c.Now()
+		days := func(from, to int) []timestamp.TimeRange {
+			var result []timestamp.TimeRange
+			for i := from; i < to; i++ {
+				result = append(result, timestamp.NewSectionTimeRange(start.AddDate(0, 0, i), start.AddDate(0, 0, i+1)))
+			}
+			return result
+		}
+		ts := start
+		for i := 0; i < 4; i++ {
+			ts = ts.Add(23 * time.Hour)
+			c.Set(ts)
+			tsdb.Tick(ts.UnixNano())
+			// The segments are released, so that the deleted ones are removed from the disk.
+			require.Eventually(t, func() bool {
+				ss := segCtrl.segments()
+				for i := range ss {
+					ss[i].DecRef()
+				}
+				return len(ss) == i+2
+			}, flags.EventuallyTimeout, time.Millisecond, "wait for %d segments to be created", i+2)
+			ts = ts.Add(time.Hour)
+		}
+		assert.Empty(t, deletedRanges(), "the rotation deletes no segment")
+
+		c.Set(ts)
+		tsdb.Tick(ts.UnixNano())
+		require.EventuallyWithT(t, func(ct *assert.CollectT) {
+			assert.Equal(ct, days(0, 1), deletedRanges())
+		}, flags.EventuallyTimeout, time.Millisecond, "wait for the 1st segment to be deleted")
+
+		ts = ts.Add(2 * 24 * time.Hour)
+		c.Set(ts)
+		tsdb.Tick(ts.UnixNano())
+		require.EventuallyWithT(t, func(ct *assert.CollectT) {
+			assert.Equal(ct, days(0, 3), deletedRanges())
+		}, flags.EventuallyTimeout, time.Millisecond, "wait for the 2nd and 3rd segments to be deleted")
+		assert.Never(t, func() bool {
+			return len(deletedRanges()) > 3
+		}, flags.NeverTimeout, time.Millisecond, "every segment is notified once")
+	})
+
 	t.Run("keep the segment volume stable", func(t *testing.T) {
 		tsdb, c, segCtrl, dfFn := setUpDB(t)
 		defer dfFn()
@@ -148,13 +297,173 @@ func TestRetention(t *testing.T) {
 	})
 }
 
-func setUpDB(t *testing.T) (*database[*MockTSTable, any], timestamp.MockClock, *segmentController[*MockTSTable, any], func()) {
+func TestRotationStatus(t *testing.T) {
 	dir, defFn := test.Space(require.New(t))
-	TSDBOpts := TSDBOpts[*MockTSTable, any]{
+	defer defFn()
+	mc := timestamp.NewMockClock()
+	start, err := time.ParseInLocation("2006-01-02 15:04:05", "2024-05-01 00:00:00", time.Local)
+	require.NoError(t, err)
+	mc.Set(start)
+	// The creator blocks the creation of the second segment until it's released.
+	entered, release := make(chan struct{}), make(chan struct{})
+	tsdb, err := OpenTSDB(timestamp.SetClock(context.Background(), mc), TSDBOpts[*MockTSTable, any]{
 		Location:        dir,
 		SegmentInterval: IntervalRule{Unit: DAY, Num: 1},
 		TTL:             IntervalRule{Unit: DAY, Num: 3},
 		ShardNum:        1,
+		TSTableCreator: func(_ fs.FileSystem, _ string, _ common.Position,
+			_ *logger.Logger, tr timestamp.TimeRange, _ any,
+		) (*MockTSTable, error) {
+			if tr.Start.After(start) {
+				close(entered)
+				<-release
+			}
+			return &MockTSTable{}, nil
+		},
+	})
+	require.NoError(t, err)
+	defer tsdb.Close()
+	tt, err := tsdb.CreateTSTableIfNotExist(0, start)
+	require.NoError(t, err)
+	tt.DecRef()
+
+	status := tsdb.Status()
+	assert.False(t, status.Rotating)
+	assert.Equal(t, start, status.WritableRange.Start)
+	assert.Equal(t, start.Add(24*time.Hour), status.WritableRange.End)
+
+	tsdb.Tick(start.Add(23*time.Hour + time.Second).UnixNano())
+	select {
+	case <-entered:
+	case <-time.After(flags.EventuallyTimeout):
+		t.Fatal("the next segment isn't created")
+	}
+	status = tsdb.Status()
+	assert.True(t, status.Rotating, "the status is rotating while the next segment is being created")
+	assert.Equal(t, start, status.WritableRange.Start)
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		return !tsdb.Status().Rotating
+	}, flags.EventuallyTimeout, time.Millisecond, "the status stops rotating once the segment is created")
+	status = tsdb.Status()
+	assert.Equal(t, start.Add(24*time.Hour), status.WritableRange.Start)
+	assert.Equal(t, start.Add(48*time.Hour), status.WritableRange.End)
+}
+
+func TestHourlyRotation(t *testing.T) {
+	tsdb, c, segCtrl, dfFn := setUpDBWithIntervals(t, IntervalRule{Unit: HOUR, Num: 6}, IntervalRule{Unit: HOUR, Num: 24})
+	defer dfFn()
+	start := c.Now()
+	starts := func() []time.Time {
+		ss := segCtrl.segments()
+		defer func() {
+			for i := range ss {
+				ss[i].DecRef()
+			}
+		}()
+		var result []time.Time
+		for _, s := range ss {
+			result = append(result, s.Start)
+		}
+		return result
+	}
+	every6Hours := func(from, to int) []time.Time {
+		var result []time.Time
+		for i := from; i < to; i++ {
+			result = append(result, start.Add(time.Duration(6*i)*time.Hour))
+		}
+		return result
+	}
+	// A tick within the last hour of a segment creates the next one.
+	for i := 0; i < 4; i++ {
+		ts := start.Add(time.Duration(6*i)*time.Hour + 5*time.Hour + 10*time.Minute)
+		c.Set(ts)
+		tsdb.Tick(ts.UnixNano())
+		require.EventuallyWithTf(t, func(ct *assert.CollectT) {
+			assert.Equal(ct, every6Hours(0, i+2), starts())
+		}, flags.EventuallyTimeout, time.Millisecond, "wait for the segment starting at %s", start.Add(time.Duration(6*(i+1))*time.Hour))
+	}
+	ss := segCtrl.segments()
+	for i := range ss {
+		assert.Equal(t, 6*time.Hour, ss[i].End.Sub(ss[i].Start), "the segment %s spans 6 hours", ss[i])
+		ss[i].DecRef()
+	}
+
+	// A write after a gap creates the segment starting from the boundary before it.
+	tt, err := tsdb.CreateTSTableIfNotExist(0, start.Add(33*time.Hour+20*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, start.Add(30*time.Hour), tt.GetTimeRange().Start)
+	tt.DecRef()
+
+	// The segments ending 24 hours before the tick are deleted.
+	ts := start.Add(35*time.Hour + 10*time.Minute)
+	c.Set(ts)
+	tsdb.Tick(ts.UnixNano())
+	require.EventuallyWithT(t, func(ct *assert.CollectT) {
+		assert.Equal(ct, every6Hours(1, 7), starts())
+	}, flags.EventuallyTimeout, time.Millisecond, "wait for the first segment to be deleted")
+}
+
+func TestDeleteExpiredSegments(t *testing.T) {
+	tsdb, c, segCtrl, dfFn := setUpDB(t)
+	defer dfFn()
+	start := c.Now()
+	for i := 1; i < 4; i++ {
+		tt, err := tsdb.CreateTSTableIfNotExist(0, start.AddDate(0, 0, i))
+		require.NoError(t, err)
+		tt.DecRef()
+	}
+	segmentDirs := func() []string {
+		entries, err := os.ReadDir(segCtrl.location)
+		require.NoError(t, err)
+		var dirs []string
+		for _, e := range entries {
+			if e.IsDir() && strings.HasPrefix(e.Name(), segPathPrefix) {
+				dirs = append(dirs, e.Name())
+			}
+		}
+		return dirs
+	}
+	dirs := segmentDirs()
+	require.Len(t, dirs, 4)
+	deadline := start.AddDate(0, 0, 2)
+	want := []timestamp.TimeRange{
+		timestamp.NewSectionTimeRange(start, start.AddDate(0, 0, 1)),
+		timestamp.NewSectionTimeRange(start.AddDate(0, 0, 1), deadline),
+	}
+
+	candidates, err := tsdb.DeleteExpiredSegments(deadline, true)
+	require.NoError(t, err)
+	assert.Equal(t, want, candidates)
+	assert.Equal(t, dirs, segmentDirs(), "a dry run removes no segment")
+	ss := segCtrl.segments()
+	for i := range ss {
+		ss[i].DecRef()
+	}
+	assert.Len(t, ss, 4)
+
+	removed, err := tsdb.DeleteExpiredSegments(deadline, false)
+	require.NoError(t, err)
+	assert.Equal(t, candidates, removed, "the dry run lists the segments the real run removes")
+	assert.Eventually(t, func() bool {
+		return len(segmentDirs()) == 2
+	}, flags.EventuallyTimeout, time.Millisecond, "wait for the expired segments to be removed from the disk")
+}
+
+func setUpDB(t testing.TB) (*database[*MockTSTable, any], timestamp.MockClock, *segmentController[*MockTSTable, any], func()) {
+	return setUpDBWithIntervals(t, IntervalRule{Unit: DAY, Num: 1}, IntervalRule{Unit: DAY, Num: 3})
+}
+
+func setUpDBWithIntervals(t testing.TB, segmentInterval, ttl IntervalRule) (*database[*MockTSTable, any], timestamp.MockClock,
+	*segmentController[*MockTSTable, any], func(),
+) {
+	dir, defFn := test.Space(require.New(t))
+	TSDBOpts := TSDBOpts[*MockTSTable, any]{
+		Location:        dir,
+		SegmentInterval: segmentInterval,
+		TTL:             ttl,
+		ShardNum:        1,
 		TSTableCreator:  MockTSTableCreator,
 	}
 	ctx := context.Background()
@@ -173,7 +482,11 @@ func setUpDB(t *testing.T) (*database[*MockTSTable, any], timestamp.MockClock, *
 	db := tsdb.(*database[*MockTSTable, any])
 	shard, ok := db.getShard(0)
 	require.True(t, ok)
-	require.Equal(t, len(shard.segmentController.segments()), 1)
+	ss := shard.segmentController.segments()
+	for i := range ss {
+		ss[i].DecRef()
+	}
+	require.Len(t, ss, 1)
 	return db, mc, shard.segmentController, func() {
 		tsdb.Close()
 		defFn()
@@ -191,3 +504,64 @@ var MockTSTableCreator = func(_ fs.FileSystem, _ string, _ common.Position,
 ) (*MockTSTable, error) {
 	return &MockTSTable{}, nil
 }
+
+type recordingCounter struct {
+	values map[string]float64
+	mu     sync.Mutex
+}
+
+func (rc *recordingCounter) Inc(delta float64, labelValues ...string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.values[strings.Join(labelValues, ",")] += delta
+}
+
+func (rc *recordingCounter) Delete(labelValues ...string) bool {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	delete(rc.values, strings.Join(labelValues, ","))
+	return true
+}
+
+func (rc *recordingCounter) value(labelValues ...string) float64 {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.values[strings.Join(labelValues, ",")]
+}
+
+func TestRetentionMetrics(t *testing.T) {
+	counters := &retentionCounters{
+		segmentsDeleted: &recordingCounter{values: make(map[string]float64)},
+		bytesReclaimed:  &recordingCounter{values: make(map[string]float64)},
+	}
+	origin := retentionMetrics
+	retentionMetrics = func() *retentionCounters { return counters }
+	defer func() { retentionMetrics = origin }()
+
+	tsdb, c, segCtrl, dfFn := setUpDB(t)
+	defer dfFn()
+	group := segCtrl.position.Database
+	start := c.Now()
+	for i := 1; i < 4; i++ {
+		tt, err := tsdb.CreateTSTableIfNotExist(0, start.AddDate(0, 0, i))
+		require.NoError(t, err)
+		tt.DecRef()
+	}
+	deleted := counters.segmentsDeleted.(*recordingCounter)
+	reclaimed := counters.bytesReclaimed.(*recordingCounter)
+
+	_, err := tsdb.DeleteExpiredSegments(start.AddDate(0, 0, 2), true)
+	require.NoError(t, err)
+	assert.Zero(t, deleted.value(group), "a dry run deletes no segment")
+
+	for _, days := range []int{2, 3} {
+		before := deleted.value(group)
+		removed, err := tsdb.DeleteExpiredSegments(start.AddDate(0, 0, days), false)
+		require.NoError(t, err)
+		assert.Equal(t, float64(len(removed)), deleted.value(group)-before)
+	}
+	assert.Equal(t, float64(3), deleted.value(group))
+	assert.Eventually(t, func() bool {
+		return reclaimed.value(group) > 0
+	}, flags.EventuallyTimeout, time.Millisecond, "wait for the reclaimed bytes to be counted")
+}
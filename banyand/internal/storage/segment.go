@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"path"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"sync"
@@ -40,12 +41,22 @@ var ErrExpiredData = errors.New("expired data")
 
 type segment[T TSTable] struct {
 	bucket.Reporter
-	tsTable  T
-	l        *logger.Logger
-	position common.Position
+	tsTable T
+	// reopen opens the table again once it's evicted by the cache.
+	reopen func() (T, error)
+	cache  *segmentCache[T]
+	// onDeleted is called with the reclaimed bytes once the segment removed by the retention is deleted from the disk.
+	onDeleted func(size int64)
+	l         *logger.Logger
+	position  common.Position
 	timestamp.TimeRange
 	path          string
 	suffix        string
+	sealedAt      int64
+	lastAccess    atomic.Int64
+	tableMu       sync.Mutex
+	opened        atomic.Bool
+	leases        atomic.Int32
 	refCount      int32
 	mustBeDeleted uint32
 	id            segmentID
@@ -69,6 +80,7 @@ func openSegment[T TSTable](ctx context.Context, startTime, endTime time.Time, p
 		tsTable:   tsTable,
 		refCount:  1,
 	}
+	s.opened.Store(true)
 	l := logger.Fetch(ctx, s.String())
 	s.l = l
 	clock, _ := timestamp.GetClock(ctx)
@@ -78,9 +90,17 @@ func openSegment[T TSTable](ctx context.Context, startTime, endTime time.Time, p
 
 func (s *segment[T]) incRef() {
 	atomic.AddInt32(&s.refCount, 1)
+	s.lease()
 }
 
+// DecRef releases a reference handed out by the controller.
 func (s *segment[T]) DecRef() {
+	s.unlease()
+	s.decRef()
+}
+
+// decRef releases a reference without a lease, such as the one held by the controller's list.
+func (s *segment[T]) decRef() {
 	n := atomic.AddInt32(&s.refCount, -1)
 	if n > 0 {
 		return
@@ -91,26 +111,112 @@ func (s *segment[T]) DecRef() {
 		deletePath = s.path
 	}
 
-	if err := s.tsTable.Close(); err != nil {
-		s.l.Panic().Err(err).Msg("failed to close tsTable")
+	s.tableMu.Lock()
+	if s.opened.Load() {
+		if err := s.tsTable.Close(); err != nil {
+			s.l.Panic().Err(err).Msg("failed to close tsTable")
+		}
+		s.opened.Store(false)
+	}
+	s.tableMu.Unlock()
+	if s.cache != nil {
+		s.cache.closed(s)
 	}
 
 	if deletePath != "" {
+		size := dirSize(deletePath)
 		lfs.MustRMAll(deletePath)
+		if s.onDeleted != nil {
+			s.onDeleted(size)
+		}
+	}
+}
+
+// lease keeps the table open until the lease is released.
+// Only the cache evicts a table, so without it a lease costs nothing.
+func (s *segment[T]) lease() {
+	if s.cache == nil {
+		return
+	}
+	s.leases.Add(1)
+	s.lastAccess.Store(time.Now().UnixNano())
+}
+
+func (s *segment[T]) unlease() {
+	if s.cache == nil {
+		return
 	}
+	s.leases.Add(-1)
 }
 
+// Table returns the table, which is reopened if it has been evicted.
+// The caller must hold a lease on the segment.
 func (s *segment[T]) Table() T {
-	return s.tsTable
+	if s.opened.Load() {
+		return s.tsTable
+	}
+	s.tableMu.Lock()
+	if s.opened.Load() {
+		s.tableMu.Unlock()
+		return s.tsTable
+	}
+	t, err := s.reopen()
+	if err != nil {
+		s.tableMu.Unlock()
+		s.l.Panic().Err(err).Msg("failed to reopen tsTable")
+	}
+	s.tsTable = t
+	s.opened.Store(true)
+	s.tableMu.Unlock()
+	s.l.Debug().Msg("reopened the evicted table")
+	s.cache.opened(s)
+	return t
 }
 
+// tryEvict closes the table if it's neither leased nor holding the data which isn't persisted yet.
+// A reader leases the segment before it loads the table, and the eviction marks the table closed before
+// it checks the leases, so either the reader waits for the eviction and reopens the table,
+// or the eviction sees the lease and gives up.
+func (s *segment[T]) tryEvict() bool {
+	s.tableMu.Lock()
+	defer s.tableMu.Unlock()
+	if !s.opened.Load() {
+		return false
+	}
+	idle, ok := any(s.tsTable).(IdleTSTable)
+	if !ok {
+		return false
+	}
+	s.opened.Store(false)
+	if s.leases.Load() > 0 || !idle.Idle() {
+		s.opened.Store(true)
+		return false
+	}
+	if err := s.tsTable.Close(); err != nil {
+		s.l.Panic().Err(err).Msg("failed to close tsTable")
+	}
+	var zero T
+	s.tsTable = zero
+	s.l.Debug().Msg("evicted the table")
+	return true
+}
+
+// GetTimeRange returns the time range accepting writes, which ends at the sealing time once the segment is sealed.
 func (s *segment[T]) GetTimeRange() timestamp.TimeRange {
+	if sealedAt := atomic.LoadInt64(&s.sealedAt); sealedAt > 0 {
+		return timestamp.NewSectionTimeRange(s.Start, time.Unix(0, sealedAt))
+	}
 	return s.TimeRange
 }
 
+// seal stops the segment from accepting the writes later than end.
+func (s *segment[T]) seal(end time.Time) {
+	atomic.StoreInt64(&s.sealedAt, end.UnixNano())
+}
+
+// delete marks the segment to be deleted once it's released.
 func (s *segment[T]) delete() {
 	atomic.StoreUint32(&s.mustBeDeleted, 1)
-	s.DecRef()
 }
 
 func (s *segment[T]) String() string {
@@ -125,7 +231,10 @@ type segmentController[T TSTable, O any] struct {
 	tsTableCreator TSTableCreator[T, O]
 	position       common.Position
 	location       string
+	snapshot       atomic.Pointer[segmentSnapshot[T]]
 	lst            []*segment[T]
+	cache          *segmentCache[T]
+	deleted        *segmentDeletedListeners
 	segmentSize    IntervalRule
 	deadline       atomic.Int64
 	sync.RWMutex
@@ -133,7 +242,7 @@ type segmentController[T TSTable, O any] struct {
 
 func newSegmentController[T TSTable, O any](ctx context.Context, location string,
 	segmentSize IntervalRule, l *logger.Logger, scheduler *timestamp.Scheduler,
-	tsTableCreator TSTableCreator[T, O], option O,
+	tsTableCreator TSTableCreator[T, O], option O, cache *segmentCache[T], deleted *segmentDeletedListeners,
 ) *segmentController[T, O] {
 	clock, _ := timestamp.GetClock(ctx)
 	return &segmentController[T, O]{
@@ -145,23 +254,52 @@ func newSegmentController[T TSTable, O any](ctx context.Context, location string
 		position:       common.GetPosition(ctx),
 		tsTableCreator: tsTableCreator,
 		option:         option,
+		cache:          cache,
+		deleted:        deleted,
 	}
 }
 
+// selectTSTables returns the tables overlapping timeRange. They share a reference on the snapshot
+// of the active segments, so that the readers don't contend on the controller's lock or the segments' references.
 func (sc *segmentController[T, O]) selectTSTables(timeRange timestamp.TimeRange) (tt []TSTableWrapper[T]) {
-	sc.RLock()
-	defer sc.RUnlock()
-	last := len(sc.lst) - 1
-	for i := range sc.lst {
-		s := sc.lst[last-i]
-		if s.Overlapping(timeRange) {
-			s.incRef()
-			tt = append(tt, s)
+	snapshot := sc.acquireSnapshot()
+	if snapshot == nil {
+		return nil
+	}
+	last := len(snapshot.tables) - 1
+	for i := range snapshot.tables {
+		t := &snapshot.tables[last-i]
+		if t.Overlapping(timeRange) {
+			t.lease()
+			tt = append(tt, t)
 		}
 	}
+	if len(tt) == 0 {
+		snapshot.release()
+		return nil
+	}
+	snapshot.refCount.Add(int32(len(tt) - 1))
 	return tt
 }
 
+// segmentContaining returns the segment containing ts with its reference increased, or nil if there isn't one.
+func (sc *segmentController[T, O]) segmentContaining(ts time.Time) *segment[T] {
+	snapshot := sc.acquireSnapshot()
+	if snapshot == nil {
+		return nil
+	}
+	defer snapshot.release()
+	last := len(snapshot.tables) - 1
+	for i := range snapshot.tables {
+		s := snapshot.tables[last-i].segment
+		if s.Contains(ts.UnixNano()) {
+			s.incRef()
+			return s
+		}
+	}
+	return nil
+}
+
 func (sc *segmentController[T, O]) createTSTable(ts time.Time) (TSTableWrapper[T], error) {
 	// Before the first remove old segment run, any segment should be created.
 	if sc.deadline.Load() > ts.UnixNano() {
@@ -171,22 +309,27 @@ func (sc *segmentController[T, O]) createTSTable(ts time.Time) (TSTableWrapper[T
 	if err != nil {
 		return nil, err
 	}
-	s.incRef()
 	return s, nil
 }
 
 func (sc *segmentController[T, O]) segments() (ss []*segment[T]) {
-	sc.RLock()
-	defer sc.RUnlock()
-	r := make([]*segment[T], len(sc.lst))
-	for i := range sc.lst {
-		sc.lst[i].incRef()
-		r[i] = sc.lst[i]
+	snapshot := sc.acquireSnapshot()
+	if snapshot == nil {
+		return []*segment[T]{}
+	}
+	defer snapshot.release()
+	r := make([]*segment[T], len(snapshot.tables))
+	for i := range snapshot.tables {
+		snapshot.tables[i].incRef()
+		r[i] = snapshot.tables[i].segment
 	}
 	return r
 }
 
 func (sc *segmentController[T, O]) Format(tm time.Time) string {
+	if !sc.segmentSize.Unit.standard(tm).Equal(tm) {
+		return tm.Format(rotatedFormat)
+	}
 	switch sc.segmentSize.Unit {
 	case HOUR:
 		return tm.Format(hourFormat)
@@ -197,6 +340,9 @@ func (sc *segmentController[T, O]) Format(tm time.Time) string {
 }
 
 func (sc *segmentController[T, O]) Parse(value string) (time.Time, error) {
+	if len(value) == len(rotatedFormat) {
+		return time.ParseInLocation(rotatedFormat, value, time.Local)
+	}
 	switch sc.segmentSize.Unit {
 	case HOUR:
 		return time.ParseInLocation(hourFormat, value, time.Local)
@@ -220,16 +366,24 @@ func (sc *segmentController[T, O]) open() error {
 		if err != nil {
 			return err
 		}
-		for _, cv := range compatibleVersions[compatibleVersionsKey] {
-			if string(version) == cv {
-				_, err := sc.load(start, end, sc.location)
+		if !slices.Contains(compatibleVersions[compatibleVersionsKey], string(version)) {
+			return fmt.Errorf("%w: segment %s is written by version %s", errVersionIncompatible, suffix, version)
+		}
+		// The parts written from now on take the current format, which the older versions can't read,
+		// so the segment is marked with the current version to keep them from opening it.
+		if string(version) != currentVersion {
+			if _, err = lfs.Write([]byte(currentVersion), metadataPath, filePermission); err != nil {
 				return err
 			}
 		}
-		return errVersionIncompatible
+		_, err = sc.load(start, end, sc.location)
+		return err
 	})
 }
 
+// create returns the segment covering start with its reference increased.
+// Concurrent callers are serialized by the controller's lock, so that only the first one
+// creates the segment directory and the others get the same segment.
 func (sc *segmentController[T, O]) create(start time.Time) (*segment[T], error) {
 	sc.Lock()
 	defer sc.Unlock()
@@ -241,17 +395,54 @@ func (sc *segmentController[T, O]) create(start time.Time) (*segment[T], error)
 			return s, nil
 		}
 	}
-	start = sc.segmentSize.Unit.standard(start)
-	var next *segment[T]
+	start = sc.segmentSize.standard(start)
 	for _, s := range sc.lst {
 		if s.Contains(start.UnixNano()) {
+			s.incRef()
 			return s, nil
 		}
-		if next == nil && s.Start.After(start) {
+	}
+	return sc.createLocked(start)
+}
+
+// rotate seals the segment containing now and creates a new one starting from now.
+// The sealed segment keeps its data, but the new one shadows it for the writes from now on.
+func (sc *segmentController[T, O]) rotate(now time.Time) (*segment[T], error) {
+	// The name of a segment keeps its start time to the second.
+	start := now.Truncate(time.Second)
+	sc.Lock()
+	defer sc.Unlock()
+	last := len(sc.lst) - 1
+	for i := range sc.lst {
+		s := sc.lst[last-i]
+		if !s.Contains(start.UnixNano()) {
+			continue
+		}
+		if s.Start.Equal(start) {
+			s.incRef()
+			return s, nil
+		}
+		seg, err := sc.createLocked(start)
+		if err != nil {
+			return nil, err
+		}
+		s.seal(start)
+		return seg, nil
+	}
+	return sc.createLocked(sc.segmentSize.standard(start))
+}
+
+func (sc *segmentController[T, O]) createLocked(start time.Time) (*segment[T], error) {
+	var next *segment[T]
+	for _, s := range sc.lst {
+		if s.Start.After(start) {
 			next = s
+			break
 		}
 	}
-	stdEnd := sc.segmentSize.nextTime(start)
+	// A rotated segment starts between two interval boundaries, and ends at the next boundary
+	// as the segment it's rotated from.
+	stdEnd := sc.segmentSize.nextTime(sc.segmentSize.standard(start))
 	var end time.Time
 	if next != nil && next.Start.Before(stdEnd) {
 		end = next.Start
@@ -273,12 +464,17 @@ func (sc *segmentController[T, O]) create(start time.Time) (*segment[T], error)
 	if n != len(data) {
 		logger.Panicf("unexpected number of bytes written to %s; got %d; want %d", metadataPath, n, len(data))
 	}
-	return sc.load(start, end, sc.location)
+	s, err := sc.load(start, end, sc.location)
+	if err != nil {
+		return nil, err
+	}
+	s.incRef()
+	return s, nil
 }
 
 func (sc *segmentController[T, O]) sortLst() {
 	sort.Slice(sc.lst, func(i, j int) bool {
-		return sc.lst[i].id < sc.lst[j].id
+		return sc.lst[i].Start.Before(sc.lst[j].Start)
 	})
 }
 
@@ -295,23 +491,50 @@ func (sc *segmentController[T, O]) load(start, end time.Time, root string) (seg
 	if err != nil {
 		return nil, err
 	}
+	seg.reopen = func() (T, error) {
+		return sc.tsTableCreator(lfs, segPath, p, sc.l, timestamp.NewSectionTimeRange(start, end), sc.option)
+	}
+	if sc.cache != nil {
+		seg.cache = sc.cache
+		seg.lastAccess.Store(time.Now().UnixNano())
+		sc.cache.opened(seg)
+	}
 	sc.lst = append(sc.lst, seg)
 	sc.sortLst()
+	sc.publishLocked()
 	return seg, nil
 }
 
-func (sc *segmentController[T, O]) remove(deadline time.Time) (err error) {
+// remove deletes the segments ending before the deadline, and returns their time ranges.
+// With dryRun, the segments are only listed.
+func (sc *segmentController[T, O]) remove(deadline time.Time, dryRun bool) (removed []timestamp.TimeRange, err error) {
 	for _, s := range sc.segments() {
 		if s.Before(deadline) {
-			s.delete()
-			sc.Lock()
-			sc.removeSeg(s.id)
-			sc.Unlock()
-			sc.l.Info().Stringer("segment", s).Msg("removed a segment")
+			removed = append(removed, s.TimeRange)
+			if !dryRun {
+				sc.deleteSegment(s)
+				sc.l.Info().Stringer("segment", s).Msg("removed a segment")
+			}
 		}
 		s.DecRef()
 	}
-	return err
+	return removed, err
+}
+
+// deleteSegment removes the segment from the controller. Its directory is deleted once it's released.
+func (sc *segmentController[T, O]) deleteSegment(s *segment[T]) {
+	group, tr := sc.position.Database, s.TimeRange
+	s.onDeleted = func(size int64) {
+		retentionMetrics().bytesReclaimed.Inc(float64(size), group)
+		if sc.deleted != nil {
+			sc.deleted.notify(group, tr)
+		}
+	}
+	retentionMetrics().segmentsDeleted.Inc(1, group)
+	s.delete()
+	sc.Lock()
+	sc.removeSeg(s.id)
+	sc.Unlock()
 }
 
 func (sc *segmentController[T, O]) removeSeg(segID segmentID) {
@@ -319,6 +542,7 @@ func (sc *segmentController[T, O]) removeSeg(segID segmentID) {
 		if b.id == segID {
 			sc.lst = append(sc.lst[:i], sc.lst[i+1:]...)
 			sc.deadline.Store(sc.lst[0].Start.UnixNano())
+			sc.publishLocked(b)
 			break
 		}
 	}
@@ -327,10 +551,29 @@ func (sc *segmentController[T, O]) removeSeg(segID segmentID) {
 func (sc *segmentController[T, O]) close() {
 	sc.Lock()
 	defer sc.Unlock()
-	for _, s := range sc.lst {
-		s.DecRef()
+	retired := sc.lst
+	sc.lst = nil
+	sc.publishLocked(retired...)
+}
+
+// segmentDeletedListeners are the callbacks registered by OnSegmentDeleted, shared by the shards of a database.
+type segmentDeletedListeners struct {
+	fns []SegmentDeletedFunc
+	mu  sync.RWMutex
+}
+
+func (l *segmentDeletedListeners) add(fn SegmentDeletedFunc) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.fns = append(l.fns, fn)
+}
+
+func (l *segmentDeletedListeners) notify(group string, timeRange timestamp.TimeRange) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for _, fn := range l.fns {
+		fn(group, timeRange)
 	}
-	sc.lst = sc.lst[:0]
 }
 
 type parser interface {
@@ -352,7 +595,7 @@ func loadSegments(root, prefix string, parser parser, intervalRule IntervalRule,
 		}); err != nil {
 		return err
 	}
-	sort.Slice(startTimeLst, func(i, j int) bool { return i < j })
+	sort.Slice(startTimeLst, func(i, j int) bool { return startTimeLst[i].Before(startTimeLst[j]) })
 	for i, start := range startTimeLst {
 		var end time.Time
 		if i < len(startTimeLst)-1 {
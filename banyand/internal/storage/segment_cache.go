@@ -0,0 +1,86 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package storage
+
+import (
+	"sync"
+)
+
+// SegmentStats counts the segments of a group.
+type SegmentStats struct {
+	// Open is the number of segments whose tables are open.
+	Open int
+	// Total is the number of segments, including the evicted ones.
+	Total int
+}
+
+// segmentCache bounds the number of open segments of a group, which are shared by all its shards.
+// Once the bound is exceeded, the tables of the least recently accessed segments are closed.
+// They're reopened on the next access.
+//
+// A segment being accessed or holding the data which isn't persisted yet is never evicted,
+// so the number of open segments may exceed the bound for a while.
+type segmentCache[T TSTable] struct {
+	open map[*segment[T]]struct{}
+	max  int
+	mu   sync.Mutex
+}
+
+func newSegmentCache[T TSTable](maxOpen int) *segmentCache[T] {
+	if maxOpen <= 0 {
+		return nil
+	}
+	return &segmentCache[T]{
+		open: make(map[*segment[T]]struct{}),
+		max:  maxOpen,
+	}
+}
+
+// opened tracks a segment whose table has just been opened, and evicts the others if the bound is exceeded.
+func (sc *segmentCache[T]) opened(s *segment[T]) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.open[s] = struct{}{}
+	skipped := map[*segment[T]]struct{}{s: {}}
+	for len(sc.open) > sc.max {
+		var victim *segment[T]
+		for candidate := range sc.open {
+			if _, ok := skipped[candidate]; ok || candidate.leases.Load() > 0 {
+				continue
+			}
+			if victim == nil || candidate.lastAccess.Load() < victim.lastAccess.Load() {
+				victim = candidate
+			}
+		}
+		if victim == nil {
+			return
+		}
+		if victim.tryEvict() {
+			delete(sc.open, victim)
+			continue
+		}
+		skipped[victim] = struct{}{}
+	}
+}
+
+// closed stops tracking a segment whose table has been closed for good.
+func (sc *segmentCache[T]) closed(s *segment[T]) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	delete(sc.open, s)
+}
@@ -0,0 +1,112 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package storage
+
+import (
+	"sync/atomic"
+)
+
+// segmentSnapshot is an immutable list of the active segments.
+// Readers share a snapshot through a single reference on it instead of bumping the reference of every segment.
+//
+// The controller replaces the snapshot whenever the list changes. The segments that a change removes are
+// released only when the replaced snapshot drains. A snapshot can't drain before its predecessor does,
+// so no reader can still see a released segment through an older snapshot.
+type segmentSnapshot[T TSTable] struct {
+	// next is the successor. It holds a reference until this snapshot drains.
+	next *segmentSnapshot[T]
+	// retired are the segments removed by the successor. They're released when this snapshot drains.
+	retired  []*segment[T]
+	tables   []snapshotTable[T]
+	refCount atomic.Int32
+}
+
+func newSegmentSnapshot[T TSTable](lst []*segment[T]) *segmentSnapshot[T] {
+	s := &segmentSnapshot[T]{
+		tables: make([]snapshotTable[T], len(lst)),
+	}
+	for i := range lst {
+		s.tables[i] = snapshotTable[T]{segment: lst[i], snapshot: s}
+	}
+	// The reference held by the controller until the snapshot is replaced.
+	s.refCount.Store(1)
+	return s
+}
+
+// tryAcquire fails if the snapshot has already drained.
+func (s *segmentSnapshot[T]) tryAcquire() bool {
+	for {
+		n := s.refCount.Load()
+		if n <= 0 {
+			return false
+		}
+		if s.refCount.CompareAndSwap(n, n+1) {
+			return true
+		}
+	}
+}
+
+func (s *segmentSnapshot[T]) release() {
+	for s != nil && s.refCount.Add(-1) == 0 {
+		for _, seg := range s.retired {
+			seg.decRef()
+		}
+		s = s.next
+	}
+}
+
+// snapshotTable is a segment seen through a snapshot. Releasing it releases the snapshot instead of the segment,
+// along with the lease on the segment.
+type snapshotTable[T TSTable] struct {
+	*segment[T]
+	snapshot *segmentSnapshot[T]
+}
+
+func (st *snapshotTable[T]) DecRef() {
+	st.unlease()
+	st.snapshot.release()
+}
+
+func (sc *segmentController[T, O]) acquireSnapshot() *segmentSnapshot[T] {
+	for {
+		s := sc.snapshot.Load()
+		if s == nil {
+			return nil
+		}
+		// A drained snapshot has already been replaced, so the next load gets its successor.
+		if s.tryAcquire() {
+			return s
+		}
+	}
+}
+
+// publishLocked replaces the snapshot with the current list. It must be called with the controller's lock held.
+func (sc *segmentController[T, O]) publishLocked(retired ...*segment[T]) {
+	s := newSegmentSnapshot(sc.lst)
+	prev := sc.snapshot.Swap(s)
+	if prev == nil {
+		for _, seg := range retired {
+			seg.decRef()
+		}
+		return
+	}
+	s.refCount.Add(1)
+	prev.next = s
+	prev.retired = retired
+	prev.release()
+}
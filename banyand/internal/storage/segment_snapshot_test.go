@@ -0,0 +1,102 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package storage
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+func TestSnapshotDefersSegmentRelease(t *testing.T) {
+	tsdb, c, segCtrl, dfFn := setUpDB(t)
+	defer dfFn()
+	now := c.Now()
+	tt := segCtrl.selectTSTables(timestamp.NewInclusiveTimeRange(now, now))
+	require.Len(t, tt, 1)
+	seg := tt[0].(*snapshotTable[*MockTSTable]).segment
+	refCount := atomic.LoadInt32(&seg.refCount)
+
+	next, err := tsdb.CreateTSTableIfNotExist(0, now.Add(24*time.Hour))
+	require.NoError(t, err)
+	next.DecRef()
+	_, err = segCtrl.remove(now.Add(24*time.Hour), false)
+	require.NoError(t, err)
+	ss := segCtrl.segments()
+	require.Len(t, ss, 1)
+	assert.NotEqual(t, seg.id, ss[0].id)
+	ss[0].DecRef()
+
+	// The reader still sees the removed segment through its snapshot.
+	assert.Equal(t, refCount, atomic.LoadInt32(&seg.refCount))
+	tt[0].DecRef()
+	assert.Equal(t, refCount-1, atomic.LoadInt32(&seg.refCount))
+}
+
+// lockedSelectSegments is how the segments were selected before the snapshot. It's the benchmark's baseline.
+func lockedSelectSegments(sc *segmentController[*MockTSTable, any], timeRange timestamp.TimeRange) (ss []*segment[*MockTSTable]) {
+	sc.RLock()
+	defer sc.RUnlock()
+	last := len(sc.lst) - 1
+	for i := range sc.lst {
+		s := sc.lst[last-i]
+		if s.Overlapping(timeRange) {
+			s.incRef()
+			ss = append(ss, s)
+		}
+	}
+	return ss
+}
+
+func BenchmarkSelectTSTables(b *testing.B) {
+	tsdb, c, segCtrl, dfFn := setUpDB(b)
+	defer dfFn()
+	now := c.Now()
+	for i := 1; i < 3; i++ {
+		tt, err := tsdb.CreateTSTableIfNotExist(0, now.Add(time.Duration(i)*24*time.Hour))
+		require.NoError(b, err)
+		tt.DecRef()
+	}
+	timeRange := timestamp.NewInclusiveTimeRange(now, now.Add(72*time.Hour))
+
+	b.Run("locked", func(b *testing.B) {
+		b.SetParallelism(16)
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				for _, s := range lockedSelectSegments(segCtrl, timeRange) {
+					s.DecRef()
+				}
+			}
+		})
+	})
+	b.Run("snapshot", func(b *testing.B) {
+		b.SetParallelism(16)
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				for _, t := range segCtrl.selectTSTables(timeRange) {
+					t.DecRef()
+				}
+			}
+		})
+	})
+}
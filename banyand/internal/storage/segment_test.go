@@ -0,0 +1,247 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/pkg/fs"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/test"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+func TestCreateTSTableIfNotExistConcurrently(t *testing.T) {
+	tsdb, c, segCtrl, dfFn := setUpDB(t)
+	defer dfFn()
+	ts := c.Now().Add(24 * time.Hour)
+
+	const workers = 100
+	var wg sync.WaitGroup
+	tables := make([]TSTableWrapper[*MockTSTable], workers)
+	errs := make([]error, workers)
+	start := make(chan struct{})
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			tables[i], errs[i] = tsdb.CreateTSTableIfNotExist(0, ts.Add(time.Duration(i)*time.Minute))
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	for i := 0; i < workers; i++ {
+		require.NoError(t, errs[i])
+		require.Same(t, tables[0], tables[i])
+	}
+	seg := tables[0].(*segment[*MockTSTable])
+	assert.Equal(t, int32(workers+1), atomic.LoadInt32(&seg.refCount))
+	assert.Zero(t, seg.leases.Load(), "the segments aren't leased without the cache")
+	for i := range tables {
+		tables[i].DecRef()
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&seg.refCount))
+
+	ss := segCtrl.segments()
+	defer func() {
+		for i := range ss {
+			ss[i].DecRef()
+		}
+	}()
+	require.Len(t, ss, 2)
+	entries, err := os.ReadDir(segCtrl.location)
+	require.NoError(t, err)
+	var segDirs int
+	for _, e := range entries {
+		if e.IsDir() && strings.HasPrefix(e.Name(), segPathPrefix) {
+			segDirs++
+		}
+	}
+	assert.Equal(t, 2, segDirs)
+}
+
+type idleTSTable struct {
+	root   string
+	closed atomic.Bool
+}
+
+func (t *idleTSTable) Close() error {
+	t.closed.Store(true)
+	return nil
+}
+
+func (t *idleTSTable) Idle() bool {
+	return true
+}
+
+func TestMaxOpenSegments(t *testing.T) {
+	const maxOpen = 3
+	const total = 10
+	dir, defFn := test.Space(require.New(t))
+	defer defFn()
+	ctx := context.Background()
+	mc := timestamp.NewMockClock()
+	ts, err := time.ParseInLocation("2006-01-02 15:04:05", "2024-05-01 00:00:00", time.Local)
+	require.NoError(t, err)
+	mc.Set(ts)
+	ctx = timestamp.SetClock(ctx, mc)
+	tsdb, err := OpenTSDB(ctx, TSDBOpts[*idleTSTable, any]{
+		Location:        dir,
+		SegmentInterval: IntervalRule{Unit: DAY, Num: 1},
+		TTL:             IntervalRule{Unit: DAY, Num: 30},
+		ShardNum:        1,
+		MaxOpenSegments: maxOpen,
+		TSTableCreator: func(_ fs.FileSystem, root string, _ common.Position,
+			_ *logger.Logger, _ timestamp.TimeRange, _ any,
+		) (*idleTSTable, error) {
+			return &idleTSTable{root: root}, nil
+		},
+	})
+	require.NoError(t, err)
+	defer tsdb.Close()
+
+	day := func(i int) time.Time {
+		return ts.AddDate(0, 0, i)
+	}
+	for i := 0; i < total; i++ {
+		tw, errCreate := tsdb.CreateTSTableIfNotExist(0, day(i))
+		require.NoError(t, errCreate)
+		tw.DecRef()
+		assert.LessOrEqual(t, tsdb.SegmentStats().Open, maxOpen)
+	}
+	assert.Equal(t, SegmentStats{Open: maxOpen, Total: total}, tsdb.SegmentStats())
+
+	db := tsdb.(*database[*idleTSTable, any])
+	shard, ok := db.getShard(0)
+	require.True(t, ok)
+	sc := shard.segmentController
+	// A leased segment stays open while the others are accessed.
+	pinned := sc.selectTSTables(timestamp.NewInclusiveTimeRange(day(0), day(0)))
+	require.Len(t, pinned, 1)
+	pinnedTable := pinned[0].Table()
+	for round := 0; round < 2; round++ {
+		for i := 0; i < total; i++ {
+			tt := sc.selectTSTables(timestamp.NewInclusiveTimeRange(day(i), day(i)))
+			require.Len(t, tt, 1)
+			table := tt[0].Table()
+			assert.False(t, table.closed.Load(), "segment %d is queryable", i)
+			assert.Equal(t, path.Join(sc.location, fmt.Sprintf(segTemplate, sc.Format(day(i)))), table.root)
+			tt[0].DecRef()
+			assert.LessOrEqual(t, tsdb.SegmentStats().Open, maxOpen)
+		}
+	}
+	assert.False(t, pinnedTable.closed.Load(), "the leased segment isn't evicted")
+	pinned[0].DecRef()
+	assert.Equal(t, total, tsdb.SegmentStats().Total)
+}
+
+func TestSegmentVersion(t *testing.T) {
+	dir, defFn := test.Space(require.New(t))
+	defer defFn()
+	ctx := context.Background()
+	mc := timestamp.NewMockClock()
+	ts, err := time.ParseInLocation("2006-01-02 15:04:05", "2024-05-01 00:00:00", time.Local)
+	require.NoError(t, err)
+	mc.Set(ts)
+	ctx = timestamp.SetClock(ctx, mc)
+	open := func() (TSDB[*idleTSTable, any], error) {
+		return OpenTSDB(ctx, TSDBOpts[*idleTSTable, any]{
+			Location:        dir,
+			SegmentInterval: IntervalRule{Unit: DAY, Num: 1},
+			TTL:             IntervalRule{Unit: DAY, Num: 30},
+			ShardNum:        1,
+			TSTableCreator: func(_ fs.FileSystem, root string, _ common.Position,
+				_ *logger.Logger, _ timestamp.TimeRange, _ any,
+			) (*idleTSTable, error) {
+				return &idleTSTable{root: root}, nil
+			},
+		})
+	}
+	tsdb, err := open()
+	require.NoError(t, err)
+	tw, err := tsdb.CreateTSTableIfNotExist(0, ts)
+	require.NoError(t, err)
+	metadataPath := path.Join(tw.Table().root, metadataFilename)
+	tw.DecRef()
+	require.NoError(t, tsdb.Close())
+	version := func() string {
+		data, errRead := os.ReadFile(metadataPath)
+		require.NoError(t, errRead)
+		return string(data)
+	}
+	assert.Equal(t, currentVersion, version())
+
+	// A segment written by an older compatible version is marked with the current one once it's opened,
+	// as the parts written into it from now on can't be read by the older version.
+	require.NoError(t, os.WriteFile(metadataPath, []byte("1.0.0"), 0o600))
+	tsdb, err = open()
+	require.NoError(t, err)
+	require.NoError(t, tsdb.Close())
+	assert.Equal(t, currentVersion, version())
+
+	// The reader of an older version refuses a segment written by a newer one.
+	require.NoError(t, os.WriteFile(metadataPath, []byte("9.9.9"), 0o600))
+	_, err = open()
+	assert.ErrorContains(t, err, errVersionIncompatible.Error())
+}
+
+func TestSegmentID(t *testing.T) {
+	tests := []struct {
+		name   string
+		suffix string
+		unit   IntervalUnit
+	}{
+		{name: "hourly segment named by an older version", suffix: "2024050115", unit: HOUR},
+		{name: "daily segment named by an older version", suffix: "20240501", unit: DAY},
+		{name: "hourly segment created by a forced rotation", suffix: "20240501153045", unit: HOUR},
+		{name: "daily segment created by a forced rotation", suffix: "20240501153045", unit: DAY},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := &segmentController[*MockTSTable, any]{segmentSize: IntervalRule{Unit: tt.unit, Num: 1}}
+			start, err := sc.Parse(tt.suffix)
+			require.NoError(t, err)
+			assert.Equal(t, tt.suffix, sc.Format(start))
+			suffix, err := strconv.Atoi(tt.suffix)
+			require.NoError(t, err)
+			// The unit tags the highest bit, and the rest keeps the suffix, which is wider than 31 bits once rotated.
+			id := generateSegID(tt.unit, suffix)
+			assert.Equal(t, tt.unit, IntervalUnit(id>>63))
+			assert.Equal(t, segmentID(suffix), id&^(1<<63))
+		})
+	}
+	// The IDs of the segments named by an older version keep their order, and don't collide across the units.
+	assert.Less(t, generateSegID(HOUR, 2024050115), generateSegID(HOUR, 2024050116))
+	assert.Less(t, generateSegID(DAY, 20240501), generateSegID(DAY, 20240502))
+	assert.NotEqual(t, generateSegID(HOUR, 20240501), generateSegID(DAY, 20240501))
+}
@@ -0,0 +1,420 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+	"github.com/pkg/errors"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/banyand/observability"
+	"github.com/apache/skywalking-banyandb/pkg/index"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/meter"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/schema"
+)
+
+const (
+	// DefaultSeriesCacheSize is the default number of series lists cached by a series index.
+	DefaultSeriesCacheSize = 1024
+	// DefaultSeriesCacheTTL is the default time a cached series list lives.
+	DefaultSeriesCacheTTL = 5 * time.Minute
+)
+
+// SeriesCacheStats shows how the series list cache performs.
+type SeriesCacheStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// HitRatio returns the ratio of lookups served by the cache.
+// It returns 0 if there is no lookup.
+func (s SeriesCacheStats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+func (s *SeriesCacheStats) add(other SeriesCacheStats) {
+	s.Hits += other.Hits
+	s.Misses += other.Misses
+}
+
+// SeriesCacheEntry describes a series list held by the cache.
+type SeriesCacheEntry struct {
+	CreatedAt  time.Time
+	LastAccess time.Time
+	SeriesIDs  []common.SeriesID
+	// SizeBytes estimates the memory the entry holds.
+	SizeBytes int
+}
+
+type seriesListEntry struct {
+	createdAt  time.Time
+	lastAccess time.Time
+	ids        map[common.SeriesID]struct{}
+	list       pbv1.SeriesList
+	matchers   []index.SeriesMatcher
+	size       int
+}
+
+// seriesListCache caches the series resolved by a set of series matchers.
+// An entry is dropped once a newly written series matches it.
+// The entries are indexed by their series and their matchers, so that a written series only reaches
+// the entries it might be added to, and an evicted series only the entries holding it.
+type seriesListCache struct {
+	lru simplelru.LRUCache[string, *seriesListEntry]
+	// bySeries maps a cached series to the keys of the entries holding it.
+	bySeries map[common.SeriesID]keySet
+	// byExact maps the entity values of an exact matcher to the keys of the entries having it.
+	byExact map[string]keySet
+	// byPrefix maps the prefix of a prefix matcher to the keys of the entries having it.
+	byPrefix map[string]keySet
+	// prefixLens counts the prefix matchers by their lengths.
+	prefixLens map[int]int
+	// wildcards are the keys of the entries having a wildcard matcher, which any new series may match.
+	wildcards  keySet
+	ttl        time.Duration
+	size       int
+	hits       uint64
+	misses     uint64
+	generation uint64
+	mu         sync.Mutex
+}
+
+type keySet map[string]struct{}
+
+func (ks keySet) add(key string) keySet {
+	if ks == nil {
+		ks = make(keySet)
+	}
+	ks[key] = struct{}{}
+	return ks
+}
+
+func newSeriesListCache(size int, ttl time.Duration, policy CachePolicy) *seriesListCache {
+	if size <= 0 {
+		return nil
+	}
+	lru, err := newPolicyCache[string, *seriesListEntry](policy, size)
+	if err != nil {
+		logger.Panicf("cannot create the series list cache: %v", err)
+	}
+	return &seriesListCache{
+		lru:        lru,
+		bySeries:   make(map[common.SeriesID]keySet),
+		byExact:    make(map[string]keySet),
+		byPrefix:   make(map[string]keySet),
+		prefixLens: make(map[int]int),
+		wildcards:  make(keySet),
+		ttl:        ttl,
+		size:       size,
+	}
+}
+
+func seriesListCacheKey(matchers []index.SeriesMatcher) string {
+	var buf []byte
+	for i := range matchers {
+		buf = append(buf, byte(matchers[i].Type))
+		buf = binary.AppendUvarint(buf, uint64(len(matchers[i].Match)))
+		buf = append(buf, matchers[i].Match...)
+	}
+	return string(buf)
+}
+
+// get returns a copy of the cached series list and the generation to pass to put on a miss.
+func (c *seriesListCache) get(key string) (pbv1.SeriesList, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.lru.Get(key)
+	if ok && c.ttl > 0 && time.Since(e.createdAt) > c.ttl {
+		c.remove(key, e)
+		ok = false
+	}
+	if !ok {
+		c.misses++
+		return nil, c.generation, false
+	}
+	c.hits++
+	e.lastAccess = time.Now()
+	list := make(pbv1.SeriesList, len(e.list))
+	copy(list, e.list)
+	return list, 0, true
+}
+
+// put caches the series list unless series were written after the lookup started.
+func (c *seriesListCache) put(key string, generation uint64, matchers []index.SeriesMatcher, list pbv1.SeriesList) {
+	now := time.Now()
+	e := &seriesListEntry{
+		createdAt:  now,
+		lastAccess: now,
+		ids:        make(map[common.SeriesID]struct{}, len(list)),
+		list:       make(pbv1.SeriesList, len(list)),
+		matchers:   make([]index.SeriesMatcher, len(matchers)),
+		size:       len(key),
+	}
+	copy(e.list, list)
+	for i := range list {
+		e.ids[list[i].ID] = struct{}{}
+		e.size += 2*8 + len(list[i].Subject) + len(list[i].Buffer)
+	}
+	for i := range matchers {
+		e.matchers[i] = index.SeriesMatcher{
+			Type:  matchers[i].Type,
+			Match: bytes.Clone(matchers[i].Match),
+		}
+		e.size += 1 + len(matchers[i].Match)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return
+	}
+	if old, ok := c.lru.Peek(key); ok {
+		c.remove(key, old)
+	} else if c.lru.Len() >= c.size {
+		// The entry the cache would evict to admit this one is removed here, so that it's unindexed too.
+		if k, v, ok := c.lru.RemoveOldest(); ok {
+			c.unindex(k, v)
+		}
+	}
+	c.lru.Add(key, e)
+	c.index(key, e)
+}
+
+func (c *seriesListCache) index(key string, e *seriesListEntry) {
+	for id := range e.ids {
+		c.bySeries[id] = c.bySeries[id].add(key)
+	}
+	for _, m := range e.matchers {
+		switch m.Type {
+		case index.SeriesMatcherTypeExact:
+			c.byExact[string(m.Match)] = c.byExact[string(m.Match)].add(key)
+		case index.SeriesMatcherTypePrefix:
+			if _, ok := c.byPrefix[string(m.Match)][key]; !ok {
+				c.prefixLens[len(m.Match)]++
+			}
+			c.byPrefix[string(m.Match)] = c.byPrefix[string(m.Match)].add(key)
+		default:
+			c.wildcards.add(key)
+		}
+	}
+}
+
+func (c *seriesListCache) unindex(key string, e *seriesListEntry) {
+	for id := range e.ids {
+		deleteKey(c.bySeries, id, key)
+	}
+	for _, m := range e.matchers {
+		switch m.Type {
+		case index.SeriesMatcherTypeExact:
+			deleteKey(c.byExact, string(m.Match), key)
+		case index.SeriesMatcherTypePrefix:
+			if _, ok := c.byPrefix[string(m.Match)][key]; !ok {
+				continue
+			}
+			deleteKey(c.byPrefix, string(m.Match), key)
+			if c.prefixLens[len(m.Match)]--; c.prefixLens[len(m.Match)] == 0 {
+				delete(c.prefixLens, len(m.Match))
+			}
+		default:
+			delete(c.wildcards, key)
+		}
+	}
+}
+
+func deleteKey[K comparable](m map[K]keySet, k K, key string) {
+	ks, ok := m[k]
+	if !ok {
+		return
+	}
+	delete(ks, key)
+	if len(ks) == 0 {
+		delete(m, k)
+	}
+}
+
+func (c *seriesListCache) remove(key string, e *seriesListEntry) {
+	c.lru.Remove(key)
+	c.unindex(key, e)
+}
+
+// invalidate drops the entries which the written series would be added to.
+// Only the entries whose matchers might match a series are checked for it.
+func (c *seriesListCache) invalidate(docs index.Documents) {
+	if len(docs) == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	for i := range docs {
+		id := common.SeriesID(docs[i].DocID)
+		entityValues := docs[i].EntityValues
+		c.invalidateNew(c.byExact[string(entityValues)], id)
+		for n := range c.prefixLens {
+			if n <= len(entityValues) {
+				c.invalidateNew(c.byPrefix[string(entityValues[:n])], id)
+			}
+		}
+		c.invalidateNew(c.wildcards, id)
+	}
+}
+
+// invalidateNew drops the entries of the keys which don't hold the series yet.
+func (c *seriesListCache) invalidateNew(keys keySet, id common.SeriesID) {
+	for key := range keys {
+		e, ok := c.lru.Peek(key)
+		if !ok {
+			continue
+		}
+		if _, ok := e.ids[id]; !ok {
+			c.remove(key, e)
+		}
+	}
+}
+
+// entries lists the cached series lists in the order they would be evicted.
+func (c *seriesListCache) entries() []SeriesCacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result := make([]SeriesCacheEntry, 0, c.lru.Len())
+	for _, key := range c.lru.Keys() {
+		e, ok := c.lru.Peek(key)
+		if !ok {
+			continue
+		}
+		ids := make([]common.SeriesID, len(e.list))
+		for i := range e.list {
+			ids[i] = e.list[i].ID
+		}
+		result = append(result, SeriesCacheEntry{
+			CreatedAt:  e.createdAt,
+			LastAccess: e.lastAccess,
+			SeriesIDs:  ids,
+			SizeBytes:  e.size,
+		})
+	}
+	return result
+}
+
+// evict drops the entries holding the series and returns how many are dropped.
+func (c *seriesListCache) evict(id common.SeriesID) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var n int
+	for key := range c.bySeries[id] {
+		if e, ok := c.lru.Peek(key); ok {
+			c.remove(key, e)
+			n++
+		}
+	}
+	return n
+}
+
+func (c *seriesListCache) stats() SeriesCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return SeriesCacheStats{
+		Hits:   c.hits,
+		Misses: c.misses,
+	}
+}
+
+// ErrSeriesCacheDebugDisabled denotes the series cache debug API isn't enabled by the module's series cache debug flag.
+var ErrSeriesCacheDebugDisabled = errors.New("the series cache debug API is disabled")
+
+// seriesCacheHolder is the part of a TSDB exposing its series list cache.
+type seriesCacheHolder interface {
+	SeriesCacheStats() SeriesCacheStats
+	SeriesCacheEntries() []SeriesCacheEntry
+	EvictSeries(series *pbv1.Series) (int, error)
+}
+
+// SeriesCaches reaches the series list caches of the groups of a module.
+type SeriesCaches struct {
+	repo   schema.Repository
+	gauge  meter.Gauge
+	module string
+	once   sync.Once
+}
+
+// NewSeriesCaches returns the SeriesCaches of the groups in the module's schema repository.
+func NewSeriesCaches(module string, repo schema.Repository) *SeriesCaches {
+	return &SeriesCaches{module: module, repo: repo}
+}
+
+// CollectorName is the name to register Collect to the metrics collector by.
+func (c *SeriesCaches) CollectorName() string {
+	return c.module + "_series_cache"
+}
+
+// Collect reports the hit ratio of every group's cache.
+func (c *SeriesCaches) Collect() {
+	c.once.Do(func() {
+		c.gauge = observability.NewGauge(observability.NewMeterProviders(observability.RootScope.SubScope(c.module)),
+			"series_cache_hit_ratio", "group")
+	})
+	for _, g := range c.repo.LoadAllGroups() {
+		name := g.GetSchema().GetMetadata().GetName()
+		h, err := c.load(name)
+		if err != nil {
+			continue
+		}
+		c.gauge.Set(h.SeriesCacheStats().HitRatio(), name)
+	}
+}
+
+// Entries lists the series lists cached by the group, the least recently used first.
+func (c *SeriesCaches) Entries(group string) ([]SeriesCacheEntry, error) {
+	h, err := c.load(group)
+	if err != nil {
+		return nil, err
+	}
+	return h.SeriesCacheEntries(), nil
+}
+
+// Evict drops the series lists cached by the group which hold the series, and returns how many are dropped.
+func (c *SeriesCaches) Evict(group string, series *pbv1.Series) (int, error) {
+	h, err := c.load(group)
+	if err != nil {
+		return 0, err
+	}
+	return h.EvictSeries(series)
+}
+
+func (c *SeriesCaches) load(group string) (seriesCacheHolder, error) {
+	g, ok := c.repo.LoadGroup(group)
+	if !ok {
+		return nil, fmt.Errorf("group %s not found", group)
+	}
+	h, ok := g.SupplyTSDB().(seriesCacheHolder)
+	if !ok {
+		return nil, fmt.Errorf("tsdb for group %s not found", group)
+	}
+	return h, nil
+}
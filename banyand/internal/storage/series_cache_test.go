@@ -0,0 +1,149 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package storage
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	"github.com/apache/skywalking-banyandb/pkg/index"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/schema"
+)
+
+type fakeSeriesCacheDB struct {
+	io.Closer
+	entries []SeriesCacheEntry
+}
+
+func (db *fakeSeriesCacheDB) SeriesCacheStats() SeriesCacheStats {
+	return SeriesCacheStats{Hits: 3, Misses: 1}
+}
+
+func (db *fakeSeriesCacheDB) SeriesCacheEntries() []SeriesCacheEntry {
+	return db.entries
+}
+
+func (db *fakeSeriesCacheDB) EvictSeries(_ *pbv1.Series) (int, error) {
+	n := len(db.entries)
+	db.entries = nil
+	return n, nil
+}
+
+type fakeSeriesCacheGroup struct {
+	db io.Closer
+}
+
+func (g *fakeSeriesCacheGroup) GetSchema() *commonv1.Group {
+	return &commonv1.Group{Metadata: &commonv1.Metadata{Name: "sw_metric"}}
+}
+
+func (g *fakeSeriesCacheGroup) SupplyTSDB() io.Closer {
+	return g.db
+}
+
+type fakeSeriesCacheRepo struct {
+	schema.Repository
+	groups map[string]schema.Group
+}
+
+func (r *fakeSeriesCacheRepo) LoadGroup(name string) (schema.Group, bool) {
+	g, ok := r.groups[name]
+	return g, ok
+}
+
+func TestSeriesCaches(t *testing.T) {
+	db := &fakeSeriesCacheDB{entries: []SeriesCacheEntry{{SeriesIDs: []common.SeriesID{1}}}}
+	caches := NewSeriesCaches("measure", &fakeSeriesCacheRepo{groups: map[string]schema.Group{
+		"sw_metric": &fakeSeriesCacheGroup{db: db},
+		"closed":    &fakeSeriesCacheGroup{},
+	}})
+	assert.Equal(t, "measure_series_cache", caches.CollectorName())
+
+	entries, err := caches.Entries("sw_metric")
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+	n, err := caches.Evict("sw_metric", &pbv1.Series{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	entries, err = caches.Entries("sw_metric")
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	_, err = caches.Entries("unknown")
+	assert.Error(t, err)
+	_, err = caches.Evict("closed", &pbv1.Series{})
+	assert.Error(t, err, "a group without a tsdb has no cache")
+}
+
+func TestSeriesListCacheInvalidate(t *testing.T) {
+	c := newSeriesListCache(3, 0, CachePolicyLRU)
+	put := func(m index.SeriesMatcher, ids ...common.SeriesID) string {
+		matchers := []index.SeriesMatcher{m}
+		key := seriesListCacheKey(matchers)
+		_, gen, ok := c.get(key)
+		require.False(t, ok)
+		var list pbv1.SeriesList
+		for _, id := range ids {
+			list = append(list, &pbv1.Series{ID: id})
+		}
+		c.put(key, gen, matchers, list)
+		return key
+	}
+	cached := func(key string) bool {
+		_, ok := c.lru.Peek(key)
+		return ok
+	}
+	exact := put(index.SeriesMatcher{Type: index.SeriesMatcherTypeExact, Match: []byte("svc_1/instance_1")}, 1)
+	prefix := put(index.SeriesMatcher{Type: index.SeriesMatcherTypePrefix, Match: []byte("svc_1/")}, 1)
+	wildcard := put(index.SeriesMatcher{Type: index.SeriesMatcherTypeWildcard, Match: []byte("svc_*")}, 1, 2)
+
+	// Rewriting a cached series keeps all the entries.
+	c.invalidate(index.Documents{{DocID: 1, EntityValues: []byte("svc_1/instance_1")}})
+	assert.True(t, cached(exact))
+	assert.True(t, cached(prefix))
+	assert.True(t, cached(wildcard))
+
+	// A new series out of the prefix only reaches the wildcard.
+	c.invalidate(index.Documents{{DocID: 3, EntityValues: []byte("svc_2/instance_1")}})
+	assert.True(t, cached(exact))
+	assert.True(t, cached(prefix))
+	assert.False(t, cached(wildcard))
+
+	c.invalidate(index.Documents{{DocID: 4, EntityValues: []byte("svc_1/instance_2")}})
+	assert.True(t, cached(exact))
+	assert.False(t, cached(prefix))
+
+	// The entries evicted for the capacity are unindexed as well.
+	put(index.SeriesMatcher{Type: index.SeriesMatcherTypeExact, Match: []byte("svc_3")}, 5)
+	put(index.SeriesMatcher{Type: index.SeriesMatcherTypeExact, Match: []byte("svc_4")}, 6)
+	put(index.SeriesMatcher{Type: index.SeriesMatcherTypePrefix, Match: []byte("svc_5")}, 7)
+	assert.False(t, cached(exact))
+	assert.Equal(t, 3, c.lru.Len())
+	assert.NotContains(t, c.bySeries, common.SeriesID(1))
+	assert.NotContains(t, c.byExact, "svc_1/instance_1")
+	assert.Equal(t, map[int]int{5: 1}, c.prefixLens)
+	assert.Equal(t, 1, c.evict(7))
+	assert.Empty(t, c.byPrefix)
+	assert.Empty(t, c.prefixLens)
+}
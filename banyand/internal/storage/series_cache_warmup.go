@@ -0,0 +1,166 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
+
+	"github.com/apache/skywalking-banyandb/pkg/fs"
+	"github.com/apache/skywalking-banyandb/pkg/index"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+// hotSeriesFilename is the file in the database root holding the matchers of the cached series lists.
+const hotSeriesFilename = "series-cache-hot"
+
+type hotSeriesMatcher struct {
+	Match []byte                  `json:"match"`
+	Type  index.SeriesMatcherType `json:"type"`
+}
+
+// hotMatchers returns the matchers of the cached series lists in the order they would be evicted.
+func (c *seriesListCache) hotMatchers() [][]index.SeriesMatcher {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result := make([][]index.SeriesMatcher, 0, c.lru.Len())
+	for _, key := range c.lru.Keys() {
+		if e, ok := c.lru.Peek(key); ok {
+			result = append(result, e.matchers)
+		}
+	}
+	return result
+}
+
+// currentGeneration returns the generation to pass to put without a lookup.
+func (c *seriesListCache) currentGeneration() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// warmup caches the series lists resolved by the matchers and returns how many are cached.
+// The matchers are added in their order, so the last ones are the last to be evicted.
+func (s *seriesIndex) warmup(ctx context.Context, hot [][]index.SeriesMatcher) int {
+	if s.cache == nil {
+		return 0
+	}
+	var n int
+	for _, matchers := range hot {
+		generation := s.cache.currentGeneration()
+		ss, err := s.store.Search(ctx, matchers, 0)
+		if err != nil {
+			s.l.Warn().Err(err).Msg("failed to warm up the series list cache")
+			continue
+		}
+		list, err := convertIndexSeriesToSeriesList(ss)
+		if err != nil {
+			s.l.Warn().Err(err).Msg("failed to warm up the series list cache")
+			continue
+		}
+		s.cache.put(seriesListCacheKey(matchers), generation, matchers, list)
+		n++
+	}
+	return n
+}
+
+func (s *seriesIndex) hotMatchers() [][]index.SeriesMatcher {
+	if s.cache == nil {
+		return nil
+	}
+	return s.cache.hotMatchers()
+}
+
+// startSeriesCacheSnapshot persists the hot series every minute, so that a crash loses at most a minute of them.
+func (d *database[T, O]) startSeriesCacheSnapshot() error {
+	if !d.opts.SeriesCacheWarmup {
+		return nil
+	}
+	return d.scheduler.Register("series-cache-snapshot", cron.Minute|cron.Hour, "* *", func(_ time.Time, l *logger.Logger) bool {
+		if err := d.indexController.snapshotHotSeries(); err != nil {
+			l.Warn().Err(err).Msg("failed to persist the hot series")
+		}
+		return true
+	})
+}
+
+// snapshotHotSeries persists the matchers of the series lists cached by the hot series index.
+func (sic *seriesIndexController[T, O]) snapshotHotSeries() error {
+	sic.RLock()
+	defer sic.RUnlock()
+	return writeHotSeries(sic.location, sic.hot.hotMatchers())
+}
+
+// warmup reloads the series lists persisted by snapshotHotSeries into the cache of the hot series index.
+func (sic *seriesIndexController[T, O]) warmup(ctx context.Context) {
+	hot, err := readHotSeries(sic.location)
+	if err != nil {
+		sic.l.Warn().Err(err).Msg("failed to read the hot series, skip warming up the series list cache")
+		return
+	}
+	if len(hot) == 0 {
+		return
+	}
+	n := sic.hot.warmup(ctx, hot)
+	sic.l.Info().Int("loaded", n).Int("persisted", len(hot)).Msg("warmed up the series list cache")
+}
+
+func writeHotSeries(location string, hot [][]index.SeriesMatcher) error {
+	entries := make([][]hotSeriesMatcher, len(hot))
+	for i := range hot {
+		entries[i] = make([]hotSeriesMatcher, len(hot[i]))
+		for j := range hot[i] {
+			entries[i][j] = hotSeriesMatcher{Type: hot[i][j].Type, Match: hot[i][j].Match}
+		}
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	_, err = lfs.Write(data, filepath.Join(location, hotSeriesFilename), filePermission)
+	return err
+}
+
+// readHotSeries returns nil if the hot series are never persisted.
+func readHotSeries(location string) ([][]index.SeriesMatcher, error) {
+	data, err := lfs.Read(filepath.Join(location, hotSeriesFilename))
+	if err != nil {
+		var fsErr *fs.FileSystemError
+		if errors.As(err, &fsErr) && fsErr.Code == fs.IsNotExistError {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var entries [][]hotSeriesMatcher
+	if err = json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	hot := make([][]index.SeriesMatcher, len(entries))
+	for i := range entries {
+		hot[i] = make([]index.SeriesMatcher, len(entries[i]))
+		for j := range entries[i] {
+			hot[i][j] = index.SeriesMatcher{Type: entries[i][j].Type, Match: entries[i][j].Match}
+		}
+	}
+	return hot, nil
+}
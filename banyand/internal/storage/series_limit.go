@@ -0,0 +1,61 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package storage
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/banyand/observability"
+	"github.com/apache/skywalking-banyandb/pkg/meter"
+)
+
+// ErrTooManySeries indicates a query matches more series than its limit.
+var ErrTooManySeries = errors.WithMessage(common.ErrResourceExhausted, "too many series")
+
+var seriesLimitExceeded = sync.OnceValue(func() meter.Counter {
+	return observability.NewCounter(observability.NewMeterProviders(observability.RootScope.SubScope("storage")),
+		"series_limit_exceeded", "group")
+})
+
+type maxSeriesContextKey struct{}
+
+// WithMaxSeries overrides the limit on the number of series a query matches.
+// It's meant for the privileged callers. Zero or a negative value lifts the limit.
+func WithMaxSeries(ctx context.Context, maxSeries int) context.Context {
+	return context.WithValue(ctx, maxSeriesContextKey{}, maxSeries)
+}
+
+func maxSeriesOf(ctx context.Context, defaultValue int) int {
+	if v, ok := ctx.Value(maxSeriesContextKey{}).(int); ok {
+		return v
+	}
+	return defaultValue
+}
+
+// checkSeriesLimit rejects a query matching more series than the limit, and counts the rejected queries.
+func (s *seriesIndex) checkSeriesLimit(ctx context.Context, matched int) error {
+	if limit := maxSeriesOf(ctx, s.maxSeries); limit > 0 && matched > limit {
+		seriesLimitExceeded().Inc(1, s.group)
+		return errors.WithMessagef(ErrTooManySeries, "the query matches more than %d series", limit)
+	}
+	return nil
+}
@@ -53,7 +53,7 @@ func (d *database[T, O]) openShard(ctx context.Context, id common.ShardID) (*sha
 		position: common.GetPosition(shardCtx),
 		segmentController: newSegmentController[T](shardCtx, location,
 			d.opts.SegmentInterval, l, d.scheduler,
-			d.opts.TSTableCreator, d.opts.Option),
+			d.opts.TSTableCreator, d.opts.Option, d.segmentCache, d.segmentDeleted),
 	}
 	var err error
 	if err = s.segmentController.open(); err != nil {
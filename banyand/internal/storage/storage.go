@@ -48,6 +48,8 @@ const (
 
 	hourFormat = "2006010215"
 	dayFormat  = "20060102"
+	// rotatedFormat names a segment starting between two interval boundaries, which is created by a forced rotation.
+	rotatedFormat = "20060102150405"
 
 	dirPerm = 0o700
 )
@@ -55,6 +57,11 @@ const (
 var (
 	// ErrUnknownShard indicates that the shard is not found.
 	ErrUnknownShard = errors.New("unknown shard")
+	// ErrResharded indicates that a query can't be pinned to some shards,
+	// because the series written before resharding aren't in the shards they're hashed into now.
+	ErrResharded = errors.New("the shards of a resharded database can't be pinned")
+	// ErrShrinkShards indicates that the number of shards is reduced, which would hide the data of the dropped shards.
+	ErrShrinkShards = errors.New("the number of shards can't be reduced")
 	errOpenDatabase = errors.New("fails to open the database")
 
 	lfs = fs.NewLocalFileSystemWithLogger(logger.GetLogger("storage"))
@@ -73,17 +80,57 @@ type IndexDB interface {
 type TSDB[T TSTable, O any] interface {
 	io.Closer
 	Lookup(ctx context.Context, series []*pbv1.Series) (pbv1.SeriesList, error)
+	// LookupByID resolves the series of the IDs, leaving out the unknown IDs.
+	LookupByID(ctx context.Context, ids []common.SeriesID) (pbv1.SeriesList, error)
+	SeriesCacheStats() SeriesCacheStats
+	// SeriesCacheEntries lists the series lists cached by the series index.
+	SeriesCacheEntries() []SeriesCacheEntry
+	// EvictSeries drops the cached series lists holding the series, and returns how many are dropped.
+	EvictSeries(series *pbv1.Series) (int, error)
+	// PersistSeriesIndex blocks until the written series are persisted on disk.
+	PersistSeriesIndex(ctx context.Context) error
+	// AdmitTimestamp applies the out-of-retention policy to the timestamp of a write.
+	AdmitTimestamp(ts time.Time) (time.Time, bool, error)
 	CreateTSTableIfNotExist(shardID common.ShardID, ts time.Time) (TSTableWrapper[T], error)
 	SelectTSTables(timeRange timestamp.TimeRange) []TSTableWrapper[T]
+	// SelectShardTSTables works like SelectTSTables, but only selects the tables of the shards.
+	// It fails with ErrResharded once the number of shards differs from the one the database was created with.
+	SelectShardTSTables(shardIDs []common.ShardID, timeRange timestamp.TimeRange) ([]TSTableWrapper[T], error)
 	IndexDB() IndexDB
 	Tick(ts int64)
+	// ShardNum returns the current number of shards.
+	ShardNum() uint32
+	// Reshard grows the number of shards without closing the database.
+	Reshard(shardNum uint32) error
+	// Status reports whether a new segment is being created and the time range of the newest segment.
+	Status() Status
+	// ForceRotate seals the current segment of every shard and starts a new one from now.
+	ForceRotate() error
+	// DeleteExpiredSegments deletes the segments of every shard ending before the deadline, and returns their time ranges.
+	// A dry run only returns the time ranges of the segments it would delete.
+	DeleteExpiredSegments(deadline time.Time, dryRun bool) ([]timestamp.TimeRange, error)
+	// SegmentStats counts the open segments and all the segments of every shard.
+	SegmentStats() SegmentStats
+	// OnSegmentDeleted registers a callback called once the retention deletes a segment of a shard from the disk,
+	// along with the index of its elements. A segment still read by a query is deleted once the query releases it.
+	OnSegmentDeleted(fn SegmentDeletedFunc)
 }
 
+// SegmentDeletedFunc receives the group and the time range of a deleted segment.
+type SegmentDeletedFunc func(group string, timeRange timestamp.TimeRange)
+
 // TSTable is time series table.
 type TSTable interface {
 	io.Closer
 }
 
+// IdleTSTable is a TSTable which tells whether it can be closed without losing any data.
+// Only such tables are evicted once a group opens too many segments.
+type IdleTSTable interface {
+	TSTable
+	Idle() bool
+}
+
 // TSTableWrapper is a wrapper of TSTable.
 // It is used to manage the reference count of TSTable.
 type TSTableWrapper[T TSTable] interface {
@@ -131,6 +178,16 @@ type IntervalRule struct {
 	Num  int
 }
 
+// standard returns the start of the interval containing t.
+// The intervals of several hours dividing a day are aligned to the start of the day,
+// so that a segment created at any time within an interval starts from the same boundary.
+func (ir IntervalRule) standard(t time.Time) time.Time {
+	if ir.Unit == HOUR && ir.Num > 1 && 24%ir.Num == 0 {
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour()-t.Hour()%ir.Num, 0, 0, 0, t.Location())
+	}
+	return ir.Unit.standard(t)
+}
+
 func (ir IntervalRule) nextTime(current time.Time) time.Time {
 	switch ir.Unit {
 	case HOUR:
@@ -20,6 +20,7 @@ package storage
 import (
 	"context"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -44,8 +45,9 @@ const (
 )
 
 const (
-	lockFilename   = "lock"
-	filePermission = 0o600
+	lockFilename     = "lock"
+	shardNumFilename = "shard-num"
+	filePermission   = 0o600
 )
 
 // TSDBOpts wraps options to create a tsdb.
@@ -57,20 +59,40 @@ type TSDBOpts[T TSTable, O any] struct {
 	TTL                            IntervalRule
 	ShardNum                       uint32
 	SeriesIndexFlushTimeoutSeconds int64
+	// SeriesCacheSize bounds the number of cached series lists. Zero disables the cache.
+	SeriesCacheSize   int
+	SeriesCacheTTL    time.Duration
+	SeriesCachePolicy CachePolicy
+	// SeriesCacheWarmup persists the cached series lists periodically and reloads them once the database opens.
+	SeriesCacheWarmup bool
+	// MaxSeriesPerQuery bounds the number of series a query matches. Zero means no limit.
+	MaxSeriesPerQuery int
+	// FutureWindow is how far ahead of now a write may be. Zero means one segment interval.
+	FutureWindow         IntervalRule
+	OutOfRetentionPolicy OutOfRetentionPolicy
+	// MaxOpenSegments bounds the number of segments whose tables are open. Zero means no limit.
+	MaxOpenSegments int
+	// MaxDiskUsagePercent is the disk usage beyond which the oldest segments are deleted before their TTL. Zero disables it.
+	MaxDiskUsagePercent int
+	// MinRetainedSegments is the number of the latest segments never deleted by the disk usage.
+	MinRetainedSegments int
 }
 
 type (
-	segmentID uint32
+	segmentID uint64
 )
 
 func generateSegID(unit IntervalUnit, suffix int) segmentID {
-	return segmentID(unit)<<31 | ((segmentID(suffix) << 1) >> 1)
+	return segmentID(unit)<<63 | ((segmentID(suffix) << 1) >> 1)
 }
 
 type database[T TSTable, O any] struct {
 	lock            fs.File
+	clock           timestamp.Clock
 	logger          *logger.Logger
 	indexController *seriesIndexController[T, O]
+	segmentCache    *segmentCache[T]
+	segmentDeleted  *segmentDeletedListeners
 	scheduler       *timestamp.Scheduler
 	sLst            atomic.Pointer[[]*shard[T, O]]
 	tsEventCh       chan int64
@@ -78,8 +100,13 @@ type database[T TSTable, O any] struct {
 	location        string
 	opts            TSDBOpts[T, O]
 	latestTickTime  atomic.Int64
+	shardNum        atomic.Uint32
+	// hashedShardNum is the number of shards the series were hashed into when the database was created.
+	hashedShardNum uint32
 	sync.RWMutex
 	rotationProcessOn atomic.Bool
+	// creatingSegment is only on while a segment following the newest one is being created.
+	creatingSegment atomic.Bool
 }
 
 func (d *database[T, O]) Close() error {
@@ -121,13 +148,17 @@ func OpenTSDB[T TSTable, O any](ctx context.Context, opts TSDBOpts[T, O]) (TSDB[
 	scheduler := timestamp.NewScheduler(l, clock)
 	db := &database[T, O]{
 		location:        location,
+		clock:           clock,
 		scheduler:       scheduler,
 		logger:          l,
 		indexController: sir,
+		segmentCache:    newSegmentCache[T](opts.MaxOpenSegments),
+		segmentDeleted:  &segmentDeletedListeners{},
 		opts:            opts,
 		tsEventCh:       make(chan int64),
 		p:               p,
 	}
+	db.shardNum.Store(opts.ShardNum)
 	db.logger.Info().Str("path", opts.Location).Msg("initialized")
 	lockPath := filepath.Join(opts.Location, lockFilename)
 	lock, err := lfs.CreateLockFile(lockPath, filePermission)
@@ -135,10 +166,16 @@ func OpenTSDB[T TSTable, O any](ctx context.Context, opts TSDBOpts[T, O]) (TSDB[
 		logger.Panicf("cannot create lock file %s: %s", lockPath, err)
 	}
 	db.lock = lock
+	if db.hashedShardNum, err = loadHashedShardNum(location, opts.ShardNum); err != nil {
+		return nil, errors.Wrap(errOpenDatabase, errors.WithMessage(err, "load the shard number failed").Error())
+	}
 	if err = db.loadDatabase(); err != nil {
 		return nil, errors.Wrap(errOpenDatabase, errors.WithMessage(err, "load database failed").Error())
 	}
-	return db, db.startRotationTask()
+	if err = db.startRotationTask(); err != nil {
+		return db, err
+	}
+	return db, db.startSeriesCacheSnapshot()
 }
 
 func (d *database[T, O]) CreateTSTableIfNotExist(shardID common.ShardID, ts time.Time) (TSTableWrapper[T], error) {
@@ -173,10 +210,8 @@ func (d *database[T, O]) getShard(shardID common.ShardID) (*shard[T, O], bool) {
 }
 
 func (d *database[T, O]) createTSTTable(shard *shard[T, O], ts time.Time) (TSTableWrapper[T], error) {
-	timeRange := timestamp.NewInclusiveTimeRange(ts, ts)
-	ss := shard.segmentController.selectTSTables(timeRange)
-	if len(ss) > 0 {
-		return ss[0], nil
+	if s := shard.segmentController.segmentContaining(ts); s != nil {
+		return s, nil
 	}
 	return shard.segmentController.createTSTable(ts)
 }
@@ -193,6 +228,55 @@ func (d *database[T, O]) SelectTSTables(timeRange timestamp.TimeRange) []TSTable
 	return result
 }
 
+func (d *database[T, O]) SelectShardTSTables(shardIDs []common.ShardID, timeRange timestamp.TimeRange) ([]TSTableWrapper[T], error) {
+	shardNum := d.shardNum.Load()
+	if shardNum != d.hashedShardNum {
+		return nil, errors.Wrapf(ErrResharded, "the series were hashed into %d shards, but there are %d shards now", d.hashedShardNum, shardNum)
+	}
+	for _, id := range shardIDs {
+		if uint32(id) >= shardNum {
+			return nil, errors.Wrapf(ErrUnknownShard, "shard %d is out of the %d shards", id, shardNum)
+		}
+	}
+	var result []TSTableWrapper[T]
+	for i, id := range shardIDs {
+		if slices.Contains(shardIDs[:i], id) {
+			continue
+		}
+		// A shard without any data isn't opened yet.
+		if s, ok := d.getShard(id); ok {
+			result = append(result, s.segmentController.selectTSTables(timeRange)...)
+		}
+	}
+	return result, nil
+}
+
+func (d *database[T, O]) OnSegmentDeleted(fn SegmentDeletedFunc) {
+	d.segmentDeleted.add(fn)
+}
+
+func (d *database[T, O]) SegmentStats() SegmentStats {
+	var stats SegmentStats
+	sLst := d.sLst.Load()
+	if sLst == nil {
+		return stats
+	}
+	for _, s := range *sLst {
+		snapshot := s.segmentController.acquireSnapshot()
+		if snapshot == nil {
+			continue
+		}
+		for i := range snapshot.tables {
+			stats.Total++
+			if snapshot.tables[i].opened.Load() {
+				stats.Open++
+			}
+		}
+		snapshot.release()
+	}
+	return stats
+}
+
 func (d *database[T, O]) registerShard(id common.ShardID) (*shard[T, O], error) {
 	if s, ok := d.getShard(id); ok {
 		return s, nil
@@ -215,6 +299,29 @@ func (d *database[T, O]) registerShard(id common.ShardID) (*shard[T, O], error)
 	return so, nil
 }
 
+func (d *database[T, O]) ShardNum() uint32 {
+	return d.shardNum.Load()
+}
+
+// Reshard grows the number of shards. A new shard is opened once it's written.
+// The data isn't moved between the shards: an existing shard keeps the data written before,
+// and a query reads all the shards, so it sees the data written before and after resharding.
+// A module overwriting the data has to tell a series' data written after resharding from the one
+// written before by the number of shards it's written with, since they may be in different shards.
+// A series may be written to another shard after resharding, so SelectShardTSTables rejects
+// the database from then on.
+func (d *database[T, O]) Reshard(shardNum uint32) error {
+	d.Lock()
+	defer d.Unlock()
+	prev := d.shardNum.Load()
+	if shardNum < prev {
+		return errors.Wrapf(ErrShrinkShards, "from %d to %d", prev, shardNum)
+	}
+	d.shardNum.Store(shardNum)
+	d.logger.Info().Uint32("from", prev).Uint32("to", shardNum).Msg("resharded")
+	return nil
+}
+
 func (d *database[T, O]) loadDatabase() error {
 	d.Lock()
 	defer d.Unlock()
@@ -223,7 +330,7 @@ func (d *database[T, O]) loadDatabase() error {
 		if err != nil {
 			return err
 		}
-		if shardID >= int(d.opts.ShardNum) {
+		if shardID >= int(d.shardNum.Load()) {
 			return nil
 		}
 		d.logger.Info().Int("shard_id", shardID).Msg("loaded a existed shard")
@@ -232,6 +339,28 @@ func (d *database[T, O]) loadDatabase() error {
 	})
 }
 
+// loadHashedShardNum returns the number of shards persisted when the database was created,
+// persisting shardNum if it's absent. A database created before the number was persisted takes shardNum.
+func loadHashedShardNum(location string, shardNum uint32) (uint32, error) {
+	path := filepath.Join(location, shardNumFilename)
+	data, err := lfs.Read(path)
+	if err == nil {
+		n, errParse := strconv.ParseUint(string(data), 10, 32)
+		if errParse != nil {
+			return 0, errors.WithMessagef(errParse, "malformed %s", path)
+		}
+		return uint32(n), nil
+	}
+	var fsErr *fs.FileSystemError
+	if !errors.As(err, &fsErr) || fsErr.Code != fs.IsNotExistError {
+		return 0, err
+	}
+	if _, err = lfs.Write([]byte(strconv.FormatUint(uint64(shardNum), 10)), path, filePermission); err != nil {
+		return 0, err
+	}
+	return shardNum, nil
+}
+
 type walkFn func(suffix string) error
 
 func walkDir(root, prefix string, wf walkFn) error {
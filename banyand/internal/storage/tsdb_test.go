@@ -0,0 +1,159 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/pkg/test"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+func TestSelectShardTSTables(t *testing.T) {
+	dir, defFn := test.Space(require.New(t))
+	defer defFn()
+	tsdb, err := OpenTSDB(context.Background(), TSDBOpts[*MockTSTable, any]{
+		Location:        dir,
+		SegmentInterval: IntervalRule{Unit: DAY, Num: 1},
+		TTL:             IntervalRule{Unit: DAY, Num: 3},
+		ShardNum:        4,
+		TSTableCreator:  MockTSTableCreator,
+	})
+	require.NoError(t, err)
+	defer tsdb.Close()
+	db := tsdb.(*database[*MockTSTable, any])
+
+	now := time.Now()
+	for _, id := range []common.ShardID{0, 1, 2} {
+		for _, ts := range []time.Time{now, now.Add(-24 * time.Hour)} {
+			tw, errCreate := tsdb.CreateTSTableIfNotExist(id, ts)
+			require.NoError(t, errCreate)
+			tw.DecRef()
+		}
+	}
+	tr := timestamp.NewInclusiveTimeRange(now.Add(-48*time.Hour), now.Add(time.Hour))
+	shardTables := func(id common.ShardID) []TSTableWrapper[*MockTSTable] {
+		s, ok := db.getShard(id)
+		require.True(t, ok)
+		return s.segmentController.selectTSTables(tr)
+	}
+	release := func(tables []TSTableWrapper[*MockTSTable]) {
+		for i := range tables {
+			tables[i].DecRef()
+		}
+	}
+
+	all := tsdb.SelectTSTables(tr)
+	defer release(all)
+	require.Len(t, all, 6)
+
+	t.Run("only the pinned shards are selected", func(t *testing.T) {
+		pinned, err := tsdb.SelectShardTSTables([]common.ShardID{2, 0, 2}, tr)
+		require.NoError(t, err)
+		defer release(pinned)
+		want := append(shardTables(2), shardTables(0)...)
+		defer release(want)
+		assert.Equal(t, want, pinned)
+		for i := range pinned {
+			assert.Contains(t, all, pinned[i], "a pinned table is one of the unpinned selection")
+		}
+	})
+
+	t.Run("a shard without data selects nothing", func(t *testing.T) {
+		pinned, err := tsdb.SelectShardTSTables([]common.ShardID{3}, tr)
+		require.NoError(t, err)
+		assert.Empty(t, pinned)
+	})
+
+	t.Run("a shard out of the shard number is rejected", func(t *testing.T) {
+		_, err := tsdb.SelectShardTSTables([]common.ShardID{1, 4}, tr)
+		assert.ErrorIs(t, err, ErrUnknownShard)
+		assert.ErrorContains(t, err, "shard 4 is out of the 4 shards")
+	})
+}
+
+func TestReshard(t *testing.T) {
+	dir, defFn := test.Space(require.New(t))
+	defer defFn()
+	opts := TSDBOpts[*MockTSTable, any]{
+		Location:        dir,
+		SegmentInterval: IntervalRule{Unit: DAY, Num: 1},
+		TTL:             IntervalRule{Unit: DAY, Num: 3},
+		ShardNum:        2,
+		TSTableCreator:  MockTSTableCreator,
+	}
+	tsdb, err := OpenTSDB(context.Background(), opts)
+	require.NoError(t, err)
+	now := time.Now()
+	tr := timestamp.NewInclusiveTimeRange(now.Add(-time.Hour), now.Add(time.Hour))
+	write := func(tsdb TSDB[*MockTSTable, any], ids ...common.ShardID) {
+		for _, id := range ids {
+			tw, errCreate := tsdb.CreateTSTableIfNotExist(id, now)
+			require.NoError(t, errCreate)
+			tw.DecRef()
+		}
+	}
+	selected := func(tsdb TSDB[*MockTSTable, any], ids ...common.ShardID) int {
+		tables, errSelect := tsdb.SelectShardTSTables(ids, tr)
+		require.NoError(t, errSelect)
+		for i := range tables {
+			tables[i].DecRef()
+		}
+		return len(tables)
+	}
+	selectedAll := func(tsdb TSDB[*MockTSTable, any]) int {
+		tables := tsdb.SelectTSTables(tr)
+		for i := range tables {
+			tables[i].DecRef()
+		}
+		return len(tables)
+	}
+	write(tsdb, 0, 1)
+	assert.Equal(t, 2, selected(tsdb, 0, 1))
+	_, err = tsdb.SelectShardTSTables([]common.ShardID{3}, tr)
+	require.ErrorIs(t, err, ErrUnknownShard)
+
+	require.NoError(t, tsdb.Reshard(4))
+	assert.Equal(t, 2, selectedAll(tsdb), "the existing shards keep their tables")
+	write(tsdb, 3)
+	assert.Equal(t, 3, selectedAll(tsdb))
+	_, err = tsdb.SelectShardTSTables([]common.ShardID{0}, tr)
+	require.ErrorIs(t, err, ErrResharded, "a series may be in another shard than the one it's hashed into")
+	require.ErrorIs(t, tsdb.Reshard(3), ErrShrinkShards)
+	require.NoError(t, tsdb.Close())
+
+	opts.ShardNum = 4
+	tsdb, err = OpenTSDB(context.Background(), opts)
+	require.NoError(t, err)
+	assert.Equal(t, 3, selectedAll(tsdb), "the shards are loaded once the database is reopened")
+	_, err = tsdb.SelectShardTSTables([]common.ShardID{0}, tr)
+	require.ErrorIs(t, err, ErrResharded, "the database stays resharded once it's reopened")
+	require.NoError(t, tsdb.Close())
+
+	opts.ShardNum = 2
+	tsdb, err = OpenTSDB(context.Background(), opts)
+	require.NoError(t, err)
+	defer tsdb.Close()
+	assert.Equal(t, 2, selected(tsdb, 0, 1), "the series are in their hashed shards with the original number of shards")
+}
@@ -26,8 +26,10 @@ import (
 )
 
 const (
+	// currentVersion is 1.1.0 since the part metadata is written in the binary format,
+	// and 1.2.0 since the measure part metadata records the number of shards.
+	currentVersion             = "1.2.0"
 	metadataFilename           = "metadata"
-	currentVersion             = "1.0.0"
 	compatibleVersionsKey      = "versions"
 	compatibleVersionsFilename = "versions.yml"
 )
@@ -20,6 +20,7 @@ package grpc
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -27,6 +28,7 @@ import (
 	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
 	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
 	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/banyand/internal/storage"
 	"github.com/apache/skywalking-banyandb/banyand/metadata"
 	"github.com/apache/skywalking-banyandb/banyand/metadata/schema"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
@@ -46,8 +48,17 @@ type discoveryService struct {
 }
 
 func newDiscoveryService(kind schema.Kind, metadataRepo metadata.Repo, nodeRegistry NodeRegistry) *discoveryService {
-	sr := &shardRepo{shardEventsMap: make(map[identity]uint32)}
-	er := &entityRepo{entitiesMap: make(map[identity]partition.EntityLocator)}
+	sr := &shardRepo{
+		shardEventsMap: make(map[identity]uint32),
+		strategies:     make(map[identity]commonv1.ShardingStrategy),
+		windows:        make(map[identity]storage.RetentionWindow),
+		aliases:        make(map[string]string),
+	}
+	er := &entityRepo{
+		entitiesMap:   make(map[identity]partition.EntityLocator),
+		rollups:       make(map[identity]identity),
+		rollupSources: make(map[identity]int),
+	}
 	return &discoveryService{
 		shardRepo:    sr,
 		entityRepo:   er,
@@ -69,10 +80,17 @@ func (ds *discoveryService) SetLogger(log *logger.Logger) {
 	ds.entityRepo.log = log
 }
 
-func (ds *discoveryService) navigate(metadata *commonv1.Metadata, tagFamilies []*modelv1.TagFamilyForWrite) (pbv1.Entity, pbv1.EntityValues, common.ShardID, error) {
-	shardNum, existed := ds.shardRepo.shardNum(getID(&commonv1.Metadata{
+// navigate picks the shard of a write by the sharding strategy of its group.
+// The options carry the routing key and the salt of the write.
+// The writes to the source of a rollup aren't salted, since every data node rolls up the points it receives,
+// and the partial buckets of an entity spread over the nodes would overwrite each other.
+func (ds *discoveryService) navigate(metadata *commonv1.Metadata, tagFamilies []*modelv1.TagFamilyForWrite,
+	opts partition.ShardingOpts,
+) (pbv1.Entity, pbv1.EntityValues, common.ShardID, error) {
+	groupID := getID(&commonv1.Metadata{
 		Name: metadata.Group,
-	}))
+	})
+	shardNum, existed := ds.shardRepo.shardNum(groupID)
 	if !existed {
 		return nil, nil, common.ShardID(0), errors.Wrapf(errNotExist, "finding the shard num by: %v", metadata)
 	}
@@ -80,7 +98,37 @@ func (ds *discoveryService) navigate(metadata *commonv1.Metadata, tagFamilies []
 	if !existed {
 		return nil, nil, common.ShardID(0), errors.Wrapf(errNotExist, "finding the locator by: %v", metadata)
 	}
-	return locator.Locate(metadata.Name, tagFamilies, shardNum)
+	opts.Strategy = ds.shardRepo.strategy(groupID)
+	if ds.entityRepo.isRollupSource(getID(metadata)) {
+		opts.Salt = nil
+	}
+	return locator.LocateWithOpts(metadata.Name, tagFamilies, shardNum, opts)
+}
+
+// admit rejects a write beyond the retention window of its group if the group rejects such writes,
+// so that the writer gets the status instead of the data node dropping the write after it's acknowledged.
+// The other policies are applied by the data nodes.
+func (ds *discoveryService) admit(metadata *commonv1.Metadata, ts time.Time) error {
+	ds.shardRepo.RWMutex.RLock()
+	w, ok := ds.shardRepo.windows[getID(&commonv1.Metadata{Name: metadata.Group})]
+	ds.shardRepo.RWMutex.RUnlock()
+	if !ok || w.Policy != storage.OutOfRetentionReject {
+		return nil
+	}
+	_, _, err := w.Admit(time.Now(), ts)
+	return err
+}
+
+// resolveAlias points the metadata to the group that its group is an alias of.
+func (ds *discoveryService) resolveAlias(metadata *commonv1.Metadata) {
+	metadata.Group = ds.shardRepo.resolveGroup(metadata.Group)
+}
+
+// resolveAliases replaces the aliases in the groups with the groups they point to.
+func (ds *discoveryService) resolveAliases(groups []string) {
+	for i := range groups {
+		groups[i] = ds.shardRepo.resolveGroup(groups[i])
+	}
 }
 
 type identity struct {
@@ -98,6 +146,9 @@ type shardRepo struct {
 	schema.UnimplementedOnInitHandler
 	log            *logger.Logger
 	shardEventsMap map[identity]uint32
+	strategies     map[identity]commonv1.ShardingStrategy
+	windows        map[identity]storage.RetentionWindow
+	aliases        map[string]string
 	sync.RWMutex
 }
 
@@ -116,6 +167,16 @@ func (s *shardRepo) OnAddOrUpdate(schemaMetadata schema.Metadata) {
 	s.RWMutex.Lock()
 	defer s.RWMutex.Unlock()
 	s.shardEventsMap[idx] = group.ResourceOpts.ShardNum
+	s.strategies[idx] = group.ResourceOpts.GetShardingStrategy()
+	if group.ResourceOpts.GetTtl() != nil && group.ResourceOpts.GetSegmentInterval() != nil {
+		s.windows[idx] = storage.NewRetentionWindow(group.ResourceOpts)
+	}
+	s.dropAliases(idx.name)
+	for _, alias := range group.GetAliases() {
+		if alias != "" && alias != idx.name {
+			s.aliases[alias] = idx.name
+		}
+	}
 }
 
 func (s *shardRepo) OnDelete(schemaMetadata schema.Metadata) {
@@ -133,6 +194,31 @@ func (s *shardRepo) OnDelete(schemaMetadata schema.Metadata) {
 	s.RWMutex.Lock()
 	defer s.RWMutex.Unlock()
 	delete(s.shardEventsMap, idx)
+	delete(s.strategies, idx)
+	delete(s.windows, idx)
+	s.dropAliases(idx.name)
+}
+
+func (s *shardRepo) dropAliases(group string) {
+	for alias, target := range s.aliases {
+		if target == group {
+			delete(s.aliases, alias)
+		}
+	}
+}
+
+// resolveGroup returns the group the name is an alias of, or the name itself.
+// A group always takes precedence over an alias with the same name.
+func (s *shardRepo) resolveGroup(name string) string {
+	s.RWMutex.RLock()
+	defer s.RWMutex.RUnlock()
+	if _, ok := s.shardEventsMap[identity{name: name}]; ok {
+		return name
+	}
+	if target, ok := s.aliases[name]; ok {
+		return target
+	}
+	return name
 }
 
 func (s *shardRepo) shardNum(idx identity) (uint32, bool) {
@@ -145,6 +231,12 @@ func (s *shardRepo) shardNum(idx identity) (uint32, bool) {
 	return sn, true
 }
 
+func (s *shardRepo) strategy(idx identity) commonv1.ShardingStrategy {
+	s.RWMutex.RLock()
+	defer s.RWMutex.RUnlock()
+	return s.strategies[idx]
+}
+
 func getID(metadata *commonv1.Metadata) identity {
 	return identity{
 		name:  metadata.GetName(),
@@ -158,6 +250,9 @@ type entityRepo struct {
 	schema.UnimplementedOnInitHandler
 	log         *logger.Logger
 	entitiesMap map[identity]partition.EntityLocator
+	// rollups maps the derived measures to their sources, and rollupSources counts the rollups of every source.
+	rollups       map[identity]identity
+	rollupSources map[identity]int
 	sync.RWMutex
 }
 
@@ -166,12 +261,14 @@ func (e *entityRepo) OnAddOrUpdate(schemaMetadata schema.Metadata) {
 	var el partition.EntityLocator
 	var id identity
 	var modRevision int64
+	var rollup *databasev1.Rollup
 	switch schemaMetadata.Kind {
 	case schema.KindMeasure:
 		measure := schemaMetadata.Spec.(*databasev1.Measure)
 		modRevision = measure.GetMetadata().GetModRevision()
 		el = partition.NewEntityLocator(measure.TagFamilies, measure.Entity, modRevision)
 		id = getID(measure.GetMetadata())
+		rollup = measure.GetRollup()
 	case schema.KindStream:
 		stream := schemaMetadata.Spec.(*databasev1.Stream)
 		modRevision = stream.GetMetadata().GetModRevision()
@@ -206,6 +303,7 @@ func (e *entityRepo) OnAddOrUpdate(schemaMetadata schema.Metadata) {
 	e.RWMutex.Lock()
 	defer e.RWMutex.Unlock()
 	e.entitiesMap[id] = partition.EntityLocator{TagLocators: en, ModRevision: modRevision}
+	e.setRollup(id, rollup)
 }
 
 // OnDelete implements schema.EventHandler.
@@ -240,6 +338,32 @@ func (e *entityRepo) OnDelete(schemaMetadata schema.Metadata) {
 	e.RWMutex.Lock()
 	defer e.RWMutex.Unlock()
 	delete(e.entitiesMap, id)
+	e.setRollup(id, nil)
+}
+
+// setRollup records the source measure of the derived one, or forgets it if the rollup is nil.
+func (e *entityRepo) setRollup(id identity, rollup *databasev1.Rollup) {
+	if source, ok := e.rollups[id]; ok {
+		delete(e.rollups, id)
+		if e.rollupSources[source]--; e.rollupSources[source] <= 0 {
+			delete(e.rollupSources, source)
+		}
+	}
+	if rollup == nil {
+		return
+	}
+	source := identity{name: rollup.GetSourceMeasure().GetName(), group: rollup.GetSourceMeasure().GetGroup()}
+	if source.group == "" {
+		source.group = id.group
+	}
+	e.rollups[id] = source
+	e.rollupSources[source]++
+}
+
+func (e *entityRepo) isRollupSource(id identity) bool {
+	e.RWMutex.RLock()
+	defer e.RWMutex.RUnlock()
+	return e.rollupSources[id] > 0
 }
 
 func (e *entityRepo) getLocator(id identity) (partition.EntityLocator, bool) {
@@ -31,10 +31,13 @@ import (
 	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
 	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
 	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/banyand/protector"
 	"github.com/apache/skywalking-banyandb/banyand/queue"
 	"github.com/apache/skywalking-banyandb/pkg/accesslog"
 	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/convert"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/partition"
 	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
 	"github.com/apache/skywalking-banyandb/pkg/timestamp"
 )
@@ -46,6 +49,7 @@ type measureService struct {
 	ingestionAccessLog accesslog.Log
 	pipeline           queue.Client
 	broadcaster        queue.Client
+	pm                 *protector.Memory
 	writeTimeout       time.Duration
 }
 
@@ -89,6 +93,14 @@ func (ms *measureService) Write(measure measurev1.MeasureService_WriteServer) er
 			reply(writeRequest.GetMetadata(), modelv1.Status_STATUS_INVALID_TIMESTAMP, writeRequest.GetMessageId(), measure, ms.sampled)
 			continue
 		}
+		// The data nodes acknowledge the batched writes before applying them,
+		// so the memory limit is checked here for the client to back off.
+		if errMemory := ms.pm.Check(); errMemory != nil {
+			ms.sampled.Warn().Err(errMemory).Stringer("written", writeRequest).Msg("reject the write")
+			reply(writeRequest.GetMetadata(), modelv1.Status_STATUS_MEMORY_EXHAUSTED, writeRequest.GetMessageId(), measure, ms.sampled)
+			continue
+		}
+		ms.resolveAlias(writeRequest.GetMetadata())
 		if writeRequest.Metadata.ModRevision > 0 {
 			measureCache, existed := ms.entityRepo.getLocator(getID(writeRequest.GetMetadata()))
 			if !existed {
@@ -102,7 +114,14 @@ func (ms *measureService) Write(measure measurev1.MeasureService_WriteServer) er
 				continue
 			}
 		}
-		entity, tagValues, shardID, err := ms.navigate(writeRequest.GetMetadata(), writeRequest.GetDataPoint().GetTagFamilies())
+		if errRetention := ms.admit(writeRequest.GetMetadata(), writeRequest.GetDataPoint().GetTimestamp().AsTime()); errRetention != nil {
+			ms.sampled.Error().Err(errRetention).Stringer("written", writeRequest).Msg("the data point time is out of the retention window")
+			reply(writeRequest.GetMetadata(), modelv1.Status_STATUS_OUT_OF_RETENTION, writeRequest.GetMessageId(), measure, ms.sampled)
+			continue
+		}
+		entity, tagValues, shardID, err := ms.navigate(writeRequest.GetMetadata(), writeRequest.GetDataPoint().GetTagFamilies(), partition.ShardingOpts{
+			Salt: convert.Int64ToBytes(writeRequest.GetDataPoint().GetTimestamp().AsTime().UnixNano()),
+		})
 		if err != nil {
 			ms.sampled.Error().Err(err).RawJSON("written", logger.Proto(writeRequest)).Msg("failed to navigate to the write target")
 			reply(writeRequest.GetMetadata(), modelv1.Status_STATUS_INTERNAL_ERROR, writeRequest.GetMessageId(), measure, ms.sampled)
@@ -126,6 +145,18 @@ func (ms *measureService) Write(measure measurev1.MeasureService_WriteServer) er
 			reply(writeRequest.GetMetadata(), modelv1.Status_STATUS_INTERNAL_ERROR, writeRequest.GetMessageId(), measure, ms.sampled)
 			continue
 		}
+		if writeRequest.GetCondition() != nil {
+			st, errCond := ms.writeConditionally(nodeID, iwr)
+			if errCond != nil {
+				ms.sampled.Error().Err(errCond).RawJSON("written", logger.Proto(writeRequest)).Str("nodeID", nodeID).Msg("failed to write conditionally")
+			}
+			if st != modelv1.Status_STATUS_SUCCEED {
+				reply(writeRequest.GetMetadata(), st, writeRequest.GetMessageId(), measure, ms.sampled)
+				continue
+			}
+			reply(nil, st, writeRequest.GetMessageId(), measure, ms.sampled)
+			continue
+		}
 		message := bus.NewBatchMessageWithNode(bus.MessageID(time.Now().UnixNano()), nodeID, iwr)
 		_, errWritePub := publisher.Publish(data.TopicMeasureWrite, message)
 		if errWritePub != nil {
@@ -137,12 +168,34 @@ func (ms *measureService) Write(measure measurev1.MeasureService_WriteServer) er
 	}
 }
 
+// writeConditionally writes a conditional data point at once instead of batching it,
+// so that the status the data node replies with, telling whether the condition holds, reaches the client.
+func (ms *measureService) writeConditionally(nodeID string, iwr *measurev1.InternalWriteRequest) (modelv1.Status, error) {
+	message := bus.NewMessageWithNode(bus.MessageID(time.Now().UnixNano()), nodeID, iwr)
+	f, err := ms.pipeline.Publish(data.TopicMeasureConditionalWrite, message)
+	if err != nil {
+		return modelv1.Status_STATUS_INTERNAL_ERROR, err
+	}
+	msg, err := f.Get()
+	if err != nil {
+		return modelv1.Status_STATUS_INTERNAL_ERROR, err
+	}
+	switch d := msg.Data().(type) {
+	case *measurev1.WriteResponse:
+		return d.GetStatus(), nil
+	case common.Error:
+		return modelv1.Status_STATUS_INTERNAL_ERROR, errors.New(d.Msg())
+	}
+	return modelv1.Status_STATUS_INTERNAL_ERROR, errors.Errorf("invalid response %T", msg.Data())
+}
+
 var emptyMeasureQueryResponse = &measurev1.QueryResponse{DataPoints: make([]*measurev1.DataPoint, 0)}
 
-func (ms *measureService) Query(_ context.Context, req *measurev1.QueryRequest) (*measurev1.QueryResponse, error) {
+func (ms *measureService) Query(ctx context.Context, req *measurev1.QueryRequest) (*measurev1.QueryResponse, error) {
 	if err := timestamp.CheckTimeRange(req.GetTimeRange()); err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "%v is invalid :%s", req.GetTimeRange(), err)
 	}
+	ms.resolveAliases(req.GetGroups())
 	message := bus.NewMessage(bus.MessageID(time.Now().UnixNano()), req)
 	feat, errQuery := ms.broadcaster.Publish(data.TopicMeasureQuery, message)
 	if errQuery != nil {
@@ -151,6 +204,11 @@ func (ms *measureService) Query(_ context.Context, req *measurev1.QueryRequest)
 	msg, errFeat := feat.Get()
 	if errFeat != nil {
 		if errors.Is(errFeat, io.EOF) {
+			if req.GetChecksum() {
+				if err := setChecksumTrailer(ctx, pbv1.MeasureQueryChecksum(nil)); err != nil {
+					return nil, err
+				}
+			}
 			return emptyMeasureQueryResponse, nil
 		}
 		return nil, errFeat
@@ -158,9 +216,17 @@ func (ms *measureService) Query(_ context.Context, req *measurev1.QueryRequest)
 	data := msg.Data()
 	switch d := data.(type) {
 	case *measurev1.QueryResponse:
+		if req.GetChecksum() {
+			if err := pbv1.VerifyMeasureQueryChecksum(d); err != nil {
+				return nil, status.Error(codes.DataLoss, err.Error())
+			}
+			if err := setChecksumTrailer(ctx, d.GetChecksum()); err != nil {
+				return nil, err
+			}
+		}
 		return d, nil
 	case common.Error:
-		return nil, errors.WithMessage(errQueryMsg, d.Msg())
+		return nil, queryError(d)
 	}
 	return nil, nil
 }
@@ -170,6 +236,7 @@ func (ms *measureService) TopN(_ context.Context, topNRequest *measurev1.TopNReq
 		return nil, status.Errorf(codes.InvalidArgument, "%v is invalid :%s", topNRequest.GetTimeRange(), err)
 	}
 
+	ms.resolveAliases(topNRequest.GetGroups())
 	message := bus.NewMessage(bus.MessageID(time.Now().UnixNano()), topNRequest)
 	feat, errQuery := ms.broadcaster.Publish(data.TopicTopNQuery, message)
 	if errQuery != nil {
@@ -184,7 +251,7 @@ func (ms *measureService) TopN(_ context.Context, topNRequest *measurev1.TopNReq
 	case *measurev1.TopNResponse:
 		return d, nil
 	case common.Error:
-		return nil, errors.WithMessage(errQueryMsg, d.Msg())
+		return nil, queryError(d)
 	}
 	return nil, nil
 }
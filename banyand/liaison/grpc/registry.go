@@ -439,6 +439,15 @@ func (rs *groupRegistryServer) Exist(ctx context.Context, req *databasev1.GroupR
 	return nil, err
 }
 
+func (rs *groupRegistryServer) Restore(ctx context.Context, req *databasev1.GroupRegistryServiceRestoreRequest) (
+	*databasev1.GroupRegistryServiceRestoreResponse, error,
+) {
+	if err := rs.schemaRegistry.GroupRegistry().RestoreGroup(ctx, req.GetGroup()); err != nil {
+		return nil, err
+	}
+	return &databasev1.GroupRegistryServiceRestoreResponse{}, nil
+}
+
 type topNAggregationRegistryServer struct {
 	databasev1.UnimplementedTopNAggregationRegistryServiceServer
 	schemaRegistry metadata.Repo
@@ -35,6 +35,7 @@ import (
 	"google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/status"
 
+	"github.com/apache/skywalking-banyandb/api/common"
 	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
 	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
 	propertyv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/property/v1"
@@ -42,6 +43,7 @@ import (
 	"github.com/apache/skywalking-banyandb/banyand/metadata"
 	"github.com/apache/skywalking-banyandb/banyand/metadata/schema"
 	"github.com/apache/skywalking-banyandb/banyand/observability"
+	"github.com/apache/skywalking-banyandb/banyand/protector"
 	"github.com/apache/skywalking-banyandb/banyand/queue"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
 	"github.com/apache/skywalking-banyandb/pkg/run"
@@ -57,6 +59,14 @@ var (
 	errAccessLogRootPath = errors.New("access log root path is required")
 )
 
+// queryError converts the error replied by the query module to a gRPC error.
+func queryError(e common.Error) error {
+	if errors.Is(e.Cause(), common.ErrResourceExhausted) {
+		return status.Error(codes.ResourceExhausted, e.Msg())
+	}
+	return errors.WithMessage(errQueryMsg, e.Msg())
+}
+
 // Server defines the gRPC server.
 type Server interface {
 	run.Unit
@@ -90,7 +100,7 @@ type server struct {
 }
 
 // NewServer returns a new gRPC server.
-func NewServer(_ context.Context, pipeline, broadcaster queue.Client, schemaRegistry metadata.Repo, nodeRegistry NodeRegistry) Server {
+func NewServer(_ context.Context, pipeline, broadcaster queue.Client, schemaRegistry metadata.Repo, nodeRegistry NodeRegistry, pm *protector.Memory) Server {
 	streamSVC := &streamService{
 		discoveryService: newDiscoveryService(schema.KindStream, schemaRegistry, nodeRegistry),
 		pipeline:         pipeline,
@@ -100,6 +110,7 @@ func NewServer(_ context.Context, pipeline, broadcaster queue.Client, schemaRegi
 		discoveryService: newDiscoveryService(schema.KindMeasure, schemaRegistry, nodeRegistry),
 		pipeline:         pipeline,
 		broadcaster:      broadcaster,
+		pm:               pm,
 	}
 	s := &server{
 		streamSVC:  streamSVC,
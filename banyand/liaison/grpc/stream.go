@@ -23,7 +23,9 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
 	"github.com/apache/skywalking-banyandb/api/common"
@@ -35,6 +37,7 @@ import (
 	"github.com/apache/skywalking-banyandb/pkg/accesslog"
 	"github.com/apache/skywalking-banyandb/pkg/bus"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/partition"
 	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
 	"github.com/apache/skywalking-banyandb/pkg/timestamp"
 )
@@ -89,6 +92,7 @@ func (s *streamService) Write(stream streamv1.StreamService_WriteServer) error {
 			reply(nil, modelv1.Status_STATUS_INVALID_TIMESTAMP, writeEntity.GetMessageId(), stream, s.sampled)
 			continue
 		}
+		s.resolveAlias(writeEntity.GetMetadata())
 		if writeEntity.Metadata.ModRevision > 0 {
 			streamCache, existed := s.entityRepo.getLocator(getID(writeEntity.GetMetadata()))
 			if !existed {
@@ -102,7 +106,15 @@ func (s *streamService) Write(stream streamv1.StreamService_WriteServer) error {
 				continue
 			}
 		}
-		entity, tagValues, shardID, err := s.navigate(writeEntity.GetMetadata(), writeEntity.GetElement().GetTagFamilies())
+		if errRetention := s.admit(writeEntity.GetMetadata(), writeEntity.GetElement().GetTimestamp().AsTime()); errRetention != nil {
+			s.sampled.Error().Err(errRetention).Stringer("written", writeEntity).Msg("the element time is out of the retention window")
+			reply(writeEntity.GetMetadata(), modelv1.Status_STATUS_OUT_OF_RETENTION, writeEntity.GetMessageId(), stream, s.sampled)
+			continue
+		}
+		entity, tagValues, shardID, err := s.navigate(writeEntity.GetMetadata(), writeEntity.GetElement().GetTagFamilies(), partition.ShardingOpts{
+			RoutingKey: []byte(writeEntity.GetRoutingKey()),
+			Salt:       []byte(writeEntity.GetElement().GetElementId()),
+		})
 		if err != nil {
 			s.sampled.Error().Err(err).RawJSON("written", logger.Proto(writeEntity)).Msg("failed to navigate to the write target")
 			reply(writeEntity.GetMetadata(), modelv1.Status_STATUS_INTERNAL_ERROR, writeEntity.GetMessageId(), stream, s.sampled)
@@ -139,7 +151,7 @@ func (s *streamService) Write(stream streamv1.StreamService_WriteServer) error {
 
 var emptyStreamQueryResponse = &streamv1.QueryResponse{Elements: make([]*streamv1.Element, 0)}
 
-func (s *streamService) Query(_ context.Context, req *streamv1.QueryRequest) (*streamv1.QueryResponse, error) {
+func (s *streamService) Query(ctx context.Context, req *streamv1.QueryRequest) (*streamv1.QueryResponse, error) {
 	timeRange := req.GetTimeRange()
 	if timeRange == nil {
 		req.TimeRange = timestamp.DefaultTimeRange
@@ -147,10 +159,16 @@ func (s *streamService) Query(_ context.Context, req *streamv1.QueryRequest) (*s
 	if err := timestamp.CheckTimeRange(req.GetTimeRange()); err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "%v is invalid :%s", req.GetTimeRange(), err)
 	}
-	message := bus.NewMessage(bus.MessageID(time.Now().UnixNano()), req)
+	s.resolveAliases(req.GetGroups())
+	message := bus.NewMessage(bus.MessageID(time.Now().UnixNano()), req).WithContext(ctx)
 	feat, errQuery := s.broadcaster.Publish(data.TopicStreamQuery, message)
 	if errQuery != nil {
 		if errors.Is(errQuery, io.EOF) {
+			if req.GetChecksum() {
+				if err := setChecksumTrailer(ctx, pbv1.StreamQueryChecksum(nil)); err != nil {
+					return nil, err
+				}
+			}
 			return emptyStreamQueryResponse, nil
 		}
 		return nil, errQuery
@@ -162,9 +180,20 @@ func (s *streamService) Query(_ context.Context, req *streamv1.QueryRequest) (*s
 	data := msg.Data()
 	switch d := data.(type) {
 	case *streamv1.QueryResponse:
+		if req.GetChecksum() {
+			if err := pbv1.VerifyStreamQueryChecksum(d); err != nil {
+				return nil, status.Error(codes.DataLoss, err.Error())
+			}
+			if err := setChecksumTrailer(ctx, d.GetChecksum()); err != nil {
+				return nil, err
+			}
+		}
+		if req.GetSampleInterval() > 1 {
+			d.SampleRate = 1 / float64(req.GetSampleInterval())
+		}
 		return d, nil
 	case common.Error:
-		return nil, errors.WithMessage(errQueryMsg, d.Msg())
+		return nil, queryError(d)
 	}
 	return nil, nil
 }
@@ -172,3 +201,7 @@ func (s *streamService) Query(_ context.Context, req *streamv1.QueryRequest) (*s
 func (s *streamService) Close() error {
 	return s.ingestionAccessLog.Close()
 }
+
+func setChecksumTrailer(ctx context.Context, checksum uint32) error {
+	return grpc.SetTrailer(ctx, metadata.Pairs(pbv1.ChecksumTrailerKey, pbv1.FormatChecksum(checksum)))
+}
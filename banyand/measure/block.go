@@ -146,7 +146,7 @@ func (b *block) mustWriteTo(sid common.SeriesID, bm *blockMetadata, ww *writers)
 	cc := f.columns
 	cmm := bm.field.resizeColumnMetadata(len(cc))
 	for i := range cc {
-		cc[i].mustWriteTo(&cmm[i], &ww.fieldValuesWriter)
+		cc[i].mustWriteTo(&cmm[i], &ww.fieldValuesWriter, codecDefault)
 	}
 }
 
@@ -181,8 +181,9 @@ func (b *block) marshalTagFamily(tf columnFamily, bm *blockMetadata, ww *writers
 	cc := tf.columns
 	cfm := generateColumnFamilyMetadata()
 	cmm := cfm.resizeColumnMetadata(len(cc))
+	codec := ww.tagFamilyCodecs[tf.name]
 	for i := range cc {
-		cc[i].mustWriteTo(&cmm[i], w)
+		cc[i].mustWriteTo(&cmm[i], w, codec)
 	}
 	bb := bigValuePool.Generate()
 	defer bigValuePool.Release(bb)
@@ -198,7 +199,7 @@ func (b *block) marshalTagFamily(tf columnFamily, bm *blockMetadata, ww *writers
 }
 
 func (b *block) unmarshalTagFamily(decoder *encoding.BytesBlockDecoder, tfIndex int, name string,
-	columnFamilyMetadataBlock *dataBlock, tagProjection []string, metaReader, valueReader fs.Reader,
+	columnFamilyMetadataBlock *dataBlock, tagProjection []string, metaReader, valueReader fs.Reader, codec valuesCodec,
 ) {
 	if len(tagProjection) < 1 {
 		return
@@ -218,7 +219,7 @@ func (b *block) unmarshalTagFamily(decoder *encoding.BytesBlockDecoder, tfIndex
 	for j := range tagProjection {
 		for i := range cfm.columnMetadata {
 			if tagProjection[j] == cfm.columnMetadata[i].name {
-				cc[j].mustReadValues(decoder, valueReader, cfm.columnMetadata[i], uint64(b.Len()))
+				cc[j].mustReadValues(decoder, valueReader, cfm.columnMetadata[i], uint64(b.Len()), codec)
 				break
 			}
 		}
@@ -226,7 +227,7 @@ func (b *block) unmarshalTagFamily(decoder *encoding.BytesBlockDecoder, tfIndex
 }
 
 func (b *block) unmarshalTagFamilyFromSeqReaders(decoder *encoding.BytesBlockDecoder, tfIndex int, name string,
-	columnFamilyMetadataBlock *dataBlock, metaReader, valueReader *seqReader,
+	columnFamilyMetadataBlock *dataBlock, metaReader, valueReader *seqReader, codec valuesCodec,
 ) {
 	if columnFamilyMetadataBlock.offset != metaReader.bytesRead {
 		logger.Panicf("offset %d must be equal to bytesRead %d", columnFamilyMetadataBlock.offset, metaReader.bytesRead)
@@ -245,7 +246,7 @@ func (b *block) unmarshalTagFamilyFromSeqReaders(decoder *encoding.BytesBlockDec
 
 	cc := b.tagFamilies[tfIndex].resizeColumns(len(cfm.columnMetadata))
 	for i := range cfm.columnMetadata {
-		cc[i].mustSeqReadValues(decoder, valueReader, cfm.columnMetadata[i], uint64(b.Len()))
+		cc[i].mustSeqReadValues(decoder, valueReader, cfm.columnMetadata[i], uint64(b.Len()), codec)
 	}
 }
 
@@ -288,7 +289,7 @@ func (b *block) mustReadFrom(decoder *encoding.BytesBlockDecoder, p *part, bm bl
 
 	cc := b.field.resizeColumns(len(bm.field.columnMetadata))
 	for i := range cc {
-		cc[i].mustReadValues(decoder, p.fieldValues, bm.field.columnMetadata[i], bm.count)
+		cc[i].mustReadValues(decoder, p.fieldValues, bm.field.columnMetadata[i], bm.count, codecDefault)
 	}
 
 	_ = b.resizeTagFamilies(len(bm.tagProjection))
@@ -300,18 +301,19 @@ func (b *block) mustReadFrom(decoder *encoding.BytesBlockDecoder, p *part, bm bl
 		}
 		b.unmarshalTagFamily(decoder, i, name, block,
 			bm.tagProjection[i].Names, p.tagFamilyMetadata[name],
-			p.tagFamilies[name])
+			p.tagFamilies[name], p.partMetadata.TagFamilyCodecs[name])
 	}
 }
 
-func (b *block) mustSeqReadFrom(decoder *encoding.BytesBlockDecoder, seqReaders *seqReaders, bm blockMetadata) {
+// mustSeqReadFrom reads the block sequentially, the tag families are decoded by the codecs of the part.
+func (b *block) mustSeqReadFrom(decoder *encoding.BytesBlockDecoder, seqReaders *seqReaders, bm blockMetadata, codecs map[string]valuesCodec) {
 	b.reset()
 
 	b.timestamps = mustSeqReadTimestampsFrom(b.timestamps, &bm.timestamps, int(bm.count), &seqReaders.timestamps)
 
 	cc := b.field.resizeColumns(len(bm.field.columnMetadata))
 	for i := range cc {
-		cc[i].mustSeqReadValues(decoder, &seqReaders.fieldValues, bm.field.columnMetadata[i], bm.count)
+		cc[i].mustSeqReadValues(decoder, &seqReaders.fieldValues, bm.field.columnMetadata[i], bm.count, codecDefault)
 	}
 	_ = b.resizeTagFamilies(len(bm.tagFamilies))
 	keys := make([]string, 0, len(bm.tagFamilies))
@@ -322,7 +324,7 @@ func (b *block) mustSeqReadFrom(decoder *encoding.BytesBlockDecoder, seqReaders
 	for i, name := range keys {
 		block := bm.tagFamilies[name]
 		b.unmarshalTagFamilyFromSeqReaders(decoder, i, name, block,
-			seqReaders.tagFamilyMetadata[name], seqReaders.tagFamilies[name])
+			seqReaders.tagFamilyMetadata[name], seqReaders.tagFamilies[name], codecs[name])
 	}
 }
 
@@ -783,8 +785,8 @@ func assertIdxAndOffset(name string, length int, idx int, offset int) {
 	}
 }
 
-func (bi *blockPointer) isFull() bool {
-	return bi.bm.count >= maxBlockLength || bi.bm.uncompressedSizeBytes >= maxUncompressedBlockSize
+func (bi *blockPointer) isFull(blockSize int) bool {
+	return bi.bm.count >= uint64(blockSize) || bi.bm.uncompressedSizeBytes >= maxUncompressedBlockSize
 }
 
 func (bi *blockPointer) reset() {
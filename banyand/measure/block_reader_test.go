@@ -127,7 +127,7 @@ func Test_blockReader_nextBlock(t *testing.T) {
 				for _, dps := range tt.dpsList {
 					mp := generateMemPart()
 					mpp = append(mpp, mp)
-					mp.mustInitFromDataPoints(dps)
+					mp.mustInitFromDataPoints(dps, maxBlockLength, nil)
 					pp = append(pp, openMemPart(mp))
 				}
 				verify(pp)
@@ -151,7 +151,7 @@ func Test_blockReader_nextBlock(t *testing.T) {
 				for i, dps := range tt.dpsList {
 					mp := generateMemPart()
 					mpp = append(mpp, mp)
-					mp.mustInitFromDataPoints(dps)
+					mp.mustInitFromDataPoints(dps, maxBlockLength, nil)
 					mp.mustFlush(fileSystem, partPath(tmpPath, uint64(i)))
 					filePW := newPartWrapper(nil, mustOpenFilePart(uint64(i), tmpPath, fileSystem))
 					filePW.p.partMetadata.ID = uint64(i)
@@ -0,0 +1,172 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/pkg/convert"
+	"github.com/apache/skywalking-banyandb/pkg/fs"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/test"
+)
+
+func generateSeqDps(startTimestamp, endTimestamp int64) *dataPoints {
+	dps := &dataPoints{}
+	for i := startTimestamp; i <= endTimestamp; i++ {
+		dps.seriesIDs = append(dps.seriesIDs, 1)
+		dps.timestamps = append(dps.timestamps, i)
+		dps.tagFamilies = append(dps.tagFamilies, []nameValues{{
+			name: "singleTag", values: []*nameValue{
+				{name: "intTag", valueType: pbv1.ValueTypeInt64, value: convert.Int64ToBytes(i)},
+			},
+		}})
+		dps.fields = append(dps.fields, nameValues{
+			name: "skipped", values: []*nameValue{
+				{name: "intField", valueType: pbv1.ValueTypeInt64, value: convert.Int64ToBytes(i * 10)},
+			},
+		})
+	}
+	return dps
+}
+
+func readPartBlocks(t *testing.T, p *part) (counts []uint64, timestamps, values []int64) {
+	pmi := &partMergeIter{}
+	pmi.mustInitFromPart(p)
+	reader := &blockReader{}
+	reader.init([]*partMergeIter{pmi})
+	decoder := generateColumnValuesDecoder()
+	defer releaseColumnValuesDecoder(decoder)
+	for reader.nextBlockMetadata() {
+		counts = append(counts, reader.block.bm.count)
+		reader.loadBlockData(decoder)
+		timestamps = append(timestamps, reader.block.timestamps...)
+		require.Len(t, reader.block.field.columns, 1)
+		for _, v := range reader.block.field.columns[0].values {
+			values = append(values, convert.BytesToInt64(v))
+		}
+	}
+	require.NoError(t, reader.error())
+	return counts, timestamps, values
+}
+
+func TestBlockSizeChangedMidStream(t *testing.T) {
+	tmpPath, defFn := test.Space(require.New(t))
+	defer defFn()
+	fileSystem := fs.NewLocalFileSystem()
+
+	newFilePart := func(id uint64, dps *dataPoints, blockSize int, wantCounts []uint64) *partWrapper {
+		mp := generateMemPart()
+		defer releaseMemPart(mp)
+		mp.mustInitFromDataPoints(dps, blockSize, nil)
+		mp.mustFlush(fileSystem, partPath(tmpPath, id))
+		// Part readers are sequential, so verify the blocks on a separate instance.
+		p := mustOpenFilePart(id, tmpPath, fileSystem)
+		counts, _, _ := readPartBlocks(t, p)
+		assert.Equal(t, wantCounts, counts)
+		assert.Equal(t, blockSize, p.partMetadata.BlockSize)
+		p.close()
+		pw := newPartWrapper(nil, mustOpenFilePart(id, tmpPath, fileSystem))
+		pw.p.partMetadata.ID = id
+		return pw
+	}
+	// The group starts with a block size of 4, and is then reconfigured to 8.
+	before := newFilePart(1, generateSeqDps(1, 10), 4, []uint64{4, 4, 2})
+	defer before.decRef()
+	after := newFilePart(2, generateSeqDps(11, 20), 8, []uint64{8, 2})
+	defer after.decRef()
+
+	// Merging the mixed parts under yet another block size must keep every data point.
+	closeCh := make(chan struct{})
+	defer close(closeCh)
+	merged, err := mergeParts(fileSystem, closeCh, []*partWrapper{before, after}, 3, tmpPath, 3, nil)
+	require.NoError(t, err)
+	defer merged.decRef()
+	assert.Equal(t, 3, merged.p.partMetadata.BlockSize)
+
+	_, timestamps, values := readPartBlocks(t, merged.p)
+	wantTimestamps := make([]int64, 0, 20)
+	wantValues := make([]int64, 0, 20)
+	for i := int64(1); i <= 20; i++ {
+		wantTimestamps = append(wantTimestamps, i)
+		wantValues = append(wantValues, i*10)
+	}
+	assert.Equal(t, wantTimestamps, timestamps)
+	assert.Equal(t, wantValues, values)
+}
+
+func BenchmarkBlockSize(b *testing.B) {
+	const total = 1 << 16
+	dps := generateSeqDps(1, total)
+	sids := []common.SeriesID{1}
+	qo := queryOptions{}
+	qo.FieldProjection = []string{"intField"}
+	for _, blockSize := range []int{128, 1024, maxBlockLength} {
+		tmpPath, defFn := test.Space(require.New(b))
+		fileSystem := fs.NewLocalFileSystem()
+		mp := generateMemPart()
+		mp.mustInitFromDataPoints(dps, blockSize, nil)
+		mp.mustFlush(fileSystem, partPath(tmpPath, 1))
+		releaseMemPart(mp)
+		p := mustOpenFilePart(1, tmpPath, fileSystem)
+
+		load := func(minTimestamp, maxTimestamp int64) int {
+			bma := generateBlockMetadataArray()
+			defer releaseBlockMetadataArray(bma)
+			var pi partIter
+			pi.init(bma, p, sids, minTimestamp, maxTimestamp)
+			bc := generateBlockCursor()
+			defer releaseBlockCursor(bc)
+			tmpBlock := generateBlock()
+			defer releaseBlock(tmpBlock)
+			qo.minTimestamp, qo.maxTimestamp = minTimestamp, maxTimestamp
+			n := 0
+			for pi.nextBlock() {
+				bc.init(p, pi.curBlock, qo)
+				if bc.loadData(tmpBlock) {
+					n += len(bc.timestamps)
+				}
+			}
+			return n
+		}
+		b.Run(fmt.Sprintf("scan/blockSize=%d", blockSize), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if n := load(1, total); n != total {
+					b.Fatalf("unexpected number of data points: got %d; want %d", n, total)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("point/blockSize=%d", blockSize), func(b *testing.B) {
+			r := rand.New(rand.NewSource(int64(blockSize)))
+			for i := 0; i < b.N; i++ {
+				ts := r.Int63n(total) + 1
+				if n := load(ts, ts); n != 1 {
+					b.Fatalf("unexpected number of data points: got %d; want 1", n)
+				}
+			}
+		})
+		p.close()
+		defFn()
+	}
+}
@@ -320,7 +320,7 @@ func Test_marshalAndUnmarshalTagFamily(t *testing.T) {
 	unmarshaled.timestamps = make([]int64, len(b.timestamps))
 	unmarshaled.resizeTagFamilies(1)
 
-	unmarshaled.unmarshalTagFamily(decoder, tfIndex, name, bm.getTagFamilyMetadata(name), tagProjection[name], metaBuffer, dataBuffer)
+	unmarshaled.unmarshalTagFamily(decoder, tfIndex, name, bm.getTagFamilyMetadata(name), tagProjection[name], metaBuffer, dataBuffer, codecDefault)
 
 	if diff := cmp.Diff(unmarshaled.tagFamilies[0], b.tagFamilies[0],
 		cmp.AllowUnexported(columnFamily{}, column{}),
@@ -340,7 +340,7 @@ func Test_marshalAndUnmarshalTagFamily(t *testing.T) {
 	defer releaseSeqReader(valueReader)
 	valueReader.init(dataBuffer)
 
-	unmarshaled2.unmarshalTagFamilyFromSeqReaders(decoder, tfIndex, name, bm.getTagFamilyMetadata(name), metaReader, valueReader)
+	unmarshaled2.unmarshalTagFamilyFromSeqReaders(decoder, tfIndex, name, bm.getTagFamilyMetadata(name), metaReader, valueReader, codecDefault)
 
 	if diff := cmp.Diff(unmarshaled2.tagFamilies[0], b.tagFamilies[0],
 		cmp.AllowUnexported(columnFamily{}, column{}),
@@ -411,7 +411,7 @@ func Test_marshalAndUnmarshalBlock(t *testing.T) {
 	sr.init(p)
 	defer sr.reset()
 
-	unmarshaled2.mustSeqReadFrom(decoder, &sr, bm)
+	unmarshaled2.mustSeqReadFrom(decoder, &sr, bm, nil)
 	if !reflect.DeepEqual(b, unmarshaled2) {
 		t.Errorf("block.mustSeqReadFrom() = %+v, want %+v", unmarshaled, b)
 	}
@@ -65,12 +65,14 @@ type writers struct {
 	primaryWriter              writer
 	tagFamilyMetadataWriters   map[string]*writer
 	tagFamilyWriters           map[string]*writer
+	tagFamilyCodecs            map[string]valuesCodec
 	timestampsWriter           writer
 	fieldValuesWriter          writer
 }
 
 func (sw *writers) reset() {
 	sw.mustCreateTagFamilyWriters = nil
+	sw.tagFamilyCodecs = nil
 	sw.metaWriter.reset()
 	sw.primaryWriter.reset()
 	sw.timestampsWriter.reset()
@@ -186,6 +188,11 @@ func (bw *blockWriter) mustInitForFilePart(fileSystem fs.FileSystem, path string
 	bw.writers.fieldValuesWriter.init(fs.MustCreateFile(fileSystem, filepath.Join(path, fieldValuesFilename), filePermission))
 }
 
+// setTagFamilyCodecs sets the codecs of the tag families, the others are encoded by codecDefault.
+func (bw *blockWriter) setTagFamilyCodecs(codecs map[string]valuesCodec) {
+	bw.writers.tagFamilyCodecs = codecs
+}
+
 func (bw *blockWriter) MustWriteDataPoints(sid common.SeriesID, timestamps []int64, tagFamilies [][]nameValues, fields []nameValues) {
 	if len(timestamps) == 0 {
 		return
@@ -270,6 +277,15 @@ func (bw *blockWriter) Flush(pm *partMetadata) {
 	bigValuePool.Release(bb)
 
 	pm.CompressedSizeBytes = bw.writers.totalBytesWritten()
+	pm.TagFamilyCodecs = nil
+	for name := range bw.writers.tagFamilyWriters {
+		if codec := bw.writers.tagFamilyCodecs[name]; codec != codecDefault {
+			if pm.TagFamilyCodecs == nil {
+				pm.TagFamilyCodecs = make(map[string]valuesCodec)
+			}
+			pm.TagFamilyCodecs[name] = codec
+		}
+	}
 
 	bw.writers.MustClose()
 	bw.reset()
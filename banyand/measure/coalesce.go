@@ -0,0 +1,98 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"google.golang.org/protobuf/proto"
+
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+)
+
+// coalescedResult coalesces the runs of equal-value points in every pulled result.
+// A pulled result holds the points of a single series. A run split across two results starts over
+// in the second one, which still reconstructs the same step function.
+type coalescedResult struct {
+	pbv1.MeasureQueryResult
+	emitEnd bool
+}
+
+func (cr *coalescedResult) Pull() *pbv1.MeasureResult {
+	r := cr.MeasureQueryResult.Pull()
+	if r == nil {
+		return nil
+	}
+	coalesce(r, cr.emitEnd)
+	return r
+}
+
+// coalesce keeps the first point of every run of consecutive points whose tags and fields are equal.
+func coalesce(r *pbv1.MeasureResult, emitEnd bool) {
+	if len(r.Timestamps) == 0 {
+		return
+	}
+	if emitEnd {
+		r.EndTimestamps = make([]int64, 0, len(r.Timestamps))
+	}
+	n := 0
+	for i := range r.Timestamps {
+		if i > 0 && equalPoints(r, n-1, i) {
+			if emitEnd {
+				r.EndTimestamps[n-1] = r.Timestamps[i]
+			}
+			continue
+		}
+		r.Timestamps[n] = r.Timestamps[i]
+		for j := range r.TagFamilies {
+			for k := range r.TagFamilies[j].Tags {
+				r.TagFamilies[j].Tags[k].Values[n] = r.TagFamilies[j].Tags[k].Values[i]
+			}
+		}
+		for j := range r.Fields {
+			r.Fields[j].Values[n] = r.Fields[j].Values[i]
+		}
+		if emitEnd {
+			r.EndTimestamps = append(r.EndTimestamps, r.Timestamps[i])
+		}
+		n++
+	}
+	r.Timestamps = r.Timestamps[:n]
+	for j := range r.TagFamilies {
+		for k := range r.TagFamilies[j].Tags {
+			r.TagFamilies[j].Tags[k].Values = r.TagFamilies[j].Tags[k].Values[:n]
+		}
+	}
+	for j := range r.Fields {
+		r.Fields[j].Values = r.Fields[j].Values[:n]
+	}
+}
+
+func equalPoints(r *pbv1.MeasureResult, i, j int) bool {
+	for _, tf := range r.TagFamilies {
+		for _, t := range tf.Tags {
+			if !proto.Equal(t.Values[i], t.Values[j]) {
+				return false
+			}
+		}
+	}
+	for _, f := range r.Fields {
+		if !proto.Equal(f.Values[i], f.Values[j]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+)
+
+func newGaugeResult(values []int64) *pbv1.MeasureResult {
+	r := &pbv1.MeasureResult{
+		SID:         1,
+		TagFamilies: []pbv1.TagFamily{{Name: "default", Tags: []pbv1.Tag{{Name: "svc"}}}},
+		Fields:      []pbv1.Field{{Name: "gauge"}},
+	}
+	for i, v := range values {
+		r.Timestamps = append(r.Timestamps, int64(i+1)*10)
+		r.TagFamilies[0].Tags[0].Values = append(r.TagFamilies[0].Tags[0].Values, strTagValue("svc-1"))
+		r.Fields[0].Values = append(r.Fields[0].Values, int64FieldValue(v))
+	}
+	return r
+}
+
+// stepValue returns the value of the step function defined by the points at the timestamp.
+func stepValue(r *pbv1.MeasureResult, ts int64) int64 {
+	var v int64
+	for i := range r.Timestamps {
+		if r.Timestamps[i] > ts {
+			break
+		}
+		v = r.Fields[0].Values[i].GetInt().GetValue()
+	}
+	return v
+}
+
+func TestCoalesce(t *testing.T) {
+	var values []int64
+	for _, run := range []struct{ value, length int64 }{{3, 50}, {7, 1}, {3, 120}, {0, 30}, {5, 1}} {
+		for i := int64(0); i < run.length; i++ {
+			values = append(values, run.value)
+		}
+	}
+	original := newGaugeResult(values)
+
+	for _, emitEnd := range []bool{false, true} {
+		r := newGaugeResult(values)
+		coalesce(r, emitEnd)
+		require.Len(t, r.Timestamps, 5)
+		require.Len(t, r.Fields[0].Values, 5)
+		require.Len(t, r.TagFamilies[0].Tags[0].Values, 5)
+		assert.Equal(t, []int64{10, 510, 520, 1720, 2020}, r.Timestamps, "a run keeps its start timestamp")
+		for _, ts := range original.Timestamps {
+			assert.Equal(t, stepValue(original, ts), stepValue(r, ts), "the step function at %d", ts)
+		}
+		if emitEnd {
+			assert.Equal(t, []int64{500, 510, 1710, 2010, 2020}, r.EndTimestamps)
+		} else {
+			assert.Nil(t, r.EndTimestamps)
+		}
+	}
+
+	t.Run("a change of tags splits a run", func(t *testing.T) {
+		r := newGaugeResult([]int64{1, 1, 1})
+		r.TagFamilies[0].Tags[0].Values[1] = strTagValue("svc-2")
+		coalesce(r, false)
+		assert.Equal(t, []int64{10, 20, 30}, r.Timestamps)
+	})
+}
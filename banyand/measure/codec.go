@@ -0,0 +1,176 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/apache/skywalking-banyandb/pkg/encoding"
+)
+
+// valuesCodec encodes the values of a column. The codec of a tag family is recorded in the metadata of the part.
+type valuesCodec byte
+
+const (
+	// codecDefault stores the values as a bytes block, which is compressed if it isn't too small.
+	// The parts written before the codecs are recorded are decoded with it.
+	codecDefault valuesCodec = iota
+	// codecDictionary stores the distinct values once, followed by the index of every value.
+	// It suits the tags of a low cardinality.
+	codecDictionary
+	// codecZSTD compresses the bytes block of the values regardless of its size.
+	codecZSTD
+)
+
+var (
+	codecNames = map[valuesCodec]string{
+		codecDefault:    "default",
+		codecDictionary: "dictionary",
+		codecZSTD:       "zstd",
+	}
+	errUnknownCodec = errors.New("unknown values codec")
+)
+
+func (c valuesCodec) String() string {
+	if name, ok := codecNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("codec(%d)", byte(c))
+}
+
+func parseValuesCodec(name string) (valuesCodec, error) {
+	for c, n := range codecNames {
+		if n == name {
+			return c, nil
+		}
+	}
+	return codecDefault, errors.Wrapf(errUnknownCodec, "%q", name)
+}
+
+// parseTagFamilyCodecs parses the comma-separated codecs of the tag families, such as "default=dictionary,searchable=zstd".
+func parseTagFamilyCodecs(s string) (map[string]valuesCodec, error) {
+	if s == "" {
+		return nil, nil
+	}
+	codecs := make(map[string]valuesCodec)
+	for _, pair := range strings.Split(s, ",") {
+		family, name, ok := strings.Cut(pair, "=")
+		if !ok || family == "" {
+			return nil, errors.Errorf("invalid tag family codec %q, it should be family=codec", pair)
+		}
+		c, err := parseValuesCodec(name)
+		if err != nil {
+			return nil, err
+		}
+		if c != codecDefault {
+			codecs[family] = c
+		}
+	}
+	return codecs, nil
+}
+
+func (c valuesCodec) encode(dst []byte, values [][]byte) []byte {
+	switch c {
+	case codecDictionary:
+		return encodeDictionary(dst, values)
+	case codecZSTD:
+		return encoding.EncodeBytesBlockWithThreshold(dst, values, 1)
+	default:
+		return encoding.EncodeBytesBlock(dst, values)
+	}
+}
+
+func (c valuesCodec) decode(decoder *encoding.BytesBlockDecoder, dst [][]byte, src []byte, count uint64) ([][]byte, error) {
+	switch c {
+	case codecDefault, codecZSTD:
+		return decoder.Decode(dst, src, count)
+	case codecDictionary:
+		return decodeDictionary(decoder, dst, src, count)
+	default:
+		return dst, errors.WithMessagef(errUnknownCodec, "%s", c)
+	}
+}
+
+// encodeDictionary appends the number of the distinct values, the size of their bytes block,
+// the bytes block, then the index of every value in the distinct ones.
+func encodeDictionary(dst []byte, values [][]byte) []byte {
+	indexes := make(map[string]uint64)
+	var dictionary [][]byte
+	positions := make([]uint64, len(values))
+	for i, v := range values {
+		idx, ok := indexes[string(v)]
+		if !ok {
+			idx = uint64(len(dictionary))
+			indexes[string(v)] = idx
+			dictionary = append(dictionary, v)
+		}
+		positions[i] = idx
+	}
+	block := encoding.EncodeBytesBlock(nil, dictionary)
+	dst = encoding.VarUint64ToBytes(dst, uint64(len(dictionary)))
+	dst = encoding.VarUint64ToBytes(dst, uint64(len(block)))
+	dst = append(dst, block...)
+	return encoding.VarUint64sToBytes(dst, positions)
+}
+
+func decodeDictionary(decoder *encoding.BytesBlockDecoder, dst [][]byte, src []byte, count uint64) ([][]byte, error) {
+	src, dictionaryLen, err := encoding.BytesToVarUint64(src)
+	if err != nil {
+		return dst, fmt.Errorf("cannot decode the dictionary size: %w", err)
+	}
+	src, blockLen, err := encoding.BytesToVarUint64(src)
+	if err != nil {
+		return dst, fmt.Errorf("cannot decode the dictionary block size: %w", err)
+	}
+	if uint64(len(src)) < blockLen {
+		return dst, fmt.Errorf("cannot read the dictionary block with the size %d bytes from %d bytes", blockLen, len(src))
+	}
+	dictionary, err := decoder.Decode(nil, src[:blockLen], dictionaryLen)
+	if err != nil {
+		return dst, fmt.Errorf("cannot decode the dictionary: %w", err)
+	}
+	positions := make([]uint64, count)
+	tail, err := encoding.BytesToVarUint64s(positions, src[blockLen:])
+	if err != nil {
+		return dst, fmt.Errorf("cannot decode the dictionary indexes: %w", err)
+	}
+	if len(tail) > 0 {
+		return dst, fmt.Errorf("unexpected non-empty tail after the dictionary indexes; len(tail)=%d", len(tail))
+	}
+	for _, idx := range positions {
+		if idx >= dictionaryLen {
+			return dst, fmt.Errorf("the dictionary index %d exceeds the dictionary size %d", idx, dictionaryLen)
+		}
+		dst = append(dst, dictionary[idx])
+	}
+	return dst, nil
+}
+
+// sortedCodecFamilies returns the tag families of the codecs in order, so that they're marshaled deterministically.
+func sortedCodecFamilies(codecs map[string]valuesCodec) []string {
+	families := make([]string, 0, len(codecs))
+	for family := range codecs {
+		families = append(families, family)
+	}
+	sort.Strings(families)
+	return families
+}
@@ -0,0 +1,142 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/pkg/convert"
+	"github.com/apache/skywalking-banyandb/pkg/encoding"
+	"github.com/apache/skywalking-banyandb/pkg/fs"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/test"
+)
+
+func generateCodecDps(count int) *dataPoints {
+	dps := &dataPoints{}
+	for i := 0; i < count; i++ {
+		dps.seriesIDs = append(dps.seriesIDs, 1)
+		dps.timestamps = append(dps.timestamps, int64(i+1))
+		dps.tagFamilies = append(dps.tagFamilies, []nameValues{
+			{name: "status", values: []*nameValue{
+				{name: "code", valueType: pbv1.ValueTypeStr, value: []byte(fmt.Sprintf("code-%d", i%3))},
+			}},
+			{name: "trace", values: []*nameValue{
+				{name: "id", valueType: pbv1.ValueTypeStr, value: []byte(fmt.Sprintf("trace-%d", i))},
+			}},
+		})
+		dps.fields = append(dps.fields, nameValues{
+			name: "skipped", values: []*nameValue{
+				{name: "intField", valueType: pbv1.ValueTypeInt64, value: convert.Int64ToBytes(int64(i))},
+			},
+		})
+	}
+	return dps
+}
+
+// readTagValues reads the values of every tag sequentially, keyed by the family and the tag.
+func readTagValues(t *testing.T, p *part) map[string][]string {
+	pmi := &partMergeIter{}
+	pmi.mustInitFromPart(p)
+	reader := &blockReader{}
+	reader.init([]*partMergeIter{pmi})
+	decoder := generateColumnValuesDecoder()
+	defer releaseColumnValuesDecoder(decoder)
+	values := make(map[string][]string)
+	for reader.nextBlockMetadata() {
+		reader.loadBlockData(decoder)
+		for _, tf := range reader.block.tagFamilies {
+			for _, c := range tf.columns {
+				for _, v := range c.values {
+					values[tf.name+"."+c.name] = append(values[tf.name+"."+c.name], string(v))
+				}
+			}
+		}
+	}
+	require.NoError(t, reader.error())
+	return values
+}
+
+func TestTagFamilyCodecs(t *testing.T) {
+	tmpPath, defFn := test.Space(require.New(t))
+	defer defFn()
+	fileSystem := fs.NewLocalFileSystem()
+	dps := generateCodecDps(100)
+	want := make(map[string][]string)
+	for i := range dps.timestamps {
+		for _, tf := range dps.tagFamilies[i] {
+			want[tf.name+"."+tf.values[0].name] = append(want[tf.name+"."+tf.values[0].name], string(tf.values[0].value))
+		}
+	}
+
+	codecs := map[string]valuesCodec{"status": codecDictionary, "trace": codecZSTD}
+	mp := generateMemPart()
+	mp.mustInitFromDataPoints(dps, maxBlockLength, codecs)
+	mp.mustFlush(fileSystem, partPath(tmpPath, 1))
+	releaseMemPart(mp)
+	// Part readers are sequential, so verify the values on a separate instance.
+	p := mustOpenFilePart(1, tmpPath, fileSystem)
+	recorded := p.partMetadata.TagFamilyCodecs
+	assert.Equal(t, codecs, recorded)
+	assert.NotEqual(t, recorded["status"], recorded["trace"])
+	assert.Equal(t, want, readTagValues(t, p))
+	p.close()
+	pw := newPartWrapper(nil, mustOpenFilePart(1, tmpPath, fileSystem))
+	defer pw.decRef()
+
+	// The merged part is encoded by the codecs of the table, regardless of the ones of its sources.
+	closeCh := make(chan struct{})
+	defer close(closeCh)
+	merged, err := mergeParts(fileSystem, closeCh, []*partWrapper{pw}, 2, tmpPath, maxBlockLength, nil)
+	require.NoError(t, err)
+	defer merged.decRef()
+	assert.Empty(t, merged.p.partMetadata.TagFamilyCodecs)
+	assert.Equal(t, want, readTagValues(t, merged.p))
+}
+
+func TestValuesCodecs(t *testing.T) {
+	values := [][]byte{[]byte("a"), []byte("b"), []byte("a"), nil, []byte("b"), []byte("a")}
+	for _, codec := range []valuesCodec{codecDefault, codecDictionary, codecZSTD} {
+		t.Run(codec.String(), func(t *testing.T) {
+			var decoder encoding.BytesBlockDecoder
+			got, err := codec.decode(&decoder, nil, codec.encode(nil, values), uint64(len(values)))
+			require.NoError(t, err)
+			require.Len(t, got, len(values))
+			for i := range values {
+				assert.Equal(t, string(values[i]), string(got[i]))
+			}
+		})
+	}
+	var decoder encoding.BytesBlockDecoder
+	_, err := valuesCodec(42).decode(&decoder, nil, codecDefault.encode(nil, values), uint64(len(values)))
+	assert.ErrorIs(t, err, errUnknownCodec)
+}
+
+func TestParseTagFamilyCodecs(t *testing.T) {
+	codecs, err := parseTagFamilyCodecs("status=dictionary,trace=zstd,default=default")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]valuesCodec{"status": codecDictionary, "trace": codecZSTD}, codecs)
+	for _, invalid := range []string{"status", "=zstd", "status=lz4"} {
+		_, err = parseTagFamilyCodecs(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
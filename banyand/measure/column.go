@@ -51,7 +51,7 @@ func (c *column) resizeValues(valuesLen int) [][]byte {
 	return values
 }
 
-func (c *column) mustWriteTo(cm *columnMetadata, columnWriter *writer) {
+func (c *column) mustWriteTo(cm *columnMetadata, columnWriter *writer, codec valuesCodec) {
 	cm.reset()
 
 	cm.name = c.name
@@ -63,7 +63,7 @@ func (c *column) mustWriteTo(cm *columnMetadata, columnWriter *writer) {
 	defer bigValuePool.Release(bb)
 
 	// marshal values
-	bb.Buf = encoding.EncodeBytesBlock(bb.Buf[:0], c.values)
+	bb.Buf = codec.encode(bb.Buf[:0], c.values)
 	cm.size = uint64(len(bb.Buf))
 	if cm.size > maxValuesBlockSize {
 		logger.Panicf("too valuesSize: %d bytes; mustn't exceed %d bytes", cm.size, maxValuesBlockSize)
@@ -72,7 +72,7 @@ func (c *column) mustWriteTo(cm *columnMetadata, columnWriter *writer) {
 	columnWriter.MustWrite(bb.Buf)
 }
 
-func (c *column) mustReadValues(decoder *encoding.BytesBlockDecoder, reader fs.Reader, cm columnMetadata, count uint64) {
+func (c *column) mustReadValues(decoder *encoding.BytesBlockDecoder, reader fs.Reader, cm columnMetadata, count uint64, codec valuesCodec) {
 	c.name = cm.name
 	c.valueType = cm.valueType
 
@@ -85,13 +85,13 @@ func (c *column) mustReadValues(decoder *encoding.BytesBlockDecoder, reader fs.R
 	bb.Buf = bytes.ResizeOver(bb.Buf, int(valuesSize))
 	fs.MustReadData(reader, int64(cm.offset), bb.Buf)
 	var err error
-	c.values, err = decoder.Decode(c.values[:0], bb.Buf, count)
+	c.values, err = codec.decode(decoder, c.values[:0], bb.Buf, count)
 	if err != nil {
 		logger.Panicf("%s: cannot decode values: %v", reader.Path(), err)
 	}
 }
 
-func (c *column) mustSeqReadValues(decoder *encoding.BytesBlockDecoder, reader *seqReader, cm columnMetadata, count uint64) {
+func (c *column) mustSeqReadValues(decoder *encoding.BytesBlockDecoder, reader *seqReader, cm columnMetadata, count uint64, codec valuesCodec) {
 	c.name = cm.name
 	c.valueType = cm.valueType
 	if cm.offset != reader.bytesRead {
@@ -108,7 +108,7 @@ func (c *column) mustSeqReadValues(decoder *encoding.BytesBlockDecoder, reader *
 	bb.Buf = bytes.ResizeOver(bb.Buf, int(valuesSize))
 	reader.mustReadFull(bb.Buf)
 	var err error
-	c.values, err = decoder.Decode(c.values[:0], bb.Buf, count)
+	c.values, err = codec.decode(decoder, c.values[:0], bb.Buf, count)
 	if err != nil {
 		logger.Panicf("%s: cannot decode values: %v", reader.Path(), err)
 	}
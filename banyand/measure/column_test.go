@@ -66,7 +66,7 @@ func TestColumn_mustWriteTo_mustReadValues(t *testing.T) {
 	buf := &bytes.Buffer{}
 	w := &writer{}
 	w.init(buf)
-	original.mustWriteTo(cm, w)
+	original.mustWriteTo(cm, w, codecDefault)
 	assert.Equal(t, w.bytesWritten, cm.size)
 	assert.Equal(t, uint64(len(buf.Buf)), cm.size)
 	assert.Equal(t, uint64(0), cm.offset)
@@ -76,7 +76,7 @@ func TestColumn_mustWriteTo_mustReadValues(t *testing.T) {
 	decoder := &encoding.BytesBlockDecoder{}
 
 	unmarshaled := &column{}
-	unmarshaled.mustReadValues(decoder, buf, *cm, uint64(len(original.values)))
+	unmarshaled.mustReadValues(decoder, buf, *cm, uint64(len(original.values)), codecDefault)
 
 	// Check that the original and new instances are equal
 	assert.Equal(t, original.name, unmarshaled.name)
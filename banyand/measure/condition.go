@@ -0,0 +1,183 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"math"
+	"sort"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/banyand/internal/storage"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+// ErrConditionFailed denotes the latest data point of a series doesn't satisfy the write condition.
+var ErrConditionFailed = errors.New("write condition failed")
+
+type pendingDataPoint struct {
+	dataPoint *measurev1.DataPointValue
+	ts        int64
+}
+
+func checkCondition(tsdb storage.TSDB[*tsTable, option], dpg *dataPointsInGroup, stm *measure,
+	sid common.SeriesID, cond *measurev1.WriteCondition,
+) error {
+	familyIdx, tagIdx, err := locateConditionTag(stm, cond)
+	if err != nil {
+		return err
+	}
+	var actual *modelv1.TagValue
+	latestTS := int64(math.MinInt64)
+	// latestPart is the part holding the latest data point, nil if it's pending in the batch, which outranks any part.
+	var latestPart *partMetadata
+	if dpg != nil {
+		if p, ok := dpg.pending[sid]; ok {
+			latestTS = p.ts
+			actual = tagValueAt(p.dataPoint, familyIdx, tagIdx)
+		}
+	}
+	tabWrappers := tsdb.SelectTSTables(timestamp.AllTime)
+	defer func() {
+		for i := range tabWrappers {
+			tabWrappers[i].DecRef()
+		}
+	}()
+	// Every table is searched, since the tables of the shards in a segment may all hold the series
+	// once it's resharded or salted. The newer segments are searched first to skip the older ones.
+	sort.Slice(tabWrappers, func(i, j int) bool {
+		return tabWrappers[i].GetTimeRange().Start.After(tabWrappers[j].GetTimeRange().Start)
+	})
+	for i := range tabWrappers {
+		if actual != nil && tabWrappers[i].GetTimeRange().End.UnixNano() < latestTS {
+			continue
+		}
+		ts, v, pm, ok := tabWrappers[i].Table().latestTagValue(sid, cond.TagFamily, cond.TagName)
+		if !ok {
+			continue
+		}
+		if actual == nil || ts > latestTS || (ts == latestTS && latestPart != nil && pm.outranks(latestPart)) {
+			latestTS = ts
+			actual = v
+			latestPart = &pm
+		}
+	}
+	if actual == nil {
+		return errors.WithMessagef(ErrConditionFailed, "series %d has no data point", sid)
+	}
+	if !proto.Equal(actual, cond.Value) {
+		return errors.WithMessagef(ErrConditionFailed, "tag %s.%s is %s, want %s", cond.TagFamily, cond.TagName, actual, cond.Value)
+	}
+	return nil
+}
+
+func locateConditionTag(stm *measure, cond *measurev1.WriteCondition) (int, int, error) {
+	for i, tf := range stm.GetSchema().GetTagFamilies() {
+		if tf.Name != cond.TagFamily {
+			continue
+		}
+		for j, t := range tf.Tags {
+			if t.Name != cond.TagName {
+				continue
+			}
+			if _, isEntity := stm.indexRuleLocators.EntitySet[t.Name]; isEntity || t.IndexedOnly {
+				return 0, 0, errors.Errorf("tag %s.%s isn't stored with data points", cond.TagFamily, cond.TagName)
+			}
+			return i, j, nil
+		}
+	}
+	return 0, 0, errors.Errorf("tag %s.%s isn't defined", cond.TagFamily, cond.TagName)
+}
+
+func tagValueAt(dp *measurev1.DataPointValue, familyIdx, tagIdx int) *modelv1.TagValue {
+	if len(dp.TagFamilies) <= familyIdx || len(dp.TagFamilies[familyIdx].Tags) <= tagIdx {
+		return pbv1.NullTagValue
+	}
+	return dp.TagFamilies[familyIdx].Tags[tagIdx]
+}
+
+// latestTagValue returns the timestamp, the tag value and the part metadata of the latest data point of the series.
+func (tst *tsTable) latestTagValue(sid common.SeriesID, tagFamily, tagName string) (int64, *modelv1.TagValue, partMetadata, bool) {
+	s := tst.currentSnapshot()
+	if s == nil {
+		return 0, nil, partMetadata{}, false
+	}
+	defer s.decRef()
+	parts, n := s.getParts(nil, math.MinInt64, math.MaxInt64)
+	if n < 1 {
+		return 0, nil, partMetadata{}, false
+	}
+	qo := queryOptions{
+		MeasureQueryOptions: pbv1.MeasureQueryOptions{
+			TagProjection: []pbv1.TagProjection{{Family: tagFamily, Names: []string{tagName}}},
+		},
+		minTimestamp: math.MinInt64,
+		maxTimestamp: math.MaxInt64,
+	}
+	bma := generateBlockMetadataArray()
+	defer releaseBlockMetadataArray(bma)
+	bc := generateBlockCursor()
+	defer releaseBlockCursor(bc)
+	var pi partIter
+	found := false
+	sids := []common.SeriesID{sid}
+	// Only the blocks ending after the latest one found so far are visited.
+	// A block of the same part ending at the same timestamp follows the found one, and wins over it,
+	// while a block of another part wins if its part outranks the found one's.
+	for _, p := range parts {
+		if found && p.partMetadata.MaxTimestamp < bc.bm.timestamps.max {
+			continue
+		}
+		pi.init(bma, p, sids, qo.minTimestamp, qo.maxTimestamp)
+		for pi.nextBlock() {
+			if found && (pi.curBlock.timestamps.max < bc.bm.timestamps.max ||
+				pi.curBlock.timestamps.max == bc.bm.timestamps.max && p != bc.p && !p.partMetadata.outranks(&bc.p.partMetadata)) {
+				continue
+			}
+			bc.init(p, pi.curBlock, qo)
+			found = true
+		}
+		pi.reset()
+	}
+	if !found {
+		return 0, nil, partMetadata{}, false
+	}
+	pm := bc.p.partMetadata
+	tmpBlock := generateBlock()
+	defer releaseBlock(tmpBlock)
+	if !bc.loadData(tmpBlock) {
+		return 0, nil, partMetadata{}, false
+	}
+	idx := len(bc.timestamps) - 1
+	for _, tf := range bc.tagFamilies {
+		if tf.name != tagFamily {
+			continue
+		}
+		for _, c := range tf.columns {
+			if c.name == tagName {
+				return bc.timestamps[idx], mustDecodeTagValue(c.valueType, c.values[idx]), pm, true
+			}
+		}
+	}
+	return bc.timestamps[idx], pbv1.NullTagValue, pm, true
+}
@@ -0,0 +1,229 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"io"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/partition"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	resourceSchema "github.com/apache/skywalking-banyandb/pkg/schema"
+	"github.com/apache/skywalking-banyandb/pkg/test"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+type fakeGroup struct {
+	db io.Closer
+}
+
+func (g *fakeGroup) GetSchema() *commonv1.Group { return nil }
+
+func (g *fakeGroup) SupplyTSDB() io.Closer { return g.db }
+
+type fakeResource struct {
+	resourceSchema.Resource
+	m *measure
+}
+
+func (r *fakeResource) Delegated() io.Closer { return r.m }
+
+type fakeRepository struct {
+	resourceSchema.Repository
+	group    *fakeGroup
+	resource *fakeResource
+}
+
+func (r *fakeRepository) LoadGroup(string) (resourceSchema.Group, bool) { return r.group, true }
+
+func (r *fakeRepository) LoadResource(*commonv1.Metadata) (resourceSchema.Resource, bool) {
+	return r.resource, true
+}
+
+func TestWriteCallback_condition(t *testing.T) {
+	tmpPath, defFn := test.Space(require.New(t))
+	defer defFn()
+	groupSchema := &commonv1.Group{
+		Metadata: &commonv1.Metadata{Name: "sw_metric"},
+		ResourceOpts: &commonv1.ResourceOpts{
+			ShardNum:        1,
+			SegmentInterval: &commonv1.IntervalRule{Unit: commonv1.IntervalRule_UNIT_DAY, Num: 1},
+			Ttl:             &commonv1.IntervalRule{Unit: commonv1.IntervalRule_UNIT_DAY, Num: 7},
+		},
+	}
+	s := &supplier{
+		path: tmpPath,
+		l:    logger.GetLogger("test"),
+		option: option{
+			flushTimeout: time.Second,
+			mergePolicy:  newDefaultMergePolicyForTesting(),
+		},
+	}
+	db, err := s.OpenDB(groupSchema)
+	require.NoError(t, err)
+	defer db.Close()
+
+	tagFamilies := []*databasev1.TagFamilySpec{{
+		Name: "default",
+		Tags: []*databasev1.TagSpec{
+			{Name: "id", Type: databasev1.TagType_TAG_TYPE_STRING},
+			{Name: "state", Type: databasev1.TagType_TAG_TYPE_STRING},
+		},
+	}}
+	entity := &databasev1.Entity{TagNames: []string{"id"}}
+	md := &commonv1.Metadata{Group: "sw_metric", Name: "service_state"}
+	m := &measure{
+		schema: &databasev1.Measure{
+			Metadata:    md,
+			TagFamilies: tagFamilies,
+			Entity:      entity,
+		},
+		indexRuleLocators: partition.ParseIndexRuleLocators(entity, tagFamilies, nil),
+	}
+	w := setUpWriteCallback(logger.GetLogger("test"), &schemaRepo{
+		Repository: &fakeRepository{
+			group:    &fakeGroup{db: db},
+			resource: &fakeResource{m: m},
+		},
+	}, nil)
+
+	now := time.Now().Truncate(time.Millisecond)
+	entityValues := []*modelv1.TagValue{strTagValue("svc")}
+	writeEvent := func(ts time.Time, state string, cond *measurev1.WriteCondition) *measurev1.InternalWriteRequest {
+		return &measurev1.InternalWriteRequest{
+			EntityValues: entityValues,
+			Request: &measurev1.WriteRequest{
+				Metadata: md,
+				DataPoint: &measurev1.DataPointValue{
+					Timestamp: timestamppb.New(ts),
+					TagFamilies: []*modelv1.TagFamilyForWrite{{
+						Tags: []*modelv1.TagValue{strTagValue("svc"), strTagValue(state)},
+					}},
+				},
+				Condition: cond,
+			},
+		}
+	}
+	expect := func(state string) *measurev1.WriteCondition {
+		return &measurev1.WriteCondition{TagFamily: "default", TagName: "state", Value: strTagValue(state)}
+	}
+	rev := func(events ...any) {
+		w.Rev(bus.NewMessage(bus.MessageID(time.Now().UnixNano()), events))
+	}
+	series := &pbv1.Series{Subject: md.Name, EntityValues: entityValues}
+	require.NoError(t, series.Marshal())
+	tsdb, err := w.schemaRepo.loadTSDB(md.Group)
+	require.NoError(t, err)
+	// latest returns the number of the data points of the series and its latest state.
+	latest := func() (int, string) {
+		tabWrappers := tsdb.SelectTSTables(timestamp.AllTime)
+		defer func() {
+			for i := range tabWrappers {
+				tabWrappers[i].DecRef()
+			}
+		}()
+		if len(tabWrappers) == 0 {
+			return 0, ""
+		}
+		require.Len(t, tabWrappers, 1)
+		tst := tabWrappers[0].Table()
+		_, v, _, ok := tst.latestTagValue(series.ID, "default", "state")
+		if !ok {
+			return 0, ""
+		}
+		snp := tst.currentSnapshot()
+		defer snp.decRef()
+		parts, _ := snp.getParts(nil, math.MinInt64, math.MaxInt64)
+		bma := generateBlockMetadataArray()
+		defer releaseBlockMetadataArray(bma)
+		var count int
+		var pi partIter
+		for _, p := range parts {
+			pi.init(bma, p, []common.SeriesID{series.ID}, math.MinInt64, math.MaxInt64)
+			for pi.nextBlock() {
+				count += int(pi.curBlock.count)
+			}
+			pi.reset()
+		}
+		return count, v.GetStr().GetValue()
+	}
+
+	// A conditional write against an empty series is rejected.
+	rev(writeEvent(now, "init", expect("none")))
+	count, _ := latest()
+	require.Zero(t, count)
+	rev(writeEvent(now, "init", nil))
+	count, state := latest()
+	require.Equal(t, 1, count)
+	require.Equal(t, "init", state)
+
+	var wg sync.WaitGroup
+	for i, state := range []string{"a", "b"} {
+		wg.Add(1)
+		go func(ts time.Time, state string) {
+			defer wg.Done()
+			rev(writeEvent(ts, state, expect("init")))
+		}(now.Add(time.Duration(i+1)*time.Second), state)
+	}
+	wg.Wait()
+	count, winner := latest()
+	assert.Equal(t, 2, count, "only one of the concurrent conditional writes should be applied")
+	assert.Contains(t, []string{"a", "b"}, winner)
+
+	// The condition is evaluated against the pending data points in the same batch.
+	rev(writeEvent(now.Add(3*time.Second), "c", expect(winner)), writeEvent(now.Add(4*time.Second), "d", expect("c")),
+		writeEvent(now.Add(5*time.Second), "e", expect(winner)))
+	count, state = latest()
+	assert.Equal(t, 4, count)
+	assert.Equal(t, "d", state)
+
+	// The conditional write topic replies whether the condition holds.
+	conditional := func(event *measurev1.InternalWriteRequest) modelv1.Status {
+		resp := conditionalWriteCallback{w}.Rev(bus.NewMessage(bus.MessageID(time.Now().UnixNano()), event))
+		wr, ok := resp.Data().(*measurev1.WriteResponse)
+		require.True(t, ok)
+		return wr.GetStatus()
+	}
+	assert.Equal(t, modelv1.Status_STATUS_CONDITION_FAILED, conditional(writeEvent(now.Add(6*time.Second), "f", expect("c"))))
+	assert.Equal(t, modelv1.Status_STATUS_SUCCEED, conditional(writeEvent(now.Add(6*time.Second), "f", expect("d"))))
+	count, state = latest()
+	assert.Equal(t, 5, count)
+	assert.Equal(t, "f", state)
+
+	// The condition is evaluated against the latest data point in any shard of the segment.
+	require.NoError(t, tsdb.Reshard(2))
+	moved := writeEvent(now.Add(7*time.Second), "g", nil)
+	moved.ShardId = 1
+	rev(moved)
+	assert.Equal(t, modelv1.Status_STATUS_CONDITION_FAILED, conditional(writeEvent(now.Add(8*time.Second), "h", expect("f"))))
+	assert.Equal(t, modelv1.Status_STATUS_SUCCEED, conditional(writeEvent(now.Add(8*time.Second), "h", expect("g"))))
+}
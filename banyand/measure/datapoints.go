@@ -120,6 +120,8 @@ type dataPoints struct {
 	timestamps  []int64
 	tagFamilies [][]nameValues
 	fields      []nameValues
+	// shardNum is the number of shards of the database when the data points are written.
+	shardNum uint32
 }
 
 func (d *dataPoints) Len() int {
@@ -148,7 +150,8 @@ type dataPointsInTable struct {
 }
 
 type dataPointsInGroup struct {
-	tsdb storage.TSDB[*tsTable, option]
+	tsdb    storage.TSDB[*tsTable, option]
+	pending map[common.SeriesID]pendingDataPoint
 
 	docs     index.Documents
 	tables   []*dataPointsInTable
@@ -0,0 +1,100 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/apache/skywalking-banyandb/banyand/internal/storage"
+)
+
+// EffectiveConfig is the configuration a running group works with.
+// It's resolved from the flags and the group's resource options, the latter taking precedence.
+type EffectiveConfig struct {
+	Location             string
+	MergePolicy          MergePolicyConfig
+	SegmentInterval      storage.IntervalRule
+	TTL                  storage.IntervalRule
+	FutureWindow         storage.IntervalRule
+	FlushTimeout         time.Duration
+	SeriesCacheTTL       time.Duration
+	OutOfRetentionPolicy storage.OutOfRetentionPolicy
+	SeriesCacheSize      int
+	SeriesCachePolicy    storage.CachePolicy
+	MaxSeriesPerQuery    int
+	MaxOpenSegments      int
+	MaxDiskUsagePercent  int
+	MinRetainedSegments  int
+	BlockSize            int
+	ShardNum             uint32
+	SeriesCacheWarmup    bool
+}
+
+// MergePolicyConfig is the configuration of the background merger.
+type MergePolicyConfig struct {
+	MaxParts           int
+	MinMergeMultiplier float64
+	MaxFanOutSize      uint64
+	TargetPartSize     uint64
+}
+
+func newEffectiveConfig(opts storage.TSDBOpts[*tsTable, option]) EffectiveConfig {
+	cfg := EffectiveConfig{
+		Location:             opts.Location,
+		ShardNum:             opts.ShardNum,
+		SegmentInterval:      opts.SegmentInterval,
+		TTL:                  opts.TTL,
+		FutureWindow:         opts.FutureWindow,
+		OutOfRetentionPolicy: opts.OutOfRetentionPolicy,
+		FlushTimeout:         opts.Option.flushTimeout,
+		SeriesCacheSize:      opts.SeriesCacheSize,
+		SeriesCacheTTL:       opts.SeriesCacheTTL,
+		SeriesCachePolicy:    opts.SeriesCachePolicy,
+		SeriesCacheWarmup:    opts.SeriesCacheWarmup,
+		MaxSeriesPerQuery:    opts.MaxSeriesPerQuery,
+		MaxOpenSegments:      opts.MaxOpenSegments,
+		MaxDiskUsagePercent:  opts.MaxDiskUsagePercent,
+		MinRetainedSegments:  opts.MinRetainedSegments,
+		BlockSize:            opts.Option.blockLength(),
+	}
+	if cfg.FutureWindow.Num == 0 {
+		cfg.FutureWindow = cfg.SegmentInterval
+	}
+	if mp := opts.Option.mergePolicy; mp != nil {
+		cfg.MergePolicy = MergePolicyConfig{
+			MaxParts:           mp.maxParts,
+			MinMergeMultiplier: mp.minMergeMultiplier,
+			MaxFanOutSize:      mp.maxFanOutSize,
+			TargetPartSize:     mp.targetPartSize,
+		}
+	}
+	return cfg
+}
+
+func (sr *schemaRepo) effectiveConfig(group string) (EffectiveConfig, error) {
+	g, ok := sr.LoadGroup(group)
+	if !ok {
+		return EffectiveConfig{}, fmt.Errorf("group %s not found", group)
+	}
+	opts, err := sr.supplier.tsdbOpts(g.GetSchema())
+	if err != nil {
+		return EffectiveConfig{}, err
+	}
+	return newEffectiveConfig(opts), nil
+}
@@ -0,0 +1,102 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	"github.com/apache/skywalking-banyandb/banyand/internal/storage"
+)
+
+func TestEffectiveConfig(t *testing.T) {
+	s := &service{}
+	require.NoError(t, s.FlagSet().Parse([]string{"--measure-block-size=4096", "--measure-flush-timeout=3s"}))
+	sp := &supplier{path: "/data/measure", option: s.option}
+	group := func(blockSize *uint32) *commonv1.Group {
+		return &commonv1.Group{
+			Metadata: &commonv1.Metadata{Name: "sw_metric"},
+			ResourceOpts: &commonv1.ResourceOpts{
+				ShardNum:        2,
+				SegmentInterval: &commonv1.IntervalRule{Unit: commonv1.IntervalRule_UNIT_DAY, Num: 1},
+				Ttl:             &commonv1.IntervalRule{Unit: commonv1.IntervalRule_UNIT_DAY, Num: 7},
+				BlockSize:       blockSize,
+			},
+		}
+	}
+
+	t.Run("the flags apply without an override", func(t *testing.T) {
+		opts, err := sp.tsdbOpts(group(nil))
+		require.NoError(t, err)
+		cfg := newEffectiveConfig(opts)
+		assert.Equal(t, 4096, cfg.BlockSize)
+		assert.Equal(t, 3*time.Second, cfg.FlushTimeout)
+		assert.Equal(t, "/data/measure/sw_metric", cfg.Location)
+		assert.Equal(t, uint32(2), cfg.ShardNum)
+		assert.Equal(t, storage.IntervalRule{Unit: storage.DAY, Num: 7}, cfg.TTL)
+		assert.Equal(t, cfg.SegmentInterval, cfg.FutureWindow)
+		assert.Equal(t, storage.OutOfRetentionAccept, cfg.OutOfRetentionPolicy)
+		assert.Equal(t, MergePolicyConfig{MaxParts: 8, MinMergeMultiplier: 1.7, MaxFanOutSize: s.option.mergePolicy.maxFanOutSize}, cfg.MergePolicy)
+	})
+
+	t.Run("the group's override takes precedence over the flag", func(t *testing.T) {
+		g := group(proto.Uint32(16))
+		g.ResourceOpts.OutOfRetentionPolicy = commonv1.OutOfRetentionPolicy_OUT_OF_RETENTION_POLICY_REJECT
+		g.ResourceOpts.FutureWindow = &commonv1.IntervalRule{Unit: commonv1.IntervalRule_UNIT_HOUR, Num: 2}
+		opts, err := sp.tsdbOpts(g)
+		require.NoError(t, err)
+		cfg := newEffectiveConfig(opts)
+		assert.Equal(t, 16, cfg.BlockSize)
+		assert.Equal(t, storage.OutOfRetentionReject, cfg.OutOfRetentionPolicy)
+		assert.Equal(t, storage.IntervalRule{Unit: storage.HOUR, Num: 2}, cfg.FutureWindow)
+		assert.Equal(t, 4096, s.option.blockLength(), "the override mustn't change the flag")
+	})
+
+	t.Run("an invalid block size is rejected", func(t *testing.T) {
+		for _, blockSize := range []uint32{0, maxBlockLength + 1} {
+			_, err := sp.tsdbOpts(group(proto.Uint32(blockSize)))
+			assert.Error(t, err, "block size %d", blockSize)
+		}
+	})
+}
+
+func TestValidateBlockSize(t *testing.T) {
+	for _, tt := range []struct {
+		flag    string
+		wantErr bool
+	}{
+		{flag: "0", wantErr: true},
+		{flag: "-1", wantErr: true},
+		{flag: "1"},
+		{flag: "8192"},
+		{flag: "8193", wantErr: true},
+	} {
+		s := &service{}
+		require.NoError(t, s.FlagSet().Parse([]string{"--measure-block-size=" + tt.flag}))
+		if tt.wantErr {
+			assert.ErrorContains(t, s.Validate(), "measure-block-size", tt.flag)
+			continue
+		}
+		assert.NoError(t, s.Validate(), tt.flag)
+	}
+}
@@ -0,0 +1,214 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/axiomhq/hyperloglog"
+	"github.com/pkg/errors"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/pkg/fs"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+const entityCardinalityFilename = "entity-cardinality.json"
+
+// EntityCardinality is the approximate number of the distinct entities of a measure.
+type EntityCardinality struct {
+	Measure string
+	// Tags are the entity tags.
+	Tags []string
+	// Estimates are aligned with the tags. An estimate counts the distinct prefixes of the entity
+	// ending at its tag, so the first one counts the services and the second one counts the instances
+	// of all services, for example. The last one counts the series.
+	Estimates []uint64
+}
+
+// entityCardinality maintains a HyperLogLog sketch per entity position of every measure written to a table.
+// A series is inserted once per table, the first time the table writes it. A nil one estimates nothing.
+type entityCardinality struct {
+	sketches map[string][]*hyperloglog.Sketch
+	// inserted holds the series already inserted since the table opened.
+	// Inserting a series again after a restart doesn't change the sketches.
+	inserted map[common.SeriesID]struct{}
+	mu       sync.Mutex
+	// dirty tells whether the sketches changed since they were persisted.
+	dirty bool
+}
+
+func newEntityCardinality() *entityCardinality {
+	return &entityCardinality{
+		sketches: make(map[string][]*hyperloglog.Sketch),
+		inserted: make(map[common.SeriesID]struct{}),
+	}
+}
+
+// loadEntityCardinality reads the sketches persisted by the table. A missing or corrupted file starts them over.
+func loadEntityCardinality(fileSystem fs.FileSystem, root string, l *logger.Logger) *entityCardinality {
+	ec := newEntityCardinality()
+	data, err := fileSystem.Read(filepath.Join(root, entityCardinalityFilename))
+	if err != nil {
+		return ec
+	}
+	var persisted map[string][][]byte
+	if err = json.Unmarshal(data, &persisted); err != nil {
+		l.Warn().Err(err).Msg("cannot parse the entity cardinality, start it over")
+		return ec
+	}
+	for subject, encoded := range persisted {
+		sketches := make([]*hyperloglog.Sketch, len(encoded))
+		for i := range encoded {
+			sketches[i] = hyperloglog.New()
+			if err = sketches[i].UnmarshalBinary(encoded[i]); err != nil {
+				l.Warn().Err(err).Str("measure", subject).Msg("cannot decode the entity cardinality, start it over")
+				sketches = nil
+				break
+			}
+		}
+		if sketches != nil {
+			ec.sketches[subject] = sketches
+		}
+	}
+	return ec
+}
+
+// insert adds the entity prefixes of a series new to the table to the sketches.
+func (ec *entityCardinality) insert(id common.SeriesID, subject string, entityValues []*modelv1.TagValue) error {
+	if ec == nil {
+		return nil
+	}
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+	if _, ok := ec.inserted[id]; ok {
+		return nil
+	}
+	sketches := ec.sketches[subject]
+	for len(sketches) < len(entityValues) {
+		sketches = append(sketches, hyperloglog.New())
+	}
+	ec.sketches[subject] = sketches
+	prefix := pbv1.Series{Subject: subject}
+	for i := range entityValues {
+		prefix.EntityValues = entityValues[:i+1]
+		prefix.Buffer = prefix.Buffer[:0]
+		if err := prefix.Marshal(); err != nil {
+			return err
+		}
+		sketches[i].Insert(prefix.Buffer)
+	}
+	ec.inserted[id] = struct{}{}
+	ec.dirty = true
+	return nil
+}
+
+// mergeInto merges the sketches into dst, which is keyed by the measure names.
+func (ec *entityCardinality) mergeInto(dst map[string][]*hyperloglog.Sketch) error {
+	if ec == nil {
+		return nil
+	}
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+	for subject, sketches := range ec.sketches {
+		merged := dst[subject]
+		for i, sk := range sketches {
+			if i >= len(merged) {
+				merged = append(merged, sk.Clone())
+				continue
+			}
+			if err := merged[i].Merge(sk); err != nil {
+				return err
+			}
+		}
+		dst[subject] = merged
+	}
+	return nil
+}
+
+// persist writes the sketches changed since they were last persisted.
+func (ec *entityCardinality) persist(fileSystem fs.FileSystem, root string) error {
+	if ec == nil {
+		return nil
+	}
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+	if !ec.dirty {
+		return nil
+	}
+	persisted := make(map[string][][]byte, len(ec.sketches))
+	for subject, sketches := range ec.sketches {
+		encoded := make([][]byte, len(sketches))
+		for i, sk := range sketches {
+			b, err := sk.MarshalBinary()
+			if err != nil {
+				return err
+			}
+			encoded[i] = b
+		}
+		persisted[subject] = encoded
+	}
+	data, err := json.Marshal(persisted)
+	if err != nil {
+		return err
+	}
+	if _, err = fileSystem.Write(data, filepath.Join(root, entityCardinalityFilename), filePermission); err != nil {
+		return err
+	}
+	ec.dirty = false
+	return nil
+}
+
+func (sr *schemaRepo) entityCardinality(group string, timeRange timestamp.TimeRange) ([]EntityCardinality, error) {
+	db, err := sr.loadTSDB(group)
+	if err != nil {
+		return nil, err
+	}
+	tabWrappers := db.SelectTSTables(timeRange)
+	defer func() {
+		for i := range tabWrappers {
+			tabWrappers[i].DecRef()
+		}
+	}()
+	merged := make(map[string][]*hyperloglog.Sketch)
+	for i := range tabWrappers {
+		if err = tabWrappers[i].Table().cardinality.mergeInto(merged); err != nil {
+			return nil, errors.WithMessage(err, "merge the entity cardinality")
+		}
+	}
+	result := make([]EntityCardinality, 0, len(merged))
+	for subject, sketches := range merged {
+		ec := EntityCardinality{Measure: subject, Estimates: make([]uint64, len(sketches))}
+		if m, ok := sr.loadMeasure(&commonv1.Metadata{Group: group, Name: subject}); ok {
+			ec.Tags = m.GetSchema().GetEntity().GetTagNames()
+		}
+		for i, sk := range sketches {
+			ec.Estimates[i] = sk.Estimate()
+		}
+		result = append(result, ec)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Measure < result[j].Measure })
+	return result, nil
+}
@@ -0,0 +1,107 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/axiomhq/hyperloglog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/pkg/fs"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/test"
+	"github.com/apache/skywalking-banyandb/pkg/test/flags"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+func TestEntityCardinality(t *testing.T) {
+	const services = 300
+	const instancesPerService = 20
+	str := func(v string) *modelv1.TagValue {
+		return &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: v}}}
+	}
+	// Every instance is written to both shards twice, and the instance names are shared by the services.
+	shards := []*entityCardinality{newEntityCardinality(), newEntityCardinality()}
+	for round := 0; round < 2; round++ {
+		for s := 0; s < services; s++ {
+			for i := 0; i < instancesPerService; i++ {
+				entity := []*modelv1.TagValue{str(fmt.Sprintf("service-%d", s)), str(fmt.Sprintf("instance-%d", i))}
+				id := common.SeriesID(s*instancesPerService + i + 1)
+				require.NoError(t, shards[(s+i)%2].insert(id, "instance_cpm", entity))
+			}
+		}
+	}
+	require.NoError(t, shards[0].insert(math.MaxUint64, "service_cpm", []*modelv1.TagValue{str("service-0")}))
+	assert.Len(t, shards[0].inserted, services*instancesPerService/2+1, "a series is inserted once")
+
+	merged := make(map[string][]*hyperloglog.Sketch)
+	for _, ec := range shards {
+		require.NoError(t, ec.mergeInto(merged))
+	}
+	require.Len(t, merged["instance_cpm"], 2)
+	// The standard error of a sketch with the precision 14 is 1.04/sqrt(2^14), about 0.8%.
+	// Three standard errors bound the estimate.
+	bound := 3 * 1.04 / math.Sqrt(1<<14)
+	assert.InEpsilon(t, services, merged["instance_cpm"][0].Estimate(), bound)
+	assert.InEpsilon(t, services*instancesPerService, merged["instance_cpm"][1].Estimate(), bound)
+	require.Len(t, merged["service_cpm"], 1)
+	assert.Equal(t, uint64(1), merged["service_cpm"][0].Estimate())
+
+	// The sketches survive the restart of the table.
+	tmpPath, defFn := test.Space(require.New(t))
+	defer defFn()
+	fileSystem := fs.NewLocalFileSystem()
+	require.NoError(t, shards[0].persist(fileSystem, tmpPath))
+	assert.False(t, shards[0].dirty)
+	require.NoError(t, shards[0].insert(math.MaxUint64, "service_cpm", []*modelv1.TagValue{str("service-0")}))
+	assert.False(t, shards[0].dirty, "a series inserted before doesn't change the sketches")
+	loaded := loadEntityCardinality(fileSystem, tmpPath, logger.GetLogger("test"))
+	reloaded := make(map[string][]*hyperloglog.Sketch)
+	require.NoError(t, loaded.mergeInto(reloaded))
+	original := make(map[string][]*hyperloglog.Sketch)
+	require.NoError(t, shards[0].mergeInto(original))
+	assert.Equal(t, original["instance_cpm"][1].Estimate(), reloaded["instance_cpm"][1].Estimate())
+	assert.Equal(t, uint64(1), reloaded["service_cpm"][0].Estimate())
+}
+
+func TestEntityCardinalityPersistedOnFlush(t *testing.T) {
+	tmpPath, defFn := test.Space(require.New(t))
+	defer defFn()
+	fileSystem := fs.NewLocalFileSystem()
+	tst, err := newTSTable(fileSystem, tmpPath, common.Position{},
+		logger.GetLogger("test"), timestamp.TimeRange{}, option{flushTimeout: 0, mergePolicy: newDefaultMergePolicyForTesting()})
+	require.NoError(t, err)
+	defer tst.Close()
+	require.NoError(t, tst.cardinality.insert(1, "service_cpm",
+		[]*modelv1.TagValue{{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "service-0"}}}}))
+	tst.mustAddDataPoints(dpsTS1)
+
+	// The sketches are written once the data points are flushed, before the table closes.
+	require.Eventually(t, func() bool {
+		_, err := fileSystem.Read(filepath.Join(tmpPath, entityCardinalityFilename))
+		return err == nil
+	}, flags.EventuallyTimeout, 10*time.Millisecond)
+}
@@ -0,0 +1,85 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultMinFlushTimeout = time.Second
+	defaultMaxFlushTimeout = 30 * time.Second
+)
+
+var errAdaptiveFlushWithoutLimit = errors.New("the adaptive flush timeout requires measure-write-memory-limit")
+
+// adaptiveFlush scales the flush timeout of a table by the bytes of its in-memory parts.
+// The timeout is the longest while the table barely buffers any data, which keeps the parts of sparse writes big,
+// and shrinks to the shortest as the buffered bytes reach half the memory limit, so that they're flushed well before
+// the memory protector rejects the writes.
+type adaptiveFlush struct {
+	minTimeout  time.Duration
+	maxTimeout  time.Duration
+	memoryLimit uint64
+}
+
+func newAdaptiveFlush(minTimeout, maxTimeout time.Duration, memoryLimit uint64) (*adaptiveFlush, error) {
+	if memoryLimit == 0 {
+		return nil, errAdaptiveFlushWithoutLimit
+	}
+	if minTimeout <= 0 || maxTimeout < minTimeout {
+		return nil, errors.Errorf("invalid adaptive flush timeout bounds [%s, %s]", minTimeout, maxTimeout)
+	}
+	return &adaptiveFlush{minTimeout: minTimeout, maxTimeout: maxTimeout, memoryLimit: memoryLimit}, nil
+}
+
+// timeout interpolates between the bounds by the ratio of the buffered bytes to half the memory limit.
+func (af *adaptiveFlush) timeout(buffered uint64) time.Duration {
+	watermark := af.memoryLimit / 2
+	if buffered >= watermark {
+		return af.minTimeout
+	}
+	ratio := float64(buffered) / float64(watermark)
+	return af.maxTimeout - time.Duration(ratio*float64(af.maxTimeout-af.minTimeout))
+}
+
+// flushTimeout returns how long the flusher waits for the in-memory parts to pile up.
+func (tst *tsTable) flushTimeout() time.Duration {
+	af := tst.option.adaptiveFlush
+	if af == nil {
+		return tst.option.flushTimeout
+	}
+	return af.timeout(tst.memPartsBytes())
+}
+
+func (tst *tsTable) memPartsBytes() uint64 {
+	snp := tst.currentSnapshot()
+	if snp == nil {
+		return 0
+	}
+	defer snp.decRef()
+	var n uint64
+	for _, pw := range snp.parts {
+		if pw.mp != nil {
+			n += pw.mp.partMetadata.CompressedSizeBytes
+		}
+	}
+	return n
+}
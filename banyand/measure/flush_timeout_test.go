@@ -0,0 +1,86 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/pkg/fs"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/test"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+func TestAdaptiveFlushTimeout(t *testing.T) {
+	af, err := newAdaptiveFlush(time.Second, 11*time.Second, 200)
+	require.NoError(t, err)
+	assert.Equal(t, 11*time.Second, af.timeout(0))
+	assert.Equal(t, 6*time.Second, af.timeout(50))
+	assert.Equal(t, time.Second, af.timeout(100))
+	assert.Equal(t, time.Second, af.timeout(300))
+
+	_, err = newAdaptiveFlush(time.Second, 11*time.Second, 0)
+	assert.ErrorIs(t, err, errAdaptiveFlushWithoutLimit)
+	_, err = newAdaptiveFlush(time.Second, time.Millisecond, 200)
+	assert.Error(t, err)
+}
+
+func TestAdaptiveFlushBeforeMemoryLimit(t *testing.T) {
+	tmpPath, defFn := test.Space(require.New(t))
+	defer defFn()
+	fileSystem := fs.NewLocalFileSystem()
+
+	mp := generateMemPart()
+	mp.mustInitFromDataPoints(generateSeqDps(1, 10), maxBlockLength, nil)
+	partSize := mp.partMetadata.CompressedSizeBytes
+	releaseMemPart(mp)
+	// The limit holds 200 parts, and the static timeout would hold them for an hour.
+	memoryLimit := 200 * partSize
+	af, err := newAdaptiveFlush(10*time.Millisecond, time.Hour, memoryLimit)
+	require.NoError(t, err)
+	tst, err := newTSTable(fileSystem, tmpPath, common.Position{}, logger.GetLogger("test"), timestamp.TimeRange{},
+		option{flushTimeout: time.Hour, adaptiveFlush: af, mergePolicy: newDefaultMergePolicyForTesting()})
+	require.NoError(t, err)
+	defer tst.Close()
+
+	var peak uint64
+	for i := int64(0); i < 500; i++ {
+		tst.mustAddDataPoints(generateSeqDps(i*10+1, i*10+10))
+		if buffered := tst.memPartsBytes(); buffered > peak {
+			peak = buffered
+		}
+		time.Sleep(time.Millisecond)
+	}
+	assert.Less(t, peak, memoryLimit, "the buffered parts must be flushed before they reach the memory limit")
+
+	snp := tst.currentSnapshot()
+	require.NotNil(t, snp)
+	defer snp.decRef()
+	var flushed int
+	for _, pw := range snp.parts {
+		if pw.mp == nil {
+			flushed++
+		}
+	}
+	assert.Positive(t, flushed, "the high write rate must shorten the flush timeout")
+}
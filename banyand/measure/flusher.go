@@ -20,6 +20,7 @@ package measure
 import (
 	"errors"
 	"math"
+	"sync/atomic"
 	"time"
 
 	"github.com/apache/skywalking-banyandb/pkg/watcher"
@@ -90,48 +91,66 @@ func (tst *tsTable) pauseFlusherToPileupMemParts(epoch uint64, flushWatcher watc
 	}
 	curSnapshot.decRef()
 	flusherWatchers.Notify(epoch)
-	select {
-	case <-tst.loopCloser.CloseNotify():
-	case <-time.After(tst.option.flushTimeout):
-	case e := <-flushWatcher:
-		flusherWatchers.Add(e)
-		flusherWatchers.Notify(epoch)
+	start := time.Now()
+	for {
+		wait := tst.flushTimeout() - time.Since(start)
+		if wait <= 0 {
+			return flusherWatchers
+		}
+		// The adaptive timeout is reevaluated as the in-memory parts pile up.
+		if af := tst.option.adaptiveFlush; af != nil && wait > af.minTimeout {
+			wait = af.minTimeout
+		}
+		select {
+		case <-tst.loopCloser.CloseNotify():
+			return flusherWatchers
+		case <-time.After(wait):
+		case e := <-flushWatcher:
+			flusherWatchers.Add(e)
+			flusherWatchers.Notify(epoch)
+			return flusherWatchers
+		}
 	}
-	return flusherWatchers
 }
 
 func (tst *tsTable) mergeMemParts(snp *snapshot, mergeCh chan *mergerIntroduction) (bool, error) {
 	var memParts []*partWrapper
-	mergedIDs := make(map[uint64]struct{})
 	for i := range snp.parts {
 		if snp.parts[i].mp != nil {
 			memParts = append(memParts, snp.parts[i])
-			mergedIDs[snp.parts[i].ID()] = struct{}{}
 			continue
 		}
 	}
-	if len(memParts) < 2 {
-		return false, nil
-	}
-	// merge memory must not be closed by the tsTable.close
-	closeCh := make(chan struct{})
-	newPart, err := tst.mergePartsThenSendIntroduction(snapshotCreatorMergedFlusher, memParts, mergedIDs, mergeCh, closeCh)
-	close(closeCh)
-	if err != nil {
-		if errors.Is(err, errClosed) {
-			return true, nil
+	var merged bool
+	for _, group := range groupByShardNum(memParts) {
+		if len(group) < 2 {
+			continue
+		}
+		mergedIDs := make(map[uint64]struct{}, len(group))
+		for _, pw := range group {
+			mergedIDs[pw.ID()] = struct{}{}
+		}
+		// merge memory must not be closed by the tsTable.close
+		closeCh := make(chan struct{})
+		newPart, err := tst.mergePartsThenSendIntroduction(snapshotCreatorMergedFlusher, group, mergedIDs, mergeCh, closeCh)
+		close(closeCh)
+		if err != nil {
+			if errors.Is(err, errClosed) {
+				return true, nil
+			}
+			return false, err
+		}
+		if newPart != nil {
+			merged = true
 		}
-		return false, err
-	}
-	if newPart == nil {
-		return false, nil
 	}
-	return true, nil
+	return merged, nil
 }
 
 func (tst *tsTable) flush(snapshot *snapshot, flushCh chan *flusherIntroduction) {
 	ind := generateFlusherIntroduction()
 	defer releaseFlusherIntroduction(ind)
+	var flushedBytes uint64
 	for _, pw := range snapshot.parts {
 		if pw.mp == nil || pw.mp.partMetadata.TotalCount < 1 {
 			continue
@@ -140,6 +159,7 @@ func (tst *tsTable) flush(snapshot *snapshot, flushCh chan *flusherIntroduction)
 		pw.mp.mustFlush(tst.fileSystem, partPath)
 		newPW := newPartWrapper(nil, mustOpenFilePart(pw.ID(), tst.root, tst.fileSystem))
 		newPW.p.partMetadata.ID = pw.ID()
+		flushedBytes += newPW.p.partMetadata.CompressedSizeBytes
 		ind.flushed[newPW.ID()] = newPW
 	}
 	if len(ind.flushed) < 1 {
@@ -153,6 +173,11 @@ func (tst *tsTable) flush(snapshot *snapshot, flushCh chan *flusherIntroduction)
 	}
 	select {
 	case <-ind.applied:
+		atomic.AddUint64(&tst.ingestedBytes, flushedBytes)
+		// The sketches are persisted along with the flushed parts, so that a crash loses few of them.
+		if err := tst.cardinality.persist(tst.fileSystem, tst.root); err != nil {
+			tst.l.Warn().Err(err).Msg("cannot persist the entity cardinality")
+		}
 	case <-tst.loopCloser.CloseNotify():
 	}
 }
@@ -0,0 +1,86 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+// forceMerge merges the file parts right away, and returns once the merged parts are introduced.
+// A merged part doesn't exceed maxFanOutSize, the fan-out cap and the target size of the merge policy,
+// and the free disk space. Zero maxFanOutSize leaves the size to the merge policy.
+func (tst *tsTable) forceMerge(maxFanOutSize uint64) error {
+	tst.mergeMu.Lock()
+	defer tst.mergeMu.Unlock()
+	snp := tst.currentSnapshot()
+	if snp == nil {
+		return nil
+	}
+	defer snp.decRef()
+
+	policy := tst.option.mergePolicy
+	limit := min(policy.maxFanOutSize, tst.freeDiskSpace(tst.root))
+	if maxFanOutSize > 0 {
+		limit = min(limit, maxFanOutSize)
+	}
+	if policy.targetPartSize > 0 {
+		limit = min(limit, policy.targetPartSize)
+	}
+	var parts []*partWrapper
+	for _, pw := range snp.parts {
+		if pw.mp != nil || pw.p.partMetadata.TotalCount < 1 || pw.p.partMetadata.CompressedSizeBytes >= limit {
+			continue
+		}
+		parts = append(parts, pw)
+	}
+	for _, group := range groupByShardNum(parts) {
+		sortPartsForOptimalMerge(group)
+		for _, pws := range (&mergePolicy{targetPartSize: limit}).splitByTarget(group) {
+			toBeMerged := make(map[uint64]struct{}, len(pws))
+			for _, pw := range pws {
+				toBeMerged[pw.ID()] = struct{}{}
+			}
+			if _, err := tst.mergePartsThenSendIntroduction(snapshotCreatorMerger, pws,
+				toBeMerged, tst.mergeCh, tst.loopCloser.CloseNotify()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (sr *schemaRepo) forceMerge(group string, maxFanOutSize uint64) error {
+	db, err := sr.loadTSDB(group)
+	if err != nil {
+		return err
+	}
+	tabWrappers := db.SelectTSTables(timestamp.AllTime)
+	defer func() {
+		for i := range tabWrappers {
+			tabWrappers[i].DecRef()
+		}
+	}()
+	for i := range tabWrappers {
+		if err = tabWrappers[i].Table().forceMerge(maxFanOutSize); err != nil {
+			return errors.WithMessagef(err, "failed to merge the parts of the segment %s", tabWrappers[i].GetTimeRange())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/pkg/fs"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/test"
+	"github.com/apache/skywalking-banyandb/pkg/test/flags"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+func Test_tsTable_forceMerge(t *testing.T) {
+	const partNum = 6
+	tests := []struct {
+		name string
+		// fanOut is the cap of a merged part in the parts.
+		fanOut          float64
+		wantParts       int
+		concurrentWrite bool
+	}{
+		{name: "merge all the parts", wantParts: 1},
+		{name: "cap the merged parts", fanOut: 2.5, wantParts: 3},
+		{name: "write during the merge", wantParts: 2, concurrentWrite: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpPath, defFn := test.Space(require.New(t))
+			defer defFn()
+			// The background merger never merges the parts.
+			tst, err := newTSTable(fs.NewLocalFileSystem(), tmpPath, common.Position{}, logger.GetLogger("test"),
+				timestamp.TimeRange{}, option{flushTimeout: 0, mergePolicy: newMergePolicy(4, math.MaxInt32, math.MaxUint64)})
+			require.NoError(t, err)
+			defer tst.Close()
+
+			var totalCount uint64
+			for i := int64(0); i < partNum; i++ {
+				dps := generateHugeDps(i*100+1, i*100+100, i*100+50)
+				totalCount += uint64(len(dps.timestamps))
+				tst.mustAddDataPoints(dps)
+				require.Eventually(t, func() bool {
+					return len(tst.partStats(timestamp.TimeRange{})) == int(i+1) && !tst.hasMemParts()
+				}, flags.EventuallyTimeout, 10*time.Millisecond, "wait for the part %d to be flushed", i)
+			}
+			var maxFanOutSize uint64
+			if tt.fanOut > 0 {
+				var partSize uint64
+				for _, ps := range tst.partStats(timestamp.TimeRange{}) {
+					partSize = max(partSize, ps.CompressedSizeBytes)
+				}
+				maxFanOutSize = uint64(tt.fanOut * float64(partSize))
+			}
+
+			written := make(chan struct{})
+			if tt.concurrentWrite {
+				dps := generateHugeDps(partNum*100+1, partNum*100+100, partNum*100+50)
+				totalCount += uint64(len(dps.timestamps))
+				go func() {
+					defer close(written)
+					tst.mustAddDataPoints(dps)
+				}()
+			} else {
+				close(written)
+			}
+
+			require.NoError(t, tst.forceMerge(maxFanOutSize))
+			<-written
+			require.Eventually(t, func() bool {
+				return !tst.hasMemParts()
+			}, flags.EventuallyTimeout, 10*time.Millisecond, "wait for the written data points to be flushed")
+			stats := tst.partStats(timestamp.TimeRange{})
+			assert.Len(t, stats, tt.wantParts)
+			var mergedCount uint64
+			for _, ps := range stats {
+				mergedCount += ps.TotalCount
+				if maxFanOutSize > 0 {
+					assert.LessOrEqual(t, ps.CompressedSizeBytes, maxFanOutSize)
+				}
+			}
+			assert.Equal(t, totalCount, mergedCount)
+		})
+	}
+}
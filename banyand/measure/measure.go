@@ -21,10 +21,15 @@
 package measure
 
 import (
+	"sync"
 	"time"
 
+	"go.uber.org/multierr"
+
 	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
 	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	"github.com/apache/skywalking-banyandb/api/validate"
+	"github.com/apache/skywalking-banyandb/banyand/internal/storage"
 	"github.com/apache/skywalking-banyandb/banyand/queue"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
 	"github.com/apache/skywalking-banyandb/pkg/partition"
@@ -40,14 +45,37 @@ const (
 	maxUncompressedBlockSize        = 2 * 1024 * 1024
 	maxUncompressedPrimaryBlockSize = 128 * 1024
 
-	maxBlockLength = 8 * 1024
+	maxBlockLength = validate.MaxMeasureBlockSize
 
-	defaultFlushTimeout = 5 * time.Second
+	defaultFlushTimeout     = 5 * time.Second
+	defaultGroupGracePeriod = 24 * time.Hour
 )
 
 type option struct {
-	mergePolicy  *mergePolicy
-	flushTimeout time.Duration
+	mergePolicy       *mergePolicy
+	flushTimeout      time.Duration
+	seriesCacheTTL    time.Duration
+	blockSize         int
+	seriesCacheSize   int
+	seriesCachePolicy storage.CachePolicy
+	maxSeriesPerQuery int
+	seriesCacheWarmup bool
+	maxOpenSegments   int
+	// maxDiskUsagePercent is the disk usage beyond which the oldest segments are deleted before their TTL.
+	maxDiskUsagePercent int
+	minRetainedSegments int
+	// adaptiveFlush scales the flush timeout by the buffered bytes, nil means the flushTimeout is used.
+	adaptiveFlush *adaptiveFlush
+	// tagFamilyCodecs are the codecs the parts encode the tag families with, the others are encoded by codecDefault.
+	tagFamilyCodecs map[string]valuesCodec
+}
+
+// blockLength returns the maximum number of data points in a block written by the table.
+func (o option) blockLength() int {
+	if o.blockSize <= 0 {
+		return maxBlockLength
+	}
+	return o.blockSize
 }
 
 type measure struct {
@@ -55,6 +83,9 @@ type measure struct {
 	l                 *logger.Logger
 	schema            *databasev1.Measure
 	processorManager  *topNProcessorManager
+	rollups           *rollupRegistry
+	rollupProcessor   *rollupProcessor
+	reopenMu          *sync.RWMutex
 	name              string
 	group             string
 	indexRules        []*databasev1.IndexRule
@@ -83,6 +114,19 @@ func (s *measure) startSteamingManager(pipeline queue.Queue) error {
 	return s.processorManager.start()
 }
 
+func (s *measure) startRollup(pipeline queue.Queue) error {
+	if s.schema.GetRollup() == nil || s.rollups == nil {
+		return nil
+	}
+	p, err := newRollupProcessor(s, pipeline)
+	if err != nil {
+		return err
+	}
+	s.rollupProcessor = p
+	s.rollups.register(p)
+	return nil
+}
+
 func (s *measure) GetSchema() *databasev1.Measure {
 	return s.schema
 }
@@ -92,10 +136,15 @@ func (s *measure) GetIndexRules() []*databasev1.IndexRule {
 }
 
 func (s *measure) Close() error {
+	var err error
+	if s.rollupProcessor != nil {
+		s.rollups.unregister(s.rollupProcessor)
+		err = s.rollupProcessor.Close()
+	}
 	if s.processorManager == nil {
-		return nil
+		return err
 	}
-	return s.processorManager.Close()
+	return multierr.Append(err, s.processorManager.Close())
 }
 
 func (s *measure) parseSpec() (err error) {
@@ -115,12 +164,15 @@ type measureSpec struct {
 }
 
 func openMeasure(shardNum uint32, db schema.Supplier, spec measureSpec, l *logger.Logger, pipeline queue.Queue,
+	rollups *rollupRegistry, reopenMu *sync.RWMutex,
 ) (*measure, error) {
 	m := &measure{
 		shardNum:         shardNum,
 		schema:           spec.schema,
 		indexRules:       spec.indexRules,
 		topNAggregations: spec.topNAggregations,
+		rollups:          rollups,
+		reopenMu:         reopenMu,
 		l:                l,
 	}
 	if err := m.parseSpec(); err != nil {
@@ -135,5 +187,9 @@ func openMeasure(shardNum uint32, db schema.Supplier, spec measureSpec, l *logge
 		l.Err(startErr).Str("measure", spec.schema.GetMetadata().GetName()).
 			Msg("fail to start streaming manager")
 	}
+	if rollupErr := m.startRollup(pipeline); rollupErr != nil {
+		l.Err(rollupErr).Str("measure", spec.schema.GetMetadata().GetName()).
+			Msg("fail to start rollup")
+	}
 	return m, nil
 }
@@ -63,6 +63,7 @@ type services struct {
 	measure         measure.Service
 	metadataService metadata.Service
 	pipeline        queue.Queue
+	root            string
 }
 
 func setUp() (*services, func()) {
@@ -77,7 +78,7 @@ func setUp() (*services, func()) {
 	gomega.Expect(err).NotTo(gomega.HaveOccurred())
 
 	// Init Measure Service
-	measureService, err := measure.NewService(context.TODO(), metadataService, pipeline)
+	measureService, err := measure.NewService(context.TODO(), metadataService, pipeline, nil)
 	gomega.Expect(err).NotTo(gomega.HaveOccurred())
 	preloadMeasureSvc := &preloadMeasureService{metaSvc: metadataService}
 	var flags []string
@@ -101,6 +102,7 @@ func setUp() (*services, func()) {
 			measure:         measureService,
 			metadataService: metadataService,
 			pipeline:        pipeline,
+			root:            rootPath,
 		}, func() {
 			moduleDeferFunc()
 			metaDeferFunc()
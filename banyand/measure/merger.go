@@ -20,6 +20,7 @@ package measure
 import (
 	"errors"
 	"fmt"
+	"slices"
 	"sync/atomic"
 	"time"
 
@@ -47,8 +48,10 @@ func (tst *tsTable) mergeLoop(merges chan *mergerIntroduction, flusherNotifier w
 		case <-tst.loopCloser.CloseNotify():
 			return
 		case <-ew.Watch():
+			tst.mergeMu.Lock()
 			curSnapshot := tst.currentSnapshot()
 			if curSnapshot == nil {
+				tst.mergeMu.Unlock()
 				continue
 			}
 			if curSnapshot.epoch != epoch {
@@ -56,15 +59,18 @@ func (tst *tsTable) mergeLoop(merges chan *mergerIntroduction, flusherNotifier w
 				if pwsChunk, err = tst.mergeSnapshot(curSnapshot, merges, pwsChunk[:0]); err != nil {
 					if errors.Is(err, errClosed) {
 						curSnapshot.decRef()
+						tst.mergeMu.Unlock()
 						return
 					}
 					tst.l.Logger.Warn().Err(err).Msgf("cannot merge snapshot: %d", curSnapshot.epoch)
 					curSnapshot.decRef()
+					tst.mergeMu.Unlock()
 					continue
 				}
 				epoch = curSnapshot.epoch
 			}
 			curSnapshot.decRef()
+			tst.mergeMu.Unlock()
 			ew = flusherNotifier.Add(epoch, tst.loopCloser.CloseNotify())
 			if ew == nil {
 				return
@@ -75,14 +81,19 @@ func (tst *tsTable) mergeLoop(merges chan *mergerIntroduction, flusherNotifier w
 
 func (tst *tsTable) mergeSnapshot(curSnapshot *snapshot, merges chan *mergerIntroduction, dst []*partWrapper) ([]*partWrapper, error) {
 	freeDiskSize := tst.freeDiskSpace(tst.root)
-	var toBeMerged map[uint64]struct{}
-	dst, toBeMerged = tst.getPartsToMerge(curSnapshot, freeDiskSize, dst)
+	dst = tst.getPartsToMerge(curSnapshot, freeDiskSize, dst)
 	if len(dst) < 2 {
 		return nil, nil
 	}
-	if _, err := tst.mergePartsThenSendIntroduction(snapshotCreatorMerger, dst,
-		toBeMerged, merges, tst.loopCloser.CloseNotify()); err != nil {
-		return dst, err
+	for _, pws := range tst.option.mergePolicy.splitByTarget(dst) {
+		toBeMerged := make(map[uint64]struct{}, len(pws))
+		for _, pw := range pws {
+			toBeMerged[pw.ID()] = struct{}{}
+		}
+		if _, err := tst.mergePartsThenSendIntroduction(snapshotCreatorMerger, pws,
+			toBeMerged, merges, tst.loopCloser.CloseNotify()); err != nil {
+			return dst, err
+		}
 	}
 	return dst, nil
 }
@@ -93,7 +104,7 @@ func (tst *tsTable) mergePartsThenSendIntroduction(creator snapshotCreator, part
 	reservedSpace := tst.reserveSpace(parts)
 	defer releaseDiskSpace(reservedSpace)
 	start := time.Now()
-	newPart, err := mergeParts(tst.fileSystem, closeCh, parts, atomic.AddUint64(&tst.curPartID, 1), tst.root)
+	newPart, err := mergeParts(tst.fileSystem, closeCh, parts, atomic.AddUint64(&tst.curPartID, 1), tst.root, tst.option.blockLength(), tst.option.tagFamilyCodecs)
 	if err != nil {
 		return nil, err
 	}
@@ -149,6 +160,12 @@ func (tst *tsTable) mergePartsThenSendIntroduction(creator snapshotCreator, part
 	case <-tst.loopCloser.CloseNotify():
 		return newPart, errClosed
 	}
+	if creator == snapshotCreatorMergedFlusher {
+		// Merging memory parts is the first time these data points reach the disk.
+		atomic.AddUint64(&tst.ingestedBytes, newPart.p.partMetadata.CompressedSizeBytes)
+	} else {
+		atomic.AddUint64(&tst.mergedBytes, newPart.p.partMetadata.CompressedSizeBytes)
+	}
 	return newPart, nil
 }
 
@@ -181,7 +198,7 @@ func releaseDiskSpace(n uint64) {
 
 var reservedDiskSpace uint64
 
-func (tst *tsTable) getPartsToMerge(snapshot *snapshot, freeDiskSize uint64, dst []*partWrapper) ([]*partWrapper, map[uint64]struct{}) {
+func (tst *tsTable) getPartsToMerge(snapshot *snapshot, freeDiskSize uint64, dst []*partWrapper) []*partWrapper {
 	var parts []*partWrapper
 
 	for _, pw := range snapshot.parts {
@@ -191,16 +208,33 @@ func (tst *tsTable) getPartsToMerge(snapshot *snapshot, freeDiskSize uint64, dst
 		parts = append(parts, pw)
 	}
 
-	dst = tst.option.mergePolicy.getPartsToMerge(dst, parts, freeDiskSize)
-	if len(dst) == 0 {
-		return nil, nil
+	for _, group := range groupByShardNum(parts) {
+		if dst = tst.option.mergePolicy.getPartsToMerge(dst, group, freeDiskSize); len(dst) > 0 {
+			break
+		}
 	}
+	return dst
+}
 
-	toBeMerged := make(map[uint64]struct{})
-	for _, pw := range dst {
-		toBeMerged[pw.ID()] = struct{}{}
+// groupByShardNum groups the parts by the number of shards they're written with in the ascending order.
+// The parts written before and after resharding aren't merged together, otherwise a merged part
+// couldn't tell which data points of a series outrank the ones in another shard.
+func groupByShardNum(pws []*partWrapper) [][]*partWrapper {
+	groups := make(map[uint32][]*partWrapper)
+	var shardNums []uint32
+	for _, pw := range pws {
+		n := pw.p.partMetadata.ShardNum
+		if _, ok := groups[n]; !ok {
+			shardNums = append(shardNums, n)
+		}
+		groups[n] = append(groups[n], pw)
 	}
-	return dst, toBeMerged
+	slices.Sort(shardNums)
+	result := make([][]*partWrapper, 0, len(groups))
+	for _, n := range shardNums {
+		result = append(result, groups[n])
+	}
+	return result
 }
 
 func (tst *tsTable) reserveSpace(parts []*partWrapper) uint64 {
@@ -216,7 +250,9 @@ func (tst *tsTable) reserveSpace(parts []*partWrapper) uint64 {
 
 var errNoPartToMerge = fmt.Errorf("no part to merge")
 
-func mergeParts(fileSystem fs.FileSystem, closeCh <-chan struct{}, parts []*partWrapper, partID uint64, root string) (*partWrapper, error) {
+func mergeParts(fileSystem fs.FileSystem, closeCh <-chan struct{}, parts []*partWrapper, partID uint64, root string, blockSize int,
+	codecs map[string]valuesCodec,
+) (*partWrapper, error) {
 	if len(parts) == 0 {
 		return nil, errNoPartToMerge
 	}
@@ -231,8 +267,9 @@ func mergeParts(fileSystem fs.FileSystem, closeCh <-chan struct{}, parts []*part
 	br.init(pii)
 	bw := generateBlockWriter()
 	bw.mustInitForFilePart(fileSystem, dstPath)
+	bw.setTagFamilyCodecs(codecs)
 
-	pm, err := mergeBlocks(closeCh, bw, br)
+	pm, err := mergeBlocks(closeCh, bw, br, blockSize)
 	releaseBlockWriter(bw)
 	releaseBlockReader(br)
 	for i := range pii {
@@ -241,6 +278,8 @@ func mergeParts(fileSystem fs.FileSystem, closeCh <-chan struct{}, parts []*part
 	if err != nil {
 		return nil, err
 	}
+	// The parts merged together are written with the same number of shards.
+	pm.ShardNum = parts[0].p.partMetadata.ShardNum
 	pm.mustWriteMetadata(fileSystem, dstPath)
 	fileSystem.SyncPath(dstPath)
 	p := mustOpenFilePart(partID, root, fileSystem)
@@ -249,7 +288,9 @@ func mergeParts(fileSystem fs.FileSystem, closeCh <-chan struct{}, parts []*part
 
 var errClosed = fmt.Errorf("the merger is closed")
 
-func mergeBlocks(closeCh <-chan struct{}, bw *blockWriter, br *blockReader) (*partMetadata, error) {
+// mergeBlocks rewrites the blocks read by br into blocks holding at most blockSize data points.
+// Source parts may have been written with a different block size.
+func mergeBlocks(closeCh <-chan struct{}, bw *blockWriter, br *blockReader, blockSize int) (*partMetadata, error) {
 	pendingBlockIsEmpty := true
 	pendingBlock := generateBlockPointer()
 	defer releaseBlockPointer(pendingBlock)
@@ -283,7 +324,7 @@ func mergeBlocks(closeCh <-chan struct{}, bw *blockWriter, br *blockReader) (*pa
 		}
 
 		if pendingBlock.bm.seriesID != b.bm.seriesID ||
-			(pendingBlock.isFull() && pendingBlock.bm.timestamps.max <= b.bm.timestamps.min) {
+			(pendingBlock.isFull(blockSize) && pendingBlock.bm.timestamps.max <= b.bm.timestamps.min) {
 			bw.mustWriteBlock(pendingBlock.bm.seriesID, &pendingBlock.block)
 			releaseDecoder()
 			pendingBlock.reset()
@@ -300,7 +341,7 @@ func mergeBlocks(closeCh <-chan struct{}, bw *blockWriter, br *blockReader) (*pa
 		tmpBlock.bm.seriesID = b.bm.seriesID
 		br.loadBlockData(getDecoder())
 		mergeTwoBlocks(tmpBlock, pendingBlock, b)
-		if len(tmpBlock.timestamps) <= maxBlockLength && tmpBlock.uncompressedSizeBytes() <= maxUncompressedBlockSize {
+		if len(tmpBlock.timestamps) <= blockSize && tmpBlock.uncompressedSizeBytes() <= maxUncompressedBlockSize {
 			if len(tmpBlock.timestamps) == 0 {
 				pendingBlockIsEmpty = true
 			}
@@ -308,13 +349,15 @@ func mergeBlocks(closeCh <-chan struct{}, bw *blockWriter, br *blockReader) (*pa
 			continue
 		}
 
-		if len(tmpBlock.timestamps) <= maxBlockLength {
+		if len(tmpBlock.timestamps) <= blockSize {
 			bw.mustWriteBlock(tmpBlock.bm.seriesID, &tmpBlock.block)
 			releaseDecoder()
 			continue
 		}
-		tmpBlock.idx = maxBlockLength
+		tmpBlock.idx = blockSize
+		pendingBlock.reset()
 		pendingBlock.copyFrom(tmpBlock)
+		pendingBlock.updateMetadata()
 		l := tmpBlock.idx
 		tmpBlock.idx = 0
 		if tmpBlock2 == nil {
@@ -335,6 +378,7 @@ func mergeBlocks(closeCh <-chan struct{}, bw *blockWriter, br *blockReader) (*pa
 	releaseDecoder()
 	var result partMetadata
 	bw.Flush(&result)
+	result.BlockSize = blockSize
 	return &result, nil
 }
 
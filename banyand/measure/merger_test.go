@@ -301,7 +301,7 @@ func Test_mergeParts(t *testing.T) {
 			verify := func(t *testing.T, pp []*partWrapper, fileSystem fs.FileSystem, root string, partID uint64) {
 				closeCh := make(chan struct{})
 				defer close(closeCh)
-				p, err := mergeParts(fileSystem, closeCh, pp, partID, root)
+				p, err := mergeParts(fileSystem, closeCh, pp, partID, root, maxBlockLength, nil)
 				if tt.wantErr != nil {
 					if !errors.Is(err, tt.wantErr) {
 						t.Fatalf("Unexpected error: got %v, want %v", err, tt.wantErr)
@@ -340,7 +340,7 @@ func Test_mergeParts(t *testing.T) {
 				}()
 				for _, dps := range tt.dpsList {
 					mp := generateMemPart()
-					mp.mustInitFromDataPoints(dps)
+					mp.mustInitFromDataPoints(dps, maxBlockLength, nil)
 					pp = append(pp, newPartWrapper(mp, openMemPart(mp)))
 				}
 				verify(t, pp, fs.NewLocalFileSystem(), tmpPath, 1)
@@ -358,7 +358,7 @@ func Test_mergeParts(t *testing.T) {
 				fileSystem := fs.NewLocalFileSystem()
 				for i, dps := range tt.dpsList {
 					mp := generateMemPart()
-					mp.mustInitFromDataPoints(dps)
+					mp.mustInitFromDataPoints(dps, maxBlockLength, nil)
 					mp.mustFlush(fileSystem, partPath(tmpPath, uint64(i)))
 					filePW := newPartWrapper(nil, mustOpenFilePart(uint64(i), tmpPath, fileSystem))
 					filePW.p.partMetadata.ID = uint64(i)
@@ -370,3 +370,12 @@ func Test_mergeParts(t *testing.T) {
 		})
 	}
 }
+
+func TestGroupByShardNum(t *testing.T) {
+	newPart := func(id uint64, shardNum uint32) *partWrapper {
+		return newPartWrapper(nil, &part{partMetadata: partMetadata{ID: id, ShardNum: shardNum}})
+	}
+	p1, p2, p3, p4 := newPart(1, 4), newPart(2, 2), newPart(3, 4), newPart(4, 2)
+	require.Equal(t, [][]*partWrapper{{p2, p4}, {p1, p3}}, groupByShardNum([]*partWrapper{p1, p2, p3, p4}))
+	require.Empty(t, groupByShardNum(nil))
+}
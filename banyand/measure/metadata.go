@@ -21,7 +21,9 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
 	"path"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -47,16 +49,22 @@ type schemaRepo struct {
 	resourceSchema.Repository
 	l        *logger.Logger
 	metadata metadata.Repo
+	supplier *supplier
+	missing  *missingMeasureCache
 }
 
 func newSchemaRepo(path string, svc *service) *schemaRepo {
+	s := newSupplier(path, svc)
 	sr := &schemaRepo{
 		l:        svc.l,
 		metadata: svc.metadata,
+		supplier: s,
+		missing:  s.missing,
 		Repository: resourceSchema.NewRepository(
 			svc.metadata,
 			svc.l,
-			newSupplier(path, svc),
+			s,
+			svc.gracePeriod,
 		),
 	}
 	sr.start()
@@ -112,6 +120,7 @@ func (sr *schemaRepo) OnAddOrUpdate(metadata schema.Metadata) {
 			sr.l.Warn().Err(err).Msg("group is ignored")
 			return
 		}
+		sr.missing.reset()
 		sr.SendMetadataEvent(resourceSchema.MetadataEvent{
 			Typ:      resourceSchema.EventAddOrUpdate,
 			Kind:     resourceSchema.EventKindGroup,
@@ -233,8 +242,13 @@ func (sr *schemaRepo) OnDelete(metadata schema.Metadata) {
 }
 
 func (sr *schemaRepo) loadMeasure(metadata *commonv1.Metadata) (*measure, bool) {
+	key := missingMeasureKey(metadata)
+	if sr.missing.contains(key) {
+		return nil, false
+	}
 	r, ok := sr.LoadResource(metadata)
 	if !ok {
+		sr.missing.put(key)
 		return nil, false
 	}
 	s, ok := r.Delegated().(*measure)
@@ -258,9 +272,26 @@ var _ resourceSchema.ResourceSupplier = (*supplier)(nil)
 type supplier struct {
 	metadata metadata.Repo
 	pipeline queue.Queue
-	option   option
 	l        *logger.Logger
+	missing  *missingMeasureCache
+	rollups  *rollupRegistry
 	path     string
+	option   option
+	pathMu   sync.RWMutex
+	// reopenMu is held by the queries for reading, and by a data path migration reopening the groups for writing.
+	reopenMu sync.RWMutex
+}
+
+func (s *supplier) getPath() string {
+	s.pathMu.RLock()
+	defer s.pathMu.RUnlock()
+	return s.path
+}
+
+func (s *supplier) setPath(path string) {
+	s.pathMu.Lock()
+	defer s.pathMu.Unlock()
+	s.path = path
 }
 
 func newSupplier(path string, svc *service) *supplier {
@@ -270,16 +301,19 @@ func newSupplier(path string, svc *service) *supplier {
 		l:        svc.l,
 		pipeline: svc.localPipeline,
 		option:   svc.option,
+		missing:  newMissingMeasureCache(svc.missingMeasureTTL),
+		rollups:  newRollupRegistry(),
 	}
 }
 
 func (s *supplier) OpenResource(shardNum uint32, supplier resourceSchema.Supplier, spec resourceSchema.Resource) (io.Closer, error) {
 	measureSchema := spec.Schema().(*databasev1.Measure)
+	s.missing.invalidate(missingMeasureKey(measureSchema.GetMetadata()))
 	return openMeasure(shardNum, supplier, measureSpec{
 		schema:           measureSchema,
 		indexRules:       spec.IndexRules(),
 		topNAggregations: spec.TopN(),
-	}, s.l, s.pipeline)
+	}, s.l, s.pipeline, s.rollups, &s.reopenMu)
 }
 
 func (s *supplier) ResourceSchema(md *commonv1.Metadata) (resourceSchema.ResourceSchema, error) {
@@ -289,14 +323,9 @@ func (s *supplier) ResourceSchema(md *commonv1.Metadata) (resourceSchema.Resourc
 }
 
 func (s *supplier) OpenDB(groupSchema *commonv1.Group) (io.Closer, error) {
-	opts := storage.TSDBOpts[*tsTable, option]{
-		ShardNum:                       groupSchema.ResourceOpts.ShardNum,
-		Location:                       path.Join(s.path, groupSchema.Metadata.Name),
-		TSTableCreator:                 newTSTable,
-		SegmentInterval:                storage.MustToIntervalRule(groupSchema.ResourceOpts.SegmentInterval),
-		TTL:                            storage.MustToIntervalRule(groupSchema.ResourceOpts.Ttl),
-		Option:                         s.option,
-		SeriesIndexFlushTimeoutSeconds: s.option.flushTimeout.Nanoseconds() / int64(time.Second),
+	opts, err := s.tsdbOpts(groupSchema)
+	if err != nil {
+		return nil, err
 	}
 	name := groupSchema.Metadata.Name
 	return storage.OpenTSDB(
@@ -308,6 +337,42 @@ func (s *supplier) OpenDB(groupSchema *commonv1.Group) (io.Closer, error) {
 		opts)
 }
 
+// tsdbOpts resolves the options of a group's tsdb from the flags and the group's resource options.
+func (s *supplier) tsdbOpts(groupSchema *commonv1.Group) (storage.TSDBOpts[*tsTable, option], error) {
+	opts := storage.TSDBOpts[*tsTable, option]{
+		ShardNum:                       groupSchema.ResourceOpts.ShardNum,
+		Location:                       path.Join(s.getPath(), groupSchema.Metadata.Name),
+		TSTableCreator:                 newTSTable,
+		SegmentInterval:                storage.MustToIntervalRule(groupSchema.ResourceOpts.SegmentInterval),
+		TTL:                            storage.MustToIntervalRule(groupSchema.ResourceOpts.Ttl),
+		Option:                         s.option,
+		SeriesIndexFlushTimeoutSeconds: s.option.flushTimeout.Nanoseconds() / int64(time.Second),
+		SeriesCacheSize:                s.option.seriesCacheSize,
+		SeriesCacheTTL:                 s.option.seriesCacheTTL,
+		SeriesCachePolicy:              s.option.seriesCachePolicy,
+		SeriesCacheWarmup:              s.option.seriesCacheWarmup,
+		MaxSeriesPerQuery:              s.option.maxSeriesPerQuery,
+		MaxOpenSegments:                s.option.maxOpenSegments,
+		MaxDiskUsagePercent:            s.option.maxDiskUsagePercent,
+		MinRetainedSegments:            s.option.minRetainedSegments,
+		OutOfRetentionPolicy:           storage.ToOutOfRetentionPolicy(groupSchema.ResourceOpts.GetOutOfRetentionPolicy()),
+	}
+	if fw := groupSchema.ResourceOpts.GetFutureWindow(); fw != nil {
+		opts.FutureWindow = storage.MustToIntervalRule(fw)
+	}
+	if groupSchema.ResourceOpts.BlockSize != nil {
+		if err := validate.BlockSize(groupSchema.ResourceOpts); err != nil {
+			return opts, err
+		}
+		opts.Option.blockSize = int(groupSchema.ResourceOpts.GetBlockSize())
+	}
+	return opts, nil
+}
+
+func (s *supplier) DropDB(groupSchema *commonv1.Group) error {
+	return os.RemoveAll(path.Join(s.getPath(), groupSchema.Metadata.Name))
+}
+
 type portableSupplier struct {
 	metadata metadata.Repo
 	l        *logger.Logger
@@ -336,5 +401,5 @@ func (s *portableSupplier) OpenResource(shardNum uint32, _ resourceSchema.Suppli
 		schema:           measureSchema,
 		indexRules:       spec.IndexRules(),
 		topNAggregations: spec.TopN(),
-	}, s.l, nil)
+	}, s.l, nil, nil, nil)
 }
@@ -19,6 +19,7 @@ package measure_test
 
 import (
 	"context"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -103,6 +104,27 @@ var _ = Describe("Metadata", func() {
 			}).WithTimeout(flags.EventuallyTimeout).Should(MatchError(measure.ErrMeasureNotExist))
 		})
 
+		It("should find a created measure despite the cached misses", func() {
+			md := &commonv1.Metadata{Name: "service_cpm_minute_created", Group: "sw_metric"}
+			for i := 0; i < 10; i++ {
+				_, err := svcs.measure.Measure(md)
+				Expect(err).Should(MatchError(measure.ErrMeasureNotExist))
+			}
+			measureSchema, err := svcs.metadataService.MeasureRegistry().GetMeasure(context.TODO(), &commonv1.Metadata{
+				Name:  "service_cpm_minute",
+				Group: "sw_metric",
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+			measureSchema.Metadata = md
+			_, err = svcs.metadataService.MeasureRegistry().CreateMeasure(context.TODO(), measureSchema)
+			Expect(err).ShouldNot(HaveOccurred())
+			// The misses are cached for longer than the timeout, so the measure is found once it's opened.
+			Eventually(func() error {
+				_, err := svcs.measure.Measure(md)
+				return err
+			}).WithTimeout(time.Second).Should(Succeed())
+		})
+
 		Context("Update a measure", func() {
 			var measureSchema *databasev1.Measure
 
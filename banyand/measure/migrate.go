@@ -0,0 +1,481 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+
+	"github.com/apache/skywalking-banyandb/banyand/internal/storage"
+	"github.com/apache/skywalking-banyandb/banyand/observability"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+const (
+	// migrationStagingSuffix names the directory the data is copied to before it's switched to.
+	migrationStagingSuffix = ".migrating"
+	// migrationObsoleteSuffix names the old data directory while it's being deleted.
+	migrationObsoleteSuffix = ".obsolete"
+	// migrationMarker records the source of a migrated directory and the digest of the source content
+	// at the time of the copy, until the source is deleted.
+	migrationMarker = "migrated-from"
+	// migrationSourceMarker records the destination in the source once it's copied.
+	// A node started on the source path rejects writes while it exists, since the source is deleted
+	// when the migration is resumed.
+	migrationSourceMarker = "migrated-to"
+	// migrationRedirectSuffix names the file next to the old data directory that records the root path
+	// the data is migrated to. A node started on the old root path serves the data from the new one.
+	migrationRedirectSuffix = ".migrated-to"
+	migrationPollInterval   = 100 * time.Millisecond
+)
+
+var (
+	errMigrationDestNotEmpty = errors.New("the destination of the migration is not empty")
+	errDataPathMigrated      = errors.New("the data path is migrated")
+	migrationChecksumTable   = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// MigrateDataPath moves the data under the root path "from" to the root path "to", and serves
+// the data from "to" afterwards.
+//
+// The data is copied while the writes go on, then the writes are held while the files changed
+// since are synced and verified, the groups are reopened from the new path, and the old data is
+// deleted once the new root path is persisted. An interrupted migration leaves the old data
+// in use, and calling it again resumes the copy. A node restarted on the old path once the data is
+// copied rejects writes until the migration is resumed.
+func (s *service) MigrateDataPath(ctx context.Context, from, to string) error {
+	s.migrateMu.Lock()
+	defer s.migrateMu.Unlock()
+	from, to = filepath.Clean(from), filepath.Clean(to)
+	if from != filepath.Clean(s.root) {
+		return errors.Errorf("%s isn't the root path %s in use", from, s.root)
+	}
+	if from == to {
+		return nil
+	}
+	src, dst := filepath.Join(from, s.Name()), filepath.Join(to, s.Name())
+	if isWithinPath(src, dst) || isWithinPath(dst, src) {
+		return errors.Errorf("cannot migrate between nested paths %s and %s", src, dst)
+	}
+	s.l.Info().Str("from", src).Str("to", dst).Msg("migrating the data path")
+	if err := preSyncDir(ctx, src, dst); err != nil {
+		return errors.WithMessagef(err, "cannot copy the data path from %s to %s", src, dst)
+	}
+
+	s.writeListener.migrationMu.Lock()
+	defer s.writeListener.migrationMu.Unlock()
+	if err := s.schemaRepo.waitForFlush(ctx); err != nil {
+		return err
+	}
+	// The queries wait for the groups to be reopened.
+	s.schemaRepo.supplier.reopenMu.Lock()
+	err := s.schemaRepo.ReopenGroups(func() error {
+		if err := migrateDir(ctx, src, dst); err != nil {
+			return err
+		}
+		s.schemaRepo.supplier.setPath(dst)
+		s.root = to
+		return nil
+	})
+	s.schemaRepo.supplier.reopenMu.Unlock()
+	if err != nil {
+		return errors.WithMessagef(err, "cannot migrate the data path from %s to %s", src, dst)
+	}
+	// The writes go to dst from now on.
+	s.writeListener.migratedErr = nil
+	observability.RemovePath(src)
+	observability.UpdatePath(dst)
+
+	// The old data is the only record of the new root path until it's persisted, since a node
+	// restarted on the old root path would find nothing to serve otherwise.
+	if err = persistMigratedRoot(s.Name(), from, to); err != nil {
+		s.l.Error().Err(err).Str("from", src).Str("to", dst).Msg("keep the old data path, since the new root path isn't persisted")
+		return errors.WithMessagef(err, "cannot persist the root path %s, the old data path %s is kept", to, src)
+	}
+	// The old data is renamed first, so that an interrupted deletion never leaves a partial directory behind.
+	obsolete := src + migrationObsoleteSuffix
+	if err = os.Rename(src, obsolete); err != nil {
+		return errors.WithMessage(err, "cannot clean up the old data path")
+	}
+	if err = os.RemoveAll(obsolete); err != nil {
+		return errors.WithMessage(err, "cannot clean up the old data path")
+	}
+	s.l.Info().Str("from", src).Str("to", dst).Msg("migrated the data path")
+	return os.Remove(filepath.Join(dst, migrationMarker))
+}
+
+// persistMigratedRoot records "to" next to the data directory under "from", and drops the record
+// under "to" left by an earlier migration the other way round.
+func persistMigratedRoot(name, from, to string) error {
+	if err := os.Remove(filepath.Join(to, name+migrationRedirectSuffix)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := writeFileSync(filepath.Join(from, name+migrationRedirectSuffix), []byte(to)); err != nil {
+		return err
+	}
+	return syncPath(from)
+}
+
+// resolveMigratedRoot follows the root paths the data under root is migrated to.
+func resolveMigratedRoot(name, root string) (string, error) {
+	visited := map[string]struct{}{}
+	for {
+		root = filepath.Clean(root)
+		if _, ok := visited[root]; ok {
+			return "", errors.Errorf("the migrated root paths of %s form a loop at %s", name, root)
+		}
+		visited[root] = struct{}{}
+		if _, err := os.Stat(filepath.Join(root, name)); err == nil {
+			// The data is still here, since the migration was interrupted before it's deleted.
+			return root, nil
+		}
+		to, err := os.ReadFile(filepath.Join(root, name+migrationRedirectSuffix))
+		if errors.Is(err, fs.ErrNotExist) {
+			return root, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		root = string(to)
+	}
+}
+
+// waitForFlush waits until the in-memory parts and the series index of all groups are persisted,
+// since they're lost once a group is closed.
+func (sr *schemaRepo) waitForFlush(ctx context.Context) error {
+	ticker := time.NewTicker(migrationPollInterval)
+	defer ticker.Stop()
+	for sr.hasMemParts() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	for _, g := range sr.LoadAllGroups() {
+		if db := g.SupplyTSDB(); db != nil {
+			if err := db.(storage.TSDB[*tsTable, option]).PersistSeriesIndex(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (sr *schemaRepo) hasMemParts() bool {
+	for _, g := range sr.LoadAllGroups() {
+		db := g.SupplyTSDB()
+		if db == nil {
+			continue
+		}
+		found := false
+		for _, tw := range db.(storage.TSDB[*tsTable, option]).SelectTSTables(timestamp.AllTime) {
+			found = found || tw.Table().hasMemParts()
+			tw.DecRef()
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+func (tst *tsTable) hasMemParts() bool {
+	snp := tst.currentSnapshot()
+	if snp == nil {
+		return false
+	}
+	defer snp.decRef()
+	for _, pw := range snp.parts {
+		if pw.mp != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// preSyncDir copies src to the staging directory next to dst, so that only the files changed
+// afterwards are copied by migrateDir. It's skipped if dst exists, which migrateDir resumes from.
+func preSyncDir(ctx context.Context, src, dst string) error {
+	if _, err := os.Stat(dst); err == nil || !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return syncDir(ctx, src, dst+migrationStagingSuffix)
+}
+
+// migrateDir copies src to a staging directory next to dst, and renames the staging directory to dst
+// once every file is verified. A staging directory left by an interrupted migration is reused.
+func migrateDir(ctx context.Context, src, dst string) error {
+	migrated, err := isMigrated(src, dst)
+	if err != nil || migrated {
+		return err
+	}
+	staging := dst + migrationStagingSuffix
+	if err = syncDir(ctx, src, staging); err != nil {
+		return err
+	}
+	digest, err := dirDigest(src)
+	if err != nil {
+		return err
+	}
+	if err = writeFileSync(filepath.Join(staging, migrationMarker), []byte(src+"\n"+digest)); err != nil {
+		return err
+	}
+	sourceMarker := filepath.Join(src, migrationSourceMarker)
+	if err = writeFileSync(sourceMarker, []byte(dst)); err != nil {
+		return err
+	}
+	if err = os.Rename(staging, dst); err != nil {
+		// The groups are reopened from src, which keeps taking writes.
+		return multierr.Append(err, os.Remove(sourceMarker))
+	}
+	return syncPath(filepath.Dir(dst))
+}
+
+// isMigrated reports whether dst holds a completed copy of src, whose source isn't deleted yet.
+// A copy made before src is changed, such as by a node restarted on src before the source marker
+// is written, is moved back to the staging directory to be synced again.
+func isMigrated(src, dst string) (bool, error) {
+	marker, err := os.ReadFile(filepath.Join(dst, migrationMarker))
+	if err == nil {
+		from, digest, _ := strings.Cut(string(marker), "\n")
+		if from != src {
+			return false, errors.WithMessagef(errMigrationDestNotEmpty, "%s is migrated from %s", dst, from)
+		}
+		current, digestErr := dirDigest(src)
+		if digestErr != nil {
+			return false, digestErr
+		}
+		if current == digest {
+			return true, nil
+		}
+		staging := dst + migrationStagingSuffix
+		if err = os.RemoveAll(staging); err != nil {
+			return false, err
+		}
+		return false, os.Rename(dst, staging)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+	entries, err := os.ReadDir(dst)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if len(entries) > 0 {
+		return false, errors.WithMessage(errMigrationDestNotEmpty, dst)
+	}
+	return false, os.Remove(dst)
+}
+
+// dirDigest digests the paths, sizes and modification times of the files under dir,
+// except the source marker of a migration.
+func dirDigest(dir string) (string, error) {
+	h := crc32.New(migrationChecksumTable)
+	if err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil || rel == migrationSourceMarker || !d.Type().IsRegular() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(h, "%s:%d:%d\n", rel, info.Size(), info.ModTime().UnixNano())
+		return err
+	}); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%08x", h.Sum32()), nil
+}
+
+// checkMigratedSource returns errDataPathMigrated if dir is the source of a copied migration.
+func checkMigratedSource(dir string) error {
+	target, err := os.ReadFile(filepath.Join(dir, migrationSourceMarker))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return errors.WithMessagef(errDataPathMigrated, "%s is migrated to %s, resume the migration or serve the new path", dir, target)
+}
+
+// syncDir makes dst a verified copy of src. The files dst already holds are kept if they match,
+// and the ones src doesn't have are deleted.
+func syncDir(ctx context.Context, src, dst string) error {
+	if err := filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		if rel == migrationSourceMarker {
+			return nil
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm())
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return copyFileVerified(p, target, info)
+	}); err != nil {
+		return err
+	}
+	return filepath.WalkDir(dst, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dst, p)
+		if err != nil {
+			return err
+		}
+		if _, err = os.Lstat(filepath.Join(src, rel)); !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		if err = os.RemoveAll(p); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
+
+// copyFileVerified copies src to dst and keeps the modification time of src. A dst of the same
+// size and modification time is a verified copy, since a copy only gets to dst once verified.
+func copyFileVerified(src, dst string, info fs.FileInfo) error {
+	if existing, err := os.Stat(dst); err == nil && existing.Size() == info.Size() {
+		if existing.ModTime().Equal(info.ModTime()) {
+			return nil
+		}
+		srcSum, err := fileChecksum(src)
+		if err != nil {
+			return err
+		}
+		dstSum, err := fileChecksum(dst)
+		if err != nil {
+			return err
+		}
+		if srcSum == dstSum {
+			return nil
+		}
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	tmp := dst + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	h := crc32.New(migrationChecksumTable)
+	_, err = io.Copy(io.MultiWriter(out, h), in)
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	dstSum, err := fileChecksum(tmp)
+	if err != nil {
+		return err
+	}
+	if dstSum != h.Sum32() {
+		return errors.Errorf("the copy of %s is corrupted: checksum %08x, want %08x", src, dstSum, h.Sum32())
+	}
+	if err = os.Chtimes(tmp, info.ModTime(), info.ModTime()); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+func fileChecksum(name string) (uint32, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	h := crc32.New(migrationChecksumTable)
+	if _, err = io.Copy(h, f); err != nil {
+		return 0, err
+	}
+	return h.Sum32(), nil
+}
+
+func writeFileSync(name string, data []byte) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func syncPath(name string) error {
+	d, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+func isWithinPath(parent, p string) bool {
+	rel, err := filepath.Rel(parent, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,97 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/test"
+)
+
+func TestMigrateDirResyncsChangedSource(t *testing.T) {
+	root, defFn := test.Space(require.New(t))
+	defer defFn()
+	src, dst := filepath.Join(root, "from", "measure"), filepath.Join(root, "to", "measure")
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "sw_metric"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "sw_metric", "part"), []byte("old"), 0o600))
+	require.NoError(t, migrateDir(context.Background(), src, dst))
+	require.ErrorIs(t, checkMigratedSource(src), errDataPathMigrated)
+
+	// The node is restarted on the source path, and writes before the source marker is honoured.
+	require.NoError(t, os.WriteFile(filepath.Join(src, "sw_metric", "part"), []byte("new writes"), 0o600))
+	later := time.Now().Add(time.Second)
+	require.NoError(t, os.Chtimes(filepath.Join(src, "sw_metric", "part"), later, later))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "sw_metric", "new-part"), []byte("new part"), 0o600))
+
+	require.NoError(t, migrateDir(context.Background(), src, dst))
+	data, err := os.ReadFile(filepath.Join(dst, "sw_metric", "part"))
+	require.NoError(t, err)
+	require.Equal(t, "new writes", string(data), "the stale copy should be synced again")
+	require.FileExists(t, filepath.Join(dst, "sw_metric", "new-part"))
+	require.NoFileExists(t, filepath.Join(dst, migrationSourceMarker))
+
+	migrated, err := isMigrated(src, dst)
+	require.NoError(t, err)
+	require.True(t, migrated, "an unchanged source should be migrated")
+}
+
+func TestResolveMigratedRoot(t *testing.T) {
+	root, defFn := test.Space(require.New(t))
+	defer defFn()
+	a, b, c := filepath.Join(root, "a"), filepath.Join(root, "b"), filepath.Join(root, "c")
+	for _, dir := range []string{a, b, filepath.Join(c, "measure")} {
+		require.NoError(t, os.MkdirAll(dir, 0o755))
+	}
+	require.NoError(t, persistMigratedRoot("measure", a, b))
+	require.NoError(t, persistMigratedRoot("measure", b, c))
+	resolved, err := resolveMigratedRoot("measure", a)
+	require.NoError(t, err)
+	require.Equal(t, c, resolved, "the root should follow the migrations")
+
+	// The data isn't deleted from b, since the migration to c was interrupted.
+	require.NoError(t, os.MkdirAll(filepath.Join(b, "measure"), 0o755))
+	resolved, err = resolveMigratedRoot("measure", a)
+	require.NoError(t, err)
+	require.Equal(t, b, resolved)
+	require.NoError(t, os.RemoveAll(filepath.Join(b, "measure")))
+
+	// Migrating back to a drops its record.
+	require.NoError(t, os.RemoveAll(filepath.Join(c, "measure")))
+	require.NoError(t, os.MkdirAll(filepath.Join(a, "measure"), 0o755))
+	require.NoError(t, persistMigratedRoot("measure", c, a))
+	require.NoFileExists(t, filepath.Join(a, "measure"+migrationRedirectSuffix))
+	resolved, err = resolveMigratedRoot("measure", b)
+	require.NoError(t, err)
+	require.Equal(t, a, resolved)
+}
+
+func TestRejectWritesToMigratedSource(t *testing.T) {
+	w := setUpWriteCallback(logger.GetLogger("test"), nil, nil)
+	w.migratedErr = errDataPathMigrated
+	resp := w.Rev(bus.NewMessage(1, []any{"event"}))
+	require.Contains(t, resp.Data().(common.Error).Msg(), errDataPathMigrated.Error())
+}
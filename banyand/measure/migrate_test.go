@@ -0,0 +1,142 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/apache/skywalking-banyandb/api/data"
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/test"
+	"github.com/apache/skywalking-banyandb/pkg/test/flags"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+var _ = Describe("Migrate data path", func() {
+	md := &commonv1.Metadata{Name: "service_cpm_minute", Group: "sw_metric"}
+	entity := []*modelv1.TagValue{{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "entity_1"}}}}
+	now := time.Now().Truncate(time.Minute)
+	var svcs *services
+	var deferFn func()
+	var to string
+	var toDeferFn func()
+
+	write := func(ts time.Time) {
+		req := &measurev1.InternalWriteRequest{
+			EntityValues: entity,
+			Request: &measurev1.WriteRequest{
+				Metadata: md,
+				DataPoint: &measurev1.DataPointValue{
+					Timestamp: timestamppb.New(ts),
+					TagFamilies: []*modelv1.TagFamilyForWrite{{
+						Tags: []*modelv1.TagValue{{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: ts.String()}}}, entity[0]},
+					}},
+					Fields: []*modelv1.FieldValue{{Value: &modelv1.FieldValue_Int{Int: &modelv1.Int{Value: ts.Unix()}}}},
+				},
+			},
+		}
+		_, err := svcs.pipeline.Publish(data.TopicMeasureWrite, bus.NewMessage(bus.MessageID(ts.UnixNano()), []any{req}))
+		Expect(err).ShouldNot(HaveOccurred())
+	}
+	count := func() int {
+		m, err := svcs.measure.Measure(md)
+		if err != nil {
+			return 0
+		}
+		tr := timestamp.NewInclusiveTimeRange(now.Add(-time.Hour), now.Add(time.Hour))
+		result, err := m.Query(context.Background(), pbv1.MeasureQueryOptions{
+			Name:            md.Name,
+			TimeRange:       &tr,
+			Entities:        [][]*modelv1.TagValue{entity},
+			FieldProjection: []string{"total"},
+		})
+		if err != nil {
+			return 0
+		}
+		defer result.Release()
+		n := 0
+		for r := result.Pull(); r != nil; r = result.Pull() {
+			n += len(r.Timestamps)
+		}
+		return n
+	}
+
+	BeforeEach(func() {
+		svcs, deferFn = setUp()
+		var err error
+		to, toDeferFn, err = test.NewSpace()
+		Expect(err).ShouldNot(HaveOccurred())
+		Eventually(func() bool {
+			_, err := svcs.measure.Measure(md)
+			return err == nil
+		}).WithTimeout(flags.EventuallyTimeout).Should(BeTrue())
+		for i := 0; i < 10; i++ {
+			write(now.Add(-time.Duration(i) * time.Minute))
+		}
+		Eventually(count).WithTimeout(flags.EventuallyTimeout).Should(Equal(10))
+	})
+
+	AfterEach(func() {
+		deferFn()
+		toDeferFn()
+	})
+
+	It("serves the data from the new path", func() {
+		Expect(svcs.measure.MigrateDataPath(context.Background(), svcs.root, to)).Should(Succeed())
+		Expect(filepath.Join(svcs.root, "measure")).ShouldNot(BeADirectory())
+		Expect(filepath.Join(to, "measure", "sw_metric")).Should(BeADirectory())
+		Expect(filepath.Join(to, "measure", "migrated-from")).ShouldNot(BeAnExistingFile())
+		Expect(os.ReadFile(filepath.Join(svcs.root, "measure.migrated-to"))).Should(BeEquivalentTo(filepath.Clean(to)))
+		Expect(count()).Should(Equal(10))
+
+		write(now.Add(time.Minute))
+		Eventually(count).WithTimeout(flags.EventuallyTimeout).Should(Equal(11))
+	})
+
+	It("resumes an interrupted migration", func() {
+		staging := filepath.Join(to, "measure.migrating")
+		Expect(os.MkdirAll(staging, 0o755)).Should(Succeed())
+		Expect(os.WriteFile(filepath.Join(staging, "stale"), []byte("stale"), 0o600)).Should(Succeed())
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		Expect(svcs.measure.MigrateDataPath(ctx, svcs.root, to)).Should(MatchError(context.Canceled))
+		Expect(filepath.Join(svcs.root, "measure", "sw_metric")).Should(BeADirectory())
+		Expect(count()).Should(Equal(10))
+
+		Expect(svcs.measure.MigrateDataPath(context.Background(), svcs.root, to)).Should(Succeed())
+		Expect(filepath.Join(to, "measure", "stale")).ShouldNot(BeAnExistingFile())
+		Expect(staging).ShouldNot(BeADirectory())
+		Expect(count()).Should(Equal(10))
+	})
+
+	It("rejects a path not in use", func() {
+		Expect(svcs.measure.MigrateDataPath(context.Background(), to, svcs.root)).ShouldNot(Succeed())
+		Expect(count()).Should(Equal(10))
+	})
+})
@@ -0,0 +1,125 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"sync"
+	"time"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+)
+
+const (
+	defaultMissingMeasureTTL = 3 * time.Second
+	// maxMissingMeasures bounds the cached misses, the ones beyond it aren't cached until the expired ones are dropped.
+	maxMissingMeasures = 4096
+)
+
+// missingMeasureCache remembers the measures a lookup misses for a short TTL,
+// so that the repeated lookups of a missing measure don't walk the schema repo.
+// Opening a measure drops its miss and stops caching it for a TTL,
+// so that the lookups racing with the opening don't hide the measure once it's opened.
+type missingMeasureCache struct {
+	misses  map[string]time.Time
+	opening map[string]time.Time
+	ttl     time.Duration
+	mu      sync.RWMutex
+}
+
+// newMissingMeasureCache returns nil if the TTL isn't positive, which caches nothing.
+func newMissingMeasureCache(ttl time.Duration) *missingMeasureCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &missingMeasureCache{
+		misses:  make(map[string]time.Time),
+		opening: make(map[string]time.Time),
+		ttl:     ttl,
+	}
+}
+
+func missingMeasureKey(metadata *commonv1.Metadata) string {
+	return metadata.GetGroup() + "/" + metadata.GetName()
+}
+
+func (c *missingMeasureCache) contains(key string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	expiry, ok := c.misses[key]
+	return ok && time.Now().Before(expiry)
+}
+
+func (c *missingMeasureCache) put(key string) {
+	if c == nil {
+		return
+	}
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if until, ok := c.opening[key]; ok && now.Before(until) {
+		return
+	}
+	if len(c.misses) >= maxMissingMeasures {
+		c.dropExpired(now)
+		if len(c.misses) >= maxMissingMeasures {
+			return
+		}
+	}
+	c.misses[key] = now.Add(c.ttl)
+}
+
+// invalidate is called once the measure is being opened.
+func (c *missingMeasureCache) invalidate(key string) {
+	if c == nil {
+		return
+	}
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.misses, key)
+	if len(c.opening) >= maxMissingMeasures {
+		c.dropExpired(now)
+	}
+	c.opening[key] = now.Add(c.ttl)
+}
+
+// reset drops all the misses, since a group or an alias makes the measures under it reachable.
+func (c *missingMeasureCache) reset() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.misses)
+}
+
+func (c *missingMeasureCache) dropExpired(now time.Time) {
+	for k, expiry := range c.misses {
+		if !now.Before(expiry) {
+			delete(c.misses, k)
+		}
+	}
+	for k, until := range c.opening {
+		if !now.Before(until) {
+			delete(c.opening, k)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMissingMeasureCache(t *testing.T) {
+	c := newMissingMeasureCache(50 * time.Millisecond)
+	c.put("sw_metric/a")
+	assert.True(t, c.contains("sw_metric/a"))
+	assert.False(t, c.contains("sw_metric/b"))
+	assert.Eventually(t, func() bool { return !c.contains("sw_metric/a") }, time.Second, 10*time.Millisecond,
+		"a miss expires after the TTL")
+
+	// Opening the measure drops its miss, and the misses racing with the opening aren't cached.
+	c.put("sw_metric/a")
+	c.invalidate("sw_metric/a")
+	assert.False(t, c.contains("sw_metric/a"))
+	c.put("sw_metric/a")
+	assert.False(t, c.contains("sw_metric/a"))
+
+	c.put("sw_metric/b")
+	c.reset()
+	assert.False(t, c.contains("sw_metric/b"))
+
+	var disabled *missingMeasureCache
+	disabled.put("sw_metric/a")
+	assert.False(t, disabled.contains("sw_metric/a"))
+	assert.Nil(t, newMissingMeasureCache(0))
+}
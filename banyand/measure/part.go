@@ -136,7 +136,7 @@ func (mp *memPart) reset() {
 	}
 }
 
-func (mp *memPart) mustInitFromDataPoints(dps *dataPoints) {
+func (mp *memPart) mustInitFromDataPoints(dps *dataPoints, blockSize int, codecs map[string]valuesCodec) {
 	mp.reset()
 
 	if len(dps.timestamps) == 0 {
@@ -147,6 +147,7 @@ func (mp *memPart) mustInitFromDataPoints(dps *dataPoints) {
 
 	bsw := generateBlockWriter()
 	bsw.MustInitForMemPart(mp)
+	bsw.setTagFamilyCodecs(codecs)
 	var sidPrev common.SeriesID
 	uncompressedBlockSizeBytes := uint64(0)
 	var indexPrev int
@@ -157,7 +158,7 @@ func (mp *memPart) mustInitFromDataPoints(dps *dataPoints) {
 		}
 
 		if uncompressedBlockSizeBytes >= maxUncompressedBlockSize ||
-			(i-indexPrev) > maxBlockLength || sid != sidPrev {
+			(i-indexPrev) >= blockSize || sid != sidPrev {
 			bsw.MustWriteDataPoints(sidPrev, dps.timestamps[indexPrev:i], dps.tagFamilies[indexPrev:i], dps.fields[indexPrev:i])
 			sidPrev = sid
 			indexPrev = i
@@ -167,6 +168,8 @@ func (mp *memPart) mustInitFromDataPoints(dps *dataPoints) {
 	}
 	bsw.MustWriteDataPoints(sidPrev, dps.timestamps[indexPrev:], dps.tagFamilies[indexPrev:], dps.fields[indexPrev:])
 	bsw.Flush(&mp.partMetadata)
+	mp.partMetadata.BlockSize = blockSize
+	mp.partMetadata.ShardNum = dps.shardNum
 	releaseBlockWriter(bsw)
 }
 
@@ -240,6 +240,7 @@ func (pi *partIter) findBlock() bool {
 type partMergeIter struct {
 	seqReaders           seqReaders
 	err                  error
+	tagFamilyCodecs      map[string]valuesCodec
 	primaryBlockMetadata []primaryBlockMetadata
 	compressedPrimaryBuf []byte
 	primaryBuf           []byte
@@ -251,6 +252,7 @@ type partMergeIter struct {
 func (pmi *partMergeIter) reset() {
 	pmi.err = nil
 	pmi.seqReaders.reset()
+	pmi.tagFamilyCodecs = nil
 	pmi.primaryBlockMetadata = nil
 	pmi.primaryMetadataIdx = 0
 	pmi.partID = 0
@@ -264,6 +266,7 @@ func (pmi *partMergeIter) mustInitFromPart(p *part) {
 	pmi.seqReaders.init(p)
 	pmi.primaryBlockMetadata = p.primaryBlockMetadata
 	pmi.partID = p.partMetadata.ID
+	pmi.tagFamilyCodecs = p.partMetadata.TagFamilyCodecs
 }
 
 func (pmi *partMergeIter) error() error {
@@ -321,7 +324,7 @@ func (pmi *partMergeIter) loadBlockMetadata() error {
 }
 
 func (pmi *partMergeIter) mustLoadBlockData(decoder *encoding.BytesBlockDecoder, block *blockPointer) {
-	block.block.mustSeqReadFrom(decoder, &pmi.seqReaders, pmi.block.bm)
+	block.block.mustSeqReadFrom(decoder, &pmi.seqReaders, pmi.block.bm, pmi.tagFamilyCodecs)
 }
 
 func generatePartMergeIter() *partMergeIter {
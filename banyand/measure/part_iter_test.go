@@ -145,7 +145,7 @@ func Test_partIter_nextBlock(t *testing.T) {
 			}
 			mp := generateMemPart()
 			releaseMemPart(mp)
-			mp.mustInitFromDataPoints(dps)
+			mp.mustInitFromDataPoints(dps, maxBlockLength, nil)
 
 			p := openMemPart(mp)
 			verifyPart(p)
@@ -209,7 +209,7 @@ func Test_partMergeIter_nextBlock(t *testing.T) {
 			}
 			mp := generateMemPart()
 			releaseMemPart(mp)
-			mp.mustInitFromDataPoints(tt.dps)
+			mp.mustInitFromDataPoints(tt.dps, maxBlockLength, nil)
 
 			decoder := generateColumnValuesDecoder()
 			defer releaseColumnValuesDecoder(decoder)
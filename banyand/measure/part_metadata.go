@@ -18,11 +18,14 @@
 package measure
 
 import (
-	"encoding/json"
+	"encoding/binary"
 	"path/filepath"
 
 	"github.com/pkg/errors"
 
+	"github.com/apache/skywalking-banyandb/banyand/internal/storage"
+	"github.com/apache/skywalking-banyandb/pkg/convert"
+	"github.com/apache/skywalking-banyandb/pkg/encoding"
 	"github.com/apache/skywalking-banyandb/pkg/fs"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
 )
@@ -34,7 +37,19 @@ type partMetadata struct {
 	BlocksCount           uint64 `json:"blocksCount"`
 	MinTimestamp          int64  `json:"minTimestamp"`
 	MaxTimestamp          int64  `json:"maxTimestamp"`
-	ID                    uint64 `json:"-"`
+	// BlockSize is the maximum number of data points per block the part was written with.
+	// Parts written before it was recorded have zero.
+	BlockSize int `json:"blockSize,omitempty"`
+	// TagFamilyCodecs are the codecs of the tag families which aren't encoded by codecDefault.
+	TagFamilyCodecs map[string]valuesCodec `json:"tagFamilyCodecs,omitempty"`
+	ID              uint64                 `json:"-"`
+	// ShardNum is the number of shards of the database when the data points were written.
+	// A data point written after resharding outranks the ones of the same series and timestamp written before,
+	// which may be in another shard. Parts written before it was recorded have zero.
+	ShardNum uint32 `json:"-"`
+	// Checksum is the checksum of the other fields in the legacy JSON format.
+	// Parts written before it was recorded have zero, and aren't verified.
+	Checksum uint32 `json:"checksum,omitempty"`
 }
 
 func (pm *partMetadata) reset() {
@@ -44,7 +59,137 @@ func (pm *partMetadata) reset() {
 	pm.BlocksCount = 0
 	pm.MinTimestamp = 0
 	pm.MaxTimestamp = 0
+	pm.BlockSize = 0
+	pm.TagFamilyCodecs = nil
 	pm.ID = 0
+	pm.ShardNum = 0
+	pm.Checksum = 0
+}
+
+// outranks reports whether the data points of the part win over the ones of the same series and timestamp in other.
+// The parts written after resharding win, since a series may move to another shard, whose part IDs are unrelated.
+func (pm *partMetadata) outranks(other *partMetadata) bool {
+	if pm.ShardNum != other.ShardNum {
+		return pm.ShardNum > other.ShardNum
+	}
+	return pm.ID > other.ID
+}
+
+func (pm *partMetadata) header() storage.PartMetadataHeader {
+	return storage.PartMetadataHeader{
+		CompressedSizeBytes:   pm.CompressedSizeBytes,
+		UncompressedSizeBytes: pm.UncompressedSizeBytes,
+		TotalCount:            pm.TotalCount,
+		BlocksCount:           pm.BlocksCount,
+		MinTimestamp:          pm.MinTimestamp,
+		MaxTimestamp:          pm.MaxTimestamp,
+	}
+}
+
+func (pm *partMetadata) setHeader(h storage.PartMetadataHeader) {
+	pm.CompressedSizeBytes = h.CompressedSizeBytes
+	pm.UncompressedSizeBytes = h.UncompressedSizeBytes
+	pm.TotalCount = h.TotalCount
+	pm.BlocksCount = h.BlocksCount
+	pm.MinTimestamp = h.MinTimestamp
+	pm.MaxTimestamp = h.MaxTimestamp
+}
+
+const (
+	// metadataVersionCodecs leads the binary metadata followed by the codecs of the tag families.
+	metadataVersionCodecs byte = 2
+	// metadataVersionShardNum leads the binary metadata followed by the shard number and the codecs of the tag families.
+	metadataVersionShardNum byte = 3
+	// metadataBinarySize is the size of the binary metadata shared with the stream parts and the block size.
+	metadataBinarySize = storage.PartMetadataBinarySize + 8
+)
+
+// marshalBinary appends the metadata to dst in the binary format shared with the stream parts,
+// whose tail is the block size, the shard number if any and the codecs of the tag families if any.
+func (pm *partMetadata) marshalBinary(dst []byte) []byte {
+	version := storage.PartMetadataVersionBinary
+	switch {
+	case pm.ShardNum > 0:
+		version = metadataVersionShardNum
+	case len(pm.TagFamilyCodecs) > 0:
+		version = metadataVersionCodecs
+	}
+	return storage.MarshalPartMetadata(dst, version, pm.header(), func(dst []byte) []byte {
+		dst = binary.LittleEndian.AppendUint64(dst, uint64(pm.BlockSize))
+		if version == metadataVersionShardNum {
+			dst = binary.LittleEndian.AppendUint32(dst, pm.ShardNum)
+		} else if len(pm.TagFamilyCodecs) == 0 {
+			return dst
+		}
+		dst = encoding.VarUint64ToBytes(dst, uint64(len(pm.TagFamilyCodecs)))
+		for _, family := range sortedCodecFamilies(pm.TagFamilyCodecs) {
+			dst = encoding.EncodeBytes(dst, convert.StringToBytes(family))
+			dst = append(dst, byte(pm.TagFamilyCodecs[family]))
+		}
+		return dst
+	})
+}
+
+func (pm *partMetadata) unmarshalBinary(src []byte) error {
+	var h storage.PartMetadataHeader
+	version, tail, err := storage.UnmarshalPartMetadata(src, &h)
+	if err != nil {
+		return err
+	}
+	switch {
+	case version == storage.PartMetadataVersionBinary && len(src) != metadataBinarySize:
+		return errors.Errorf("unexpected metadata size; got %d; want %d", len(src), metadataBinarySize)
+	case version == metadataVersionCodecs && len(src) <= metadataBinarySize:
+		return errors.Errorf("unexpected metadata size; got %d; want more than %d", len(src), metadataBinarySize)
+	case version == metadataVersionShardNum && len(src) <= metadataBinarySize+4:
+		return errors.Errorf("unexpected metadata size; got %d; want more than %d", len(src), metadataBinarySize+4)
+	case version != storage.PartMetadataVersionBinary && version != metadataVersionCodecs && version != metadataVersionShardNum:
+		return errors.Errorf("unknown metadata version %d", version)
+	}
+	pm.setHeader(h)
+	pm.BlockSize = int(binary.LittleEndian.Uint64(tail))
+	switch version {
+	case metadataVersionCodecs:
+		return pm.unmarshalCodecs(tail[8:])
+	case metadataVersionShardNum:
+		pm.ShardNum = binary.LittleEndian.Uint32(tail[8:])
+		return pm.unmarshalCodecs(tail[12:])
+	}
+	return nil
+}
+
+func (pm *partMetadata) unmarshalCodecs(src []byte) error {
+	src, n, err := encoding.BytesToVarUint64(src)
+	if err != nil {
+		return errors.WithMessage(err, "cannot unmarshal the number of the tag family codecs")
+	}
+	if n > 0 {
+		pm.TagFamilyCodecs = make(map[string]valuesCodec, n)
+	}
+	for i := uint64(0); i < n; i++ {
+		var family []byte
+		src, family, err = encoding.DecodeBytes(src)
+		if err != nil {
+			return errors.WithMessage(err, "cannot unmarshal the tag family of a codec")
+		}
+		if len(src) < 1 {
+			return errors.Errorf("missing the codec of the tag family %q", family)
+		}
+		pm.TagFamilyCodecs[string(family)] = valuesCodec(src[0])
+		src = src[1:]
+	}
+	if len(src) > 0 {
+		return errors.Errorf("unexpected non-empty tail after the tag family codecs; len(tail)=%d", len(src))
+	}
+	return nil
+}
+
+// unmarshal decodes the metadata in either the binary or the legacy JSON format.
+func (pm *partMetadata) unmarshal(data []byte) error {
+	if len(data) > 0 && (data[0] == storage.PartMetadataVersionBinary || data[0] == metadataVersionCodecs || data[0] == metadataVersionShardNum) {
+		return pm.unmarshalBinary(data)
+	}
+	return storage.UnmarshalPartMetadataJSON(data, pm, &pm.Checksum)
 }
 
 func validatePartMetadata(fileSystem fs.FileSystem, partPath string) error {
@@ -54,10 +199,7 @@ func validatePartMetadata(fileSystem fs.FileSystem, partPath string) error {
 		return errors.WithMessage(err, "cannot read metadata.json")
 	}
 	var pm partMetadata
-	if err := json.Unmarshal(metadata, &pm); err != nil {
-		return errors.WithMessage(err, "cannot parse metadata.json")
-	}
-	return nil
+	return errors.WithMessage(pm.unmarshal(metadata), "cannot parse metadata.json")
 }
 
 func (pm *partMetadata) mustReadMetadata(fileSystem fs.FileSystem, partPath string) {
@@ -69,7 +211,7 @@ func (pm *partMetadata) mustReadMetadata(fileSystem fs.FileSystem, partPath stri
 		logger.Panicf("cannot read %s", err)
 		return
 	}
-	if err := json.Unmarshal(metadata, pm); err != nil {
+	if err := pm.unmarshal(metadata); err != nil {
 		logger.Panicf("cannot parse %q: %s", metadataPath, err)
 		return
 	}
@@ -80,11 +222,8 @@ func (pm *partMetadata) mustReadMetadata(fileSystem fs.FileSystem, partPath stri
 }
 
 func (pm *partMetadata) mustWriteMetadata(fileSystem fs.FileSystem, partPath string) {
-	metadata, err := json.Marshal(pm)
-	if err != nil {
-		logger.Panicf("cannot marshal metadata: %s", err)
-		return
-	}
+	// The metadata keeps its legacy file name, though it's no longer JSON.
+	metadata := pm.marshalBinary(nil)
 	metadataPath := filepath.Join(partPath, metadataFilename)
 	n, err := fileSystem.Write(metadata, metadataPath, filePermission)
 	if err != nil {
@@ -0,0 +1,94 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+// PartStat describes a part of a group.
+type PartStat struct {
+	// Segment is the time range of the segment holding the part.
+	Segment               timestamp.TimeRange
+	ID                    uint64
+	CompressedSizeBytes   uint64
+	UncompressedSizeBytes uint64
+	TotalCount            uint64
+	BlocksCount           uint64
+	MinTimestamp          int64
+	MaxTimestamp          int64
+	// CompressionRatio is the uncompressed size divided by the compressed size.
+	// It's 0 if nothing is compressed.
+	CompressionRatio float64
+	// RowsPerBlock is the average number of data points in a block. It's 0 if the part has no block.
+	RowsPerBlock float64
+	// InMemory reports whether the part isn't flushed to the disk yet.
+	InMemory bool
+}
+
+func newPartStat(pm *partMetadata, segment timestamp.TimeRange, inMemory bool) PartStat {
+	ps := PartStat{
+		Segment:               segment,
+		ID:                    pm.ID,
+		CompressedSizeBytes:   pm.CompressedSizeBytes,
+		UncompressedSizeBytes: pm.UncompressedSizeBytes,
+		TotalCount:            pm.TotalCount,
+		BlocksCount:           pm.BlocksCount,
+		MinTimestamp:          pm.MinTimestamp,
+		MaxTimestamp:          pm.MaxTimestamp,
+		InMemory:              inMemory,
+	}
+	if pm.CompressedSizeBytes > 0 {
+		ps.CompressionRatio = float64(pm.UncompressedSizeBytes) / float64(pm.CompressedSizeBytes)
+	}
+	if pm.BlocksCount > 0 {
+		ps.RowsPerBlock = float64(pm.TotalCount) / float64(pm.BlocksCount)
+	}
+	return ps
+}
+
+func (tst *tsTable) partStats(segment timestamp.TimeRange) []PartStat {
+	snp := tst.currentSnapshot()
+	if snp == nil {
+		return nil
+	}
+	defer snp.decRef()
+	stats := make([]PartStat, 0, len(snp.parts))
+	for _, pw := range snp.parts {
+		stats = append(stats, newPartStat(&pw.p.partMetadata, segment, pw.mp != nil))
+	}
+	return stats
+}
+
+func (sr *schemaRepo) partStats(group string) ([]PartStat, error) {
+	db, err := sr.loadTSDB(group)
+	if err != nil {
+		return nil, err
+	}
+	tabWrappers := db.SelectTSTables(timestamp.AllTime)
+	defer func() {
+		for i := range tabWrappers {
+			tabWrappers[i].DecRef()
+		}
+	}()
+	var stats []PartStat
+	for i := range tabWrappers {
+		stats = append(stats, tabWrappers[i].Table().partStats(tabWrappers[i].GetTimeRange())...)
+	}
+	return stats, nil
+}
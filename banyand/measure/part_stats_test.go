@@ -0,0 +1,73 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/pkg/fs"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/test"
+	"github.com/apache/skywalking-banyandb/pkg/test/flags"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+func Test_tsTable_partStats(t *testing.T) {
+	tmpPath, defFn := test.Space(require.New(t))
+	defer defFn()
+	segment := timestamp.NewSectionTimeRange(time.Unix(0, 0), time.Unix(0, 1000))
+	tst, err := newTSTable(fs.NewLocalFileSystem(), tmpPath, common.Position{},
+		logger.GetLogger("test"), segment, option{flushTimeout: 0, mergePolicy: newDefaultMergePolicyForTesting()})
+	require.NoError(t, err)
+	defer tst.Close()
+
+	dps := generateHugeDps(1, 100, 50)
+	tst.mustAddDataPoints(dps)
+	require.Eventually(t, func() bool {
+		snp := tst.currentSnapshot()
+		if snp == nil {
+			return false
+		}
+		defer snp.decRef()
+		return snp.creator != snapshotCreatorMemPart
+	}, flags.EventuallyTimeout, 100*time.Millisecond, "wait for the data points to be flushed")
+
+	stats := tst.partStats(segment)
+	require.Len(t, stats, 1)
+	ps := stats[0]
+	assert.False(t, ps.InMemory)
+	assert.Equal(t, segment, ps.Segment)
+	assert.Equal(t, uint64(len(dps.timestamps)), ps.TotalCount)
+	assert.Equal(t, int64(1), ps.MinTimestamp)
+	assert.Equal(t, int64(100), ps.MaxTimestamp)
+	require.Positive(t, ps.BlocksCount)
+	assert.InDelta(t, float64(ps.TotalCount)/float64(ps.BlocksCount), ps.RowsPerBlock, 1e-9)
+	require.Positive(t, ps.CompressedSizeBytes)
+	assert.InDelta(t, float64(ps.UncompressedSizeBytes)/float64(ps.CompressedSizeBytes), ps.CompressionRatio, 1e-9)
+}
+
+func TestPartStatWithoutBlocks(t *testing.T) {
+	ps := newPartStat(&partMetadata{}, timestamp.TimeRange{}, true)
+	assert.Zero(t, ps.CompressionRatio)
+	assert.Zero(t, ps.RowsPerBlock)
+}
@@ -90,7 +90,7 @@ func TestMustInitFromDataPoints(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mp := &memPart{}
-			mp.mustInitFromDataPoints(tt.dps)
+			mp.mustInitFromDataPoints(tt.dps, maxBlockLength, nil)
 			assert.Equal(t, tt.want.BlocksCount, mp.partMetadata.BlocksCount)
 			assert.Equal(t, tt.want.MinTimestamp, mp.partMetadata.MinTimestamp)
 			assert.Equal(t, tt.want.MaxTimestamp, mp.partMetadata.MaxTimestamp)
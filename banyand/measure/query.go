@@ -70,6 +70,14 @@ func (s *measure) Query(ctx context.Context, mqo pbv1.MeasureQueryOptions) (pbv1
 	if len(mqo.TagProjection) == 0 && len(mqo.FieldProjection) == 0 {
 		return nil, errors.New("invalid query options: tagProjection or fieldProjection is required")
 	}
+	if err := validateProjection(s.schema, mqo); err != nil {
+		return nil, err
+	}
+	if s.reopenMu != nil {
+		// The snapshots a result holds stay readable after their group is reopened.
+		s.reopenMu.RLock()
+		defer s.reopenMu.RUnlock()
+	}
 	var result queryResult
 	db := s.databaseSupplier.SupplyTSDB()
 	if db == nil {
@@ -102,10 +110,11 @@ func (s *measure) Query(ctx context.Context, mqo pbv1.MeasureQueryOptions) (pbv1
 		sids = append(sids, sl[i].ID)
 	}
 	var parts []*part
+	minTimestamp, maxTimestamp := mqo.TimeRange.UnixNanoBounds()
 	qo := queryOptions{
 		MeasureQueryOptions: mqo,
-		minTimestamp:        mqo.TimeRange.Start.UnixNano(),
-		maxTimestamp:        mqo.TimeRange.End.UnixNano(),
+		minTimestamp:        minTimestamp,
+		maxTimestamp:        maxTimestamp,
 	}
 	var n int
 	for i := range tabWrappers {
@@ -113,7 +122,7 @@ func (s *measure) Query(ctx context.Context, mqo pbv1.MeasureQueryOptions) (pbv1
 		if s == nil {
 			continue
 		}
-		parts, n = s.getParts(parts, qo.minTimestamp, qo.maxTimestamp)
+		parts, n = s.selectParts(parts, qo.minTimestamp, qo.maxTimestamp, qo.FlushedOnly)
 		if n < 1 {
 			s.decRef()
 			continue
@@ -175,9 +184,50 @@ func (s *measure) Query(ctx context.Context, mqo pbv1.MeasureQueryOptions) (pbv1
 	case pbv1.OrderByTypeSeries:
 		result.orderByTS = false
 	}
+	if mqo.Coalesce != nil {
+		return &coalescedResult{MeasureQueryResult: &result, emitEnd: mqo.Coalesce.EmitEnd}, nil
+	}
 	return &result, nil
 }
 
+var errUnknownColumn = errors.New("the projected column isn't defined by the measure")
+
+// validateProjection checks that the measure defines every projected tag and field,
+// so that a query never decodes a column the caller can't get back.
+func validateProjection(m *databasev1.Measure, mqo pbv1.MeasureQueryOptions) error {
+	for _, tp := range mqo.TagProjection {
+		var family *databasev1.TagFamilySpec
+		for _, tf := range m.GetTagFamilies() {
+			if tf.GetName() == tp.Family {
+				family = tf
+				break
+			}
+		}
+		if family == nil {
+			return errors.WithMessagef(errUnknownColumn, "tag family %s of measure %s", tp.Family, m.GetMetadata().GetName())
+		}
+	tags:
+		for _, name := range tp.Names {
+			for _, t := range family.GetTags() {
+				if t.GetName() == name {
+					continue tags
+				}
+			}
+			return errors.WithMessagef(errUnknownColumn, "tag %s.%s of measure %s", tp.Family, name, m.GetMetadata().GetName())
+		}
+	}
+fields:
+	for _, name := range mqo.FieldProjection {
+		for _, f := range m.GetFields() {
+			if f.GetName() == name {
+				continue fields
+			}
+		}
+		return errors.WithMessagef(errUnknownColumn, "field %s of measure %s", name, m.GetMetadata().GetName())
+	}
+	return nil
+}
+
 func (s *measure) parseTagProjection(qo queryOptions, result *queryResult) (projectedEntityOffsets map[string]int, tagProjectionOnPart []pbv1.TagProjection) {
 	projectedEntityOffsets = make(map[string]int)
 	for i := range qo.TagProjection {
@@ -438,13 +488,13 @@ func (qr queryResult) Len() int {
 func (qr queryResult) Less(i, j int) bool {
 	leftTS := qr.data[i].timestamps[qr.data[i].idx]
 	rightTS := qr.data[j].timestamps[qr.data[j].idx]
-	leftVersion := qr.data[i].p.partMetadata.ID
-	rightVersion := qr.data[j].p.partMetadata.ID
+	leftPart := &qr.data[i].p.partMetadata
+	rightPart := &qr.data[j].p.partMetadata
 	if qr.orderByTS {
 		if leftTS == rightTS {
 			if qr.data[i].bm.seriesID == qr.data[j].bm.seriesID {
 				// sort version in descending order if timestamps and seriesID are equal
-				return leftVersion > rightVersion
+				return leftPart.outranks(rightPart)
 			}
 			// sort seriesID in ascending order if timestamps are equal
 			return qr.data[i].bm.seriesID < qr.data[j].bm.seriesID
@@ -459,7 +509,7 @@ func (qr queryResult) Less(i, j int) bool {
 	if leftSIDIndex == rightSIDIndex {
 		if leftTS == rightTS {
 			// sort version in descending order if timestamps and seriesID are equal
-			return leftVersion > rightVersion
+			return leftPart.outranks(rightPart)
 		}
 		// sort timestamps in ascending order if seriesID are equal
 		return leftTS < rightTS
@@ -495,7 +545,7 @@ func (qr *queryResult) merge(entityValuesAll map[common.SeriesID]map[string]*mod
 		step = -1
 	}
 	result := &pbv1.MeasureResult{}
-	var lastPartVersion uint64
+	var lastPart *partMetadata
 	var lastSid common.SeriesID
 
 	for qr.Len() > 0 {
@@ -507,12 +557,12 @@ func (qr *queryResult) merge(entityValuesAll map[common.SeriesID]map[string]*mod
 
 		if len(result.Timestamps) > 0 &&
 			topBC.timestamps[topBC.idx] == result.Timestamps[len(result.Timestamps)-1] {
-			if topBC.p.partMetadata.ID > lastPartVersion {
+			if topBC.p.partMetadata.outranks(lastPart) {
 				logger.Panicf("following parts version should be less or equal to the previous one")
 			}
 		} else {
 			topBC.copyTo(result, entityValuesAll, tagProjection)
-			lastPartVersion = topBC.p.partMetadata.ID
+			lastPart = &topBC.p.partMetadata
 		}
 
 		topBC.idx += step
@@ -19,6 +19,7 @@ package measure
 
 import (
 	"errors"
+	"fmt"
 	"sort"
 	"testing"
 	"time"
@@ -28,7 +29,10 @@ import (
 	"google.golang.org/protobuf/testing/protocmp"
 
 	"github.com/apache/skywalking-banyandb/api/common"
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
 	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/pkg/convert"
 	"github.com/apache/skywalking-banyandb/pkg/fs"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
 	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
@@ -671,3 +675,112 @@ func TestQueryResult(t *testing.T) {
 		})
 	}
 }
+
+func TestQueryFlushedOnly(t *testing.T) {
+	tmpPath, defFn := test.Space(require.New(t))
+	defer defFn()
+	fileSystem := fs.NewLocalFileSystem()
+	newMemPart := func(dps *dataPoints) *memPart {
+		mp := generateMemPart()
+		mp.mustInitFromDataPoints(dps, maxBlockLength, nil)
+		return mp
+	}
+	// The data points at 1 are flushed, the ones at 2 are still in memory.
+	flushed := newMemPart(dpsTS1)
+	flushed.mustFlush(fileSystem, partPath(tmpPath, 1))
+	releaseMemPart(flushed)
+	unflushed := newMemPart(dpsTS2)
+	s := &snapshot{parts: []*partWrapper{
+		newPartWrapper(nil, mustOpenFilePart(1, tmpPath, fileSystem)),
+		newPartWrapper(unflushed, openMemPart(unflushed)),
+	}, ref: 1}
+	defer s.decRef()
+
+	bma := generateBlockMetadataArray()
+	defer releaseBlockMetadataArray(bma)
+	query := func(flushedOnly bool) []int64 {
+		qo := queryOptions{MeasureQueryOptions: pbv1.MeasureQueryOptions{FlushedOnly: flushedOnly}, minTimestamp: 1, maxTimestamp: 2}
+		pp, _ := s.selectParts(nil, qo.minTimestamp, qo.maxTimestamp, qo.FlushedOnly)
+		ti := &tstIter{}
+		ti.init(bma, pp, []common.SeriesID{1, 2, 3}, qo.minTimestamp, qo.maxTimestamp)
+		var timestamps []int64
+		for ti.nextBlock() {
+			b := ti.piHeap[0].curBlock
+			timestamps = append(timestamps, b.timestamps.min, b.timestamps.max)
+		}
+		require.NoError(t, ti.Error())
+		return timestamps
+	}
+	require.ElementsMatch(t, []int64{1, 1, 1, 1, 1, 1, 2, 2, 2, 2, 2, 2}, query(false))
+	require.Equal(t, []int64{1, 1, 1, 1, 1, 1}, query(true), "the unflushed data points mustn't be read")
+}
+
+func TestQueryProjectsFields(t *testing.T) {
+	const fieldCount = 10
+	dps := &dataPoints{}
+	for i := 0; i < 3; i++ {
+		dps.seriesIDs = append(dps.seriesIDs, 1)
+		dps.timestamps = append(dps.timestamps, int64(i+1))
+		dps.tagFamilies = append(dps.tagFamilies, []nameValues{{name: "arrTag", values: []*nameValue{
+			{name: "strTag", valueType: pbv1.ValueTypeStr, value: []byte("value")},
+		}}})
+		fields := nameValues{name: "skipped"}
+		for j := 0; j < fieldCount; j++ {
+			fields.values = append(fields.values, &nameValue{
+				name: fmt.Sprintf("field%d", j), valueType: pbv1.ValueTypeInt64, value: convert.Int64ToBytes(int64(i * j)),
+			})
+		}
+		dps.fields = append(dps.fields, fields)
+	}
+	mp := generateMemPart()
+	mp.mustInitFromDataPoints(dps, maxBlockLength, nil)
+	defer releaseMemPart(mp)
+	p := openMemPart(mp)
+
+	bma := generateBlockMetadataArray()
+	defer releaseBlockMetadataArray(bma)
+	ti := &tstIter{}
+	ti.init(bma, []*part{p}, []common.SeriesID{1}, 1, 3)
+	require.True(t, ti.nextBlock())
+	bc := generateBlockCursor()
+	defer releaseBlockCursor(bc)
+	bc.init(ti.piHeap[0].p, ti.piHeap[0].curBlock, queryOptions{
+		MeasureQueryOptions: pbv1.MeasureQueryOptions{FieldProjection: []string{"field7", "field2"}},
+		minTimestamp:        1,
+		maxTimestamp:        3,
+	})
+	tmpBlock := generateBlock()
+	defer releaseBlock(tmpBlock)
+	require.True(t, bc.loadData(tmpBlock))
+	require.NoError(t, ti.Error())
+
+	var decoded []string
+	for _, c := range bc.fields.columns {
+		decoded = append(decoded, c.name)
+		require.Len(t, c.values, 3)
+	}
+	require.Equal(t, []string{"field7", "field2"}, decoded, "only the projected fields should be decoded")
+	require.Equal(t, convert.Int64ToBytes(14), bc.fields.columns[0].values[2])
+}
+
+func TestValidateProjection(t *testing.T) {
+	schema := &databasev1.Measure{
+		Metadata: &commonv1.Metadata{Name: "service_cpm_minute", Group: "sw_metric"},
+		TagFamilies: []*databasev1.TagFamilySpec{{
+			Name: "default",
+			Tags: []*databasev1.TagSpec{{Name: "id", Type: databasev1.TagType_TAG_TYPE_STRING}},
+		}},
+		Fields: []*databasev1.FieldSpec{{Name: "total", FieldType: databasev1.FieldType_FIELD_TYPE_INT}},
+	}
+	require.NoError(t, validateProjection(schema, pbv1.MeasureQueryOptions{
+		TagProjection:   []pbv1.TagProjection{{Family: "default", Names: []string{"id"}}},
+		FieldProjection: []string{"total"},
+	}))
+	for _, mqo := range []pbv1.MeasureQueryOptions{
+		{TagProjection: []pbv1.TagProjection{{Family: "unknown", Names: []string{"id"}}}},
+		{TagProjection: []pbv1.TagProjection{{Family: "default", Names: []string{"unknown"}}}},
+		{FieldProjection: []string{"total", "unknown"}},
+	} {
+		require.ErrorIs(t, validateProjection(schema, mqo), errUnknownColumn)
+	}
+}
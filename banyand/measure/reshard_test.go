@@ -0,0 +1,123 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/apache/skywalking-banyandb/api/data"
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/test/flags"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+var _ = Describe("Reshard", func() {
+	md := &commonv1.Metadata{Name: "service_cpm_minute", Group: "sw_metric"}
+	entity := []*modelv1.TagValue{{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "entity_1"}}}}
+	now := time.Now().Truncate(time.Minute)
+	tr := timestamp.NewInclusiveTimeRange(now.Add(-time.Hour), now.Add(time.Hour))
+	var svcs *services
+	var deferFn func()
+
+	BeforeEach(func() {
+		svcs, deferFn = setUp()
+		Eventually(func() bool {
+			_, err := svcs.measure.Measure(md)
+			return err == nil
+		}).WithTimeout(flags.EventuallyTimeout).Should(BeTrue())
+	})
+
+	AfterEach(func() {
+		deferFn()
+	})
+
+	writeToShard := func(ts time.Time, total int64, shardID uint32) {
+		req := &measurev1.InternalWriteRequest{
+			EntityValues: entity,
+			ShardId:      shardID,
+			Request: &measurev1.WriteRequest{
+				Metadata: md,
+				DataPoint: &measurev1.DataPointValue{
+					Timestamp: timestamppb.New(ts),
+					TagFamilies: []*modelv1.TagFamilyForWrite{{
+						Tags: []*modelv1.TagValue{{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "id"}}}, entity[0]},
+					}},
+					Fields: []*modelv1.FieldValue{{Value: &modelv1.FieldValue_Int{Int: &modelv1.Int{Value: total}}}},
+				},
+			},
+		}
+		_, err := svcs.pipeline.Publish(data.TopicMeasureWrite, bus.NewMessage(bus.MessageID(time.Now().UnixNano()), []any{req}))
+		Expect(err).ShouldNot(HaveOccurred())
+	}
+	query := func() map[int64]int64 {
+		m, err := svcs.measure.Measure(md)
+		Expect(err).ShouldNot(HaveOccurred())
+		result, err := m.Query(context.Background(), pbv1.MeasureQueryOptions{
+			Name:            md.Name,
+			TimeRange:       &tr,
+			Entities:        [][]*modelv1.TagValue{entity},
+			FieldProjection: []string{"total"},
+		})
+		Expect(err).ShouldNot(HaveOccurred())
+		defer result.Release()
+		totals := make(map[int64]int64)
+		for r := result.Pull(); r != nil; r = result.Pull() {
+			for i, ts := range r.Timestamps {
+				totals[ts] = r.Fields[0].Values[i].GetInt().GetValue()
+			}
+		}
+		return totals
+	}
+
+	It("overwrites the data points written before growing the shards", func() {
+		// Every write makes a part, so the parts of the old shard get higher IDs than the ones of the new shard.
+		want := make(map[int64]int64)
+		for i := 0; i < 10; i++ {
+			ts := now.Add(-time.Duration(i) * time.Minute)
+			writeToShard(ts, 1, 0)
+			want[ts.UnixNano()] = 1
+		}
+		Eventually(query).WithTimeout(flags.EventuallyTimeout).Should(Equal(want))
+
+		ctx, cancel := context.WithTimeout(context.Background(), flags.EventuallyTimeout)
+		defer cancel()
+		groupSchema, err := svcs.metadataService.GroupRegistry().GetGroup(ctx, md.Group)
+		Expect(err).ShouldNot(HaveOccurred())
+		groupSchema.ResourceOpts.ShardNum = 4
+		Expect(svcs.metadataService.GroupRegistry().UpdateGroup(ctx, groupSchema)).To(Succeed())
+		Eventually(func() uint32 {
+			cfg, errCfg := svcs.measure.EffectiveConfig(md.Group)
+			Expect(errCfg).ShouldNot(HaveOccurred())
+			return cfg.ShardNum
+		}).WithTimeout(flags.EventuallyTimeout).Should(Equal(uint32(4)))
+
+		// The series hashes into another shard after resharding.
+		writeToShard(now, 2, 3)
+		want[now.UnixNano()] = 2
+		Eventually(query).WithTimeout(flags.EventuallyTimeout).Should(Equal(want))
+	})
+})
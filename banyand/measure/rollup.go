@@ -0,0 +1,570 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	apiData "github.com/apache/skywalking-banyandb/api/data"
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/banyand/queue"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/convert"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/partition"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/query/aggregation"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+var (
+	_ io.Closer = (*rollupProcessor)(nil)
+
+	errRollupMismatch = errors.New("the rollup doesn't match the source measure")
+)
+
+// rollupRegistry routes the data points written to a source measure to the processors rolling it up.
+type rollupRegistry struct {
+	sources map[string]map[string]*rollupProcessor
+	mu      sync.RWMutex
+}
+
+func newRollupRegistry() *rollupRegistry {
+	return &rollupRegistry{sources: make(map[string]map[string]*rollupProcessor)}
+}
+
+func (rr *rollupRegistry) register(p *rollupProcessor) {
+	source := missingMeasureKey(p.source)
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	processors, ok := rr.sources[source]
+	if !ok {
+		processors = make(map[string]*rollupProcessor)
+		rr.sources[source] = processors
+	}
+	processors[missingMeasureKey(p.m.schema.GetMetadata())] = p
+}
+
+// unregister removes the processor unless the reopened derived measure has replaced it.
+func (rr *rollupRegistry) unregister(p *rollupProcessor) {
+	source := missingMeasureKey(p.source)
+	derived := missingMeasureKey(p.m.schema.GetMetadata())
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	if processors := rr.sources[source]; processors[derived] == p {
+		delete(processors, derived)
+		if len(processors) == 0 {
+			delete(rr.sources, source)
+		}
+	}
+}
+
+func (rr *rollupRegistry) onMeasureWrite(schema *databasev1.Measure, source rollupSource, request *measurev1.InternalWriteRequest) {
+	if rr == nil {
+		return
+	}
+	rr.mu.RLock()
+	defer rr.mu.RUnlock()
+	for _, p := range rr.sources[missingMeasureKey(schema.GetMetadata())] {
+		p.in(schema, source, request)
+	}
+}
+
+// rollupFunc aggregates the values of a source field within a bucket.
+type rollupFunc interface {
+	in(value *modelv1.FieldValue) error
+	val() *modelv1.FieldValue
+}
+
+type numberRollupFunc[N aggregation.Number] struct {
+	fn aggregation.Func[N]
+}
+
+func (f *numberRollupFunc[N]) in(value *modelv1.FieldValue) error {
+	v, err := aggregation.FromFieldValue[N](value)
+	if err != nil {
+		return err
+	}
+	f.fn.In(v)
+	return nil
+}
+
+func (f *numberRollupFunc[N]) val() *modelv1.FieldValue {
+	v, _ := aggregation.ToFieldValue(f.fn.Val())
+	return v
+}
+
+func newRollupFunc(fieldType databasev1.FieldType, function modelv1.AggregationFunction) (rollupFunc, error) {
+	switch fieldType {
+	case databasev1.FieldType_FIELD_TYPE_INT:
+		fn, err := aggregation.NewFunc[int64](function)
+		if err != nil {
+			return nil, err
+		}
+		return &numberRollupFunc[int64]{fn: fn}, nil
+	case databasev1.FieldType_FIELD_TYPE_FLOAT:
+		fn, err := aggregation.NewFunc[float64](function)
+		if err != nil {
+			return nil, err
+		}
+		return &numberRollupFunc[float64]{fn: fn}, nil
+	}
+	return nil, fmt.Errorf("field type %s can't be rolled up", fieldType)
+}
+
+// rollupField aggregates a source field into the field of the derived measure at the offset.
+type rollupField struct {
+	name     string
+	source   string
+	function modelv1.AggregationFunction
+	fType    databasev1.FieldType
+	offset   int
+}
+
+// rollupBinding locates the source tags and fields of a revision of the source measure.
+type rollupBinding struct {
+	tags     [][]partition.TagLocator
+	fields   []int
+	revision int64
+}
+
+// rollupSource reads the data points which have been written to the source measure.
+type rollupSource interface {
+	Query(ctx context.Context, opts pbv1.MeasureQueryOptions) (pbv1.MeasureQueryResult, error)
+}
+
+// rollupBucket holds the data points of an entity within a time bucket.
+// The points are keyed by their timestamps, so a point written twice is rolled up once.
+type rollupBucket struct {
+	source       rollupSource
+	points       map[int64][]*modelv1.FieldValue
+	entityValues []*modelv1.TagValue
+	tagFamilies  []*modelv1.TagFamilyForWrite
+	start        int64
+	shardID      uint32
+	// merge reads the points of the bucket persisted in the source measure before the bucket is written.
+	// It's set when the bucket might have points this processor hasn't received.
+	merge bool
+}
+
+// rollupSeries tracks the buckets of an entity.
+type rollupSeries struct {
+	lastSeen  time.Time
+	buckets   map[int64]*rollupBucket
+	watermark int64
+}
+
+// rollupProcessor aggregates the data points of the source measure into the buckets of the derived measure.
+// Every entity has its own watermark, the latest timestamp it received. A bucket is written once
+// the watermark of its entity passes its end by the allowed lateness.
+//
+// A bucket is merged with the points persisted in the source measure when it's written if
+// it's the first bucket of an entity the processor receives, or it's reopened by a late point.
+// The previous bucket of that first one is rewritten as well, since a crash may have lost it.
+// Hence, the rolled-up data point is always computed from all the points of the bucket.
+//
+// An entity which hasn't received any point for the idle duration has its open buckets written and is evicted,
+// so the entities which are gone don't hold their buckets forever. It's tracked as a new one once it comes back.
+type rollupProcessor struct {
+	m        *measure
+	source   *commonv1.Metadata
+	l        *logger.Logger
+	pipeline queue.Queue
+	binding  *rollupBinding
+	series   map[string]*rollupSeries
+	cond     *sync.Cond
+	done     chan struct{}
+	stop     chan struct{}
+	fields   []rollupField
+	pending  []*rollupBucket
+	mu       sync.Mutex
+	interval int64
+	lateness int64
+	idle     time.Duration
+	closing  bool
+}
+
+func newRollupProcessor(m *measure, pipeline queue.Queue) (*rollupProcessor, error) {
+	rollup := m.schema.GetRollup()
+	if m.interval <= 0 {
+		return nil, errors.New("the interval of a rollup measure is required")
+	}
+	if g := rollup.GetSourceMeasure().GetGroup(); g != "" && g != m.group {
+		return nil, fmt.Errorf("the source measure %s isn't in the group %s", rollup.GetSourceMeasure().GetName(), m.group)
+	}
+	p := &rollupProcessor{
+		m:        m,
+		source:   &commonv1.Metadata{Group: m.group, Name: rollup.GetSourceMeasure().GetName()},
+		l:        m.l,
+		pipeline: pipeline,
+		series:   make(map[string]*rollupSeries),
+		done:     make(chan struct{}),
+		stop:     make(chan struct{}),
+		interval: m.interval.Milliseconds(),
+		lateness: m.interval.Milliseconds(),
+		idle:     2 * m.interval,
+	}
+	p.cond = sync.NewCond(&p.mu)
+	for _, rf := range rollup.GetFields() {
+		offset := -1
+		for i, f := range m.schema.GetFields() {
+			if f.GetName() == rf.GetName() {
+				offset = i
+			}
+		}
+		if offset < 0 {
+			return nil, fmt.Errorf("the rollup field %s isn't defined", rf.GetName())
+		}
+		source := rf.GetSourceField()
+		if source == "" {
+			source = rf.GetName()
+		}
+		field := rollupField{
+			name:     rf.GetName(),
+			source:   source,
+			function: rf.GetFunction(),
+			fType:    m.schema.GetFields()[offset].GetFieldType(),
+			offset:   offset,
+		}
+		if _, err := newRollupFunc(field.fType, field.function); err != nil {
+			return nil, errors.WithMessagef(err, "rollup field %s", rf.GetName())
+		}
+		p.fields = append(p.fields, field)
+	}
+	go p.run()
+	go p.evictLoop()
+	return p, nil
+}
+
+// bind locates the tags and fields of the derived measure in the source measure.
+func (p *rollupProcessor) bind(source *databasev1.Measure) (*rollupBinding, error) {
+	if p.binding != nil && p.binding.revision == source.GetMetadata().GetModRevision() {
+		return p.binding, nil
+	}
+	derivedEntity, sourceEntity := p.m.schema.GetEntity().GetTagNames(), source.GetEntity().GetTagNames()
+	if len(derivedEntity) != len(sourceEntity) {
+		return nil, errors.WithMessage(errRollupMismatch, "the entities are different")
+	}
+	for i := range derivedEntity {
+		if derivedEntity[i] != sourceEntity[i] {
+			return nil, errors.WithMessage(errRollupMismatch, "the entities are different")
+		}
+	}
+	b := &rollupBinding{revision: source.GetMetadata().GetModRevision()}
+	for _, tf := range p.m.schema.GetTagFamilies() {
+		locators := make([]partition.TagLocator, 0, len(tf.GetTags()))
+		for _, t := range tf.GetTags() {
+			fIdx, tIdx, spec := pbv1.FindTagByName(source.GetTagFamilies(), t.GetName())
+			if spec == nil {
+				return nil, errors.WithMessagef(errRollupMismatch, "tag %s isn't found", t.GetName())
+			}
+			locators = append(locators, partition.TagLocator{FamilyOffset: fIdx, TagOffset: tIdx})
+		}
+		b.tags = append(b.tags, locators)
+	}
+	for _, f := range p.fields {
+		idx := -1
+		for i, spec := range source.GetFields() {
+			if spec.GetName() == f.source {
+				idx = i
+			}
+		}
+		if idx < 0 {
+			return nil, errors.WithMessagef(errRollupMismatch, "field %s isn't found", f.source)
+		}
+		b.fields = append(b.fields, idx)
+	}
+	p.binding = b
+	return b, nil
+}
+
+func (p *rollupProcessor) in(schema *databasev1.Measure, source rollupSource, request *measurev1.InternalWriteRequest) {
+	dp := request.GetRequest().GetDataPoint()
+	ts := dp.GetTimestamp().AsTime().UnixMilli()
+	start := ts - ts%p.interval
+	series := &pbv1.Series{Subject: p.m.name, EntityValues: request.GetEntityValues()}
+	if err := series.Marshal(); err != nil {
+		p.l.Warn().Err(err).Str("rollup", p.m.name).Msg("fail to marshal the series")
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closing {
+		return
+	}
+	b, err := p.bind(schema)
+	if err != nil {
+		p.l.Warn().Err(err).Str("rollup", p.m.name).Msg("the data point isn't rolled up")
+		return
+	}
+	// The tags are copied from the latest data point of the bucket.
+	tagFamilies := make([]*modelv1.TagFamilyForWrite, 0, len(b.tags))
+	for _, locators := range b.tags {
+		tf := &modelv1.TagFamilyForWrite{Tags: make([]*modelv1.TagValue, len(locators))}
+		for i, locator := range locators {
+			tf.Tags[i] = extractTagValue(dp, locator)
+		}
+		tagFamilies = append(tagFamilies, tf)
+	}
+	newBucket := func(start int64, merge bool) *rollupBucket {
+		return &rollupBucket{
+			source:       source,
+			points:       make(map[int64][]*modelv1.FieldValue),
+			entityValues: request.GetEntityValues(),
+			tagFamilies:  tagFamilies,
+			start:        start,
+			shardID:      request.GetShardId(),
+			merge:        merge,
+		}
+	}
+	key := convert.BytesToString(series.Buffer)
+	s, seen := p.series[key]
+	if !seen {
+		s = &rollupSeries{buckets: make(map[int64]*rollupBucket), watermark: ts}
+		p.series[string(series.Buffer)] = s
+		// The previous bucket may be left unwritten by a crash.
+		p.pending = append(p.pending, newBucket(start-p.interval, true))
+	}
+	bucket, ok := s.buckets[start]
+	if !ok {
+		// The first bucket of the entity or a bucket reopened by a late point may have persisted points.
+		bucket = newBucket(start, !seen || start+p.interval+p.lateness <= s.watermark)
+		s.buckets[start] = bucket
+	}
+	bucket.tagFamilies, bucket.source, bucket.shardID = tagFamilies, source, request.GetShardId()
+	fields := make([]*modelv1.FieldValue, len(b.fields))
+	for i, idx := range b.fields {
+		fields[i] = pbv1.NullFieldValue
+		if idx < len(dp.GetFields()) {
+			fields[i] = dp.GetFields()[idx]
+		}
+	}
+	bucket.points[ts] = fields
+	s.watermark = max(s.watermark, ts)
+	s.lastSeen = time.Now()
+	for start, bucket := range s.buckets {
+		if start+p.interval+p.lateness <= s.watermark {
+			p.pending = append(p.pending, bucket)
+			delete(s.buckets, start)
+		}
+	}
+	if len(p.pending) > 0 {
+		p.cond.Signal()
+	}
+}
+
+func (p *rollupProcessor) evictLoop() {
+	ticker := time.NewTicker(p.idle)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case now := <-ticker.C:
+			p.evictIdle(now)
+		}
+	}
+}
+
+// evictIdle writes the open buckets of the entities which haven't received any point since the idle duration before now,
+// and stops tracking them.
+func (p *rollupProcessor) evictIdle(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closing {
+		return
+	}
+	for key, s := range p.series {
+		if now.Sub(s.lastSeen) < p.idle {
+			continue
+		}
+		for _, bucket := range s.buckets {
+			p.pending = append(p.pending, bucket)
+		}
+		delete(p.series, key)
+	}
+	if len(p.pending) > 0 {
+		p.cond.Signal()
+	}
+}
+
+// run is the only writer of the processor. It writes the closed buckets through the write path
+// which has called the processor, so it never blocks the caller.
+func (p *rollupProcessor) run() {
+	defer close(p.done)
+	for {
+		p.mu.Lock()
+		for len(p.pending) == 0 && !p.closing {
+			p.cond.Wait()
+		}
+		buckets, closing := p.pending, p.closing
+		p.pending = nil
+		p.mu.Unlock()
+		if len(buckets) > 0 {
+			p.write(buckets)
+		}
+		if closing && len(buckets) == 0 {
+			return
+		}
+	}
+}
+
+func (p *rollupProcessor) write(buckets []*rollupBucket) {
+	publisher := p.pipeline.NewBatchPublisher(resultPersistencyTimeout)
+	defer publisher.Close()
+	for _, bucket := range buckets {
+		iwr, err := p.toWriteRequest(bucket)
+		if err != nil {
+			p.l.Err(err).Str("rollup", p.m.name).Int64("bucket", bucket.start).Msg("fail to roll up the bucket")
+			continue
+		}
+		if iwr == nil {
+			continue
+		}
+		message := bus.NewBatchMessageWithNode(bus.MessageID(time.Now().UnixNano()), "local", iwr)
+		if _, err = publisher.Publish(apiData.TopicMeasureWrite, message); err != nil {
+			p.l.Err(err).Str("rollup", p.m.name).Msg("fail to write the rolled-up data point")
+		}
+	}
+}
+
+// mergePersisted adds the points of the bucket persisted in the source measure which the bucket doesn't hold.
+func (p *rollupProcessor) mergePersisted(bucket *rollupBucket) error {
+	projection := make([]string, 0, len(p.fields))
+	offsets := make(map[string]int, len(p.fields))
+	for _, f := range p.fields {
+		if _, ok := offsets[f.source]; !ok {
+			offsets[f.source] = len(projection)
+			projection = append(projection, f.source)
+		}
+	}
+	tr := timestamp.NewSectionTimeRange(time.UnixMilli(bucket.start), time.UnixMilli(bucket.start+p.interval))
+	result, err := bucket.source.Query(context.Background(), pbv1.MeasureQueryOptions{
+		Name:            p.source.GetName(),
+		TimeRange:       &tr,
+		Entities:        [][]*modelv1.TagValue{bucket.entityValues},
+		FieldProjection: projection,
+	})
+	if err != nil {
+		return err
+	}
+	defer result.Release()
+	for r := result.Pull(); r != nil; r = result.Pull() {
+		columns := make([]int, len(projection))
+		for i := range columns {
+			columns[i] = -1
+		}
+		for i, f := range r.Fields {
+			if j, ok := offsets[f.Name]; ok {
+				columns[j] = i
+			}
+		}
+		for i, ts := range r.Timestamps {
+			ts = time.Unix(0, ts).UnixMilli()
+			if _, ok := bucket.points[ts]; ok {
+				continue
+			}
+			fields := make([]*modelv1.FieldValue, len(p.fields))
+			for j, f := range p.fields {
+				fields[j] = pbv1.NullFieldValue
+				if c := columns[offsets[f.source]]; c >= 0 && i < len(r.Fields[c].Values) {
+					fields[j] = r.Fields[c].Values[i]
+				}
+			}
+			bucket.points[ts] = fields
+		}
+	}
+	return nil
+}
+
+func (p *rollupProcessor) toWriteRequest(bucket *rollupBucket) (*measurev1.InternalWriteRequest, error) {
+	if bucket.merge && bucket.source != nil {
+		if err := p.mergePersisted(bucket); err != nil {
+			return nil, err
+		}
+	}
+	if len(bucket.points) == 0 {
+		return nil, nil
+	}
+	funcs := make([]rollupFunc, len(p.fields))
+	for i, f := range p.fields {
+		funcs[i], _ = newRollupFunc(f.fType, f.function)
+	}
+	for _, values := range bucket.points {
+		for i, v := range values {
+			if _, isNull := v.GetValue().(*modelv1.FieldValue_Null); isNull {
+				continue
+			}
+			if err := funcs[i].in(v); err != nil {
+				p.l.Warn().Err(err).Str("rollup", p.m.name).Str("field", p.fields[i].name).Msg("fail to roll up the field")
+			}
+		}
+	}
+	fields := make([]*modelv1.FieldValue, len(p.m.schema.GetFields()))
+	for i := range fields {
+		fields[i] = pbv1.NullFieldValue
+	}
+	for i, f := range p.fields {
+		fields[f.offset] = funcs[i].val()
+	}
+	return &measurev1.InternalWriteRequest{
+		Request: &measurev1.WriteRequest{
+			MessageId: uint64(time.Now().UnixNano()),
+			Metadata:  p.m.schema.GetMetadata(),
+			DataPoint: &measurev1.DataPointValue{
+				Timestamp:   timestamppb.New(time.UnixMilli(bucket.start)),
+				TagFamilies: bucket.tagFamilies,
+				Fields:      fields,
+			},
+		},
+		EntityValues: bucket.entityValues,
+		ShardId:      bucket.shardID,
+	}, nil
+}
+
+// Close writes the open buckets and waits for the writer to finish.
+// The next processor of the derived measure merges them with the points it receives later.
+func (p *rollupProcessor) Close() error {
+	p.mu.Lock()
+	if p.closing {
+		p.mu.Unlock()
+		return nil
+	}
+	for _, s := range p.series {
+		for _, bucket := range s.buckets {
+			p.pending = append(p.pending, bucket)
+		}
+	}
+	p.series = make(map[string]*rollupSeries)
+	p.closing = true
+	p.cond.Signal()
+	p.mu.Unlock()
+	close(p.stop)
+	<-p.done
+	return nil
+}
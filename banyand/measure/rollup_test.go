@@ -0,0 +1,335 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	apiData "github.com/apache/skywalking-banyandb/api/data"
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/banyand/queue"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+)
+
+type rollupListener chan *measurev1.InternalWriteRequest
+
+func (l rollupListener) Rev(message bus.Message) bus.Message {
+	for _, data := range message.Data().([]any) {
+		l <- data.(*measurev1.InternalWriteRequest)
+	}
+	return bus.Message{}
+}
+
+// rollupStore is the source measure holding the points written to it.
+type rollupStore struct {
+	points map[string]map[int64][]*modelv1.FieldValue
+	mu     sync.Mutex
+}
+
+func (s *rollupStore) add(id string, ts time.Time, fields []*modelv1.FieldValue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.points[id] == nil {
+		s.points[id] = make(map[int64][]*modelv1.FieldValue)
+	}
+	s.points[id][ts.UnixNano()] = fields
+}
+
+func (s *rollupStore) Query(_ context.Context, opts pbv1.MeasureQueryOptions) (pbv1.MeasureQueryResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := &pbv1.MeasureResult{}
+	for _, name := range opts.FieldProjection {
+		result.Fields = append(result.Fields, pbv1.Field{Name: name})
+	}
+	for ts, fields := range s.points[opts.Entities[0][0].GetStr().GetValue()] {
+		if !opts.TimeRange.Contains(ts) {
+			continue
+		}
+		result.Timestamps = append(result.Timestamps, ts)
+		for i, name := range opts.FieldProjection {
+			v := fields[0]
+			if name == "latency" {
+				v = fields[1]
+			}
+			result.Fields[i].Values = append(result.Fields[i].Values, v)
+		}
+	}
+	return &rollupStoreResult{result: result}, nil
+}
+
+type rollupStoreResult struct {
+	result *pbv1.MeasureResult
+}
+
+func (r *rollupStoreResult) Pull() *pbv1.MeasureResult {
+	result := r.result
+	r.result = nil
+	return result
+}
+
+func (r *rollupStoreResult) Release() {}
+
+var rollupSourceSchema = &databasev1.Measure{
+	Metadata: &commonv1.Metadata{Name: "service_cpm_second", Group: "sw_metric", ModRevision: 1},
+	TagFamilies: []*databasev1.TagFamilySpec{{
+		Name: "default",
+		Tags: []*databasev1.TagSpec{
+			{Name: "id", Type: databasev1.TagType_TAG_TYPE_STRING},
+			{Name: "layer", Type: databasev1.TagType_TAG_TYPE_STRING},
+		},
+	}},
+	Fields: []*databasev1.FieldSpec{
+		{Name: "total", FieldType: databasev1.FieldType_FIELD_TYPE_INT},
+		{Name: "latency", FieldType: databasev1.FieldType_FIELD_TYPE_FLOAT},
+	},
+	Entity: &databasev1.Entity{TagNames: []string{"id"}},
+}
+
+type rollupManual struct {
+	total, maxTotal, minTotal, count int64
+	latency                          float64
+}
+
+type rollupTester struct {
+	t       *testing.T
+	m       *measure
+	rollups *rollupRegistry
+	store   *rollupStore
+	written rollupListener
+	want    map[string]map[int64]*rollupManual
+	got     map[string]map[int64]*measurev1.InternalWriteRequest
+}
+
+func newRollupTester(t *testing.T) *rollupTester {
+	rollup := func(name, sourceField string, function modelv1.AggregationFunction) *databasev1.RollupField {
+		return &databasev1.RollupField{Name: name, SourceField: sourceField, Function: function}
+	}
+	derived := &databasev1.Measure{
+		Metadata: &commonv1.Metadata{Name: "service_cpm_minute", Group: "sw_metric"},
+		TagFamilies: []*databasev1.TagFamilySpec{{
+			Name: "default",
+			Tags: []*databasev1.TagSpec{{Name: "id", Type: databasev1.TagType_TAG_TYPE_STRING}},
+		}},
+		Fields: []*databasev1.FieldSpec{
+			{Name: "total", FieldType: databasev1.FieldType_FIELD_TYPE_INT},
+			{Name: "max_total", FieldType: databasev1.FieldType_FIELD_TYPE_INT},
+			{Name: "min_total", FieldType: databasev1.FieldType_FIELD_TYPE_INT},
+			{Name: "count", FieldType: databasev1.FieldType_FIELD_TYPE_INT},
+			{Name: "latency", FieldType: databasev1.FieldType_FIELD_TYPE_FLOAT},
+		},
+		Entity:   &databasev1.Entity{TagNames: []string{"id"}},
+		Interval: "1m",
+		Rollup: &databasev1.Rollup{
+			SourceMeasure: &commonv1.Metadata{Name: "service_cpm_second"},
+			Fields: []*databasev1.RollupField{
+				rollup("total", "", modelv1.AggregationFunction_AGGREGATION_FUNCTION_SUM),
+				rollup("max_total", "total", modelv1.AggregationFunction_AGGREGATION_FUNCTION_MAX),
+				rollup("min_total", "total", modelv1.AggregationFunction_AGGREGATION_FUNCTION_MIN),
+				rollup("count", "total", modelv1.AggregationFunction_AGGREGATION_FUNCTION_COUNT),
+				rollup("latency", "", modelv1.AggregationFunction_AGGREGATION_FUNCTION_MEAN),
+			},
+		},
+	}
+	pipeline := queue.Local()
+	t.Cleanup(pipeline.GracefulStop)
+	rt := &rollupTester{
+		t:       t,
+		rollups: newRollupRegistry(),
+		store:   &rollupStore{points: make(map[string]map[int64][]*modelv1.FieldValue)},
+		written: make(rollupListener, 64),
+		want:    make(map[string]map[int64]*rollupManual),
+		got:     make(map[string]map[int64]*measurev1.InternalWriteRequest),
+	}
+	require.NoError(t, pipeline.Subscribe(apiData.TopicMeasureWrite, rt.written))
+	rt.m = &measure{schema: derived, l: logger.GetLogger("test"), rollups: rt.rollups}
+	require.NoError(t, rt.m.parseSpec())
+	require.NoError(t, rt.m.startRollup(pipeline))
+	return rt
+}
+
+// point writes a data point to the source measure. The ones which aren't received persist the point only.
+func (rt *rollupTester) point(id string, ts time.Time, total int64, latency float64, received bool) {
+	fields := []*modelv1.FieldValue{
+		{Value: &modelv1.FieldValue_Int{Int: &modelv1.Int{Value: total}}},
+		{Value: &modelv1.FieldValue_Float{Float: &modelv1.Float{Value: latency}}},
+	}
+	rt.store.add(id, ts, fields)
+	if received {
+		rt.rollups.onMeasureWrite(rollupSourceSchema, rt.store, &measurev1.InternalWriteRequest{
+			Request: &measurev1.WriteRequest{
+				Metadata: rollupSourceSchema.GetMetadata(),
+				DataPoint: &measurev1.DataPointValue{
+					Timestamp: timestamppb.New(ts),
+					TagFamilies: []*modelv1.TagFamilyForWrite{{Tags: []*modelv1.TagValue{
+						{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: id}}},
+						{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "GENERAL"}}},
+					}}},
+					Fields: fields,
+				},
+			},
+			EntityValues: []*modelv1.TagValue{{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: id}}}},
+			ShardId:      1,
+		})
+	}
+	if rt.want[id] == nil {
+		rt.want[id] = make(map[int64]*rollupManual)
+	}
+	bucket := ts.Truncate(time.Minute).UnixMilli()
+	agg, ok := rt.want[id][bucket]
+	if !ok {
+		agg = &rollupManual{maxTotal: total, minTotal: total}
+		rt.want[id][bucket] = agg
+	}
+	agg.total += total
+	agg.maxTotal = max(agg.maxTotal, total)
+	agg.minTotal = min(agg.minTotal, total)
+	agg.count++
+	agg.latency += latency
+}
+
+// receive collects the written data points. The later ones replace the earlier ones of the same bucket.
+func (rt *rollupTester) receive(n int) {
+	for i := 0; i < n; i++ {
+		select {
+		case iwr := <-rt.written:
+			require.Equal(rt.t, rt.m.schema.GetMetadata(), iwr.GetRequest().GetMetadata())
+			require.Equal(rt.t, uint32(1), iwr.GetShardId())
+			id := iwr.GetEntityValues()[0].GetStr().GetValue()
+			if rt.got[id] == nil {
+				rt.got[id] = make(map[int64]*measurev1.InternalWriteRequest)
+			}
+			rt.got[id][iwr.GetRequest().GetDataPoint().GetTimestamp().AsTime().UnixMilli()] = iwr
+		case <-time.After(5 * time.Second):
+			rt.t.Fatalf("expected %d rolled-up data points, got %d", n, i)
+		}
+	}
+	select {
+	case iwr := <-rt.written:
+		rt.t.Fatalf("unexpected rolled-up data point %v", iwr)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func (rt *rollupTester) verify() {
+	for id, buckets := range rt.want {
+		require.Len(rt.t, rt.got[id], len(buckets))
+		for bucket, agg := range buckets {
+			iwr, ok := rt.got[id][bucket]
+			require.True(rt.t, ok, "the bucket %d of %s isn't written", bucket, id)
+			dp := iwr.GetRequest().GetDataPoint()
+			require.Equal(rt.t, id, dp.GetTagFamilies()[0].GetTags()[0].GetStr().GetValue())
+			fields := dp.GetFields()
+			require.Equal(rt.t, agg.total, fields[0].GetInt().GetValue())
+			require.Equal(rt.t, agg.maxTotal, fields[1].GetInt().GetValue())
+			require.Equal(rt.t, agg.minTotal, fields[2].GetInt().GetValue())
+			require.Equal(rt.t, agg.count, fields[3].GetInt().GetValue())
+			require.InDelta(rt.t, agg.latency/float64(agg.count), fields[4].GetFloat().GetValue(), 1e-9)
+		}
+	}
+}
+
+func TestRollup(t *testing.T) {
+	rt := newRollupTester(t)
+	start := time.Date(2026, 10, 15, 10, 0, 0, 0, time.UTC)
+	// Three minutes of per-second data points of two services.
+	for i := 0; i < 180; i++ {
+		ts := start.Add(time.Duration(i) * time.Second)
+		for j, id := range []string{"svc-a", "svc-b"} {
+			rt.point(id, ts, int64((i*7+j*13)%50+j), float64(i%9)+float64(j)/2, true)
+		}
+	}
+	require.NoError(t, rt.m.Close())
+	rt.receive(6)
+	rt.verify()
+
+	rt.rollups.mu.RLock()
+	defer rt.rollups.mu.RUnlock()
+	require.Empty(t, rt.rollups.sources, "the closed rollup should be unregistered")
+}
+
+func TestRollupWatermarkPerSeries(t *testing.T) {
+	rt := newRollupTester(t)
+	start := time.Date(2026, 10, 15, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 30; i++ {
+		rt.point("svc-a", start.Add(time.Duration(i)*time.Second), int64(i), float64(i), true)
+	}
+	// A future-dated point of another service doesn't close the buckets of svc-a.
+	rt.point("svc-b", start.Add(time.Hour), 1, 1, true)
+	for i := 30; i < 60; i++ {
+		rt.point("svc-a", start.Add(time.Duration(i)*time.Second), int64(i), float64(i), true)
+	}
+	require.NoError(t, rt.m.Close())
+	rt.receive(2)
+	rt.verify()
+}
+
+func TestRollupMergePersistedPoints(t *testing.T) {
+	rt := newRollupTester(t)
+	start := time.Date(2026, 10, 15, 10, 0, 0, 0, time.UTC)
+	// The last bucket before a restart was lost, and the first bucket after it is partially received.
+	for i := -10; i < 20; i++ {
+		rt.point("svc-a", start.Add(time.Duration(i)*time.Second), int64(i+10), float64(i+10), false)
+	}
+	for i := 20; i < 180; i++ {
+		rt.point("svc-a", start.Add(time.Duration(i)*time.Second), int64(i%17), float64(i%5), true)
+	}
+	// The late point reopens the written bucket.
+	rt.point("svc-a", start.Add(1500*time.Millisecond), 100, 3, true)
+	require.NoError(t, rt.m.Close())
+	rt.receive(5)
+	rt.verify()
+}
+
+func TestRollupEvictIdleSeries(t *testing.T) {
+	rt := newRollupTester(t)
+	start := time.Date(2026, 10, 15, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 30; i++ {
+		rt.point("svc-a", start.Add(time.Duration(i)*time.Second), int64(i), float64(i), true)
+	}
+	p := rt.m.rollupProcessor
+	p.evictIdle(time.Now())
+	p.mu.Lock()
+	require.Len(t, p.series, 1, "the series received a point within the idle duration")
+	p.mu.Unlock()
+
+	p.evictIdle(time.Now().Add(p.idle))
+	p.mu.Lock()
+	require.Empty(t, p.series, "the idle series should be evicted")
+	p.mu.Unlock()
+	rt.receive(1)
+
+	// The series coming back reopens the written bucket, which is merged with its persisted points.
+	for i := 30; i < 60; i++ {
+		rt.point("svc-a", start.Add(time.Duration(i)*time.Second), int64(i), float64(i), true)
+	}
+	require.NoError(t, rt.m.Close())
+	rt.receive(1)
+	rt.verify()
+}
@@ -0,0 +1,51 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"sync"
+
+	"github.com/apache/skywalking-banyandb/banyand/observability"
+	"github.com/apache/skywalking-banyandb/pkg/meter"
+)
+
+const segmentCollectorName = "measure_segments"
+
+type segmentCollector struct {
+	gauge meter.Gauge
+	sr    *schemaRepo
+	once  sync.Once
+}
+
+// collect reports the open segments and all the segments of every group.
+func (c *segmentCollector) collect() {
+	c.once.Do(func() {
+		c.gauge = observability.NewGauge(observability.NewMeterProviders(observability.RootScope.SubScope("measure")),
+			"segments", "group", "state")
+	})
+	for _, g := range c.sr.LoadAllGroups() {
+		name := g.GetSchema().GetMetadata().GetName()
+		db, err := c.sr.loadTSDB(name)
+		if err != nil {
+			continue
+		}
+		stats := db.SegmentStats()
+		c.gauge.Set(float64(stats.Open), name, "open")
+		c.gauge.Set(float64(stats.Total), name, "total")
+	}
+}
@@ -21,19 +21,26 @@ import (
 	"context"
 	"math"
 	"path"
+	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/apache/skywalking-banyandb/api/data"
 	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
 	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	"github.com/apache/skywalking-banyandb/api/validate"
+	"github.com/apache/skywalking-banyandb/banyand/internal/storage"
 	"github.com/apache/skywalking-banyandb/banyand/metadata"
 	"github.com/apache/skywalking-banyandb/banyand/observability"
+	"github.com/apache/skywalking-banyandb/banyand/protector"
 	"github.com/apache/skywalking-banyandb/banyand/queue"
-	"github.com/apache/skywalking-banyandb/pkg/bus"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
 	"github.com/apache/skywalking-banyandb/pkg/run"
 	resourceSchema "github.com/apache/skywalking-banyandb/pkg/schema"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
 )
 
 var (
@@ -48,19 +55,41 @@ type Service interface {
 	run.Config
 	run.Service
 	Query
+	WriteAmplification(group string, timeRange timestamp.TimeRange) (WriteAmplification, error)
+	EntityCardinality(group string, timeRange timestamp.TimeRange) ([]EntityCardinality, error)
+	EffectiveConfig(group string) (EffectiveConfig, error)
+	RotationStatus(group string) (storage.Status, error)
+	ForceRotate(group string) error
+	PartStats(group string) ([]PartStat, error)
+	ForceMerge(group string, maxFanOutSize uint64) error
+	SeriesCacheEntries(group string) ([]storage.SeriesCacheEntry, error)
+	EvictSeries(group string, series *pbv1.Series) (int, error)
+	MigrateDataPath(ctx context.Context, from, to string) error
 }
 
 var _ Service = (*service)(nil)
 
 type service struct {
 	schemaRepo    *schemaRepo
-	writeListener bus.MessageListener
+	writeListener *writeCallback
 	metadata      metadata.Repo
 	pipeline      queue.Server
 	localPipeline queue.Queue
-	option        option
+	pm            *protector.Memory
+	seriesCaches  *storage.SeriesCaches
 	l             *logger.Logger
-	root          string
+	// root is guarded by migrateMu once the service runs.
+	root              string
+	option            option
+	gracePeriod       time.Duration
+	missingMeasureTTL time.Duration
+	minFlushTimeout   time.Duration
+	maxFlushTimeout   time.Duration
+	migrateMu         sync.Mutex
+	seriesCachePolicy string
+	tagFamilyCodecs   string
+	seriesCacheDebug  bool
+	adaptiveFlush     bool
 }
 
 func (s *service) Measure(metadata *commonv1.Metadata) (Measure, error) {
@@ -75,12 +104,97 @@ func (s *service) LoadGroup(name string) (resourceSchema.Group, bool) {
 	return s.schemaRepo.LoadGroup(name)
 }
 
+func (s *service) WriteAmplification(group string, timeRange timestamp.TimeRange) (WriteAmplification, error) {
+	return s.schemaRepo.writeAmplification(group, timeRange)
+}
+
+// EntityCardinality estimates the distinct entities of every measure in the group written within the time range.
+// The sketches of all the shards and segments overlapping the time range are merged.
+func (s *service) EntityCardinality(group string, timeRange timestamp.TimeRange) ([]EntityCardinality, error) {
+	return s.schemaRepo.entityCardinality(group, timeRange)
+}
+
+func (s *service) EffectiveConfig(group string) (EffectiveConfig, error) {
+	return s.schemaRepo.effectiveConfig(group)
+}
+
+// RotationStatus reports whether the group is creating a new segment, along with the time range of its newest segment.
+// A readiness probe holds off the writes into the group while it's rotating.
+func (s *service) RotationStatus(group string) (storage.Status, error) {
+	db, err := s.schemaRepo.loadTSDB(group)
+	if err != nil {
+		return storage.Status{}, err
+	}
+	return db.Status(), nil
+}
+
+// PartStats returns the metadata of the parts in all the segments of the group.
+func (s *service) PartStats(group string) ([]PartStat, error) {
+	return s.schemaRepo.partStats(group)
+}
+
+// ForceMerge merges the parts of the group right away, and returns once they're merged.
+// A merged part doesn't exceed maxFanOutSize, or the cap of the merge policy if it's zero.
+func (s *service) ForceMerge(group string, maxFanOutSize uint64) error {
+	return s.schemaRepo.forceMerge(group, maxFanOutSize)
+}
+
+// SeriesCacheEntries lists the series lists cached by the group, the least recently used first.
+func (s *service) SeriesCacheEntries(group string) ([]storage.SeriesCacheEntry, error) {
+	if !s.seriesCacheDebug {
+		return nil, storage.ErrSeriesCacheDebugDisabled
+	}
+	return s.seriesCaches.Entries(group)
+}
+
+// EvictSeries drops the series lists cached by the group which hold the series.
+// The series is made of the subject and the entity values, and it returns the number of the dropped lists.
+func (s *service) EvictSeries(group string, series *pbv1.Series) (int, error) {
+	if !s.seriesCacheDebug {
+		return 0, storage.ErrSeriesCacheDebugDisabled
+	}
+	return s.seriesCaches.Evict(group, series)
+}
+
 func (s *service) FlagSet() *run.FlagSet {
 	flagS := run.NewFlagSet("storage")
 	flagS.StringVar(&s.root, "measure-root-path", "/tmp", "the root path of database")
 	flagS.DurationVar(&s.option.flushTimeout, "measure-flush-timeout", defaultFlushTimeout, "the memory data timeout of measure")
+	flagS.IntVar(&s.option.seriesCacheSize, "measure-series-cache-size", storage.DefaultSeriesCacheSize,
+		"the maximum number of cached series lists per group, 0 disables the cache")
+	flagS.DurationVar(&s.option.seriesCacheTTL, "measure-series-cache-ttl", storage.DefaultSeriesCacheTTL, "the time to live of a cached series list")
+	flagS.StringVar(&s.seriesCachePolicy, "measure-series-cache-policy", storage.CachePolicyLRU.String(),
+		"the eviction policy of the series list cache, one of lru, lfu and slru")
+	flagS.BoolVar(&s.option.seriesCacheWarmup, "measure-series-cache-warmup", false,
+		"persist the cached series lists every minute and reload them on startup")
+	flagS.BoolVar(&s.seriesCacheDebug, "measure-series-cache-debug", false,
+		"enable the debug API listing the cached series lists and evicting the ones holding a series")
+	flagS.IntVar(&s.option.maxSeriesPerQuery, "measure-max-series-per-query", 0,
+		"the maximum number of series a query matches, 0 means no limit")
+	flagS.IntVar(&s.option.maxOpenSegments, "measure-max-open-segments", 0,
+		"the maximum number of open segments per group, the least recently accessed ones are closed until they're accessed again, 0 means no limit")
+	flagS.IntVar(&s.option.maxDiskUsagePercent, "measure-max-disk-usage-percent", 0,
+		"the disk usage beyond which the oldest segments are deleted before their TTL, 0 disables it")
+	flagS.IntVar(&s.option.minRetainedSegments, "measure-min-retained-segments", 1,
+		"the number of the latest segments per shard never deleted by the disk usage")
+	flagS.IntVar(&s.option.blockSize, "measure-block-size", maxBlockLength,
+		"the default maximum number of data points in a block, which can be overridden by a group's resource options")
+	flagS.DurationVar(&s.gracePeriod, "measure-dropped-group-grace-period", defaultGroupGracePeriod,
+		"the period to retain the data of a dropped group before deleting it")
+	flagS.DurationVar(&s.missingMeasureTTL, "measure-missing-cache-ttl", defaultMissingMeasureTTL,
+		"the time to remember a missing measure, a created measure is found right away, 0 disables it")
+	flagS.BoolVar(&s.adaptiveFlush, "measure-adaptive-flush", false,
+		"scale the flush timeout between its bounds by the buffered bytes relative to measure-write-memory-limit")
+	flagS.DurationVar(&s.minFlushTimeout, "measure-min-flush-timeout", defaultMinFlushTimeout,
+		"the flush timeout of the adaptive flush when the buffered bytes reach the memory limit")
+	flagS.DurationVar(&s.maxFlushTimeout, "measure-max-flush-timeout", defaultMaxFlushTimeout,
+		"the flush timeout of the adaptive flush when nothing is buffered")
+	flagS.StringVar(&s.tagFamilyCodecs, "measure-tag-family-codecs", "",
+		"the comma-separated codecs of the tag families, such as default=dictionary, a codec is one of default, dictionary and zstd")
 	s.option.mergePolicy = newDefaultMergePolicy()
 	flagS.Uint64Var(&s.option.mergePolicy.maxFanOutSize, "max-fan-out-size", math.MaxUint64, "the upper bound of a single file size after merge")
+	flagS.Uint64Var(&s.option.mergePolicy.targetPartSize, "target-part-size", 0,
+		"the size a merged part aims for, the merger splits a merge exceeding it into several parts, 0 means no target")
 	return flagS
 }
 
@@ -88,6 +202,22 @@ func (s *service) Validate() error {
 	if s.root == "" {
 		return errEmptyRootPath
 	}
+	if s.option.blockSize <= 0 || s.option.blockSize > validate.MaxMeasureBlockSize {
+		return errors.Errorf("measure-block-size %d is out of (0, %d]", s.option.blockSize, validate.MaxMeasureBlockSize)
+	}
+	policy, err := storage.ParseCachePolicy(s.seriesCachePolicy)
+	if err != nil {
+		return err
+	}
+	s.option.seriesCachePolicy = policy
+	if s.option.tagFamilyCodecs, err = parseTagFamilyCodecs(s.tagFamilyCodecs); err != nil {
+		return err
+	}
+	if s.adaptiveFlush {
+		if s.option.adaptiveFlush, err = newAdaptiveFlush(s.minFlushTimeout, s.maxFlushTimeout, s.pm.GetLimit()); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -101,17 +231,42 @@ func (s *service) Role() databasev1.Role {
 
 func (s *service) PreRun(_ context.Context) error {
 	s.l = logger.GetLogger(s.Name())
+	root, err := resolveMigratedRoot(s.Name(), s.root)
+	if err != nil {
+		return err
+	}
+	if root != filepath.Clean(s.root) {
+		s.l.Info().Str("root", s.root).Str("migrated-to", root).Msg("serve the migrated data path")
+		s.root = root
+	}
 	path := path.Join(s.root, s.Name())
 	observability.UpdatePath(path)
 	s.localPipeline = queue.Local()
 	s.schemaRepo = newSchemaRepo(path, s)
+	wac := &writeAmplificationCollector{sr: s.schemaRepo}
+	observability.MetricsCollector.Register(writeAmplificationCollectorName, wac.collect)
+	s.seriesCaches = storage.NewSeriesCaches(s.Name(), s.schemaRepo.Repository)
+	observability.MetricsCollector.Register(s.seriesCaches.CollectorName(), s.seriesCaches.Collect)
+	sc := &segmentCollector{sr: s.schemaRepo}
+	observability.MetricsCollector.Register(segmentCollectorName, sc.collect)
+	observability.AdminHandlers.Register(forceRotateAdminName, s.serveForceRotate)
 	// run a serial watcher
 
-	s.writeListener = setUpWriteCallback(s.l, s.schemaRepo)
-	err := s.pipeline.Subscribe(data.TopicMeasureWrite, s.writeListener)
+	s.writeListener = setUpWriteCallback(s.l, s.schemaRepo, s.pm)
+	if err := checkMigratedSource(path); err != nil {
+		if !errors.Is(err, errDataPathMigrated) {
+			return err
+		}
+		s.l.Warn().Err(err).Msg("the writes are rejected")
+		s.writeListener.migratedErr = err
+	}
+	err = s.pipeline.Subscribe(data.TopicMeasureWrite, s.writeListener)
 	if err != nil {
 		return err
 	}
+	if err = s.pipeline.Subscribe(data.TopicMeasureConditionalWrite, conditionalWriteCallback{s.writeListener}); err != nil {
+		return err
+	}
 	return s.localPipeline.Subscribe(data.TopicMeasureWrite, s.writeListener)
 }
 
@@ -120,14 +275,19 @@ func (s *service) Serve() run.StopNotify {
 }
 
 func (s *service) GracefulStop() {
+	observability.MetricsCollector.Unregister(writeAmplificationCollectorName)
+	observability.MetricsCollector.Unregister(s.seriesCaches.CollectorName())
+	observability.MetricsCollector.Unregister(segmentCollectorName)
+	observability.AdminHandlers.Unregister(forceRotateAdminName)
 	s.localPipeline.GracefulStop()
 	s.schemaRepo.Close()
 }
 
 // NewService returns a new service.
-func NewService(_ context.Context, metadata metadata.Repo, pipeline queue.Server) (Service, error) {
+func NewService(_ context.Context, metadata metadata.Repo, pipeline queue.Server, pm *protector.Memory) (Service, error) {
 	return &service{
 		metadata: metadata,
 		pipeline: pipeline,
+		pm:       pm,
 	}, nil
 }
@@ -53,8 +53,16 @@ type snapshot struct {
 }
 
 func (s *snapshot) getParts(dst []*part, minTimestamp, maxTimestamp int64) ([]*part, int) {
+	return s.selectParts(dst, minTimestamp, maxTimestamp, false)
+}
+
+// selectParts returns the parts overlapping the time range. The in-memory parts are skipped if flushedOnly is true.
+func (s *snapshot) selectParts(dst []*part, minTimestamp, maxTimestamp int64, flushedOnly bool) ([]*part, int) {
 	var count int
 	for _, p := range s.parts {
+		if flushedOnly && p.mp != nil {
+			continue
+		}
 		pm := p.p.partMetadata
 		if maxTimestamp < pm.MinTimestamp || minTimestamp > pm.MaxTimestamp {
 			continue
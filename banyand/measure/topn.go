@@ -46,6 +46,7 @@ import (
 	"github.com/apache/skywalking-banyandb/pkg/logger"
 	"github.com/apache/skywalking-banyandb/pkg/partition"
 	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/query/aggregation"
 	"github.com/apache/skywalking-banyandb/pkg/query/logical"
 )
 
@@ -76,7 +77,9 @@ type topNStreamingProcessor struct {
 	in            chan flow.StreamRecord
 	errCh         <-chan error
 	stopCh        chan struct{}
+	newReducers   func() []streaming.Reducer
 	flow.ComponentState
+	aggregations  []string
 	interval      time.Duration
 	sortDirection modelv1.Sort
 }
@@ -127,32 +130,46 @@ func (t *topNStreamingProcessor) Close() error {
 }
 
 func (t *topNStreamingProcessor) writeStreamRecord(record flow.StreamRecord) error {
-	tuplesGroups, ok := record.Data().(map[string][]*streaming.Tuple2)
-	if !ok {
-		return errors.New("invalid data type")
-	}
 	// down-sample the start of the timeWindow to a time-bucket
 	eventTime := t.downSampleTimeBucket(record.TimestampMillis())
-	var err error
 	publisher := t.pipeline.NewBatchPublisher(resultPersistencyTimeout)
 	defer publisher.Close()
+	switch ranks := record.Data().(type) {
+	case map[string][]*streaming.Tuple2:
+		return t.writeTuplesGroups(publisher, eventTime, "", ranks)
+	case map[string]map[string][]*streaming.Tuple2:
+		// The ranks of every pre-aggregation function are written as a separate result set.
+		var err error
+		for aggregation, tuplesGroups := range ranks {
+			err = multierr.Append(err, t.writeTuplesGroups(publisher, eventTime, aggregation, tuplesGroups))
+		}
+		return err
+	}
+	return errors.New("invalid data type")
+}
+
+func (t *topNStreamingProcessor) writeTuplesGroups(publisher queue.BatchPublisher, eventTime time.Time, aggregation string,
+	tuplesGroups map[string][]*streaming.Tuple2,
+) error {
+	var err error
 	for group, tuples := range tuplesGroups {
 		if e := t.l.Debug(); e.Enabled() {
 			e.Str("TopN", t.topNSchema.GetMetadata().GetName()).
 				Str("group", group).
+				Str("aggregation", aggregation).
 				Int("rankNums", len(tuples)).
 				Msg("Write tuples")
 		}
 		for rankNum, tuple := range tuples {
 			fieldValue := tuple.V1.(int64)
 			data := tuple.V2.(flow.StreamRecord).Data().(flow.Data)
-			err = multierr.Append(err, t.writeData(publisher, eventTime, fieldValue, data, rankNum))
+			err = multierr.Append(err, t.writeData(publisher, eventTime, aggregation, fieldValue, data, rankNum))
 		}
 	}
 	return err
 }
 
-func (t *topNStreamingProcessor) writeData(publisher queue.BatchPublisher, eventTime time.Time, fieldValue int64,
+func (t *topNStreamingProcessor) writeData(publisher queue.BatchPublisher, eventTime time.Time, aggregation string, fieldValue int64,
 	data flow.Data, rankNum int,
 ) error {
 	var tagValues []*modelv1.TagValue
@@ -167,10 +184,17 @@ func (t *topNStreamingProcessor) writeData(publisher queue.BatchPublisher, event
 			t.l.Warn().Msg("tag value is nil")
 		}
 	}
-	series, shardID, err := t.locate(tagValues, rankNum)
+	series, shardID, err := t.locate(tagValues, aggregation, rankNum)
 	if err != nil {
 		return err
 	}
+	// The entity values are shared by the result sets of all the aggregations, so they're copied.
+	entityValues := data[0].([]*modelv1.TagValue)
+	tags := make([]*modelv1.TagValue, 0, len(entityValues)+3)
+	tags = append(tags, entityValues...)
+	if aggregation != "" {
+		tags = append(tags, aggregationTagValue(aggregation))
+	}
 
 	iwr := &measurev1.InternalWriteRequest{
 		Request: &measurev1.WriteRequest{
@@ -181,7 +205,7 @@ func (t *topNStreamingProcessor) writeData(publisher queue.BatchPublisher, event
 				TagFamilies: []*modelv1.TagFamilyForWrite{
 					{
 						Tags: append(
-							data[0].([]*modelv1.TagValue),
+							tags,
 							// SortDirection
 							&modelv1.TagValue{
 								Value: &modelv1.TagValue_Int{
@@ -224,16 +248,20 @@ func (t *topNStreamingProcessor) downSampleTimeBucket(eventTimeMillis int64) tim
 	return time.UnixMilli(eventTimeMillis - eventTimeMillis%t.interval.Milliseconds())
 }
 
-func (t *topNStreamingProcessor) locate(tagValues []*modelv1.TagValue, rankNum int) (*pbv1.Series, common.ShardID, error) {
+func (t *topNStreamingProcessor) locate(tagValues []*modelv1.TagValue, aggregation string, rankNum int) (*pbv1.Series, common.ShardID, error) {
 	if len(tagValues) != 0 && len(t.topNSchema.GetGroupByTagNames()) != len(tagValues) {
 		return nil, 0, errors.New("no enough tag values for the entity")
 	}
 	series := &pbv1.Series{
 		Subject:      t.topNSchema.GetMetadata().GetName(),
-		EntityValues: make([]*modelv1.TagValue, 1+1+len(tagValues)),
+		EntityValues: make([]*modelv1.TagValue, len(tagValues), 1+1+1+len(tagValues)),
 	}
 
 	copy(series.EntityValues, tagValues)
+	if aggregation != "" {
+		series.EntityValues = append(series.EntityValues, aggregationTagValue(aggregation))
+	}
+	series.EntityValues = series.EntityValues[:len(series.EntityValues)+2]
 	series.EntityValues[len(series.EntityValues)-2] = &modelv1.TagValue{
 		Value: &modelv1.TagValue_Int{
 			Int: &modelv1.Int{
@@ -269,21 +297,54 @@ func (t *topNStreamingProcessor) start() *topNStreamingProcessor {
 	if flushInterval > maxFlushInterval {
 		flushInterval = maxFlushInterval
 	}
+	opts := []any{
+		streaming.WithSortKeyExtractor(func(record flow.StreamRecord) int64 {
+			return record.Data().(flow.Data)[2].(int64)
+		}),
+		orderBy(t.topNSchema.GetFieldValueSort()),
+		streaming.WithGroupKeyExtractor(func(record flow.StreamRecord) string {
+			return record.Data().(flow.Data)[1].(string)
+		}),
+	}
+	if len(t.aggregations) > 0 {
+		// The entities are pre-aggregated by all the functions in a single pass.
+		opts = append(opts, streaming.WithAggregations(func(record flow.StreamRecord) string {
+			return strings.Join(transform(record.Data().(flow.Data)[0].([]*modelv1.TagValue), stringify), "|")
+		}, t.aggregations, t.newReducers))
+	}
 	t.errCh = t.streamingFlow.Window(streaming.NewTumblingTimeWindows(t.interval, flushInterval)).
 		AllowedMaxWindows(int(t.topNSchema.GetLruSize())).
-		TopN(int(t.topNSchema.GetCountersNumber()),
-			streaming.WithSortKeyExtractor(func(record flow.StreamRecord) int64 {
-				return record.Data().(flow.Data)[2].(int64)
-			}),
-			orderBy(t.topNSchema.GetFieldValueSort()),
-			streaming.WithGroupKeyExtractor(func(record flow.StreamRecord) string {
-				return record.Data().(flow.Data)[1].(string)
-			}),
-		).To(t).Open()
+		TopN(int(t.topNSchema.GetCountersNumber()), opts...).To(t).Open()
 	go t.handleError()
 	return t
 }
 
+func aggregationTagValue(aggregation string) *modelv1.TagValue {
+	return &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: aggregation}}}
+}
+
+// newTopNReducers returns the names of the pre-aggregation functions, and the factory of their reducers.
+func newTopNReducers(functions []modelv1.AggregationFunction) ([]string, func() []streaming.Reducer, error) {
+	if len(functions) == 0 {
+		return nil, nil, nil
+	}
+	names := make([]string, len(functions))
+	for i, f := range functions {
+		if _, err := aggregation.NewFunc[int64](f); err != nil {
+			return nil, nil, err
+		}
+		names[i] = f.String()
+	}
+	return names, func() []streaming.Reducer {
+		reducers := make([]streaming.Reducer, len(functions))
+		for i, f := range functions {
+			fn, _ := aggregation.NewFunc[int64](f)
+			reducers[i] = fn
+		}
+		return reducers
+	}, nil
+}
+
 func orderBy(sort modelv1.Sort) streaming.TopNOption {
 	if sort == modelv1.Sort_SORT_ASC {
 		return streaming.OrderBy(streaming.ASC)
@@ -365,7 +426,13 @@ func (manager *topNProcessorManager) start() error {
 				return innerErr
 			}
 			streamingFlow = streamingFlow.Map(mapper)
+			aggregations, newReducers, aggErr := newTopNReducers(topNSchema.GetAggregationFunctions())
+			if aggErr != nil {
+				return aggErr
+			}
 			processor := &topNStreamingProcessor{
+				aggregations:  aggregations,
+				newReducers:   newReducers,
 				m:             manager.m,
 				l:             manager.l,
 				interval:      interval,
@@ -54,6 +54,7 @@ func newTSTable(fileSystem fs.FileSystem, rootPath string, p common.Position,
 		l:          l,
 		p:          p,
 	}
+	tst.cardinality = loadEntityCardinality(fileSystem, rootPath, l)
 	tst.gc.init(&tst)
 	ee := fileSystem.ReadDir(rootPath)
 	if len(ee) == 0 {
@@ -114,15 +115,21 @@ func newTSTable(fileSystem fs.FileSystem, rootPath string, p common.Position,
 
 type tsTable struct {
 	fileSystem    fs.FileSystem
-	option        option
 	l             *logger.Logger
 	snapshot      *snapshot
 	introductions chan *introduction
 	loopCloser    *run.Closer
+	mergeCh       chan *mergerIntroduction
+	cardinality   *entityCardinality
 	p             common.Position
 	root          string
 	gc            garbageCleaner
+	option        option
 	curPartID     uint64
+	ingestedBytes uint64
+	mergedBytes   uint64
+	// mergeMu serializes the merges of the file parts, so that a part isn't merged twice.
+	mergeMu sync.Mutex
 	sync.RWMutex
 }
 
@@ -175,6 +182,7 @@ func (tst *tsTable) startLoop(cur uint64) {
 	tst.introductions = make(chan *introduction)
 	flushCh := make(chan *flusherIntroduction)
 	mergeCh := make(chan *mergerIntroduction)
+	tst.mergeCh = mergeCh
 	introducerWatcher := make(watcher.Channel, 1)
 	flusherWatcher := make(watcher.Channel, 1)
 	go tst.introducerLoop(flushCh, mergeCh, introducerWatcher, cur+1)
@@ -230,11 +238,19 @@ func (tst *tsTable) mustReadSnapshot(snapshot uint64) []uint64 {
 	return result
 }
 
+// Idle reports whether the table has no memory part, which would be lost once the table is closed.
+func (tst *tsTable) Idle() bool {
+	return !tst.hasMemParts()
+}
+
 func (tst *tsTable) Close() error {
 	if tst.loopCloser != nil {
 		tst.loopCloser.Done()
 		tst.loopCloser.CloseThenWait()
 	}
+	if err := tst.cardinality.persist(tst.fileSystem, tst.root); err != nil {
+		tst.l.Warn().Err(err).Msg("cannot persist the entity cardinality")
+	}
 	tst.RLock()
 	defer tst.RUnlock()
 	if tst.snapshot == nil {
@@ -251,7 +267,7 @@ func (tst *tsTable) mustAddDataPoints(dps *dataPoints) {
 	}
 
 	mp := generateMemPart()
-	mp.mustInitFromDataPoints(dps)
+	mp.mustInitFromDataPoints(dps, tst.option.blockLength(), tst.option.tagFamilyCodecs)
 	p := openMemPart(mp)
 
 	ind := generateIntroduction()
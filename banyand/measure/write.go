@@ -20,14 +20,18 @@ package measure
 import (
 	"bytes"
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/pkg/errors"
 	"google.golang.org/protobuf/types/known/anypb"
 
 	"github.com/apache/skywalking-banyandb/api/common"
 	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
 	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
 	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/banyand/internal/storage"
+	"github.com/apache/skywalking-banyandb/banyand/protector"
 	"github.com/apache/skywalking-banyandb/pkg/bus"
 	"github.com/apache/skywalking-banyandb/pkg/convert"
 	"github.com/apache/skywalking-banyandb/pkg/index"
@@ -39,12 +43,22 @@ import (
 type writeCallback struct {
 	l          *logger.Logger
 	schemaRepo *schemaRepo
+	protector  *protector.Memory
+	// conditionMu is held by a batch carrying conditional writes for writing, and by the other batches for reading,
+	// from evaluating the conditions to applying the data points. No data point is written to a series
+	// between evaluating a condition on its latest data point and applying the conditional write.
+	conditionMu sync.RWMutex
+	// migratedErr rejects the writes to the source path of a copied migration. It's guarded by migrationMu.
+	migratedErr error
+	// migrationMu is held by every batch for reading and by a data path migration for writing.
+	migrationMu sync.RWMutex
 }
 
-func setUpWriteCallback(l *logger.Logger, schemaRepo *schemaRepo) bus.MessageListener {
+func setUpWriteCallback(l *logger.Logger, schemaRepo *schemaRepo, protector *protector.Memory) *writeCallback {
 	return &writeCallback{
 		l:          l,
 		schemaRepo: schemaRepo,
+		protector:  protector,
 	}
 }
 
@@ -54,21 +68,51 @@ func (w *writeCallback) handle(dst map[string]*dataPointsInGroup, writeEvent *me
 	if err := timestamp.Check(t); err != nil {
 		return nil, fmt.Errorf("invalid timestamp: %w", err)
 	}
-	ts := t.UnixNano()
 
 	gn := req.Metadata.Group
 	tsdb, err := w.schemaRepo.loadTSDB(gn)
 	if err != nil {
 		return nil, fmt.Errorf("cannot load tsdb for group %s: %w", gn, err)
 	}
+	if t, _, err = tsdb.AdmitTimestamp(t); err != nil {
+		return nil, err
+	}
+	ts := t.UnixNano()
+	stm, ok := w.schemaRepo.loadMeasure(req.GetMetadata())
+	if !ok {
+		return nil, fmt.Errorf("cannot find measure definition: %s", req.GetMetadata())
+	}
+	fLen := len(req.DataPoint.GetTagFamilies())
+	if fLen < 1 {
+		return nil, fmt.Errorf("%s has no tag family", req.Metadata)
+	}
+	if fLen > len(stm.schema.GetTagFamilies()) {
+		return nil, fmt.Errorf("%s has more tag families than %s", req.Metadata, stm.schema)
+	}
+	series := &pbv1.Series{
+		Subject:      req.Metadata.Name,
+		EntityValues: writeEvent.EntityValues,
+	}
+	if err := series.Marshal(); err != nil {
+		return nil, fmt.Errorf("cannot marshal series: %w", err)
+	}
+	if cond := req.GetCondition(); cond != nil {
+		if err := checkCondition(tsdb, dst[gn], stm, series.ID, cond); err != nil {
+			return nil, err
+		}
+	}
 	dpg, ok := dst[gn]
 	if !ok {
 		dpg = &dataPointsInGroup{
-			tsdb:   tsdb,
-			tables: make([]*dataPointsInTable, 0),
+			tsdb:    tsdb,
+			tables:  make([]*dataPointsInTable, 0),
+			pending: make(map[common.SeriesID]pendingDataPoint),
 		}
 		dst[gn] = dpg
 	}
+	if p, ok := dpg.pending[series.ID]; !ok || p.ts <= ts {
+		dpg.pending[series.ID] = pendingDataPoint{ts: ts, dataPoint: req.DataPoint}
+	}
 	if dpg.latestTS < ts {
 		dpg.latestTS = ts
 	}
@@ -87,30 +131,16 @@ func (w *writeCallback) handle(dst map[string]*dataPointsInGroup, writeEvent *me
 			return nil, fmt.Errorf("cannot create ts table: %w", err)
 		}
 		dpt = &dataPointsInTable{
-			timeRange: tstb.GetTimeRange(),
-			tsTable:   tstb,
+			timeRange:  tstb.GetTimeRange(),
+			tsTable:    tstb,
+			dataPoints: dataPoints{shardNum: tsdb.ShardNum()},
 		}
 		dpg.tables = append(dpg.tables, dpt)
 	}
-	dpt.dataPoints.timestamps = append(dpt.dataPoints.timestamps, ts)
-	stm, ok := w.schemaRepo.loadMeasure(req.GetMetadata())
-	if !ok {
-		return nil, fmt.Errorf("cannot find measure definition: %s", req.GetMetadata())
-	}
-	fLen := len(req.DataPoint.GetTagFamilies())
-	if fLen < 1 {
-		return nil, fmt.Errorf("%s has no tag family", req.Metadata)
-	}
-	if fLen > len(stm.schema.GetTagFamilies()) {
-		return nil, fmt.Errorf("%s has more tag families than %s", req.Metadata, stm.schema)
-	}
-	series := &pbv1.Series{
-		Subject:      req.Metadata.Name,
-		EntityValues: writeEvent.EntityValues,
-	}
-	if err := series.Marshal(); err != nil {
-		return nil, fmt.Errorf("cannot marshal series: %w", err)
+	if err := dpt.tsTable.Table().cardinality.insert(series.ID, series.Subject, series.EntityValues); err != nil {
+		return nil, fmt.Errorf("cannot estimate the entity cardinality: %w", err)
 	}
+	dpt.dataPoints.timestamps = append(dpt.dataPoints.timestamps, ts)
 	dpt.dataPoints.seriesIDs = append(dpt.dataPoints.seriesIDs, series.ID)
 	field := nameValues{}
 	for i := range stm.GetSchema().GetFields() {
@@ -201,6 +231,7 @@ func (w *writeCallback) handle(dst map[string]*dataPointsInGroup, writeEvent *me
 			EntityValues: writeEvent.EntityValues,
 		})
 	}
+	stm.rollups.onMeasureWrite(stm.schema, stm, writeEvent)
 
 	dpg.docs = append(dpg.docs, index.Document{
 		DocID:        uint64(series.ID),
@@ -220,28 +251,77 @@ func (w *writeCallback) Rev(message bus.Message) (resp bus.Message) {
 		w.l.Warn().Msg("empty event")
 		return
 	}
-	groups := make(map[string]*dataPointsInGroup)
+	if _, err := w.write(events); err != nil {
+		return bus.NewMessage(message.ID(), common.NewError("%v", err))
+	}
+	return
+}
+
+// write writes the events, it returns the errors of the rejected ones in the order of the events.
+// The error returned alone rejects all of them.
+func (w *writeCallback) write(events []any) ([]error, error) {
+	if err := w.protector.Check(); err != nil {
+		w.l.Warn().Err(err).Int("events", len(events)).Msg("reject the writes")
+		return nil, err
+	}
+	w.migrationMu.RLock()
+	defer w.migrationMu.RUnlock()
+	if w.migratedErr != nil {
+		w.l.Warn().Err(w.migratedErr).Int("events", len(events)).Msg("reject the writes")
+		return nil, w.migratedErr
+	}
+	errs := make([]error, len(events))
+	writeEvents := make([]*measurev1.InternalWriteRequest, len(events))
+	conditional := false
 	for i := range events {
-		var writeEvent *measurev1.InternalWriteRequest
 		switch e := events[i].(type) {
 		case *measurev1.InternalWriteRequest:
-			writeEvent = e
+			writeEvents[i] = e
 		case *anypb.Any:
-			writeEvent = &measurev1.InternalWriteRequest{}
+			writeEvent := &measurev1.InternalWriteRequest{}
 			if err := e.UnmarshalTo(writeEvent); err != nil {
 				w.l.Error().Err(err).RawJSON("written", logger.Proto(e)).Msg("fail to unmarshal event")
+				errs[i] = err
 				continue
 			}
+			writeEvents[i] = writeEvent
 		default:
 			w.l.Warn().Msg("invalid event data type")
+			errs[i] = errors.New("invalid event data type")
+			continue
+		}
+		if writeEvents[i].GetRequest().GetCondition() != nil {
+			conditional = true
+		}
+	}
+	if conditional {
+		w.conditionMu.Lock()
+		defer w.conditionMu.Unlock()
+	} else {
+		w.conditionMu.RLock()
+		defer w.conditionMu.RUnlock()
+	}
+	groups := make(map[string]*dataPointsInGroup)
+	for i, writeEvent := range writeEvents {
+		if writeEvent == nil {
 			continue
 		}
-		var err error
-		if groups, err = w.handle(groups, writeEvent); err != nil {
+		dst, err := w.handle(groups, writeEvent)
+		errs[i] = err
+		if errors.Is(err, ErrConditionFailed) {
+			w.l.Warn().Err(err).RawJSON("written", logger.Proto(writeEvent)).Msg("reject the conditional write")
+			continue
+		}
+		if errors.Is(err, storage.ErrOutOfRetention) {
+			w.l.Warn().Err(err).RawJSON("written", logger.Proto(writeEvent)).Msg("reject the write out of the retention window")
+			continue
+		}
+		if err != nil {
 			w.l.Error().Err(err).RawJSON("written", logger.Proto(writeEvent)).Msg("cannot handle write event")
 			groups = make(map[string]*dataPointsInGroup)
 			continue
 		}
+		groups = dst
 	}
 	for i := range groups {
 		g := groups[i]
@@ -255,7 +335,43 @@ func (w *writeCallback) Rev(message bus.Message) (resp bus.Message) {
 			w.l.Error().Err(err).Msg("cannot write index")
 		}
 	}
-	return
+	return errs, nil
+}
+
+// conditionalWriteCallback writes a conditional data point at once, and replies whether its condition holds.
+type conditionalWriteCallback struct {
+	*writeCallback
+}
+
+func (w conditionalWriteCallback) Rev(message bus.Message) (resp bus.Message) {
+	writeEvent, ok := message.Data().(*measurev1.InternalWriteRequest)
+	if !ok {
+		return bus.NewMessage(message.ID(), common.NewError("invalid event data type %T", message.Data()))
+	}
+	errs, err := w.write([]any{writeEvent})
+	if err == nil {
+		err = errs[0]
+	}
+	wr := &measurev1.WriteResponse{
+		MessageId: writeEvent.GetRequest().GetMessageId(),
+		Status:    modelv1.Status_STATUS_SUCCEED,
+	}
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrConditionFailed):
+		wr.Status = modelv1.Status_STATUS_CONDITION_FAILED
+		wr.Metadata = writeEvent.GetRequest().GetMetadata()
+	case errors.Is(err, storage.ErrOutOfRetention):
+		wr.Status = modelv1.Status_STATUS_OUT_OF_RETENTION
+		wr.Metadata = writeEvent.GetRequest().GetMetadata()
+	case errors.Is(err, protector.ErrMemoryExhausted):
+		wr.Status = modelv1.Status_STATUS_MEMORY_EXHAUSTED
+		wr.Metadata = writeEvent.GetRequest().GetMetadata()
+	default:
+		wr.Status = modelv1.Status_STATUS_INTERNAL_ERROR
+		wr.Metadata = writeEvent.GetRequest().GetMetadata()
+	}
+	return bus.NewMessage(message.ID(), wr)
 }
 
 func encodeFieldValue(name string, fieldType databasev1.FieldType, fieldValue *modelv1.FieldValue) *nameValue {
@@ -0,0 +1,97 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/apache/skywalking-banyandb/banyand/observability"
+	"github.com/apache/skywalking-banyandb/pkg/meter"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+const writeAmplificationCollectorName = "measure_write_amplification"
+
+// WriteAmplification records the bytes written to the disk by a group.
+type WriteAmplification struct {
+	// IngestedBytes is the size of parts flushed from the memory.
+	IngestedBytes uint64
+	// MergedBytes is the size of parts rewritten by the background merger.
+	MergedBytes uint64
+}
+
+// Ratio returns the total written bytes divided by the ingested bytes.
+// It returns 0 if nothing is ingested.
+func (wa WriteAmplification) Ratio() float64 {
+	if wa.IngestedBytes == 0 {
+		return 0
+	}
+	return float64(wa.IngestedBytes+wa.MergedBytes) / float64(wa.IngestedBytes)
+}
+
+func (wa *WriteAmplification) add(other WriteAmplification) {
+	wa.IngestedBytes += other.IngestedBytes
+	wa.MergedBytes += other.MergedBytes
+}
+
+func (tst *tsTable) writeAmplification() WriteAmplification {
+	return WriteAmplification{
+		IngestedBytes: atomic.LoadUint64(&tst.ingestedBytes),
+		MergedBytes:   atomic.LoadUint64(&tst.mergedBytes),
+	}
+}
+
+func (sr *schemaRepo) writeAmplification(group string, timeRange timestamp.TimeRange) (WriteAmplification, error) {
+	var wa WriteAmplification
+	db, err := sr.loadTSDB(group)
+	if err != nil {
+		return wa, err
+	}
+	tabWrappers := db.SelectTSTables(timeRange)
+	defer func() {
+		for i := range tabWrappers {
+			tabWrappers[i].DecRef()
+		}
+	}()
+	for i := range tabWrappers {
+		wa.add(tabWrappers[i].Table().writeAmplification())
+	}
+	return wa, nil
+}
+
+type writeAmplificationCollector struct {
+	gauge meter.Gauge
+	sr    *schemaRepo
+	once  sync.Once
+}
+
+func (c *writeAmplificationCollector) collect() {
+	c.once.Do(func() {
+		c.gauge = observability.NewGauge(observability.NewMeterProviders(observability.RootScope.SubScope("measure")),
+			"write_amplification", "group")
+	})
+	for _, g := range c.sr.LoadAllGroups() {
+		name := g.GetSchema().GetMetadata().GetName()
+		wa, err := c.sr.writeAmplification(name, timestamp.AllTime)
+		if err != nil {
+			continue
+		}
+		c.gauge.Set(wa.Ratio(), name)
+	}
+}
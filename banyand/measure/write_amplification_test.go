@@ -0,0 +1,72 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/pkg/fs"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/test"
+	"github.com/apache/skywalking-banyandb/pkg/test/flags"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+func TestWriteAmplificationRatio(t *testing.T) {
+	assert.Zero(t, WriteAmplification{}.Ratio())
+	assert.Zero(t, WriteAmplification{MergedBytes: 10}.Ratio())
+	assert.InDelta(t, 1.0, WriteAmplification{IngestedBytes: 10}.Ratio(), 1e-9)
+	assert.InDelta(t, 2.5, WriteAmplification{IngestedBytes: 10, MergedBytes: 15}.Ratio(), 1e-9)
+}
+
+func Test_tsTable_writeAmplification(t *testing.T) {
+	tmpPath, defFn := test.Space(require.New(t))
+	defer defFn()
+	tst, err := newTSTable(fs.NewLocalFileSystem(), tmpPath, common.Position{},
+		logger.GetLogger("test"), timestamp.TimeRange{}, option{flushTimeout: 0, mergePolicy: newDefaultMergePolicyForTesting()})
+	require.NoError(t, err)
+	defer tst.Close()
+
+	const partNum = 6
+	for i := int64(0); i < partNum; i++ {
+		tst.mustAddDataPoints(generateHugeDps(i*100+1, i*100+100, i*100+50))
+		require.Eventually(t, func() bool {
+			snp := tst.currentSnapshot()
+			if snp == nil {
+				return false
+			}
+			defer snp.decRef()
+			return snp.creator != snapshotCreatorMemPart
+		}, flags.EventuallyTimeout, 100*time.Millisecond)
+	}
+	require.Eventually(t, func() bool {
+		return tst.writeAmplification().MergedBytes > 0
+	}, flags.EventuallyTimeout, 100*time.Millisecond)
+
+	wa := tst.writeAmplification()
+	require.Positive(t, wa.IngestedBytes)
+	ratio := wa.Ratio()
+	assert.Greater(t, ratio, 1.0)
+	// Every merge rewrites at most all of the ingested bytes.
+	assert.LessOrEqual(t, ratio, float64(partNum+1))
+}
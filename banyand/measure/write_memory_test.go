@@ -0,0 +1,50 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/banyand/protector"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+func TestMemoryProtector(t *testing.T) {
+	mp := protector.NewMemoryWithUsage(100, func() uint64 { return 101 })
+	// The writes are rejected before they reach the schema repository, which isn't set up.
+	w := setUpWriteCallback(logger.GetLogger("test"), &schemaRepo{}, mp)
+	resp := w.Rev(bus.NewMessage(bus.MessageID(time.Now().UnixNano()), []any{&measurev1.InternalWriteRequest{}}))
+	require.NotNil(t, resp.Data())
+	e, ok := resp.Data().(common.Error)
+	require.True(t, ok, "expected a common.Error, got %T", resp.Data())
+	assert.Contains(t, e.Msg(), protector.ErrMemoryExhausted.Error())
+
+	// A conditional write replies with the status, which tells the client to retry it later.
+	resp = conditionalWriteCallback{w}.Rev(bus.NewMessage(bus.MessageID(time.Now().UnixNano()), &measurev1.InternalWriteRequest{}))
+	wr, ok := resp.Data().(*measurev1.WriteResponse)
+	require.True(t, ok, "expected a WriteResponse, got %T", resp.Data())
+	assert.Equal(t, modelv1.Status_STATUS_MEMORY_EXHAUSTED, wr.GetStatus())
+}
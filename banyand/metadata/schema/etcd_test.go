@@ -29,9 +29,11 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 
 	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
 	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	"github.com/apache/skywalking-banyandb/api/validate"
 	"github.com/apache/skywalking-banyandb/banyand/metadata/embeddedetcd"
 	"github.com/apache/skywalking-banyandb/banyand/metadata/schema"
 	"github.com/apache/skywalking-banyandb/pkg/test"
@@ -421,3 +423,46 @@ func Test_Etcd_Entity_Update(t *testing.T) {
 		})
 	}
 }
+
+func Test_Etcd_Group_BlockSize(t *testing.T) {
+	registry, closer := initServerAndRegister(t)
+	defer closer()
+
+	g := &commonv1.Group{}
+	require.NoError(t, protojson.Unmarshal([]byte(groupJSON), g))
+	for _, blockSize := range []uint32{0, validate.MaxMeasureBlockSize + 1} {
+		g.ResourceOpts.BlockSize = proto.Uint32(blockSize)
+		assert.Error(t, registry.CreateGroup(context.TODO(), g), "block size %d", blockSize)
+	}
+	g.ResourceOpts.BlockSize = proto.Uint32(128)
+	require.NoError(t, registry.CreateGroup(context.TODO(), g))
+	g.ResourceOpts.BlockSize = proto.Uint32(0)
+	assert.Error(t, registry.UpdateGroup(context.TODO(), g))
+}
+
+func Test_Etcd_Group_Restore(t *testing.T) {
+	registry, closer := initServerAndRegister(t)
+	defer closer()
+	require.NoError(t, preloadSchema(registry))
+	ctx := context.TODO()
+	streamMeta := &commonv1.Metadata{Name: "sw", Group: "default"}
+
+	assert.ErrorIs(t, registry.RestoreGroup(ctx, "default"), schema.ErrGRPCResourceNotFound, "a group in use can't be restored")
+	deleted, err := registry.DeleteGroup(ctx, "default")
+	require.NoError(t, err)
+	require.True(t, deleted)
+	_, err = registry.GetStream(ctx, streamMeta)
+	require.ErrorIs(t, err, schema.ErrGRPCResourceNotFound)
+
+	require.NoError(t, registry.RestoreGroup(ctx, "default"))
+	g, err := registry.GetGroup(ctx, "default")
+	require.NoError(t, err)
+	assert.Equal(t, "default", g.GetMetadata().GetName())
+	s, err := registry.GetStream(ctx, streamMeta)
+	require.NoError(t, err)
+	assert.Equal(t, "sw", s.GetMetadata().GetName())
+	rules, err := registry.ListIndexRule(ctx, schema.ListOpt{Group: "default"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, rules)
+	assert.ErrorIs(t, registry.RestoreGroup(ctx, "default"), schema.ErrGRPCResourceNotFound, "a group is only restored once")
+}
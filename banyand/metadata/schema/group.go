@@ -20,15 +20,25 @@ package schema
 import (
 	"context"
 	"path"
+	"strings"
 
 	"github.com/pkg/errors"
+	"go.etcd.io/etcd/api/v3/mvccpb"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	"github.com/apache/skywalking-banyandb/api/validate"
 )
 
-var groupsKeyPrefix = "/groups/"
+var (
+	groupsKeyPrefix = "/groups/"
+	// deletedGroupsKeyPrefix holds the items of the deleted groups until they're restored.
+	deletedGroupsKeyPrefix = "/deleted-groups/"
+)
+
+// maxTxnOps is below the default limit of the operations in an etcd transaction.
+const maxTxnOps = 100
 
 func (e *etcdSchemaRegistry) GetGroup(ctx context.Context, group string) (*commonv1.Group, error) {
 	var entity commonv1.Group
@@ -57,11 +67,38 @@ func (e *etcdSchemaRegistry) DeleteGroup(ctx context.Context, group string) (boo
 		return false, errors.Wrap(err, group)
 	}
 	keysToDelete := allKeys()
-	deleteOPs := make([]clientv3.Op, 0, len(keysToDelete)+1)
+	prefixes := make([]string, 0, len(keysToDelete)+1)
 	for _, key := range keysToDelete {
-		deleteOPs = append(deleteOPs, clientv3.OpDelete(e.prependNamespace(listPrefixesForEntity(group, key)), clientv3.WithPrefix()))
+		prefixes = append(prefixes, e.prependNamespace(listPrefixesForEntity(group, key)))
+	}
+	prefixes = append(prefixes, e.prependNamespace(formatGroupKey(group)))
+	// The items are kept under the deleted groups, so that the group can be restored.
+	trash := e.deletedGroupPrefix(group)
+	if _, err = e.client.Delete(ctx, trash, clientv3.WithPrefix()); err != nil {
+		return false, err
+	}
+	var kvs []*mvccpb.KeyValue
+	// The group key is listed under the prefix of its kind too.
+	seen := make(map[string]struct{})
+	for _, prefix := range prefixes {
+		resp, getErr := e.client.Get(ctx, prefix, clientv3.WithPrefix())
+		if getErr != nil {
+			return false, getErr
+		}
+		for _, kv := range resp.Kvs {
+			if _, ok := seen[string(kv.Key)]; !ok {
+				seen[string(kv.Key)] = struct{}{}
+				kvs = append(kvs, kv)
+			}
+		}
+	}
+	if err = e.putAll(ctx, trash, kvs); err != nil {
+		return false, err
+	}
+	deleteOPs := make([]clientv3.Op, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		deleteOPs = append(deleteOPs, clientv3.OpDelete(prefix, clientv3.WithPrefix()))
 	}
-	deleteOPs = append(deleteOPs, clientv3.OpDelete(e.prependNamespace(formatGroupKey(group)), clientv3.WithPrefix()))
 	txnResponse, err := e.client.Txn(ctx).Then(deleteOPs...).Commit()
 	if err != nil {
 		return false, err
@@ -72,6 +109,65 @@ func (e *etcdSchemaRegistry) DeleteGroup(ctx context.Context, group string) (boo
 	return true, nil
 }
 
+// RestoreGroup creates the deleted group again, then its items. The data nodes re-link the data of
+// the group once it's created, if it's restored within their grace period.
+func (e *etcdSchemaRegistry) RestoreGroup(ctx context.Context, group string) error {
+	trash := e.deletedGroupPrefix(group)
+	resp, err := e.client.Get(ctx, trash, clientv3.WithPrefix())
+	if err != nil {
+		return err
+	}
+	groupKey := e.prependNamespace(formatGroupKey(group))
+	var groupValue []byte
+	kvs := make([]*mvccpb.KeyValue, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		key := strings.TrimPrefix(string(kv.Key), trash)
+		if key == groupKey {
+			groupValue = kv.Value
+			continue
+		}
+		kvs = append(kvs, &mvccpb.KeyValue{Key: []byte(key), Value: kv.Value})
+	}
+	if groupValue == nil {
+		return errors.WithMessagef(ErrGRPCResourceNotFound, "deleted group %s", group)
+	}
+	txnResp, err := e.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(groupKey), "=", 0)).
+		Then(clientv3.OpPut(groupKey, string(groupValue))).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if !txnResp.Succeeded {
+		return errors.WithMessagef(ErrGRPCAlreadyExists, "group %s", group)
+	}
+	if err = e.putAll(ctx, "", kvs); err != nil {
+		return err
+	}
+	_, err = e.client.Delete(ctx, trash, clientv3.WithPrefix())
+	return err
+}
+
+// putAll puts the key-values with the keys prefixed, in transactions within the limit of etcd.
+func (e *etcdSchemaRegistry) putAll(ctx context.Context, prefix string, kvs []*mvccpb.KeyValue) error {
+	for len(kvs) > 0 {
+		n := min(len(kvs), maxTxnOps)
+		ops := make([]clientv3.Op, 0, n)
+		for _, kv := range kvs[:n] {
+			ops = append(ops, clientv3.OpPut(prefix+string(kv.Key), string(kv.Value)))
+		}
+		if _, err := e.client.Txn(ctx).Then(ops...).Commit(); err != nil {
+			return err
+		}
+		kvs = kvs[n:]
+	}
+	return nil
+}
+
+func (e *etcdSchemaRegistry) deletedGroupPrefix(group string) string {
+	return e.prependNamespace(path.Join(deletedGroupsKeyPrefix, group)) + "/"
+}
+
 func (e *etcdSchemaRegistry) CreateGroup(ctx context.Context, group *commonv1.Group) error {
 	if group.UpdatedAt != nil {
 		group.UpdatedAt = timestamppb.Now()
@@ -82,6 +178,9 @@ func (e *etcdSchemaRegistry) CreateGroup(ctx context.Context, group *commonv1.Gr
 	if group.Metadata.Name == "" {
 		return errors.New("metadata.name is required")
 	}
+	if err := validate.BlockSize(group.GetResourceOpts()); err != nil {
+		return err
+	}
 	_, err := e.create(ctx, Metadata{
 		TypeMeta: TypeMeta{
 			Kind: KindGroup,
@@ -102,6 +201,9 @@ func (e *etcdSchemaRegistry) UpdateGroup(ctx context.Context, group *commonv1.Gr
 	if group.Metadata.Name == "" {
 		return errors.New("metadata.name is required")
 	}
+	if err := validate.BlockSize(group.GetResourceOpts()); err != nil {
+		return err
+	}
 	_, err := e.update(ctx, Metadata{
 		TypeMeta: TypeMeta{
 			Kind: KindGroup,
@@ -183,6 +183,8 @@ type Group interface {
 	DeleteGroup(ctx context.Context, group string) (bool, error)
 	CreateGroup(ctx context.Context, group *commonv1.Group) error
 	UpdateGroup(ctx context.Context, group *commonv1.Group) error
+	// RestoreGroup creates a deleted group again with the items it had when it was deleted.
+	RestoreGroup(ctx context.Context, group string) error
 }
 
 // TopNAggregation allows CRUD top-n aggregation schemas in a group.
@@ -0,0 +1,66 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+package observability
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const adminPathPrefix = "/admin/"
+
+// AdminHandlers serves the admin endpoints under /admin/ of the metric server.
+var AdminHandlers = AdminMux{
+	handlers: make(map[string]http.HandlerFunc),
+}
+
+func init() {
+	metricsMux.Handle(adminPathPrefix, &AdminHandlers)
+}
+
+// AdminMux dispatches the admin requests by the path following /admin/.
+type AdminMux struct {
+	handlers map[string]http.HandlerFunc
+	hMux     sync.RWMutex
+}
+
+// Register registers the handler serving /admin/<name>. It replaces the handler registered with the same name.
+func (m *AdminMux) Register(name string, handler http.HandlerFunc) {
+	m.hMux.Lock()
+	defer m.hMux.Unlock()
+	m.handlers[name] = handler
+}
+
+// Unregister unregisters the handler serving /admin/<name>.
+func (m *AdminMux) Unregister(name string) {
+	m.hMux.Lock()
+	defer m.hMux.Unlock()
+	delete(m.handlers, name)
+}
+
+// ServeHTTP dispatches the request to the handler registered with the path following /admin/.
+func (m *AdminMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.hMux.RLock()
+	handler, ok := m.handlers[strings.TrimPrefix(r.URL.Path, adminPathPrefix)]
+	m.hMux.RUnlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	handler(w, r)
+}
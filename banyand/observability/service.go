@@ -44,8 +44,23 @@ var (
 	metricsMux             = http.NewServeMux()
 	// MetricsServerInterceptor is the function to obtain grpc metrics interceptor.
 	MetricsServerInterceptor func() (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) = emptyMetricsServerInterceptor
+
+	providerFactories   []func(meter.Scope) meter.Provider
+	providerFactoriesMu sync.RWMutex
 )
 
+// NewMeterProviders returns the meter providers of the enabled observability modes for the given scope.
+// It returns nothing before the metric service is pre-run.
+func NewMeterProviders(scope meter.Scope) []meter.Provider {
+	providerFactoriesMu.RLock()
+	defer providerFactoriesMu.RUnlock()
+	providers := make([]meter.Provider, 0, len(providerFactories))
+	for _, f := range providerFactories {
+		providers = append(providers, f(scope))
+	}
+	return providers
+}
+
 // Service type for Metric Service.
 type Service interface {
 	run.PreRunner
@@ -98,21 +113,24 @@ func (p *metricService) Validate() error {
 func (p *metricService) PreRun(ctx context.Context) error {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
-	var providers []meter.Provider
+	var factories []func(meter.Scope) meter.Provider
 	for _, mode := range p.modes {
 		switch mode {
 		case flagPromethusMode:
 			MetricsServerInterceptor = promMetricsServerInterceptor
-			providers = append(providers, newPromMeterProvider(SystemScope))
+			factories = append(factories, newPromMeterProvider)
 		case flagNativeMode:
 			err := createNativeObservabilityGroup(ctx, p.metadata)
 			if err != nil {
 				p.l.Warn().Err(err).Msg("Failed to create native observability group")
 			}
-			providers = append(providers, newNativeMeterProvider(SystemScope))
+			factories = append(factories, newNativeMeterProvider)
 		}
 	}
-	initMetrics(providers)
+	providerFactoriesMu.Lock()
+	providerFactories = factories
+	providerFactoriesMu.Unlock()
+	initMetrics(NewMeterProviders(SystemScope))
 	return nil
 }
 
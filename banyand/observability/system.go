@@ -60,6 +60,13 @@ func UpdatePath(path string) {
 	systemInfoInstance.DiskUsages[path] = nil
 }
 
+// RemovePath stops reporting the disk usage of a path.
+func RemovePath(path string) {
+	systemInfoInstance.Lock()
+	defer systemInfoInstance.Unlock()
+	delete(systemInfoInstance.DiskUsages, path)
+}
+
 func getPath() map[string]*DiskUsage {
 	systemInfoInstance.RLock()
 	defer systemInfoInstance.RUnlock()
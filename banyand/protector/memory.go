@@ -0,0 +1,104 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package protector rejects the writes arriving while the process runs short of memory.
+package protector
+
+import (
+	"runtime/metrics"
+
+	"github.com/pkg/errors"
+
+	"github.com/apache/skywalking-banyandb/pkg/run"
+)
+
+// ErrMemoryExhausted rejects the writes arriving while the memory in use exceeds the limit.
+// The writes aren't applied, so the client should back off and retry them.
+var ErrMemoryExhausted = errors.New("the memory in use exceeds the limit, retry the write later")
+
+// liveHeapMetric is the heap marked live by the latest garbage collection,
+// which excludes the garbage not collected yet.
+const liveHeapMetric = "/gc/heap/live:bytes"
+
+var _ run.Config = (*Memory)(nil)
+
+// Memory rejects the writes while the live heap is over the limit,
+// so that a write burst backs off instead of running the process out of memory.
+// The liaison checks it before acknowledging a write, and the data node before applying it.
+type Memory struct {
+	usage func() uint64
+	limit uint64
+}
+
+// NewMemory returns a memory protector whose limit is set by its flag.
+func NewMemory() *Memory {
+	return &Memory{usage: liveHeap}
+}
+
+// NewMemoryWithUsage returns a memory protector reading the memory in use from usage.
+func NewMemoryWithUsage(limit uint64, usage func() uint64) *Memory {
+	return &Memory{usage: usage, limit: limit}
+}
+
+// FlagSet implements run.Config.
+func (m *Memory) FlagSet() *run.FlagSet {
+	flagS := run.NewFlagSet("memory-protector")
+	flagS.Uint64Var(&m.limit, "measure-write-memory-limit", 0,
+		"the bytes of the live heap beyond which the measure writes are rejected until it drops, 0 disables it")
+	return flagS
+}
+
+// Validate implements run.Config.
+func (m *Memory) Validate() error {
+	return nil
+}
+
+// Name implements run.Unit.
+func (m *Memory) Name() string {
+	return "memory-protector"
+}
+
+// GetLimit returns the limit in bytes, 0 means no limit.
+func (m *Memory) GetLimit() uint64 {
+	if m == nil {
+		return 0
+	}
+	return m.limit
+}
+
+// Check returns an error wrapping ErrMemoryExhausted if the live heap exceeds the limit.
+// A nil protector or a zero limit doesn't reject any write.
+func (m *Memory) Check() error {
+	if m == nil || m.limit == 0 {
+		return nil
+	}
+	if used := m.usage(); used > m.limit {
+		return errors.Wrapf(ErrMemoryExhausted, "%d bytes in use, the limit is %d bytes", used, m.limit)
+	}
+	return nil
+}
+
+// liveHeap returns the bytes of the heap objects that survived the latest garbage collection.
+// It's read without stopping the world.
+func liveHeap() uint64 {
+	sample := []metrics.Sample{{Name: liveHeapMetric}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
@@ -0,0 +1,41 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package protector
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemory(t *testing.T) {
+	var used uint64
+	m := NewMemoryWithUsage(100, func() uint64 { return used })
+	used = 100
+	assert.NoError(t, m.Check())
+	used = 101
+	assert.ErrorIs(t, m.Check(), ErrMemoryExhausted)
+	assert.NoError(t, NewMemoryWithUsage(0, func() uint64 { return used }).Check(), "a zero limit doesn't reject any write")
+	var nilMemory *Memory
+	assert.NoError(t, nilMemory.Check())
+	assert.Zero(t, nilMemory.GetLimit())
+	// The live heap is known after the first garbage collection.
+	runtime.GC()
+	assert.NotZero(t, liveHeap())
+}
@@ -19,6 +19,7 @@ package query
 
 import (
 	"context"
+	"crypto/subtle"
 	"time"
 
 	"go.uber.org/multierr"
@@ -27,13 +28,17 @@ import (
 	"github.com/apache/skywalking-banyandb/api/data"
 	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
 	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
 	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/banyand/internal/storage"
 	"github.com/apache/skywalking-banyandb/banyand/measure"
 	"github.com/apache/skywalking-banyandb/banyand/metadata"
 	"github.com/apache/skywalking-banyandb/banyand/queue"
 	"github.com/apache/skywalking-banyandb/banyand/stream"
 	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/grpchelper"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
 	"github.com/apache/skywalking-banyandb/pkg/query/executor"
 	logical_measure "github.com/apache/skywalking-banyandb/pkg/query/logical/measure"
 	logical_stream "github.com/apache/skywalking-banyandb/pkg/query/logical/stream"
@@ -46,6 +51,7 @@ const (
 
 var (
 	_ run.PreRunner       = (*queryService)(nil)
+	_ run.Config          = (*queryService)(nil)
 	_ bus.MessageListener = (*streamQueryProcessor)(nil)
 	_ bus.MessageListener = (*measureQueryProcessor)(nil)
 	_ bus.MessageListener = (*topNQueryProcessor)(nil)
@@ -59,6 +65,12 @@ type queryService struct {
 	sqp         *streamQueryProcessor
 	mqp         *measureQueryProcessor
 	tqp         *topNQueryProcessor
+	// privilegedToken is the bearer token of the privileged callers, whose queries are bounded by privilegedMaxSeries.
+	privilegedToken string
+	// maxBytesRead bounds the bytes a stream query reads from disk. Zero means no limit.
+	maxBytesRead uint64
+	// privilegedMaxSeries overrides the limit on the number of series a privileged caller's query matches.
+	privilegedMaxSeries int
 }
 
 type streamQueryProcessor struct {
@@ -96,6 +108,12 @@ func (p *streamQueryProcessor) Rev(message bus.Message) (resp bus.Message) {
 		return
 	}
 
+	orders := append([]*modelv1.QueryOrder{queryCriteria.GetOrderBy()}, queryCriteria.GetThenBy()...)
+	if err = ec.CheckRestricted(message.Context(), queryCriteria.GetCriteria(), orders...); err != nil {
+		resp = bus.NewMessage(bus.MessageID(now), common.NewError("fail to query stream %s: %v", meta.GetName(), err))
+		return
+	}
+
 	plan, err := logical_stream.Analyze(context.TODO(), queryCriteria, meta, s)
 	if err != nil {
 		resp = bus.NewMessage(bus.MessageID(now), common.NewError("fail to analyze the query request for stream %s: %v", meta.GetName(), err))
@@ -103,16 +121,31 @@ func (p *streamQueryProcessor) Rev(message bus.Message) (resp bus.Message) {
 	}
 
 	if p.log.Debug().Enabled() {
-		p.log.Debug().Str("plan", plan.String()).Msg("query plan")
+		p.log.Debug().Str("plan", plan.String()).Interface("explain", logical_stream.Explain(plan)).Msg("query plan")
+	}
+	account := storage.NewReadAccount(p.maxBytesRead)
+	ctx := storage.WithReadAccount(executor.WithStreamExecutionContext(p.privileged(message.Context()), ec), account)
+	entities, err := plan.(executor.StreamExecutable).Execute(ctx)
+	if err == nil {
+		// The blocks exceeding the limit are dropped while the results are pulled, so the plan may not see the error.
+		err = account.Err()
 	}
-	entities, err := plan.(executor.StreamExecutable).Execute(executor.WithStreamExecutionContext(context.Background(), ec))
 	if err != nil {
-		p.log.Error().Err(err).RawJSON("req", logger.Proto(queryCriteria)).Msg("fail to execute the query plan")
-		resp = bus.NewMessage(bus.MessageID(now), common.NewError("execute the query plan for stream %s: %v", meta.GetName(), err))
+		p.log.Error().Err(err).Uint64("bytes_read", account.BytesRead()).RawJSON("req", logger.Proto(queryCriteria)).Msg("fail to execute the query plan")
+		resp = bus.NewMessage(bus.MessageID(now), common.NewErrorWithCause(err, "execute the query plan for stream %s: %v", meta.GetName(), err))
 		return
 	}
 
-	resp = bus.NewMessage(bus.MessageID(now), &streamv1.QueryResponse{Elements: entities})
+	nextPageToken, err := logical_stream.NextPageToken(queryCriteria, entities)
+	if err != nil {
+		resp = bus.NewMessage(bus.MessageID(now), common.NewError("fail to build the next page token for stream %s: %v", meta.GetName(), err))
+		return
+	}
+	r := &streamv1.QueryResponse{Elements: entities, BytesRead: account.BytesRead(), NextPageToken: nextPageToken}
+	if queryCriteria.GetChecksum() {
+		r.Checksum = pbv1.StreamQueryChecksum(entities)
+	}
+	resp = bus.NewMessage(bus.MessageID(now), r)
 
 	return
 }
@@ -165,10 +198,10 @@ func (p *measureQueryProcessor) Rev(message bus.Message) (resp bus.Message) {
 		e.Str("plan", plan.String()).Msg("query plan")
 	}
 
-	mIterator, err := plan.(executor.MeasureExecutable).Execute(executor.WithMeasureExecutionContext(context.Background(), ec))
+	mIterator, err := plan.(executor.MeasureExecutable).Execute(executor.WithMeasureExecutionContext(p.privileged(message.Context()), ec))
 	if err != nil {
 		ml.Error().Err(err).RawJSON("req", logger.Proto(queryCriteria)).Msg("fail to close the query plan")
-		resp = bus.NewMessage(bus.MessageID(now), common.NewError("fail to execute the query plan for measure %s: %v", meta.GetName(), err))
+		resp = bus.NewMessage(bus.MessageID(now), common.NewErrorWithCause(err, "fail to execute the query plan for measure %s: %v", meta.GetName(), err))
 		return
 	}
 	defer func() {
@@ -186,7 +219,11 @@ func (p *measureQueryProcessor) Rev(message bus.Message) (resp bus.Message) {
 	if e := ml.Debug(); e.Enabled() {
 		e.RawJSON("ret", logger.Proto(&measurev1.QueryResponse{DataPoints: result})).Msg("got a measure")
 	}
-	resp = bus.NewMessage(bus.MessageID(now), &measurev1.QueryResponse{DataPoints: result})
+	r := &measurev1.QueryResponse{DataPoints: result}
+	if queryCriteria.GetChecksum() {
+		r.Checksum = pbv1.MeasureQueryChecksum(result)
+	}
+	resp = bus.NewMessage(bus.MessageID(now), r)
 	return
 }
 
@@ -194,6 +231,32 @@ func (q *queryService) Name() string {
 	return moduleName
 }
 
+func (q *queryService) FlagSet() *run.FlagSet {
+	flagS := run.NewFlagSet("query")
+	flagS.Uint64Var(&q.maxBytesRead, "stream-max-bytes-read-per-query", 0,
+		"the maximum number of bytes a stream query reads from disk, 0 means no limit")
+	flagS.StringVar(&q.privilegedToken, "query-privileged-token", "",
+		"the bearer token of the privileged callers, whose queries are bounded by query-privileged-max-series instead of the limit of their group")
+	flagS.IntVar(&q.privilegedMaxSeries, "query-privileged-max-series", 0,
+		"the maximum number of series a query of the privileged callers matches, 0 means no limit")
+	return flagS
+}
+
+// privileged lifts the limit on the number of series to privilegedMaxSeries if the caller carried by ctx presents the privileged token.
+func (q *queryService) privileged(ctx context.Context) context.Context {
+	if q.privilegedToken == "" {
+		return ctx
+	}
+	if subtle.ConstantTimeCompare([]byte(grpchelper.Authorization(ctx)), []byte("Bearer "+q.privilegedToken)) != 1 {
+		return ctx
+	}
+	return storage.WithMaxSeries(ctx, q.privilegedMaxSeries)
+}
+
+func (q *queryService) Validate() error {
+	return nil
+}
+
 func (q *queryService) PreRun(_ context.Context) error {
 	q.log = logger.GetLogger(moduleName)
 	return multierr.Combine(
@@ -0,0 +1,45 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package query
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/apache/skywalking-banyandb/pkg/grpchelper"
+)
+
+func TestPrivileged(t *testing.T) {
+	caller := func(credential string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(grpchelper.AuthorizationKey, credential))
+	}
+	q := &queryService{privilegedToken: "secret", privilegedMaxSeries: 1000}
+	anonymous := context.Background()
+	assert.Equal(t, anonymous, q.privileged(anonymous))
+	other := caller("Bearer other")
+	assert.Equal(t, other, q.privileged(other))
+	privileged := caller("Bearer secret")
+	assert.NotEqual(t, privileged, q.privileged(privileged))
+
+	// Without a token, no caller is privileged.
+	q.privilegedToken = ""
+	assert.Equal(t, privileged, q.privileged(privileged))
+}
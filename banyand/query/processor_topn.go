@@ -83,6 +83,13 @@ func (t *topNQueryProcessor) Rev(message bus.Message) (resp bus.Message) {
 		t.log.Warn().Msg("unmatched sort direction")
 		return
 	}
+	var rollupFunc modelv1.AggregationFunction
+	if request.GetRollupInterval() != nil {
+		if rollupFunc, err = TopNRollupFunction(request, topNSchema); err != nil {
+			resp = bus.NewMessage(bus.MessageID(now), common.NewError("fail to roll up topn %s: %v", topNMetadata.GetName(), err))
+			return
+		}
+	}
 	sourceMeasure, err := t.measureService.Measure(topNSchema.GetSourceMeasure())
 	if err != nil {
 		t.log.Error().Err(err).
@@ -127,6 +134,20 @@ func (t *topNQueryProcessor) Rev(message bus.Message) (resp bus.Message) {
 		}
 	}()
 
+	if interval := request.GetRollupInterval(); interval != nil {
+		rollup := NewTopNRollup(interval.AsDuration(), request.GetTopN(), rollupFunc, request.GetFieldValueSort())
+		for mIterator.Next() {
+			for _, dp := range mIterator.Current() {
+				if err = rollup.Put(dp.GetTimestamp().AsTime(), dp.GetTagFamilies()[0].GetTags(), dp.GetFields()[0].GetValue().GetInt().GetValue()); err != nil {
+					resp = bus.NewMessage(bus.MessageID(now), common.NewError("fail to roll up topn %s: %v", topNMetadata.GetName(), err))
+					return
+				}
+			}
+		}
+		resp = bus.NewMessage(bus.MessageID(now), &measurev1.TopNResponse{Lists: rollup.Val()})
+		return
+	}
+
 	result := make([]*measurev1.DataPoint, 0)
 	for mIterator.Next() {
 		current := mIterator.Current()
@@ -0,0 +1,154 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package query
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/pkg/query/aggregation"
+)
+
+var errRollupWithoutFunction = errors.New("rollup_interval requires agg unless the topn has a single aggregation function")
+
+// TopNRollupFunction returns the function re-aggregating the entries of a rollup query.
+// The agg of the request takes precedence over the only aggregation function of the TopN.
+// The entries pre-aggregated by COUNT are rolled up by SUM.
+func TopNRollupFunction(request *measurev1.TopNRequest, topNSchema *databasev1.TopNAggregation) (modelv1.AggregationFunction, error) {
+	if request.GetAgg() != modelv1.AggregationFunction_AGGREGATION_FUNCTION_UNSPECIFIED {
+		return request.GetAgg(), nil
+	}
+	if functions := topNSchema.GetAggregationFunctions(); len(functions) == 1 {
+		return RollupPartialFunction(functions[0]), nil
+	}
+	return modelv1.AggregationFunction_AGGREGATION_FUNCTION_UNSPECIFIED, errRollupWithoutFunction
+}
+
+// RollupPartialFunction returns the function combining the partial results of the function.
+func RollupPartialFunction(aggrFunc modelv1.AggregationFunction) modelv1.AggregationFunction {
+	if aggrFunc == modelv1.AggregationFunction_AGGREGATION_FUNCTION_COUNT {
+		return modelv1.AggregationFunction_AGGREGATION_FUNCTION_SUM
+	}
+	return aggrFunc
+}
+
+// TopNRollup re-aggregates the TopN entries into the buckets of an interval, and ranks the entities of every bucket.
+type TopNRollup struct {
+	buckets  map[int64]map[string]*rollupEntity
+	interval int64
+	topN     int32
+	sort     modelv1.Sort
+	aggrFunc modelv1.AggregationFunction
+}
+
+type rollupEntity struct {
+	int64Func aggregation.Func[int64]
+	key       string
+	tags      []*modelv1.Tag
+}
+
+// NewTopNRollup returns a TopNRollup. The buckets are aligned to the Unix epoch.
+func NewTopNRollup(interval time.Duration, topN int32, aggrFunc modelv1.AggregationFunction, sort modelv1.Sort) *TopNRollup {
+	return &TopNRollup{
+		buckets:  make(map[int64]map[string]*rollupEntity),
+		interval: int64(interval),
+		topN:     topN,
+		sort:     sort,
+		aggrFunc: aggrFunc,
+	}
+}
+
+// Put adds an entry to the bucket of the timestamp.
+func (r *TopNRollup) Put(timestamp time.Time, entity []*modelv1.Tag, val int64) error {
+	ts := timestamp.UnixNano()
+	bucket := ts - ts%r.interval
+	entities, ok := r.buckets[bucket]
+	if !ok {
+		entities = make(map[string]*rollupEntity)
+		r.buckets[bucket] = entities
+	}
+	values := make([]string, len(entity))
+	for i, t := range entity {
+		values[i] = t.GetValue().String()
+	}
+	key := strings.Join(values, ".")
+	item, ok := entities[key]
+	if !ok {
+		aggrFunc, err := aggregation.NewFunc[int64](r.aggrFunc)
+		if err != nil {
+			return err
+		}
+		item = &rollupEntity{int64Func: aggrFunc, key: key, tags: entity}
+		entities[key] = item
+	}
+	item.int64Func.In(val)
+	return nil
+}
+
+// Val returns a list per bucket in the order of time. The entities sharing a value are ordered by their keys.
+func (r *TopNRollup) Val() []*measurev1.TopNList {
+	buckets := make([]int64, 0, len(r.buckets))
+	for bucket := range r.buckets {
+		buckets = append(buckets, bucket)
+	}
+	slices.Sort(buckets)
+	lists := make([]*measurev1.TopNList, 0, len(buckets))
+	for _, bucket := range buckets {
+		items := make([]*rollupEntity, 0, len(r.buckets[bucket]))
+		for _, item := range r.buckets[bucket] {
+			items = append(items, item)
+		}
+		slices.SortFunc(items, func(a, b *rollupEntity) int {
+			c := cmp.Compare(a.int64Func.Val(), b.int64Func.Val())
+			if r.sort == modelv1.Sort_SORT_DESC {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+			return strings.Compare(a.key, b.key)
+		})
+		if len(items) > int(r.topN) {
+			items = items[:r.topN]
+		}
+		topNItems := make([]*measurev1.TopNList_Item, len(items))
+		for i, item := range items {
+			topNItems[i] = &measurev1.TopNList_Item{
+				Entity: item.tags,
+				Value: &modelv1.FieldValue{
+					Value: &modelv1.FieldValue_Int{
+						Int: &modelv1.Int{Value: item.int64Func.Val()},
+					},
+				},
+			}
+		}
+		lists = append(lists, &measurev1.TopNList{
+			Timestamp: timestamppb.New(time.Unix(0, bucket)),
+			Items:     topNItems,
+		})
+	}
+	return lists
+}
@@ -0,0 +1,144 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package query
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+)
+
+func TestTopNRollup(t *testing.T) {
+	const entityNum = 8
+	begin := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := rand.New(rand.NewSource(1))
+	// Every entity has an entry in each of the 60 minute buckets.
+	values := make([][]int64, entityNum)
+	for i := range values {
+		values[i] = make([]int64, 60)
+		for m := range values[i] {
+			values[i][m] = r.Int63n(1000)
+		}
+	}
+	entity := func(i int) []*modelv1.Tag {
+		return []*modelv1.Tag{{
+			Key:   "service_id",
+			Value: &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: fmt.Sprintf("svc_%d", i)}}},
+		}}
+	}
+	tests := []struct {
+		reduce   func([]int64) int64
+		name     string
+		aggrFunc modelv1.AggregationFunction
+		sort     modelv1.Sort
+	}{
+		{
+			name:     "max desc",
+			aggrFunc: modelv1.AggregationFunction_AGGREGATION_FUNCTION_MAX,
+			sort:     modelv1.Sort_SORT_DESC,
+			reduce:   slices.Max[[]int64],
+		},
+		{
+			name:     "sum desc",
+			aggrFunc: modelv1.AggregationFunction_AGGREGATION_FUNCTION_SUM,
+			sort:     modelv1.Sort_SORT_DESC,
+			reduce: func(vv []int64) (sum int64) {
+				for _, v := range vv {
+					sum += v
+				}
+				return sum
+			},
+		},
+		{
+			name:     "min asc",
+			aggrFunc: modelv1.AggregationFunction_AGGREGATION_FUNCTION_MIN,
+			sort:     modelv1.Sort_SORT_ASC,
+			reduce:   slices.Min[[]int64],
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rollup := NewTopNRollup(time.Hour, 3, tt.aggrFunc, tt.sort)
+			for m := 0; m < 60; m++ {
+				for i := range values {
+					require.NoError(t, rollup.Put(begin.Add(time.Duration(m)*time.Minute), entity(i), values[i][m]))
+				}
+			}
+			type ranked struct {
+				name  string
+				value int64
+			}
+			want := make([]ranked, entityNum)
+			for i := range values {
+				want[i] = ranked{name: fmt.Sprintf("svc_%d", i), value: tt.reduce(values[i])}
+			}
+			slices.SortStableFunc(want, func(a, b ranked) int {
+				if tt.sort == modelv1.Sort_SORT_DESC {
+					return int(b.value - a.value)
+				}
+				return int(a.value - b.value)
+			})
+
+			lists := rollup.Val()
+			require.Len(t, lists, 1)
+			require.True(t, lists[0].GetTimestamp().AsTime().Equal(begin))
+			got := make([]ranked, 0, len(lists[0].GetItems()))
+			for _, item := range lists[0].GetItems() {
+				got = append(got, ranked{name: item.GetEntity()[0].GetValue().GetStr().GetValue(), value: item.GetValue().GetInt().GetValue()})
+			}
+			require.Equal(t, want[:3], got)
+		})
+	}
+}
+
+func TestTopNRollupBuckets(t *testing.T) {
+	begin := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	svc := []*modelv1.Tag{{Key: "service_id", Value: &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "svc"}}}}}
+	rollup := NewTopNRollup(time.Hour, 1, modelv1.AggregationFunction_AGGREGATION_FUNCTION_SUM, modelv1.Sort_SORT_DESC)
+	for m := 0; m < 120; m += 30 {
+		require.NoError(t, rollup.Put(begin.Add(time.Duration(m)*time.Minute), svc, 1))
+	}
+	lists := rollup.Val()
+	require.Len(t, lists, 2)
+	for i, l := range lists {
+		require.True(t, l.GetTimestamp().AsTime().Equal(begin.Add(time.Duration(i)*time.Hour)))
+		require.Equal(t, int64(2), l.GetItems()[0].GetValue().GetInt().GetValue())
+	}
+}
+
+func TestTopNRollupFunction(t *testing.T) {
+	count := &databasev1.TopNAggregation{
+		AggregationFunctions: []modelv1.AggregationFunction{modelv1.AggregationFunction_AGGREGATION_FUNCTION_COUNT},
+	}
+	aggrFunc, err := TopNRollupFunction(&measurev1.TopNRequest{}, count)
+	require.NoError(t, err)
+	require.Equal(t, modelv1.AggregationFunction_AGGREGATION_FUNCTION_SUM, aggrFunc, "the counts are summed up")
+	aggrFunc, err = TopNRollupFunction(&measurev1.TopNRequest{Agg: modelv1.AggregationFunction_AGGREGATION_FUNCTION_MAX}, count)
+	require.NoError(t, err)
+	require.Equal(t, modelv1.AggregationFunction_AGGREGATION_FUNCTION_MAX, aggrFunc)
+	_, err = TopNRollupFunction(&measurev1.TopNRequest{}, &databasev1.TopNAggregation{})
+	require.ErrorIs(t, err, errRollupWithoutFunction)
+}
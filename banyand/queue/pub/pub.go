@@ -32,12 +32,14 @@ import (
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/anypb"
 
+	"github.com/apache/skywalking-banyandb/api/common"
 	"github.com/apache/skywalking-banyandb/api/data"
 	clusterv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/cluster/v1"
 	"github.com/apache/skywalking-banyandb/banyand/metadata"
 	"github.com/apache/skywalking-banyandb/banyand/metadata/schema"
 	"github.com/apache/skywalking-banyandb/banyand/queue"
 	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/grpchelper"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
 	"github.com/apache/skywalking-banyandb/pkg/run"
 )
@@ -96,7 +98,7 @@ func (p *pub) Broadcast(timeout time.Duration, topic bus.Topic, messages bus.Mes
 		wg.Add(1)
 		go func(n string) {
 			defer wg.Done()
-			f, err := p.publish(timeout, topic, bus.NewMessageWithNode(messages.ID(), n, messages.Data()))
+			f, err := p.publish(timeout, topic, bus.NewMessageWithNode(messages.ID(), n, messages.Data()).WithContext(messages.Context()))
 			futureCh <- publishResult{n: n, f: f, e: err}
 		}(n)
 	}
@@ -149,7 +151,7 @@ func (p *pub) publish(timeout time.Duration, topic bus.Topic, messages ...bus.Me
 		if !ok {
 			return multierr.Append(err, fmt.Errorf("failed to get client for node %s", node))
 		}
-		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		ctx, cancel := context.WithTimeout(grpchelper.ForwardAuthorization(context.Background(), m.Context()), timeout)
 		f.cancelFn = append(f.cancelFn, cancel)
 		stream, errCreateStream := client.client.Send(ctx)
 		if errCreateStream != nil {
@@ -351,6 +353,9 @@ func (l *future) Get() (bus.Message, error) {
 		return bus.Message{}, err
 	}
 	if resp.Error != "" {
+		if resp.ResourceExhausted {
+			return bus.Message{}, errors.WithMessage(common.ErrResourceExhausted, resp.Error)
+		}
 		return bus.Message{}, errors.New(resp.Error)
 	}
 	if resp.Body == nil {
@@ -73,6 +73,18 @@ func (s *mockServer) Send(stream clusterv1.Service_SendServer) error {
 			return err
 		}
 
+		if s.code == codes.ResourceExhausted {
+			// The data node stays serving, but rejects the request exceeding its limits.
+			if err := stream.Send(&clusterv1.SendResponse{
+				MessageId:         req.MessageId,
+				Error:             "mock error",
+				ResourceExhausted: true,
+			}); err != nil {
+				return err
+			}
+			continue
+		}
+
 		if s.code != codes.OK {
 			s.healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
 			return status.Error(s.code, "mock error")
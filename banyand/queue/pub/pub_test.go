@@ -28,6 +28,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"github.com/apache/skywalking-banyandb/api/common"
 	"github.com/apache/skywalking-banyandb/api/data"
 	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
 	"github.com/apache/skywalking-banyandb/pkg/bus"
@@ -172,6 +173,24 @@ var _ = ginkgo.Describe("Publish and Broadcast", func() {
 			gomega.Expect(messages).Should(gomega.HaveLen(1))
 		})
 
+		ginkgo.It("should pass the resource exhausted errors on", func() {
+			addr := getAddress()
+			closeFn := setup(addr, codes.ResourceExhausted, 0)
+			p := newPub()
+			defer func() {
+				p.GracefulStop()
+				closeFn()
+			}()
+			p.OnAddOrUpdate(getDataNode("node1", addr))
+
+			ff, err := p.Broadcast(3*time.Second, data.TopicStreamQuery, bus.NewMessage(bus.MessageID(1), &streamv1.QueryRequest{}))
+			gomega.Expect(err).ShouldNot(gomega.HaveOccurred())
+			gomega.Expect(ff).Should(gomega.HaveLen(1))
+			_, err = ff[0].GetAll()
+			gomega.Expect(err).Should(gomega.MatchError(common.ErrResourceExhausted))
+			gomega.Expect(err.Error()).Should(gomega.ContainSubstring("mock error"))
+		})
+
 		ginkgo.It("should broadcast messages to failed nodes", func() {
 			addr1 := getAddress()
 			addr2 := getAddress()
@@ -38,8 +38,9 @@ func (s *server) Send(stream clusterv1.Service_SendServer) error {
 	reply := func(writeEntity *clusterv1.SendRequest, err error, message string) {
 		s.log.Error().Stringer("written", writeEntity).Err(err).Msg(message)
 		if errResp := stream.Send(&clusterv1.SendResponse{
-			MessageId: writeEntity.MessageId,
-			Error:     message,
+			MessageId:         writeEntity.MessageId,
+			Error:             message,
+			ResourceExhausted: errors.Is(err, common.ErrResourceExhausted),
 		}); errResp != nil {
 			s.log.Err(errResp).Msg("failed to send response")
 		}
@@ -93,7 +94,7 @@ func (s *server) Send(stream clusterv1.Service_SendServer) error {
 				reply(writeEntity, errUnmarshal, "failed to unmarshal message")
 				continue
 			}
-			m = bus.NewMessage(bus.MessageID(writeEntity.MessageId), req)
+			m = bus.NewMessage(bus.MessageID(writeEntity.MessageId), req).WithContext(stream.Context())
 		} else {
 			reply(writeEntity, err, "unknown topic")
 			continue
@@ -127,7 +128,7 @@ func (s *server) Send(stream clusterv1.Service_SendServer) error {
 		case proto.Message:
 			message = d
 		case common.Error:
-			reply(writeEntity, nil, d.Msg())
+			reply(writeEntity, d.Cause(), d.Msg())
 			continue
 		default:
 			reply(writeEntity, nil, fmt.Sprintf("invalid response: %T", d))
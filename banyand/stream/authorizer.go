@@ -0,0 +1,142 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"context"
+	"crypto/subtle"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/pkg/grpchelper"
+)
+
+// ErrRestrictedTag indicates that a query filters or sorts by a restricted tag the caller isn't allowed to read.
+var ErrRestrictedTag = errors.New("the caller isn't allowed to query by the restricted tag")
+
+// Authorizer decides whether the caller of a query may read the restricted tags of a stream.
+type Authorizer interface {
+	// CanReadRestricted reports whether the caller carried by ctx may read the restricted tags of the stream.
+	CanReadRestricted(ctx context.Context, stream *commonv1.Metadata) bool
+}
+
+// AuthorizerFunc adapts a function to an Authorizer.
+type AuthorizerFunc func(ctx context.Context, stream *commonv1.Metadata) bool
+
+// CanReadRestricted calls f(ctx, stream).
+func (f AuthorizerFunc) CanReadRestricted(ctx context.Context, stream *commonv1.Metadata) bool {
+	return f(ctx, stream)
+}
+
+// tokenAuthorizer approves the callers presenting the bearer token.
+type tokenAuthorizer string
+
+func (t tokenAuthorizer) CanReadRestricted(ctx context.Context, _ *commonv1.Metadata) bool {
+	return subtle.ConstantTimeCompare([]byte(grpchelper.Authorization(ctx)), []byte("Bearer "+string(t))) == 1
+}
+
+type authorizerHolder struct {
+	Authorizer
+}
+
+// authorizerHook is shared by all the streams of a service,
+// so an authorizer registered after they are opened still applies.
+type authorizerHook struct {
+	holder atomic.Pointer[authorizerHolder]
+}
+
+func (h *authorizerHook) set(a Authorizer) {
+	h.holder.Store(&authorizerHolder{Authorizer: a})
+}
+
+// canReadRestricted denies the access if no authorizer is registered.
+func (h *authorizerHook) canReadRestricted(ctx context.Context, stream *commonv1.Metadata) bool {
+	if h == nil {
+		return false
+	}
+	holder := h.holder.Load()
+	if holder == nil || holder.Authorizer == nil {
+		return false
+	}
+	return holder.CanReadRestricted(ctx, stream)
+}
+
+// redactedTags returns the restricted tags of the stream the caller isn't allowed to read.
+func (s *stream) redactedTags(ctx context.Context) map[string]struct{} {
+	var restricted map[string]struct{}
+	for _, tf := range s.schema.GetTagFamilies() {
+		for _, ts := range tf.GetTags() {
+			if !ts.GetRestricted() {
+				continue
+			}
+			if restricted == nil {
+				restricted = make(map[string]struct{})
+			}
+			restricted[ts.GetName()] = struct{}{}
+		}
+	}
+	if restricted == nil || s.authorizer.canReadRestricted(ctx, s.schema.GetMetadata()) {
+		return nil
+	}
+	return restricted
+}
+
+// CheckRestricted rejects the query whose criteria or orders refer to the restricted tags the caller isn't allowed to read.
+// Otherwise, the matched elements would reveal the redacted values.
+func (s *stream) CheckRestricted(ctx context.Context, criteria *modelv1.Criteria, orders ...*modelv1.QueryOrder) error {
+	redacted := s.redactedTags(ctx)
+	if redacted == nil {
+		return nil
+	}
+	if name, ok := restrictedCondition(criteria, redacted); ok {
+		return errors.WithMessagef(ErrRestrictedTag, "the criteria refer to %s", name)
+	}
+	for _, o := range orders {
+		if o.GetIndexRuleName() == "" {
+			continue
+		}
+		for _, rule := range s.indexRules {
+			if rule.GetMetadata().GetName() != o.GetIndexRuleName() {
+				continue
+			}
+			for _, name := range rule.GetTags() {
+				if _, ok := redacted[name]; ok {
+					return errors.WithMessagef(ErrRestrictedTag, "the index rule %s sorts by %s", o.GetIndexRuleName(), name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func restrictedCondition(criteria *modelv1.Criteria, redacted map[string]struct{}) (string, bool) {
+	switch exp := criteria.GetExp().(type) {
+	case *modelv1.Criteria_Condition:
+		_, ok := redacted[exp.Condition.GetName()]
+		return exp.Condition.GetName(), ok
+	case *modelv1.Criteria_Le:
+		if name, ok := restrictedCondition(exp.Le.GetLeft(), redacted); ok {
+			return name, true
+		}
+		return restrictedCondition(exp.Le.GetRight(), redacted)
+	}
+	return "", false
+}
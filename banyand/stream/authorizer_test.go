@@ -0,0 +1,99 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/banyand/stream"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/test/flags"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+type callerKey struct{}
+
+var _ = Describe("Restricted tags", func() {
+	now := time.Now()
+	tr := timestamp.NewInclusiveTimeRange(now.Add(-time.Hour), now.Add(time.Hour))
+	var svcs *services
+	var deferFn func()
+
+	BeforeEach(func() {
+		svcs, deferFn = setUp()
+		waitForStream(svcs)
+		svcs.stream.SetAuthorizer(stream.AuthorizerFunc(func(ctx context.Context, _ *commonv1.Metadata) bool {
+			return ctx.Value(callerKey{}) == "admin"
+		}))
+		ctx, cancel := context.WithTimeout(context.Background(), flags.EventuallyTimeout)
+		defer cancel()
+		s, err := svcs.metadataService.StreamRegistry().GetStream(ctx, swMetadata)
+		Expect(err).ShouldNot(HaveOccurred())
+		for _, tf := range s.GetTagFamilies() {
+			for _, ts := range tf.GetTags() {
+				if ts.GetName() == "trace_id" {
+					ts.Restricted = true
+				}
+			}
+		}
+		_, err = svcs.metadataService.StreamRegistry().UpdateStream(ctx, s)
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		deferFn()
+	})
+
+	queryTraceIDs := func(ctx context.Context) []string {
+		s, err := svcs.stream.Stream(swMetadata)
+		Expect(err).ShouldNot(HaveOccurred())
+		result, err := s.Query(ctx, pbv1.StreamQueryOptions{
+			Name:          swMetadata.Name,
+			TimeRange:     &tr,
+			Entities:      [][]*modelv1.TagValue{swEntity},
+			TagProjection: []pbv1.TagProjection{{Family: "searchable", Names: []string{"trace_id", "service_id"}}},
+		})
+		Expect(err).ShouldNot(HaveOccurred())
+		if result == nil {
+			return nil
+		}
+		defer result.Release()
+		var traceIDs []string
+		for r := result.Pull(); r != nil; r = result.Pull() {
+			Expect(r.TagFamilies[0].Tags[1].Values).To(HaveLen(len(r.ElementIDs)), "the unrestricted tags are readable")
+			for _, v := range r.TagFamilies[0].Tags[0].Values {
+				traceIDs = append(traceIDs, v.GetStr().GetValue())
+			}
+		}
+		return traceIDs
+	}
+
+	It("redacts a restricted tag unless the caller is authorized", func() {
+		writeElement(svcs, newWriteRequest("restricted", now))
+		Eventually(func() []string {
+			return queryTraceIDs(context.Background())
+		}).WithTimeout(flags.EventuallyTimeout).Should(Equal([]string{""}))
+		Expect(queryTraceIDs(context.WithValue(context.Background(), callerKey{}, "admin"))).To(Equal([]string{"restricted"}))
+	})
+})
@@ -364,8 +364,66 @@ func BenchmarkSort(b *testing.B) {
 		s := generateStream(db)
 		sqo := generateStreamQueryOptions(p, idx)
 		b.Run("sort-"+p.scenario, func(b *testing.B) {
-			_, err := s.Sort(context.TODO(), sqo)
+			ssr, err := s.Sort(context.TODO(), sqo)
 			require.NoError(b, err)
+			if ssr == nil {
+				return
+			}
+			for ssr.Pull() != nil {
+			}
+			ssr.Release()
 		})
 	}
 }
+
+func BenchmarkWriteBatch(b *testing.B) {
+	const batchSize = 100
+	batch := &elements{}
+	var docs index.Documents
+	singles := make([]*elements, batchSize)
+	for i := 0; i < batchSize; i++ {
+		id := strconv.Itoa(i)
+		tagFamilies := []tagValues{{
+			tag: "singleTag", values: []*tagValue{{tag: "strTag", valueType: pbv1.ValueTypeStr, value: []byte(filterTagValuePrefix + id)}},
+		}}
+		batch.seriesIDs = append(batch.seriesIDs, 1)
+		batch.timestamps = append(batch.timestamps, int64(i+1))
+		batch.elementIDs = append(batch.elementIDs, id)
+		batch.tagFamilies = append(batch.tagFamilies, tagFamilies)
+		docs = append(docs, index.Document{DocID: uint64(i + 1)})
+		singles[i] = &elements{
+			seriesIDs:   []common.SeriesID{1},
+			timestamps:  []int64{int64(i + 1)},
+			elementIDs:  []string{id},
+			tagFamilies: [][]tagValues{tagFamilies},
+		}
+	}
+	// The parts of the writes are merged, or the single writes run out of file descriptors.
+	openTable := func(b *testing.B) *tsTable {
+		tmpPath, defFn := test.Space(require.New(b))
+		b.Cleanup(defFn)
+		tst, err := newTSTable(fs.NewLocalFileSystem(), tmpPath, common.Position{},
+			logger.GetLogger("benchmark"), timestamp.TimeRange{}, option{flushTimeout: time.Second, mergePolicy: newDefaultMergePolicyForTesting()})
+		require.NoError(b, err)
+		b.Cleanup(func() { tst.Close() })
+		return tst
+	}
+	b.Run("single", func(b *testing.B) {
+		tst := openTable(b)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			for j := range singles {
+				tst.mustAddElements(singles[j])
+				require.NoError(b, tst.Index().Write(docs[j:j+1]))
+			}
+		}
+	})
+	b.Run("batch", func(b *testing.B) {
+		tst := openTable(b)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			tst.mustAddElements(batch)
+			require.NoError(b, tst.Index().Write(docs))
+		}
+	})
+}
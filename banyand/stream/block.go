@@ -18,10 +18,12 @@
 package stream
 
 import (
+	"slices"
 	"sort"
 	"sync"
 
 	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/banyand/internal/storage"
 	"github.com/apache/skywalking-banyandb/pkg/bytes"
 	"github.com/apache/skywalking-banyandb/pkg/encoding"
 	"github.com/apache/skywalking-banyandb/pkg/fs"
@@ -72,32 +74,60 @@ func assertTimestampsSorted(timestamps []int64) {
 	}
 }
 
+// mustInitFromTags lays out the tags of the elements by their names,
+// so that the elements written under different schema revisions share a block.
+// A tag absent from an element is null for it.
 func (b *block) mustInitFromTags(tagFamilies [][]tagValues) {
 	elementsLen := len(tagFamilies)
 	if elementsLen == 0 {
 		return
 	}
 	for i, tff := range tagFamilies {
-		b.processTagFamilies(tff, i, elementsLen)
+		for j := range tff {
+			tf := b.getOrAddTagFamily(tff[j].tag, j)
+			for k, t := range tff[j].values {
+				c := tf.getOrAddTag(t.tag, k, elementsLen)
+				c.valueType = t.valueType
+				c.values[i] = t.marshal()
+			}
+		}
 	}
 }
 
-func (b *block) processTagFamilies(tff []tagValues, i int, elementsLen int) {
-	tagFamilies := b.resizeTagFamilies(len(tff))
-	for j, tf := range tff {
-		tagFamilies[j].name = tf.tag
-		b.processTags(tf, j, i, elementsLen)
+// getOrAddTagFamily returns the tag family of the name. The hint is the position the family is expected at.
+func (b *block) getOrAddTagFamily(name string, hint int) *tagFamily {
+	if hint < len(b.tagFamilies) && b.tagFamilies[hint].name == name {
+		return &b.tagFamilies[hint]
+	}
+	for i := range b.tagFamilies {
+		if b.tagFamilies[i].name == name {
+			return &b.tagFamilies[i]
+		}
 	}
+	tff := b.resizeTagFamilies(len(b.tagFamilies) + 1)
+	tf := &tff[len(tff)-1]
+	tf.reset()
+	tf.name = name
+	return tf
 }
 
-func (b *block) processTags(tf tagValues, tagFamilyIdx, i int, elementsLen int) {
-	tags := b.tagFamilies[tagFamilyIdx].resizeTags(len(tf.values))
-	for j, t := range tf.values {
-		tags[j].name = t.tag
-		tags[j].resizeValues(elementsLen)
-		tags[j].valueType = t.valueType
-		tags[j].values[i] = t.marshal()
+// getOrAddTag returns the tag of the name with the values of all the elements.
+// The hint is the position the tag is expected at.
+func (tf *tagFamily) getOrAddTag(name string, hint, elementsLen int) *tag {
+	if hint < len(tf.tags) && tf.tags[hint].name == name {
+		return &tf.tags[hint]
 	}
+	for i := range tf.tags {
+		if tf.tags[i].name == name {
+			return &tf.tags[i]
+		}
+	}
+	tags := tf.resizeTags(len(tf.tags) + 1)
+	t := &tags[len(tags)-1]
+	t.reset()
+	t.name = name
+	t.resizeValues(elementsLen)
+	return t
 }
 
 func (b *block) resizeTagFamilies(tagFamiliesLen int) []tagFamily {
@@ -123,7 +153,7 @@ func (b *block) mustWriteTo(sid common.SeriesID, bm *blockMetadata, ww *writers)
 	bm.count = uint64(b.Len())
 
 	mustWriteTimestampsTo(&bm.timestamps, b.timestamps, &ww.timestampsWriter)
-	mustWriteElementIDsTo(&bm.elementIDs, b.elementIDs, &ww.elementIDsWriter)
+	mustWriteElementIDsTo(&bm.elementIDs, b.elementIDs, &ww.elementIDsWriter, ww.compressThreshold)
 
 	for ti := range b.tagFamilies {
 		b.marshalTagFamily(b.tagFamilies[ti], bm, ww)
@@ -159,7 +189,7 @@ func (b *block) marshalTagFamily(tf tagFamily, bm *blockMetadata, ww *writers) {
 	cfm := generateTagFamilyMetadata()
 	cmm := cfm.resizeTagMetadata(len(cc))
 	for i := range cc {
-		cc[i].mustWriteTo(&cmm[i], w)
+		cc[i].mustWriteTo(&cmm[i], w, ww.compressThreshold)
 	}
 	bb := bigValuePool.Generate()
 	defer bigValuePool.Release(bb)
@@ -174,11 +204,12 @@ func (b *block) marshalTagFamily(tf tagFamily, bm *blockMetadata, ww *writers) {
 	hw.MustWrite(bb.Buf)
 }
 
+// unmarshalTagFamily returns the number of bytes it reads.
 func (b *block) unmarshalTagFamily(decoder *encoding.BytesBlockDecoder, tfIndex int, name string,
 	tagFamilyMetadataBlock *dataBlock, tagProjection []string, metaReader, valueReader fs.Reader,
-) {
+) uint64 {
 	if len(tagProjection) < 1 {
-		return
+		return 0
 	}
 	bb := bigValuePool.Generate()
 	bb.Buf = bytes.ResizeExact(bb.Buf, int(tagFamilyMetadataBlock.size))
@@ -192,14 +223,17 @@ func (b *block) unmarshalTagFamily(decoder *encoding.BytesBlockDecoder, tfIndex
 	bigValuePool.Release(bb)
 	b.tagFamilies[tfIndex].name = name
 	cc := b.tagFamilies[tfIndex].resizeTags(len(tagProjection))
+	n := tagFamilyMetadataBlock.size
 	for j := range tagProjection {
 		for i := range tfm.tagMetadata {
 			if tagProjection[j] == tfm.tagMetadata[i].name {
 				cc[j].mustReadValues(decoder, valueReader, tfm.tagMetadata[i], uint64(b.Len()))
+				n += tfm.tagMetadata[i].size
 				break
 			}
 		}
 	}
+	return n
 }
 
 func (b *block) unmarshalTagFamilyFromSeqReaders(decoder *encoding.BytesBlockDecoder, tfIndex int, name string,
@@ -248,22 +282,34 @@ func (b *block) uncompressedSizeBytes() uint64 {
 }
 
 func (b *block) mustReadFrom(decoder *encoding.BytesBlockDecoder, p *part, bm blockMetadata) {
+	b.mustReadElementsFrom(p, bm)
+	b.mustReadTagFamiliesFrom(decoder, p, bm)
+}
+
+// mustReadElementsFrom resets the block and reads the timestamps and element IDs only.
+func (b *block) mustReadElementsFrom(p *part, bm blockMetadata) {
 	b.reset()
 
 	b.timestamps = mustReadTimestampsFrom(b.timestamps, &bm.timestamps, int(bm.count), p.timestamps)
 	b.elementIDs = mustReadElementIDsFrom(b.elementIDs, &bm.elementIDs, int(bm.count), p.elementIDs)
+}
 
+// mustReadTagFamiliesFrom reads the projected tag families after mustReadElementsFrom.
+// It returns the number of bytes it reads.
+func (b *block) mustReadTagFamiliesFrom(decoder *encoding.BytesBlockDecoder, p *part, bm blockMetadata) uint64 {
 	_ = b.resizeTagFamilies(len(bm.tagProjection))
+	var n uint64
 	for i := range bm.tagProjection {
 		name := bm.tagProjection[i].Family
 		block, ok := bm.tagFamilies[name]
 		if !ok {
 			continue
 		}
-		b.unmarshalTagFamily(decoder, i, name, block,
+		n += b.unmarshalTagFamily(decoder, i, name, block,
 			bm.tagProjection[i].Names, p.tagFamilyMetadata[name],
 			p.tagFamilies[name])
 	}
+	return n
 }
 
 func (b *block) mustSeqReadFrom(decoder *encoding.BytesBlockDecoder, seqReaders *seqReaders, bm blockMetadata) {
@@ -320,7 +366,7 @@ func mustReadTimestampsFrom(dst []int64, tm *timestampsMetadata, count int, read
 	return dst
 }
 
-func mustWriteElementIDsTo(em *elementIDsMetadata, elementIDs []string, elementIDsWriter *writer) {
+func mustWriteElementIDsTo(em *elementIDsMetadata, elementIDs []string, elementIDsWriter *writer, compressThreshold int) {
 	em.reset()
 
 	bb := bigValuePool.Generate()
@@ -329,7 +375,7 @@ func mustWriteElementIDsTo(em *elementIDsMetadata, elementIDs []string, elementI
 	for i, elementID := range elementIDs {
 		elementIDsByteSlice[i] = []byte(elementID)
 	}
-	bb.Buf = encoding.EncodeBytesBlock(bb.Buf, elementIDsByteSlice)
+	bb.Buf = encoding.EncodeBytesBlockWithThreshold(bb.Buf, elementIDsByteSlice, compressThreshold)
 	if len(bb.Buf) > maxElementIDsBlockSize {
 		logger.Panicf("too big block with elementIDs: %d bytes; the maximum supported size is %d bytes", len(bb.Buf), maxElementIDsBlockSize)
 	}
@@ -414,11 +460,16 @@ type blockCursor struct {
 	elementIDs         []string
 	tagFamilies        []tagFamily
 	tagValuesDecoder   encoding.BytesBlockDecoder
+	elementIDRange     *pbv1.ElementIDRange
+	redactedTags       map[string]struct{}
+	schema             *schemaResolver
+	account            *storage.ReadAccount
 	tagProjection      []pbv1.TagProjection
 	bm                 blockMetadata
 	idx                int
 	minTimestamp       int64
 	maxTimestamp       int64
+	sampleInterval     uint32
 }
 
 func (bc *blockCursor) reset() {
@@ -427,6 +478,11 @@ func (bc *blockCursor) reset() {
 	bc.bm.reset()
 	bc.minTimestamp = 0
 	bc.maxTimestamp = 0
+	bc.elementIDRange = nil
+	bc.redactedTags = nil
+	bc.schema = nil
+	bc.account = nil
+	bc.sampleInterval = 0
 	bc.tagProjection = bc.tagProjection[:0]
 
 	bc.timestamps = bc.timestamps[:0]
@@ -446,6 +502,11 @@ func (bc *blockCursor) init(p *part, bm *blockMetadata, opts queryOptions) {
 	bc.minTimestamp = opts.minTimestamp
 	bc.maxTimestamp = opts.maxTimestamp
 	bc.tagProjection = opts.TagProjection
+	bc.elementIDRange = opts.ElementIDRange
+	bc.sampleInterval = opts.SampleInterval
+	bc.redactedTags = opts.redactedTags
+	bc.schema = opts.schema
+	bc.account = opts.account
 	if opts.elementRefMap != nil {
 		seriesID := bc.bm.seriesID
 		bc.expectedTimestamps = opts.elementRefMap[seriesID]
@@ -531,6 +592,34 @@ func (bc *blockCursor) copyTo(r *pbv1.StreamResult) {
 	}
 }
 
+// appendTo appends n elements to the batch from the current one, moving by step.
+// The tag values are appended in their storage encoding without building a TagValue per value.
+func (bc *blockCursor) appendTo(b *pbv1.StreamBatch, n, step int) {
+	if len(b.TagFamilies) != len(bc.tagProjection) {
+		for _, tp := range bc.tagProjection {
+			cf := pbv1.ColumnFamily{Name: tp.Family, Columns: make([]pbv1.Column, len(tp.Names))}
+			for i, name := range tp.Names {
+				cf.Columns[i].Name = name
+			}
+			b.TagFamilies = append(b.TagFamilies, cf)
+		}
+	}
+	for k, idx := 0, bc.idx; k < n; k, idx = k+1, idx+step {
+		b.SIDs = append(b.SIDs, bc.bm.seriesID)
+		b.Timestamps = append(b.Timestamps, bc.timestamps[idx])
+		b.ElementIDs = append(b.ElementIDs, bc.elementIDs[idx])
+		for i, cf := range bc.tagFamilies {
+			for j, c := range cf.tags {
+				var v []byte
+				if c.values != nil {
+					v = c.values[idx]
+				}
+				b.TagFamilies[i].Columns[j].Append(c.valueType, v)
+			}
+		}
+	}
+}
+
 func (bc *blockCursor) loadData(tmpBlock *block) bool {
 	tmpBlock.reset()
 	bc.bm.tagProjection = bc.tagProjection
@@ -545,15 +634,31 @@ func (bc *blockCursor) loadData(tmpBlock *block) bool {
 			}
 		}
 	}
+	// The revisions stamped on the elements are read along with the projected tags.
+	revisionBlock, stamped := bc.bm.tagFamilies[schemaRevisionTagFamily]
+	if stamped = stamped && bc.schema != nil && len(bc.tagProjection) > 0; stamped {
+		if tf == nil {
+			tf = make(map[string]*dataBlock, 1)
+		}
+		tf[schemaRevisionTagFamily] = revisionBlock
+		bc.bm.tagProjection = append(slices.Clip(bc.tagProjection), schemaRevisionProjection)
+	}
 	bc.bm.tagFamilies = tf
-	tmpBlock.mustReadFrom(&bc.tagValuesDecoder, bc.p, bc.bm)
+	// A cursor exceeding the account's limit is dropped. The query reports the account's error once it completes.
+	if bc.account.Charge(bc.bm.timestamps.size+bc.bm.elementIDs.size) != nil {
+		return false
+	}
+	tmpBlock.mustReadElementsFrom(bc.p, bc.bm)
 
 	idxList := make([]int, 0)
 	var start, end int
+	// Element IDs aren't sorted within a block, so the ID range and the sampling are applied
+	// as a scan filter over the selected timestamps.
+	useIdxList := bc.expectedTimestamps != nil || bc.elementIDRange != nil || bc.sampleInterval > 1
 	if bc.expectedTimestamps != nil {
 		for _, ts := range bc.expectedTimestamps {
 			idx := timestamp.Find(tmpBlock.timestamps, ts)
-			if idx == -1 {
+			if idx == -1 || !bc.containsElementID(tmpBlock.elementIDs[idx]) {
 				continue
 			}
 			idxList = append(idxList, idx)
@@ -569,11 +674,42 @@ func (bc *blockCursor) loadData(tmpBlock *block) bool {
 		if !ok {
 			return false
 		}
-		bc.timestamps = append(bc.timestamps, tmpBlock.timestamps[s:e+1]...)
-		bc.elementIDs = append(bc.elementIDs, tmpBlock.elementIDs[s:e+1]...)
+		if !useIdxList {
+			bc.timestamps = append(bc.timestamps, tmpBlock.timestamps[s:e+1]...)
+			bc.elementIDs = append(bc.elementIDs, tmpBlock.elementIDs[s:e+1]...)
+		} else {
+			for idx := s; idx <= e; idx++ {
+				if !bc.containsElementID(tmpBlock.elementIDs[idx]) {
+					continue
+				}
+				idxList = append(idxList, idx)
+				bc.timestamps = append(bc.timestamps, tmpBlock.timestamps[idx])
+				bc.elementIDs = append(bc.elementIDs, tmpBlock.elementIDs[idx])
+			}
+			if len(bc.timestamps) == 0 {
+				return false
+			}
+		}
+	}
+	// Tags are only decoded for the blocks that still have elements selected.
+	if bc.account.Charge(tmpBlock.mustReadTagFamiliesFrom(&bc.tagValuesDecoder, bc.p, bc.bm)) != nil {
+		return false
 	}
 
-	for i, projection := range bc.bm.tagProjection {
+	var revisions []int64
+	if stamped {
+		if rt := &tmpBlock.tagFamilies[len(bc.tagProjection)].tags[0]; rt.name == schemaRevisionTag {
+			all := decodeSchemaRevisions(rt)
+			if useIdxList {
+				for _, idx := range idxList {
+					revisions = append(revisions, all[idx])
+				}
+			} else {
+				revisions = all[start : end+1]
+			}
+		}
+	}
+	for i, projection := range bc.tagProjection {
 		tf := tagFamily{
 			name: projection.Family,
 		}
@@ -582,19 +718,22 @@ func (bc *blockCursor) loadData(tmpBlock *block) bool {
 			t := tag{
 				name: name,
 			}
-			if len(tmpBlock.tagFamilies[i].tags) != 0 && tmpBlock.tagFamilies[i].tags[blockIndex].name == name {
+			// The values of a redacted tag are left empty, and they are read as nulls.
+			_, redacted := bc.redactedTags[name]
+			if !redacted && len(tmpBlock.tagFamilies[i].tags) != 0 && tmpBlock.tagFamilies[i].tags[blockIndex].name == name {
 				t.valueType = tmpBlock.tagFamilies[i].tags[blockIndex].valueType
 				if len(tmpBlock.tagFamilies[i].tags[blockIndex].values) != len(tmpBlock.timestamps) {
 					logger.Panicf("unexpected number of values for tags %q: got %d; want %d",
 						tmpBlock.tagFamilies[i].tags[blockIndex].name, len(tmpBlock.tagFamilies[i].tags[blockIndex].values), len(tmpBlock.timestamps))
 				}
-				if bc.expectedTimestamps != nil {
+				if useIdxList {
 					for _, idx := range idxList {
 						t.values = append(t.values, tmpBlock.tagFamilies[i].tags[blockIndex].values[idx])
 					}
 				} else {
 					t.values = append(t.values, tmpBlock.tagFamilies[i].tags[blockIndex].values[start:end+1]...)
 				}
+				bc.schema.resolve(&t, revisions)
 			}
 			blockIndex++
 			tf.tags = append(tf.tags, t)
@@ -604,6 +743,10 @@ func (bc *blockCursor) loadData(tmpBlock *block) bool {
 	return true
 }
 
+func (bc *blockCursor) containsElementID(id string) bool {
+	return (bc.elementIDRange == nil || bc.elementIDRange.Contains(id)) && pbv1.Sampled(id, bc.sampleInterval)
+}
+
 var blockCursorPool sync.Pool
 
 func generateBlockCursor() *blockCursor {
@@ -648,47 +791,51 @@ func (bi *blockPointer) appendAll(b *blockPointer) {
 	bi.append(b, len(b.timestamps))
 }
 
+// append appends the elements of b in [b.idx, offset).
+// The tags are matched by their names, and the ones absent from either block are null for its elements.
+// A tag keeps the value type of bi, the elements are read with the type of the revision they're written under.
 func (bi *blockPointer) append(b *blockPointer, offset int) {
 	if offset <= b.idx {
 		return
 	}
-	if len(bi.tagFamilies) == 0 && len(b.tagFamilies) > 0 {
-		for _, tf := range b.tagFamilies {
-			tFamily := tagFamily{name: tf.name}
-			for _, c := range tf.tags {
-				col := tag{name: c.name, valueType: c.valueType}
-				assertIdxAndOffset(col.name, len(c.values), b.idx, offset)
-				col.values = append(col.values, c.values[b.idx:offset]...)
-				tFamily.tags = append(tFamily.tags, col)
-			}
-			bi.tagFamilies = append(bi.tagFamilies, tFamily)
-		}
-	} else {
-		if len(bi.tagFamilies) != len(b.tagFamilies) {
-			logger.Panicf("unexpected number of tag families: got %d; want %d", len(bi.tagFamilies), len(b.tagFamilies))
+	assertIdxAndOffset("timestamps", len(b.timestamps), b.idx, offset)
+	existing, n := len(bi.timestamps), offset-b.idx
+	for i, tf := range b.tagFamilies {
+		tFamily := bi.getOrAddTagFamily(tf.name, i)
+		for j, c := range tf.tags {
+			assertIdxAndOffset(c.name, len(c.values), b.idx, offset)
+			col := tFamily.getOrAppendTag(c.name, j, c.valueType, existing)
+			col.values = append(col.values, c.values[b.idx:offset]...)
 		}
-		for i := range bi.tagFamilies {
-			if bi.tagFamilies[i].name != b.tagFamilies[i].name {
-				logger.Panicf("unexpected tag family name: got %q; want %q", bi.tagFamilies[i].name, b.tagFamilies[i].name)
-			}
-			if len(bi.tagFamilies[i].tags) != len(b.tagFamilies[i].tags) {
-				logger.Panicf("unexpected number of tags for tag family %q: got %d; want %d", bi.tagFamilies[i].name, len(bi.tagFamilies[i].tags), len(b.tagFamilies[i].tags))
-			}
-			for j := range bi.tagFamilies[i].tags {
-				if bi.tagFamilies[i].tags[j].name != b.tagFamilies[i].tags[j].name {
-					logger.Panicf("unexpected tag name for tag family %q: got %q; want %q", bi.tagFamilies[i].name, bi.tagFamilies[i].tags[j].name, b.tagFamilies[i].tags[j].name)
-				}
-				assertIdxAndOffset(b.tagFamilies[i].tags[j].name, len(b.tagFamilies[i].tags[j].values), b.idx, offset)
-				bi.tagFamilies[i].tags[j].values = append(bi.tagFamilies[i].tags[j].values, b.tagFamilies[i].tags[j].values[b.idx:offset]...)
+	}
+	for i := range bi.tagFamilies {
+		for j := range bi.tagFamilies[i].tags {
+			col := &bi.tagFamilies[i].tags[j]
+			for len(col.values) < existing+n {
+				col.values = append(col.values, nil)
 			}
 		}
 	}
 
-	assertIdxAndOffset("timestamps", len(b.timestamps), bi.idx, offset)
 	bi.timestamps = append(bi.timestamps, b.timestamps[b.idx:offset]...)
 	bi.elementIDs = append(bi.elementIDs, b.elementIDs[b.idx:offset]...)
 }
 
+// getOrAppendTag returns the tag of the name, a new tag is null for the existing elements.
+// The hint is the position the tag is expected at.
+func (tf *tagFamily) getOrAppendTag(name string, hint int, valueType pbv1.ValueType, existing int) *tag {
+	if hint < len(tf.tags) && tf.tags[hint].name == name {
+		return &tf.tags[hint]
+	}
+	for i := range tf.tags {
+		if tf.tags[i].name == name {
+			return &tf.tags[i]
+		}
+	}
+	tf.tags = append(tf.tags, tag{name: name, valueType: valueType, values: make([][]byte, existing)})
+	return &tf.tags[len(tf.tags)-1]
+}
+
 func assertIdxAndOffset(name string, length int, idx int, offset int) {
 	if idx >= offset {
 		logger.Panicf("%q idx %d must be less than offset %d", name, idx, offset)
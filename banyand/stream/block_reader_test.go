@@ -127,7 +127,7 @@ func Test_blockReader_nextBlock(t *testing.T) {
 				for _, es := range tt.esList {
 					mp := generateMemPart()
 					mpp = append(mpp, mp)
-					mp.mustInitFromElements(es)
+					mp.mustInitFromElements(es, 0)
 					pp = append(pp, openMemPart(mp))
 				}
 				verify(pp)
@@ -151,7 +151,7 @@ func Test_blockReader_nextBlock(t *testing.T) {
 				for i, es := range tt.esList {
 					mp := generateMemPart()
 					mpp = append(mpp, mp)
-					mp.mustInitFromElements(es)
+					mp.mustInitFromElements(es, 0)
 					mp.mustFlush(fileSystem, partPath(tmpPath, uint64(i)))
 					filePW := newPartWrapper(nil, mustOpenFilePart(uint64(i), tmpPath, fileSystem))
 					filePW.p.partMetadata.ID = uint64(i)
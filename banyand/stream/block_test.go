@@ -276,7 +276,7 @@ func Test_mustWriteAndReadElementIDs(t *testing.T) {
 			b := &bytes.Buffer{}
 			w := new(writer)
 			w.init(b)
-			mustWriteElementIDsTo(em, tt.args, w)
+			mustWriteElementIDsTo(em, tt.args, w, 0)
 			elementIDs := mustReadElementIDsFrom(nil, em, len(tt.args), b)
 			if !reflect.DeepEqual(elementIDs, tt.args) {
 				t.Errorf("mustReadElementIDsFrom() = %v, want %v", elementIDs, tt.args)
@@ -659,6 +659,60 @@ func Test_blockPointer_append(t *testing.T) {
 				idx: 0,
 			},
 		},
+		{
+			name: "Test append with tags of different schema revisions",
+			fields: fields{
+				timestamps: []int64{1, 2},
+				elementIDs: []string{"0", "1"},
+				tagFamilies: []tagFamily{
+					{
+						name: "searchable",
+						tags: []tag{{name: "strTag", valueType: pbv1.ValueTypeStr, values: [][]byte{[]byte("a"), []byte("b")}}},
+					},
+					{
+						name: "removed",
+						tags: []tag{{name: "intTag", valueType: pbv1.ValueTypeInt64, values: [][]byte{convert.Int64ToBytes(1), convert.Int64ToBytes(2)}}},
+					},
+				},
+			},
+			args: args{
+				b: &blockPointer{
+					block: block{
+						timestamps: []int64{4},
+						elementIDs: []string{"3"},
+						tagFamilies: []tagFamily{
+							{
+								name: "searchable",
+								tags: []tag{
+									{name: "addedTag", valueType: pbv1.ValueTypeStr, values: [][]byte{[]byte("added")}},
+									{name: "strTag", valueType: pbv1.ValueTypeStr, values: [][]byte{[]byte("c")}},
+								},
+							},
+						},
+					},
+				},
+				offset: 1,
+			},
+			want: &blockPointer{
+				block: block{
+					timestamps: []int64{1, 2, 4},
+					elementIDs: []string{"0", "1", "3"},
+					tagFamilies: []tagFamily{
+						{
+							name: "searchable",
+							tags: []tag{
+								{name: "strTag", valueType: pbv1.ValueTypeStr, values: [][]byte{[]byte("a"), []byte("b"), []byte("c")}},
+								{name: "addedTag", valueType: pbv1.ValueTypeStr, values: [][]byte{nil, nil, []byte("added")}},
+							},
+						},
+						{
+							name: "removed",
+							tags: []tag{{name: "intTag", valueType: pbv1.ValueTypeInt64, values: [][]byte{convert.Int64ToBytes(1), convert.Int64ToBytes(2), nil}}},
+						},
+					},
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -66,10 +66,12 @@ type writers struct {
 	tagFamilyWriters           map[string]*writer
 	timestampsWriter           writer
 	elementIDsWriter           writer
+	compressThreshold          int
 }
 
 func (sw *writers) reset() {
 	sw.mustCreateTagFamilyWriters = nil
+	sw.compressThreshold = 0
 	sw.metaWriter.reset()
 	sw.primaryWriter.reset()
 	sw.timestampsWriter.reset()
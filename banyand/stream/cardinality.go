@@ -0,0 +1,113 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"context"
+
+	"github.com/axiomhq/hyperloglog"
+	"github.com/pkg/errors"
+
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	"github.com/apache/skywalking-banyandb/banyand/internal/storage"
+	"github.com/apache/skywalking-banyandb/pkg/index"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+var errTagNotIndexed = errors.New("the tag isn't indexed by a keyword inverted index")
+
+// termCounter counts the distinct terms inserted into it.
+// An exact counter keeps every term, while an approximate one keeps a HyperLogLog sketch,
+// whose size is fixed and whose standard error is about 1%.
+type termCounter struct {
+	terms  map[string]struct{}
+	sketch *hyperloglog.Sketch
+}
+
+func newTermCounter(approximate bool) *termCounter {
+	if approximate {
+		return &termCounter{sketch: hyperloglog.New()}
+	}
+	return &termCounter{terms: make(map[string]struct{})}
+}
+
+func (tc *termCounter) insert(term []byte) {
+	if tc.sketch != nil {
+		tc.sketch.Insert(term)
+		return
+	}
+	if _, ok := tc.terms[string(term)]; !ok {
+		tc.terms[string(term)] = struct{}{}
+	}
+}
+
+func (tc *termCounter) count() uint64 {
+	if tc.sketch != nil {
+		return tc.sketch.Estimate()
+	}
+	return uint64(len(tc.terms))
+}
+
+// TagCardinality returns the number of the distinct values of an indexed tag within the time range.
+// The values are read from the term dictionaries of the element indices, so the time range is widened
+// to the segments it overlaps, and the values are counted over all the series of the stream.
+// An approximate count bounds the memory for a tag of very high cardinality.
+func (s *stream) TagCardinality(ctx context.Context, tagName string, timeRange timestamp.TimeRange, approximate bool) (uint64, error) {
+	rule := s.tagIndexRule(tagName)
+	if rule == nil {
+		return 0, errors.WithMessagef(errTagNotIndexed, "tag %s", tagName)
+	}
+	_, done, err := s.drainer.begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+	db := s.databaseSupplier.SupplyTSDB()
+	if db == nil {
+		return 0, nil
+	}
+	tabWrappers := db.(storage.TSDB[*tsTable, option]).SelectTSTables(timeRange)
+	defer func() {
+		for i := range tabWrappers {
+			tabWrappers[i].DecRef()
+		}
+	}()
+	tc := newTermCounter(approximate)
+	for i := range tabWrappers {
+		if err = tabWrappers[i].Table().Index().visitTerms(rule.GetMetadata().GetId(), tc.insert); err != nil {
+			return 0, err
+		}
+	}
+	return tc.count(), nil
+}
+
+// tagIndexRule returns the inverted index rule of the tag without an analyzer.
+// The entity tags are left out, they're only indexed by the series index.
+func (s *stream) tagIndexRule(tagName string) *databasev1.IndexRule {
+	for _, rules := range s.indexRuleLocators.TagFamilyTRule {
+		if r, ok := rules[tagName]; ok && r.GetType() == databasev1.IndexRule_TYPE_INVERTED &&
+			r.GetAnalyzer() == databasev1.IndexRule_ANALYZER_UNSPECIFIED {
+			return r
+		}
+	}
+	return nil
+}
+
+func (e *elementIndex) visitTerms(indexRuleID uint32, fn func(term []byte)) error {
+	return e.store.VisitTerms(index.FieldKey{IndexRuleID: indexRuleID}, fn)
+}
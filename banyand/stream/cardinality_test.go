@@ -0,0 +1,68 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/pkg/index"
+)
+
+func TestTagCardinality(t *testing.T) {
+	const endpointRule, statusRule = 10, 11
+	// Two segments share the endpoints from 1000 to 1499, every endpoint is written by two series.
+	segments := [][2]int{{0, 1500}, {1000, 2500}}
+	var indices []*elementIndex
+	for _, endpoints := range segments {
+		tst := openTestTable(t)
+		var docs index.Documents
+		docID := uint64(1)
+		for i := endpoints[0]; i < endpoints[1]; i++ {
+			for _, sid := range []common.SeriesID{1, 2} {
+				docs = append(docs, index.Document{
+					DocID: docID,
+					Fields: []index.Field{
+						{Key: index.FieldKey{IndexRuleID: endpointRule, SeriesID: sid}, Term: []byte(fmt.Sprintf("/api/endpoint/%d", i))},
+						{Key: index.FieldKey{IndexRuleID: statusRule, SeriesID: sid}, Term: []byte(fmt.Sprintf("%d", 200+i%3*100))},
+					},
+				})
+				docID++
+			}
+		}
+		require.NoError(t, tst.Index().Write(docs))
+		indices = append(indices, tst.Index())
+	}
+	count := func(indexRuleID uint32, approximate bool) uint64 {
+		tc := newTermCounter(approximate)
+		for _, ei := range indices {
+			require.NoError(t, ei.visitTerms(indexRuleID, tc.insert))
+		}
+		return tc.count()
+	}
+
+	assert.Equal(t, uint64(2500), count(endpointRule, false))
+	assert.Equal(t, uint64(3), count(statusRule, false))
+	assert.InEpsilon(t, 2500, count(endpointRule, true), 0.03, "the estimate is within the error bound")
+	assert.Equal(t, uint64(3), count(statusRule, true), "a sketch counts a few terms exactly")
+	assert.Equal(t, uint64(0), count(12, false), "a rule without any term")
+}
@@ -0,0 +1,181 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/banyand/internal/storage"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+)
+
+// Count returns the number of the elements matching the query for each series, without decoding their tags.
+// Series without matching elements are left out. The tag projection, the order and the limit of the query are ignored.
+func (s *stream) Count(ctx context.Context, sqo pbv1.StreamQueryOptions) ([]pbv1.SeriesCount, error) {
+	if sqo.TimeRange == nil || len(sqo.Entities) < 1 {
+		return nil, errors.New("invalid count options: timeRange and series are required")
+	}
+	ctx, done, err := s.drainer.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	if err = s.waitForFreshness(ctx, sqo); err != nil {
+		return nil, err
+	}
+	db := s.databaseSupplier.SupplyTSDB()
+	if db == nil {
+		return nil, nil
+	}
+	tsdb := db.(storage.TSDB[*tsTable, option])
+	tabWrappers, err := selectTSTables(tsdb, sqo)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for i := range tabWrappers {
+			tabWrappers[i].DecRef()
+		}
+	}()
+	series := make([]*pbv1.Series, len(sqo.Entities))
+	for i := range sqo.Entities {
+		series[i] = &pbv1.Series{
+			Subject:      sqo.Name,
+			EntityValues: sqo.Entities[i],
+		}
+	}
+	sl, err := tsdb.Lookup(ctx, series)
+	if err != nil {
+		return nil, err
+	}
+	if len(sl) < 1 {
+		return nil, nil
+	}
+	minTimestamp, maxTimestamp := sqo.TimeRange.UnixNanoBounds()
+	qo := queryOptions{
+		StreamQueryOptions: sqo,
+		minTimestamp:       minTimestamp,
+		maxTimestamp:       maxTimestamp,
+		account:            storage.ReadAccountFrom(ctx),
+	}
+	// The elements matching the filter are looked up in the index, then checked against the blocks as a query does.
+	if sqo.Filter != nil {
+		refLists, errSearch := searchIndexes(ctx, tableIndexes(tabWrappers), sl, sqo.Filter, sqo.TimeRange, s.queryParallelism)
+		if errSearch != nil {
+			return nil, errSearch
+		}
+		qo.elementRefMap = make(map[common.SeriesID][]int64)
+		for _, erl := range refLists {
+			for _, ref := range erl {
+				qo.elementRefMap[ref.seriesID] = append(qo.elementRefMap[ref.seriesID], ref.timestamp)
+			}
+		}
+		if len(qo.elementRefMap) == 0 {
+			return nil, nil
+		}
+	}
+	var parts []*part
+	var snapshots []*snapshot
+	defer func() {
+		for i := range snapshots {
+			snapshots[i].decRef()
+		}
+	}()
+	var n int
+	for i := range tabWrappers {
+		snp := tabWrappers[i].Table().currentSnapshot()
+		if snp == nil {
+			continue
+		}
+		parts, n = snp.selectParts(parts, qo.minTimestamp, qo.maxTimestamp, qo.FlushedOnly)
+		if n < 1 {
+			snp.decRef()
+			continue
+		}
+		snapshots = append(snapshots, snp)
+	}
+	counts, err := countElements(parts, sl.IDs(), qo)
+	if err != nil {
+		return nil, err
+	}
+	var result []pbv1.SeriesCount
+	for i := range sl {
+		c, ok := counts[sl[i].ID]
+		if !ok {
+			continue
+		}
+		result = append(result, pbv1.SeriesCount{
+			Series: sl[i],
+			Count:  c,
+		})
+	}
+	return result, nil
+}
+
+// countElements counts the elements of the series matching the options.
+// A block lying in the time range is counted from its metadata unless its elements are filtered by their IDs.
+// The other blocks only decode their timestamps and element IDs.
+func countElements(parts []*part, sids []common.SeriesID, qo queryOptions) (map[common.SeriesID]int64, error) {
+	// As a query does, only the series with elements found in the index are read.
+	var filtered []common.SeriesID
+	for _, sid := range sids {
+		if _, ok := qo.elementRefMap[sid]; qo.elementRefMap == nil || ok {
+			filtered = append(filtered, sid)
+		}
+	}
+	sids = filtered
+	sort.Slice(sids, func(i, j int) bool { return sids[i] < sids[j] })
+	bma := generateBlockMetadataArray()
+	defer releaseBlockMetadataArray(bma)
+	var ti tstIter
+	defer ti.reset()
+	ti.account = qo.account
+	ti.init(bma, parts, sids, qo.minTimestamp, qo.maxTimestamp)
+	if ti.Error() != nil {
+		return nil, fmt.Errorf("cannot init tstIter: %w", ti.Error())
+	}
+	// The tags aren't read.
+	qo.TagProjection = nil
+	scanned := qo.elementRefMap != nil || qo.ElementIDRange != nil || qo.SampleInterval > 1
+	counts := make(map[common.SeriesID]int64)
+	tmpBlock := generateBlock()
+	defer releaseBlock(tmpBlock)
+	bc := generateBlockCursor()
+	defer releaseBlockCursor(bc)
+	for ti.nextBlock() {
+		pi := ti.piHeap[0]
+		bm := pi.curBlock
+		if !scanned && bm.timestamps.min >= qo.minTimestamp && bm.timestamps.max <= qo.maxTimestamp {
+			counts[bm.seriesID] += int64(bm.count)
+			continue
+		}
+		bc.init(pi.p, bm, qo)
+		if bc.loadData(tmpBlock) {
+			counts[bm.seriesID] += int64(len(bc.timestamps))
+		}
+	}
+	if ti.Error() != nil {
+		return nil, fmt.Errorf("cannot iterate tstIter: %w", ti.Error())
+	}
+	return counts, nil
+}
@@ -0,0 +1,141 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/pkg/convert"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+)
+
+// pulledCounts counts the elements returned by the full query path.
+func pulledCounts(t *testing.T, s *snapshot, sids []common.SeriesID, qo queryOptions) map[common.SeriesID]int64 {
+	parts, _ := s.getParts(nil, qo.minTimestamp, qo.maxTimestamp)
+	if qo.elementRefMap != nil {
+		sids = sids[:0:0]
+		for sid := range qo.elementRefMap {
+			sids = append(sids, sid)
+		}
+		slices.Sort(sids)
+	}
+	bma := generateBlockMetadataArray()
+	defer releaseBlockMetadataArray(bma)
+	var ti tstIter
+	defer ti.reset()
+	ti.init(bma, parts, sids, qo.minTimestamp, qo.maxTimestamp)
+	result := &queryResult{orderByTS: true, ascTS: true}
+	defer result.Release()
+	for ti.nextBlock() {
+		bc := generateBlockCursor()
+		bc.init(ti.piHeap[0].p, ti.piHeap[0].curBlock, qo)
+		result.data = append(result.data, bc)
+	}
+	require.NoError(t, ti.Error())
+	counts := make(map[common.SeriesID]int64)
+	for r := result.Pull(); r != nil; r = result.Pull() {
+		counts[r.SID] += int64(len(r.Timestamps))
+	}
+	return counts
+}
+
+func TestCountElements(t *testing.T) {
+	esLong := &elements{}
+	for i := int64(1); i <= 100; i++ {
+		esLong.seriesIDs = append(esLong.seriesIDs, 4)
+		esLong.timestamps = append(esLong.timestamps, i*10)
+		esLong.elementIDs = append(esLong.elementIDs, strconv.FormatInt(i, 10))
+		esLong.tagFamilies = append(esLong.tagFamilies, []tagValues{{
+			tag: "singleTag", values: []*tagValue{{tag: "intTag", valueType: pbv1.ValueTypeInt64, value: convert.Int64ToBytes(i)}},
+		}})
+	}
+	tst := openTestTable(t, esTS1, esTS2, esTS1, esLong)
+	sids := []common.SeriesID{1, 2, 3, 4}
+	tests := []struct {
+		elementRefMap  map[common.SeriesID][]int64
+		elementIDRange *pbv1.ElementIDRange
+		want           map[common.SeriesID]int64
+		name           string
+		minTimestamp   int64
+		maxTimestamp   int64
+		sampleInterval uint32
+	}{
+		{
+			name:         "blocks in the range",
+			minTimestamp: 1,
+			maxTimestamp: 1000,
+			want:         map[common.SeriesID]int64{1: 3, 2: 3, 3: 3, 4: 100},
+		},
+		{
+			name:         "range inside a block",
+			minTimestamp: 95,
+			maxTimestamp: 401,
+			want:         map[common.SeriesID]int64{4: 31},
+		},
+		{
+			name:         "range between elements",
+			minTimestamp: 101,
+			maxTimestamp: 109,
+			want:         map[common.SeriesID]int64{},
+		},
+		{
+			name:           "element ID range",
+			minTimestamp:   1,
+			maxTimestamp:   1000,
+			elementIDRange: &pbv1.ElementIDRange{From: "20", To: "29"},
+			want:           map[common.SeriesID]int64{2: 3, 4: 10},
+		},
+		{
+			name:           "sampled",
+			minTimestamp:   1,
+			maxTimestamp:   1000,
+			sampleInterval: 3,
+		},
+		{
+			name:          "filtered by the index",
+			minTimestamp:  1,
+			maxTimestamp:  1000,
+			elementRefMap: map[common.SeriesID][]int64{1: {2}, 4: {100, 200, 205}},
+			want:          map[common.SeriesID]int64{1: 1, 4: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tst.currentSnapshot()
+			require.NotNil(t, s)
+			defer s.decRef()
+			qo := queryOptions{minTimestamp: tt.minTimestamp, maxTimestamp: tt.maxTimestamp, elementRefMap: tt.elementRefMap}
+			qo.ElementIDRange = tt.elementIDRange
+			qo.SampleInterval = tt.sampleInterval
+			qo.TagProjection = tagProjections[1]
+			parts, _ := s.getParts(nil, tt.minTimestamp, tt.maxTimestamp)
+			got, err := countElements(parts, sids, qo)
+			require.NoError(t, err)
+			assert.Equal(t, pulledCounts(t, s, sids, qo), got, "the counts differ from the elements of the full query")
+			if tt.want != nil {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+)
+
+const defaultQueryDrainTimeout = 10 * time.Second
+
+// ErrServiceDraining denotes the service is shutting down and doesn't accept new queries.
+var ErrServiceDraining = errors.New("the stream service is draining")
+
+// queryDrainer tracks the in-flight queries, so that the shutdown waits for them.
+// A query is in flight from its start until its result is released.
+type queryDrainer struct {
+	cancels  map[uint64]context.CancelFunc
+	inFlight sync.WaitGroup
+	mu       sync.Mutex
+	nextID   uint64
+	draining bool
+}
+
+func newQueryDrainer() *queryDrainer {
+	return &queryDrainer{cancels: make(map[uint64]context.CancelFunc)}
+}
+
+// begin registers a query. The returned context is canceled if the query outlives the drain timeout,
+// and the returned function ends the query.
+func (d *queryDrainer) begin(ctx context.Context) (context.Context, func(), error) {
+	if d == nil {
+		return ctx, func() {}, nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return nil, nil, ErrServiceDraining
+	}
+	d.inFlight.Add(1)
+	qCtx, cancel := context.WithCancel(ctx)
+	id := d.nextID
+	d.nextID++
+	d.cancels[id] = cancel
+	var once sync.Once
+	return qCtx, func() {
+		once.Do(func() {
+			d.mu.Lock()
+			delete(d.cancels, id)
+			d.mu.Unlock()
+			cancel()
+			d.inFlight.Done()
+		})
+	}, nil
+}
+
+// drain stops accepting queries and waits for the in-flight ones up to the timeout.
+// The queries still in flight after the timeout are canceled.
+// It reports whether all the queries ended in time.
+func (d *queryDrainer) drain(timeout time.Duration) bool {
+	if d == nil {
+		return true
+	}
+	d.mu.Lock()
+	d.draining = true
+	d.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		d.inFlight.Wait()
+		close(done)
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		d.mu.Lock()
+		for _, cancel := range d.cancels {
+			cancel()
+		}
+		d.mu.Unlock()
+		return false
+	}
+}
+
+// drainedResult ends the query once the result is released.
+// A canceled query stops returning data.
+type drainedResult struct {
+	pbv1.StreamQueryResult
+	ctx  context.Context
+	done func()
+}
+
+func newDrainedResult(ctx context.Context, done func(), result pbv1.StreamQueryResult, err error) (pbv1.StreamQueryResult, error) {
+	if err != nil || result == nil {
+		done()
+		return result, err
+	}
+	return &drainedResult{StreamQueryResult: result, ctx: ctx, done: done}, nil
+}
+
+func (dr *drainedResult) Pull() *pbv1.StreamResult {
+	if dr.ctx.Err() != nil {
+		return nil
+	}
+	return dr.StreamQueryResult.Pull()
+}
+
+func (dr *drainedResult) NextBatch(size int) *pbv1.StreamBatch {
+	if dr.ctx.Err() != nil {
+		return nil
+	}
+	if br, ok := dr.StreamQueryResult.(pbv1.StreamBatchResult); ok {
+		return br.NextBatch(size)
+	}
+	return nil
+}
+
+// BuildCursor returns nil if the result can't be read by a cursor.
+func (dr *drainedResult) BuildCursor(batchSize int) pbv1.StreamCursor {
+	cb, ok := dr.StreamQueryResult.(pbv1.StreamCursorBuilder)
+	if !ok {
+		return nil
+	}
+	return &drainedCursor{StreamCursor: cb.BuildCursor(batchSize), ctx: dr.ctx}
+}
+
+type drainedCursor struct {
+	pbv1.StreamCursor
+	ctx context.Context
+}
+
+func (dc *drainedCursor) Next() *pbv1.StreamBatch {
+	if dc.ctx.Err() != nil {
+		return nil
+	}
+	return dc.StreamCursor.Next()
+}
+
+func (dr *drainedResult) Release() {
+	dr.StreamQueryResult.Release()
+	dr.done()
+}
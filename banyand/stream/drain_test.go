@@ -0,0 +1,93 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+)
+
+// startLongQuery starts a query which holds its result until it's released.
+func startLongQuery(t *testing.T, d *queryDrainer) (pbv1.StreamQueryResult, context.Context, *trackedResult) {
+	ctx, done, err := d.begin(context.Background())
+	require.NoError(t, err)
+	tr := &trackedResult{results: []*pbv1.StreamResult{{ElementIDs: []string{"1"}}, {ElementIDs: []string{"2"}}}}
+	result, err := newDrainedResult(ctx, done, tr, nil)
+	require.NoError(t, err)
+	return result, ctx, tr
+}
+
+func TestQueryDrainer(t *testing.T) {
+	const drainTimeout = 200 * time.Millisecond
+
+	t.Run("the shutdown waits for an in-flight query", func(t *testing.T) {
+		d := newQueryDrainer()
+		result, _, tr := startLongQuery(t, d)
+		drained := make(chan bool)
+		go func() {
+			drained <- d.drain(10 * drainTimeout)
+		}()
+
+		assert.Eventually(t, func() bool {
+			_, done, err := d.begin(context.Background())
+			if err != nil {
+				return true
+			}
+			done()
+			return false
+		}, drainTimeout, time.Millisecond, "a new query is rejected once the drain starts")
+		_, _, err := d.begin(context.Background())
+		assert.ErrorIs(t, err, ErrServiceDraining)
+
+		var ids []string
+		for r := result.Pull(); r != nil; r = result.Pull() {
+			ids = append(ids, r.ElementIDs...)
+			select {
+			case <-drained:
+				t.Fatal("the drain ends before the query")
+			default:
+			}
+		}
+		result.Release()
+		assert.Equal(t, []string{"1", "2"}, ids)
+		assert.True(t, <-drained)
+		assert.Equal(t, 1, tr.released)
+	})
+
+	t.Run("a query outliving the timeout is canceled", func(t *testing.T) {
+		d := newQueryDrainer()
+		result, ctx, tr := startLongQuery(t, d)
+		require.NotNil(t, result.Pull())
+
+		start := time.Now()
+		assert.False(t, d.drain(drainTimeout))
+		assert.Less(t, time.Since(start), 10*drainTimeout)
+		assert.ErrorIs(t, ctx.Err(), context.Canceled)
+		assert.Nil(t, result.Pull(), "a canceled query stops returning data")
+		assert.Equal(t, 1, tr.pulled)
+		result.Release()
+		result.Release()
+		assert.Equal(t, 2, tr.released, "the result is still released after the cancellation")
+	})
+}
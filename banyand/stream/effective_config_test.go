@@ -0,0 +1,57 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	"github.com/apache/skywalking-banyandb/pkg/encoding"
+)
+
+func TestEffectiveConfig(t *testing.T) {
+	s := &service{}
+	require.NoError(t, s.FlagSet().Parse([]string{"--stream-flush-timeout=3s", "--stream-max-series-per-query=100"}))
+	sp := &supplier{path: "/data/stream", option: s.option}
+	g := &commonv1.Group{
+		Metadata: &commonv1.Metadata{Name: "sw_trace"},
+		ResourceOpts: &commonv1.ResourceOpts{
+			ShardNum:        2,
+			SegmentInterval: &commonv1.IntervalRule{Unit: commonv1.IntervalRule_UNIT_DAY, Num: 1},
+			Ttl:             &commonv1.IntervalRule{Unit: commonv1.IntervalRule_UNIT_DAY, Num: 3},
+		},
+	}
+
+	cfg := newEffectiveConfig(sp.tsdbOpts(g))
+	assert.Equal(t, 3*time.Second, cfg.FlushTimeout)
+	assert.Equal(t, 100, cfg.MaxSeriesPerQuery)
+	assert.Equal(t, "/data/stream/sw_trace", cfg.Location)
+	assert.Equal(t, encoding.DefaultCompressThreshold, cfg.CompressThreshold)
+	assert.Empty(t, cfg.ClusteringKey)
+
+	g.ResourceOpts.CompressThreshold = 1024
+	g.ResourceOpts.ClusteringKey = "trace_id"
+	cfg = newEffectiveConfig(sp.tsdbOpts(g))
+	assert.Equal(t, 1024, cfg.CompressThreshold)
+	assert.Equal(t, "trace_id", cfg.ClusteringKey)
+	assert.Equal(t, 3*time.Second, cfg.FlushTimeout)
+}
@@ -0,0 +1,147 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/pkg/index"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	logicalstream "github.com/apache/skywalking-banyandb/pkg/query/logical/stream"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+// elementIDIndexRuleID is the index rule the element IDs are indexed by in the element index.
+// An index rule gets a non-zero ID once it's created, so the element IDs never share a field with the tags.
+const elementIDIndexRuleID = 0
+
+func elementIDField(seriesID common.SeriesID, elementID string) index.Field {
+	return index.Field{
+		Key: index.FieldKey{
+			IndexRuleID: elementIDIndexRuleID,
+			SeriesID:    seriesID,
+		},
+		Term: []byte(elementID),
+	}
+}
+
+// getByElementID returns the element of the group by its ID with all its stored tags.
+// The element indices of every segment locate the series and the timestamp of the element,
+// then the series is resolved by its ID and the element is read from the stream the series belongs to.
+// Only the elements of the streams indexing their element IDs can be found,
+// and only the ones written after it's enabled.
+func (sr *schemaRepo) getByElementID(ctx context.Context, group, elementID string) (*streamv1.Element, error) {
+	tsdb, err := sr.loadTSDB(group)
+	if err != nil {
+		return nil, err
+	}
+	tabWrappers := tsdb.SelectTSTables(timestamp.AllTime)
+	defer func() {
+		for i := range tabWrappers {
+			tabWrappers[i].DecRef()
+		}
+	}()
+	var refs []elementRef
+	for i := range tabWrappers {
+		tableRefs, seekErr := tabWrappers[i].Table().Index().seekElementID(elementID)
+		if seekErr != nil {
+			return nil, seekErr
+		}
+		refs = append(refs, tableRefs...)
+	}
+	notFound := errors.WithMessagef(ErrElementNotExist, "element %s in group %s", elementID, group)
+	if len(refs) == 0 {
+		return nil, notFound
+	}
+	var ids []common.SeriesID
+	for _, ref := range refs {
+		if !slices.Contains(ids, ref.seriesID) {
+			ids = append(ids, ref.seriesID)
+		}
+	}
+	sl, err := tsdb.LookupByID(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range refs {
+		idx := slices.IndexFunc(sl, func(s *pbv1.Series) bool { return s.ID == ref.seriesID })
+		if idx < 0 {
+			continue
+		}
+		s, ok := sr.loadStream(&commonv1.Metadata{Group: group, Name: sl[idx].Subject})
+		if !ok {
+			continue
+		}
+		e, getErr := s.getElement(ctx, sl[idx], ref.timestamp, elementID)
+		if getErr != nil {
+			return nil, getErr
+		}
+		if e != nil {
+			return e, nil
+		}
+	}
+	return nil, notFound
+}
+
+// getElement reads the element of the series at the timestamp, or returns nil if it's absent.
+// The indexed-only tags are left out, as their values aren't stored.
+func (s *stream) getElement(ctx context.Context, series *pbv1.Series, ts int64, elementID string) (*streamv1.Element, error) {
+	var tagProjection []pbv1.TagProjection
+	for _, tf := range s.schema.GetTagFamilies() {
+		tp := pbv1.TagProjection{Family: tf.GetName()}
+		for _, t := range tf.GetTags() {
+			if !t.GetIndexedOnly() {
+				tp.Names = append(tp.Names, t.GetName())
+			}
+		}
+		if len(tp.Names) > 0 {
+			tagProjection = append(tagProjection, tp)
+		}
+	}
+	timeRange := timestamp.NewInclusiveTimeRange(time.Unix(0, ts), time.Unix(0, ts))
+	result, err := s.Query(ctx, pbv1.StreamQueryOptions{
+		Name:          s.name,
+		TimeRange:     &timeRange,
+		Entities:      [][]*modelv1.TagValue{series.EntityValues},
+		TagProjection: tagProjection,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer result.Release()
+	for _, e := range logicalstream.BuildElementsFromStreamResult(result) {
+		if e.GetElementId() == elementID {
+			return e, nil
+		}
+	}
+	return nil, nil
+}
+
+// seekElementID returns the elements of every series indexed by the element ID.
+func (e *elementIndex) seekElementID(elementID string) ([]elementRef, error) {
+	term := []byte(elementID)
+	return e.SeekRange(index.FieldKey{IndexRuleID: elementIDIndexRuleID}, term, term, true)
+}
@@ -0,0 +1,113 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/banyand/stream"
+	"github.com/apache/skywalking-banyandb/pkg/test/flags"
+)
+
+var _ = Describe("Get an element by its ID", func() {
+	now := time.Now()
+	var svcs *services
+	var deferFn func()
+
+	BeforeEach(func() {
+		svcs, deferFn = setUp()
+		waitForStream(svcs)
+		indexElementID(svcs, true)
+	})
+
+	AfterEach(func() {
+		deferFn()
+	})
+
+	getByElementID := func(elementID string) (*streamv1.Element, error) {
+		return svcs.stream.GetByElementID(context.Background(), swMetadata.Group, elementID)
+	}
+
+	It("returns the tag families of the element", func() {
+		writeElements(svcs, newWriteRequest("first", now), newWriteRequest("second", now.Add(time.Millisecond)))
+		var e *streamv1.Element
+		Eventually(func() (err error) {
+			e, err = getByElementID("second")
+			return err
+		}).WithTimeout(flags.EventuallyTimeout).Should(Succeed())
+		Expect(e.GetElementId()).To(Equal("second"))
+		Expect(e.GetTagFamilies()).To(HaveLen(2))
+		Expect(e.GetTagFamilies()[0]).To(BeComparableTo(&modelv1.TagFamily{
+			Name: "data",
+			Tags: []*modelv1.Tag{{Key: "data_binary", Value: &modelv1.TagValue{Value: &modelv1.TagValue_BinaryData{BinaryData: []byte("second")}}}},
+		}, protocmp.Transform()))
+		searchable := e.GetTagFamilies()[1]
+		Expect(searchable.GetName()).To(Equal("searchable"))
+		Expect(searchable.GetTags()[:3]).To(BeComparableTo([]*modelv1.Tag{
+			{Key: "trace_id", Value: &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "second"}}}},
+			{Key: "state", Value: swEntity[2]},
+			{Key: "service_id", Value: swEntity[0]},
+		}, protocmp.Transform()))
+		for _, t := range searchable.GetTags()[3:] {
+			Expect(t.GetValue().GetNull()).NotTo(BeNil(), "tag %s isn't written", t.GetKey())
+		}
+	})
+
+	It("fails to get an unknown element", func() {
+		writeElement(svcs, newWriteRequest("first", now))
+		Eventually(func() error {
+			_, err := getByElementID("first")
+			return err
+		}).WithTimeout(flags.EventuallyTimeout).Should(Succeed())
+		_, err := getByElementID("unknown")
+		Expect(err).To(MatchError(stream.ErrElementNotExist))
+		_, err = getByElementID("firs")
+		Expect(err).To(MatchError(stream.ErrElementNotExist))
+	})
+
+	It("doesn't index the element IDs of a stream not opting in", func() {
+		indexElementID(svcs, false)
+		writeElement(svcs, newWriteRequest("first", now))
+		Consistently(func() error {
+			_, getErr := getByElementID("first")
+			return getErr
+		}).Should(MatchError(stream.ErrElementNotExist))
+	})
+})
+
+// indexElementID turns the element ID index of the stream on or off, and waits for the stream service to pick it up.
+func indexElementID(svcs *services, enabled bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), flags.EventuallyTimeout)
+	defer cancel()
+	s, err := svcs.metadataService.StreamRegistry().GetStream(ctx, swMetadata)
+	Expect(err).ShouldNot(HaveOccurred())
+	s.IndexElementId = enabled
+	_, err = svcs.metadataService.StreamRegistry().UpdateStream(ctx, s)
+	Expect(err).ShouldNot(HaveOccurred())
+	Eventually(func() bool {
+		st, streamErr := svcs.stream.Stream(swMetadata)
+		return streamErr == nil && st.GetSchema().GetIndexElementId() == enabled
+	}).WithTimeout(flags.EventuallyTimeout).Should(BeTrue())
+}
@@ -143,6 +143,7 @@ func (e *elements) Swap(i, j int) {
 type elementsInTable struct {
 	timeRange timestamp.TimeRange
 	tsTable   storage.TSTableWrapper[*tsTable]
+	shardID   common.ShardID
 
 	elements elements
 	docs     index.Documents
@@ -150,6 +151,7 @@ type elementsInTable struct {
 
 type elementsInGroup struct {
 	tsdb     storage.TSDB[*tsTable, option]
+	pending  *pendingWrites
 	docs     index.Documents
 	tables   []*elementsInTable
 	latestTS int64
@@ -0,0 +1,404 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/banyand/internal/storage"
+	"github.com/apache/skywalking-banyandb/banyand/metadata/schema"
+	"github.com/apache/skywalking-banyandb/pkg/partition"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+const (
+	importBatchSize    = 1000
+	importWaitInterval = 100 * time.Millisecond
+)
+
+var (
+	errInvalidExport  = errors.New("invalid export")
+	errIndexedOnlyTag = errors.New("the indexed-only tags can't be exported")
+)
+
+// An export is a sequence of size-delimited anypb.Any records. The group comes first, followed by
+// its index rules, streams and index rule bindings. The elements come last, in timestamp order within each stream.
+
+// exportRange writes the group's schemas and its elements within the time range to w.
+// The tables and the snapshots of their parts are held until the export completes,
+// so the concurrent merges and the retention don't change what it reads.
+func (sr *schemaRepo) exportRange(ctx context.Context, group string, timeRange timestamp.TimeRange, w io.Writer) error {
+	g, ok := sr.LoadGroup(group)
+	if !ok {
+		return fmt.Errorf("group %s not found", group)
+	}
+	streams, err := sr.metadata.StreamRegistry().ListStream(ctx, schema.ListOpt{Group: group})
+	if err != nil {
+		return err
+	}
+	indexRules, err := sr.metadata.IndexRuleRegistry().ListIndexRule(ctx, schema.ListOpt{Group: group})
+	if err != nil {
+		return err
+	}
+	bindings, err := sr.metadata.IndexRuleBindingRegistry().ListIndexRuleBinding(ctx, schema.ListOpt{Group: group})
+	if err != nil {
+		return err
+	}
+	// The values of the indexed-only tags live in the index terms alone, which can't be turned back into the tags,
+	// so the export fails rather than dropping them.
+	for i := range streams {
+		if tags := indexedOnlyTags(streams[i]); len(tags) > 0 {
+			return fmt.Errorf("%w: stream %s has %v", errIndexedOnlyTag, streams[i].GetMetadata().GetName(), tags)
+		}
+	}
+	bw := bufio.NewWriter(w)
+	if err = writeRecord(bw, g.GetSchema()); err != nil {
+		return err
+	}
+	for i := range indexRules {
+		if err = writeRecord(bw, indexRules[i]); err != nil {
+			return err
+		}
+	}
+	for i := range streams {
+		if err = writeRecord(bw, streams[i]); err != nil {
+			return err
+		}
+	}
+	for i := range bindings {
+		if err = writeRecord(bw, bindings[i]); err != nil {
+			return err
+		}
+	}
+
+	db := g.SupplyTSDB()
+	if db == nil {
+		return bw.Flush()
+	}
+	tsdb := db.(storage.TSDB[*tsTable, option])
+	tabWrappers := tsdb.SelectTSTables(timeRange)
+	defer func() {
+		for i := range tabWrappers {
+			tabWrappers[i].DecRef()
+		}
+	}()
+	minTimestamp, maxTimestamp := timeRange.UnixNanoBounds()
+	var parts []*part
+	var snapshots []*snapshot
+	defer func() {
+		for i := range snapshots {
+			snapshots[i].decRef()
+		}
+	}()
+	var n int
+	for i := range tabWrappers {
+		snp := tabWrappers[i].Table().currentSnapshot()
+		if snp == nil {
+			continue
+		}
+		parts, n = snp.getParts(parts, minTimestamp, maxTimestamp)
+		if n < 1 {
+			snp.decRef()
+			continue
+		}
+		snapshots = append(snapshots, snp)
+	}
+	if len(parts) > 0 {
+		for i := range streams {
+			s, ok := sr.loadStream(streams[i].GetMetadata())
+			if !ok {
+				return fmt.Errorf("stream %s is not loaded", streams[i].GetMetadata())
+			}
+			if err = s.export(ctx, tsdb, parts, minTimestamp, maxTimestamp, bw); err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// indexedOnlyTags returns the indexed-only tags of the stream except its entity tags, which are kept by the series.
+func indexedOnlyTags(s *databasev1.Stream) []string {
+	var tags []string
+	for _, tf := range s.GetTagFamilies() {
+		for _, t := range tf.GetTags() {
+			if t.GetIndexedOnly() && !slices.Contains(s.GetEntity().GetTagNames(), t.GetName()) {
+				tags = append(tags, t.GetName())
+			}
+		}
+	}
+	return tags
+}
+
+// export writes the stream's elements in the parts to w.
+func (s *stream) export(ctx context.Context, tsdb storage.TSDB[*tsTable, option], parts []*part,
+	minTimestamp, maxTimestamp int64, w io.Writer,
+) error {
+	entity := make([]*modelv1.TagValue, len(s.schema.GetEntity().GetTagNames()))
+	for i := range entity {
+		entity[i] = pbv1.AnyTagValue
+	}
+	sl, err := tsdb.Lookup(ctx, []*pbv1.Series{{Subject: s.name, EntityValues: entity}})
+	if err != nil || len(sl) < 1 {
+		return err
+	}
+	var tagProjection []pbv1.TagProjection
+	for _, tf := range s.schema.GetTagFamilies() {
+		tp := pbv1.TagProjection{Family: tf.GetName()}
+		for _, t := range tf.GetTags() {
+			if _, isEntity := s.indexRuleLocators.EntitySet[t.GetName()]; isEntity || t.GetIndexedOnly() {
+				continue
+			}
+			tp.Names = append(tp.Names, t.GetName())
+		}
+		if len(tp.Names) > 0 {
+			tagProjection = append(tagProjection, tp)
+		}
+	}
+	var result queryResult
+	defer result.Release()
+	qo := queryOptions{
+		StreamQueryOptions: pbv1.StreamQueryOptions{Name: s.name, TagProjection: tagProjection},
+		minTimestamp:       minTimestamp,
+		maxTimestamp:       maxTimestamp,
+	}
+	if err = s.loadResult(&result, parts, sl, qo); err != nil {
+		return err
+	}
+	entityMap := make(map[string]int, len(s.schema.GetEntity().GetTagNames()))
+	for idx, name := range s.schema.GetEntity().GetTagNames() {
+		entityMap[name] = idx
+	}
+	for r := result.Pull(); r != nil; r = result.Pull() {
+		for i := range r.Timestamps {
+			if err = writeRecord(w, s.toInternalWriteRequest(entityMap, r.Series, r, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// toInternalWriteRequest rebuilds the write of the i-th element in the result.
+// The entity tags are taken from the series, and the other tags from the stored values.
+func (s *stream) toInternalWriteRequest(entityMap map[string]int, series *pbv1.Series, r *pbv1.StreamResult, i int) *streamv1.InternalWriteRequest {
+	values := make(map[string]*modelv1.TagValue)
+	for _, tf := range r.TagFamilies {
+		for _, t := range tf.Tags {
+			values[t.Name] = t.Values[i]
+		}
+	}
+	tagFamilies := make([]*modelv1.TagFamilyForWrite, 0, len(s.schema.GetTagFamilies()))
+	for _, tfSpec := range s.schema.GetTagFamilies() {
+		tf := &modelv1.TagFamilyForWrite{}
+		for _, tSpec := range tfSpec.GetTags() {
+			if idx, ok := entityMap[tSpec.GetName()]; ok {
+				tf.Tags = append(tf.Tags, series.EntityValues[idx])
+				continue
+			}
+			if v, ok := values[tSpec.GetName()]; ok {
+				tf.Tags = append(tf.Tags, v)
+				continue
+			}
+			tf.Tags = append(tf.Tags, pbv1.NullTagValue)
+		}
+		tagFamilies = append(tagFamilies, tf)
+	}
+	return &streamv1.InternalWriteRequest{
+		EntityValues: series.EntityValues,
+		Request: &streamv1.WriteRequest{
+			Metadata: &commonv1.Metadata{Group: s.group, Name: s.name},
+			Element: &streamv1.ElementValue{
+				ElementId:   r.ElementIDs[i],
+				Timestamp:   timestamppb.New(time.Unix(0, r.Timestamps[i])),
+				TagFamilies: tagFamilies,
+			},
+		},
+	}
+}
+
+// importRange reads an export from r into the group, creating the schemas absent from the group.
+// The elements are written as new parts of the group.
+func (sr *schemaRepo) importRange(ctx context.Context, group string, r io.Reader) error {
+	br := bufio.NewReader(r)
+	first, err := readRecord(br)
+	if errors.Is(err, io.EOF) {
+		return fmt.Errorf("%w: the export is empty", errInvalidExport)
+	}
+	if err != nil {
+		return err
+	}
+	g, ok := first.(*commonv1.Group)
+	if !ok {
+		return fmt.Errorf("%w: the group is absent", errInvalidExport)
+	}
+	g.Metadata.Name = group
+	resetRevision(g.Metadata)
+	if err = ignoreExists(sr.metadata.GroupRegistry().CreateGroup(ctx, g)); err != nil {
+		return err
+	}
+	wc := &writeCallback{l: sr.l, schemaRepo: sr}
+	var events []*streamv1.InternalWriteRequest
+	var pending *commonv1.Metadata
+	for {
+		m, errRead := readRecord(br)
+		if errors.Is(errRead, io.EOF) {
+			break
+		}
+		if errRead != nil {
+			return errRead
+		}
+		switch rec := m.(type) {
+		case *databasev1.IndexRule:
+			rec.Metadata.Group = group
+			resetRevision(rec.Metadata)
+			err = ignoreExists(sr.metadata.IndexRuleRegistry().CreateIndexRule(ctx, rec))
+		case *databasev1.Stream:
+			rec.Metadata.Group = group
+			resetRevision(rec.Metadata)
+			_, err = sr.metadata.StreamRegistry().CreateStream(ctx, rec)
+			err = ignoreExists(err)
+		case *databasev1.IndexRuleBinding:
+			rec.Metadata.Group = group
+			resetRevision(rec.Metadata)
+			err = ignoreExists(sr.metadata.IndexRuleBindingRegistry().CreateIndexRuleBinding(ctx, rec))
+		case *streamv1.InternalWriteRequest:
+			md := rec.GetRequest().GetMetadata()
+			md.Group = group
+			if pending == nil || pending.GetName() != md.GetName() {
+				if err = wc.flushImport(ctx, events, g.GetResourceOpts()); err != nil {
+					return err
+				}
+				events = events[:0]
+				if err = sr.waitForStream(ctx, md); err != nil {
+					return err
+				}
+				pending = md
+			}
+			events = append(events, rec)
+			if len(events) >= importBatchSize {
+				err = wc.flushImport(ctx, events, g.GetResourceOpts())
+				events = events[:0]
+			}
+		default:
+			err = fmt.Errorf("%w: unknown record %T", errInvalidExport, m)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return wc.flushImport(ctx, events, g.GetResourceOpts())
+}
+
+// flushImport routes the imported writes to the shards of the target group by its sharding strategy and writes them.
+func (w *writeCallback) flushImport(ctx context.Context, events []*streamv1.InternalWriteRequest, opts *commonv1.ResourceOpts) error {
+	if len(events) == 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	groups := make(map[string]*elementsInGroup)
+	for _, e := range events {
+		entity, err := pbv1.EntityValues(append([]*modelv1.TagValue{pbv1.StrValue(e.GetRequest().GetMetadata().GetName())},
+			e.GetEntityValues()...)).ToEntity()
+		if err != nil {
+			return err
+		}
+		shardingKey := partition.ShardingKey(opts.GetShardingStrategy(), entity.Marshal(), []byte(e.GetRequest().GetElement().GetElementId()))
+		shardID, err := partition.ShardID(shardingKey, opts.GetShardNum())
+		if err != nil {
+			return err
+		}
+		e.ShardId = uint32(shardID)
+		e.SeriesHash = pbv1.HashEntity(entity)
+		if groups, err = w.handle(groups, e, nil); err != nil {
+			return err
+		}
+	}
+	w.write(groups, nil)
+	return nil
+}
+
+// waitForStream blocks until the stream and its group are loaded.
+func (sr *schemaRepo) waitForStream(ctx context.Context, md *commonv1.Metadata) error {
+	ticker := time.NewTicker(importWaitInterval)
+	defer ticker.Stop()
+	for {
+		if _, ok := sr.loadStream(md); ok {
+			if _, err := sr.loadTSDB(md.GetGroup()); err == nil {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("stream %s isn't loaded: %w", md, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+func resetRevision(md *commonv1.Metadata) {
+	md.Id = 0
+	md.CreateRevision = 0
+	md.ModRevision = 0
+}
+
+func ignoreExists(err error) error {
+	if errors.Is(err, schema.ErrGRPCAlreadyExists) {
+		return nil
+	}
+	return err
+}
+
+func writeRecord(w io.Writer, m proto.Message) error {
+	a, err := anypb.New(m)
+	if err != nil {
+		return err
+	}
+	_, err = protodelim.MarshalTo(w, a)
+	return err
+}
+
+func readRecord(r *bufio.Reader) (proto.Message, error) {
+	a := &anypb.Any{}
+	if err := protodelim.UnmarshalFrom(r, a); err != nil {
+		return nil, err
+	}
+	m, err := a.UnmarshalNew()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidExport, err)
+	}
+	return m, nil
+}
@@ -0,0 +1,110 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream_test
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/test/flags"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+var _ = Describe("Export and import a time range", func() {
+	now := time.Now()
+	tr := timestamp.NewInclusiveTimeRange(now.Add(-4*time.Hour), now.Add(time.Hour))
+	var svcs *services
+	var deferFn func()
+
+	BeforeEach(func() {
+		svcs, deferFn = setUp()
+		waitForStream(svcs)
+	})
+
+	AfterEach(func() {
+		deferFn()
+	})
+
+	It("imports the elements of a sub-range into a fresh group", func() {
+		writeElement(svcs, newWriteRequest("before", now.Add(-150*time.Minute)))
+		writeElement(svcs, newWriteRequest("second", now.Add(-80*time.Minute)))
+		writeElement(svcs, newWriteRequest("first", now.Add(-100*time.Minute)))
+		writeElement(svcs, newWriteRequest("after", now.Add(-30*time.Minute)))
+		Eventually(func() []string { return queryElementIDs(svcs, tr, 0) }).WithTimeout(flags.EventuallyTimeout).Should(HaveLen(4))
+
+		var buf bytes.Buffer
+		subRange := timestamp.NewInclusiveTimeRange(now.Add(-2*time.Hour), now.Add(-time.Hour))
+		Expect(svcs.stream.ExportRange(context.Background(), swMetadata.Group, subRange, &buf)).To(Succeed())
+
+		ctx, cancel := context.WithTimeout(context.Background(), flags.EventuallyTimeout)
+		defer cancel()
+		Expect(svcs.stream.ImportRange(ctx, "imported", &buf)).To(Succeed())
+
+		s, err := svcs.stream.Stream(&commonv1.Metadata{Name: swMetadata.Name, Group: "imported"})
+		Expect(err).ShouldNot(HaveOccurred())
+		result, err := s.Query(context.Background(), pbv1.StreamQueryOptions{
+			Name:          swMetadata.Name,
+			TimeRange:     &tr,
+			Entities:      [][]*modelv1.TagValue{swEntity},
+			TagProjection: []pbv1.TagProjection{{Family: "searchable", Names: []string{"trace_id", "service_id"}}},
+		})
+		Expect(err).ShouldNot(HaveOccurred())
+		defer result.Release()
+		var ids, traceIDs, serviceIDs []string
+		for r := result.Pull(); r != nil; r = result.Pull() {
+			ids = append(ids, r.ElementIDs...)
+			for _, v := range r.TagFamilies[0].Tags[0].Values {
+				traceIDs = append(traceIDs, v.GetStr().GetValue())
+			}
+			for _, v := range r.TagFamilies[0].Tags[1].Values {
+				serviceIDs = append(serviceIDs, v.GetStr().GetValue())
+			}
+		}
+		Expect(ids).To(Equal([]string{"first", "second"}))
+		Expect(traceIDs).To(Equal([]string{"first", "second"}))
+		Expect(serviceIDs).To(Equal([]string{"svc_1", "svc_1"}))
+	})
+
+	It("fails to export a stream whose indexed-only tags can't be restored", func() {
+		writeElement(svcs, newWriteRequest("first", now.Add(-100*time.Minute)))
+		Eventually(func() []string { return queryElementIDs(svcs, tr, 0) }).WithTimeout(flags.EventuallyTimeout).Should(HaveLen(1))
+
+		registry := svcs.metadataService.StreamRegistry()
+		s, err := registry.GetStream(context.Background(), swMetadata)
+		Expect(err).ShouldNot(HaveOccurred())
+		for _, t := range s.GetTagFamilies()[1].GetTags() {
+			if t.GetName() == "trace_id" {
+				t.IndexedOnly = true
+			}
+		}
+		_, err = registry.UpdateStream(context.Background(), s)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		var buf bytes.Buffer
+		err = svcs.stream.ExportRange(context.Background(), swMetadata.Group, tr, &buf)
+		Expect(err).To(MatchError(ContainSubstring("trace_id")))
+		Expect(err).To(MatchError(ContainSubstring("the indexed-only tags can't be exported")))
+	})
+})
@@ -0,0 +1,89 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/apache/skywalking-banyandb/banyand/internal/storage"
+	"github.com/apache/skywalking-banyandb/pkg/index"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+func tableIndexes(tabWrappers []storage.TSTableWrapper[*tsTable]) []*elementIndex {
+	indexes := make([]*elementIndex, len(tabWrappers))
+	for i := range tabWrappers {
+		indexes[i] = tabWrappers[i].Table().Index()
+	}
+	return indexes
+}
+
+// searchIndexes searches the element indexes of the tables a query fans out to, running at most parallelism searches at a time.
+// Zero parallelism runs a search per CPU. The references of every index are returned in the order of the indexes,
+// so that the result doesn't depend on which search finishes first. The first error in that order is returned.
+func searchIndexes(ctx context.Context, indexes []*elementIndex, seriesList pbv1.SeriesList, filter index.Filter,
+	timeRange *timestamp.TimeRange, parallelism int,
+) ([][]elementRef, error) {
+	if parallelism <= 0 {
+		parallelism = runtime.GOMAXPROCS(0)
+	}
+	results := make([][]elementRef, len(indexes))
+	if parallelism == 1 || len(indexes) < 2 {
+		for i := range indexes {
+			erl, err := indexes[i].Search(ctx, seriesList, filter, timeRange)
+			if err != nil {
+				return nil, err
+			}
+			results[i] = erl
+		}
+		return results, nil
+	}
+	errs := make([]error, len(indexes))
+	sem := make(chan struct{}, parallelism)
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	for i := range indexes {
+		sem <- struct{}{}
+		// The searches not started yet are skipped once one fails.
+		if failed.Load() {
+			<-sem
+			break
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if results[i], errs[i] = indexes[i].Search(ctx, seriesList, filter, timeRange); errs[i] != nil {
+				failed.Store(true)
+			}
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,115 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	"github.com/apache/skywalking-banyandb/pkg/index"
+	"github.com/apache/skywalking-banyandb/pkg/index/posting"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+const fanOutRuleID = 1
+
+// termFilter matches the elements indexed with the term.
+type termFilter struct {
+	term string
+}
+
+func (tf termFilter) String() string {
+	return "term:" + tf.term
+}
+
+func (tf termFilter) Execute(getSearcher index.GetSearcher, seriesID common.SeriesID) (posting.List, error) {
+	searcher, err := getSearcher(databasev1.IndexRule_TYPE_INVERTED)
+	if err != nil {
+		return nil, err
+	}
+	return searcher.MatchTerms(index.Field{
+		Key:  index.FieldKey{IndexRuleID: fanOutRuleID, SeriesID: seriesID},
+		Term: []byte(tf.term),
+	})
+}
+
+// openShardIndexes opens an element index per shard, each indexing the elements of every series
+// with one of the terms in turn.
+func openShardIndexes(tb testing.TB, shardNum, seriesNum, elementNum int) ([]*elementIndex, pbv1.SeriesList) {
+	var indexes []*elementIndex
+	for shard := 0; shard < shardNum; shard++ {
+		tst := openTestTable(tb)
+		var docs index.Documents
+		for sid := 1; sid <= seriesNum; sid++ {
+			for i := 0; i < elementNum; i++ {
+				docs = append(docs, index.Document{
+					DocID: uint64(shard*elementNum + i + 1),
+					Fields: []index.Field{{
+						Key:  index.FieldKey{IndexRuleID: fanOutRuleID, SeriesID: common.SeriesID(sid)},
+						Term: []byte("term" + strconv.Itoa(i%3)),
+					}},
+				})
+			}
+		}
+		require.NoError(tb, tst.Index().Write(docs))
+		indexes = append(indexes, tst.Index())
+	}
+	var seriesList pbv1.SeriesList
+	for sid := 1; sid <= seriesNum; sid++ {
+		seriesList = append(seriesList, &pbv1.Series{ID: common.SeriesID(sid)})
+	}
+	return indexes, seriesList
+}
+
+func TestSearchIndexes(t *testing.T) {
+	indexes, seriesList := openShardIndexes(t, 6, 4, 30)
+	tr := timestamp.NewInclusiveTimeRange(time.Unix(0, 0), time.Unix(0, 1000))
+	want, err := searchIndexes(context.Background(), indexes, seriesList, termFilter{term: "term1"}, &tr, 1)
+	require.NoError(t, err)
+	require.Len(t, want, len(indexes))
+	for i := range want {
+		require.Len(t, want[i], 4*10, "shard %d", i)
+	}
+	for _, parallelism := range []int{0, 2, 4, 16} {
+		got, errSearch := searchIndexes(context.Background(), indexes, seriesList, termFilter{term: "term1"}, &tr, parallelism)
+		require.NoError(t, errSearch)
+		require.Equal(t, want, got, "parallelism %d", parallelism)
+	}
+}
+
+func BenchmarkSearchIndexes(b *testing.B) {
+	indexes, seriesList := openShardIndexes(b, 8, 20, 500)
+	tr := timestamp.NewInclusiveTimeRange(time.Unix(0, 0), time.Unix(0, 1<<62))
+	for _, parallelism := range []int{1, 2, 4, 8} {
+		b.Run("parallelism-"+strconv.Itoa(parallelism), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := searchIndexes(context.Background(), indexes, seriesList, termFilter{term: "term1"}, &tr, parallelism); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
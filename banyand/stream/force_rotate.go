@@ -0,0 +1,59 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+package stream
+
+import (
+	"net/http"
+)
+
+// forceRotateAdminName names the admin endpoint forcing a group to rotate its segment.
+const forceRotateAdminName = "stream/rotate"
+
+// ForceRotate seals the current segment of the group and starts a new one from now.
+// The writes from now on go to the new segment.
+func (s *service) ForceRotate(group string) error {
+	db, err := s.schemaRepo.loadTSDB(group)
+	if err != nil {
+		return err
+	}
+	return db.ForceRotate()
+}
+
+// serveForceRotate forces the group named by the query parameter "group" to rotate its segment.
+func (s *service) serveForceRotate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	group := r.URL.Query().Get("group")
+	if group == "" {
+		http.Error(w, "the group is required", http.StatusBadRequest)
+		return
+	}
+	db, err := s.schemaRepo.loadTSDB(group)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err = db.ForceRotate(); err != nil {
+		s.l.Error().Err(err).Str("group", group).Msg("failed to force to rotate the segment")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,74 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+package stream_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/apache/skywalking-banyandb/banyand/internal/storage"
+	"github.com/apache/skywalking-banyandb/banyand/observability"
+	"github.com/apache/skywalking-banyandb/pkg/test/flags"
+)
+
+var _ = Describe("Force to rotate the segment", func() {
+	var svcs *services
+	var deferFn func()
+
+	BeforeEach(func() {
+		svcs, deferFn = setUp()
+		waitForStream(svcs)
+	})
+
+	AfterEach(func() {
+		deferFn()
+	})
+
+	rotate := func(method, group string) int {
+		rec := httptest.NewRecorder()
+		observability.AdminHandlers.ServeHTTP(rec, httptest.NewRequest(method, "/admin/stream/rotate?group="+group, nil))
+		return rec.Code
+	}
+
+	It("starts a new segment from now through the admin endpoint", func() {
+		writeElement(svcs, newWriteRequest("before", time.Now()))
+		var before storage.Status
+		Eventually(func() time.Time {
+			var err error
+			before, err = svcs.stream.RotationStatus(swMetadata.Group)
+			Expect(err).NotTo(HaveOccurred())
+			return before.WritableRange.Start
+		}).WithTimeout(flags.EventuallyTimeout).ShouldNot(BeZero())
+
+		Expect(rotate(http.MethodPost, swMetadata.Group)).To(Equal(http.StatusNoContent))
+		after, err := svcs.stream.RotationStatus(swMetadata.Group)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(after.WritableRange.Start).To(BeTemporally(">", before.WritableRange.Start))
+		Expect(after.WritableRange.Start).To(BeTemporally("~", time.Now(), time.Minute))
+	})
+
+	It("rejects the invalid requests", func() {
+		Expect(rotate(http.MethodGet, swMetadata.Group)).To(Equal(http.StatusMethodNotAllowed))
+		Expect(rotate(http.MethodPost, "")).To(Equal(http.StatusBadRequest))
+		Expect(rotate(http.MethodPost, "unknown")).To(Equal(http.StatusNotFound))
+		Expect(svcs.stream.ForceRotate("unknown")).NotTo(Succeed())
+	})
+})
@@ -0,0 +1,95 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+// ErrStopIteration is returned by the function passed to ForEachElement to stop the iteration without an error.
+var ErrStopIteration = errors.New("stop iteration")
+
+// Element is a single element visited by ForEachElement.
+// Every tag of TagFamilies holds exactly one value.
+type Element struct {
+	ElementID   string
+	TagFamilies []pbv1.TagFamily
+	Timestamp   int64
+	SID         common.SeriesID
+}
+
+// ForEachElement queries the elements of the series within the time range and calls fn for each of them.
+// The other query options, such as the projection and the filter, are taken from opts.
+// The query result is released once the iteration ends, even if fn stops it, fails or panics.
+func ForEachElement(ctx context.Context, s Stream, entity []*modelv1.TagValue, timeRange timestamp.TimeRange,
+	opts pbv1.StreamQueryOptions, fn func(Element) error,
+) error {
+	opts.Entities = [][]*modelv1.TagValue{entity}
+	opts.TimeRange = &timeRange
+	result, err := s.Query(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if result == nil {
+		return nil
+	}
+	defer result.Release()
+	for r := result.Pull(); r != nil; r = result.Pull() {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		for i := range r.Timestamps {
+			if err = fn(elementAt(r, i)); err != nil {
+				if errors.Is(err, ErrStopIteration) {
+					return nil
+				}
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func elementAt(r *pbv1.StreamResult, i int) Element {
+	e := Element{
+		ElementID:   r.ElementIDs[i],
+		Timestamp:   r.Timestamps[i],
+		SID:         r.SID,
+		TagFamilies: make([]pbv1.TagFamily, len(r.TagFamilies)),
+	}
+	for j, tf := range r.TagFamilies {
+		e.TagFamilies[j] = pbv1.TagFamily{
+			Name: tf.Name,
+			Tags: make([]pbv1.Tag, len(tf.Tags)),
+		}
+		for k, t := range tf.Tags {
+			e.TagFamilies[j].Tags[k] = pbv1.Tag{
+				Name:   t.Name,
+				Values: t.Values[i : i+1],
+			}
+		}
+	}
+	return e
+}
@@ -0,0 +1,123 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+type trackedResult struct {
+	results  []*pbv1.StreamResult
+	pulled   int
+	released int
+}
+
+func (tr *trackedResult) Pull() *pbv1.StreamResult {
+	if tr.pulled >= len(tr.results) {
+		return nil
+	}
+	tr.pulled++
+	return tr.results[tr.pulled-1]
+}
+
+func (tr *trackedResult) Release() {
+	tr.released++
+}
+
+type trackedStream struct {
+	Stream
+	result *trackedResult
+}
+
+func (ts *trackedStream) Query(_ context.Context, _ pbv1.StreamQueryOptions) (pbv1.StreamQueryResult, error) {
+	ts.result = &trackedResult{}
+	for _, ids := range [][]string{{"1", "2"}, {"3"}} {
+		r := &pbv1.StreamResult{TagFamilies: []pbv1.TagFamily{{Name: "default", Tags: []pbv1.Tag{{Name: "id"}}}}}
+		for _, id := range ids {
+			r.ElementIDs = append(r.ElementIDs, id)
+			r.Timestamps = append(r.Timestamps, int64(len(r.Timestamps)))
+			r.TagFamilies[0].Tags[0].Values = append(r.TagFamilies[0].Tags[0].Values, strTagValue(id))
+		}
+		ts.result.results = append(ts.result.results, r)
+	}
+	return ts.result, nil
+}
+
+func TestForEachElement(t *testing.T) {
+	tr := timestamp.NewInclusiveTimeRange(time.Unix(0, 0), time.Unix(0, 10))
+	forEach := func(ctx context.Context, s *trackedStream, fn func(Element) error) error {
+		return ForEachElement(ctx, s, []*modelv1.TagValue{strTagValue("entity")}, tr, pbv1.StreamQueryOptions{}, fn)
+	}
+
+	t.Run("all the elements are visited", func(t *testing.T) {
+		s := &trackedStream{}
+		var ids []string
+		require.NoError(t, forEach(context.Background(), s, func(e Element) error {
+			ids = append(ids, e.ElementID)
+			assert.Equal(t, e.ElementID, e.TagFamilies[0].Tags[0].Values[0].GetStr().GetValue())
+			return nil
+		}))
+		assert.Equal(t, []string{"1", "2", "3"}, ids)
+		assert.Equal(t, 1, s.result.released)
+	})
+
+	t.Run("an early return releases the result", func(t *testing.T) {
+		s := &trackedStream{}
+		var visited int
+		require.NoError(t, forEach(context.Background(), s, func(Element) error {
+			visited++
+			return ErrStopIteration
+		}))
+		assert.Equal(t, 1, visited)
+		assert.Equal(t, 1, s.result.pulled, "the rest of the results aren't pulled")
+		assert.Equal(t, 1, s.result.released)
+	})
+
+	t.Run("an error releases the result", func(t *testing.T) {
+		s := &trackedStream{}
+		errVisit := errors.New("visit")
+		assert.ErrorIs(t, forEach(context.Background(), s, func(Element) error { return errVisit }), errVisit)
+		assert.Equal(t, 1, s.result.released)
+	})
+
+	t.Run("a panic releases the result", func(t *testing.T) {
+		s := &trackedStream{}
+		assert.Panics(t, func() {
+			_ = forEach(context.Background(), s, func(Element) error { panic("visit") })
+		})
+		assert.Equal(t, 1, s.result.released)
+	})
+
+	t.Run("a canceled context releases the result", func(t *testing.T) {
+		s := &trackedStream{}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.ErrorIs(t, forEach(ctx, s, func(Element) error { return nil }), context.Canceled)
+		assert.Equal(t, 1, s.result.released)
+	})
+}
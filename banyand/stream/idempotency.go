@@ -0,0 +1,204 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/run"
+)
+
+const (
+	idempotencyKeysFilename        = "idempotency-keys.json"
+	defaultIdempotencyWindow       = 10 * time.Minute
+	defaultIdempotencyMaxKeys      = 100000
+	idempotencyKeysPersistInterval = time.Second
+)
+
+type idempotencyEntry struct {
+	Key      string `json:"key"`
+	ExpireAt int64  `json:"expire_at"`
+}
+
+// idempotencyCache remembers the idempotency keys of the accepted writes for a window.
+// Keys are evicted in the order they are added, either when they expire or when
+// the cache holds more than maxKeys. The live keys are saved to a file periodically
+// and loaded on start, so that a retry still hits the cache after a brief restart.
+type idempotencyCache struct {
+	l        *logger.Logger
+	closer   *run.Closer
+	entries  map[string]int64
+	path     string
+	queue    []idempotencyEntry
+	window   time.Duration
+	maxKeys  int
+	mu       sync.Mutex
+	modified bool
+}
+
+func newIdempotencyCache(root string, window time.Duration, maxKeys int, l *logger.Logger) *idempotencyCache {
+	c := &idempotencyCache{
+		l:       l,
+		path:    filepath.Join(root, idempotencyKeysFilename),
+		entries: make(map[string]int64),
+		window:  window,
+		maxKeys: maxKeys,
+	}
+	if !c.enabled() {
+		return c
+	}
+	c.closer = run.NewCloser(1)
+	if err := c.load(time.Now()); err != nil {
+		l.Warn().Err(err).Str("path", c.path).Msg("cannot load the idempotency keys")
+	}
+	go c.persistLoop()
+	return c
+}
+
+func (c *idempotencyCache) enabled() bool {
+	return c.window > 0 && c.maxKeys > 0
+}
+
+// contains reports whether the key was accepted within the window.
+func (c *idempotencyCache) contains(key string, now time.Time) bool {
+	if !c.enabled() {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expireAt, ok := c.entries[key]
+	return ok && expireAt > now.UnixNano()
+}
+
+// add records the keys of the accepted writes.
+func (c *idempotencyCache) add(keys []string, now time.Time) {
+	if !c.enabled() || len(keys) < 1 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expireAt := now.Add(c.window).UnixNano()
+	for _, k := range keys {
+		c.entries[k] = expireAt
+		c.queue = append(c.queue, idempotencyEntry{Key: k, ExpireAt: expireAt})
+	}
+	c.evict(now.UnixNano())
+	c.modified = true
+}
+
+func (c *idempotencyCache) evict(now int64) {
+	var n int
+	for ; n < len(c.queue); n++ {
+		e := c.queue[n]
+		if e.ExpireAt > now && len(c.entries) <= c.maxKeys {
+			break
+		}
+		// The key was added again later, so a newer entry in the queue owns it.
+		if c.entries[e.Key] == e.ExpireAt {
+			delete(c.entries, e.Key)
+		}
+	}
+	if n == 0 {
+		return
+	}
+	copy(c.queue, c.queue[n:])
+	c.queue = c.queue[:len(c.queue)-n]
+}
+
+func (c *idempotencyCache) load(now time.Time) error {
+	data, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var queue []idempotencyEntry
+	if err = json.Unmarshal(data, &queue); err != nil {
+		return fmt.Errorf("cannot parse %s: %w", c.path, err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range queue {
+		c.entries[e.Key] = e.ExpireAt
+	}
+	c.queue = queue
+	c.evict(now.UnixNano())
+	return nil
+}
+
+func (c *idempotencyCache) persist() error {
+	c.mu.Lock()
+	if !c.modified {
+		c.mu.Unlock()
+		return nil
+	}
+	queue := make([]idempotencyEntry, 0, len(c.entries))
+	for _, e := range c.queue {
+		if c.entries[e.Key] == e.ExpireAt {
+			queue = append(queue, e)
+		}
+	}
+	c.modified = false
+	c.mu.Unlock()
+	data, err := json.Marshal(queue)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+func (c *idempotencyCache) persistLoop() {
+	defer c.closer.Done()
+	ticker := time.NewTicker(idempotencyKeysPersistInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.closer.CloseNotify():
+			return
+		case <-ticker.C:
+			if err := c.persist(); err != nil {
+				c.l.Warn().Err(err).Str("path", c.path).Msg("cannot persist the idempotency keys")
+			}
+		}
+	}
+}
+
+func (c *idempotencyCache) close() {
+	c.closer.CloseThenWait()
+	if !c.enabled() {
+		return
+	}
+	if err := c.persist(); err != nil {
+		c.l.Warn().Err(err).Str("path", c.path).Msg("cannot persist the idempotency keys")
+	}
+}
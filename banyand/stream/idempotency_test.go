@@ -0,0 +1,57 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/test"
+)
+
+func TestIdempotencyCache(t *testing.T) {
+	tmpPath, defFn := test.Space(require.New(t))
+	defer defFn()
+	now := time.Now()
+	c := newIdempotencyCache(tmpPath, time.Minute, 2, logger.GetLogger("test"))
+	c.add([]string{"a", "b"}, now)
+	assert.True(t, c.contains("a", now))
+	assert.True(t, c.contains("b", now))
+	assert.False(t, c.contains("a", now.Add(time.Minute)))
+
+	c.add([]string{"c"}, now.Add(time.Second))
+	assert.False(t, c.contains("a", now), "the oldest key should be evicted beyond the bound")
+	assert.True(t, c.contains("b", now))
+	assert.True(t, c.contains("c", now))
+	c.close()
+
+	reopened := newIdempotencyCache(tmpPath, time.Minute, 2, logger.GetLogger("test"))
+	defer reopened.close()
+	assert.False(t, reopened.contains("a", now))
+	assert.True(t, reopened.contains("b", now))
+	assert.True(t, reopened.contains("c", now))
+
+	disabled := newIdempotencyCache(tmpPath, 0, 2, logger.GetLogger("test"))
+	defer disabled.close()
+	disabled.add([]string{"d"}, now)
+	assert.False(t, disabled.contains("d", now))
+}
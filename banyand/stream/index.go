@@ -26,6 +26,7 @@ import (
 	"github.com/apache/skywalking-banyandb/api/common"
 	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
 	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/banyand/internal/storage"
 	"github.com/apache/skywalking-banyandb/pkg/index"
 	"github.com/apache/skywalking-banyandb/pkg/index/inverted"
 	"github.com/apache/skywalking-banyandb/pkg/index/posting/roaring"
@@ -34,6 +35,8 @@ import (
 	"github.com/apache/skywalking-banyandb/pkg/timestamp"
 )
 
+const seekRangePreloadSize = 1000
+
 type elementIndex struct {
 	store index.Store
 	l     *logger.Logger
@@ -75,7 +78,9 @@ func (e *elementIndex) Write(docs index.Documents) error {
 	return nil
 }
 
-func (e *elementIndex) Search(_ context.Context, seriesList pbv1.SeriesList, filter index.Filter, timeRange *timestamp.TimeRange) ([]elementRef, error) {
+func (e *elementIndex) Search(ctx context.Context, seriesList pbv1.SeriesList, filter index.Filter, timeRange *timestamp.TimeRange) ([]elementRef, error) {
+	account := storage.ReadAccountFrom(ctx)
+	minTimestamp, maxTimestamp := timeRange.UnixNanoBounds()
 	pm := make(map[common.SeriesID][]uint64)
 	for _, series := range seriesList {
 		pl, err := filter.Execute(func(_ databasev1.IndexRule_Type) (index.Searcher, error) {
@@ -90,11 +95,15 @@ func (e *elementIndex) Search(_ context.Context, seriesList pbv1.SeriesList, fil
 		if pl.IsEmpty() {
 			continue
 		}
+		// The index reads are accounted by the size of the postings they return.
+		if err = account.Charge(uint64(pl.Len()) * 8); err != nil {
+			return nil, err
+		}
 		timestamps := pl.ToSlice()
 		sort.Slice(timestamps, func(i, j int) bool {
 			return timestamps[i] < timestamps[j]
 		})
-		start, end, ok := timestamp.FindRange(timestamps, uint64(timeRange.Start.UnixNano()), uint64(timeRange.End.UnixNano()))
+		start, end, ok := timestamp.FindRange(timestamps, uint64(minTimestamp), uint64(maxTimestamp))
 		if !ok {
 			pm[series.ID] = []uint64{}
 		} else {
@@ -104,6 +113,27 @@ func (e *elementIndex) Search(_ context.Context, seriesList pbv1.SeriesList, fil
 	return merge(pm), nil
 }
 
+// SeekRange returns the elements whose terms of the field fall in the range, in the order of the terms.
+// A field key without a series scans the terms of every series in the index.
+// Either bound can be nil to leave the range open on that side.
+func (e *elementIndex) SeekRange(fieldKey index.FieldKey, lower, upper []byte, inclusive bool) ([]elementRef, error) {
+	iter, err := e.store.Iterator(fieldKey, index.RangeOpts{
+		Lower:         lower,
+		Upper:         upper,
+		IncludesLower: inclusive,
+		IncludesUpper: inclusive,
+	}, modelv1.Sort_SORT_ASC, seekRangePreloadSize)
+	if err != nil {
+		return nil, err
+	}
+	var result []elementRef
+	for iter.Next() {
+		ts, sid := iter.Val()
+		result = append(result, elementRef{seriesID: sid, timestamp: int64(ts)})
+	}
+	return result, iter.Close()
+}
+
 func (e *elementIndex) Close() error {
 	return e.store.Close()
 }
@@ -122,7 +152,11 @@ type priorityQueue []*indexedElementRef
 
 func (pq priorityQueue) Len() int { return len(pq) }
 
+// Less orders the references sharing a timestamp by their series, so that a search returns them in the same order every time.
 func (pq priorityQueue) Less(i, j int) bool {
+	if pq[i].timestamp == pq[j].timestamp {
+		return pq[i].seriesID < pq[j].seriesID
+	}
 	return pq[i].timestamp < pq[j].timestamp
 }
 
@@ -0,0 +1,119 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/pkg/convert"
+	"github.com/apache/skywalking-banyandb/pkg/index"
+)
+
+func TestElementIndexSeekRange(t *testing.T) {
+	tst := openTestTable(t)
+	const ruleID = 10
+	// latency_bucket of the elements, keyed by their series and timestamps.
+	seeds := []struct {
+		seriesID  common.SeriesID
+		timestamp int64
+		bucket    int64
+	}{
+		{seriesID: 1, timestamp: 1, bucket: 50},
+		{seriesID: 1, timestamp: 2, bucket: 300},
+		{seriesID: 2, timestamp: 3, bucket: 100},
+		{seriesID: 2, timestamp: 4, bucket: 200},
+		{seriesID: 3, timestamp: 5, bucket: 400},
+		{seriesID: 3, timestamp: 6, bucket: 150},
+	}
+	var docs index.Documents
+	for _, s := range seeds {
+		docs = append(docs, index.Document{
+			DocID: uint64(s.timestamp),
+			Fields: []index.Field{{
+				Key:  index.FieldKey{IndexRuleID: ruleID, SeriesID: s.seriesID},
+				Term: convert.Int64ToBytes(s.bucket),
+			}},
+		})
+	}
+	require.NoError(t, tst.Index().Write(docs))
+
+	tests := []struct {
+		name      string
+		fieldKey  index.FieldKey
+		lower     []byte
+		upper     []byte
+		want      []elementRef
+		inclusive bool
+	}{
+		{
+			name:      "inclusive",
+			fieldKey:  index.FieldKey{IndexRuleID: ruleID},
+			lower:     convert.Int64ToBytes(100),
+			upper:     convert.Int64ToBytes(300),
+			inclusive: true,
+			want:      []elementRef{{seriesID: 2, timestamp: 3}, {seriesID: 3, timestamp: 6}, {seriesID: 2, timestamp: 4}, {seriesID: 1, timestamp: 2}},
+		},
+		{
+			name:     "exclusive",
+			fieldKey: index.FieldKey{IndexRuleID: ruleID},
+			lower:    convert.Int64ToBytes(100),
+			upper:    convert.Int64ToBytes(300),
+			want:     []elementRef{{seriesID: 3, timestamp: 6}, {seriesID: 2, timestamp: 4}},
+		},
+		{
+			name:      "open lower bound",
+			fieldKey:  index.FieldKey{IndexRuleID: ruleID},
+			upper:     convert.Int64ToBytes(100),
+			inclusive: true,
+			want:      []elementRef{{seriesID: 1, timestamp: 1}, {seriesID: 2, timestamp: 3}},
+		},
+		{
+			name:      "a single series",
+			fieldKey:  index.FieldKey{IndexRuleID: ruleID, SeriesID: 3},
+			lower:     convert.Int64ToBytes(100),
+			upper:     convert.Int64ToBytes(500),
+			inclusive: true,
+			want:      []elementRef{{seriesID: 3, timestamp: 6}, {seriesID: 3, timestamp: 5}},
+		},
+		{
+			name:      "no term in the range",
+			fieldKey:  index.FieldKey{IndexRuleID: ruleID},
+			lower:     convert.Int64ToBytes(500),
+			upper:     convert.Int64ToBytes(1000),
+			inclusive: true,
+		},
+		{
+			name:      "inverted range",
+			fieldKey:  index.FieldKey{IndexRuleID: ruleID},
+			lower:     convert.Int64ToBytes(300),
+			upper:     convert.Int64ToBytes(100),
+			inclusive: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tst.Index().SeekRange(tt.fieldKey, tt.lower, tt.upper, tt.inclusive)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -78,10 +78,11 @@ func releaseFlusherIntroduction(i *flusherIntroduction) {
 }
 
 type mergerIntroduction struct {
-	merged  map[uint64]struct{}
-	newPart *partWrapper
-	applied chan struct{}
-	creator snapshotCreator
+	merged      map[uint64]struct{}
+	newPart     *partWrapper
+	applied     chan struct{}
+	creator     snapshotCreator
+	quarantined bool
 }
 
 func (i *mergerIntroduction) reset() {
@@ -91,6 +92,7 @@ func (i *mergerIntroduction) reset() {
 	i.newPart = nil
 	i.applied = nil
 	i.creator = 0
+	i.quarantined = false
 }
 
 var mergerIntroductionPool = sync.Pool{}
@@ -174,8 +176,17 @@ func (tst *tsTable) introduceMerged(nextIntroduction *mergerIntroduction, epoch
 		return
 	}
 	defer cur.decRef()
+	if nextIntroduction.quarantined {
+		for _, pw := range cur.parts {
+			if _, ok := nextIntroduction.merged[pw.ID()]; ok {
+				pw.quarantined.Store(true)
+			}
+		}
+	}
 	nextSnp := cur.remove(epoch, nextIntroduction.merged)
-	nextSnp.parts = append(nextSnp.parts, nextIntroduction.newPart)
+	if nextIntroduction.newPart != nil {
+		nextSnp.parts = append(nextSnp.parts, nextIntroduction.newPart)
+	}
 	nextSnp.creator = nextIntroduction.creator
 	tst.replaceSnapshot(&nextSnp)
 	tst.persistSnapshot(&nextSnp)
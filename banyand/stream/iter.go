@@ -20,11 +20,13 @@ package stream
 import (
 	"errors"
 	"io"
+	"slices"
 
 	"go.uber.org/multierr"
 
 	"github.com/apache/skywalking-banyandb/api/common"
 	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	"github.com/apache/skywalking-banyandb/banyand/internal/storage"
 	"github.com/apache/skywalking-banyandb/pkg/index"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
 	"github.com/apache/skywalking-banyandb/pkg/partition"
@@ -35,15 +37,19 @@ type searcherIterator struct {
 	fieldIterator     index.FieldIterator
 	err               error
 	tagSpecIndex      map[string]*databasev1.TagSpec
+	redactedTags      map[string]struct{}
+	schema            *schemaResolver
 	timeFilter        filterFn
 	table             *tsTable
 	l                 *logger.Logger
+	account           *storage.ReadAccount
 	indexFilter       map[common.SeriesID]filterFn
 	tagProjIndex      map[string]partition.TagLocator
 	sidToIndex        map[common.SeriesID]int
 	entityMap         map[string]int
 	tagProjection     []pbv1.TagProjection
 	seriesList        pbv1.SeriesList
+	thenByLocations   []tagLocation
 	currItem          item
 	sortedTagLocation tagLocation
 }
@@ -87,15 +93,29 @@ func (s *searcherIterator) Next() bool {
 			return s.Next()
 		}
 	}
-	e, c, err := s.table.getElement(seriesID, int64(itemID), s.tagProjection)
+	tagProjection := s.tagProjection
+	if s.schema != nil {
+		tagProjection = append(slices.Clip(tagProjection), schemaRevisionProjection)
+	}
+	e, c, err := s.table.getElement(seriesID, int64(itemID), tagProjection, s.account)
 	if err != nil {
 		s.err = err
 		return false
 	}
+	if s.schema != nil {
+		last := len(e.tagFamilies) - 1
+		revisions := decodeSchemaRevisions(&e.tagFamilies[last].tags[0])
+		e.tagFamilies = e.tagFamilies[:last]
+		for _, tf := range e.tagFamilies {
+			for i := range tf.tags {
+				s.schema.resolve(&tf.tags[i], revisions)
+			}
+		}
+	}
 	if len(s.tagProjIndex) != 0 {
 		for entity, offset := range s.tagProjIndex {
 			tagSpec := s.tagSpecIndex[entity]
-			if tagSpec.IndexedOnly {
+			if _, redacted := s.redactedTags[entity]; redacted || tagSpec.IndexedOnly {
 				continue
 			}
 			index, ok := s.sidToIndex[seriesID]
@@ -116,10 +136,29 @@ func (s *searcherIterator) Next() bool {
 		s.err = err
 		return false
 	}
+	var thenByValues [][]byte
+	if len(s.thenByLocations) > 0 {
+		thenByValues = make([][]byte, len(s.thenByLocations))
+		for i := range s.thenByLocations {
+			if thenByValues[i], err = s.thenByLocations[i].getTagValue(e); err != nil {
+				s.err = err
+				return false
+			}
+		}
+	}
+	// The redacted tags are sorted, but their values are dropped from the element.
+	for _, tf := range e.tagFamilies {
+		for i := range tf.tags {
+			if _, redacted := s.redactedTags[tf.tags[i].name]; redacted {
+				tf.tags[i] = tag{}
+			}
+		}
+	}
 	s.currItem = item{
 		element:        e,
 		count:          c,
 		sortedTagValue: sv,
+		thenByValues:   thenByValues,
 		seriesID:       seriesID,
 	}
 	return true
@@ -129,6 +168,16 @@ func (s *searcherIterator) Val() item {
 	return s.currItem
 }
 
+// Timestamp returns the timestamp the current element is written with.
+func (s *searcherIterator) Timestamp() int64 {
+	return s.currItem.element.timestamp
+}
+
+// ElementID returns the ID the current element is written with.
+func (s *searcherIterator) ElementID() string {
+	return s.currItem.element.elementID
+}
+
 func (s *searcherIterator) Close() error {
 	if errors.Is(s.err, io.EOF) {
 		return s.fieldIterator.Close()
@@ -139,6 +188,7 @@ func (s *searcherIterator) Close() error {
 type item struct {
 	element        *element
 	sortedTagValue []byte
+	thenByValues   [][]byte
 	count          int
 	seriesID       common.SeriesID
 }
@@ -146,3 +196,11 @@ type item struct {
 func (i item) SortedField() []byte {
 	return i.sortedTagValue
 }
+
+// SortKeys breaks the ties of the sorted tag by the then-by tags, then by the element ID.
+func (i item) SortKeys() [][]byte {
+	keys := make([][]byte, 0, len(i.thenByValues)+2)
+	keys = append(keys, i.sortedTagValue)
+	keys = append(keys, i.thenByValues...)
+	return append(keys, pbv1.ElementIDSortKey(i.element.elementID))
+}
@@ -31,7 +31,7 @@ import (
 type filterFn func(itemID uint64) bool
 
 func (s *stream) buildSeriesByIndex(tableWrappers []storage.TSTableWrapper[*tsTable],
-	seriesList pbv1.SeriesList, sqo pbv1.StreamQueryOptions,
+	seriesList pbv1.SeriesList, sqo pbv1.StreamQueryOptions, redactedTags map[string]struct{}, account *storage.ReadAccount,
 ) (series []*searcherIterator, err error) {
 	timeFilter := func(itemID uint64) bool {
 		return sqo.TimeRange.Contains(int64(itemID))
@@ -41,17 +41,21 @@ func (s *stream) buildSeriesByIndex(tableWrappers []storage.TSTableWrapper[*tsTa
 		return nil, fmt.Errorf("only support one tag for sorting, but got %d", len(indexRuleForSorting.Tags))
 	}
 	sortedTag := indexRuleForSorting.Tags[0]
-	tl := newTagLocation()
-	for i := range sqo.TagProjection {
-		for j := range sqo.TagProjection[i].Names {
-			if sqo.TagProjection[i].Names[j] == sortedTag {
-				tl.familyIndex, tl.tagIndex = i, j
-			}
-		}
-	}
+	schema := s.schemaResolver()
+	tl := locateTag(sqo.TagProjection, sortedTag)
 	if !tl.valid() {
 		return nil, fmt.Errorf("sorted tag %s not found in tag projection", sortedTag)
 	}
+	thenByLocations := make([]tagLocation, len(sqo.ThenBy))
+	for i, o := range sqo.ThenBy {
+		if len(o.Index.GetTags()) != 1 {
+			return nil, fmt.Errorf("only support one tag for sorting, but got %d", len(o.Index.GetTags()))
+		}
+		thenByLocations[i] = locateTag(sqo.TagProjection, o.Index.Tags[0])
+		if !thenByLocations[i].valid() {
+			return nil, fmt.Errorf("sorted tag %s not found in tag projection", o.Index.Tags[0])
+		}
+	}
 	entityMap, tagSpecIndex, tagProjIndex, sidToIndex := s.genIndex(sqo.TagProjection, seriesList)
 	sids := seriesList.IDs()
 	for _, tw := range tableWrappers {
@@ -79,15 +83,24 @@ func (s *stream) buildSeriesByIndex(tableWrappers []storage.TSTableWrapper[*tsTa
 			IndexRuleID: indexRuleForSorting.GetMetadata().GetId(),
 			Analyzer:    indexRuleForSorting.GetAnalyzer(),
 		}
-		inner, err = tw.Table().Index().Sort(sids, fieldKey, sqo.Order.Sort, sqo.MaxElementSize)
+		preLoadSize := sqo.MaxElementSize
+		if preLoadSize <= 0 {
+			preLoadSize = sortBatchSize
+		}
+		inner, err = tw.Table().Index().Sort(sids, fieldKey, sqo.Order.Sort, preLoadSize)
 		if err != nil {
 			return nil, err
 		}
 
 		if inner != nil {
-			series = append(series, newSearcherIterator(s.l, inner, tw.Table(),
+			si := newSearcherIterator(s.l, inner, tw.Table(),
 				seriesFilter, timeFilter, sqo.TagProjection, tl,
-				tagSpecIndex, tagProjIndex, sidToIndex, seriesList, entityMap))
+				tagSpecIndex, tagProjIndex, sidToIndex, seriesList, entityMap)
+			si.account = account
+			si.redactedTags = redactedTags
+			si.schema = schema
+			si.thenByLocations = thenByLocations
+			series = append(series, si)
 		}
 	}
 	return
@@ -105,6 +118,18 @@ func newTagLocation() tagLocation {
 	}
 }
 
+func locateTag(tagProjection []pbv1.TagProjection, name string) tagLocation {
+	tl := newTagLocation()
+	for i := range tagProjection {
+		for j := range tagProjection[i].Names {
+			if tagProjection[i].Names[j] == name {
+				tl.familyIndex, tl.tagIndex = i, j
+			}
+		}
+	}
+	return tl
+}
+
 func (t tagLocation) valid() bool {
 	return t.familyIndex != -1 && t.tagIndex != -1
 }
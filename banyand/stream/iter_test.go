@@ -0,0 +1,67 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+)
+
+// itemIDIterator returns the timestamps of the elements of a series as the index does.
+type itemIDIterator struct {
+	itemIDs  []uint64
+	seriesID common.SeriesID
+	index    int
+}
+
+func (i *itemIDIterator) Next() bool {
+	i.index++
+	return i.index <= len(i.itemIDs)
+}
+
+func (i *itemIDIterator) Val() (uint64, common.SeriesID) {
+	return i.itemIDs[i.index-1], i.seriesID
+}
+
+func (i *itemIDIterator) Close() error {
+	return nil
+}
+
+func TestSearcherIteratorElement(t *testing.T) {
+	tst := openTestTable(t, esTS1, esTS2)
+	projection := []pbv1.TagProjection{{Family: "singleTag", Names: []string{"strTag"}}}
+	it := newSearcherIterator(logger.GetLogger("test"), &itemIDIterator{itemIDs: []uint64{1, 2}, seriesID: 1}, tst,
+		nil, func(uint64) bool { return true }, projection, locateTag(projection, "strTag"),
+		nil, nil, nil, nil, nil)
+	var timestamps []int64
+	var elementIDs []string
+	for it.Next() {
+		assert.Equal(t, it.Val().element.timestamp, it.Timestamp())
+		timestamps = append(timestamps, it.Timestamp())
+		elementIDs = append(elementIDs, it.ElementID())
+	}
+	require.NoError(t, it.Close())
+	assert.Equal(t, []int64{1, 2}, timestamps, "the timestamps are the ones the elements are written with")
+	assert.Equal(t, []string{"11", "12"}, elementIDs)
+}
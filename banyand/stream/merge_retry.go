@@ -0,0 +1,145 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/apache/skywalking-banyandb/banyand/observability"
+	"github.com/apache/skywalking-banyandb/pkg/meter"
+)
+
+const (
+	defaultMergeMaxRetries   = 3
+	defaultMergeRetryBackoff = time.Second
+	maxMergeRetryBackoffExp  = 6
+)
+
+// errPartCorrupted means a source part of a merge can't be decoded, which fails every merge of the part.
+var errPartCorrupted = errors.New("the part is corrupted")
+
+var mergeQuarantinedParts = sync.OnceValue(func() meter.Counter {
+	return observability.NewCounter(observability.NewMeterProviders(observability.RootScope.SubScope("stream")),
+		"merge_quarantined_parts", "group")
+})
+
+// isDeterministicMergeError reports whether the merge fails again with the same parts.
+func isDeterministicMergeError(err error) bool {
+	return errors.Is(err, errPartCorrupted) || errors.Is(err, errMergeVerification)
+}
+
+// mergeFailed records a failed merge of the parts and reports whether they have used up their retries.
+// The failures are only tracked by the merge loop, so they aren't guarded.
+func (tst *tsTable) mergeFailed(pws []*partWrapper) bool {
+	if tst.mergeFailures == nil {
+		tst.mergeFailures = make(map[uint64]int)
+	}
+	tst.mergeAttempts++
+	exhausted := false
+	for _, pw := range pws {
+		tst.mergeFailures[pw.ID()]++
+		if tst.option.mergeMaxRetries >= 0 && tst.mergeFailures[pw.ID()] > tst.option.mergeMaxRetries {
+			exhausted = true
+		}
+	}
+	return exhausted
+}
+
+// forgetMergeFailures forgets the failures of the parts merged or quarantined.
+func (tst *tsTable) forgetMergeFailures(pws []*partWrapper) {
+	tst.mergeAttempts = 0
+	for _, pw := range pws {
+		delete(tst.mergeFailures, pw.ID())
+	}
+}
+
+// waitMergeRetry backs off exponentially with the consecutive failed merges.
+// It returns false if the table is closed in the meantime.
+func (tst *tsTable) waitMergeRetry() bool {
+	backoff := tst.option.mergeRetryBackoff
+	if backoff <= 0 {
+		return true
+	}
+	backoff <<= min(tst.mergeAttempts-1, maxMergeRetryBackoffExp)
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-tst.loopCloser.CloseNotify():
+		return false
+	}
+}
+
+// bisectMerge merges each half of the parts failing every retry on its own, splitting the failing halves further.
+// Only the single parts which still fail are quarantined, so the healthy parts merged with them are kept.
+func (tst *tsTable) bisectMerge(pws []*partWrapper, merges chan *mergerIntroduction) error {
+	if len(pws) == 1 {
+		return tst.quarantineMergeParts(pws, merges)
+	}
+	mid := len(pws) / 2
+	for _, half := range [][]*partWrapper{pws[:mid], pws[mid:]} {
+		toBeMerged := make(map[uint64]struct{}, len(half))
+		for _, pw := range half {
+			toBeMerged[pw.ID()] = struct{}{}
+		}
+		_, err := tst.mergePartsThenSendIntroduction(snapshotCreatorMerger, half, toBeMerged, merges, tst.loopCloser.CloseNotify())
+		if err == nil {
+			continue
+		}
+		if !isDeterministicMergeError(err) {
+			return err
+		}
+		if err = tst.bisectMerge(half, merges); err != nil {
+			return err
+		}
+	}
+	tst.forgetMergeFailures(pws)
+	return nil
+}
+
+// quarantineMergeParts removes the corrupted parts from the table and moves them aside,
+// so that the merger carries on with the other parts.
+func (tst *tsTable) quarantineMergeParts(pws []*partWrapper, merges chan *mergerIntroduction) error {
+	tst.l.Error().Int("parts", len(pws)).Int("maxRetries", tst.option.mergeMaxRetries).
+		Msg("the parts are corrupted, quarantine them")
+	mi := generateMergerIntroduction()
+	defer releaseMergerIntroduction(mi)
+	mi.creator = snapshotCreatorMerger
+	mi.merged = make(map[uint64]struct{}, len(pws))
+	for _, pw := range pws {
+		mi.merged[pw.ID()] = struct{}{}
+	}
+	mi.quarantined = true
+	mi.applied = make(chan struct{})
+	select {
+	case merges <- mi:
+	case <-tst.loopCloser.CloseNotify():
+		return errClosed
+	}
+	select {
+	case <-mi.applied:
+	case <-tst.loopCloser.CloseNotify():
+		return errClosed
+	}
+	mergeQuarantinedParts().Inc(float64(len(pws)), tst.p.Database)
+	tst.forgetMergeFailures(pws)
+	return nil
+}
@@ -0,0 +1,140 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/pkg/fs"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/test"
+	"github.com/apache/skywalking-banyandb/pkg/test/flags"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+// openMergeRetryTable opens a table whose background merges of the file parts fail with the error of the check,
+// while the flushed memory parts are merged as usual.
+func openMergeRetryTable(t *testing.T, check func(parts []*partWrapper) error) (*tsTable, string) {
+	tmpPath, defFn := test.Space(require.New(t))
+	t.Cleanup(defFn)
+	tst, err := newTSTable(fs.NewLocalFileSystem(), tmpPath, common.Position{}, logger.GetLogger("test"), timestamp.TimeRange{},
+		option{
+			flushTimeout:      0,
+			mergePolicy:       newDefaultMergePolicyForTesting(),
+			mergeMaxRetries:   2,
+			mergeRetryBackoff: time.Millisecond,
+			partMerger: func(fileSystem fs.FileSystem, closeCh <-chan struct{}, parts []*partWrapper, partID uint64, root string,
+				clusteringKey string, compressThreshold int,
+			) (*partWrapper, error) {
+				if parts[0].mp == nil {
+					if err := check(parts); err != nil {
+						return nil, err
+					}
+				}
+				return mergeParts(fileSystem, closeCh, parts, partID, root, clusteringKey, compressThreshold)
+			},
+		})
+	require.NoError(t, err)
+	t.Cleanup(func() { tst.Close() })
+	return tst, tmpPath
+}
+
+// addMergedParts adds two parts of the same size, which are merged by the default policy.
+func addMergedParts(tst *tsTable) {
+	for _, es := range []*elements{esTS1, esTS1} {
+		tst.mustAddElements(es)
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func tableParts(tst *tsTable) (ids []uint64, creator snapshotCreator) {
+	s := tst.currentSnapshot()
+	if s == nil {
+		return nil, 0
+	}
+	defer s.decRef()
+	for _, pw := range s.parts {
+		ids = append(ids, pw.ID())
+	}
+	return ids, s.creator
+}
+
+func quarantinedParts(root string) []string {
+	var names []string
+	entries, _ := os.ReadDir(filepath.Join(root, quarantineDirname))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func Test_tsTable_retryTransientMergeFailures(t *testing.T) {
+	var failures atomic.Int32
+	tst, root := openMergeRetryTable(t, func([]*partWrapper) error {
+		if failures.Add(1) <= 5 {
+			return errors.New("injected transient failure")
+		}
+		return nil
+	})
+
+	addMergedParts(tst)
+	assert.Eventually(t, func() bool {
+		ids, creator := tableParts(tst)
+		return len(ids) == 1 && creator == snapshotCreatorMerger
+	}, flags.EventuallyTimeout, 10*time.Millisecond, "the merge succeeds once the failure goes away")
+	assert.Greater(t, failures.Load(), int32(5), "the transient failures are retried beyond the max retries")
+	assert.Empty(t, quarantinedParts(root))
+}
+
+func Test_tsTable_quarantineCorruptedParts(t *testing.T) {
+	var corrupted atomic.Uint64
+	var failures atomic.Int32
+	tst, root := openMergeRetryTable(t, func(parts []*partWrapper) error {
+		// The first file part merged is the corrupted one.
+		corrupted.CompareAndSwap(0, parts[0].ID())
+		for _, pw := range parts {
+			if pw.ID() == corrupted.Load() {
+				failures.Add(1)
+				return fmt.Errorf("%w: injected decode failure", errPartCorrupted)
+			}
+		}
+		return nil
+	})
+
+	addMergedParts(tst)
+	assert.Eventually(t, func() bool {
+		return len(quarantinedParts(root)) == 1
+	}, flags.EventuallyTimeout, 10*time.Millisecond, "only the corrupted part is quarantined")
+	assert.Equal(t, []string{partName(corrupted.Load())}, quarantinedParts(root))
+	// The merge is retried twice, and the corrupted half fails once more when it's bisected.
+	assert.Equal(t, int32(4), failures.Load())
+	assert.Eventually(t, func() bool {
+		ids, creator := tableParts(tst)
+		return len(ids) == 1 && ids[0] != corrupted.Load() && creator == snapshotCreatorMerger
+	}, flags.EventuallyTimeout, 10*time.Millisecond, "the healthy part is kept")
+}
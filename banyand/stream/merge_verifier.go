@@ -0,0 +1,119 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/pkg/convert"
+	"github.com/apache/skywalking-banyandb/pkg/encoding"
+	"github.com/apache/skywalking-banyandb/pkg/fs"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+const quarantineDirname = "quarantine"
+
+var errMergeVerification = fmt.Errorf("the merged part doesn't hold the rows of its source parts")
+
+// rowSetDigest is an order-independent digest of a multiset of rows.
+// Losing, duplicating or altering a row changes it with a high probability.
+type rowSetDigest struct {
+	count uint64
+	sum   uint64
+	sumSq uint64
+}
+
+func (d *rowSetDigest) add(sid common.SeriesID, elementID string, ts int64, buf []byte) []byte {
+	buf = encoding.Uint64ToBytes(buf[:0], uint64(sid))
+	buf = encoding.Int64ToBytes(buf, ts)
+	buf = append(buf, elementID...)
+	h := convert.Hash(buf)
+	d.count++
+	d.sum += h
+	d.sumSq += h * h
+	return buf
+}
+
+func (d *rowSetDigest) merge(other rowSetDigest) {
+	d.count += other.count
+	d.sum += other.sum
+	d.sumSq += other.sumSq
+}
+
+// digestPart streams all the rows of a part. The part's sequential readers are consumed,
+// so a file part is reopened to leave the original one untouched.
+func digestPart(pw *partWrapper) rowSetDigest {
+	p := pw.p
+	if pw.mp == nil {
+		p = mustOpenFilePart(pw.ID(), filepath.Dir(pw.p.path), pw.p.fileSystem)
+		defer p.close()
+	}
+	pmi := generatePartMergeIter()
+	defer releasePartMergeIter(pmi)
+	pmi.mustInitFromPart(p)
+	decoder := generateColumnValuesDecoder()
+	defer releaseColumnValuesDecoder(decoder)
+	b := generateBlockPointer()
+	defer releaseBlockPointer(b)
+	var d rowSetDigest
+	var buf []byte
+	for pmi.nextBlockMetadata() {
+		b.reset()
+		pmi.mustLoadBlockData(decoder, b)
+		sid := pmi.block.bm.seriesID
+		for i := range b.timestamps {
+			buf = d.add(sid, b.elementIDs[i], b.timestamps[i], buf)
+		}
+	}
+	if err := pmi.error(); err != nil {
+		logger.Panicf("cannot read part %d: %s", pw.ID(), err)
+	}
+	return d
+}
+
+// verifyMergedPart asserts the merged part holds exactly the rows of the source parts.
+func verifyMergedPart(parts []*partWrapper, merged *partWrapper) error {
+	var expected rowSetDigest
+	for i := range parts {
+		expected.merge(digestPart(parts[i]))
+	}
+	actual := digestPart(merged)
+	if expected != actual {
+		return fmt.Errorf("%w: expected %d rows, got %d rows in part %d", errMergeVerification, expected.count, actual.count, merged.ID())
+	}
+	return nil
+}
+
+// quarantinePart moves a part out of the table, keeping it for the investigation.
+func (tst *tsTable) quarantinePart(pw *partWrapper) {
+	pw.decRef()
+	if err := moveToQuarantine(tst.fileSystem, tst.root, pw.ID()); err != nil {
+		tst.l.Error().Err(err).Uint64("part", pw.ID()).Msg("cannot quarantine the merged part, remove it")
+		tst.fileSystem.MustRMAll(partPath(tst.root, pw.ID()))
+	}
+}
+
+// moveToQuarantine moves the directory of a part into the quarantine directory of its table.
+func moveToQuarantine(fileSystem fs.FileSystem, root string, id uint64) error {
+	dir := filepath.Join(root, quarantineDirname)
+	fileSystem.MkdirIfNotExist(dir, dirPermission)
+	return os.Rename(partPath(root, id), filepath.Join(dir, partName(id)))
+}
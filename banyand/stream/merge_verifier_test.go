@@ -0,0 +1,122 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/pkg/fs"
+	"github.com/apache/skywalking-banyandb/pkg/test"
+)
+
+func Test_verifyMergedPart(t *testing.T) {
+	tmpPath, defFn := test.Space(require.New(t))
+	defer defFn()
+	fileSystem := fs.NewLocalFileSystem()
+	var partID uint64
+	var opened []*partWrapper
+	defer func() {
+		for _, pw := range opened {
+			pw.decRef()
+		}
+	}()
+	newFilePart := func(es *elements) *partWrapper {
+		partID++
+		mp := generateMemPart()
+		defer releaseMemPart(mp)
+		mp.mustInitFromElements(es, 0)
+		mp.mustFlush(fileSystem, partPath(tmpPath, partID))
+		pw := newPartWrapper(nil, mustOpenFilePart(partID, tmpPath, fileSystem))
+		opened = append(opened, pw)
+		return pw
+	}
+	merge := func(parts ...*partWrapper) *partWrapper {
+		partID++
+		pw, err := mergeParts(fileSystem, nil, parts, partID, tmpPath, "", 0)
+		require.NoError(t, err)
+		opened = append(opened, pw)
+		return pw
+	}
+	// dropLast mimics a merge losing the last row of its input.
+	dropLast := func(es *elements) *elements {
+		n := len(es.seriesIDs) - 1
+		return &elements{
+			seriesIDs:   es.seriesIDs[:n],
+			timestamps:  es.timestamps[:n],
+			elementIDs:  es.elementIDs[:n],
+			tagFamilies: es.tagFamilies[:n],
+		}
+	}
+	renamed := func(es *elements) *elements {
+		ids := append([]string{}, es.elementIDs...)
+		ids[0] += "-renamed"
+		return &elements{
+			seriesIDs:   es.seriesIDs,
+			timestamps:  es.timestamps,
+			elementIDs:  ids,
+			tagFamilies: es.tagFamilies,
+		}
+	}
+
+	p1, p2 := newFilePart(esTS1), newFilePart(esTS2)
+	tests := []struct {
+		merged  *partWrapper
+		name    string
+		wantErr bool
+	}{
+		{name: "correct merge", merged: merge(newFilePart(esTS1), newFilePart(esTS2))},
+		{name: "lost row", merged: merge(newFilePart(esTS1), newFilePart(dropLast(esTS2))), wantErr: true},
+		{name: "duplicated rows", merged: merge(newFilePart(esTS1), newFilePart(esTS2), newFilePart(esTS2)), wantErr: true},
+		{name: "altered element id", merged: merge(newFilePart(renamed(esTS1)), newFilePart(esTS2)), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyMergedPart([]*partWrapper{p1, p2}, tt.merged)
+			if !tt.wantErr {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, errMergeVerification))
+		})
+	}
+
+	// The verifier leaves the source parts readable by the merger.
+	merged := merge(p1, p2)
+	assert.NoError(t, verifyMergedPart([]*partWrapper{newFilePart(esTS1), newFilePart(esTS2)}, merged))
+}
+
+func Test_tsTable_quarantinePart(t *testing.T) {
+	tmpPath, defFn := test.Space(require.New(t))
+	defer defFn()
+	fileSystem := fs.NewLocalFileSystem()
+	tst := &tsTable{fileSystem: fileSystem, root: tmpPath}
+	mp := generateMemPart()
+	defer releaseMemPart(mp)
+	mp.mustInitFromElements(esTS1, 0)
+	mp.mustFlush(fileSystem, partPath(tmpPath, 1))
+	tst.quarantinePart(newPartWrapper(nil, mustOpenFilePart(1, tmpPath, fileSystem)))
+
+	assert.NoDirExists(t, partPath(tmpPath, 1))
+	assert.DirExists(t, filepath.Join(tmpPath, quarantineDirname, partName(1)))
+}
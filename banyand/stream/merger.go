@@ -60,6 +60,9 @@ func (tst *tsTable) mergeLoop(merges chan *mergerIntroduction, flusherNotifier w
 					}
 					tst.l.Logger.Warn().Err(err).Msgf("cannot merge snapshot: %d", curSnapshot.epoch)
 					curSnapshot.decRef()
+					if !tst.waitMergeRetry() {
+						return
+					}
 					continue
 				}
 				epoch = curSnapshot.epoch
@@ -75,14 +78,35 @@ func (tst *tsTable) mergeLoop(merges chan *mergerIntroduction, flusherNotifier w
 
 func (tst *tsTable) mergeSnapshot(curSnapshot *snapshot, merges chan *mergerIntroduction, dst []*partWrapper) ([]*partWrapper, error) {
 	freeDiskSize := tst.freeDiskSpace(tst.root)
-	var toBeMerged map[uint64]struct{}
-	dst, toBeMerged = tst.getPartsToMerge(curSnapshot, freeDiskSize, dst)
+	dst = tst.getPartsToMerge(curSnapshot, freeDiskSize, dst)
 	if len(dst) < 2 {
 		return nil, nil
 	}
-	if _, err := tst.mergePartsThenSendIntroduction(snapshotCreatorMerger, dst,
-		toBeMerged, merges, tst.loopCloser.CloseNotify()); err != nil {
-		return dst, err
+	for _, pws := range tst.option.mergePolicy.splitByTarget(dst) {
+		toBeMerged := make(map[uint64]struct{}, len(pws))
+		for _, pw := range pws {
+			toBeMerged[pw.ID()] = struct{}{}
+		}
+		_, err := tst.mergePartsThenSendIntroduction(snapshotCreatorMerger, pws,
+			toBeMerged, merges, tst.loopCloser.CloseNotify())
+		if err == nil {
+			tst.forgetMergeFailures(pws)
+			continue
+		}
+		if errors.Is(err, errClosed) {
+			return dst, err
+		}
+		// The other errors, such as an exhausted disk, are retried until they go away.
+		if !isDeterministicMergeError(err) {
+			tst.mergeAttempts++
+			return dst, err
+		}
+		if !tst.mergeFailed(pws) {
+			return dst, err
+		}
+		if err = tst.bisectMerge(pws, merges); err != nil {
+			return dst, err
+		}
 	}
 	return dst, nil
 }
@@ -93,10 +117,26 @@ func (tst *tsTable) mergePartsThenSendIntroduction(creator snapshotCreator, part
 	reservedSpace := tst.reserveSpace(parts)
 	defer releaseDiskSpace(reservedSpace)
 	start := time.Now()
-	newPart, err := mergeParts(tst.fileSystem, closeCh, parts, atomic.AddUint64(&tst.curPartID, 1), tst.root)
+	var clusteringKey string
+	// Only the background merger reorders rows, so that the flushed parts keep the order of writes.
+	if creator == snapshotCreatorMerger {
+		clusteringKey = tst.option.clusteringKey
+	}
+	merge := mergeParts
+	if tst.option.partMerger != nil {
+		merge = tst.option.partMerger
+	}
+	newPart, err := merge(tst.fileSystem, closeCh, parts, atomic.AddUint64(&tst.curPartID, 1), tst.root, clusteringKey, tst.option.compressThreshold)
 	if err != nil {
 		return nil, err
 	}
+	if tst.option.verifyMerge {
+		if err = verifyMergedPart(parts, newPart); err != nil {
+			tst.l.Error().Err(err).Str("path", newPart.p.path).Msg("quarantine the merged part")
+			tst.quarantinePart(newPart)
+			return nil, err
+		}
+	}
 	elapsed := time.Since(start)
 	if elapsed > 30*time.Second {
 		var totalCount uint64
@@ -181,7 +221,7 @@ func releaseDiskSpace(n uint64) {
 
 var reservedDiskSpace uint64
 
-func (tst *tsTable) getPartsToMerge(snapshot *snapshot, freeDiskSize uint64, dst []*partWrapper) ([]*partWrapper, map[uint64]struct{}) {
+func (tst *tsTable) getPartsToMerge(snapshot *snapshot, freeDiskSize uint64, dst []*partWrapper) []*partWrapper {
 	var parts []*partWrapper
 
 	for _, pw := range snapshot.parts {
@@ -191,16 +231,7 @@ func (tst *tsTable) getPartsToMerge(snapshot *snapshot, freeDiskSize uint64, dst
 		parts = append(parts, pw)
 	}
 
-	dst = tst.option.mergePolicy.getPartsToMerge(dst, parts, freeDiskSize)
-	if len(dst) == 0 {
-		return nil, nil
-	}
-
-	toBeMerged := make(map[uint64]struct{})
-	for _, pw := range dst {
-		toBeMerged[pw.ID()] = struct{}{}
-	}
-	return dst, toBeMerged
+	return tst.option.mergePolicy.getPartsToMerge(dst, parts, freeDiskSize)
 }
 
 func (tst *tsTable) reserveSpace(parts []*partWrapper) uint64 {
@@ -216,7 +247,12 @@ func (tst *tsTable) reserveSpace(parts []*partWrapper) uint64 {
 
 var errNoPartToMerge = fmt.Errorf("no part to merge")
 
-func mergeParts(fileSystem fs.FileSystem, closeCh <-chan struct{}, parts []*partWrapper, partID uint64, root string) (*partWrapper, error) {
+type mergeFunc func(fileSystem fs.FileSystem, closeCh <-chan struct{}, parts []*partWrapper, partID uint64, root string,
+	clusteringKey string, compressThreshold int) (*partWrapper, error)
+
+func mergeParts(fileSystem fs.FileSystem, closeCh <-chan struct{}, parts []*partWrapper, partID uint64, root string,
+	clusteringKey string, compressThreshold int,
+) (*partWrapper, error) {
 	if len(parts) == 0 {
 		return nil, errNoPartToMerge
 	}
@@ -231,8 +267,9 @@ func mergeParts(fileSystem fs.FileSystem, closeCh <-chan struct{}, parts []*part
 	br.init(pii)
 	bw := generateBlockWriter()
 	bw.mustInitForFilePart(fileSystem, dstPath)
+	bw.writers.compressThreshold = compressThreshold
 
-	pm, err := mergeBlocks(closeCh, bw, br)
+	pm, err := mergeBlocks(closeCh, bw, br, clusteringKey)
 	releaseBlockWriter(bw)
 	releaseBlockReader(br)
 	for i := range pii {
@@ -249,7 +286,14 @@ func mergeParts(fileSystem fs.FileSystem, closeCh <-chan struct{}, parts []*part
 
 var errClosed = fmt.Errorf("the merger is closed")
 
-func mergeBlocks(closeCh <-chan struct{}, bw *blockWriter, br *blockReader) (*partMetadata, error) {
+func mergeBlocks(closeCh <-chan struct{}, bw *blockWriter, br *blockReader, clusteringKey string) (*partMetadata, error) {
+	writeBlock := bw.mustWriteBlock
+	var cw *clusteringWriter
+	if clusteringKey != "" {
+		cw = generateClusteringWriter(bw, clusteringKey)
+		defer releaseClusteringWriter(cw)
+		writeBlock = cw.mustWriteBlock
+	}
 	pendingBlockIsEmpty := true
 	pendingBlock := generateBlockPointer()
 	defer releaseBlockPointer(pendingBlock)
@@ -284,7 +328,7 @@ func mergeBlocks(closeCh <-chan struct{}, bw *blockWriter, br *blockReader) (*pa
 
 		if pendingBlock.bm.seriesID != b.bm.seriesID ||
 			(pendingBlock.isFull() && pendingBlock.bm.timestamps.max <= b.bm.timestamps.min) {
-			bw.mustWriteBlock(pendingBlock.bm.seriesID, &pendingBlock.block)
+			writeBlock(pendingBlock.bm.seriesID, &pendingBlock.block)
 			releaseDecoder()
 			pendingBlock.reset()
 			br.loadBlockData(getDecoder())
@@ -307,17 +351,20 @@ func mergeBlocks(closeCh <-chan struct{}, bw *blockWriter, br *blockReader) (*pa
 			pendingBlock, tmpBlock = tmpBlock, pendingBlock
 			continue
 		}
-		bw.mustWriteBlock(tmpBlock.bm.seriesID, &tmpBlock.block)
+		writeBlock(tmpBlock.bm.seriesID, &tmpBlock.block)
 		releaseDecoder()
 		pendingBlock.reset()
 		tmpBlock.reset()
 		pendingBlockIsEmpty = true
 	}
 	if err := br.error(); err != nil {
-		return nil, fmt.Errorf("cannot read block to merge: %w", err)
+		return nil, fmt.Errorf("%w: cannot read block to merge: %w", errPartCorrupted, err)
 	}
 	if !pendingBlockIsEmpty {
-		bw.mustWriteBlock(pendingBlock.bm.seriesID, &pendingBlock.block)
+		writeBlock(pendingBlock.bm.seriesID, &pendingBlock.block)
+	}
+	if cw != nil {
+		cw.flush()
 	}
 	releaseDecoder()
 	var result partMetadata
@@ -0,0 +1,264 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+)
+
+// maxClusteringSeriesSize caps the rows of a series buffered for reordering.
+// A larger series is written in its merged order.
+const maxClusteringSeriesSize = 32 * maxUncompressedBlockSize
+
+// clusteringWriter reorders the rows of every series by the value of the clustering key
+// before handing them to the blockWriter, so that rows sharing a value end up in the same blocks.
+//
+// A block keeps its timestamps sorted, hence the rows are sorted by (key, timestamp), cut into
+// blocks of the usual size, and every block is sorted by timestamp again. The blocks of a series
+// are written by their minimum timestamps and may overlap in time.
+type clusteringWriter struct {
+	bw          *blockWriter
+	key         string
+	pending     []*blockPointer
+	arena       []byte
+	rows        []clusteringRow
+	size        uint64
+	sid         common.SeriesID
+	passThrough bool
+}
+
+type clusteringRow struct {
+	key   []byte
+	ts    int64
+	block int
+	idx   int
+	size  uint64
+}
+
+func (cw *clusteringWriter) mustWriteBlock(sid common.SeriesID, b *block) {
+	if b.Len() == 0 {
+		return
+	}
+	if sid != cw.sid {
+		cw.flush()
+		cw.sid = sid
+	}
+	if cw.passThrough {
+		cw.bw.mustWriteBlock(sid, b)
+		return
+	}
+	size := b.uncompressedSizeBytes()
+	if cw.size+size > maxClusteringSeriesSize {
+		cw.writePending()
+		cw.passThrough = true
+		cw.bw.mustWriteBlock(sid, b)
+		return
+	}
+	cw.size += size
+	cw.pending = append(cw.pending, cw.copyBlock(b))
+}
+
+// copyBlock copies b out of the reader's buffers, which are reused once the block is written.
+func (cw *clusteringWriter) copyBlock(b *block) *blockPointer {
+	bp := generateBlockPointer()
+	bp.appendAll(&blockPointer{block: *b})
+	for i := range bp.tagFamilies {
+		for j := range bp.tagFamilies[i].tags {
+			values := bp.tagFamilies[i].tags[j].values
+			for k, v := range values {
+				if len(v) == 0 {
+					continue
+				}
+				start := len(cw.arena)
+				cw.arena = append(cw.arena, v...)
+				values[k] = cw.arena[start:len(cw.arena):len(cw.arena)]
+			}
+		}
+	}
+	return bp
+}
+
+// flush writes the buffered rows of the current series.
+func (cw *clusteringWriter) flush() {
+	if len(cw.pending) > 0 {
+		fi, ti, ok := cw.locateKey()
+		if ok {
+			cw.writeClustered(fi, ti)
+		} else {
+			cw.writePending()
+		}
+	}
+	cw.discard()
+}
+
+func (cw *clusteringWriter) discard() {
+	for i := range cw.pending {
+		releaseBlockPointer(cw.pending[i])
+		cw.pending[i] = nil
+	}
+	cw.pending = cw.pending[:0]
+	cw.arena = cw.arena[:0]
+	cw.rows = cw.rows[:0]
+	cw.size = 0
+	cw.passThrough = false
+}
+
+// locateKey returns the position of the clustering key in the buffered blocks.
+// It fails if the key is missing or the blocks don't share the same tag layout,
+// since rows of different layouts can't be mixed in a block.
+func (cw *clusteringWriter) locateKey() (int, int, bool) {
+	first := cw.pending[0]
+	fi, ti := -1, -1
+	for i := range first.tagFamilies {
+		for j := range first.tagFamilies[i].tags {
+			if fi < 0 && first.tagFamilies[i].tags[j].name == cw.key {
+				fi, ti = i, j
+			}
+		}
+	}
+	if fi < 0 {
+		return 0, 0, false
+	}
+	for _, bp := range cw.pending[1:] {
+		if !hasSameLayout(&first.block, &bp.block) {
+			return 0, 0, false
+		}
+	}
+	return fi, ti, true
+}
+
+func hasSameLayout(a, b *block) bool {
+	if len(a.tagFamilies) != len(b.tagFamilies) {
+		return false
+	}
+	for i := range a.tagFamilies {
+		tfa, tfb := &a.tagFamilies[i], &b.tagFamilies[i]
+		if tfa.name != tfb.name || len(tfa.tags) != len(tfb.tags) {
+			return false
+		}
+		for j := range tfa.tags {
+			if tfa.tags[j].name != tfb.tags[j].name || tfa.tags[j].valueType != tfb.tags[j].valueType {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (cw *clusteringWriter) writePending() {
+	for _, bp := range cw.pending {
+		cw.bw.mustWriteBlock(cw.sid, &bp.block)
+	}
+}
+
+func (cw *clusteringWriter) writeClustered(fi, ti int) {
+	for i, bp := range cw.pending {
+		for j := range bp.timestamps {
+			cw.rows = append(cw.rows, clusteringRow{
+				key:   bp.tagFamilies[fi].tags[ti].values[j],
+				ts:    bp.timestamps[j],
+				block: i,
+				idx:   j,
+				size:  rowSizeBytes(&bp.block, j),
+			})
+		}
+	}
+	rows := cw.rows
+	sort.Slice(rows, func(i, j int) bool {
+		if c := bytes.Compare(rows[i].key, rows[j].key); c != 0 {
+			return c < 0
+		}
+		return rows[i].ts < rows[j].ts
+	})
+
+	var chunks [][]clusteringRow
+	var start int
+	var size uint64
+	for i := range rows {
+		if i > start && size+rows[i].size > maxUncompressedBlockSize {
+			chunks = append(chunks, rows[start:i])
+			start, size = i, 0
+		}
+		size += rows[i].size
+	}
+	chunks = append(chunks, rows[start:])
+	for _, chunk := range chunks {
+		sort.Slice(chunk, func(i, j int) bool {
+			return chunk[i].ts < chunk[j].ts
+		})
+	}
+	// The blockWriter expects the blocks of a series in the order of their minimum timestamps.
+	sort.SliceStable(chunks, func(i, j int) bool {
+		return chunks[i][0].ts < chunks[j][0].ts
+	})
+
+	out := generateBlockPointer()
+	defer releaseBlockPointer(out)
+	for _, chunk := range chunks {
+		out.reset()
+		for _, r := range chunk {
+			src := cw.pending[r.block]
+			src.idx = r.idx
+			out.append(src, r.idx+1)
+		}
+		cw.bw.mustWriteBlock(cw.sid, &out.block)
+	}
+}
+
+// rowSizeBytes approximates the share of the row at idx in block.uncompressedSizeBytes.
+func rowSizeBytes(b *block, idx int) uint64 {
+	n := uint64(8)
+	for i := range b.tagFamilies {
+		tf := &b.tagFamilies[i]
+		for j := range tf.tags {
+			if v := tf.tags[j].values[idx]; len(v) > 0 {
+				n += uint64(len(tf.name)+len(tf.tags[j].name)) + uint64(len(v))
+			}
+		}
+	}
+	return n
+}
+
+func (cw *clusteringWriter) reset() {
+	cw.discard()
+	cw.bw = nil
+	cw.key = ""
+	cw.sid = 0
+}
+
+func generateClusteringWriter(bw *blockWriter, key string) *clusteringWriter {
+	v := clusteringWriterPool.Get()
+	if v == nil {
+		return &clusteringWriter{bw: bw, key: key}
+	}
+	cw := v.(*clusteringWriter)
+	cw.bw = bw
+	cw.key = key
+	return cw
+}
+
+func releaseClusteringWriter(cw *clusteringWriter) {
+	cw.reset()
+	clusteringWriterPool.Put(cw)
+}
+
+var clusteringWriterPool sync.Pool
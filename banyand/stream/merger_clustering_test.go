@@ -0,0 +1,217 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/pkg/fs"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/test"
+)
+
+const (
+	clusteringServices = 10
+	clusteringElements = 20000
+)
+
+func clusteringService(i int64) string {
+	return fmt.Sprintf("svc-%d", i%clusteringServices)
+}
+
+func generateServiceEs(startTimestamp, endTimestamp int64) *elements {
+	es := &elements{}
+	for i := startTimestamp; i <= endTimestamp; i++ {
+		es.seriesIDs = append(es.seriesIDs, 1)
+		es.timestamps = append(es.timestamps, i)
+		es.elementIDs = append(es.elementIDs, fmt.Sprint(i))
+		es.tagFamilies = append(es.tagFamilies, []tagValues{
+			{
+				tag: "binaryTag", values: []*tagValue{
+					{tag: "binaryTag", valueType: pbv1.ValueTypeBinaryData, value: longText},
+				},
+			},
+			{
+				tag: "singleTag", values: []*tagValue{
+					{tag: "service", valueType: pbv1.ValueTypeStr, value: []byte(clusteringService(i))},
+				},
+			},
+		})
+	}
+	return es
+}
+
+type clusteredBlock struct {
+	services   map[string]struct{}
+	timestamps []int64
+}
+
+func readClusteredBlocks(t testing.TB, p *part) []clusteredBlock {
+	pmi := &partMergeIter{}
+	pmi.mustInitFromPart(p)
+	reader := &blockReader{}
+	reader.init([]*partMergeIter{pmi})
+	decoder := generateColumnValuesDecoder()
+	defer releaseColumnValuesDecoder(decoder)
+	var blocks []clusteredBlock
+	for reader.nextBlockMetadata() {
+		reader.loadBlockData(decoder)
+		cb := clusteredBlock{
+			services:   make(map[string]struct{}),
+			timestamps: append([]int64(nil), reader.block.timestamps...),
+		}
+		for _, tf := range reader.block.tagFamilies {
+			for _, tag := range tf.tags {
+				if tag.name != "service" {
+					continue
+				}
+				for _, v := range tag.values {
+					cb.services[string(v)] = struct{}{}
+				}
+			}
+		}
+		blocks = append(blocks, cb)
+	}
+	require.NoError(t, reader.error())
+	return blocks
+}
+
+func mergeServiceParts(t testing.TB, fileSystem fs.FileSystem, root string, partID uint64, clusteringKey string) *partWrapper {
+	var pp []*partWrapper
+	for _, es := range []*elements{generateServiceEs(1, clusteringElements/2), generateServiceEs(clusteringElements/2+1, clusteringElements)} {
+		mp := generateMemPart()
+		mp.mustInitFromElements(es, 0)
+		pp = append(pp, newPartWrapper(mp, openMemPart(mp)))
+	}
+	defer func() {
+		for _, pw := range pp {
+			pw.decRef()
+		}
+	}()
+	closeCh := make(chan struct{})
+	defer close(closeCh)
+	p, err := mergeParts(fileSystem, closeCh, pp, partID, root, clusteringKey, 0)
+	require.NoError(t, err)
+	return p
+}
+
+func blocksWithService(blocks []clusteredBlock, service string) int {
+	n := 0
+	for _, b := range blocks {
+		if _, ok := b.services[service]; ok {
+			n++
+		}
+	}
+	return n
+}
+
+func TestMergePartsClustering(t *testing.T) {
+	tmpPath, defFn := test.Space(require.New(t))
+	defer defFn()
+	fileSystem := fs.NewLocalFileSystem()
+
+	plain := mergeServiceParts(t, fileSystem, tmpPath, 1, "")
+	defer plain.decRef()
+	clustered := mergeServiceParts(t, fileSystem, tmpPath, 2, "service")
+	defer clustered.decRef()
+	missing := mergeServiceParts(t, fileSystem, tmpPath, 3, "unknown")
+	defer missing.decRef()
+
+	plainBlocks := readClusteredBlocks(t, plain.p)
+	clusteredBlocks := readClusteredBlocks(t, clustered.p)
+	assert.Equal(t, plainBlocks, readClusteredBlocks(t, missing.p), "a missing clustering key keeps the merged order")
+	assert.Equal(t, plain.p.partMetadata.TotalCount, clustered.p.partMetadata.TotalCount)
+
+	var timestamps []int64
+	var lastMin int64
+	for _, b := range clusteredBlocks {
+		require.True(t, sort.SliceIsSorted(b.timestamps, func(i, j int) bool { return b.timestamps[i] < b.timestamps[j] }))
+		require.GreaterOrEqual(t, b.timestamps[0], lastMin)
+		lastMin = b.timestamps[0]
+		timestamps = append(timestamps, b.timestamps...)
+	}
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+	require.Len(t, timestamps, clusteringElements)
+	for i, ts := range timestamps {
+		require.Equal(t, int64(i+1), ts)
+	}
+
+	for i := int64(0); i < clusteringServices; i++ {
+		svc := clusteringService(i)
+		assert.Equal(t, len(plainBlocks), blocksWithService(plainBlocks, svc))
+		assert.LessOrEqual(t, blocksWithService(clusteredBlocks, svc), 2, svc)
+	}
+}
+
+func BenchmarkMergePartsClustering(b *testing.B) {
+	const service = "svc-3"
+	var expectedTimestamps []int64
+	for i := int64(1); i <= clusteringElements; i++ {
+		if clusteringService(i) == service {
+			expectedTimestamps = append(expectedTimestamps, i)
+		}
+	}
+	qo := queryOptions{
+		elementRefMap: map[common.SeriesID][]int64{1: expectedTimestamps},
+		minTimestamp:  1,
+		maxTimestamp:  clusteringElements,
+	}
+	qo.TagProjection = []pbv1.TagProjection{{Family: "singleTag", Names: []string{"service"}}}
+	sids := []common.SeriesID{1}
+	for i, clusteringKey := range []string{"", "service"} {
+		tmpPath, defFn := test.Space(require.New(b))
+		fileSystem := fs.NewLocalFileSystem()
+		pw := mergeServiceParts(b, fileSystem, tmpPath, uint64(i+1), clusteringKey)
+		p := pw.p
+
+		b.Run(fmt.Sprintf("clusteringKey=%q", clusteringKey), func(b *testing.B) {
+			var scanned int
+			for i := 0; i < b.N; i++ {
+				bma := generateBlockMetadataArray()
+				var pi partIter
+				pi.init(bma, p, sids, qo.minTimestamp, qo.maxTimestamp)
+				bc := generateBlockCursor()
+				tmpBlock := generateBlock()
+				scanned = 0
+				n := 0
+				for pi.nextBlock() {
+					bc.init(p, pi.curBlock, qo)
+					if bc.loadData(tmpBlock) {
+						scanned++
+						n += len(bc.timestamps)
+					}
+				}
+				releaseBlock(tmpBlock)
+				releaseBlockCursor(bc)
+				releaseBlockMetadataArray(bma)
+				if n != len(expectedTimestamps) {
+					b.Fatalf("unexpected number of elements: got %d; want %d", n, len(expectedTimestamps))
+				}
+			}
+			b.ReportMetric(float64(scanned), "blocks/op")
+		})
+		pw.decRef()
+		defFn()
+	}
+}
@@ -28,6 +28,8 @@ type mergePolicy struct {
 	maxParts           int
 	minMergeMultiplier float64
 	maxFanOutSize      uint64
+	// targetPartSize is the size a merged part aims for. Zero leaves the size of a merged part to maxFanOutSize.
+	targetPartSize uint64
 }
 
 // NewDefaultMergePolicy create a MergePolicy with default parameters.
@@ -66,6 +68,10 @@ func (l *mergePolicy) getPartsToMerge(dst, src []*partWrapper, freeDiskSize uint
 		if pw.p.partMetadata.CompressedSizeBytes > maxInPartBytes {
 			continue
 		}
+		// A part reaching the target has the expected size already.
+		if l.targetPartSize > 0 && pw.p.partMetadata.CompressedSizeBytes >= l.targetPartSize {
+			continue
+		}
 		tmp = append(tmp, pw)
 	}
 	src = tmp
@@ -118,6 +124,33 @@ func (l *mergePolicy) getPartsToMerge(dst, src []*partWrapper, freeDiskSize uint
 	return append(dst, pws...)
 }
 
+// splitByTarget splits the parts to merge into groups, each of which merges into a part not exceeding the target size.
+// The parts are sorted by size, so the groups are filled from the smallest parts.
+// A group holding a single part is dropped since there is nothing to merge it with.
+func (l *mergePolicy) splitByTarget(pws []*partWrapper) [][]*partWrapper {
+	if l.targetPartSize == 0 {
+		return [][]*partWrapper{pws}
+	}
+	var groups [][]*partWrapper
+	var group []*partWrapper
+	var size uint64
+	for _, pw := range pws {
+		n := pw.p.partMetadata.CompressedSizeBytes
+		if len(group) > 0 && size+n > l.targetPartSize {
+			if len(group) > 1 {
+				groups = append(groups, group)
+			}
+			group, size = nil, 0
+		}
+		group = append(group, pw)
+		size += n
+	}
+	if len(group) > 1 {
+		groups = append(groups, group)
+	}
+	return groups
+}
+
 func sortPartsForOptimalMerge(pws []*partWrapper) {
 	// Sort src parts by size and backwards timestamp.
 	// This should improve adjacent points' locality in the merged parts.
@@ -0,0 +1,79 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSizedPartWrappers(sizes ...uint64) []*partWrapper {
+	pws := make([]*partWrapper, 0, len(sizes))
+	for i, size := range sizes {
+		pws = append(pws, newPartWrapper(nil, &part{partMetadata: partMetadata{
+			ID:                  uint64(i + 1),
+			CompressedSizeBytes: size,
+			MinTimestamp:        int64(i),
+		}}))
+	}
+	return pws
+}
+
+func TestMergePolicyTargetPartSize(t *testing.T) {
+	const (
+		partSize   = 10 << 20
+		target     = 256 << 20
+		maxFanOut  = 1 << 30
+		smallParts = 100
+	)
+	sizes := make([]uint64, smallParts)
+	for i := range sizes {
+		sizes[i] = partSize
+	}
+	mp := newMergePolicy(smallParts, 1.7, maxFanOut)
+	mp.targetPartSize = target
+
+	dst := mp.getPartsToMerge(nil, newSizedPartWrappers(sizes...), maxFanOut)
+	require.NotEmpty(t, dst)
+	assert.LessOrEqual(t, sumCompressedSize(dst), uint64(maxFanOut))
+
+	groups := mp.splitByTarget(dst)
+	require.Greater(t, len(groups), 1, "the merge is split into several parts")
+	var merged int
+	for _, g := range groups {
+		out := sumCompressedSize(g)
+		assert.LessOrEqual(t, out, uint64(target))
+		assert.Greater(t, out, uint64(target-partSize), "a merged part is as close to the target as the inputs allow")
+		merged += len(g)
+	}
+	assert.Equal(t, len(dst), merged)
+
+	t.Run("the parts reaching the target aren't merged again", func(t *testing.T) {
+		dst := mp.getPartsToMerge(nil, newSizedPartWrappers(target, target, target+1, partSize), maxFanOut)
+		assert.Empty(t, dst)
+	})
+
+	t.Run("no target merges into a single part", func(t *testing.T) {
+		mp := newMergePolicy(smallParts, 1.7, maxFanOut)
+		dst := mp.getPartsToMerge(nil, newSizedPartWrappers(sizes...), maxFanOut)
+		require.NotEmpty(t, dst)
+		assert.Equal(t, [][]*partWrapper{dst}, mp.splitByTarget(dst))
+	})
+}
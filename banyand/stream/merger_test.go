@@ -291,7 +291,7 @@ func Test_mergeParts(t *testing.T) {
 			verify := func(t *testing.T, pp []*partWrapper, fileSystem fs.FileSystem, root string, partID uint64) {
 				closeCh := make(chan struct{})
 				defer close(closeCh)
-				p, err := mergeParts(fileSystem, closeCh, pp, partID, root)
+				p, err := mergeParts(fileSystem, closeCh, pp, partID, root, "", 0)
 				if tt.wantErr != nil {
 					if !errors.Is(err, tt.wantErr) {
 						t.Fatalf("Unexpected error: got %v, want %v", err, tt.wantErr)
@@ -330,7 +330,7 @@ func Test_mergeParts(t *testing.T) {
 				}()
 				for _, es := range tt.esList {
 					mp := generateMemPart()
-					mp.mustInitFromElements(es)
+					mp.mustInitFromElements(es, 0)
 					pp = append(pp, newPartWrapper(mp, openMemPart(mp)))
 				}
 				verify(t, pp, fs.NewLocalFileSystem(), tmpPath, 1)
@@ -348,7 +348,7 @@ func Test_mergeParts(t *testing.T) {
 				fileSystem := fs.NewLocalFileSystem()
 				for i, es := range tt.esList {
 					mp := generateMemPart()
-					mp.mustInitFromElements(es)
+					mp.mustInitFromElements(es, 0)
 					mp.mustFlush(fileSystem, partPath(tmpPath, uint64(i)))
 					filePW := newPartWrapper(nil, mustOpenFilePart(uint64(i), tmpPath, fileSystem))
 					filePW.p.partMetadata.ID = uint64(i)
@@ -21,6 +21,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
 	"path"
 	"time"
 
@@ -47,16 +48,20 @@ type schemaRepo struct {
 	resourceSchema.Repository
 	l        *logger.Logger
 	metadata metadata.Repo
+	supplier *supplier
 }
 
 func newSchemaRepo(path string, svc *service) schemaRepo {
+	s := newSupplier(path, svc)
 	sr := schemaRepo{
 		l:        svc.l,
 		metadata: svc.metadata,
+		supplier: s,
 		Repository: resourceSchema.NewRepository(
 			svc.metadata,
 			svc.l,
-			newSupplier(path, svc),
+			s,
+			svc.gracePeriod,
 		),
 	}
 	sr.start()
@@ -259,10 +264,23 @@ func newSupplier(path string, svc *service) *supplier {
 
 func (s *supplier) OpenResource(shardNum uint32, supplier resourceSchema.Supplier, spec resourceSchema.Resource) (io.Closer, error) {
 	streamSchema := spec.Schema().(*databasev1.Stream)
-	return openStream(shardNum, supplier, streamSpec{
+	st := openStream(shardNum, supplier, streamSpec{
 		schema:     streamSchema,
 		indexRules: spec.IndexRules(),
-	}, s.l), nil
+	}, s.l)
+	st.maxStalenessWait = s.option.maxStalenessWait
+	st.queryParallelism = s.option.queryParallelism
+	st.authorizer = s.option.authorizer
+	st.drainer = s.option.drainer
+	st.pendingWrites = s.option.pendingWrites
+	st.schemaHistory = s.option.schemaHistory
+	stamped, err := st.schemaHistory.record(streamSchema)
+	if err != nil {
+		s.l.Warn().Err(err).Str("group", streamSchema.GetMetadata().GetGroup()).
+			Msg("cannot persist the schema history, stamp the elements with their schema revision")
+	}
+	st.stampRevision = stamped
+	return st, nil
 }
 
 func (s *supplier) ResourceSchema(md *commonv1.Metadata) (resourceSchema.ResourceSchema, error) {
@@ -272,6 +290,19 @@ func (s *supplier) ResourceSchema(md *commonv1.Metadata) (resourceSchema.Resourc
 }
 
 func (s *supplier) OpenDB(groupSchema *commonv1.Group) (io.Closer, error) {
+	opts := s.tsdbOpts(groupSchema)
+	name := groupSchema.Metadata.Name
+	return storage.OpenTSDB(
+		common.SetPosition(context.Background(), func(p common.Position) common.Position {
+			p.Module = "stream"
+			p.Database = name
+			return p
+		}),
+		opts)
+}
+
+// tsdbOpts resolves the options of a group's tsdb from the flags and the group's resource options.
+func (s *supplier) tsdbOpts(groupSchema *commonv1.Group) storage.TSDBOpts[*tsTable, option] {
 	opts := storage.TSDBOpts[*tsTable, option]{
 		ShardNum:                       groupSchema.ResourceOpts.ShardNum,
 		Location:                       path.Join(s.path, groupSchema.Metadata.Name),
@@ -280,15 +311,27 @@ func (s *supplier) OpenDB(groupSchema *commonv1.Group) (io.Closer, error) {
 		TTL:                            storage.MustToIntervalRule(groupSchema.ResourceOpts.Ttl),
 		Option:                         s.option,
 		SeriesIndexFlushTimeoutSeconds: s.option.flushTimeout.Nanoseconds() / int64(time.Second),
+		SeriesCacheSize:                s.option.seriesCacheSize,
+		SeriesCacheTTL:                 s.option.seriesCacheTTL,
+		SeriesCachePolicy:              s.option.seriesCachePolicy,
+		SeriesCacheWarmup:              s.option.seriesCacheWarmup,
+		MaxSeriesPerQuery:              s.option.maxSeriesPerQuery,
+		MaxOpenSegments:                s.option.maxOpenSegments,
+		MaxDiskUsagePercent:            s.option.maxDiskUsagePercent,
+		MinRetainedSegments:            s.option.minRetainedSegments,
+		OutOfRetentionPolicy:           storage.ToOutOfRetentionPolicy(groupSchema.ResourceOpts.GetOutOfRetentionPolicy()),
 	}
-	name := groupSchema.Metadata.Name
-	return storage.OpenTSDB(
-		common.SetPosition(context.Background(), func(p common.Position) common.Position {
-			p.Module = "stream"
-			p.Database = name
-			return p
-		}),
-		opts)
+	if fw := groupSchema.ResourceOpts.GetFutureWindow(); fw != nil {
+		opts.FutureWindow = storage.MustToIntervalRule(fw)
+	}
+	opts.Option.clusteringKey = groupSchema.ResourceOpts.GetClusteringKey()
+	opts.Option.compressThreshold = int(groupSchema.ResourceOpts.GetCompressThreshold())
+	return opts
+}
+
+func (s *supplier) DropDB(groupSchema *commonv1.Group) error {
+	s.option.schemaHistory.dropGroup(groupSchema.Metadata.Name)
+	return os.RemoveAll(path.Join(s.path, groupSchema.Metadata.Name))
 }
 
 type portableSupplier struct {
@@ -28,6 +28,7 @@ import (
 	"time"
 
 	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/banyand/internal/storage"
 	"github.com/apache/skywalking-banyandb/pkg/bytes"
 	"github.com/apache/skywalking-banyandb/pkg/compress/zstd"
 	"github.com/apache/skywalking-banyandb/pkg/encoding"
@@ -140,7 +141,9 @@ func (p *part) String() string {
 	return fmt.Sprintf("part %d", p.partMetadata.ID)
 }
 
-func (p *part) getElement(seriesID common.SeriesID, timestamp int64, tagProjection []pbv1.TagProjection) (*element, int, error) {
+func (p *part) getElement(seriesID common.SeriesID, timestamp int64, tagProjection []pbv1.TagProjection,
+	account *storage.ReadAccount,
+) (*element, int, error) {
 	// TODO: refactor to column-based query
 	// TODO: cache blocks
 	if seriesID < p.primaryBlockMetadata[0].seriesID {
@@ -151,6 +154,9 @@ func (p *part) getElement(seriesID common.SeriesID, timestamp int64, tagProjecti
 			continue
 		}
 
+		if err := account.Charge(primaryMeta.size); err != nil {
+			return nil, 0, err
+		}
 		compressedPrimaryBuf := make([]byte, primaryMeta.size)
 		fs.MustReadData(p.primary, int64(primaryMeta.offset), compressedPrimaryBuf)
 		var err error
@@ -166,10 +172,16 @@ func (p *part) getElement(seriesID common.SeriesID, timestamp int64, tagProjecti
 		}
 		for i := range bm {
 			if bm[i].seriesID == seriesID && bm[i].timestamps.max >= timestamp && bm[i].timestamps.min <= timestamp {
+				if err = account.Charge(bm[i].timestamps.size); err != nil {
+					return nil, 0, err
+				}
 				timestamps := make([]int64, 0)
 				timestamps = mustReadTimestampsFrom(timestamps, &bm[i].timestamps, int(bm[i].count), p.timestamps)
 				for j, ts := range timestamps {
 					if timestamp == ts {
+						if err = account.Charge(bm[i].elementIDs.size); err != nil {
+							return nil, 0, err
+						}
 						elementIDs := make([]string, 0)
 						elementIDs = mustReadElementIDsFrom(elementIDs, &bm[i].elementIDs, int(bm[i].count), p.elementIDs)
 						tfs := make([]*tagFamily, 0)
@@ -181,7 +193,10 @@ func (p *part) getElement(seriesID common.SeriesID, timestamp int64, tagProjecti
 								continue
 							}
 							decoder := &encoding.BytesBlockDecoder{}
-							tf := unmarshalTagFamily(decoder, name, block, tagProjection[k].Names, p.tagFamilyMetadata[name], p.tagFamilies[name], len(timestamps))
+							tf, n := unmarshalTagFamily(decoder, name, block, tagProjection[k].Names, p.tagFamilyMetadata[name], p.tagFamilies[name], len(timestamps))
+							if err = account.Charge(n); err != nil {
+								return nil, 0, err
+							}
 							tfs = append(tfs, tf)
 						}
 
@@ -202,11 +217,12 @@ func (p *part) getElement(seriesID common.SeriesID, timestamp int64, tagProjecti
 	return nil, 0, errors.New("element not found")
 }
 
+// unmarshalTagFamily returns the tag family and the number of bytes it reads.
 func unmarshalTagFamily(decoder *encoding.BytesBlockDecoder, name string,
 	tagFamilyMetadataBlock *dataBlock, tagProjection []string, metaReader, valueReader fs.Reader, count int,
-) *tagFamily {
+) (*tagFamily, uint64) {
 	if len(tagProjection) < 1 {
-		return &tagFamily{}
+		return &tagFamily{}, 0
 	}
 	bb := bigValuePool.Generate()
 	bb.Buf = bytes.ResizeExact(bb.Buf, int(tagFamilyMetadataBlock.size))
@@ -222,15 +238,17 @@ func unmarshalTagFamily(decoder *encoding.BytesBlockDecoder, name string,
 	tf.name = name
 	tf.tags = tf.resizeTags(len(tagProjection))
 
+	n := tagFamilyMetadataBlock.size
 	for j := range tagProjection {
 		for i := range tfm.tagMetadata {
 			if tagProjection[j] == tfm.tagMetadata[i].name {
 				tf.tags[j].mustReadValues(decoder, valueReader, tfm.tagMetadata[i], uint64(count))
+				n += tfm.tagMetadata[i].size
 				break
 			}
 		}
 	}
-	return &tf
+	return &tf, n
 }
 
 func openMemPart(mp *memPart) *part {
@@ -299,7 +317,7 @@ func (mp *memPart) reset() {
 	}
 }
 
-func (mp *memPart) mustInitFromElements(es *elements) {
+func (mp *memPart) mustInitFromElements(es *elements, compressThreshold int) {
 	mp.reset()
 
 	if len(es.timestamps) == 0 {
@@ -310,6 +328,7 @@ func (mp *memPart) mustInitFromElements(es *elements) {
 
 	bsw := generateBlockWriter()
 	bsw.MustInitForMemPart(mp)
+	bsw.writers.compressThreshold = compressThreshold
 	var sidPrev common.SeriesID
 	uncompressedBlockSizeBytes := uint64(0)
 	var indexPrev int
@@ -378,10 +397,11 @@ func releaseMemPart(mp *memPart) {
 var memPartPool sync.Pool
 
 type partWrapper struct {
-	mp        *memPart
-	p         *part
-	ref       int32
-	removable atomic.Bool
+	mp          *memPart
+	p           *part
+	ref         int32
+	removable   atomic.Bool
+	quarantined atomic.Bool
 }
 
 func newPartWrapper(mp *memPart, p *part) *partWrapper {
@@ -404,6 +424,12 @@ func (pw *partWrapper) decRef() {
 		return
 	}
 	pw.p.close()
+	if pw.quarantined.Load() && pw.p.fileSystem != nil {
+		if err := moveToQuarantine(pw.p.fileSystem, filepath.Dir(pw.p.path), pw.ID()); err != nil {
+			logger.GetLogger("stream").Error().Err(err).Str("path", pw.p.path).Msg("cannot quarantine the part")
+		}
+		return
+	}
 	if pw.removable.Load() && pw.p.fileSystem != nil {
 		go func(pw *partWrapper) {
 			pw.p.fileSystem.MustRMAll(pw.p.path)
@@ -144,7 +144,7 @@ func Test_partIter_nextBlock(t *testing.T) {
 			}
 			mp := generateMemPart()
 			releaseMemPart(mp)
-			mp.mustInitFromElements(es)
+			mp.mustInitFromElements(es, 0)
 
 			p := openMemPart(mp)
 			verifyPart(p)
@@ -207,7 +207,7 @@ func Test_partMergeIter_nextBlock(t *testing.T) {
 			}
 			mp := generateMemPart()
 			releaseMemPart(mp)
-			mp.mustInitFromElements(tt.es)
+			mp.mustInitFromElements(tt.es, 0)
 
 			decoder := generateColumnValuesDecoder()
 			defer releaseColumnValuesDecoder(decoder)
@@ -18,11 +18,11 @@
 package stream
 
 import (
-	"encoding/json"
 	"path/filepath"
 
 	"github.com/pkg/errors"
 
+	"github.com/apache/skywalking-banyandb/banyand/internal/storage"
 	"github.com/apache/skywalking-banyandb/pkg/fs"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
 )
@@ -35,6 +35,9 @@ type partMetadata struct {
 	MinTimestamp          int64  `json:"minTimestamp"`
 	MaxTimestamp          int64  `json:"maxTimestamp"`
 	ID                    uint64 `json:"-"`
+	// Checksum is the checksum of the other fields in the legacy JSON format.
+	// Parts written before it was recorded have zero, and aren't verified.
+	Checksum uint32 `json:"checksum,omitempty"`
 }
 
 func (pm *partMetadata) reset() {
@@ -45,6 +48,56 @@ func (pm *partMetadata) reset() {
 	pm.MinTimestamp = 0
 	pm.MaxTimestamp = 0
 	pm.ID = 0
+	pm.Checksum = 0
+}
+
+func (pm *partMetadata) header() storage.PartMetadataHeader {
+	return storage.PartMetadataHeader{
+		CompressedSizeBytes:   pm.CompressedSizeBytes,
+		UncompressedSizeBytes: pm.UncompressedSizeBytes,
+		TotalCount:            pm.TotalCount,
+		BlocksCount:           pm.BlocksCount,
+		MinTimestamp:          pm.MinTimestamp,
+		MaxTimestamp:          pm.MaxTimestamp,
+	}
+}
+
+func (pm *partMetadata) setHeader(h storage.PartMetadataHeader) {
+	pm.CompressedSizeBytes = h.CompressedSizeBytes
+	pm.UncompressedSizeBytes = h.UncompressedSizeBytes
+	pm.TotalCount = h.TotalCount
+	pm.BlocksCount = h.BlocksCount
+	pm.MinTimestamp = h.MinTimestamp
+	pm.MaxTimestamp = h.MaxTimestamp
+}
+
+// marshalBinary appends the metadata to dst in the binary format shared with the measure parts.
+func (pm *partMetadata) marshalBinary(dst []byte) []byte {
+	return storage.MarshalPartMetadata(dst, storage.PartMetadataVersionBinary, pm.header(), nil)
+}
+
+func (pm *partMetadata) unmarshalBinary(src []byte) error {
+	var h storage.PartMetadataHeader
+	version, tail, err := storage.UnmarshalPartMetadata(src, &h)
+	if err != nil {
+		return err
+	}
+	if version != storage.PartMetadataVersionBinary {
+		return errors.Errorf("unknown metadata version %d", version)
+	}
+	if len(tail) > 0 {
+		return errors.Errorf("unexpected metadata size; got %d; want %d", len(src), storage.PartMetadataBinarySize)
+	}
+	pm.setHeader(h)
+	return nil
+}
+
+// unmarshal decodes the metadata in either the binary or the legacy JSON format.
+func (pm *partMetadata) unmarshal(data []byte) error {
+	if len(data) > 0 && data[0] == storage.PartMetadataVersionBinary {
+		return pm.unmarshalBinary(data)
+	}
+	return storage.UnmarshalPartMetadataJSON(data, pm, &pm.Checksum)
 }
 
 func validatePartMetadata(fileSystem fs.FileSystem, partPath string) error {
@@ -54,10 +107,7 @@ func validatePartMetadata(fileSystem fs.FileSystem, partPath string) error {
 		return errors.WithMessage(err, "cannot read metadata.json")
 	}
 	var pm partMetadata
-	if err := json.Unmarshal(metadata, &pm); err != nil {
-		return errors.WithMessage(err, "cannot parse metadata.json")
-	}
-	return nil
+	return errors.WithMessage(pm.unmarshal(metadata), "cannot parse metadata.json")
 }
 
 func (pm *partMetadata) mustReadMetadata(fileSystem fs.FileSystem, partPath string) {
@@ -69,7 +119,7 @@ func (pm *partMetadata) mustReadMetadata(fileSystem fs.FileSystem, partPath stri
 		logger.Panicf("cannot read %s", err)
 		return
 	}
-	if err := json.Unmarshal(metadata, pm); err != nil {
+	if err := pm.unmarshal(metadata); err != nil {
 		logger.Panicf("cannot parse %q: %s", metadataPath, err)
 		return
 	}
@@ -80,11 +130,8 @@ func (pm *partMetadata) mustReadMetadata(fileSystem fs.FileSystem, partPath stri
 }
 
 func (pm *partMetadata) mustWriteMetadata(fileSystem fs.FileSystem, partPath string) {
-	metadata, err := json.Marshal(pm)
-	if err != nil {
-		logger.Panicf("cannot marshal metadata: %s", err)
-		return
-	}
+	// The metadata keeps its legacy file name, though it's no longer JSON.
+	metadata := pm.marshalBinary(nil)
 	metadataPath := filepath.Join(partPath, metadataFilename)
 	n, err := fileSystem.Write(metadata, metadataPath, filePermission)
 	if err != nil {
@@ -0,0 +1,102 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/banyand/internal/storage"
+	"github.com/apache/skywalking-banyandb/pkg/fs"
+	"github.com/apache/skywalking-banyandb/pkg/test"
+)
+
+var testPartMetadata = partMetadata{
+	CompressedSizeBytes:   100,
+	UncompressedSizeBytes: 200,
+	TotalCount:            42,
+	BlocksCount:           3,
+	MinTimestamp:          -1,
+	MaxTimestamp:          10,
+}
+
+func marshalLegacyMetadata(tb testing.TB, pm partMetadata) []byte {
+	pm.Checksum = storage.PartMetadataJSONChecksum(&pm, &pm.Checksum)
+	data, err := json.Marshal(&pm)
+	require.NoError(tb, err)
+	return data
+}
+
+func TestPartMetadataEncodings(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata []byte
+	}{
+		{name: "binary", metadata: testPartMetadata.marshalBinary(nil)},
+		{name: "json", metadata: marshalLegacyMetadata(t, testPartMetadata)},
+		{name: "json without a checksum", metadata: []byte(`{"compressedSizeBytes":100,"uncompressedSizeBytes":200,` +
+			`"totalCount":42,"blocksCount":3,"minTimestamp":-1,"maxTimestamp":10}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pm partMetadata
+			require.NoError(t, pm.unmarshal(tt.metadata))
+			pm.Checksum = 0
+			assert.Equal(t, testPartMetadata, pm)
+		})
+	}
+}
+
+func TestPartMetadataChecksum(t *testing.T) {
+	tmpPath, defFn := test.Space(require.New(t))
+	defer defFn()
+	fileSystem := fs.NewLocalFileSystem()
+	metadataPath := filepath.Join(tmpPath, metadataFilename)
+	mustRead := func() (pm partMetadata, panicked string) {
+		defer func() {
+			if r := recover(); r != nil {
+				panicked = fmt.Sprint(r)
+			}
+		}()
+		pm.mustReadMetadata(fileSystem, tmpPath)
+		return pm, ""
+	}
+	written := testPartMetadata
+	written.MinTimestamp = 1
+	written.mustWriteMetadata(fileSystem, tmpPath)
+	pm, panicked := mustRead()
+	require.Empty(t, panicked)
+	assert.Equal(t, written, pm)
+	require.NoError(t, validatePartMetadata(fileSystem, tmpPath))
+
+	binaryMetadata, err := fileSystem.Read(metadataPath)
+	require.NoError(t, err)
+	require.Len(t, binaryMetadata, storage.PartMetadataBinarySize)
+	// Corrupt the total count.
+	binaryMetadata[1+2*8]++
+	_, err = fileSystem.Write(binaryMetadata, metadataPath, filePermission)
+	require.NoError(t, err)
+	assert.ErrorIs(t, validatePartMetadata(fileSystem, tmpPath), storage.ErrPartMetadataChecksumMismatch)
+	_, panicked = mustRead()
+	assert.Contains(t, panicked, "metadata checksum mismatch")
+}
@@ -84,7 +84,7 @@ func TestMustInitFromElements(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mp := &memPart{}
-			mp.mustInitFromElements(tt.es)
+			mp.mustInitFromElements(tt.es, 0)
 			assert.Equal(t, tt.want.BlocksCount, mp.partMetadata.BlocksCount)
 			assert.Equal(t, tt.want.MinTimestamp, mp.partMetadata.MinTimestamp)
 			assert.Equal(t, tt.want.MaxTimestamp, mp.partMetadata.MaxTimestamp)
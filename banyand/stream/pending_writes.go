@@ -0,0 +1,115 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+)
+
+// pendingWrites counts the elements of every series accepted by the write listener but not written yet,
+// so that a query with a staleness bound only waits for the series having such writes.
+// The series are kept with their entity values, since a series written for the first time
+// isn't in the series index until its elements are written.
+type pendingWrites struct {
+	series map[common.SeriesID]*pendingSeries
+	mu     sync.Mutex
+}
+
+type pendingSeries struct {
+	subject      string
+	entityValues []*modelv1.TagValue
+	count        int
+}
+
+func newPendingWrites() *pendingWrites {
+	return &pendingWrites{series: make(map[common.SeriesID]*pendingSeries)}
+}
+
+func (p *pendingWrites) add(series *pbv1.Series) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ps, ok := p.series[series.ID]
+	if !ok {
+		ps = &pendingSeries{subject: series.Subject, entityValues: series.EntityValues}
+		p.series[series.ID] = ps
+	}
+	ps.count++
+}
+
+// done removes the written or dropped elements of the series.
+func (p *pendingWrites) done(ids []common.SeriesID) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, id := range ids {
+		ps, ok := p.series[id]
+		if !ok {
+			continue
+		}
+		if ps.count--; ps.count <= 0 {
+			delete(p.series, id)
+		}
+	}
+}
+
+// pending reports whether any series of the subject matching one of the entities has elements not written yet.
+// A null entity value matches any value, as pbv1.AnyTagValue does in a query.
+func (p *pendingWrites) pending(subject string, entities [][]*modelv1.TagValue) bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, ps := range p.series {
+		if ps.subject != subject {
+			continue
+		}
+		for _, entity := range entities {
+			if ps.matches(entity) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (ps *pendingSeries) matches(entity []*modelv1.TagValue) bool {
+	if len(entity) > len(ps.entityValues) {
+		return false
+	}
+	for i, v := range entity {
+		if _, isNull := v.GetValue().(*modelv1.TagValue_Null); isNull {
+			continue
+		}
+		if !proto.Equal(v, ps.entityValues[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,98 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+func TestPendingWrites(t *testing.T) {
+	strValue := func(v string) *modelv1.TagValue {
+		return &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: v}}}
+	}
+	p := newPendingWrites()
+	entities := [][]*modelv1.TagValue{{strValue("svc-1"), strValue("a")}, {strValue("svc-2"), strValue("b")}}
+	assert.False(t, p.pending("sw", entities))
+
+	// The series is written for the first time, so it isn't indexed until its elements are written.
+	series := &pbv1.Series{Subject: "sw", EntityValues: []*modelv1.TagValue{strValue("svc-2"), strValue("b")}}
+	if err := series.Marshal(); err != nil {
+		t.Fatal(err)
+	}
+	p.add(series)
+	p.add(series)
+	assert.True(t, p.pending("sw", entities))
+	assert.False(t, p.pending("sw", entities[:1]), "only the series with pending writes are waited for")
+	assert.False(t, p.pending("other", entities), "the series of another stream aren't waited for")
+	assert.True(t, p.pending("sw", [][]*modelv1.TagValue{{strValue("svc-2"), pbv1.AnyTagValue}}), "a wildcard matches any value")
+
+	p.done([]common.SeriesID{series.ID})
+	assert.True(t, p.pending("sw", entities), "one of the elements is still pending")
+	p.done([]common.SeriesID{series.ID})
+	assert.False(t, p.pending("sw", entities))
+	assert.Empty(t, p.series)
+
+	var nilPending *pendingWrites
+	nilPending.add(series)
+	assert.False(t, nilPending.pending("sw", entities))
+}
+
+func TestWaitForFreshnessOfNewSeries(t *testing.T) {
+	entity := []*modelv1.TagValue{{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "svc-1"}}}}
+	series := &pbv1.Series{Subject: "sw", EntityValues: entity}
+	if err := series.Marshal(); err != nil {
+		t.Fatal(err)
+	}
+	p := newPendingWrites()
+	// The series isn't in any series index, as its first element is still pending.
+	p.add(series)
+	s := &stream{name: "sw", pendingWrites: p, maxStalenessWait: time.Minute, l: logger.GetLogger("test")}
+	now := time.Now()
+	tr := timestamp.NewInclusiveTimeRange(now.Add(-time.Hour), now.Add(time.Hour))
+	waited := make(chan error)
+	go func() {
+		waited <- s.waitForFreshness(context.Background(), pbv1.StreamQueryOptions{
+			Name:         "sw",
+			TimeRange:    &tr,
+			Entities:     [][]*modelv1.TagValue{entity},
+			MaxStaleness: time.Second,
+		})
+	}()
+	select {
+	case <-waited:
+		t.Fatal("the query should wait for the pending write of the new series")
+	case <-time.After(100 * time.Millisecond):
+	}
+	p.done([]common.SeriesID{series.ID})
+	select {
+	case err := <-waited:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("the query should go on once the write is done")
+	}
+}
@@ -24,8 +24,6 @@ import (
 	"fmt"
 	"sort"
 
-	"go.uber.org/multierr"
-
 	"github.com/apache/skywalking-banyandb/api/common"
 	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
 	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
@@ -39,6 +37,9 @@ import (
 
 type queryOptions struct {
 	elementRefMap map[common.SeriesID][]int64
+	redactedTags  map[string]struct{}
+	schema        *schemaResolver
+	account       *storage.ReadAccount
 	pbv1.StreamQueryOptions
 	minTimestamp int64
 	maxTimestamp int64
@@ -145,11 +146,15 @@ type queryResult struct {
 	tagNameIndex map[string]partition.TagLocator
 	schema       *databasev1.Stream
 	data         []*blockCursor
+	pending      []*blockCursor
 	snapshots    []*snapshot
 	seriesList   pbv1.SeriesList
 	loaded       bool
 	orderByTS    bool
 	ascTS        bool
+	// lazy loads the blocks once the merge reaches them, which is opted in by BuildCursor.
+	lazy           bool
+	elementIDOrder modelv1.Sort
 }
 
 func (qr *queryResult) Pull() *pbv1.StreamResult {
@@ -157,91 +162,242 @@ func (qr *queryResult) Pull() *pbv1.StreamResult {
 		if len(qr.data) == 0 {
 			return nil
 		}
-
-		cursorChan := make(chan int, len(qr.data))
-		for i := 0; i < len(qr.data); i++ {
-			go func(i int) {
-				tmpBlock := generateBlock()
-				defer releaseBlock(tmpBlock)
-				if !qr.data[i].loadData(tmpBlock) {
-					cursorChan <- i
-					return
-				}
-				if qr.schema.GetEntity() == nil || len(qr.schema.GetEntity().GetTagNames()) == 0 {
-					cursorChan <- -1
-					return
-				}
-				sidIndex := qr.sidToIndex[qr.data[i].bm.seriesID]
-				series := qr.seriesList[sidIndex]
-				entityMap := make(map[string]int)
-				tagFamilyMap := make(map[string]int)
-				for idx, entity := range qr.schema.GetEntity().GetTagNames() {
-					entityMap[entity] = idx + 1
-				}
-				for idx, tagFamily := range qr.data[i].tagFamilies {
-					tagFamilyMap[tagFamily.name] = idx + 1
-				}
-				for _, tagFamilyProj := range qr.data[i].tagProjection {
-					for j, tagProj := range tagFamilyProj.Names {
-						offset := qr.tagNameIndex[tagProj]
-						tagFamilySpec := qr.schema.GetTagFamilies()[offset.FamilyOffset]
-						tagSpec := tagFamilySpec.GetTags()[offset.TagOffset]
-						if tagSpec.IndexedOnly {
-							continue
-						}
-						entityPos := entityMap[tagProj]
-						tagFamilyPos := tagFamilyMap[tagFamilyProj.Family]
-						if entityPos == 0 {
-							continue
-						}
-						if tagFamilyPos == 0 {
-							qr.data[i].tagFamilies[tagFamilyPos-1] = tagFamily{
-								name: tagFamilyProj.Family,
-								tags: make([]tag, 0),
-							}
-						}
-						valueType := pbv1.MustTagValueToValueType(series.EntityValues[entityPos-1])
-						qr.data[i].tagFamilies[tagFamilyPos-1].tags[j] = tag{
-							name:      tagProj,
-							values:    mustEncodeTagValue(tagProj, tagSpec.GetType(), series.EntityValues[entityPos-1], len(qr.data[i].timestamps)),
-							valueType: valueType,
-						}
-					}
-				}
-				if qr.orderByTimestampDesc() {
-					qr.data[i].idx = len(qr.data[i].timestamps) - 1
-				}
-				cursorChan <- -1
-			}(i)
-		}
-
-		blankCursorList := []int{}
-		for completed := 0; completed < len(qr.data); completed++ {
-			result := <-cursorChan
-			if result != -1 {
-				blankCursorList = append(blankCursorList, result)
-			}
-		}
-		sort.Slice(blankCursorList, func(i, j int) bool {
-			return blankCursorList[i] > blankCursorList[j]
-		})
-		for _, index := range blankCursorList {
-			qr.data = append(qr.data[:index], qr.data[index+1:]...)
-		}
-		qr.loaded = true
-		heap.Init(qr)
+		qr.load()
 	}
 	if len(qr.data) == 0 {
 		return nil
 	}
-	if len(qr.data) == 1 {
+	if len(qr.data) == 1 && len(qr.pending) == 0 {
 		r := &pbv1.StreamResult{}
 		bc := qr.data[0]
 		bc.copyAllTo(r, qr.orderByTimestampDesc())
+		r.Series = qr.series(r.SID)
 		qr.data = qr.data[:0]
-		return r
+		return qr.orderByElementID(r)
 	}
-	return qr.merge()
+	return qr.orderByElementID(qr.merge())
+}
+
+func (qr *queryResult) orderByElementID(r *pbv1.StreamResult) *pbv1.StreamResult {
+	if qr.elementIDOrder != modelv1.Sort_SORT_UNSPECIFIED {
+		r.OrderByElementID(qr.elementIDOrder)
+	}
+	return r
+}
+
+// NextBatch returns up to size elements in columns, merging the blocks in the order of the query.
+// It shouldn't be mixed with Pull on the same result.
+func (qr *queryResult) NextBatch(size int) *pbv1.StreamBatch {
+	if !qr.loaded {
+		if len(qr.data) == 0 {
+			return nil
+		}
+		qr.load()
+	}
+	if len(qr.data) == 0 || size <= 0 {
+		return nil
+	}
+	step := 1
+	if qr.orderByTimestampDesc() {
+		step = -1
+	}
+	b := &pbv1.StreamBatch{}
+	for qr.Len() > 0 && b.Len() < size {
+		topBC := qr.data[0]
+		n := 1
+		if qr.Len() == 1 && len(qr.pending) == 0 {
+			// The last block is copied in bulk.
+			n = len(topBC.timestamps) - topBC.idx
+			if step < 0 {
+				n = topBC.idx + 1
+			}
+			n = min(n, size-b.Len())
+		}
+		topBC.appendTo(b, n, step)
+		qr.advance(n, step)
+	}
+	return b
+}
+
+// BuildCursor returns a cursor reading the result in batches of up to batchSize elements.
+// Unlike Pull and NextBatch, the cursor loads a block only once the merge reaches it.
+// It shouldn't be mixed with them on the same result.
+func (qr *queryResult) BuildCursor(batchSize int) pbv1.StreamCursor {
+	qr.lazy = true
+	return &queryCursor{qr: qr, batchSize: batchSize}
+}
+
+type queryCursor struct {
+	qr        *queryResult
+	batchSize int
+}
+
+func (qc *queryCursor) Next() *pbv1.StreamBatch {
+	return qc.qr.NextBatch(qc.batchSize)
+}
+
+// load loads the data of the blocks, dropping the empty ones, and orders the blocks.
+func (qr *queryResult) load() {
+	if qr.lazy {
+		qr.loadLazily()
+		return
+	}
+	qr.data = qr.loadBlocks(qr.data)
+	qr.loaded = true
+	heap.Init(qr)
+}
+
+// loadLazily orders the blocks by the first element they may hold, and loads the leading ones.
+// The other blocks are loaded only when the merge reaches them, and dropped once they are exhausted,
+// so the result holds the blocks overlapping the merge position rather than all the blocks.
+func (qr *queryResult) loadLazily() {
+	qr.pending = append(make([]*blockCursor, 0, len(qr.data)), qr.data...)
+	clear(qr.data)
+	qr.data = qr.data[:0]
+	sort.SliceStable(qr.pending, func(i, j int) bool {
+		return qr.startsBefore(qr.pending[i], qr.pending[j])
+	})
+	qr.loaded = true
+	heap.Init(qr)
+	qr.loadPending()
+}
+
+// startsBefore reports whether the first element the block a may hold is ordered before the one of the block b.
+func (qr *queryResult) startsBefore(a, b *blockCursor) bool {
+	if !qr.orderByTS {
+		if ai, bi := qr.sidToIndex[a.bm.seriesID], qr.sidToIndex[b.bm.seriesID]; ai != bi {
+			return ai < bi
+		}
+		return a.bm.timestamps.min < b.bm.timestamps.min
+	}
+	if qr.ascTS {
+		return a.bm.timestamps.min < b.bm.timestamps.min
+	}
+	return a.bm.timestamps.max > b.bm.timestamps.max
+}
+
+// reaches reports whether the pending block may hold an element ordered no later than the current one of the loaded block.
+func (qr *queryResult) reaches(pending, loaded *blockCursor) bool {
+	if !qr.orderByTS {
+		if pi, li := qr.sidToIndex[pending.bm.seriesID], qr.sidToIndex[loaded.bm.seriesID]; pi != li {
+			return pi < li
+		}
+		return pending.bm.timestamps.min <= loaded.timestamps[loaded.idx]
+	}
+	if qr.ascTS {
+		return pending.bm.timestamps.min <= loaded.timestamps[loaded.idx]
+	}
+	return pending.bm.timestamps.max >= loaded.timestamps[loaded.idx]
+}
+
+// loadPending loads the pending blocks reaching the top of the heap.
+func (qr *queryResult) loadPending() {
+	for len(qr.pending) > 0 {
+		n := 0
+		if qr.Len() == 0 {
+			n = 1
+		} else {
+			for n < len(qr.pending) && qr.reaches(qr.pending[n], qr.data[0]) {
+				n++
+			}
+		}
+		if n == 0 {
+			return
+		}
+		for _, bc := range qr.loadBlocks(qr.pending[:n]) {
+			heap.Push(qr, bc)
+		}
+		clear(qr.pending[:n])
+		qr.pending = qr.pending[n:]
+	}
+}
+
+// advance moves the top block by n elements, drops it once it's exhausted, and loads the blocks reaching the new top.
+func (qr *queryResult) advance(n, step int) {
+	topBC := qr.data[0]
+	topBC.idx += n * step
+	if topBC.idx < 0 || topBC.idx >= len(topBC.timestamps) {
+		heap.Pop(qr)
+	} else {
+		heap.Fix(qr, 0)
+	}
+	qr.loadPending()
+}
+
+// loadBlocks loads the data of the blocks, dropping the empty ones.
+func (qr *queryResult) loadBlocks(blocks []*blockCursor) []*blockCursor {
+	cursorChan := make(chan int, len(blocks))
+	for i := 0; i < len(blocks); i++ {
+		go func(i int) {
+			tmpBlock := generateBlock()
+			defer releaseBlock(tmpBlock)
+			if !blocks[i].loadData(tmpBlock) {
+				cursorChan <- i
+				return
+			}
+			if qr.orderByTimestampDesc() {
+				blocks[i].idx = len(blocks[i].timestamps) - 1
+			}
+			if qr.schema.GetEntity() == nil || len(qr.schema.GetEntity().GetTagNames()) == 0 {
+				cursorChan <- -1
+				return
+			}
+			sidIndex := qr.sidToIndex[blocks[i].bm.seriesID]
+			series := qr.seriesList[sidIndex]
+			entityMap := make(map[string]int)
+			tagFamilyMap := make(map[string]int)
+			for idx, entity := range qr.schema.GetEntity().GetTagNames() {
+				entityMap[entity] = idx + 1
+			}
+			for idx, tagFamily := range blocks[i].tagFamilies {
+				tagFamilyMap[tagFamily.name] = idx + 1
+			}
+			for _, tagFamilyProj := range blocks[i].tagProjection {
+				for j, tagProj := range tagFamilyProj.Names {
+					offset := qr.tagNameIndex[tagProj]
+					tagFamilySpec := qr.schema.GetTagFamilies()[offset.FamilyOffset]
+					tagSpec := tagFamilySpec.GetTags()[offset.TagOffset]
+					if _, redacted := blocks[i].redactedTags[tagProj]; redacted || tagSpec.IndexedOnly {
+						continue
+					}
+					entityPos := entityMap[tagProj]
+					tagFamilyPos := tagFamilyMap[tagFamilyProj.Family]
+					if entityPos == 0 {
+						continue
+					}
+					if tagFamilyPos == 0 {
+						blocks[i].tagFamilies[tagFamilyPos-1] = tagFamily{
+							name: tagFamilyProj.Family,
+							tags: make([]tag, 0),
+						}
+					}
+					valueType := pbv1.MustTagValueToValueType(series.EntityValues[entityPos-1])
+					blocks[i].tagFamilies[tagFamilyPos-1].tags[j] = tag{
+						name:      tagProj,
+						values:    mustEncodeTagValue(tagProj, tagSpec.GetType(), series.EntityValues[entityPos-1], len(blocks[i].timestamps)),
+						valueType: valueType,
+					}
+				}
+			}
+			cursorChan <- -1
+		}(i)
+	}
+
+	blank := make(map[int]struct{})
+	for completed := 0; completed < len(blocks); completed++ {
+		if result := <-cursorChan; result != -1 {
+			blank[result] = struct{}{}
+		}
+	}
+	loaded := make([]*blockCursor, 0, len(blocks)-len(blank))
+	for i := range blocks {
+		if _, ok := blank[i]; ok {
+			releaseBlockCursor(blocks[i])
+			continue
+		}
+		loaded = append(loaded, blocks[i])
+	}
+	return loaded
 }
 
 func (qr *queryResult) Release() {
@@ -250,6 +406,11 @@ func (qr *queryResult) Release() {
 		qr.data[i] = nil
 	}
 	qr.data = qr.data[:0]
+	for i, v := range qr.pending {
+		releaseBlockCursor(v)
+		qr.pending[i] = nil
+	}
+	qr.pending = qr.pending[:0]
 	for i := range qr.snapshots {
 		qr.snapshots[i].decRef()
 	}
@@ -260,10 +421,16 @@ func (qr queryResult) Len() int {
 	return len(qr.data)
 }
 
+// Less orders the blocks by their current elements. Loading the blocks lazily, it orders the elements
+// sharing a timestamp by their series, and the elements of a series by their timestamps,
+// so that the order doesn't depend on the blocks loaded at a time.
 func (qr queryResult) Less(i, j int) bool {
 	leftTS := qr.data[i].timestamps[qr.data[i].idx]
 	rightTS := qr.data[j].timestamps[qr.data[j].idx]
 	if qr.orderByTS {
+		if qr.lazy && leftTS == rightTS {
+			return qr.data[i].bm.seriesID < qr.data[j].bm.seriesID
+		}
 		if qr.ascTS {
 			return leftTS < rightTS
 		}
@@ -271,6 +438,9 @@ func (qr queryResult) Less(i, j int) bool {
 	}
 	leftSIDIndex := qr.sidToIndex[qr.data[i].bm.seriesID]
 	rightSIDIndex := qr.sidToIndex[qr.data[j].bm.seriesID]
+	if qr.lazy && leftSIDIndex == rightSIDIndex {
+		return leftTS < rightTS
+	}
 	return leftSIDIndex < rightSIDIndex
 }
 
@@ -305,31 +475,26 @@ func (qr *queryResult) merge() *pbv1.StreamResult {
 	for qr.Len() > 0 {
 		topBC := qr.data[0]
 		if lastSid != 0 && topBC.bm.seriesID != lastSid {
-			return result
+			break
 		}
 		lastSid = topBC.bm.seriesID
 
 		topBC.copyTo(result)
-		topBC.idx += step
-
-		if qr.orderByTimestampDesc() {
-			if topBC.idx < 0 {
-				heap.Pop(qr)
-			} else {
-				heap.Fix(qr, 0)
-			}
-		} else {
-			if topBC.idx >= len(topBC.timestamps) {
-				heap.Pop(qr)
-			} else {
-				heap.Fix(qr, 0)
-			}
-		}
+		qr.advance(1, step)
 	}
-
+	result.Series = qr.series(lastSid)
 	return result
 }
 
+// series returns the series the query resolved for the ID, or nil if there isn't one.
+func (qr *queryResult) series(sid common.SeriesID) *pbv1.Series {
+	idx, ok := qr.sidToIndex[sid]
+	if !ok || idx >= len(qr.seriesList) {
+		return nil
+	}
+	return qr.seriesList[idx]
+}
+
 func (s *stream) genIndex(tagProj []pbv1.TagProjection, seriesList pbv1.SeriesList) (map[string]int, map[string]*databasev1.TagSpec,
 	map[string]partition.TagLocator, map[common.SeriesID]int,
 ) {
@@ -363,20 +528,43 @@ func (s *stream) genIndex(tagProj []pbv1.TagProjection, seriesList pbv1.SeriesLi
 	return entityMap, tagSpecIndex, tagProjIndex, sidToIndex
 }
 
+// selectTSTables selects the tables in the time range of the query, only from the shards the query is pinned to if any.
+func selectTSTables(tsdb storage.TSDB[*tsTable, option], sqo pbv1.StreamQueryOptions) ([]storage.TSTableWrapper[*tsTable], error) {
+	if len(sqo.ShardIDs) == 0 {
+		return tsdb.SelectTSTables(*sqo.TimeRange), nil
+	}
+	return tsdb.SelectShardTSTables(sqo.ShardIDs, *sqo.TimeRange)
+}
+
 func (s *stream) Query(ctx context.Context, sqo pbv1.StreamQueryOptions) (pbv1.StreamQueryResult, error) {
+	ctx, done, err := s.drainer.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.query(ctx, sqo)
+	return newDrainedResult(ctx, done, result, err)
+}
+
+func (s *stream) query(ctx context.Context, sqo pbv1.StreamQueryOptions) (pbv1.StreamQueryResult, error) {
 	if sqo.TimeRange == nil || len(sqo.Entities) < 1 {
 		return nil, errors.New("invalid query options: timeRange and series are required")
 	}
 	if len(sqo.TagProjection) == 0 {
 		return nil, errors.New("invalid query options: tagProjection is required")
 	}
+	if err := s.waitForFreshness(ctx, sqo); err != nil {
+		return nil, err
+	}
 	db := s.databaseSupplier.SupplyTSDB()
 	var result queryResult
 	if db == nil {
 		return &result, nil
 	}
 	tsdb := db.(storage.TSDB[*tsTable, option])
-	tabWrappers := tsdb.SelectTSTables(*sqo.TimeRange)
+	tabWrappers, err := selectTSTables(tsdb, sqo)
+	if err != nil {
+		return nil, err
+	}
 	defer func() {
 		for i := range tabWrappers {
 			tabWrappers[i].DecRef()
@@ -397,15 +585,15 @@ func (s *stream) Query(ctx context.Context, sqo pbv1.StreamQueryOptions) (pbv1.S
 	if len(sl) < 1 {
 		return &result, nil
 	}
-	var sids []common.SeriesID
-	for i := range sl {
-		sids = append(sids, sl[i].ID)
-	}
 	var parts []*part
+	minTimestamp, maxTimestamp := sqo.TimeRange.UnixNanoBounds()
 	qo := queryOptions{
 		StreamQueryOptions: sqo,
-		minTimestamp:       sqo.TimeRange.Start.UnixNano(),
-		maxTimestamp:       sqo.TimeRange.End.UnixNano(),
+		minTimestamp:       minTimestamp,
+		maxTimestamp:       maxTimestamp,
+		redactedTags:       s.redactedTags(ctx),
+		schema:             s.schemaResolver(),
+		account:            storage.ReadAccountFrom(ctx),
 	}
 	var n int
 	for i := range tabWrappers {
@@ -413,13 +601,23 @@ func (s *stream) Query(ctx context.Context, sqo pbv1.StreamQueryOptions) (pbv1.S
 		if s == nil {
 			continue
 		}
-		parts, n = s.getParts(parts, qo.minTimestamp, qo.maxTimestamp)
+		parts, n = s.selectParts(parts, qo.minTimestamp, qo.maxTimestamp, qo.FlushedOnly)
 		if n < 1 {
 			s.decRef()
 			continue
 		}
 		result.snapshots = append(result.snapshots, s)
 	}
+	if err := s.loadResult(&result, parts, sl, qo); err != nil {
+		result.Release()
+		return nil, err
+	}
+	return &result, nil
+}
+
+// loadResult fills the result with the cursors over the blocks of the series in the parts.
+func (s *stream) loadResult(result *queryResult, parts []*part, sl pbv1.SeriesList, qo queryOptions) error {
+	sids := sl.IDs()
 	bma := generateBlockMetadataArray()
 	defer releaseBlockMetadataArray(bma)
 	// TODO: cache tstIter
@@ -428,9 +626,10 @@ func (s *stream) Query(ctx context.Context, sqo pbv1.StreamQueryOptions) (pbv1.S
 	originalSids := make([]common.SeriesID, len(sids))
 	copy(originalSids, sids)
 	sort.Slice(sids, func(i, j int) bool { return sids[i] < sids[j] })
+	ti.account = qo.account
 	ti.init(bma, parts, sids, qo.minTimestamp, qo.maxTimestamp)
 	if ti.Error() != nil {
-		return nil, fmt.Errorf("cannot init tstIter: %w", ti.Error())
+		return fmt.Errorf("cannot init tstIter: %w", ti.Error())
 	}
 	for ti.nextBlock() {
 		bc := generateBlockCursor()
@@ -439,10 +638,10 @@ func (s *stream) Query(ctx context.Context, sqo pbv1.StreamQueryOptions) (pbv1.S
 		result.data = append(result.data, bc)
 	}
 	if ti.Error() != nil {
-		return nil, fmt.Errorf("cannot iterate tstIter: %w", ti.Error())
+		return fmt.Errorf("cannot iterate tstIter: %w", ti.Error())
 	}
 
-	entityMap, _, _, sidToIndex := s.genIndex(sqo.TagProjection, sl)
+	entityMap, _, _, sidToIndex := s.genIndex(qo.TagProjection, sl)
 	result.entityMap = entityMap
 	result.sidToIndex = sidToIndex
 	result.tagNameIndex = make(map[string]partition.TagLocator)
@@ -460,34 +659,55 @@ func (s *stream) Query(ctx context.Context, sqo pbv1.StreamQueryOptions) (pbv1.S
 		}
 	}
 	result.orderByTS = true
-	if sqo.Order == nil {
+	result.elementIDOrder = qo.ElementIDOrder
+	if qo.Order == nil {
 		result.ascTS = true
-		return &result, nil
+		return nil
 	}
-	if sqo.Order.Sort == modelv1.Sort_SORT_ASC || sqo.Order.Sort == modelv1.Sort_SORT_UNSPECIFIED {
+	if qo.Order.Sort == modelv1.Sort_SORT_ASC || qo.Order.Sort == modelv1.Sort_SORT_UNSPECIFIED {
 		result.ascTS = true
 	}
-	return &result, nil
+	return nil
 }
 
-func (s *stream) Sort(ctx context.Context, sqo pbv1.StreamQueryOptions) (ssr pbv1.StreamSortResult, err error) {
+func (s *stream) Sort(ctx context.Context, sqo pbv1.StreamQueryOptions) (pbv1.StreamSortResult, error) {
 	if sqo.TimeRange == nil || len(sqo.Entities) < 1 {
 		return nil, errors.New("invalid query options: timeRange and series are required")
 	}
 	if len(sqo.TagProjection) == 0 {
 		return nil, errors.New("invalid query options: tagProjection is required")
 	}
+	ctx, done, err := s.drainer.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ssr, err := s.sort(ctx, sqo)
+	if err != nil || ssr == nil {
+		done()
+		return nil, err
+	}
+	ssr.ctx, ssr.done = ctx, done
+	return ssr, nil
+}
+
+func (s *stream) sort(ctx context.Context, sqo pbv1.StreamQueryOptions) (*sortResult, error) {
+	if err := s.waitForFreshness(ctx, sqo); err != nil {
+		return nil, err
+	}
 	db := s.databaseSupplier.SupplyTSDB()
 	if db == nil {
-		return ssr, nil
+		return nil, nil
 	}
 	tsdb := db.(storage.TSDB[*tsTable, option])
-	tabWrappers := tsdb.SelectTSTables(*sqo.TimeRange)
-	defer func() {
+	tabWrappers, err := selectTSTables(tsdb, sqo)
+	if err != nil {
+		return nil, err
+	}
+	release := func() {
 		for i := range tabWrappers {
 			tabWrappers[i].DecRef()
 		}
-	}()
+	}
 
 	series := make([]*pbv1.Series, len(sqo.Entities))
 	for i := range sqo.Entities {
@@ -498,63 +718,155 @@ func (s *stream) Sort(ctx context.Context, sqo pbv1.StreamQueryOptions) (ssr pbv
 	}
 	seriesList, err := tsdb.Lookup(ctx, series)
 	if err != nil {
+		release()
 		return nil, err
 	}
 	if len(seriesList) == 0 {
-		return ssr, nil
+		release()
+		return nil, nil
 	}
 
-	iters, err := s.buildSeriesByIndex(tabWrappers, seriesList, sqo)
+	iters, err := s.buildSeriesByIndex(tabWrappers, seriesList, sqo, s.redactedTags(ctx), storage.ReadAccountFrom(ctx))
 	if err != nil {
+		release()
 		return nil, err
 	}
 	if len(iters) == 0 {
-		return ssr, nil
-	}
+		release()
+		return nil, nil
+	}
+	return &sortResult{
+		it:      newItemIter(iters, sortOrder(sqo.Order, sqo.ThenBy)),
+		sqo:     sqo,
+		release: release,
+		l:       s.l,
+	}, nil
+}
 
-	it := newItemIter(iters, sqo.Order.Sort)
-	defer func() {
-		err = multierr.Append(err, it.Close())
-	}()
+// sortBatchSize is the number of the elements a sort result pulls at a time.
+const sortBatchSize = 1000
+
+// sortResult walks the merged index iterators lazily, so that the caller holds a batch of the sorted elements at a time.
+// The scan stops at MaxElementSize elements, it isn't bounded if MaxElementSize is not positive.
+type sortResult struct {
+	ctx     context.Context
+	it      itersort.Iterator[item]
+	l       *logger.Logger
+	release func()
+	done    func()
+	sqo     pbv1.StreamQueryOptions
+	pulled  int
+}
 
+func (sr *sortResult) Pull() *pbv1.StreamColumnResult {
+	if sr.ctx.Err() != nil {
+		return nil
+	}
+	size := sortBatchSize
+	if sr.sqo.MaxElementSize > 0 {
+		if size = sr.sqo.MaxElementSize - sr.pulled; size > sortBatchSize {
+			size = sortBatchSize
+		}
+	}
+	if size <= 0 {
+		return nil
+	}
 	ces := newColumnElements()
-	for it.Next() {
-		nextItem := it.Val()
-		e := nextItem.element
-		ces.BuildFromElement(e, sqo.TagProjection)
-		if len(ces.timestamp) >= sqo.MaxElementSize {
-			break
+	for len(ces.timestamp) < size && sr.it.Next() {
+		e := sr.it.Val().element
+		if !pbv1.Sampled(e.elementID, sr.sqo.SampleInterval) {
+			continue
 		}
+		ces.BuildFromElement(e, sr.sqo.TagProjection)
+	}
+	if len(ces.timestamp) == 0 {
+		return nil
+	}
+	sr.pulled += len(ces.timestamp)
+	return ces.Pull()
+}
+
+func (sr *sortResult) Release() {
+	if err := sr.it.Close(); err != nil {
+		sr.l.Warn().Err(err).Msg("failed to close the sorted iterators")
 	}
-	return ces, err
+	sr.release()
+	sr.done()
 }
 
 // newItemIter returns a ItemIterator which mergers several tsdb.Iterator by input sorting order.
-func newItemIter(iters []*searcherIterator, s modelv1.Sort) itersort.Iterator[item] {
+func newItemIter(iters []*searcherIterator, spec itersort.OrderSpec) itersort.Iterator[item] {
 	var ii []itersort.Iterator[item]
 	for _, iter := range iters {
 		ii = append(ii, iter)
 	}
-	if s == modelv1.Sort_SORT_DESC {
-		return itersort.NewItemIter[item](ii, true)
+	return itersort.NewOrderedItemIter[item](ii, spec)
+}
+
+// sortOrder sorts by the sorted tag, then by the then-by tags in their own directions.
+// The elements sharing all of them are ordered by their IDs, as the coordinator of a distributed query does.
+func sortOrder(order *pbv1.OrderBy, thenBy []*pbv1.OrderBy) itersort.OrderSpec {
+	desc := make([]bool, 0, len(thenBy)+2)
+	desc = append(desc, order.Sort == modelv1.Sort_SORT_DESC)
+	for _, o := range thenBy {
+		desc = append(desc, o.Sort == modelv1.Sort_SORT_DESC)
+	}
+	return itersort.NewOrderSpec(append(desc, false)...)
+}
+
+// firstElementRefs returns the first max references in the order of their timestamps, along with the rest of the references
+// sharing the timestamp of the last one, so that their elements are sorted by IDs as the results of an unlimited query are.
+func firstElementRefs(refLists [][]elementRef, order *pbv1.OrderBy, max int) []elementRef {
+	var refs []elementRef
+	for _, erl := range refLists {
+		refs = append(refs, erl...)
+	}
+	if len(refs) <= max {
+		return refs
+	}
+	desc := order != nil && order.Sort == modelv1.Sort_SORT_DESC
+	sort.SliceStable(refs, func(i, j int) bool {
+		if desc {
+			return refs[i].timestamp > refs[j].timestamp
+		}
+		return refs[i].timestamp < refs[j].timestamp
+	})
+	n := max
+	for n > 0 && n < len(refs) && refs[n].timestamp == refs[n-1].timestamp {
+		n++
+	}
+	return refs[:n]
+}
+
+func (s *stream) Filter(ctx context.Context, sqo pbv1.StreamQueryOptions) (pbv1.StreamQueryResult, error) {
+	ctx, done, err := s.drainer.begin(ctx)
+	if err != nil {
+		return nil, err
 	}
-	return itersort.NewItemIter[item](ii, false)
+	result, err := s.filter(ctx, sqo)
+	return newDrainedResult(ctx, done, result, err)
 }
 
-func (s *stream) Filter(ctx context.Context, sqo pbv1.StreamQueryOptions) (sqr pbv1.StreamQueryResult, err error) {
+func (s *stream) filter(ctx context.Context, sqo pbv1.StreamQueryOptions) (sqr pbv1.StreamQueryResult, err error) {
 	if sqo.TimeRange == nil || len(sqo.Entities) < 1 {
 		return nil, errors.New("invalid query options: timeRange and series are required")
 	}
 	if len(sqo.TagProjection) == 0 {
 		return nil, errors.New("invalid query options: tagProjection is required")
 	}
+	if err = s.waitForFreshness(ctx, sqo); err != nil {
+		return nil, err
+	}
 	db := s.databaseSupplier.SupplyTSDB()
 	var result queryResult
 	if db == nil {
 		return sqr, nil
 	}
 	tsdb := db.(storage.TSDB[*tsTable, option])
-	tabWrappers := tsdb.SelectTSTables(*sqo.TimeRange)
+	tabWrappers, err := selectTSTables(tsdb, sqo)
+	if err != nil {
+		return nil, err
+	}
 	defer func() {
 		for i := range tabWrappers {
 			tabWrappers[i].DecRef()
@@ -576,19 +888,11 @@ func (s *stream) Filter(ctx context.Context, sqo pbv1.StreamQueryOptions) (sqr p
 		return sqr, nil
 	}
 
-	var elementRefList []elementRef
-	for _, tw := range tabWrappers {
-		index := tw.Table().Index()
-		erl, err := index.Search(ctx, seriesList, sqo.Filter, sqo.TimeRange)
-		if err != nil {
-			return nil, err
-		}
-		elementRefList = append(elementRefList, erl...)
-		if len(elementRefList) > sqo.MaxElementSize {
-			elementRefList = elementRefList[:sqo.MaxElementSize]
-			break
-		}
+	refLists, err := searchIndexes(ctx, tableIndexes(tabWrappers), seriesList, sqo.Filter, sqo.TimeRange, s.queryParallelism)
+	if err != nil {
+		return nil, err
 	}
+	elementRefList := firstElementRefs(refLists, sqo.Order, sqo.MaxElementSize)
 	var elementRefMap map[common.SeriesID][]int64
 	if len(elementRefList) != 0 {
 		elementRefMap = make(map[common.SeriesID][]int64)
@@ -600,11 +904,15 @@ func (s *stream) Filter(ctx context.Context, sqo pbv1.StreamQueryOptions) (sqr p
 			}
 		}
 	}
+	minTimestamp, maxTimestamp := sqo.TimeRange.UnixNanoBounds()
 	qo := queryOptions{
 		StreamQueryOptions: sqo,
-		minTimestamp:       sqo.TimeRange.Start.UnixNano(),
-		maxTimestamp:       sqo.TimeRange.End.UnixNano(),
+		minTimestamp:       minTimestamp,
+		maxTimestamp:       maxTimestamp,
 		elementRefMap:      elementRefMap,
+		redactedTags:       s.redactedTags(ctx),
+		schema:             s.schemaResolver(),
+		account:            storage.ReadAccountFrom(ctx),
 	}
 
 	var parts []*part
@@ -614,7 +922,7 @@ func (s *stream) Filter(ctx context.Context, sqo pbv1.StreamQueryOptions) (sqr p
 		if s == nil {
 			continue
 		}
-		parts, n = s.getParts(parts, qo.minTimestamp, qo.maxTimestamp)
+		parts, n = s.selectParts(parts, qo.minTimestamp, qo.maxTimestamp, qo.FlushedOnly)
 		if n < 1 {
 			s.decRef()
 			continue
@@ -639,6 +947,7 @@ func (s *stream) Filter(ctx context.Context, sqo pbv1.StreamQueryOptions) (sqr p
 	originalSids := make([]common.SeriesID, len(sids))
 	copy(originalSids, sids)
 	sort.Slice(sids, func(i, j int) bool { return sids[i] < sids[j] })
+	ti.account = qo.account
 	ti.init(bma, parts, sids, qo.minTimestamp, qo.maxTimestamp)
 	if ti.Error() != nil {
 		return nil, fmt.Errorf("cannot init tstIter: %w", ti.Error())
@@ -671,6 +980,7 @@ func (s *stream) Filter(ctx context.Context, sqo pbv1.StreamQueryOptions) (sqr p
 		}
 	}
 	result.orderByTS = true
+	result.elementIDOrder = sqo.ElementIDOrder
 	if sqo.Order == nil {
 		result.ascTS = true
 		return &result, nil
@@ -0,0 +1,288 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/pkg/convert"
+	"github.com/apache/skywalking-banyandb/pkg/fs"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/test"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+func openTestTable(tb testing.TB, esList ...*elements) *tsTable {
+	tmpPath, defFn := test.Space(require.New(tb))
+	tb.Cleanup(defFn)
+	tst, err := newTSTable(fs.NewLocalFileSystem(), tmpPath, common.Position{},
+		logger.GetLogger("test"), timestamp.TimeRange{}, option{flushTimeout: 0, mergePolicy: newDisabledMergePolicyForTesting()})
+	require.NoError(tb, err)
+	tb.Cleanup(func() { tst.Close() })
+	for _, es := range esList {
+		tst.mustAddElements(es)
+	}
+	return tst
+}
+
+// newTestResult opens a result over the series of the table ordered by the timestamps ascending.
+func newTestResult(tb testing.TB, tst *tsTable, sids []common.SeriesID, projection []pbv1.TagProjection) *queryResult {
+	s := tst.currentSnapshot()
+	require.NotNil(tb, s)
+	qo := queryOptions{minTimestamp: 0, maxTimestamp: 1 << 62}
+	qo.TagProjection = projection
+	pp, _ := s.getParts(nil, qo.minTimestamp, qo.maxTimestamp)
+	bma := generateBlockMetadataArray()
+	defer releaseBlockMetadataArray(bma)
+	ti := &tstIter{}
+	defer ti.reset()
+	ti.init(bma, pp, sids, qo.minTimestamp, qo.maxTimestamp)
+	result := &queryResult{orderByTS: true, ascTS: true, snapshots: []*snapshot{s}}
+	for ti.nextBlock() {
+		bc := generateBlockCursor()
+		bc.init(ti.piHeap[0].p, ti.piHeap[0].curBlock, qo)
+		result.data = append(result.data, bc)
+	}
+	require.NoError(tb, ti.Error())
+	return result
+}
+
+// columnValue converts a value of a column back to a TagValue.
+func columnValue(c *pbv1.Column, i int) *modelv1.TagValue {
+	if c.Nulls[i] {
+		return pbv1.NullTagValue
+	}
+	if c.Type == pbv1.ValueTypeInt64 {
+		return int64TagValue(c.Ints[i])
+	}
+	return mustDecodeTagValue(c.Type, c.Bytes.Get(i))
+}
+
+func TestQueryResultNextBatch(t *testing.T) {
+	tst := openTestTable(t, esTS1, esTS2)
+	sids := []common.SeriesID{1, 2}
+	projection := tagProjections[1]
+
+	// rows maps the element IDs to their tags, which are null for the second series missing the projected families.
+	want := make(map[string][]*modelv1.TagValue)
+	pulled := newTestResult(t, tst, sids, projection)
+	defer pulled.Release()
+	for r := pulled.Pull(); r != nil; r = pulled.Pull() {
+		for i, id := range r.ElementIDs {
+			for _, tf := range r.TagFamilies {
+				for _, tag := range tf.Tags {
+					want[id] = append(want[id], tag.Values[i])
+				}
+			}
+		}
+	}
+	require.Len(t, want, 4)
+
+	for _, size := range []int{1, 3, 100} {
+		batched := newTestResult(t, tst, sids, projection)
+		got := make(map[string][]*modelv1.TagValue)
+		var timestamps []int64
+		for b := batched.NextBatch(size); b != nil && b.Len() > 0; b = batched.NextBatch(size) {
+			require.LessOrEqual(t, b.Len(), size)
+			timestamps = append(timestamps, b.Timestamps...)
+			for i, id := range b.ElementIDs {
+				for _, cf := range b.TagFamilies {
+					for j := range cf.Columns {
+						require.Equal(t, b.Len(), cf.Columns[j].Len())
+						got[id] = append(got[id], columnValue(&cf.Columns[j], i))
+					}
+				}
+			}
+		}
+		batched.Release()
+		require.IsNonDecreasing(t, timestamps, "size %d", size)
+		if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+			t.Errorf("unexpected batches of size %d (-want +got):\n%s", size, diff)
+		}
+	}
+}
+
+// BenchmarkQueryResultScan compares scanning a wide projection row by row with scanning it in columnar batches.
+func BenchmarkQueryResultScan(b *testing.B) {
+	const (
+		total    = 100000
+		intTags  = 8
+		strTags  = 8
+		batchLen = 1024
+	)
+	var names []string
+	for i := 0; i < intTags+strTags; i++ {
+		names = append(names, "tag"+strconv.Itoa(i))
+	}
+	es := &elements{}
+	for i := 0; i < total; i++ {
+		es.seriesIDs = append(es.seriesIDs, common.SeriesID(i%10+1))
+		es.timestamps = append(es.timestamps, int64(i+1))
+		es.elementIDs = append(es.elementIDs, strconv.Itoa(i))
+		tf := tagValues{tag: "wide"}
+		for j, name := range names {
+			if j < intTags {
+				tf.values = append(tf.values, &tagValue{tag: name, valueType: pbv1.ValueTypeInt64, value: convert.Int64ToBytes(int64(i * j))})
+			} else {
+				tf.values = append(tf.values, &tagValue{tag: name, valueType: pbv1.ValueTypeStr, value: []byte("value-" + strconv.Itoa(i%100))})
+			}
+		}
+		es.tagFamilies = append(es.tagFamilies, []tagValues{tf})
+	}
+	tst := openTestTable(b, es)
+	sids := []common.SeriesID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	projection := []pbv1.TagProjection{{Family: "wide", Names: names}}
+
+	b.Run("row", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			result := newTestResult(b, tst, sids, projection)
+			var sum int64
+			for r := result.Pull(); r != nil; r = result.Pull() {
+				for _, v := range r.TagFamilies[0].Tags[0].Values {
+					sum += v.GetInt().GetValue()
+				}
+			}
+			result.Release()
+		}
+	})
+	b.Run("batch", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			result := newTestResult(b, tst, sids, projection)
+			var sum int64
+			for batch := result.NextBatch(batchLen); batch != nil && batch.Len() > 0; batch = result.NextBatch(batchLen) {
+				for _, v := range batch.TagFamilies[0].Columns[0].Ints {
+					sum += v
+				}
+			}
+			result.Release()
+		}
+	})
+}
+
+func TestQueryResultLoadsBlocksLazily(t *testing.T) {
+	const parts = 8
+	var esList []*elements
+	for i := 0; i < parts; i++ {
+		es := &elements{}
+		for j := 0; j < 3; j++ {
+			es.seriesIDs = append(es.seriesIDs, 1)
+			es.timestamps = append(es.timestamps, int64(i*10+j+1))
+			es.elementIDs = append(es.elementIDs, strconv.Itoa(i*10+j))
+			es.tagFamilies = append(es.tagFamilies, []tagValues{{tag: "singleTag", values: []*tagValue{
+				{tag: "intTag", valueType: pbv1.ValueTypeInt64, value: convert.Int64ToBytes(int64(j))},
+			}}})
+		}
+		esList = append(esList, es)
+	}
+	tst := openTestTable(t, esList...)
+	for _, asc := range []bool{true, false} {
+		result := newTestResult(t, tst, []common.SeriesID{1}, []pbv1.TagProjection{{Family: "singleTag", Names: []string{"intTag"}}})
+		result.ascTS = asc
+		require.Len(t, result.data, parts)
+		var timestamps []int64
+		cursor := result.BuildCursor(2)
+		for b := cursor.Next(); b != nil && b.Len() > 0; b = cursor.Next() {
+			// The parts don't overlap, so a block is only loaded once the previous one is exhausted.
+			require.LessOrEqual(t, result.Len(), 1)
+			timestamps = append(timestamps, b.Timestamps...)
+		}
+		result.Release()
+		require.Len(t, timestamps, parts*3)
+		if asc {
+			require.IsIncreasing(t, timestamps)
+		} else {
+			require.IsDecreasing(t, timestamps)
+		}
+	}
+}
+
+// BenchmarkQueryResultPeakHeap compares the peak heap of materializing the rows of a long time range
+// with the one of streaming them through a cursor, which only holds the blocks reaching the merge position.
+func BenchmarkQueryResultPeakHeap(b *testing.B) {
+	const (
+		parts    = 50
+		perPart  = 2000
+		batchLen = 1024
+	)
+	var esList []*elements
+	for i := 0; i < parts; i++ {
+		es := &elements{}
+		for j := 0; j < perPart; j++ {
+			ts := int64(i*perPart + j + 1)
+			es.seriesIDs = append(es.seriesIDs, common.SeriesID(j%2+1))
+			es.timestamps = append(es.timestamps, ts)
+			es.elementIDs = append(es.elementIDs, strconv.FormatInt(ts, 10))
+			es.tagFamilies = append(es.tagFamilies, []tagValues{{tag: "singleTag", values: []*tagValue{
+				{tag: "strTag", valueType: pbv1.ValueTypeStr, value: []byte("value-" + strconv.Itoa(j))},
+			}}})
+		}
+		esList = append(esList, es)
+	}
+	tst := openTestTable(b, esList...)
+	sids := []common.SeriesID{1, 2}
+	projection := []pbv1.TagProjection{{Family: "singleTag", Names: []string{"strTag"}}}
+	var ms runtime.MemStats
+	heapAlloc := func() uint64 {
+		runtime.ReadMemStats(&ms)
+		return ms.HeapAlloc
+	}
+
+	b.Run("materialized", func(b *testing.B) {
+		b.ReportAllocs()
+		var peak uint64
+		for i := 0; i < b.N; i++ {
+			runtime.GC()
+			base := heapAlloc()
+			result := newTestResult(b, tst, sids, projection)
+			var rows []*pbv1.StreamResult
+			for r := result.Pull(); r != nil; r = result.Pull() {
+				rows = append(rows, r)
+				peak = max(peak, heapAlloc()-min(base, heapAlloc()))
+			}
+			result.Release()
+			runtime.KeepAlive(rows)
+		}
+		b.ReportMetric(float64(peak), "peak-heap-B")
+	})
+	b.Run("cursor", func(b *testing.B) {
+		b.ReportAllocs()
+		var peak uint64
+		for i := 0; i < b.N; i++ {
+			runtime.GC()
+			base := heapAlloc()
+			result := newTestResult(b, tst, sids, projection)
+			cursor := result.BuildCursor(batchLen)
+			for batch := cursor.Next(); batch != nil && batch.Len() > 0; batch = cursor.Next() {
+				peak = max(peak, heapAlloc()-min(base, heapAlloc()))
+			}
+			result.Release()
+		}
+		b.ReportMetric(float64(peak), "peak-heap-B")
+	})
+}
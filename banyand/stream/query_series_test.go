@@ -0,0 +1,73 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/test/flags"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+var _ = Describe("The series of the query results", func() {
+	now := time.Now()
+	tr := timestamp.NewInclusiveTimeRange(now.Add(-time.Hour), now.Add(time.Hour))
+	var svcs *services
+	var deferFn func()
+
+	BeforeEach(func() {
+		svcs, deferFn = setUp()
+		waitForStream(svcs)
+	})
+
+	AfterEach(func() {
+		deferFn()
+	})
+
+	It("carries the entity values of the series", func() {
+		writeElement(svcs, newWriteRequest("labeled", now))
+		Eventually(func() []string { return queryElementIDs(svcs, tr, 0) }).WithTimeout(flags.EventuallyTimeout).Should(ConsistOf("labeled"))
+
+		s, err := svcs.stream.Stream(swMetadata)
+		Expect(err).ShouldNot(HaveOccurred())
+		result, err := s.Query(context.Background(), pbv1.StreamQueryOptions{
+			Name:          swMetadata.Name,
+			TimeRange:     &tr,
+			Entities:      [][]*modelv1.TagValue{swEntity},
+			TagProjection: []pbv1.TagProjection{{Family: "searchable", Names: []string{"trace_id"}}},
+		})
+		Expect(err).ShouldNot(HaveOccurred())
+		defer result.Release()
+		r := result.Pull()
+		Expect(r).NotTo(BeNil())
+		Expect(r.Series).NotTo(BeNil())
+		Expect(r.Series.ID).To(Equal(r.SID))
+		Expect(r.Series.Subject).To(Equal(swMetadata.Name))
+		Expect(r.Series.EntityValues).To(HaveLen(3))
+		for i, v := range swEntity {
+			Expect(r.Series.EntityValues[i]).To(BeComparableTo(v, protocmp.Transform()))
+		}
+	})
+})
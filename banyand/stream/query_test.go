@@ -21,6 +21,7 @@ import (
 	"context"
 	"errors"
 	"sort"
+	"strconv"
 	"testing"
 	"time"
 
@@ -30,6 +31,7 @@ import (
 
 	"github.com/apache/skywalking-banyandb/api/common"
 	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/pkg/convert"
 	"github.com/apache/skywalking-banyandb/pkg/fs"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
 	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
@@ -41,15 +43,17 @@ import (
 
 func TestQueryResult(t *testing.T) {
 	tests := []struct {
-		wantErr       error
-		name          string
-		esList        []*elements
-		sids          []common.SeriesID
-		want          []pbv1.StreamResult
-		minTimestamp  int64
-		maxTimestamp  int64
-		orderBySeries bool
-		ascTS         bool
+		wantErr        error
+		elementIDRange *pbv1.ElementIDRange
+		name           string
+		esList         []*elements
+		sids           []common.SeriesID
+		want           []pbv1.StreamResult
+		minTimestamp   int64
+		maxTimestamp   int64
+		elementIDOrder modelv1.Sort
+		orderBySeries  bool
+		ascTS          bool
 	}{
 		{
 			name:         "Test with multiple parts with duplicated data order by TS",
@@ -325,6 +329,93 @@ func TestQueryResult(t *testing.T) {
 				TagFamilies: nil,
 			}},
 		},
+		{
+			name:           "Test with the elements of a series ordered by element ID",
+			esList:         []*elements{esTS1, esTS2},
+			sids:           []common.SeriesID{3},
+			minTimestamp:   1,
+			maxTimestamp:   2,
+			elementIDOrder: modelv1.Sort_SORT_ASC,
+			want: []pbv1.StreamResult{{
+				SID:         3,
+				Timestamps:  []int64{1, 2},
+				ElementIDs:  []string{"31", "32"},
+				TagFamilies: nil,
+			}},
+		},
+		{
+			name:           "Test with element ID range",
+			esList:         []*elements{esTS1, esTS2},
+			sids:           []common.SeriesID{2, 1, 3},
+			orderBySeries:  true,
+			minTimestamp:   1,
+			maxTimestamp:   2,
+			elementIDRange: &pbv1.ElementIDRange{From: "12", To: "21"},
+			want: []pbv1.StreamResult{{
+				SID:        2,
+				Timestamps: []int64{1},
+				ElementIDs: []string{"21"},
+				TagFamilies: []pbv1.TagFamily{
+					{Name: "singleTag", Tags: []pbv1.Tag{
+						{Name: "strTag1", Values: []*modelv1.TagValue{strTagValue("tag1")}},
+						{Name: "strTag2", Values: []*modelv1.TagValue{strTagValue("tag2")}},
+					}},
+				},
+			}, {
+				SID:        1,
+				Timestamps: []int64{2},
+				ElementIDs: []string{"12"},
+				TagFamilies: []pbv1.TagFamily{
+					{Name: "arrTag", Tags: []pbv1.Tag{
+						{Name: "strArrTag", Values: []*modelv1.TagValue{strArrTagValue([]string{"value5", "value6"})}},
+						{Name: "intArrTag", Values: []*modelv1.TagValue{int64ArrTagValue([]int64{35, 40})}},
+					}},
+					{Name: "binaryTag", Tags: []pbv1.Tag{
+						{Name: "binaryTag", Values: []*modelv1.TagValue{binaryDataTagValue(longText)}},
+					}},
+					{Name: "singleTag", Tags: []pbv1.Tag{
+						{Name: "strTag", Values: []*modelv1.TagValue{strTagValue("value3")}},
+						{Name: "intTag", Values: []*modelv1.TagValue{int64TagValue(30)}},
+					}},
+				},
+			}},
+		},
+		{
+			name:           "Test with open-ended element ID range and a missing endpoint",
+			esList:         []*elements{esTS1, esTS2},
+			sids:           []common.SeriesID{2, 1, 3},
+			orderBySeries:  true,
+			minTimestamp:   1,
+			maxTimestamp:   2,
+			elementIDRange: &pbv1.ElementIDRange{To: "15"},
+			want: []pbv1.StreamResult{{
+				SID:        1,
+				Timestamps: []int64{1, 2},
+				ElementIDs: []string{"11", "12"},
+				TagFamilies: []pbv1.TagFamily{
+					{Name: "arrTag", Tags: []pbv1.Tag{
+						{Name: "strArrTag", Values: []*modelv1.TagValue{strArrTagValue([]string{"value1", "value2"}), strArrTagValue([]string{"value5", "value6"})}},
+						{Name: "intArrTag", Values: []*modelv1.TagValue{int64ArrTagValue([]int64{25, 30}), int64ArrTagValue([]int64{35, 40})}},
+					}},
+					{Name: "binaryTag", Tags: []pbv1.Tag{
+						{Name: "binaryTag", Values: []*modelv1.TagValue{binaryDataTagValue(longText), binaryDataTagValue(longText)}},
+					}},
+					{Name: "singleTag", Tags: []pbv1.Tag{
+						{Name: "strTag", Values: []*modelv1.TagValue{strTagValue("value1"), strTagValue("value3")}},
+						{Name: "intTag", Values: []*modelv1.TagValue{int64TagValue(10), int64TagValue(30)}},
+					}},
+				},
+			}},
+		},
+		{
+			name:           "Test with element ID range starting after the data",
+			esList:         []*elements{esTS1, esTS2},
+			sids:           []common.SeriesID{2, 1, 3},
+			orderBySeries:  true,
+			minTimestamp:   1,
+			maxTimestamp:   2,
+			elementIDRange: &pbv1.ElementIDRange{From: "33"},
+		},
 	}
 	bma := generateBlockMetadataArray()
 	defer releaseBlockMetadataArray(bma)
@@ -336,6 +427,7 @@ func TestQueryResult(t *testing.T) {
 					minTimestamp: tt.minTimestamp,
 					maxTimestamp: tt.maxTimestamp,
 				}
+				queryOpts.ElementIDRange = tt.elementIDRange
 				s := tst.currentSnapshot()
 				require.NotNil(t, s)
 				defer s.decRef()
@@ -348,7 +440,7 @@ func TestQueryResult(t *testing.T) {
 				ti := &tstIter{}
 				ti.init(bma, pp, sids, tt.minTimestamp, tt.maxTimestamp)
 
-				var result queryResult
+				result := queryResult{elementIDOrder: tt.elementIDOrder}
 				for ti.nextBlock() {
 					bc := generateBlockCursor()
 					p := ti.piHeap[0]
@@ -453,3 +545,67 @@ func TestQueryResult(t *testing.T) {
 		})
 	}
 }
+
+func TestQuerySampling(t *testing.T) {
+	const total = 20000
+	es := &elements{}
+	for i := 0; i < total; i++ {
+		es.seriesIDs = append(es.seriesIDs, 1)
+		es.timestamps = append(es.timestamps, int64(i+1))
+		es.elementIDs = append(es.elementIDs, strconv.Itoa(i))
+		es.tagFamilies = append(es.tagFamilies, []tagValues{{
+			tag: "singleTag", values: []*tagValue{
+				{tag: "intTag", valueType: pbv1.ValueTypeInt64, value: convert.Int64ToBytes(int64(i))},
+			},
+		}})
+	}
+	tmpPath, defFn := test.Space(require.New(t))
+	defer defFn()
+	tst, err := newTSTable(fs.NewLocalFileSystem(), tmpPath, common.Position{},
+		logger.GetLogger("test"), timestamp.TimeRange{}, option{flushTimeout: 0, mergePolicy: newDisabledMergePolicyForTesting()})
+	require.NoError(t, err)
+	defer tst.Close()
+	tst.mustAddElements(es)
+
+	sample := func(interval uint32) []string {
+		s := tst.currentSnapshot()
+		require.NotNil(t, s)
+		defer s.decRef()
+		qo := queryOptions{minTimestamp: 1, maxTimestamp: total}
+		qo.TagProjection = []pbv1.TagProjection{{Family: "singleTag", Names: []string{"intTag"}}}
+		qo.SampleInterval = interval
+		pp, _ := s.getParts(nil, qo.minTimestamp, qo.maxTimestamp)
+		bma := generateBlockMetadataArray()
+		defer releaseBlockMetadataArray(bma)
+		ti := &tstIter{}
+		defer ti.reset()
+		ti.init(bma, pp, []common.SeriesID{1}, qo.minTimestamp, qo.maxTimestamp)
+		result := queryResult{orderByTS: true, ascTS: true}
+		for ti.nextBlock() {
+			bc := generateBlockCursor()
+			bc.init(ti.piHeap[0].p, ti.piHeap[0].curBlock, qo)
+			result.data = append(result.data, bc)
+		}
+		require.NoError(t, ti.Error())
+		defer result.Release()
+		var ids []string
+		for r := result.Pull(); r != nil; r = result.Pull() {
+			for i, id := range r.ElementIDs {
+				require.True(t, pbv1.Sampled(id, interval), id)
+				require.Equal(t, id, strconv.FormatInt(r.TagFamilies[0].Tags[0].Values[i].GetInt().GetValue(), 10),
+					"the tags should stay aligned with the sampled elements")
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	}
+
+	require.Len(t, sample(0), total)
+	require.Len(t, sample(1), total)
+	for _, interval := range []uint32{10, 100} {
+		got := sample(interval)
+		want := total / int(interval)
+		require.InDelta(t, want, len(got), float64(want)*0.2, "interval %d", interval)
+		require.Equal(t, got, sample(interval), "the sample should be stable across queries")
+	}
+}
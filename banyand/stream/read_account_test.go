@@ -0,0 +1,95 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream_test
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/banyand/internal/storage"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/test/flags"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+var _ = Describe("Query with a limit on the bytes read", func() {
+	now := time.Now()
+	tr := timestamp.NewInclusiveTimeRange(now.Add(-time.Hour), now.Add(time.Hour))
+	var svcs *services
+	var deferFn func()
+
+	BeforeEach(func() {
+		svcs, deferFn = setUp()
+		waitForStream(svcs)
+		for i := 0; i < 10; i++ {
+			writeElement(svcs, newWriteRequest(fmt.Sprintf("id-%d", i), now.Add(-time.Duration(i)*time.Minute)))
+		}
+		Eventually(func() []string { return queryElementIDs(svcs, tr, 0) }).WithTimeout(flags.EventuallyTimeout).Should(HaveLen(10))
+	})
+
+	AfterEach(func() {
+		deferFn()
+	})
+
+	query := func(account *storage.ReadAccount) ([]string, error) {
+		s, err := svcs.stream.Stream(swMetadata)
+		Expect(err).ShouldNot(HaveOccurred())
+		result, err := s.Query(storage.WithReadAccount(context.Background(), account), pbv1.StreamQueryOptions{
+			Name:          swMetadata.Name,
+			TimeRange:     &tr,
+			Entities:      [][]*modelv1.TagValue{swEntity},
+			TagProjection: []pbv1.TagProjection{{Family: "searchable", Names: []string{"trace_id"}}},
+		})
+		if err != nil {
+			return nil, err
+		}
+		defer result.Release()
+		var ids []string
+		for r := result.Pull(); r != nil; r = result.Pull() {
+			ids = append(ids, r.ElementIDs...)
+		}
+		return ids, account.Err()
+	}
+
+	It("accounts the bytes read", func() {
+		account := storage.NewReadAccount(0)
+		ids, err := query(account)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(ids).Should(HaveLen(10))
+		Expect(account.BytesRead()).Should(BeNumerically(">", 0))
+	})
+
+	It("aborts the query exceeding the limit", func() {
+		unlimited := storage.NewReadAccount(0)
+		_, err := query(unlimited)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		for _, limit := range []uint64{1, unlimited.BytesRead() - 1} {
+			account := storage.NewReadAccount(limit)
+			_, err = query(account)
+			Expect(err).Should(MatchError(storage.ErrTooManyBytesRead))
+			Expect(account.BytesRead()).Should(BeNumerically(">", limit))
+			Expect(account.BytesRead()).Should(BeNumerically("<=", unlimited.BytesRead()))
+		}
+	})
+})
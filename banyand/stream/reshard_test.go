@@ -0,0 +1,119 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/api/data"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/banyand/internal/storage"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/test/flags"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+var _ = Describe("Reshard", func() {
+	now := time.Now()
+	tr := timestamp.NewInclusiveTimeRange(now.Add(-time.Hour), now.Add(time.Hour))
+	var svcs *services
+	var deferFn func()
+
+	BeforeEach(func() {
+		svcs, deferFn = setUp()
+		waitForStream(svcs)
+	})
+
+	AfterEach(func() {
+		deferFn()
+	})
+
+	writeToShard := func(id string, shardID uint32) {
+		iwr := &streamv1.InternalWriteRequest{
+			EntityValues: swEntity,
+			Request:      newWriteRequest(id, now),
+			ShardId:      shardID,
+		}
+		_, err := svcs.pipeline.Publish(data.TopicStreamWrite, bus.NewMessage(bus.MessageID(time.Now().UnixNano()), []any{iwr}))
+		Expect(err).ShouldNot(HaveOccurred())
+	}
+	queryShard := func(shardID common.ShardID) error {
+		s, err := svcs.stream.Stream(swMetadata)
+		Expect(err).ShouldNot(HaveOccurred())
+		result, err := s.Query(context.Background(), pbv1.StreamQueryOptions{
+			Name:          swMetadata.Name,
+			TimeRange:     &tr,
+			Entities:      [][]*modelv1.TagValue{swEntity},
+			TagProjection: []pbv1.TagProjection{{Family: "searchable", Names: []string{"trace_id"}}},
+			ShardIDs:      []common.ShardID{shardID},
+		})
+		if result != nil {
+			result.Release()
+		}
+		return err
+	}
+	tsdb := func() io.Closer {
+		g, ok := svcs.stream.LoadGroup(swMetadata.Group)
+		Expect(ok).To(BeTrue())
+		return g.SupplyTSDB()
+	}
+
+	It("keeps the elements queryable after growing the shards", func() {
+		var written []string
+		for i := 0; i < 10; i++ {
+			id := fmt.Sprintf("before_%d", i)
+			writeToShard(id, uint32(i%2))
+			written = append(written, id)
+		}
+		Eventually(func() []string { return queryElementIDs(svcs, tr, 0) }).WithTimeout(flags.EventuallyTimeout).Should(ConsistOf(written))
+		Expect(queryShard(0)).To(Succeed())
+		db := tsdb()
+
+		ctx, cancel := context.WithTimeout(context.Background(), flags.EventuallyTimeout)
+		defer cancel()
+		groupSchema, err := svcs.metadataService.GroupRegistry().GetGroup(ctx, swMetadata.Group)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(groupSchema.GetResourceOpts().GetShardNum()).To(Equal(uint32(2)))
+		groupSchema.ResourceOpts.ShardNum = 4
+		Expect(svcs.metadataService.GroupRegistry().UpdateGroup(ctx, groupSchema)).To(Succeed())
+		Eventually(func() uint32 {
+			cfg, errCfg := svcs.stream.EffectiveConfig(swMetadata.Group)
+			Expect(errCfg).ShouldNot(HaveOccurred())
+			return cfg.ShardNum
+		}).WithTimeout(flags.EventuallyTimeout).Should(Equal(uint32(4)))
+		Expect(tsdb()).To(BeIdenticalTo(db), "the tsdb keeps serving while it's resharded")
+		Expect(queryElementIDs(svcs, tr, 0)).To(ConsistOf(written))
+		Expect(queryShard(0)).To(MatchError(storage.ErrResharded), "the series written before may be in another shard")
+
+		for i := 0; i < 4; i++ {
+			id := fmt.Sprintf("after_%d", i)
+			writeToShard(id, uint32(i))
+			written = append(written, id)
+		}
+		Eventually(func() []string { return queryElementIDs(svcs, tr, 0) }).WithTimeout(flags.EventuallyTimeout).Should(ConsistOf(written))
+	})
+})
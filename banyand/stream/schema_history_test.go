@@ -0,0 +1,76 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+)
+
+func TestSchemaHistory(t *testing.T) {
+	root := t.TempDir()
+	md := &commonv1.Metadata{Group: "sw", Name: "trace"}
+	revision := func(rev int64, startTimeType databasev1.TagType, indexedOnly bool) *databasev1.Stream {
+		return &databasev1.Stream{
+			Metadata: &commonv1.Metadata{Group: md.Group, Name: md.Name, ModRevision: rev},
+			TagFamilies: []*databasev1.TagFamilySpec{{
+				Name: "searchable",
+				Tags: []*databasev1.TagSpec{
+					{Name: "trace_id", Type: databasev1.TagType_TAG_TYPE_STRING},
+					{Name: "start_time", Type: startTimeType, IndexedOnly: indexedOnly},
+				},
+			}},
+			Entity: &databasev1.Entity{TagNames: []string{"trace_id"}},
+		}
+	}
+	sh := newSchemaHistory()
+	sh.open(root)
+	// The second revision doesn't change the tag types, so its elements aren't stamped.
+	for _, c := range []struct {
+		s       *databasev1.Stream
+		stamped bool
+	}{
+		{revision(1, databasev1.TagType_TAG_TYPE_INT, false), false},
+		{revision(2, databasev1.TagType_TAG_TYPE_INT, true), false},
+		{revision(3, databasev1.TagType_TAG_TYPE_STRING, false), true},
+	} {
+		stamped, err := sh.record(c.s)
+		require.NoError(t, err)
+		assert.Equal(t, c.stamped, stamped, "revision %d", c.s.GetMetadata().GetModRevision())
+	}
+
+	// The revisions are loaded from the group directory after a restart.
+	reopened := newSchemaHistory()
+	reopened.open(root)
+	stamped, err := reopened.record(revision(3, databasev1.TagType_TAG_TYPE_STRING, false))
+	require.NoError(t, err)
+	assert.True(t, stamped)
+	revisions := reopened.revisions(md)
+	assert.Len(t, revisions, 3)
+	assert.Equal(t, pbv1.ValueTypeInt64, baseTagTypes(revisions)["start_time"])
+	assert.Equal(t, pbv1.ValueTypeStr, revisions[3]["start_time"])
+
+	reopened.dropGroup(md.Group)
+	assert.Empty(t, reopened.revisions(md))
+}
@@ -0,0 +1,255 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	"github.com/apache/skywalking-banyandb/api/validate"
+	"github.com/apache/skywalking-banyandb/pkg/convert"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+)
+
+const (
+	// schemaRevisionTagFamily is the reserved tag family stamping every element
+	// with the revision of the schema it's written under.
+	schemaRevisionTagFamily = validate.StreamSchemaRevisionTagFamily
+	schemaRevisionTag       = "revision"
+	schemaHistoryFilename   = "schema-history.json"
+)
+
+var schemaRevisionProjection = pbv1.TagProjection{Family: schemaRevisionTagFamily, Names: []string{schemaRevisionTag}}
+
+func schemaRevisionValues(revision int64) tagValues {
+	return tagValues{
+		tag: schemaRevisionTagFamily,
+		values: []*tagValue{{
+			tag:       schemaRevisionTag,
+			valueType: pbv1.ValueTypeInt64,
+			value:     convert.Int64ToBytes(revision),
+		}},
+	}
+}
+
+func tagValueTypes(s *databasev1.Stream) map[string]pbv1.ValueType {
+	types := make(map[string]pbv1.ValueType)
+	for _, tf := range s.GetTagFamilies() {
+		for _, t := range tf.GetTags() {
+			types[t.GetName()] = encodeTagValue(t.GetName(), t.GetType(), pbv1.NullTagValue).valueType
+		}
+	}
+	return types
+}
+
+// schemaHistory remembers the tag types of every schema revision the service has loaded.
+// It's persisted in the directory of every group, so the elements written under a revision loaded before a restart
+// are read with the tag types of that revision.
+// The elements written under a revision with the tag types of the first revision in the history aren't stamped,
+// and they're read with the tag types of the first revision.
+type schemaHistory struct {
+	groups map[string]map[string]map[int64]map[string]pbv1.ValueType
+	root   string
+	mu     sync.RWMutex
+}
+
+func newSchemaHistory() *schemaHistory {
+	return &schemaHistory{groups: make(map[string]map[string]map[int64]map[string]pbv1.ValueType)}
+}
+
+// open sets the directory of the groups. The history of a group is loaded once a stream of it is recorded.
+func (sh *schemaHistory) open(root string) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	sh.root = root
+}
+
+// record adds the revision of the stream, and reports whether the elements written under it are stamped.
+// They're stamped if the revision can't be persisted, since they can't be told apart after a restart otherwise.
+func (sh *schemaHistory) record(s *databasev1.Stream) (bool, error) {
+	if sh == nil {
+		return true, nil
+	}
+	md := s.GetMetadata()
+	types := tagValueTypes(s)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	streams, err := sh.loadGroup(md.GetGroup())
+	if err != nil {
+		return true, err
+	}
+	revisions, ok := streams[md.GetName()]
+	if !ok {
+		revisions = make(map[int64]map[string]pbv1.ValueType)
+		streams[md.GetName()] = revisions
+	}
+	if _, ok = revisions[md.GetModRevision()]; !ok {
+		revisions[md.GetModRevision()] = types
+		if err = sh.persist(md.GetGroup(), streams); err != nil {
+			return true, err
+		}
+	}
+	return !maps.Equal(types, baseTagTypes(revisions)), nil
+}
+
+func (sh *schemaHistory) path(group string) string {
+	return filepath.Join(sh.root, group, schemaHistoryFilename)
+}
+
+func (sh *schemaHistory) loadGroup(group string) (map[string]map[int64]map[string]pbv1.ValueType, error) {
+	if streams, ok := sh.groups[group]; ok {
+		return streams, nil
+	}
+	streams := make(map[string]map[int64]map[string]pbv1.ValueType)
+	if sh.root != "" {
+		data, err := os.ReadFile(sh.path(group))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if err == nil {
+			if err = json.Unmarshal(data, &streams); err != nil {
+				return nil, fmt.Errorf("cannot parse %s: %w", sh.path(group), err)
+			}
+		}
+	}
+	sh.groups[group] = streams
+	return streams, nil
+}
+
+func (sh *schemaHistory) persist(group string, streams map[string]map[int64]map[string]pbv1.ValueType) error {
+	if sh.root == "" {
+		return nil
+	}
+	data, err := json.Marshal(streams)
+	if err != nil {
+		return err
+	}
+	path := sh.path(group)
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// dropGroup forgets the history of a dropped group, whose directory is removed along with the history file.
+func (sh *schemaHistory) dropGroup(group string) {
+	if sh == nil {
+		return
+	}
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	delete(sh.groups, group)
+}
+
+// revisions returns a copy of the stream's revisions. The tag types of a revision are never modified.
+func (sh *schemaHistory) revisions(md *commonv1.Metadata) map[int64]map[string]pbv1.ValueType {
+	if sh == nil {
+		return nil
+	}
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	revisions := sh.groups[md.GetGroup()][md.GetName()]
+	result := make(map[int64]map[string]pbv1.ValueType, len(revisions))
+	for rev, types := range revisions {
+		result[rev] = types
+	}
+	return result
+}
+
+// baseTagTypes returns the tag types of the first revision, or nil if there isn't any.
+func baseTagTypes(revisions map[int64]map[string]pbv1.ValueType) map[string]pbv1.ValueType {
+	var base map[string]pbv1.ValueType
+	first := int64(math.MaxInt64)
+	for rev, types := range revisions {
+		if rev <= first {
+			first, base = rev, types
+		}
+	}
+	return base
+}
+
+// schemaResolver reads every element with the schema revision it's written under.
+type schemaResolver struct {
+	current   map[string]pbv1.ValueType
+	base      map[string]pbv1.ValueType
+	revisions map[int64]map[string]pbv1.ValueType
+}
+
+func (s *stream) schemaResolver() *schemaResolver {
+	revisions := s.schemaHistory.revisions(s.schema.GetMetadata())
+	return &schemaResolver{
+		current:   s.tagTypes,
+		base:      baseTagTypes(revisions),
+		revisions: revisions,
+	}
+}
+
+// resolve drops the values which aren't written with the current type of the tag,
+// including the ones written under a revision without the tag.
+// The revisions are aligned with the values. An element without a revision is checked against the tag types of
+// the first revision. Without them, or written under a revision the resolver doesn't know,
+// it's checked against the value type stored with the tag.
+func (sr *schemaResolver) resolve(t *tag, revisions []int64) {
+	if sr == nil || t.values == nil {
+		return
+	}
+	want, ok := sr.current[t.name]
+	if !ok {
+		return
+	}
+	stored := t.valueType
+	t.valueType = want
+	for i := range t.values {
+		got := stored
+		var rev int64
+		if i < len(revisions) {
+			rev = revisions[i]
+		}
+		if types, known := sr.revisions[rev]; known {
+			got = types[t.name]
+		} else if rev == 0 && sr.base != nil {
+			got = sr.base[t.name]
+		}
+		if got != want {
+			t.values[i] = nil
+		}
+	}
+}
+
+// decodeSchemaRevisions returns the revisions stamped on the elements, zero for the ones without a stamp.
+func decodeSchemaRevisions(t *tag) []int64 {
+	revisions := make([]int64, len(t.values))
+	for i, v := range t.values {
+		if len(v) == 8 {
+			revisions[i] = convert.BytesToInt64(v)
+		}
+	}
+	return revisions
+}
@@ -0,0 +1,144 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/test/flags"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+var _ = Describe("Schema revisions", func() {
+	now := time.Now()
+	tr := timestamp.NewInclusiveTimeRange(now.Add(-time.Hour), now.Add(time.Hour))
+	var svcs *services
+	var deferFn func()
+
+	BeforeEach(func() {
+		svcs, deferFn = setUp()
+		waitForStream(svcs)
+	})
+
+	AfterEach(func() {
+		deferFn()
+	})
+
+	// writeSearchable writes an element with the searchable tags set by their names.
+	writeSearchable := func(id string, ts time.Time, tags map[string]*modelv1.TagValue) {
+		s, err := svcs.stream.Stream(swMetadata)
+		Expect(err).ShouldNot(HaveOccurred())
+		req := newWriteRequest(id, ts)
+		searchable := req.Element.TagFamilies[1]
+		for i, spec := range s.GetSchema().GetTagFamilies()[1].GetTags() {
+			v, ok := tags[spec.GetName()]
+			if !ok {
+				continue
+			}
+			for len(searchable.Tags) <= i {
+				searchable.Tags = append(searchable.Tags, pbv1.NullTagValue)
+			}
+			searchable.Tags[i] = v
+		}
+		writeElement(svcs, req)
+	}
+
+	query := func() map[string][]*modelv1.TagValue {
+		s, err := svcs.stream.Stream(swMetadata)
+		Expect(err).ShouldNot(HaveOccurred())
+		result, err := s.Query(context.Background(), pbv1.StreamQueryOptions{
+			Name:          swMetadata.Name,
+			TimeRange:     &tr,
+			Entities:      [][]*modelv1.TagValue{swEntity},
+			TagProjection: []pbv1.TagProjection{{Family: "searchable", Names: []string{"start_time", "region"}}},
+		})
+		Expect(err).ShouldNot(HaveOccurred())
+		if result == nil {
+			return nil
+		}
+		defer result.Release()
+		elements := make(map[string][]*modelv1.TagValue)
+		for r := result.Pull(); r != nil; r = result.Pull() {
+			for i, id := range r.ElementIDs {
+				elements[id] = []*modelv1.TagValue{r.TagFamilies[0].Tags[0].Values[i], r.TagFamilies[0].Tags[1].Values[i]}
+			}
+		}
+		return elements
+	}
+
+	It("reads the elements with the schema revision they're written under", func() {
+		writeSearchable("v1", now, map[string]*modelv1.TagValue{
+			"start_time": {Value: &modelv1.TagValue_Int{Int: &modelv1.Int{Value: 100}}},
+		})
+		Eventually(query).WithTimeout(flags.EventuallyTimeout).Should(HaveKey("v1"))
+
+		// The second revision changes the type of start_time and adds a tag.
+		ctx, cancel := context.WithTimeout(context.Background(), flags.EventuallyTimeout)
+		defer cancel()
+		schema, err := svcs.metadataService.StreamRegistry().GetStream(ctx, swMetadata)
+		Expect(err).ShouldNot(HaveOccurred())
+		searchable := schema.GetTagFamilies()[1]
+		for _, spec := range searchable.GetTags() {
+			if spec.GetName() == "start_time" {
+				spec.Type = databasev1.TagType_TAG_TYPE_STRING
+			}
+		}
+		searchable.Tags = append(searchable.Tags, &databasev1.TagSpec{Name: "region", Type: databasev1.TagType_TAG_TYPE_STRING})
+		revision, err := svcs.metadataService.StreamRegistry().UpdateStream(ctx, schema)
+		Expect(err).ShouldNot(HaveOccurred())
+		Eventually(func() int64 {
+			s, errStream := svcs.stream.Stream(swMetadata)
+			if errStream != nil {
+				return 0
+			}
+			return s.GetSchema().GetMetadata().GetModRevision()
+		}).WithTimeout(flags.EventuallyTimeout).Should(Equal(revision))
+
+		writeSearchable("v2", now.Add(time.Millisecond), map[string]*modelv1.TagValue{
+			"start_time": {Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "late"}}},
+			"region":     {Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "eu"}}},
+		})
+		Eventually(query).WithTimeout(flags.EventuallyTimeout).Should(HaveKey("v2"))
+
+		elements := query()
+		Expect(elements["v1"]).To(Equal([]*modelv1.TagValue{pbv1.NullTagValue, pbv1.NullTagValue}),
+			"the integer written under the first revision isn't read as a string")
+		Expect(elements["v2"][0].GetStr().GetValue()).To(Equal("late"))
+		Expect(elements["v2"][1].GetStr().GetValue()).To(Equal("eu"))
+	})
+
+	It("rejects a tag family named after the reserved one", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), flags.EventuallyTimeout)
+		defer cancel()
+		schema, err := svcs.metadataService.StreamRegistry().GetStream(ctx, swMetadata)
+		Expect(err).ShouldNot(HaveOccurred())
+		schema.TagFamilies = append(schema.TagFamilies, &databasev1.TagFamilySpec{
+			Name: "_schema",
+			Tags: []*databasev1.TagSpec{{Name: "revision", Type: databasev1.TagType_TAG_TYPE_INT}},
+		})
+		_, err = svcs.metadataService.StreamRegistry().UpdateStream(ctx, schema)
+		Expect(err).To(MatchError(ContainSubstring("_schema is reserved")))
+	})
+})
@@ -19,27 +19,35 @@ package stream
 
 import (
 	"context"
+	"io"
 	"math"
 	"path"
+	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/apache/skywalking-banyandb/api/data"
 	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
 	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/banyand/internal/storage"
 	"github.com/apache/skywalking-banyandb/banyand/metadata"
 	"github.com/apache/skywalking-banyandb/banyand/observability"
 	"github.com/apache/skywalking-banyandb/banyand/queue"
 	"github.com/apache/skywalking-banyandb/pkg/bus"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
 	"github.com/apache/skywalking-banyandb/pkg/run"
 	resourceSchema "github.com/apache/skywalking-banyandb/pkg/schema"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
 )
 
 var (
 	errEmptyRootPath = errors.New("root path is empty")
 	// ErrStreamNotExist denotes a stream doesn't exist in the metadata repo.
 	ErrStreamNotExist = errors.New("stream doesn't exist")
+	// ErrElementNotExist denotes an element doesn't exist in the group.
+	ErrElementNotExist = errors.New("element doesn't exist")
 )
 
 // Service allows inspecting the stream elements.
@@ -48,19 +56,40 @@ type Service interface {
 	run.Config
 	run.Service
 	Query
+	EffectiveConfig(group string) (EffectiveConfig, error)
+	RotationStatus(group string) (storage.Status, error)
+	ForceRotate(group string) error
+	SeriesCacheEntries(group string) ([]storage.SeriesCacheEntry, error)
+	EvictSeries(group string, series *pbv1.Series) (int, error)
+	ExportRange(ctx context.Context, group string, timeRange timestamp.TimeRange, w io.Writer) error
+	ImportRange(ctx context.Context, group string, r io.Reader) error
+	GetByElementID(ctx context.Context, group, elementID string) (*streamv1.Element, error)
+	SetAuthorizer(a Authorizer)
 }
 
 var _ Service = (*service)(nil)
 
 type service struct {
-	schemaRepo    schemaRepo
-	writeListener bus.MessageListener
-	metadata      metadata.Repo
-	pipeline      queue.Server
-	localPipeline queue.Queue
-	l             *logger.Logger
-	root          string
-	option        option
+	schemaRepo        schemaRepo
+	writeListener     bus.MessageListener
+	writeLimiter      *groupWriteLimiter
+	idempotency       *idempotencyCache
+	metadata          metadata.Repo
+	pipeline          queue.Server
+	localPipeline     queue.Queue
+	seriesCaches      *storage.SeriesCaches
+	l                 *logger.Logger
+	root              string
+	option            option
+	gracePeriod       time.Duration
+	drainTimeout      time.Duration
+	writeOverflow     string
+	writeSampling     int
+	writeConcurrency  int
+	seriesCachePolicy string
+	// restrictedToken approves the callers presenting it to read the restricted tags.
+	restrictedToken  string
+	seriesCacheDebug bool
 }
 
 func (s *service) Stream(metadata *commonv1.Metadata) (Stream, error) {
@@ -75,13 +104,114 @@ func (s *service) LoadGroup(name string) (resourceSchema.Group, bool) {
 	return s.schemaRepo.LoadGroup(name)
 }
 
+func (s *service) EffectiveConfig(group string) (EffectiveConfig, error) {
+	return s.schemaRepo.effectiveConfig(group)
+}
+
+// RotationStatus reports whether the group is creating a new segment, along with the time range of its newest segment.
+// A readiness probe holds off the writes into the group while it's rotating.
+func (s *service) RotationStatus(group string) (storage.Status, error) {
+	db, err := s.schemaRepo.loadTSDB(group)
+	if err != nil {
+		return storage.Status{}, err
+	}
+	return db.Status(), nil
+}
+
+// SeriesCacheEntries lists the series lists cached by the group, the least recently used first.
+func (s *service) SeriesCacheEntries(group string) ([]storage.SeriesCacheEntry, error) {
+	if !s.seriesCacheDebug {
+		return nil, storage.ErrSeriesCacheDebugDisabled
+	}
+	return s.seriesCaches.Entries(group)
+}
+
+// EvictSeries drops the series lists cached by the group which hold the series.
+// The series is made of the subject and the entity values, and it returns the number of the dropped lists.
+func (s *service) EvictSeries(group string, series *pbv1.Series) (int, error) {
+	if !s.seriesCacheDebug {
+		return 0, storage.ErrSeriesCacheDebugDisabled
+	}
+	return s.seriesCaches.Evict(group, series)
+}
+
+// ExportRange writes the schemas of the group and its elements within the time range to w.
+// The export is consistent, it isn't affected by the merges and the retention running at the same time.
+func (s *service) ExportRange(ctx context.Context, group string, timeRange timestamp.TimeRange, w io.Writer) error {
+	return s.schemaRepo.exportRange(ctx, group, timeRange, w)
+}
+
+// ImportRange reads an export written by ExportRange into the group.
+// The schemas absent from the group are created, and the elements are written into new parts.
+func (s *service) ImportRange(ctx context.Context, group string, r io.Reader) error {
+	return s.schemaRepo.importRange(ctx, group, r)
+}
+
+// GetByElementID returns the element of the group by its ID, or ErrElementNotExist if there isn't one.
+// It only finds the elements of the streams enabling index_element_id.
+func (s *service) GetByElementID(ctx context.Context, group, elementID string) (*streamv1.Element, error) {
+	return s.schemaRepo.getByElementID(ctx, group, elementID)
+}
+
+// SetAuthorizer registers the authorizer of the queries reading restricted tags.
+// Without an authorizer, the restricted tags are redacted from all the results.
+func (s *service) SetAuthorizer(a Authorizer) {
+	s.option.authorizer.set(a)
+}
+
 func (s *service) FlagSet() *run.FlagSet {
 	flagS := run.NewFlagSet("storage")
 	flagS.StringVar(&s.root, "stream-root-path", "/tmp", "the root path of database")
 	flagS.DurationVar(&s.option.flushTimeout, "stream-flush-timeout", defaultFlushTimeout, "the memory data timeout of stream")
+	flagS.DurationVar(&s.gracePeriod, "stream-dropped-group-grace-period", defaultGroupGracePeriod,
+		"the period to retain the data of a dropped group before deleting it")
+	flagS.DurationVar(&s.drainTimeout, "stream-query-drain-timeout", defaultQueryDrainTimeout,
+		"the time the shutdown waits for the in-flight queries, the ones still running afterwards are canceled")
 	flagS.DurationVar(&s.option.elementIndexFlushTimeout, "element-index-flush-timeout", defaultFlushTimeout, "the elementIndex timeout of stream")
+	flagS.IntVar(&s.option.seriesCacheSize, "stream-series-cache-size", storage.DefaultSeriesCacheSize,
+		"the maximum number of cached series lists per group, 0 disables the cache")
+	flagS.DurationVar(&s.option.seriesCacheTTL, "stream-series-cache-ttl", storage.DefaultSeriesCacheTTL, "the time to live of a cached series list")
+	flagS.StringVar(&s.seriesCachePolicy, "stream-series-cache-policy", storage.CachePolicyLRU.String(),
+		"the eviction policy of the series list cache, one of lru, lfu and slru")
+	flagS.BoolVar(&s.option.seriesCacheWarmup, "stream-series-cache-warmup", false,
+		"persist the cached series lists every minute and reload them on startup")
+	flagS.BoolVar(&s.seriesCacheDebug, "stream-series-cache-debug", false,
+		"enable the debug API listing the cached series lists and evicting the ones holding a series")
+	flagS.DurationVar(&s.option.maxStalenessWait, "stream-max-staleness-wait", defaultMaxStalenessWait,
+		"the maximum time a query with a staleness bound waits for the pending writes to become visible")
+	flagS.DurationVar(&s.option.idempotencyWindow, "stream-idempotency-window", defaultIdempotencyWindow,
+		"the time to remember the idempotency key of a write, zero disables the deduplication of retried writes")
+	flagS.IntVar(&s.option.idempotencyMaxKeys, "stream-idempotency-max-keys", defaultIdempotencyMaxKeys,
+		"the maximum number of idempotency keys to remember, the oldest ones are forgotten first")
+	flagS.IntVar(&s.option.maxSeriesPerQuery, "stream-max-series-per-query", 0,
+		"the maximum number of series a query matches, 0 means no limit")
+	flagS.IntVar(&s.option.maxOpenSegments, "stream-max-open-segments", 0,
+		"the maximum number of open segments per group, the least recently accessed ones are closed until they're accessed again, 0 means no limit")
+	flagS.IntVar(&s.option.maxDiskUsagePercent, "stream-max-disk-usage-percent", 0,
+		"the disk usage beyond which the oldest segments are deleted before their TTL, 0 disables it")
+	flagS.IntVar(&s.option.minRetainedSegments, "stream-min-retained-segments", 1,
+		"the number of the latest segments per shard never deleted by the disk usage")
+	flagS.IntVar(&s.option.queryParallelism, "stream-query-parallelism", 0,
+		"the maximum number of shards a query searches at a time, 0 means one per CPU and 1 searches the shards one by one")
+	flagS.IntVar(&s.writeSampling, "stream-write-sampling-rate", 0,
+		"log the timing of the write stages for one in every N writes, 0 disables the sampling")
+	flagS.IntVar(&s.writeConcurrency, "stream-write-group-concurrency", 0,
+		"the maximum number of concurrent writes per group, 0 means no limit")
+	flagS.StringVar(&s.writeOverflow, "stream-write-group-overflow", writeOverflowQueue,
+		"how the writes exceeding the concurrency limit of their group are handled, either queue to wait for a free slot, "+
+			"or reject to fail them at once")
+	flagS.StringVar(&s.restrictedToken, "stream-restricted-tag-token", "",
+		"the bearer token approving the callers presenting it to read the restricted tags, empty redacts them from all the callers")
+	flagS.BoolVar(&s.option.verifyMerge, "stream-verify-merge", false,
+		"verify every merged part holds the rows of its source parts, which is expensive and meant for canary nodes")
+	flagS.IntVar(&s.option.mergeMaxRetries, "stream-merge-max-retries", defaultMergeMaxRetries,
+		"the number of times a merge failing on corrupted parts is retried before the corrupted ones are quarantined, a negative value retries forever")
+	flagS.DurationVar(&s.option.mergeRetryBackoff, "stream-merge-retry-backoff", defaultMergeRetryBackoff,
+		"the delay before retrying a failed merge, which doubles with every consecutive failure")
 	s.option.mergePolicy = newDefaultMergePolicy()
 	flagS.Uint64Var(&s.option.mergePolicy.maxFanOutSize, "max-fan-out-size", math.MaxUint64, "the upper bound of a single file size after merge")
+	flagS.Uint64Var(&s.option.mergePolicy.targetPartSize, "target-part-size", 0,
+		"the size a merged part aims for, the merger splits a merge exceeding it into several parts, 0 means no target")
 	return flagS
 }
 
@@ -89,6 +219,14 @@ func (s *service) Validate() error {
 	if s.root == "" {
 		return errEmptyRootPath
 	}
+	policy, err := storage.ParseCachePolicy(s.seriesCachePolicy)
+	if err != nil {
+		return err
+	}
+	s.option.seriesCachePolicy = policy
+	if s.writeOverflow != writeOverflowQueue && s.writeOverflow != writeOverflowReject {
+		return errInvalidWriteOverflow
+	}
 	return nil
 }
 
@@ -104,11 +242,23 @@ func (s *service) PreRun(_ context.Context) error {
 	s.l = logger.GetLogger(s.Name())
 	path := path.Join(s.root, s.Name())
 	observability.UpdatePath(path)
+	if s.restrictedToken != "" {
+		s.SetAuthorizer(tokenAuthorizer(s.restrictedToken))
+	}
 	s.localPipeline = queue.Local()
+	s.option.schemaHistory.open(path)
 	s.schemaRepo = newSchemaRepo(path, s)
 	// run a serial watcher
 
-	s.writeListener = setUpWriteCallback(s.l, &s.schemaRepo)
+	s.seriesCaches = storage.NewSeriesCaches(s.Name(), s.schemaRepo.Repository)
+	observability.MetricsCollector.Register(s.seriesCaches.CollectorName(), s.seriesCaches.Collect)
+	sc := &segmentCollector{sr: &s.schemaRepo}
+	observability.MetricsCollector.Register(segmentCollectorName, sc.collect)
+	observability.AdminHandlers.Register(forceRotateAdminName, s.serveForceRotate)
+
+	s.idempotency = newIdempotencyCache(path, s.option.idempotencyWindow, s.option.idempotencyMaxKeys, s.l)
+	s.writeLimiter = newGroupWriteLimiter(s.writeConcurrency, s.writeOverflow)
+	s.writeListener = setUpWriteCallback(s.l, &s.schemaRepo, s.idempotency, newWriteSampler(s.writeSampling, s.l), s.writeLimiter)
 	err := s.pipeline.Subscribe(data.TopicStreamWrite, s.writeListener)
 	if err != nil {
 		return err
@@ -121,8 +271,15 @@ func (s *service) Serve() run.StopNotify {
 }
 
 func (s *service) GracefulStop() {
+	if !s.option.drainer.drain(s.drainTimeout) {
+		s.l.Warn().Dur("timeout", s.drainTimeout).Msg("cancel the queries outliving the drain timeout")
+	}
+	observability.MetricsCollector.Unregister(s.seriesCaches.CollectorName())
+	observability.MetricsCollector.Unregister(segmentCollectorName)
+	observability.AdminHandlers.Unregister(forceRotateAdminName)
 	s.localPipeline.GracefulStop()
 	s.schemaRepo.Close()
+	s.idempotency.close()
 }
 
 // NewService returns a new service.
@@ -130,5 +287,11 @@ func NewService(_ context.Context, metadata metadata.Repo, pipeline queue.Server
 	return &service{
 		metadata: metadata,
 		pipeline: pipeline,
+		option: option{
+			authorizer:    &authorizerHook{},
+			drainer:       newQueryDrainer(),
+			pendingWrites: newPendingWrites(),
+			schemaHistory: newSchemaHistory(),
+		},
 	}, nil
 }
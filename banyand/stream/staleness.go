@@ -0,0 +1,67 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"context"
+	"time"
+
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+)
+
+const (
+	defaultMaxStalenessWait = 3 * time.Second
+	stalenessCheckInterval  = 20 * time.Millisecond
+)
+
+// waitForFreshness blocks until the series matching the query have no pending writes,
+// so that the writes accepted before the query become visible to it.
+// It gives up silently after the staleness wait timeout and lets the query read what is visible.
+func (s *stream) waitForFreshness(ctx context.Context, sqo pbv1.StreamQueryOptions) error {
+	if sqo.MaxStaleness <= 0 || s.maxStalenessWait <= 0 {
+		return nil
+	}
+	bound := time.Now().Add(-sqo.MaxStaleness)
+	if bound.After(sqo.TimeRange.End) {
+		return nil
+	}
+	timer := time.NewTimer(s.maxStalenessWait)
+	defer timer.Stop()
+	ticker := time.NewTicker(stalenessCheckInterval)
+	defer ticker.Stop()
+	for {
+		if s.isFresh(sqo) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			s.l.Debug().Str("stream", s.name).Dur("maxStaleness", sqo.MaxStaleness).Msg("the stream data is still stale after waiting")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// isFresh reports whether none of the series matching the query has pending writes.
+// The pending writes are matched by the entities of the query rather than the series index,
+// which doesn't have the series written for the first time yet.
+func (s *stream) isFresh(sqo pbv1.StreamQueryOptions) bool {
+	return !s.pendingWrites.pending(sqo.Name, sqo.Entities)
+}
@@ -0,0 +1,116 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/apache/skywalking-banyandb/api/data"
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/test/flags"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+var _ = Describe("Query with a staleness bound", func() {
+	md := &commonv1.Metadata{Name: "sw", Group: "default"}
+	entity := []*modelv1.TagValue{
+		{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "svc_1"}}},
+		{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "svc_1_instance_1"}}},
+		{Value: &modelv1.TagValue_Int{Int: &modelv1.Int{Value: 0}}},
+	}
+	now := time.Now().Truncate(time.Millisecond)
+	var svcs *services
+	var deferFn func()
+
+	write := func(id string, ts time.Time) {
+		req := &streamv1.InternalWriteRequest{
+			EntityValues: entity,
+			Request: &streamv1.WriteRequest{
+				Metadata: md,
+				Element: &streamv1.ElementValue{
+					ElementId: id,
+					Timestamp: timestamppb.New(ts),
+					TagFamilies: []*modelv1.TagFamilyForWrite{
+						{Tags: []*modelv1.TagValue{{Value: &modelv1.TagValue_BinaryData{BinaryData: []byte(id)}}}},
+						{Tags: []*modelv1.TagValue{{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: id}}}, entity[2], entity[0], entity[1]}},
+					},
+				},
+			},
+		}
+		_, err := svcs.pipeline.Publish(data.TopicStreamWrite, bus.NewMessage(bus.MessageID(ts.UnixNano()), []any{req}))
+		Expect(err).ShouldNot(HaveOccurred())
+	}
+	query := func(entity []*modelv1.TagValue, maxStaleness time.Duration) []string {
+		s, err := svcs.stream.Stream(md)
+		Expect(err).ShouldNot(HaveOccurred())
+		tr := timestamp.NewInclusiveTimeRange(now.Add(-time.Hour), now.Add(time.Hour))
+		result, err := s.Query(context.Background(), pbv1.StreamQueryOptions{
+			Name:          md.Name,
+			TimeRange:     &tr,
+			Entities:      [][]*modelv1.TagValue{entity},
+			TagProjection: []pbv1.TagProjection{{Family: "searchable", Names: []string{"trace_id"}}},
+			MaxStaleness:  maxStaleness,
+		})
+		Expect(err).ShouldNot(HaveOccurred())
+		if result == nil {
+			return nil
+		}
+		defer result.Release()
+		var ids []string
+		for r := result.Pull(); r != nil; r = result.Pull() {
+			ids = append(ids, r.ElementIDs...)
+		}
+		return ids
+	}
+
+	BeforeEach(func() {
+		svcs, deferFn = setUp()
+		Eventually(func() bool {
+			_, err := svcs.stream.Stream(md)
+			return err == nil
+		}).WithTimeout(flags.EventuallyTimeout).Should(BeTrue())
+		write("old", now.Add(-30*time.Minute))
+		Eventually(func() []string { return query(entity, 0) }).WithTimeout(flags.EventuallyTimeout).Should(ConsistOf("old"))
+	})
+
+	AfterEach(func() {
+		deferFn()
+	})
+
+	It("doesn't wait for the series without pending writes", func() {
+		start := time.Now()
+		Expect(query(entity, time.Second)).Should(ConsistOf("old"))
+		Expect(time.Since(start)).Should(BeNumerically("<", time.Second))
+	})
+
+	It("doesn't wait for a query matching no series", func() {
+		unknown := []*modelv1.TagValue{entity[0], {Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "unknown"}}}, entity[2]}
+		start := time.Now()
+		Expect(query(unknown, time.Second)).Should(BeEmpty())
+		Expect(time.Since(start)).Should(BeNumerically("<", time.Second))
+	})
+})
@@ -26,10 +26,13 @@ import (
 
 	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
 	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/banyand/internal/storage"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
 	"github.com/apache/skywalking-banyandb/pkg/partition"
 	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
 	"github.com/apache/skywalking-banyandb/pkg/schema"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
 )
 
 const (
@@ -40,13 +43,38 @@ const (
 	maxUncompressedBlockSize        = 2 * 1024 * 1024
 	maxUncompressedPrimaryBlockSize = 128 * 1024
 
-	defaultFlushTimeout = time.Second
+	defaultFlushTimeout     = time.Second
+	defaultGroupGracePeriod = 24 * time.Hour
 )
 
 type option struct {
-	mergePolicy              *mergePolicy
+	mergePolicy   *mergePolicy
+	authorizer    *authorizerHook
+	drainer       *queryDrainer
+	pendingWrites *pendingWrites
+	schemaHistory *schemaHistory
+	// partMerger merges the parts in place of mergeParts if it's set.
+	partMerger               mergeFunc
+	clusteringKey            string
 	flushTimeout             time.Duration
 	elementIndexFlushTimeout time.Duration
+	seriesCacheTTL           time.Duration
+	maxStalenessWait         time.Duration
+	idempotencyWindow        time.Duration
+	mergeRetryBackoff        time.Duration
+	seriesCacheSize          int
+	seriesCachePolicy        storage.CachePolicy
+	idempotencyMaxKeys       int
+	maxSeriesPerQuery        int
+	maxOpenSegments          int
+	// maxDiskUsagePercent is the disk usage beyond which the oldest segments are deleted before their TTL.
+	maxDiskUsagePercent int
+	minRetainedSegments int
+	queryParallelism    int
+	compressThreshold   int
+	mergeMaxRetries     int
+	verifyMerge         bool
+	seriesCacheWarmup   bool
 }
 
 // Query allow to retrieve elements in a series of streams.
@@ -63,6 +91,10 @@ type Stream interface {
 	Query(ctx context.Context, opts pbv1.StreamQueryOptions) (pbv1.StreamQueryResult, error)
 	Sort(ctx context.Context, opts pbv1.StreamQueryOptions) (pbv1.StreamSortResult, error)
 	Filter(ctx context.Context, opts pbv1.StreamQueryOptions) (pbv1.StreamQueryResult, error)
+	TimeExtent(ctx context.Context, entities [][]*modelv1.TagValue, timeRange timestamp.TimeRange) ([]pbv1.SeriesTimeExtent, error)
+	Count(ctx context.Context, opts pbv1.StreamQueryOptions) ([]pbv1.SeriesCount, error)
+	TagCardinality(ctx context.Context, tagName string, timeRange timestamp.TimeRange, approximate bool) (uint64, error)
+	CheckRestricted(ctx context.Context, criteria *modelv1.Criteria, orders ...*modelv1.QueryOrder) error
 }
 
 var _ Stream = (*stream)(nil)
@@ -75,7 +107,16 @@ type stream struct {
 	group             string
 	indexRules        []*databasev1.IndexRule
 	indexRuleLocators partition.IndexRuleLocator
+	tagTypes          map[string]pbv1.ValueType
+	authorizer        *authorizerHook
+	drainer           *queryDrainer
+	pendingWrites     *pendingWrites
+	schemaHistory     *schemaHistory
+	maxStalenessWait  time.Duration
+	queryParallelism  int
 	shardNum          uint32
+	// stampRevision stamps the elements with the schema revision, which is set once the tag types have changed.
+	stampRevision bool
 }
 
 func (s *stream) GetSchema() *databasev1.Stream {
@@ -93,6 +134,7 @@ func (s *stream) Close() error {
 func (s *stream) parseSpec() {
 	s.name, s.group = s.schema.GetMetadata().GetName(), s.schema.GetMetadata().GetGroup()
 	s.indexRuleLocators = partition.ParseIndexRuleLocators(s.schema.GetEntity(), s.schema.GetTagFamilies(), s.indexRules)
+	s.tagTypes = tagValueTypes(s.schema)
 }
 
 type streamSpec struct {
@@ -62,6 +62,7 @@ func (p *preloadStreamService) PreRun(ctx context.Context) error {
 type services struct {
 	stream          stream.Service
 	metadataService metadata.Service
+	pipeline        queue.Queue
 }
 
 func setUp() (*services, func()) {
@@ -99,6 +100,7 @@ func setUp() (*services, func()) {
 	return &services{
 			stream:          streamService,
 			metadataService: metadataService,
+			pipeline:        pipeline,
 		}, func() {
 			moduleDeferFunc()
 			metaDeferFunc()
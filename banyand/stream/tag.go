@@ -51,7 +51,7 @@ func (t *tag) resizeValues(valuesLen int) [][]byte {
 	return values
 }
 
-func (t *tag) mustWriteTo(tm *tagMetadata, tagWriter *writer) {
+func (t *tag) mustWriteTo(tm *tagMetadata, tagWriter *writer, compressThreshold int) {
 	tm.reset()
 
 	tm.name = t.name
@@ -63,7 +63,7 @@ func (t *tag) mustWriteTo(tm *tagMetadata, tagWriter *writer) {
 	defer bigValuePool.Release(bb)
 
 	// marshal values
-	bb.Buf = encoding.EncodeBytesBlock(bb.Buf[:0], t.values)
+	bb.Buf = encoding.EncodeBytesBlockWithThreshold(bb.Buf[:0], t.values, compressThreshold)
 	tm.size = uint64(len(bb.Buf))
 	if tm.size > maxValuesBlockSize {
 		logger.Panicf("too valuesSize: %d bytes; mustn't exceed %d bytes", tm.size, maxValuesBlockSize)
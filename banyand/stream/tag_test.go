@@ -18,6 +18,7 @@
 package stream
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -66,7 +67,7 @@ func TestTag_mustWriteTo_mustReadValues(t *testing.T) {
 	buf := &bytes.Buffer{}
 	w := &writer{}
 	w.init(buf)
-	original.mustWriteTo(tm, w)
+	original.mustWriteTo(tm, w, 0)
 	assert.Equal(t, w.bytesWritten, tm.size)
 	assert.Equal(t, uint64(len(buf.Buf)), tm.size)
 	assert.Equal(t, uint64(0), tm.offset)
@@ -84,6 +85,46 @@ func TestTag_mustWriteTo_mustReadValues(t *testing.T) {
 	assert.Equal(t, original.values, unmarshaled.values)
 }
 
+func TestTag_mustWriteTo_compressThreshold(t *testing.T) {
+	const threshold = 512
+	for _, tc := range []struct {
+		name       string
+		valueSize  int
+		compressed bool
+	}{
+		{name: "below the threshold", valueSize: threshold/4 - 1, compressed: false},
+		{name: "above the threshold", valueSize: threshold / 2, compressed: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			original := &tag{
+				name:      "searchable",
+				valueType: pbv1.ValueTypeStr,
+			}
+			var rawSize int
+			for i := 0; i < 4; i++ {
+				v := []byte(strings.Repeat("x", tc.valueSize))
+				original.values = append(original.values, v)
+				rawSize += len(v)
+			}
+
+			tm := &tagMetadata{}
+			buf := &bytes.Buffer{}
+			w := &writer{}
+			w.init(buf)
+			original.mustWriteTo(tm, w, threshold)
+			if tc.compressed {
+				assert.Less(t, tm.size, uint64(rawSize))
+			} else {
+				assert.Greater(t, tm.size, uint64(rawSize))
+			}
+
+			unmarshaled := &tag{}
+			unmarshaled.mustReadValues(&encoding.BytesBlockDecoder{}, buf, *tm, uint64(len(original.values)))
+			assert.Equal(t, original.values, unmarshaled.values)
+		})
+	}
+}
+
 func TestTagFamily_reset(t *testing.T) {
 	tf := &tagFamily{
 		name: "test",
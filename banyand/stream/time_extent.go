@@ -0,0 +1,158 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/banyand/internal/storage"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+type timeExtent struct {
+	min int64
+	max int64
+}
+
+func (te *timeExtent) merge(minTimestamp, maxTimestamp int64) {
+	if minTimestamp < te.min {
+		te.min = minTimestamp
+	}
+	if maxTimestamp > te.max {
+		te.max = maxTimestamp
+	}
+}
+
+// TimeExtent returns the timestamps of the first and the last element within the time range
+// for each series matching the entities. Series without elements in the range are left out.
+func (s *stream) TimeExtent(ctx context.Context, entities [][]*modelv1.TagValue, timeRange timestamp.TimeRange) ([]pbv1.SeriesTimeExtent, error) {
+	if len(entities) < 1 {
+		return nil, errors.New("invalid time extent options: series are required")
+	}
+	ctx, done, err := s.drainer.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	db := s.databaseSupplier.SupplyTSDB()
+	if db == nil {
+		return nil, nil
+	}
+	tsdb := db.(storage.TSDB[*tsTable, option])
+	tabWrappers := tsdb.SelectTSTables(timeRange)
+	defer func() {
+		for i := range tabWrappers {
+			tabWrappers[i].DecRef()
+		}
+	}()
+	series := make([]*pbv1.Series, len(entities))
+	for i := range entities {
+		series[i] = &pbv1.Series{
+			Subject:      s.name,
+			EntityValues: entities[i],
+		}
+	}
+	sl, err := tsdb.Lookup(ctx, series)
+	if err != nil {
+		return nil, err
+	}
+	if len(sl) < 1 {
+		return nil, nil
+	}
+	minTimestamp, maxTimestamp := timeRange.UnixNanoBounds()
+	var parts []*part
+	var snapshots []*snapshot
+	defer func() {
+		for i := range snapshots {
+			snapshots[i].decRef()
+		}
+	}()
+	var n int
+	for i := range tabWrappers {
+		snp := tabWrappers[i].Table().currentSnapshot()
+		if snp == nil {
+			continue
+		}
+		parts, n = snp.getParts(parts, minTimestamp, maxTimestamp)
+		if n < 1 {
+			snp.decRef()
+			continue
+		}
+		snapshots = append(snapshots, snp)
+	}
+	extents, err := timeExtents(parts, sl.IDs(), minTimestamp, maxTimestamp)
+	if err != nil {
+		return nil, err
+	}
+	var result []pbv1.SeriesTimeExtent
+	for i := range sl {
+		te, ok := extents[sl[i].ID]
+		if !ok {
+			continue
+		}
+		result = append(result, pbv1.SeriesTimeExtent{
+			Series:       sl[i],
+			MinTimestamp: te.min,
+			MaxTimestamp: te.max,
+		})
+	}
+	return result, nil
+}
+
+// timeExtents collects the time extents of the series from the block metadata.
+// Only the timestamps of the blocks crossing a bound of the range are decoded.
+func timeExtents(parts []*part, sids []common.SeriesID, minTimestamp, maxTimestamp int64) (map[common.SeriesID]*timeExtent, error) {
+	sids = append([]common.SeriesID(nil), sids...)
+	sort.Slice(sids, func(i, j int) bool { return sids[i] < sids[j] })
+	bma := generateBlockMetadataArray()
+	defer releaseBlockMetadataArray(bma)
+	var ti tstIter
+	defer ti.reset()
+	ti.init(bma, parts, sids, minTimestamp, maxTimestamp)
+	extents := make(map[common.SeriesID]*timeExtent)
+	var timestamps []int64
+	for ti.nextBlock() {
+		pi := ti.piHeap[0]
+		bm := pi.curBlock
+		blockMin, blockMax := bm.timestamps.min, bm.timestamps.max
+		if blockMin < minTimestamp || blockMax > maxTimestamp {
+			timestamps = mustReadTimestampsFrom(timestamps[:0], &bm.timestamps, int(bm.count), pi.p.timestamps)
+			start, end, ok := timestamp.FindRange(timestamps, minTimestamp, maxTimestamp)
+			if !ok {
+				continue
+			}
+			blockMin, blockMax = timestamps[start], timestamps[end]
+		}
+		if te, ok := extents[bm.seriesID]; ok {
+			te.merge(blockMin, blockMax)
+			continue
+		}
+		extents[bm.seriesID] = &timeExtent{min: blockMin, max: blockMax}
+	}
+	if ti.Error() != nil {
+		return nil, fmt.Errorf("cannot iterate tstIter: %w", ti.Error())
+	}
+	return extents, nil
+}
@@ -0,0 +1,162 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/pkg/convert"
+	"github.com/apache/skywalking-banyandb/pkg/fs"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/run"
+	"github.com/apache/skywalking-banyandb/pkg/test"
+	"github.com/apache/skywalking-banyandb/pkg/test/flags"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+	"github.com/apache/skywalking-banyandb/pkg/watcher"
+)
+
+func TestTimeExtents(t *testing.T) {
+	esLong := &elements{}
+	for i := int64(1); i <= 100; i++ {
+		esLong.seriesIDs = append(esLong.seriesIDs, 4)
+		esLong.timestamps = append(esLong.timestamps, i*10)
+		esLong.elementIDs = append(esLong.elementIDs, strconv.FormatInt(i, 10))
+		esLong.tagFamilies = append(esLong.tagFamilies, []tagValues{{
+			tag: "singleTag", values: []*tagValue{{tag: "intTag", valueType: pbv1.ValueTypeInt64, value: convert.Int64ToBytes(i)}},
+		}})
+	}
+	tests := []struct {
+		want         map[common.SeriesID]timeExtent
+		name         string
+		sids         []common.SeriesID
+		minTimestamp int64
+		maxTimestamp int64
+	}{
+		{
+			name:         "series across parts",
+			sids:         []common.SeriesID{3, 1, 2},
+			minTimestamp: 1,
+			maxTimestamp: 2,
+			want: map[common.SeriesID]timeExtent{
+				1: {min: 1, max: 2},
+				2: {min: 1, max: 2},
+				3: {min: 1, max: 2},
+			},
+		},
+		{
+			name:         "range covering a part only",
+			sids:         []common.SeriesID{1, 2},
+			minTimestamp: 2,
+			maxTimestamp: 5,
+			want: map[common.SeriesID]timeExtent{
+				1: {min: 2, max: 2},
+				2: {min: 2, max: 2},
+			},
+		},
+		{
+			name:         "range inside a block",
+			sids:         []common.SeriesID{4},
+			minTimestamp: 95,
+			maxTimestamp: 401,
+			want:         map[common.SeriesID]timeExtent{4: {min: 100, max: 400}},
+		},
+		{
+			name:         "range between elements",
+			sids:         []common.SeriesID{4},
+			minTimestamp: 101,
+			maxTimestamp: 109,
+			want:         map[common.SeriesID]timeExtent{},
+		},
+		{
+			name:         "no data in range",
+			sids:         []common.SeriesID{1, 2, 3},
+			minTimestamp: 3,
+			maxTimestamp: 9,
+			want:         map[common.SeriesID]timeExtent{},
+		},
+		{
+			name:         "unknown series",
+			sids:         []common.SeriesID{5},
+			minTimestamp: 1,
+			maxTimestamp: 1000,
+			want:         map[common.SeriesID]timeExtent{},
+		},
+	}
+	tmpPath, defFn := test.Space(require.New(t))
+	defer defFn()
+	index, _ := newElementIndex(context.TODO(), tmpPath, 0)
+	tst := &tsTable{
+		index:         index,
+		loopCloser:    run.NewCloser(2),
+		introductions: make(chan *introduction),
+		fileSystem:    fs.NewLocalFileSystem(),
+		root:          tmpPath,
+	}
+	tst.gc.init(tst)
+	go tst.introducerLoop(make(chan *flusherIntroduction), make(chan *mergerIntroduction), make(watcher.Channel, 1), 1)
+	defer tst.Close()
+	for _, es := range []*elements{esTS1, esTS2, esLong} {
+		tst.mustAddElements(es)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tst.currentSnapshot()
+			require.NotNil(t, s)
+			defer s.decRef()
+			parts, _ := s.getParts(nil, tt.minTimestamp, tt.maxTimestamp)
+			extents, err := timeExtents(parts, tt.sids, tt.minTimestamp, tt.maxTimestamp)
+			require.NoError(t, err)
+			got := make(map[common.SeriesID]timeExtent, len(extents))
+			for sid, te := range extents {
+				got[sid] = *te
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("file parts", func(t *testing.T) {
+		filePath, defFilePath := test.Space(require.New(t))
+		defer defFilePath()
+		fileTST, err := newTSTable(fs.NewLocalFileSystem(), filePath, common.Position{},
+			logger.GetLogger("test"), timestamp.TimeRange{}, option{flushTimeout: 0, mergePolicy: newDisabledMergePolicyForTesting()})
+		require.NoError(t, err)
+		defer fileTST.Close()
+		fileTST.mustAddElements(esLong)
+		require.Eventually(t, func() bool {
+			s := fileTST.currentSnapshot()
+			defer s.decRef()
+			return s.creator != snapshotCreatorMemPart
+		}, flags.EventuallyTimeout, 10*time.Millisecond)
+		s := fileTST.currentSnapshot()
+		defer s.decRef()
+		parts, _ := s.getParts(nil, 0, 1000)
+		extents, err := timeExtents(parts, []common.SeriesID{4}, 15, 995)
+		require.NoError(t, err)
+		require.Contains(t, extents, common.SeriesID(4))
+		assert.Equal(t, timeExtent{min: 20, max: 990}, *extents[4])
+	})
+}
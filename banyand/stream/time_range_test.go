@@ -0,0 +1,84 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/test/flags"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+var _ = Describe("Time range boundaries", func() {
+	begin := time.Now().Truncate(time.Millisecond)
+	end := begin.Add(time.Minute)
+	var svcs *services
+	var deferFn func()
+
+	BeforeEach(func() {
+		svcs, deferFn = setUp()
+		waitForStream(svcs)
+		writeElement(svcs, newWriteRequest("begin", begin))
+		writeElement(svcs, newWriteRequest("middle", begin.Add(30*time.Second)))
+		writeElement(svcs, newWriteRequest("end", end))
+	})
+
+	AfterEach(func() {
+		deferFn()
+	})
+
+	query := func(tr timestamp.TimeRange) []string {
+		s, err := svcs.stream.Stream(swMetadata)
+		Expect(err).ShouldNot(HaveOccurred())
+		result, err := s.Query(context.Background(), pbv1.StreamQueryOptions{
+			Name:          swMetadata.Name,
+			TimeRange:     &tr,
+			Entities:      [][]*modelv1.TagValue{swEntity},
+			TagProjection: []pbv1.TagProjection{{Family: "searchable", Names: []string{"trace_id"}}},
+		})
+		Expect(err).ShouldNot(HaveOccurred())
+		if result == nil {
+			return nil
+		}
+		defer result.Release()
+		var ids []string
+		for r := result.Pull(); r != nil; r = result.Pull() {
+			ids = append(ids, r.ElementIDs...)
+		}
+		return ids
+	}
+
+	DescribeTable("includes the elements on a bound as the range specifies",
+		func(includeBegin, includeEnd bool, want []string) {
+			Eventually(func() []string {
+				return query(timestamp.NewInclusiveTimeRange(begin, end))
+			}).WithTimeout(flags.EventuallyTimeout).Should(HaveLen(3))
+			Expect(query(timestamp.NewTimeRange(begin, end, includeBegin, includeEnd))).To(ConsistOf(want))
+		},
+		Entry("inclusive", true, true, []string{"begin", "middle", "end"}),
+		Entry("exclusive end", true, false, []string{"begin", "middle"}),
+		Entry("exclusive begin", false, true, []string{"middle", "end"}),
+		Entry("exclusive", false, false, []string{"middle"}),
+	)
+})
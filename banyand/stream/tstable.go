@@ -32,6 +32,7 @@ import (
 	"time"
 
 	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/banyand/internal/storage"
 	"github.com/apache/skywalking-banyandb/pkg/fs"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
 	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
@@ -49,15 +50,17 @@ const (
 type tsTable struct {
 	index         *elementIndex
 	fileSystem    fs.FileSystem
-	option        option
 	l             *logger.Logger
 	snapshot      *snapshot
 	introductions chan *introduction
 	loopCloser    *run.Closer
+	mergeFailures map[uint64]int
 	p             common.Position
 	root          string
 	gc            garbageCleaner
+	option        option
 	curPartID     uint64
+	mergeAttempts int
 	sync.RWMutex
 }
 
@@ -192,7 +195,7 @@ func newTSTable(fileSystem fs.FileSystem, rootPath string, p common.Position,
 	var needToDelete []string
 	for i := range ee {
 		if ee[i].IsDir() {
-			if ee[i].Name() == elementIndexFilename {
+			if ee[i].Name() == elementIndexFilename || ee[i].Name() == quarantineDirname {
 				continue
 			}
 			p, err := parseEpoch(ee[i].Name())
@@ -244,6 +247,21 @@ func (tst *tsTable) Index() *elementIndex {
 	return tst.index
 }
 
+// Idle reports whether the table has no memory part, which would be lost once the table is closed.
+func (tst *tsTable) Idle() bool {
+	snp := tst.currentSnapshot()
+	if snp == nil {
+		return true
+	}
+	defer snp.decRef()
+	for _, pw := range snp.parts {
+		if pw.mp != nil {
+			return false
+		}
+	}
+	return true
+}
+
 func (tst *tsTable) Close() error {
 	if tst.loopCloser != nil {
 		tst.loopCloser.Done()
@@ -265,7 +283,7 @@ func (tst *tsTable) mustAddElements(es *elements) {
 	}
 
 	mp := generateMemPart()
-	mp.mustInitFromElements(es)
+	mp.mustInitFromElements(es, tst.option.compressThreshold)
 	p := openMemPart(mp)
 
 	ind := generateIntroduction()
@@ -284,7 +302,9 @@ func (tst *tsTable) mustAddElements(es *elements) {
 	}
 }
 
-func (tst *tsTable) getElement(seriesID common.SeriesID, timestamp int64, tagProjection []pbv1.TagProjection) (*element, int, error) {
+func (tst *tsTable) getElement(seriesID common.SeriesID, timestamp int64, tagProjection []pbv1.TagProjection,
+	account *storage.ReadAccount,
+) (*element, int, error) {
 	s := tst.currentSnapshot()
 	if s == nil {
 		return nil, 0, fmt.Errorf("snapshot is absent, cannot find element with seriesID %d and timestamp %d", seriesID, timestamp)
@@ -295,16 +315,21 @@ func (tst *tsTable) getElement(seriesID common.SeriesID, timestamp int64, tagPro
 		if !p.p.containTimestamp(timestamp) {
 			continue
 		}
-		elem, count, err := p.p.getElement(seriesID, timestamp, tagProjection)
+		elem, count, err := p.p.getElement(seriesID, timestamp, tagProjection, account)
 		if err == nil {
 			return elem, count, nil
 		}
+		if errors.Is(err, storage.ErrTooManyBytesRead) {
+			return nil, 0, err
+		}
 	}
 	return nil, 0, fmt.Errorf("cannot find element with seriesID %d and timestamp %d", seriesID, timestamp)
 }
 
 type tstIter struct {
-	err           error
+	err error
+	// account is set by the query before init. It's charged for the primary blocks the iteration reads.
+	account       *storage.ReadAccount
 	parts         []*part
 	piPool        []partIter
 	piHeap        partIterHeap
@@ -341,6 +366,7 @@ func (ti *tstIter) init(bma *blockMetadataArray, parts []*part, sids []common.Se
 	ti.piPool = ti.piPool[:len(ti.parts)]
 	for i, p := range ti.parts {
 		ti.piPool[i].init(bma, p, sids, minTimestamp, maxTimestamp)
+		ti.piPool[i].account = ti.account
 	}
 
 	ti.piHeap = ti.piHeap[:0]
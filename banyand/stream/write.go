@@ -20,13 +20,19 @@ package stream
 import (
 	"bytes"
 	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/pkg/errors"
 	"google.golang.org/protobuf/types/known/anypb"
 
 	"github.com/apache/skywalking-banyandb/api/common"
 	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
 	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
 	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/banyand/internal/storage"
 	"github.com/apache/skywalking-banyandb/pkg/bus"
 	"github.com/apache/skywalking-banyandb/pkg/convert"
 	"github.com/apache/skywalking-banyandb/pkg/index"
@@ -36,82 +42,100 @@ import (
 )
 
 type writeCallback struct {
-	l          *logger.Logger
-	schemaRepo *schemaRepo
+	l           *logger.Logger
+	schemaRepo  *schemaRepo
+	idempotency *idempotencyCache
+	sampler     *writeSampler
+	limiter     *groupWriteLimiter
 }
 
-func setUpWriteCallback(l *logger.Logger, schemaRepo *schemaRepo) bus.MessageListener {
+func setUpWriteCallback(l *logger.Logger, schemaRepo *schemaRepo, idempotency *idempotencyCache, sampler *writeSampler,
+	limiter *groupWriteLimiter,
+) bus.MessageListener {
 	return &writeCallback{
-		l:          l,
-		schemaRepo: schemaRepo,
+		l:           l,
+		schemaRepo:  schemaRepo,
+		idempotency: idempotency,
+		sampler:     sampler,
+		limiter:     limiter,
 	}
 }
 
-func (w *writeCallback) handle(dst map[string]*elementsInGroup, writeEvent *streamv1.InternalWriteRequest) (map[string]*elementsInGroup, error) {
+func (w *writeCallback) handle(dst map[string]*elementsInGroup, writeEvent *streamv1.InternalWriteRequest,
+	timing *writeTiming,
+) (map[string]*elementsInGroup, error) {
 	req := writeEvent.Request
 	t := req.Element.Timestamp.AsTime().Local()
 	if err := timestamp.Check(t); err != nil {
 		return nil, fmt.Errorf("invalid timestamp: %w", err)
 	}
-	ts := t.UnixNano()
 
 	gn := req.Metadata.Group
 	tsdb, err := w.schemaRepo.loadTSDB(gn)
 	if err != nil {
 		return nil, fmt.Errorf("cannot load tsdb for group %s: %w", gn, err)
 	}
+	if t, _, err = tsdb.AdmitTimestamp(t); err != nil {
+		return nil, err
+	}
+	ts := t.UnixNano()
+	stm, ok := w.schemaRepo.loadStream(writeEvent.GetRequest().GetMetadata())
+	if !ok {
+		return nil, fmt.Errorf("cannot find stream definition: %s", writeEvent.GetRequest().GetMetadata())
+	}
+	fLen := len(req.Element.GetTagFamilies())
+	if fLen < 1 {
+		return nil, fmt.Errorf("%s has no tag family", req)
+	}
+	if fLen > len(stm.schema.GetTagFamilies()) {
+		return nil, fmt.Errorf("%s has more tag families than %s", req.Metadata, stm.schema)
+	}
+	series := &pbv1.Series{
+		Subject:      req.Metadata.Name,
+		EntityValues: writeEvent.EntityValues,
+	}
+	if err := series.Marshal(); err != nil {
+		return nil, fmt.Errorf("cannot marshal series: %w", err)
+	}
+
+	// Nothing is added to the batch until the event can't fail, so a failed event leaves the others intact.
 	eg, ok := dst[gn]
 	if !ok {
 		eg = &elementsInGroup{
-			tsdb:   tsdb,
-			tables: make([]*elementsInTable, 0),
+			tsdb:    tsdb,
+			pending: stm.pendingWrites,
+			tables:  make([]*elementsInTable, 0),
 		}
-		dst[gn] = eg
 	}
-	if eg.latestTS < ts {
-		eg.latestTS = ts
-	}
-
+	shardID := common.ShardID(writeEvent.ShardId)
 	var et *elementsInTable
 	for i := range eg.tables {
-		if eg.tables[i].timeRange.Contains(ts) {
+		if eg.tables[i].shardID == shardID && eg.tables[i].timeRange.Contains(ts) {
 			et = eg.tables[i]
 			break
 		}
 	}
-	shardID := common.ShardID(writeEvent.ShardId)
 	if et == nil {
 		tsdb, err := tsdb.CreateTSTableIfNotExist(shardID, t)
 		if err != nil {
 			return nil, fmt.Errorf("cannot create ts table: %w", err)
 		}
 		et = &elementsInTable{
+			shardID:   shardID,
 			timeRange: tsdb.GetTimeRange(),
 			tsTable:   tsdb,
 		}
 		eg.tables = append(eg.tables, et)
 	}
+	dst[gn] = eg
+	if eg.latestTS < ts {
+		eg.latestTS = ts
+	}
+	timing.mark(writeStageResolve)
 	et.elements.timestamps = append(et.elements.timestamps, ts)
 	et.elements.elementIDs = append(et.elements.elementIDs, writeEvent.Request.Element.GetElementId())
-	stm, ok := w.schemaRepo.loadStream(writeEvent.GetRequest().GetMetadata())
-	if !ok {
-		return nil, fmt.Errorf("cannot find stream definition: %s", writeEvent.GetRequest().GetMetadata())
-	}
-	fLen := len(req.Element.GetTagFamilies())
-	if fLen < 1 {
-		return nil, fmt.Errorf("%s has no tag family", req)
-	}
-	if fLen > len(stm.schema.GetTagFamilies()) {
-		return nil, fmt.Errorf("%s has more tag families than %s", req.Metadata, stm.schema)
-	}
-	series := &pbv1.Series{
-		Subject:      req.Metadata.Name,
-		EntityValues: writeEvent.EntityValues,
-	}
-	if err := series.Marshal(); err != nil {
-		return nil, fmt.Errorf("cannot marshal series: %w", err)
-	}
 	et.elements.seriesIDs = append(et.elements.seriesIDs, series.ID)
+	eg.pending.add(series)
 
 	tagFamilies := make([]tagValues, 0, len(stm.schema.TagFamilies))
 	if len(stm.indexRuleLocators.TagFamilyTRule) != len(stm.GetSchema().GetTagFamilies()) {
@@ -178,6 +202,12 @@ func (w *writeCallback) handle(dst map[string]*elementsInGroup, writeEvent *stre
 			tagFamilies = append(tagFamilies, tf)
 		}
 	}
+	if stm.stampRevision {
+		tagFamilies = append(tagFamilies, schemaRevisionValues(stm.schema.GetMetadata().GetModRevision()))
+	}
+	if stm.schema.GetIndexElementId() {
+		fields = append(fields, elementIDField(series.ID, writeEvent.Request.Element.GetElementId()))
+	}
 	et.elements.tagFamilies = append(et.elements.tagFamilies, tagFamilies)
 
 	et.docs = append(et.docs, index.Document{
@@ -189,6 +219,7 @@ func (w *writeCallback) handle(dst map[string]*elementsInGroup, writeEvent *stre
 		DocID:        uint64(series.ID),
 		EntityValues: series.Buffer,
 	})
+	timing.mark(writeStageSerialize)
 	return dst, nil
 }
 
@@ -202,7 +233,7 @@ func (w *writeCallback) Rev(message bus.Message) (resp bus.Message) {
 		w.l.Warn().Msg("empty event")
 		return
 	}
-	groups := make(map[string]*elementsInGroup)
+	batch := make([]*streamv1.InternalWriteRequest, 0, len(events))
 	for i := range events {
 		var writeEvent *streamv1.InternalWriteRequest
 		switch e := events[i].(type) {
@@ -218,34 +249,162 @@ func (w *writeCallback) Rev(message bus.Message) (resp bus.Message) {
 			w.l.Warn().Msg("invalid event data type")
 			continue
 		}
-		var err error
-		if groups, err = w.handle(groups, writeEvent); err != nil {
-			w.l.Error().Err(err).Msg("cannot handle write event")
-			groups = make(map[string]*elementsInGroup)
+		batch = append(batch, writeEvent)
+	}
+	var failed []string
+	for i, err := range w.writeBatch(batch) {
+		switch {
+		case err == nil:
+			continue
+		case errors.Is(err, storage.ErrOutOfRetention):
+			w.l.Warn().Err(err).RawJSON("written", logger.Proto(batch[i])).Msg("reject the write out of the retention window")
+		case errors.Is(err, errWriteRejected):
+			w.l.Warn().Err(err).RawJSON("written", logger.Proto(batch[i])).Msg("reject the write exceeding the concurrency limit of the group")
+		default:
+			w.l.Error().Err(err).RawJSON("written", logger.Proto(batch[i])).Msg("cannot handle write event")
+		}
+		failed = append(failed, fmt.Sprintf("message %d: %v", batch[i].GetRequest().GetMessageId(), err))
+	}
+	if len(failed) > 0 {
+		// The failed writes are returned to the sender, while the rest of the batch is written.
+		return bus.NewMessage(message.ID(), common.NewError("%d of %d writes failed: %s", len(failed), len(events), strings.Join(failed, "; ")))
+	}
+	return
+}
+
+// writeBatch writes the events in a single pass. The elements are grouped by their tables,
+// and every table adds its elements and updates its element index once.
+// It returns an error per event, which is nil if the event is written or skipped as a duplicate.
+// A failed event doesn't abort the rest of the batch.
+// If the writes are limited per group, the groups are written concurrently, each once it has a free slot,
+// and writeBatch returns once all of them are written or rejected, so a batch is acknowledged after its writes.
+func (w *writeCallback) writeBatch(events []*streamv1.InternalWriteRequest) []error {
+	errs := make([]error, len(events))
+	groups := make(map[string]*elementsInGroup)
+	// handled lists the events of every group to write.
+	handled := make(map[string][]int)
+	now := time.Now()
+	var keys []string
+	var timings []*writeTiming
+	for i, writeEvent := range events {
+		key := idempotencyKey(writeEvent.GetRequest())
+		if key != "" && (w.idempotency.contains(key, now) || slices.Contains(keys, key)) {
+			w.l.Debug().Str("key", writeEvent.GetRequest().GetIdempotencyKey()).Msg("skip a duplicated write")
+			continue
+		}
+		timing := w.sampler.sample(writeEvent.GetRequest())
+		if _, err := w.handle(groups, writeEvent, timing); err != nil {
+			errs[i] = err
 			continue
 		}
+		group := writeEvent.GetRequest().GetMetadata().GetGroup()
+		handled[group] = append(handled[group], i)
+		if key != "" {
+			keys = append(keys, key)
+		}
+		if timing != nil {
+			timings = append(timings, timing)
+		}
+	}
+	if w.limiter == nil {
+		w.write(groups, timings)
+		w.idempotency.add(keys, now)
+		return errs
 	}
+	var wg sync.WaitGroup
+	for name, g := range groups {
+		// The keys of the writes are remembered once they're written.
+		groupKeys := slices.DeleteFunc(slices.Clone(keys), func(key string) bool {
+			return !strings.HasPrefix(key, name+"/")
+		})
+		groupTimings := slices.DeleteFunc(slices.Clone(timings), func(t *writeTiming) bool {
+			return t.req.GetMetadata().GetGroup() != name
+		})
+		wg.Add(1)
+		go func(name string, g *elementsInGroup) {
+			defer wg.Done()
+			if !w.limiter.acquire(name) {
+				for j := range g.tables {
+					g.pending.done(g.tables[j].elements.seriesIDs)
+					g.tables[j].tsTable.DecRef()
+				}
+				// Every goroutine sets the errors of its own group's events.
+				for _, i := range handled[name] {
+					errs[i] = errWriteRejected
+				}
+				return
+			}
+			defer w.limiter.release(name)
+			w.write(map[string]*elementsInGroup{name: g}, groupTimings)
+			w.idempotency.add(groupKeys, time.Now())
+		}(name, g)
+	}
+	wg.Wait()
+	return errs
+}
+
+// write adds the handled elements to their tables, then writes the element and the series indexes.
+// The sampled writes of the batch are reported once it's written.
+func (w *writeCallback) write(groups map[string]*elementsInGroup, timings []*writeTiming) {
+	sampled := len(timings) > 0
+	var start time.Time
+	var buffered, flushed time.Duration
+	var batchSize int
 	for i := range groups {
 		g := groups[i]
 		g.tsdb.Tick(g.latestTS)
 		for j := range g.tables {
 			es := g.tables[j]
+			if sampled {
+				start = time.Now()
+				batchSize += len(es.elements.timestamps)
+			}
 			es.tsTable.Table().mustAddElements(&es.elements)
+			if sampled {
+				buffered += time.Since(start)
+				start = time.Now()
+			}
 			if len(es.docs) > 0 {
 				index := es.tsTable.Table().Index()
 				if err := index.Write(es.docs); err != nil {
 					w.l.Error().Err(err).Msg("cannot write element index")
 				}
 			}
+			if sampled {
+				flushed += time.Since(start)
+			}
 			es.tsTable.DecRef()
 		}
+		if sampled {
+			start = time.Now()
+		}
 		if len(g.docs) > 0 {
 			if err := g.tsdb.IndexDB().Write(g.docs); err != nil {
 				w.l.Error().Err(err).Msg("cannot write series index")
 			}
 		}
+		// The elements become visible once both the tables and the series index are written.
+		for j := range g.tables {
+			g.pending.done(g.tables[j].elements.seriesIDs)
+		}
+		if sampled {
+			flushed += time.Since(start)
+		}
 	}
-	return
+	for _, t := range timings {
+		t.durations[writeStageBuffer] = buffered
+		t.durations[writeStageFlush] = flushed
+		t.batchSize = batchSize
+		w.sampler.report(t)
+	}
+}
+
+// idempotencyKey scopes the idempotency key of a write to its stream.
+func idempotencyKey(req *streamv1.WriteRequest) string {
+	if req.GetIdempotencyKey() == "" {
+		return ""
+	}
+	return req.GetMetadata().GetGroup() + "/" + req.GetMetadata().GetName() + "/" + req.GetIdempotencyKey()
 }
 
 func encodeTagValue(name string, tagType databasev1.TagType, tagVal *modelv1.TagValue) *tagValue {
@@ -0,0 +1,56 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/apache/skywalking-banyandb/pkg/test/flags"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+var _ = Describe("Write with an idempotency key", func() {
+	now := time.Now()
+	tr := timestamp.NewInclusiveTimeRange(now.Add(-time.Hour), now.Add(time.Hour))
+	var svcs *services
+	var deferFn func()
+
+	BeforeEach(func() {
+		svcs, deferFn = setUp()
+		waitForStream(svcs)
+	})
+
+	AfterEach(func() {
+		deferFn()
+	})
+
+	It("stores a retried write once", func() {
+		req := newWriteRequest("keyed", now)
+		req.IdempotencyKey = "retry-1"
+		writeElement(svcs, req)
+		Eventually(func() []string { return queryElementIDs(svcs, tr, 0) }).WithTimeout(flags.EventuallyTimeout).Should(ConsistOf("keyed"))
+
+		writeElement(svcs, req)
+		writeElement(svcs, newWriteRequest("unkeyed", now))
+		Eventually(func() []string { return queryElementIDs(svcs, tr, 0) }).WithTimeout(flags.EventuallyTimeout).Should(ConsistOf("keyed", "unkeyed"))
+		Consistently(func() []string { return queryElementIDs(svcs, tr, 0) }).WithTimeout(500 * time.Millisecond).Should(ConsistOf("keyed", "unkeyed"))
+	})
+})
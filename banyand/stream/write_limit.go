@@ -0,0 +1,109 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/apache/skywalking-banyandb/banyand/observability"
+	"github.com/apache/skywalking-banyandb/pkg/meter"
+)
+
+const (
+	writeOverflowQueue  = "queue"
+	writeOverflowReject = "reject"
+)
+
+var (
+	errInvalidWriteOverflow = errors.New("the write overflow policy should be either queue or reject")
+	errWriteRejected        = errors.New("the write exceeds the concurrency limit of the group")
+)
+
+var (
+	writeInflight = sync.OnceValue(func() meter.Gauge {
+		return observability.NewGauge(observability.NewMeterProviders(observability.RootScope.SubScope("stream")),
+			"write_inflight", "group")
+	})
+	writeRejected = sync.OnceValue(func() meter.Counter {
+		return observability.NewCounter(observability.NewMeterProviders(observability.RootScope.SubScope("stream")),
+			"write_rejected", "group")
+	})
+)
+
+// groupWriteLimiter bounds the concurrent writes of every group, so that a group under a write storm
+// can't occupy all the write workers and starve the others.
+// The writes exceeding the limit either wait for a free slot, or are rejected at once.
+type groupWriteLimiter struct {
+	groups map[string]chan struct{}
+	limit  int
+	reject bool
+	mu     sync.Mutex
+}
+
+// newGroupWriteLimiter returns nil if the limit isn't positive, which doesn't limit the writes.
+func newGroupWriteLimiter(limit int, overflow string) *groupWriteLimiter {
+	if limit <= 0 {
+		return nil
+	}
+	return &groupWriteLimiter{
+		groups: make(map[string]chan struct{}),
+		limit:  limit,
+		reject: overflow == writeOverflowReject,
+	}
+}
+
+func (l *groupWriteLimiter) slots(group string) chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	slots, ok := l.groups[group]
+	if !ok {
+		slots = make(chan struct{}, l.limit)
+		l.groups[group] = slots
+	}
+	return slots
+}
+
+// acquire takes a write slot of the group. It returns false if all the slots are taken and the limiter rejects the overflow.
+func (l *groupWriteLimiter) acquire(group string) bool {
+	if l == nil {
+		return true
+	}
+	slots := l.slots(group)
+	if l.reject {
+		select {
+		case slots <- struct{}{}:
+		default:
+			writeRejected().Inc(1, group)
+			return false
+		}
+	} else {
+		slots <- struct{}{}
+	}
+	writeInflight().Add(1, group)
+	return true
+}
+
+func (l *groupWriteLimiter) release(group string) {
+	if l == nil {
+		return
+	}
+	writeInflight().Add(-1, group)
+	<-l.slots(group)
+}
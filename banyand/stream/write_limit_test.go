@@ -0,0 +1,92 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupWriteLimiter(t *testing.T) {
+	const (
+		limit     = 2
+		writers   = 16
+		writeTime = 20 * time.Millisecond
+	)
+	limiter := newGroupWriteLimiter(limit, writeOverflowQueue)
+	var inflight, maxInflight atomic.Int32
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	// The storm group keeps many more writers busy than its limit.
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				limiter.acquire("storm")
+				n := inflight.Add(1)
+				for {
+					m := maxInflight.Load()
+					if n <= m || maxInflight.CompareAndSwap(m, n) {
+						break
+					}
+				}
+				time.Sleep(writeTime)
+				inflight.Add(-1)
+				limiter.release("storm")
+			}
+		}()
+	}
+	time.Sleep(writeTime)
+	var maxWait time.Duration
+	for i := 0; i < 10; i++ {
+		start := time.Now()
+		assert.True(t, limiter.acquire("quiet"))
+		maxWait = max(maxWait, time.Since(start))
+		limiter.release("quiet")
+		time.Sleep(time.Millisecond)
+	}
+	close(stop)
+	wg.Wait()
+
+	assert.Equal(t, int32(limit), maxInflight.Load(), "the storm group never exceeds its limit")
+	assert.Less(t, maxWait, writeTime, "the quiet group doesn't wait for the storm group")
+}
+
+func TestGroupWriteLimiterReject(t *testing.T) {
+	limiter := newGroupWriteLimiter(1, writeOverflowReject)
+	assert.True(t, limiter.acquire("storm"))
+	assert.False(t, limiter.acquire("storm"), "the overflow is rejected")
+	assert.True(t, limiter.acquire("quiet"))
+	limiter.release("storm")
+	assert.True(t, limiter.acquire("storm"))
+
+	var unlimited *groupWriteLimiter
+	assert.Nil(t, newGroupWriteLimiter(0, writeOverflowReject))
+	assert.True(t, unlimited.acquire("storm"))
+	unlimited.release("storm")
+}
@@ -0,0 +1,60 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+	resourceSchema "github.com/apache/skywalking-banyandb/pkg/schema"
+)
+
+type emptyRepository struct {
+	resourceSchema.Repository
+}
+
+func (emptyRepository) LoadGroup(string) (resourceSchema.Group, bool) {
+	return nil, false
+}
+
+func TestWriteResponse(t *testing.T) {
+	l := logger.GetLogger("test")
+	w := setUpWriteCallback(l, &schemaRepo{Repository: emptyRepository{}}, newIdempotencyCache(t.TempDir(), 0, 0, l), nil, nil)
+	write := func(messageID uint64, ts time.Time) any {
+		return &streamv1.InternalWriteRequest{Request: &streamv1.WriteRequest{
+			MessageId: messageID,
+			Metadata:  &commonv1.Metadata{Name: "sw", Group: "missing"},
+			Element:   &streamv1.ElementValue{ElementId: "1", Timestamp: timestamppb.New(ts)},
+		}}
+	}
+	resp := w.Rev(bus.NewMessage(1, []any{write(11, time.Now().Truncate(time.Millisecond)), write(12, time.Unix(1700000000, 123))}))
+	msg, ok := resp.Data().(common.Error)
+	require.True(t, ok, "the failed writes are returned in the response")
+	assert.Contains(t, msg.Msg(), "2 of 2 writes failed")
+	assert.Contains(t, msg.Msg(), "message 11: cannot load tsdb for group missing")
+	assert.Contains(t, msg.Msg(), "message 12: invalid timestamp")
+}
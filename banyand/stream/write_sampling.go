@@ -0,0 +1,97 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"sync/atomic"
+	"time"
+
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+type writeStage int
+
+const (
+	// writeStageResolve loads the group's database and the table of the element's shard.
+	writeStageResolve writeStage = iota
+	// writeStageSerialize encodes the series and the tags of the element.
+	writeStageSerialize
+	// writeStageBuffer adds the batch to the memory parts.
+	writeStageBuffer
+	// writeStageFlush writes the element and the series indexes of the batch.
+	writeStageFlush
+	writeStageNum
+)
+
+// writeTiming records how long the stages of a sampled write take.
+// The buffer and the flush stages are shared by all the writes of a batch.
+type writeTiming struct {
+	last      time.Time
+	req       *streamv1.WriteRequest
+	durations [writeStageNum]time.Duration
+	batchSize int
+}
+
+// mark ends the stage. It does nothing for a write which isn't sampled.
+func (wt *writeTiming) mark(stage writeStage) {
+	if wt == nil {
+		return
+	}
+	now := time.Now()
+	wt.durations[stage] = now.Sub(wt.last)
+	wt.last = now
+}
+
+// writeSampler picks one in every rate writes to record their timing. A zero rate disables the sampling.
+type writeSampler struct {
+	report  func(*writeTiming)
+	counter atomic.Uint64
+	rate    uint64
+}
+
+func newWriteSampler(rate int, l *logger.Logger) *writeSampler {
+	ws := &writeSampler{}
+	if rate > 0 {
+		ws.rate = uint64(rate)
+	}
+	ws.report = func(wt *writeTiming) {
+		l.Info().
+			Str("group", wt.req.GetMetadata().GetGroup()).
+			Str("name", wt.req.GetMetadata().GetName()).
+			Str("element_id", wt.req.GetElement().GetElementId()).
+			Int("batch_size", wt.batchSize).
+			Dur("resolve", wt.durations[writeStageResolve]).
+			Dur("serialize", wt.durations[writeStageSerialize]).
+			Dur("buffer", wt.durations[writeStageBuffer]).
+			Dur("flush", wt.durations[writeStageFlush]).
+			Msg("sampled write timing")
+	}
+	return ws
+}
+
+// sample returns the timing record of a sampled write, and nil for the others.
+func (ws *writeSampler) sample(req *streamv1.WriteRequest) *writeTiming {
+	if ws == nil || ws.rate == 0 {
+		return nil
+	}
+	if ws.counter.Add(1)%ws.rate != 0 {
+		return nil
+	}
+	return &writeTiming{req: req, last: time.Now()}
+}
@@ -0,0 +1,57 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+// sampleWrites passes the writes through the sampler and returns the IDs of the reported ones.
+func sampleWrites(rate, writes int) []string {
+	l := logger.GetLogger("test")
+	sampler := newWriteSampler(rate, l)
+	var reported []string
+	sampler.report = func(wt *writeTiming) {
+		reported = append(reported, wt.req.GetElement().GetElementId())
+	}
+	w := &writeCallback{l: l, sampler: sampler}
+	var timings []*writeTiming
+	for i := 1; i <= writes; i++ {
+		t := sampler.sample(&streamv1.WriteRequest{Element: &streamv1.ElementValue{ElementId: strconv.Itoa(i)}})
+		t.mark(writeStageResolve)
+		t.mark(writeStageSerialize)
+		if t != nil {
+			timings = append(timings, t)
+		}
+	}
+	w.write(nil, timings)
+	return reported
+}
+
+func TestWriteSampling(t *testing.T) {
+	assert.Equal(t, []string{"3", "6", "9"}, sampleWrites(3, 10), "one in every three writes is reported")
+	assert.Len(t, sampleWrites(1, 10), 10)
+	assert.Empty(t, sampleWrites(0, 10), "the sampling is off by default")
+	assert.Empty(t, sampleWrites(-1, 10))
+}
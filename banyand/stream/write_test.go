@@ -0,0 +1,128 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/apache/skywalking-banyandb/api/data"
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/test/flags"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+var (
+	swMetadata = &commonv1.Metadata{Name: "sw", Group: "default"}
+	swEntity   = []*modelv1.TagValue{
+		{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "svc_1"}}},
+		{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "svc_1_instance_1"}}},
+		{Value: &modelv1.TagValue_Int{Int: &modelv1.Int{Value: 0}}},
+	}
+)
+
+func writeElement(svcs *services, req *streamv1.WriteRequest) {
+	writeElements(svcs, req)
+}
+
+// writeElements publishes the requests as a batch.
+func writeElements(svcs *services, reqs ...*streamv1.WriteRequest) {
+	events := make([]any, 0, len(reqs))
+	for _, req := range reqs {
+		events = append(events, &streamv1.InternalWriteRequest{
+			EntityValues: swEntity,
+			Request:      req,
+		})
+	}
+	_, err := svcs.pipeline.Publish(data.TopicStreamWrite, bus.NewMessage(bus.MessageID(time.Now().UnixNano()), events))
+	Expect(err).ShouldNot(HaveOccurred())
+}
+
+func newWriteRequest(id string, ts time.Time) *streamv1.WriteRequest {
+	return &streamv1.WriteRequest{
+		Metadata: swMetadata,
+		Element: &streamv1.ElementValue{
+			ElementId: id,
+			Timestamp: timestamppb.New(ts.Truncate(time.Millisecond)),
+			TagFamilies: []*modelv1.TagFamilyForWrite{
+				{Tags: []*modelv1.TagValue{{Value: &modelv1.TagValue_BinaryData{BinaryData: []byte(id)}}}},
+				{Tags: []*modelv1.TagValue{{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: id}}}, swEntity[2], swEntity[0], swEntity[1]}},
+			},
+		},
+	}
+}
+
+func queryElementIDs(svcs *services, timeRange timestamp.TimeRange, maxStaleness time.Duration) []string {
+	s, err := svcs.stream.Stream(swMetadata)
+	Expect(err).ShouldNot(HaveOccurred())
+	result, err := s.Query(context.Background(), pbv1.StreamQueryOptions{
+		Name:          swMetadata.Name,
+		TimeRange:     &timeRange,
+		Entities:      [][]*modelv1.TagValue{swEntity},
+		TagProjection: []pbv1.TagProjection{{Family: "searchable", Names: []string{"trace_id"}}},
+		MaxStaleness:  maxStaleness,
+	})
+	Expect(err).ShouldNot(HaveOccurred())
+	if result == nil {
+		return nil
+	}
+	defer result.Release()
+	var ids []string
+	for r := result.Pull(); r != nil; r = result.Pull() {
+		ids = append(ids, r.ElementIDs...)
+	}
+	return ids
+}
+
+func waitForStream(svcs *services) {
+	Eventually(func() bool {
+		_, err := svcs.stream.Stream(swMetadata)
+		return err == nil
+	}).WithTimeout(flags.EventuallyTimeout).Should(BeTrue())
+}
+
+var _ = Describe("Write a batch", func() {
+	now := time.Now()
+	tr := timestamp.NewInclusiveTimeRange(now.Add(-time.Hour), now.Add(time.Hour))
+	var svcs *services
+	var deferFn func()
+
+	BeforeEach(func() {
+		svcs, deferFn = setUp()
+		waitForStream(svcs)
+	})
+
+	AfterEach(func() {
+		deferFn()
+	})
+
+	It("writes the rest of the batch if an element fails", func() {
+		invalid := newWriteRequest("invalid", now)
+		invalid.Element.TagFamilies = nil
+		writeElements(svcs, newWriteRequest("first", now), invalid, newWriteRequest("last", now.Add(time.Millisecond)))
+		Eventually(func() []string { return queryElementIDs(svcs, tr, 0) }).WithTimeout(flags.EventuallyTimeout).Should(ConsistOf("first", "last"))
+	})
+})
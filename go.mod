@@ -5,6 +5,7 @@ go 1.22
 require (
 	github.com/RoaringBitmap/roaring v1.9.3
 	github.com/apache/skywalking-cli v0.0.0-20240227151024-ee371a210afe
+	github.com/axiomhq/hyperloglog v0.0.0-20240319100328-84253e514e02
 	github.com/benbjohnson/clock v1.3.0
 	github.com/blugelabs/bluge v0.2.2
 	github.com/cespare/xxhash v1.1.0
@@ -58,7 +59,6 @@ require (
 )
 
 require (
-	github.com/axiomhq/hyperloglog v0.0.0-20240319100328-84253e514e02 // indirect
 	github.com/beorn7/perks v1.0.1 // indirect
 	github.com/bits-and-blooms/bitset v1.13.0 // indirect
 	github.com/blevesearch/go-porterstemmer v1.0.3 // indirect
@@ -20,6 +20,7 @@
 package bus
 
 import (
+	"context"
 	"errors"
 	"io"
 	"sync"
@@ -45,6 +46,7 @@ type (
 
 // Message is send on the bus to all subscribed listeners.
 type Message struct {
+	ctx       context.Context
 	payload   payload
 	node      string
 	id        MessageID
@@ -71,6 +73,20 @@ func (m Message) BatchModeEnabled() bool {
 	return m.batchMode
 }
 
+// Context returns the context of the caller publishing the Message.
+func (m Message) Context() context.Context {
+	if m.ctx == nil {
+		return context.Background()
+	}
+	return m.ctx
+}
+
+// WithContext returns a copy of the Message carrying the context of the caller.
+func (m Message) WithContext(ctx context.Context) Message {
+	m.ctx = ctx
+	return m
+}
+
 // NewMessage returns a new Message with a MessageID and embed data.
 func NewMessage(id MessageID, data interface{}) Message {
 	return Message{id: id, node: "local", payload: data}
@@ -29,6 +29,7 @@ import (
 	"github.com/apache/skywalking-banyandb/banyand/liaison/http"
 	"github.com/apache/skywalking-banyandb/banyand/metadata"
 	"github.com/apache/skywalking-banyandb/banyand/observability"
+	"github.com/apache/skywalking-banyandb/banyand/protector"
 	"github.com/apache/skywalking-banyandb/banyand/queue"
 	"github.com/apache/skywalking-banyandb/banyand/queue/pub"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
@@ -46,11 +47,12 @@ func newLiaisonCmd(runners ...run.Unit) *cobra.Command {
 	}
 	pipeline := pub.New(metaSvc)
 	localPipeline := queue.Local()
+	pm := protector.NewMemory()
 	nodeSel, err := node.NewMaglevSelector()
 	if err != nil {
 		l.Fatal().Err(err).Msg("failed to initiate required node selector")
 	}
-	grpcServer := grpc.NewServer(ctx, pipeline, localPipeline, metaSvc, grpc.NewClusterNodeRegistry(pipeline, nodeSel))
+	grpcServer := grpc.NewServer(ctx, pipeline, localPipeline, metaSvc, grpc.NewClusterNodeRegistry(pipeline, nodeSel), pm)
 	profSvc := observability.NewProfService()
 	metricSvc := observability.NewMetricService(metaSvc)
 	httpServer := http.NewServer()
@@ -64,6 +66,7 @@ func newLiaisonCmd(runners ...run.Unit) *cobra.Command {
 		metaSvc,
 		localPipeline,
 		pipeline,
+		pm,
 		dQuery,
 		grpcServer,
 		httpServer,
@@ -19,6 +19,7 @@ package encoding
 
 import (
 	"fmt"
+	"math"
 
 	"github.com/apache/skywalking-banyandb/pkg/bytes"
 	"github.com/apache/skywalking-banyandb/pkg/compress/zstd"
@@ -44,15 +45,28 @@ func DecodeBytes(src []byte) ([]byte, []byte, error) {
 	return src[n:], src[:n], nil
 }
 
+// DefaultCompressThreshold is the size in bytes below which a bytes block is stored uncompressed.
+const DefaultCompressThreshold = 128
+
 // EncodeBytesBlock encodes a block of strings into dst.
 func EncodeBytesBlock(dst []byte, a [][]byte) []byte {
+	return EncodeBytesBlockWithThreshold(dst, a, DefaultCompressThreshold)
+}
+
+// EncodeBytesBlockWithThreshold encodes a block of strings into dst.
+// Each part of the block is stored uncompressed if it's smaller than threshold bytes.
+// A non-positive threshold falls back to DefaultCompressThreshold.
+func EncodeBytesBlockWithThreshold(dst []byte, a [][]byte, threshold int) []byte {
+	if threshold <= 0 {
+		threshold = DefaultCompressThreshold
+	}
 	u64s := GenerateUint64List(len(a))
 	aLens := u64s.L[:0]
 	for _, s := range a {
 		aLens = append(aLens, uint64(len(s)))
 	}
 	u64s.L = aLens
-	dst = encodeUint64Block(dst, u64s.L)
+	dst = encodeUint64Block(dst, u64s.L, threshold)
 	ReleaseUint64List(u64s)
 
 	bb := bbPool.Generate()
@@ -61,7 +75,7 @@ func EncodeBytesBlock(dst []byte, a [][]byte) []byte {
 		b = append(b, s...)
 	}
 	bb.Buf = b
-	dst = compressBlock(dst, bb.Buf)
+	dst = compressBlock(dst, bb.Buf, threshold)
 	bbPool.Release(bb)
 
 	return dst
@@ -117,10 +131,10 @@ func (bbd *BytesBlockDecoder) Decode(dst [][]byte, src []byte, itemsCount uint64
 	return dst, nil
 }
 
-func encodeUint64Block(dst []byte, a []uint64) []byte {
+func encodeUint64Block(dst []byte, a []uint64, threshold int) []byte {
 	bb := bbPool.Generate()
 	bb.Buf = encodeUint64List(bb.Buf[:0], a)
-	dst = compressBlock(dst, bb.Buf)
+	dst = compressBlock(dst, bb.Buf, threshold)
 	bbPool.Release(bb)
 	return dst
 }
@@ -232,11 +246,18 @@ func decodeUint64List(dst []uint64, src []byte, itemsCount uint64) ([]uint64, er
 const (
 	compressTypePlain = 0
 	compressTypeZSTD  = 1
+	// compressTypePlainLong is a plain block whose size doesn't fit in a byte.
+	compressTypePlainLong = 2
 )
 
-func compressBlock(dst, src []byte) []byte {
-	if len(src) < 128 {
-		dst = append(dst, compressTypePlain, byte(len(src)))
+func compressBlock(dst, src []byte, threshold int) []byte {
+	if len(src) < threshold {
+		if len(src) <= math.MaxUint8 {
+			dst = append(dst, compressTypePlain, byte(len(src)))
+			return append(dst, src...)
+		}
+		dst = append(dst, compressTypePlainLong)
+		dst = VarUint64ToBytes(dst, uint64(len(src)))
 		return append(dst, src...)
 	}
 
@@ -266,6 +287,18 @@ func decompressBlock(dst, src []byte) ([]byte, []byte, error) {
 			return dst, src, fmt.Errorf("cannot read plain block with the size %d bytes from %b bytes", blockLen, len(src))
 		}
 
+		dst = append(dst, src[:blockLen]...)
+		src = src[blockLen:]
+		return dst, src, nil
+	case compressTypePlainLong:
+		tail, blockLen, err := BytesToVarUint64(src)
+		if err != nil {
+			return dst, src, fmt.Errorf("cannot decode plain block size: %w", err)
+		}
+		src = tail
+		if uint64(len(src)) < blockLen {
+			return dst, src, fmt.Errorf("cannot read plain block with the size %d bytes from %d bytes", blockLen, len(src))
+		}
 		dst = append(dst, src[:blockLen]...)
 		src = src[blockLen:]
 		return dst, src, nil
@@ -293,7 +326,7 @@ func decompressBlock(dst, src []byte) ([]byte, []byte, error) {
 		bbPool.Release(bb)
 		return dst, src, nil
 	default:
-		return dst, src, fmt.Errorf("unexpected block type: %d; supported types: 0, 1", blockType)
+		return dst, src, fmt.Errorf("unexpected block type: %d; supported types: 0, 1, 2", blockType)
 	}
 }
 
@@ -0,0 +1,86 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package encoding
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressBlockThreshold(t *testing.T) {
+	tests := []struct {
+		name      string
+		size      int
+		threshold int
+		wantType  byte
+	}{
+		{name: "tiny block under the default", size: 16, threshold: DefaultCompressThreshold, wantType: compressTypePlain},
+		{name: "block at the default", size: DefaultCompressThreshold, threshold: DefaultCompressThreshold, wantType: compressTypeZSTD},
+		{name: "one byte under the threshold", size: 199, threshold: 200, wantType: compressTypePlain},
+		{name: "at the threshold", size: 200, threshold: 200, wantType: compressTypeZSTD},
+		{name: "long plain block under the threshold", size: 1023, threshold: 1024, wantType: compressTypePlainLong},
+		{name: "long block at the threshold", size: 1024, threshold: 1024, wantType: compressTypeZSTD},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := bytes.Repeat([]byte("searchable"), tt.size/10+1)[:tt.size]
+			encoded := compressBlock(nil, src, tt.threshold)
+			require.NotEmpty(t, encoded)
+			assert.Equal(t, tt.wantType, encoded[0])
+
+			decoded, tail, err := decompressBlock(nil, encoded)
+			require.NoError(t, err)
+			assert.Empty(t, tail)
+			assert.Equal(t, src, decoded)
+		})
+	}
+}
+
+func TestEncodeBytesBlockWithThreshold(t *testing.T) {
+	const threshold = 300
+	small := [][]byte{[]byte("trace_id"), []byte("svc"), bytes.Repeat([]byte("a"), 280)}
+	large := [][]byte{[]byte("trace_id"), []byte("svc"), bytes.Repeat([]byte("a"), 300)}
+	for _, tc := range []struct {
+		name     string
+		values   [][]byte
+		wantType byte
+	}{
+		{name: "below the threshold", values: small, wantType: compressTypePlainLong},
+		{name: "above the threshold", values: large, wantType: compressTypeZSTD},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			encoded := EncodeBytesBlockWithThreshold(nil, tc.values, threshold)
+
+			lens := make([]uint64, len(tc.values))
+			for i, v := range tc.values {
+				lens[i] = uint64(len(v))
+			}
+			prefix := encodeUint64Block(nil, lens, threshold)
+			require.Greater(t, len(encoded), len(prefix))
+			assert.Equal(t, tc.wantType, encoded[len(prefix)])
+
+			decoder := &BytesBlockDecoder{}
+			decoded, err := decoder.Decode(nil, encoded, uint64(len(tc.values)))
+			require.NoError(t, err)
+			assert.Equal(t, tc.values, decoded)
+		})
+	}
+}
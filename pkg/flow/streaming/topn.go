@@ -68,6 +68,9 @@ func (s *windowedFlow) TopN(topNum int, opts ...any) flow.Flow {
 				return utils.Int64Comparator(b, a)
 			}
 		}
+		if topNAggrFunc.aggregations != nil {
+			return &aggregatedTopN{topNAggregatorGroup: topNAggrFunc, groups: make(map[string]*aggregatedGroup)}
+		}
 		topNAggrFunc.aggregatorGroup = make(map[string]*topNAggregator)
 		return topNAggrFunc
 	}
@@ -79,6 +82,7 @@ type topNAggregatorGroup struct {
 	sortKeyExtractor  func(flow.StreamRecord) int64
 	groupKeyExtractor func(flow.StreamRecord) string
 	comparator        utils.Comparator
+	aggregations      *topNAggregations
 	l                 *logger.Logger
 	cacheSize         int
 	sort              TopNSort
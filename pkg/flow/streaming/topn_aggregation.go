@@ -0,0 +1,131 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package streaming
+
+import (
+	"sort"
+
+	"github.com/apache/skywalking-banyandb/pkg/flow"
+)
+
+// Reducer reduces the sort keys of the records sharing an entity.
+type Reducer interface {
+	In(int64)
+	Val() int64
+}
+
+// WithAggregations ranks the entities by their reduced sort keys instead of ranking the records.
+// Every reducer ranks the entities separately, and the snapshot maps the name of a reducer to its ranks by group.
+// The records of an entity are identified by entityKeyExtractor, and the latest one stands for the entity in the ranks.
+func WithAggregations(entityKeyExtractor func(flow.StreamRecord) string, names []string, newReducers func() []Reducer) TopNOption {
+	return func(aggregator *topNAggregatorGroup) {
+		aggregator.aggregations = &topNAggregations{
+			entityKeyExtractor: entityKeyExtractor,
+			names:              names,
+			newReducers:        newReducers,
+		}
+	}
+}
+
+type topNAggregations struct {
+	entityKeyExtractor func(flow.StreamRecord) string
+	newReducers        func() []Reducer
+	names              []string
+}
+
+// aggregatedTopN shares a pass over the records among the reducers.
+type aggregatedTopN struct {
+	*topNAggregatorGroup
+	groups map[string]*aggregatedGroup
+}
+
+type aggregatedGroup struct {
+	entities map[string]*aggregatedEntity
+	dirty    bool
+}
+
+type aggregatedEntity struct {
+	record   flow.StreamRecord
+	reducers []Reducer
+}
+
+func (a *aggregatedTopN) Add(input []flow.StreamRecord) {
+	for _, item := range input {
+		groupKey := a.groupKeyExtractor(item)
+		group, ok := a.groups[groupKey]
+		if !ok {
+			group = &aggregatedGroup{entities: make(map[string]*aggregatedEntity)}
+			a.groups[groupKey] = group
+		}
+		entityKey := a.aggregations.entityKeyExtractor(item)
+		entity, ok := group.entities[entityKey]
+		if !ok {
+			entity = &aggregatedEntity{reducers: a.aggregations.newReducers()}
+			group.entities[entityKey] = entity
+		}
+		entity.record = item
+		sortKey := a.sortKeyExtractor(item)
+		for _, r := range entity.reducers {
+			r.In(sortKey)
+		}
+		group.dirty = true
+	}
+}
+
+func (a *aggregatedTopN) Snapshot() interface{} {
+	ranks := make(map[string]map[string][]*Tuple2, len(a.aggregations.names))
+	for groupKey, group := range a.groups {
+		if !group.dirty {
+			continue
+		}
+		group.dirty = false
+		// The entities are sorted by their keys first to break the ties.
+		entityKeys := make([]string, 0, len(group.entities))
+		for k := range group.entities {
+			entityKeys = append(entityKeys, k)
+		}
+		sort.Strings(entityKeys)
+		for i, name := range a.aggregations.names {
+			items := make([]*Tuple2, 0, len(entityKeys))
+			for _, k := range entityKeys {
+				entity := group.entities[k]
+				items = append(items, &Tuple2{entity.reducers[i].Val(), entity.record})
+			}
+			sort.SliceStable(items, func(x, y int) bool {
+				return a.comparator(items[x].V1, items[y].V1) < 0
+			})
+			if len(items) > a.cacheSize {
+				items = items[:a.cacheSize]
+			}
+			if ranks[name] == nil {
+				ranks[name] = make(map[string][]*Tuple2)
+			}
+			ranks[name][groupKey] = items
+		}
+	}
+	return ranks
+}
+
+func (a *aggregatedTopN) Dirty() bool {
+	for _, group := range a.groups {
+		if group.dirty {
+			return true
+		}
+	}
+	return false
+}
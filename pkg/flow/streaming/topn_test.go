@@ -131,3 +131,60 @@ func TestFlow_TopN_Aggregator(t *testing.T) {
 		})
 	}
 }
+
+type maxReducer struct{ v int64 }
+
+func (r *maxReducer) In(v int64) { r.v = max(r.v, v) }
+
+func (r *maxReducer) Val() int64 { return r.v }
+
+type meanReducer struct{ sum, count int64 }
+
+func (r *meanReducer) In(v int64) { r.sum += v; r.count++ }
+
+func (r *meanReducer) Val() int64 { return r.sum / r.count }
+
+func TestFlow_TopN_Aggregations(t *testing.T) {
+	record := func(entity string, v int) flow.StreamRecord {
+		return flow.NewStreamRecordWithoutTS(flow.Data{"svc", v, entity})
+	}
+	input := []flow.StreamRecord{record("a", 10), record("b", 25), record("a", 30), record("b", 25)}
+	topN := &aggregatedTopN{
+		topNAggregatorGroup: &topNAggregatorGroup{
+			cacheSize: 2,
+			sort:      DESC,
+			comparator: func(a, b interface{}) int {
+				return utils.Int64Comparator(b, a)
+			},
+			sortKeyExtractor: func(record flow.StreamRecord) int64 {
+				return int64(record.Data().(flow.Data)[1].(int))
+			},
+			groupKeyExtractor: func(record flow.StreamRecord) string {
+				return record.Data().(flow.Data)[0].(string)
+			},
+			l: logger.GetLogger("test"),
+		},
+		groups: make(map[string]*aggregatedGroup),
+	}
+	WithAggregations(func(record flow.StreamRecord) string {
+		return record.Data().(flow.Data)[2].(string)
+	}, []string{"MAX", "MEAN"}, func() []Reducer {
+		return []Reducer{&maxReducer{}, &meanReducer{}}
+	})(topN.topNAggregatorGroup)
+	topN.Add(input)
+	require.True(t, topN.Dirty())
+	expected := map[string]map[string][]*Tuple2{
+		"MAX": {"svc": {
+			{int64(30), record("a", 30)},
+			{int64(25), record("b", 25)},
+		}},
+		"MEAN": {"svc": {
+			{int64(25), record("b", 25)},
+			{int64(20), record("a", 30)},
+		}},
+	}
+	if diff := cmp.Diff(expected, topN.Snapshot()); diff != "" {
+		t.Errorf("Snapshot() mismatch (-want +got):\n%s", diff)
+	}
+	require.False(t, topN.Dirty())
+}
@@ -0,0 +1,49 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpchelper
+
+import (
+	"context"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// AuthorizationKey is the metadata key of the credential presented by the caller.
+const AuthorizationKey = "authorization"
+
+// Authorization returns the credential presented by the caller of the incoming call carried by ctx.
+func Authorization(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(AuthorizationKey); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// ForwardAuthorization returns a copy of parent passing the credential of the incoming call
+// carried by from on to the outgoing call.
+func ForwardAuthorization(parent, from context.Context) context.Context {
+	credential := Authorization(from)
+	if credential == "" {
+		return parent
+	}
+	return metadata.AppendToOutgoingContext(parent, AuthorizationKey, credential)
+}
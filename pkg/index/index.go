@@ -218,9 +218,14 @@ type FieldIterable interface {
 type Searcher interface {
 	FieldIterable
 	Match(fieldKey FieldKey, match []string) (list posting.List, err error)
+	Regexp(fieldKey FieldKey, pattern string, allowFullScan bool) (list posting.List, err error)
 	MatchField(fieldKey FieldKey) (list posting.List, err error)
 	MatchTerms(field Field) (list posting.List, err error)
 	Range(fieldKey FieldKey, opts RangeOpts) (list posting.List, err error)
+	// VisitTerms calls fn with every distinct term of the field, without reading the documents.
+	// The terms aren't partitioned by series, so the series of the field key is ignored.
+	// A term is only valid during the call.
+	VisitTerms(fieldKey FieldKey, fn func(term []byte)) error
 }
 
 // Store is an abstract of a index repository.
@@ -245,7 +250,12 @@ func (s Series) String() string {
 type SeriesStore interface {
 	Store
 	// Search returns a list of series that match the given matchers.
-	Search(context.Context, []SeriesMatcher) ([]Series, error)
+	// It stops once the list reaches the limit, zero or a negative limit returns all of them.
+	Search(ctx context.Context, seriesMatchers []SeriesMatcher, limit int) ([]Series, error)
+	// SearchByID returns the series of the IDs, leaving out the IDs absent from the store.
+	SearchByID(ctx context.Context, ids []common.SeriesID) ([]Series, error)
+	// Persist blocks until the written series are persisted on disk.
+	Persist(context.Context) error
 }
 
 // SeriesMatcherType represents the type of series matcher.
@@ -247,23 +247,28 @@ func (s *store) MatchTerms(field index.Field) (list posting.List, err error) {
 	return list, err
 }
 
+// Match runs a full-text search on an analyzed field.
+// The matches of a field without an analyzer are wildcard patterns of its terms.
 func (s *store) Match(fieldKey index.FieldKey, matches []string) (posting.List, error) {
-	if len(matches) == 0 || fieldKey.Analyzer == databasev1.IndexRule_ANALYZER_UNSPECIFIED {
+	if len(matches) == 0 {
 		return roaring.DummyPostingList, nil
 	}
 	reader, err := s.writer.Reader()
 	if err != nil {
 		return nil, err
 	}
-	analyzer := analyzers[fieldKey.Analyzer]
 	fk := fieldKey.MarshalIndexRule()
 	query := bluge.NewBooleanQuery()
 	if fieldKey.HasSeriesID() {
 		query.AddMust(bluge.NewTermQuery(string(fieldKey.SeriesID.Marshal())).SetField(seriesIDField))
 	}
 	for _, m := range matches {
+		if fieldKey.Analyzer == databasev1.IndexRule_ANALYZER_UNSPECIFIED {
+			query.AddMust(wildcardQuery(fk, m))
+			continue
+		}
 		query.AddMust(bluge.NewMatchQuery(m).SetField(fk).
-			SetAnalyzer(analyzer))
+			SetAnalyzer(analyzers[fieldKey.Analyzer]))
 	}
 	documentMatchIterator, err := reader.Search(context.Background(), bluge.NewAllMatches(query))
 	if err != nil {
@@ -281,6 +286,60 @@ func (s *store) Match(fieldKey index.FieldKey, matches []string) (posting.List,
 	return list, err
 }
 
+// Regexp returns the documents having a term of the field matched by the whole pattern.
+func (s *store) Regexp(fieldKey index.FieldKey, pattern string, allowFullScan bool) (list posting.List, err error) {
+	if err = index.ValidateRegexp(pattern, allowFullScan); err != nil {
+		return nil, err
+	}
+	reader, err := s.writer.Reader()
+	if err != nil {
+		return nil, err
+	}
+	query := bluge.NewBooleanQuery().
+		AddMust(bluge.NewRegexpQuery(pattern).SetField(fieldKey.MarshalIndexRule()))
+	if fieldKey.HasSeriesID() {
+		query = query.AddMust(bluge.NewTermQuery(string(fieldKey.SeriesID.Marshal())).
+			SetField(seriesIDField))
+	}
+	documentMatchIterator, err := reader.Search(context.Background(), bluge.NewAllMatches(query))
+	if err != nil {
+		return nil, err
+	}
+	iter := newBlugeMatchIterator(documentMatchIterator, reader)
+	defer func() {
+		err = multierr.Append(err, iter.Close())
+	}()
+	list = roaring.NewPostingList()
+	for iter.Next() {
+		docID, _ := iter.Val()
+		list.Insert(docID)
+	}
+	return list, err
+}
+
+func (s *store) VisitTerms(fieldKey index.FieldKey, fn func(term []byte)) (err error) {
+	reader, err := s.writer.Reader()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = multierr.Append(err, reader.Close())
+	}()
+	dict, err := reader.DictionaryIterator(fieldKey.MarshalIndexRule(), nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = multierr.Append(err, dict.Close())
+	}()
+	entry, err := dict.Next()
+	for err == nil && entry != nil {
+		fn(convert.StringToBytes(entry.Term()))
+		entry, err = dict.Next()
+	}
+	return err
+}
+
 func (s *store) Range(fieldKey index.FieldKey, opts index.RangeOpts) (list posting.List, err error) {
 	iter, err := s.Iterator(fieldKey, opts, modelv1.Sort_SORT_ASC, defaultRangePreloadSize)
 	if err != nil {
@@ -407,6 +466,30 @@ func (s *store) flush() {
 	<-onComplete
 }
 
+// Persist implements index.SeriesStore.
+// The writer persists batches in the background, and closing it drops the ones not persisted yet.
+func (s *store) Persist(ctx context.Context) error {
+	if !s.closer.AddRunning() {
+		return nil
+	}
+	defer s.closer.Done()
+	s.flush()
+	persisted := make(chan error, 1)
+	batch := bluge.NewBatch()
+	batch.SetPersistedCallback(func(err error) {
+		persisted <- err
+	})
+	if err := s.writer.Batch(batch); err != nil {
+		return err
+	}
+	select {
+	case err := <-persisted:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 type blugeMatchIterator struct {
 	delegated search.DocumentMatchIterator
 	err       error
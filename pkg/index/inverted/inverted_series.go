@@ -33,7 +33,7 @@ import (
 var emptySeries = make([]index.Series, 0)
 
 // Search implements index.SeriesStore.
-func (s *store) Search(ctx context.Context, seriesMatchers []index.SeriesMatcher) ([]index.Series, error) {
+func (s *store) Search(ctx context.Context, seriesMatchers []index.SeriesMatcher, limit int) ([]index.Series, error) {
 	if len(seriesMatchers) == 0 {
 		return emptySeries, nil
 	}
@@ -77,14 +77,40 @@ func (s *store) Search(ctx context.Context, seriesMatchers []index.SeriesMatcher
 	if err != nil {
 		return nil, err
 	}
-	return parseResult(dmi)
+	return parseResult(dmi, limit)
 }
 
-func parseResult(dmi search.DocumentMatchIterator) ([]index.Series, error) {
+// SearchByID implements index.SeriesStore.
+func (s *store) SearchByID(ctx context.Context, ids []common.SeriesID) ([]index.Series, error) {
+	if len(ids) == 0 {
+		return emptySeries, nil
+	}
+	reader, err := s.writer.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+	bq := bluge.NewBooleanQuery()
+	for i := range ids {
+		q := bluge.NewTermQuery(convert.BytesToString(convert.Uint64ToBytes(uint64(ids[i]))))
+		q.SetField(docIDField)
+		bq.AddShould(q)
+	}
+	bq.SetMinShould(1)
+	dmi, err := reader.Search(ctx, bluge.NewAllMatches(bq))
+	if err != nil {
+		return nil, err
+	}
+	return parseResult(dmi, 0)
+}
+
+func parseResult(dmi search.DocumentMatchIterator, limit int) ([]index.Series, error) {
 	result := make([]index.Series, 0, 10)
 	next, err := dmi.Next()
 	docIDMap := make(map[uint64]struct{})
-	for err == nil && next != nil {
+	for err == nil && next != nil && (limit <= 0 || len(result) < limit) {
 		var series index.Series
 		err = next.VisitStoredFields(func(field string, value []byte) bool {
 			if field == docIDField {
@@ -93,13 +93,45 @@ func TestStore_Search(t *testing.T) {
 			name += string(term) + "-"
 		}
 		t.Run(name, func(_ *testing.T) {
-			got, err := s.Search(context.Background(), matchers)
+			got, err := s.Search(context.Background(), matchers, 0)
 			tester.NoError(err)
 			tester.Equal(tt.want, got)
 		})
 	}
 }
 
+func TestStore_SearchByID(t *testing.T) {
+	tester := assert.New(t)
+	path, fn := setUp(require.New(t))
+	s, err := NewStore(StoreOpts{
+		Path:   path,
+		Logger: logger.GetLogger("test"),
+	})
+	tester.NoError(err)
+	defer func() {
+		tester.NoError(s.Close())
+		fn()
+	}()
+
+	setupData(tester, s)
+
+	got, err := s.SearchByID(context.Background(), []common.SeriesID{3, 1, 4})
+	tester.NoError(err)
+	tester.ElementsMatch([]index.Series{
+		{
+			ID:           common.SeriesID(1),
+			EntityValues: []byte("test1"),
+		},
+		{
+			ID:           common.SeriesID(3),
+			EntityValues: []byte("test3"),
+		},
+	}, got)
+	got, err = s.SearchByID(context.Background(), []common.SeriesID{4})
+	tester.NoError(err)
+	tester.Empty(got)
+}
+
 func TestStore_SearchWildcard(t *testing.T) {
 	tester := assert.New(t)
 	path, fn := setUp(require.New(t))
@@ -169,7 +201,7 @@ func TestStore_SearchWildcard(t *testing.T) {
 					Type:  index.SeriesMatcherTypeWildcard,
 					Match: tt.wildcard,
 				},
-			})
+			}, 0)
 			tester.NoError(err)
 			tester.ElementsMatch(tt.want, got)
 		})
@@ -227,13 +259,37 @@ func TestStore_SearchPrefix(t *testing.T) {
 					Type:  index.SeriesMatcherTypePrefix,
 					Match: tt.prefix,
 				},
-			})
+			}, 0)
 			tester.NoError(err)
 			tester.ElementsMatch(tt.want, got)
 		})
 	}
 }
 
+func TestStore_SearchLimit(t *testing.T) {
+	tester := assert.New(t)
+	path, fn := setUp(require.New(t))
+	s, err := NewStore(StoreOpts{
+		Path:   path,
+		Logger: logger.GetLogger("test"),
+	})
+	tester.NoError(err)
+	defer func() {
+		tester.NoError(s.Close())
+		fn()
+	}()
+
+	setupData(tester, s)
+
+	matchers := []index.SeriesMatcher{{Type: index.SeriesMatcherTypePrefix, Match: []byte("test")}}
+	got, err := s.Search(context.Background(), matchers, 2)
+	tester.NoError(err)
+	tester.Len(got, 2)
+	got, err = s.Search(context.Background(), matchers, 4)
+	tester.NoError(err)
+	tester.Len(got, 3)
+}
+
 func setupData(tester *assert.Assertions, s index.SeriesStore) {
 	series1 := index.Document{
 		DocID:        1,
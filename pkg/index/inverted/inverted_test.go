@@ -184,6 +184,185 @@ func TestStore_SeriesMatch(t *testing.T) {
 	}
 }
 
+func TestStore_WildcardMatch(t *testing.T) {
+	tester := assert.New(t)
+	path, fn := setUp(require.New(t))
+	s, err := NewStore(StoreOpts{
+		Path:   path,
+		Logger: logger.GetLogger("test"),
+	})
+	tester.NoError(err)
+	defer func() {
+		tester.NoError(s.Close())
+		fn()
+	}()
+	endpointID := index.FieldKey{
+		// endpoint_id, a keyword without any analyzer
+		IndexRuleID: 7,
+		SeriesID:    common.SeriesID(11),
+	}
+	for i, term := range []string{"/home_id", "/home/list", "/homepage", "/about", "/user/home", "/home*/star", "/home\\slash"} {
+		tester.NoError(s.Write([]index.Field{{
+			Key:  endpointID,
+			Term: []byte(term),
+		}}, uint64(i+1)))
+	}
+	s.(*store).flush()
+
+	tests := []struct {
+		want    posting.List
+		matches []string
+	}{
+		{
+			matches: []string{"/home*"},
+			want:    roaring.NewPostingListWithInitialData(1, 2, 3, 6, 7),
+		},
+		{
+			matches: []string{"/home_id"},
+			want:    roaring.NewPostingListWithInitialData(1),
+		},
+		{
+			matches: []string{"/home"},
+			want:    roaring.NewPostingListWithInitialData(),
+		},
+		{
+			matches: []string{"*home*"},
+			want:    roaring.NewPostingListWithInitialData(1, 2, 3, 5, 6, 7),
+		},
+		{
+			matches: []string{"/home*", "*list"},
+			want:    roaring.NewPostingListWithInitialData(2),
+		},
+		{
+			matches: []string{"/home\\*"},
+			want:    roaring.NewPostingListWithInitialData(),
+		},
+		{
+			matches: []string{"/home\\**"},
+			want:    roaring.NewPostingListWithInitialData(6),
+		},
+		{
+			matches: []string{"/home\\\\*"},
+			want:    roaring.NewPostingListWithInitialData(7),
+		},
+		{
+			matches: []string{"/home.*"},
+			want:    roaring.NewPostingListWithInitialData(),
+		},
+	}
+	for _, tt := range tests {
+		name := strings.Join(tt.matches, " and ")
+		t.Run(name, func(t *testing.T) {
+			list, err := s.Match(endpointID, tt.matches)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, list)
+		})
+	}
+}
+
+func TestStore_Regexp(t *testing.T) {
+	tester := assert.New(t)
+	path, fn := setUp(require.New(t))
+	s, err := NewStore(StoreOpts{
+		Path:   path,
+		Logger: logger.GetLogger("test"),
+	})
+	tester.NoError(err)
+	defer func() {
+		tester.NoError(s.Close())
+		fn()
+	}()
+	traceURL := index.FieldKey{
+		IndexRuleID: 8,
+		SeriesID:    common.SeriesID(11),
+	}
+	for i, term := range []string{"/api/v1/users/42", "/api/v1/users/list", "/api/v2/orders/7", "/api/v1/orders/1001", "/health", "/static/api/v1/users/1"} {
+		tester.NoError(s.Write([]index.Field{{
+			Key:  traceURL,
+			Term: []byte(term),
+		}}, uint64(i+1)))
+	}
+	s.(*store).flush()
+
+	tests := []struct {
+		want          posting.List
+		pattern       string
+		allowFullScan bool
+	}{
+		{
+			pattern: "/api/v[0-9]+/(users|orders)/[0-9]+",
+			want:    roaring.NewPostingListWithInitialData(1, 3, 4),
+		},
+		{
+			pattern: "/api/v1/.*",
+			want:    roaring.NewPostingListWithInitialData(1, 2, 4),
+		},
+		{
+			pattern: "/api/v1",
+			want:    roaring.NewPostingListWithInitialData(),
+		},
+		{
+			pattern:       ".*/users/[0-9]+",
+			allowFullScan: true,
+			want:          roaring.NewPostingListWithInitialData(1, 6),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			list, err := s.Regexp(traceURL, tt.pattern, tt.allowFullScan)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, list)
+		})
+	}
+}
+
+func TestStore_RegexpRejected(t *testing.T) {
+	tester := assert.New(t)
+	path, fn := setUp(require.New(t))
+	s, err := NewStore(StoreOpts{
+		Path:   path,
+		Logger: logger.GetLogger("test"),
+	})
+	tester.NoError(err)
+	defer func() {
+		tester.NoError(s.Close())
+		fn()
+	}()
+	traceURL := index.FieldKey{
+		IndexRuleID: 8,
+		SeriesID:    common.SeriesID(11),
+	}
+	tests := []struct {
+		wantErr error
+		pattern string
+	}{
+		{
+			pattern: ".*/users/[0-9]+",
+			wantErr: index.ErrRegexpFullScan,
+		},
+		{
+			pattern: "(a|b)*c",
+			wantErr: index.ErrRegexpFullScan,
+		},
+		{
+			pattern: "/api/a{1000}b{1000}c{1000}d{1000}e{1000}",
+			wantErr: index.ErrRegexpTooLarge,
+		},
+		{
+			pattern: "/api/" + strings.Repeat("a", 1024),
+			wantErr: index.ErrRegexpTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.wantErr.Error(), func(t *testing.T) {
+			_, err := s.Regexp(traceURL, tt.pattern, false)
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+	_, err = s.Regexp(traceURL, "/api/(", false)
+	tester.Error(err)
+}
+
 func setup(tester *assert.Assertions, s index.Store, serviceName index.FieldKey) {
 	tester.NoError(s.Write([]index.Field{{
 		Key:  serviceName,
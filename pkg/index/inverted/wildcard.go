@@ -0,0 +1,59 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package inverted
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/blugelabs/bluge"
+)
+
+// wildcardQuery matches the terms of the field by the pattern, in which '*' matches any sequence of characters.
+// A backslash escapes the next character, so that "\*" matches a literal '*'.
+// A pattern only ending with '*' scans the terms sharing its prefix.
+func wildcardQuery(field, pattern string) bluge.Query {
+	var segments []string
+	var literal strings.Builder
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			literal.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '*':
+			segments = append(segments, literal.String())
+			literal.Reset()
+		default:
+			literal.WriteRune(r)
+		}
+	}
+	segments = append(segments, literal.String())
+	switch {
+	case len(segments) == 1:
+		return bluge.NewTermQuery(segments[0]).SetField(field)
+	case len(segments) == 2 && segments[1] == "":
+		return bluge.NewPrefixQuery(segments[0]).SetField(field)
+	}
+	for i := range segments {
+		segments[i] = regexp.QuoteMeta(segments[i])
+	}
+	return bluge.NewRegexpQuery(strings.Join(segments, ".*")).SetField(field)
+}
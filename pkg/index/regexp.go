@@ -0,0 +1,77 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package index
+
+import (
+	"regexp/syntax"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	maxRegexpLength       = 1024
+	maxRegexpInstructions = 4096
+)
+
+var (
+	// ErrRegexpTooLarge is returned when a regular expression exceeds the size the index compiles.
+	ErrRegexpTooLarge = errors.New("the regular expression is too large")
+	// ErrRegexpFullScan is returned when a regular expression without a literal prefix isn't allowed to scan all the terms.
+	ErrRegexpFullScan = errors.New("the regular expression has no literal prefix to narrow the scanned terms")
+)
+
+// ValidateRegexp checks a regular expression before the index compiles it to an automaton over its terms.
+// The expression is matched by RE2 semantics without backtracking, so its cost is bounded by capping
+// the length of the pattern and the size of the compiled program.
+// A pattern without a literal prefix, such as ".*foo", scans every term of the field,
+// which is rejected unless allowFullScan is set.
+func ValidateRegexp(pattern string, allowFullScan bool) error {
+	if len(pattern) > maxRegexpLength {
+		return errors.WithMessagef(ErrRegexpTooLarge, "the length %d exceeds %d", len(pattern), maxRegexpLength)
+	}
+	parsed, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return errors.Wrapf(err, "parse the regular expression %q", pattern)
+	}
+	prog, err := syntax.Compile(parsed.Simplify())
+	if err != nil {
+		return errors.Wrapf(err, "compile the regular expression %q", pattern)
+	}
+	if len(prog.Inst) > maxRegexpInstructions {
+		return errors.WithMessagef(ErrRegexpTooLarge, "%q compiles to %d instructions, more than %d", pattern, len(prog.Inst), maxRegexpInstructions)
+	}
+	if !allowFullScan && regexpLiteralPrefix(parsed) == "" {
+		return errors.WithMessagef(ErrRegexpFullScan, "%q", pattern)
+	}
+	return nil
+}
+
+// regexpLiteralPrefix returns the prefix every term matching the expression starts with,
+// the index only scans the terms sharing it.
+func regexpLiteralPrefix(re *syntax.Regexp) string {
+	for re.Op == syntax.OpConcat {
+		if len(re.Sub) == 0 {
+			return ""
+		}
+		re = re.Sub[0]
+	}
+	if re.Op == syntax.OpLiteral && re.Flags&syntax.FoldCase == 0 {
+		return string(re.Rune)
+	}
+	return ""
+}
@@ -0,0 +1,198 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package sort
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	orderAsc  = "asc"
+	orderDesc = "desc"
+)
+
+// MultiComparable is an item sorted by several keys. The first key is its sorted field.
+type MultiComparable interface {
+	Comparable
+	SortKeys() [][]byte
+}
+
+// OrderSpec is the canonical comparator of MultiComparable items.
+// Every key is compared in its own direction, and a key only orders the items whose previous keys are equal.
+// The data nodes and the coordinator of a distributed query sort by the same spec,
+// so that they agree on the order of the items sharing a sorted field.
+type OrderSpec struct {
+	desc []bool
+}
+
+// NewOrderSpec returns a spec with a direction per key, true means descending.
+func NewOrderSpec(desc ...bool) OrderSpec {
+	return OrderSpec{desc: desc}
+}
+
+// TieBrokenOrder sorts by the sorted field in the direction, then by the tie-break key ascending.
+func TieBrokenOrder(desc bool) OrderSpec {
+	return NewOrderSpec(desc, false)
+}
+
+// ParseOrderSpec parses a spec serialized by String.
+func ParseOrderSpec(s string) (OrderSpec, error) {
+	if s == "" {
+		return OrderSpec{}, nil
+	}
+	parts := strings.Split(s, ",")
+	desc := make([]bool, len(parts))
+	for i, p := range parts {
+		switch p {
+		case orderAsc:
+		case orderDesc:
+			desc[i] = true
+		default:
+			return OrderSpec{}, fmt.Errorf("invalid order %q in %q", p, s)
+		}
+	}
+	return OrderSpec{desc: desc}, nil
+}
+
+// String serializes the spec as the comma-separated directions of its keys, such as "desc,asc".
+func (o OrderSpec) String() string {
+	parts := make([]string, len(o.desc))
+	for i, desc := range o.desc {
+		if desc {
+			parts[i] = orderDesc
+		} else {
+			parts[i] = orderAsc
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// Compare compares the keys of two items. The keys missing from an item sort first.
+func (o OrderSpec) Compare(a, b [][]byte) int {
+	for i, desc := range o.desc {
+		var c int
+		switch {
+		case i >= len(a) && i >= len(b):
+			return 0
+		case i >= len(a):
+			c = -1
+		case i >= len(b):
+			c = 1
+		default:
+			c = bytes.Compare(a[i], b[i])
+		}
+		if desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// NewOrderedItemIter merges the iterators by the spec.
+// An iterator only has to be sorted by the first key of the spec,
+// the items sharing the first key are sorted by the whole spec before they are merged.
+func NewOrderedItemIter[T MultiComparable](iters []Iterator[T], spec OrderSpec) Iterator[T] {
+	sorted := make([]Iterator[T], len(iters))
+	for i := range iters {
+		sorted[i] = &tieSorter[T]{iter: iters[i], spec: spec}
+	}
+	var def T
+	it := &itemIter[T]{
+		iters: sorted,
+		h: &containerHeap[T]{items: make([]*container[T], 0), less: func(a, b T) bool {
+			return spec.Compare(a.SortKeys(), b.SortKeys()) < 0
+		}},
+		curr: def,
+	}
+	it.initialize()
+	return it
+}
+
+// SortTies sorts the runs of items sharing the sorted field by the spec.
+// The items have to be sorted by the first key of the spec.
+func SortTies[T MultiComparable](items []T, spec OrderSpec) {
+	for start := 0; start < len(items); {
+		end := start + 1
+		for end < len(items) && bytes.Equal(items[end].SortedField(), items[start].SortedField()) {
+			end++
+		}
+		if end-start > 1 {
+			slices.SortStableFunc(items[start:end], func(a, b T) int {
+				return spec.Compare(a.SortKeys(), b.SortKeys())
+			})
+		}
+		start = end
+	}
+}
+
+// tieSorter buffers the run of items sharing the sorted field and returns them sorted by the spec.
+type tieSorter[T MultiComparable] struct {
+	iter    Iterator[T]
+	next    T
+	spec    OrderSpec
+	run     []T
+	idx     int
+	started bool
+	hasNext bool
+}
+
+func (ts *tieSorter[T]) Next() bool {
+	if ts.idx+1 < len(ts.run) {
+		ts.idx++
+		return true
+	}
+	if !ts.started {
+		ts.started = true
+		if ts.hasNext = ts.iter.Next(); ts.hasNext {
+			ts.next = ts.iter.Val()
+		}
+	}
+	if !ts.hasNext {
+		ts.run = ts.run[:0]
+		return false
+	}
+	ts.run = append(ts.run[:0], ts.next)
+	for {
+		if ts.hasNext = ts.iter.Next(); !ts.hasNext {
+			break
+		}
+		v := ts.iter.Val()
+		if !bytes.Equal(v.SortedField(), ts.run[0].SortedField()) {
+			ts.next = v
+			break
+		}
+		ts.run = append(ts.run, v)
+	}
+	SortTies(ts.run, ts.spec)
+	ts.idx = 0
+	return true
+}
+
+func (ts *tieSorter[T]) Val() T {
+	return ts.run[ts.idx]
+}
+
+func (ts *tieSorter[T]) Close() error {
+	return ts.iter.Close()
+}
@@ -0,0 +1,139 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package sort_test
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/apache/skywalking-banyandb/pkg/iter/sort"
+)
+
+// Ranked is an item ranked by its score, the ties of which are broken by its ID.
+type Ranked struct {
+	ID    string
+	Score uint64
+}
+
+func (r Ranked) SortedField() []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, r.Score)
+	return b
+}
+
+func (r Ranked) SortKeys() [][]byte {
+	return [][]byte{r.SortedField(), []byte(r.ID)}
+}
+
+type sliceIterator[T any] struct {
+	items []T
+	index int
+}
+
+func newSliceIterator[T any](items []T) *sliceIterator[T] {
+	return &sliceIterator[T]{items: items, index: -1}
+}
+
+func (si *sliceIterator[T]) Next() bool {
+	si.index++
+	return si.index < len(si.items)
+}
+
+func (si *sliceIterator[T]) Val() T {
+	return si.items[si.index]
+}
+
+func (si *sliceIterator[T]) Close() error {
+	return nil
+}
+
+func topN(items []Ranked, spec sort.OrderSpec, n int) []Ranked {
+	sorted := slices.Clone(items)
+	slices.SortStableFunc(sorted, func(a, b Ranked) int {
+		return spec.Compare(a.SortKeys(), b.SortKeys())
+	})
+	return sorted[:min(n, len(sorted))]
+}
+
+func TestOrderedItemIterMatchesGlobalSort(t *testing.T) {
+	const limit = 7
+	var all []Ranked
+	for i := 0; i < 20; i++ {
+		all = append(all, Ranked{ID: fmt.Sprintf("e%02d", (i*7)%20), Score: uint64(i % 4)})
+	}
+	for _, desc := range []bool{false, true} {
+		spec := sort.TieBrokenOrder(desc)
+		var nodes [2][]Ranked
+		for i, r := range all {
+			nodes[i%2] = append(nodes[i%2], r)
+		}
+		var iters []sort.Iterator[Ranked]
+		for _, node := range nodes {
+			// Every node returns its own top-N by the shared spec.
+			iters = append(iters, newSliceIterator(topN(node, spec, limit)))
+		}
+		iter := sort.NewOrderedItemIter(iters, spec)
+		var merged []Ranked
+		for len(merged) < limit && iter.Next() {
+			merged = append(merged, iter.Val())
+		}
+		if err := iter.Close(); err != nil {
+			t.Fatalf("expected Close() to return nil, got error: %v", err)
+		}
+		if want := topN(all, spec, limit); !reflect.DeepEqual(want, merged) {
+			t.Errorf("%s: expected the merged result %v to match the global sort %v", spec, merged, want)
+		}
+	}
+}
+
+func TestOrderedItemIterSortsTies(t *testing.T) {
+	// The iterators are only sorted by the score.
+	iters := []sort.Iterator[Ranked]{
+		newSliceIterator([]Ranked{{ID: "c", Score: 1}, {ID: "a", Score: 1}, {ID: "e", Score: 2}}),
+		newSliceIterator([]Ranked{{ID: "d", Score: 1}, {ID: "b", Score: 2}}),
+	}
+	iter := sort.NewOrderedItemIter(iters, sort.TieBrokenOrder(false))
+	var ids []string
+	for iter.Next() {
+		ids = append(ids, iter.Val().ID)
+	}
+	if want := []string{"a", "c", "d", "b", "e"}; !reflect.DeepEqual(want, ids) {
+		t.Errorf("expected %v, got %v", want, ids)
+	}
+}
+
+func TestOrderSpec(t *testing.T) {
+	spec := sort.NewOrderSpec(true, false)
+	parsed, err := sort.ParseOrderSpec(spec.String())
+	if err != nil {
+		t.Fatalf("expected the spec %q to be parsed, got error: %v", spec, err)
+	}
+	if spec.String() != "desc,asc" || !reflect.DeepEqual(spec, parsed) {
+		t.Errorf("expected the spec to round trip, got %q", parsed)
+	}
+	a, b := Ranked{ID: "a", Score: 1}, Ranked{ID: "b", Score: 1}
+	if c := parsed.Compare(a.SortKeys(), b.SortKeys()); c >= 0 {
+		t.Errorf("expected the ascending tie-break to order %v first, got %d", a, c)
+	}
+	if _, err = sort.ParseOrderSpec("desc,up"); err == nil {
+		t.Errorf("expected an invalid direction to be rejected")
+	}
+}
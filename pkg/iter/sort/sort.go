@@ -43,8 +43,8 @@ type container[T Comparable] struct {
 }
 
 type containerHeap[T Comparable] struct {
+	less  func(a, b T) bool
 	items []*container[T]
-	desc  bool
 }
 
 func (h containerHeap[T]) Len() int {
@@ -52,10 +52,7 @@ func (h containerHeap[T]) Len() int {
 }
 
 func (h containerHeap[T]) Less(i, j int) bool {
-	if h.desc {
-		return bytes.Compare(h.items[i].item.SortedField(), h.items[j].item.SortedField()) > 0
-	}
-	return bytes.Compare(h.items[i].item.SortedField(), h.items[j].item.SortedField()) < 0
+	return h.less(h.items[i].item, h.items[j].item)
 }
 
 func (h containerHeap[T]) Swap(i, j int) {
@@ -84,9 +81,17 @@ type itemIter[T Comparable] struct {
 // NewItemIter returns a new iterator that merges multiple sorted iterators.
 func NewItemIter[T Comparable](iters []Iterator[T], desc bool) Iterator[T] {
 	var def T
+	less := func(a, b T) bool {
+		return bytes.Compare(a.SortedField(), b.SortedField()) < 0
+	}
+	if desc {
+		less = func(a, b T) bool {
+			return bytes.Compare(a.SortedField(), b.SortedField()) > 0
+		}
+	}
 	it := &itemIter[T]{
 		iters: iters,
-		h:     &containerHeap[T]{items: make([]*container[T], 0), desc: desc},
+		h:     &containerHeap[T]{items: make([]*container[T], 0), less: less},
 		curr:  def,
 	}
 	it.initialize()
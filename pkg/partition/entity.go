@@ -23,6 +23,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/apache/skywalking-banyandb/api/common"
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
 	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
 	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
 	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
@@ -75,11 +76,39 @@ func (e EntityLocator) Find(subject string, value []*modelv1.TagFamilyForWrite)
 
 // Locate a shard and find the entity from a tag family, prepend a subject to the entity.
 func (e EntityLocator) Locate(subject string, value []*modelv1.TagFamilyForWrite, shardNum uint32) (pbv1.Entity, pbv1.EntityValues, common.ShardID, error) {
+	return e.LocateWithRoutingKey(subject, value, shardNum, nil)
+}
+
+// LocateWithRoutingKey works like Locate, but a non-empty routing key instead of the entity picks the shard.
+// The entity still determines the series, so elements of different series sharing a routing key land on the same shard.
+func (e EntityLocator) LocateWithRoutingKey(subject string, value []*modelv1.TagFamilyForWrite, shardNum uint32,
+	routingKey []byte,
+) (pbv1.Entity, pbv1.EntityValues, common.ShardID, error) {
+	return e.LocateWithOpts(subject, value, shardNum, ShardingOpts{RoutingKey: routingKey})
+}
+
+// ShardingOpts decides the key a write's shard is picked by.
+type ShardingOpts struct {
+	// RoutingKey, if not empty, picks the shard regardless of the strategy.
+	RoutingKey []byte
+	// Salt is mixed with the entity by the salted strategy, such as the timestamp of a data point.
+	Salt     []byte
+	Strategy commonv1.ShardingStrategy
+}
+
+// LocateWithOpts works like Locate, but the options pick the shard.
+func (e EntityLocator) LocateWithOpts(subject string, value []*modelv1.TagFamilyForWrite, shardNum uint32,
+	opts ShardingOpts,
+) (pbv1.Entity, pbv1.EntityValues, common.ShardID, error) {
 	entity, tagValues, err := e.Find(subject, value)
 	if err != nil {
 		return nil, nil, 0, err
 	}
-	id, err := ShardID(entity.Marshal(), shardNum)
+	shardingKey := opts.RoutingKey
+	if len(shardingKey) == 0 {
+		shardingKey = ShardingKey(opts.Strategy, entity.Marshal(), opts.Salt)
+	}
+	id, err := ShardID(shardingKey, shardNum)
 	if err != nil {
 		return nil, nil, 0, err
 	}
@@ -0,0 +1,132 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package partition
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/pkg/convert"
+)
+
+func TestLocateWithRoutingKey(t *testing.T) {
+	const shardNum = 16
+	locator := NewEntityLocator([]*databasev1.TagFamilySpec{{
+		Name: "searchable",
+		Tags: []*databasev1.TagSpec{
+			{Name: "service_id", Type: databasev1.TagType_TAG_TYPE_STRING},
+			{Name: "trace_id", Type: databasev1.TagType_TAG_TYPE_STRING},
+		},
+	}}, &databasev1.Entity{TagNames: []string{"service_id"}}, 0)
+	span := func(service, trace string) []*modelv1.TagFamilyForWrite {
+		return []*modelv1.TagFamilyForWrite{{Tags: []*modelv1.TagValue{
+			{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: service}}},
+			{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: trace}}},
+		}}}
+	}
+
+	entities := make(map[string]struct{})
+	routedShards := make(map[common.ShardID]struct{})
+	entityShards := make(map[common.ShardID]struct{})
+	for i := 0; i < 32; i++ {
+		tagFamilies := span(fmt.Sprintf("service-%d", i), "trace-1")
+		routed, _, routedShard, err := locator.LocateWithRoutingKey("sw", tagFamilies, shardNum, []byte("trace-1"))
+		require.NoError(t, err)
+		entity, _, entityShard, err := locator.Locate("sw", tagFamilies, shardNum)
+		require.NoError(t, err)
+		assert.Equal(t, entity, routed, "the routing key should not change the series")
+		entities[string(routed.Marshal())] = struct{}{}
+		routedShards[routedShard] = struct{}{}
+		entityShards[entityShard] = struct{}{}
+
+		_, _, noKeyShard, err := locator.LocateWithRoutingKey("sw", tagFamilies, shardNum, nil)
+		require.NoError(t, err)
+		assert.Equal(t, entityShard, noKeyShard, "an empty routing key should fall back to the entity")
+	}
+	assert.Len(t, entities, 32)
+	assert.Len(t, routedShards, 1, "elements sharing a routing key should be co-located")
+	assert.Greater(t, len(entityShards), 1)
+}
+
+func TestLocateWithShardingStrategy(t *testing.T) {
+	const shardNum = 8
+	locator := NewEntityLocator([]*databasev1.TagFamilySpec{{
+		Name: "default",
+		Tags: []*databasev1.TagSpec{{Name: "service_id", Type: databasev1.TagType_TAG_TYPE_STRING}},
+	}}, &databasev1.Entity{TagNames: []string{"service_id"}}, 0)
+	dataPoint := func(service string) []*modelv1.TagFamilyForWrite {
+		return []*modelv1.TagFamilyForWrite{{Tags: []*modelv1.TagValue{
+			{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: service}}},
+		}}}
+	}
+	// One dominant service writes 90% of the data points, nine others share the rest.
+	var writes []string
+	for i := 0; i < 900; i++ {
+		writes = append(writes, "service-0")
+	}
+	for i := 1; i < 10; i++ {
+		for j := 0; j < 10; j++ {
+			writes = append(writes, fmt.Sprintf("service-%d", i))
+		}
+	}
+	// skew is the ratio of the busiest shard's writes to the average of the shards.
+	skew := func(strategy commonv1.ShardingStrategy) float64 {
+		loads := make([]int, shardNum)
+		for i, service := range writes {
+			opts := ShardingOpts{Strategy: strategy, Salt: convert.Int64ToBytes(int64(i))}
+			_, _, shardID, err := locator.LocateWithOpts("service_cpm", dataPoint(service), shardNum, opts)
+			require.NoError(t, err)
+			loads[shardID]++
+		}
+		busiest := 0
+		for _, l := range loads {
+			busiest = max(busiest, l)
+		}
+		return float64(busiest) / (float64(len(writes)) / shardNum)
+	}
+	entitySkew := skew(commonv1.ShardingStrategy_SHARDING_STRATEGY_UNSPECIFIED)
+	saltedSkew := skew(commonv1.ShardingStrategy_SHARDING_STRATEGY_SALTED_ENTITY)
+	assert.Equal(t, entitySkew, skew(commonv1.ShardingStrategy_SHARDING_STRATEGY_ENTITY))
+	assert.Greater(t, entitySkew, 7.0, "the dominant service lands on a single shard")
+	assert.Less(t, saltedSkew, 1.3, "the salt spreads the dominant service over the shards")
+
+	salted := ShardingOpts{Strategy: commonv1.ShardingStrategy_SHARDING_STRATEGY_SALTED_ENTITY, Salt: []byte("element-1")}
+	entity, _, shardID, err := locator.LocateWithOpts("service_cpm", dataPoint("service-0"), shardNum, salted)
+	require.NoError(t, err)
+	plain, _, _, err := locator.Locate("service_cpm", dataPoint("service-0"), shardNum)
+	require.NoError(t, err)
+	assert.Equal(t, plain, entity, "the salt should not change the series")
+	for i := 0; i < 3; i++ {
+		_, _, retried, errRetry := locator.LocateWithOpts("service_cpm", dataPoint("service-0"), shardNum, salted)
+		require.NoError(t, errRetry)
+		assert.Equal(t, shardID, retried, "a write retried with the same salt keeps its shard")
+	}
+	salted.RoutingKey = []byte("trace-1")
+	_, _, routed, err := locator.LocateWithOpts("service_cpm", dataPoint("service-0"), shardNum, salted)
+	require.NoError(t, err)
+	_, _, routedOnly, err := locator.LocateWithRoutingKey("service_cpm", dataPoint("service-1"), shardNum, []byte("trace-1"))
+	require.NoError(t, err)
+	assert.Equal(t, routedOnly, routed, "the routing key takes precedence over the strategy")
+}
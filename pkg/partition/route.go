@@ -20,6 +20,7 @@ package partition
 import (
 	"github.com/pkg/errors"
 
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
 	"github.com/apache/skywalking-banyandb/pkg/convert"
 )
 
@@ -31,3 +32,14 @@ func ShardID(key []byte, shardNum uint32) (uint, error) {
 	encodeKey := convert.Hash(key)
 	return uint(encodeKey % uint64(shardNum)), nil
 }
+
+// ShardingKey returns the key the strategy picks the shard of a write by.
+// The salted strategy appends the salt to the entity, while the others only take the entity.
+func ShardingKey(strategy commonv1.ShardingStrategy, entity, salt []byte) []byte {
+	if strategy != commonv1.ShardingStrategy_SHARDING_STRATEGY_SALTED_ENTITY || len(salt) == 0 {
+		return entity
+	}
+	key := make([]byte, 0, len(entity)+len(salt))
+	key = append(key, entity...)
+	return append(key, salt...)
+}
@@ -0,0 +1,139 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package v1
+
+import (
+	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/pkg/convert"
+)
+
+// StreamBatch is a batch of elements laid out in columns, one vector per projected tag,
+// for the scans processing many elements at a time.
+// Unlike a StreamResult, the elements of a batch may come from several series.
+type StreamBatch struct {
+	SIDs        []common.SeriesID
+	Timestamps  []int64
+	ElementIDs  []string
+	TagFamilies []ColumnFamily
+}
+
+// Len returns the number of elements in the batch.
+func (b *StreamBatch) Len() int {
+	return len(b.Timestamps)
+}
+
+// ColumnFamily is the columns of a projected tag family.
+type ColumnFamily struct {
+	Name    string
+	Columns []Column
+}
+
+// Column is the values of a tag, one per element of the batch.
+// The integers are held by Ints, and the other values by Bytes in their storage encoding.
+// The type of a column is decided by its first non-null value,
+// a value of another type is taken as null.
+type Column struct {
+	Name  string
+	Ints  []int64
+	Bytes BytesVector
+	Nulls []bool
+	Type  ValueType
+}
+
+// Len returns the number of values in the column.
+func (c *Column) Len() int {
+	return len(c.Nulls)
+}
+
+// Append appends a value in its storage encoding. A nil value is null.
+func (c *Column) Append(valueType ValueType, value []byte) {
+	if value != nil && c.Type == ValueTypeUnknown {
+		c.Type = valueType
+		// The nulls appended before the type is known are filled now.
+		for i := 0; i < len(c.Nulls); i++ {
+			c.appendZero()
+		}
+	}
+	if value == nil || valueType != c.Type {
+		c.Nulls = append(c.Nulls, true)
+		if c.Type != ValueTypeUnknown {
+			c.appendZero()
+		}
+		return
+	}
+	c.Nulls = append(c.Nulls, false)
+	if c.Type == ValueTypeInt64 {
+		c.Ints = append(c.Ints, convert.BytesToInt64(value))
+		return
+	}
+	c.Bytes.Append(value)
+}
+
+func (c *Column) appendZero() {
+	if c.Type == ValueTypeInt64 {
+		c.Ints = append(c.Ints, 0)
+		return
+	}
+	c.Bytes.Append(nil)
+}
+
+// BytesVector holds variable-length values back to back in one buffer.
+// The i-th value is Data[Offsets[i]:Offsets[i+1]].
+type BytesVector struct {
+	Offsets []int
+	Data    []byte
+}
+
+// Len returns the number of values in the vector.
+func (v *BytesVector) Len() int {
+	return max(len(v.Offsets)-1, 0)
+}
+
+// Get returns the i-th value, which shares the buffer of the vector.
+func (v *BytesVector) Get(i int) []byte {
+	return v.Data[v.Offsets[i]:v.Offsets[i+1]]
+}
+
+// Append appends a value.
+func (v *BytesVector) Append(value []byte) {
+	if len(v.Offsets) == 0 {
+		v.Offsets = append(v.Offsets, 0)
+	}
+	v.Data = append(v.Data, value...)
+	v.Offsets = append(v.Offsets, len(v.Data))
+}
+
+// StreamBatchResult reads the result of a stream query in batches.
+type StreamBatchResult interface {
+	// NextBatch returns up to size elements, or nil once the result is drained.
+	NextBatch(size int) *StreamBatch
+}
+
+// StreamCursor reads the result of a stream query in batches of a fixed size.
+type StreamCursor interface {
+	// Next returns the next batch, or nil once the result is drained.
+	Next() *StreamBatch
+}
+
+// StreamCursorBuilder opts a stream query result in to being read by a cursor, which loads the blocks of the result
+// only once the merge reaches them, bounding the memory of the queries over long time ranges.
+// The elements sharing a timestamp are ordered by their series, and the elements of a series by their timestamps.
+type StreamCursorBuilder interface {
+	// BuildCursor returns the cursor yielding up to batchSize elements a batch.
+	BuildCursor(batchSize int) StreamCursor
+}
@@ -0,0 +1,207 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package v1
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+)
+
+// ChecksumTrailerKey is the gRPC trailer carrying the checksum of a query result.
+const ChecksumTrailerKey = "banyandb-query-checksum"
+
+// ErrChecksumMismatch indicates the results don't match the checksum computed by the node producing them.
+var ErrChecksumMismatch = errors.New("query checksum mismatch")
+
+var checksumTable = crc32.MakeTable(crc32.Castagnoli)
+
+// The checksum is computed over a canonical encoding of the results instead of
+// their protobuf wire form, which isn't guaranteed to be stable across
+// implementations or versions. Every field is written in declaration order:
+// strings and bytes as a uvarint length followed by the raw bytes, integers and
+// float bits as 8-byte big-endian values, repeated fields as a uvarint count
+// followed by the items, and a tag or field value as a kind byte followed by its
+// payload. An unset timestamp is encoded as zero seconds and nanoseconds. The
+// encoding is documented on the checksum field of the query requests.
+
+// StreamQueryChecksum computes the CRC-32C checksum of the elements in their returned order.
+func StreamQueryChecksum(elements []*streamv1.Element) uint32 {
+	h := crc32.New(checksumTable)
+	for _, e := range elements {
+		writeChecksumString(h, e.GetElementId())
+		writeChecksumTimestamp(h, e.GetTimestamp())
+		writeChecksumTagFamilies(h, e.GetTagFamilies())
+	}
+	return h.Sum32()
+}
+
+// MeasureQueryChecksum computes the CRC-32C checksum of the data points in their returned order.
+func MeasureQueryChecksum(dataPoints []*measurev1.DataPoint) uint32 {
+	h := crc32.New(checksumTable)
+	for _, dp := range dataPoints {
+		writeChecksumTimestamp(h, dp.GetTimestamp())
+		writeChecksumTagFamilies(h, dp.GetTagFamilies())
+		writeChecksumLen(h, len(dp.GetFields()))
+		for _, f := range dp.GetFields() {
+			writeChecksumString(h, f.GetName())
+			writeChecksumFieldValue(h, f.GetValue())
+		}
+	}
+	return h.Sum32()
+}
+
+// VerifyStreamQueryChecksum checks the elements of resp against its checksum.
+func VerifyStreamQueryChecksum(resp *streamv1.QueryResponse) error {
+	return verifyChecksum(resp.GetChecksum(), StreamQueryChecksum(resp.GetElements()))
+}
+
+// VerifyMeasureQueryChecksum checks the data points of resp against its checksum.
+func VerifyMeasureQueryChecksum(resp *measurev1.QueryResponse) error {
+	return verifyChecksum(resp.GetChecksum(), MeasureQueryChecksum(resp.GetDataPoints()))
+}
+
+func verifyChecksum(expected, actual uint32) error {
+	if expected != actual {
+		return errors.WithMessage(ErrChecksumMismatch, fmt.Sprintf("expected %s, got %s", FormatChecksum(expected), FormatChecksum(actual)))
+	}
+	return nil
+}
+
+// FormatChecksum encodes a checksum as the value of ChecksumTrailerKey.
+func FormatChecksum(checksum uint32) string {
+	return strconv.FormatUint(uint64(checksum), 16)
+}
+
+// ParseChecksum decodes the value of ChecksumTrailerKey.
+func ParseChecksum(s string) (uint32, error) {
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(v), nil
+}
+
+const (
+	checksumKindNull byte = iota
+	checksumKindStr
+	checksumKindStrArray
+	checksumKindInt
+	checksumKindIntArray
+	checksumKindBinary
+	checksumKindFloat
+)
+
+func writeChecksumTagFamilies(h io.Writer, tagFamilies []*modelv1.TagFamily) {
+	writeChecksumLen(h, len(tagFamilies))
+	for _, tf := range tagFamilies {
+		writeChecksumString(h, tf.GetName())
+		writeChecksumLen(h, len(tf.GetTags()))
+		for _, t := range tf.GetTags() {
+			writeChecksumString(h, t.GetKey())
+			writeChecksumTagValue(h, t.GetValue())
+		}
+	}
+}
+
+func writeChecksumTagValue(h io.Writer, tv *modelv1.TagValue) {
+	switch v := tv.GetValue().(type) {
+	case *modelv1.TagValue_Str:
+		writeChecksumKind(h, checksumKindStr)
+		writeChecksumString(h, v.Str.GetValue())
+	case *modelv1.TagValue_StrArray:
+		writeChecksumKind(h, checksumKindStrArray)
+		writeChecksumLen(h, len(v.StrArray.GetValue()))
+		for _, s := range v.StrArray.GetValue() {
+			writeChecksumString(h, s)
+		}
+	case *modelv1.TagValue_Int:
+		writeChecksumKind(h, checksumKindInt)
+		writeChecksumUint64(h, uint64(v.Int.GetValue()))
+	case *modelv1.TagValue_IntArray:
+		writeChecksumKind(h, checksumKindIntArray)
+		writeChecksumLen(h, len(v.IntArray.GetValue()))
+		for _, i := range v.IntArray.GetValue() {
+			writeChecksumUint64(h, uint64(i))
+		}
+	case *modelv1.TagValue_BinaryData:
+		writeChecksumKind(h, checksumKindBinary)
+		writeChecksumBytes(h, v.BinaryData)
+	default:
+		writeChecksumKind(h, checksumKindNull)
+	}
+}
+
+func writeChecksumFieldValue(h io.Writer, fv *modelv1.FieldValue) {
+	switch v := fv.GetValue().(type) {
+	case *modelv1.FieldValue_Str:
+		writeChecksumKind(h, checksumKindStr)
+		writeChecksumString(h, v.Str.GetValue())
+	case *modelv1.FieldValue_Int:
+		writeChecksumKind(h, checksumKindInt)
+		writeChecksumUint64(h, uint64(v.Int.GetValue()))
+	case *modelv1.FieldValue_BinaryData:
+		writeChecksumKind(h, checksumKindBinary)
+		writeChecksumBytes(h, v.BinaryData)
+	case *modelv1.FieldValue_Float:
+		writeChecksumKind(h, checksumKindFloat)
+		writeChecksumUint64(h, math.Float64bits(v.Float.GetValue()))
+	default:
+		writeChecksumKind(h, checksumKindNull)
+	}
+}
+
+func writeChecksumTimestamp(h io.Writer, ts *timestamppb.Timestamp) {
+	writeChecksumUint64(h, uint64(ts.GetSeconds()))
+	writeChecksumUint64(h, uint64(ts.GetNanos()))
+}
+
+func writeChecksumKind(h io.Writer, kind byte) {
+	_, _ = h.Write([]byte{kind})
+}
+
+func writeChecksumLen(h io.Writer, n int) {
+	var b [binary.MaxVarintLen64]byte
+	_, _ = h.Write(b[:binary.PutUvarint(b[:], uint64(n))])
+}
+
+func writeChecksumString(h io.Writer, s string) {
+	writeChecksumLen(h, len(s))
+	_, _ = h.Write([]byte(s))
+}
+
+func writeChecksumBytes(h io.Writer, data []byte) {
+	writeChecksumLen(h, len(data))
+	_, _ = h.Write(data)
+}
+
+func writeChecksumUint64(h io.Writer, v uint64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	_, _ = h.Write(b[:])
+}
@@ -0,0 +1,151 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package v1
+
+import (
+	"hash/crc32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+)
+
+func checksumElements() []*streamv1.Element {
+	var elements []*streamv1.Element
+	for _, id := range []string{"1", "2", "3", "4"} {
+		elements = append(elements, &streamv1.Element{
+			ElementId: id,
+			TagFamilies: []*modelv1.TagFamily{{
+				Name: "default",
+				Tags: []*modelv1.Tag{{
+					Key:   "trace_id",
+					Value: &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "trace-" + id}}},
+				}},
+			}},
+		})
+	}
+	return elements
+}
+
+func TestStreamQueryChecksum(t *testing.T) {
+	elements := checksumElements()
+	checksum := StreamQueryChecksum(elements)
+	assert.Equal(t, checksum, StreamQueryChecksum(checksumElements()))
+
+	s := FormatChecksum(checksum)
+	parsed, err := ParseChecksum(s)
+	require.NoError(t, err)
+	assert.Equal(t, checksum, parsed)
+
+	t.Run("truncated mid-stream", func(t *testing.T) {
+		assert.NotEqual(t, checksum, StreamQueryChecksum(elements[:2]))
+	})
+	t.Run("corrupted value", func(t *testing.T) {
+		corrupted := checksumElements()
+		corrupted[2].TagFamilies[0].Tags[0].Value = &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "trace-x"}}}
+		assert.NotEqual(t, checksum, StreamQueryChecksum(corrupted))
+	})
+	t.Run("reordered", func(t *testing.T) {
+		reordered := checksumElements()
+		reordered[1], reordered[2] = reordered[2], reordered[1]
+		assert.NotEqual(t, checksum, StreamQueryChecksum(reordered))
+	})
+	t.Run("received over the wire", func(t *testing.T) {
+		var received []*streamv1.Element
+		for _, e := range elements {
+			data, err := proto.Marshal(e)
+			require.NoError(t, err)
+			r := &streamv1.Element{}
+			require.NoError(t, proto.Unmarshal(data, r))
+			received = append(received, r)
+		}
+		assert.Equal(t, checksum, StreamQueryChecksum(received))
+	})
+}
+
+func TestVerifyQueryChecksum(t *testing.T) {
+	resp := &streamv1.QueryResponse{Elements: checksumElements()}
+	resp.Checksum = StreamQueryChecksum(resp.Elements)
+	require.NoError(t, VerifyStreamQueryChecksum(resp))
+
+	resp.Elements[1].ElementId = "x"
+	assert.ErrorIs(t, VerifyStreamQueryChecksum(resp), ErrChecksumMismatch)
+
+	dataPoints := []*measurev1.DataPoint{
+		{Fields: []*measurev1.DataPoint_Field{{Name: "total", Value: &modelv1.FieldValue{Value: &modelv1.FieldValue_Int{Int: &modelv1.Int{Value: 10}}}}}},
+	}
+	measureResp := &measurev1.QueryResponse{DataPoints: dataPoints, Checksum: MeasureQueryChecksum(dataPoints)}
+	require.NoError(t, VerifyMeasureQueryChecksum(measureResp))
+	measureResp.DataPoints = nil
+	assert.ErrorIs(t, VerifyMeasureQueryChecksum(measureResp), ErrChecksumMismatch)
+}
+
+func TestMeasureQueryChecksum(t *testing.T) {
+	dataPoints := []*measurev1.DataPoint{
+		{Fields: []*measurev1.DataPoint_Field{{Name: "total", Value: &modelv1.FieldValue{Value: &modelv1.FieldValue_Int{Int: &modelv1.Int{Value: 10}}}}}},
+		{Fields: []*measurev1.DataPoint_Field{{Name: "total", Value: &modelv1.FieldValue{Value: &modelv1.FieldValue_Int{Int: &modelv1.Int{Value: 20}}}}}},
+	}
+	checksum := MeasureQueryChecksum(dataPoints)
+	assert.NotEqual(t, checksum, MeasureQueryChecksum(dataPoints[:1]))
+	assert.NotEqual(t, MeasureQueryChecksum(nil), MeasureQueryChecksum(dataPoints[:1]))
+}
+
+func TestQueryChecksumCanonicalEncoding(t *testing.T) {
+	// The encoding is a contract with the clients verifying the trailer, so a
+	// change to it must be intentional.
+	assert.Equal(t, uint32(0), StreamQueryChecksum(nil))
+	element := &streamv1.Element{
+		ElementId: "e1",
+		Timestamp: timestamppb.New(time.Unix(1, 2)),
+		TagFamilies: []*modelv1.TagFamily{{
+			Name: "default",
+			Tags: []*modelv1.Tag{{Key: "id", Value: &modelv1.TagValue{Value: &modelv1.TagValue_Int{Int: &modelv1.Int{Value: 3}}}}},
+		}},
+	}
+	expected := []byte{2, 'e', '1', 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 2}
+	expected = append(expected, 1, 7, 'd', 'e', 'f', 'a', 'u', 'l', 't', 1, 2, 'i', 'd', checksumKindInt, 0, 0, 0, 0, 0, 0, 0, 3)
+	assert.Equal(t, crc32.Checksum(expected, checksumTable), StreamQueryChecksum([]*streamv1.Element{element}))
+
+	t.Run("value kinds", func(t *testing.T) {
+		tagChecksum := func(v *modelv1.TagValue) uint32 {
+			return StreamQueryChecksum([]*streamv1.Element{{
+				TagFamilies: []*modelv1.TagFamily{{Name: "default", Tags: []*modelv1.Tag{{Key: "t", Value: v}}}},
+			}})
+		}
+		assert.NotEqual(t, tagChecksum(&modelv1.TagValue{Value: &modelv1.TagValue_Null{}}),
+			tagChecksum(&modelv1.TagValue{Value: &modelv1.TagValue_Int{Int: &modelv1.Int{}}}))
+		assert.NotEqual(t, tagChecksum(&modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "a"}}}),
+			tagChecksum(&modelv1.TagValue{Value: &modelv1.TagValue_BinaryData{BinaryData: []byte("a")}}))
+		assert.NotEqual(t, tagChecksum(&modelv1.TagValue{Value: &modelv1.TagValue_StrArray{StrArray: &modelv1.StrArray{Value: []string{"ab"}}}}),
+			tagChecksum(&modelv1.TagValue{Value: &modelv1.TagValue_StrArray{StrArray: &modelv1.StrArray{Value: []string{"a", "b"}}}}))
+	})
+	t.Run("field values", func(t *testing.T) {
+		fieldChecksum := func(v *modelv1.FieldValue) uint32 {
+			return MeasureQueryChecksum([]*measurev1.DataPoint{{Fields: []*measurev1.DataPoint_Field{{Name: "f", Value: v}}}})
+		}
+		assert.NotEqual(t, fieldChecksum(&modelv1.FieldValue{Value: &modelv1.FieldValue_Int{Int: &modelv1.Int{Value: 1}}}),
+			fieldChecksum(&modelv1.FieldValue{Value: &modelv1.FieldValue_Float{Float: &modelv1.Float{Value: 1}}}))
+	})
+}
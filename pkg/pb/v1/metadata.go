@@ -19,9 +19,16 @@
 package v1
 
 import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/apache/skywalking-banyandb/api/common"
 	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
 	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/pkg/convert"
 	"github.com/apache/skywalking-banyandb/pkg/index"
 	"github.com/apache/skywalking-banyandb/pkg/timestamp"
 )
@@ -103,14 +110,20 @@ type Field struct {
 
 // MeasureResult is the result of a query.
 type MeasureResult struct {
-	Timestamps  []int64
-	TagFamilies []TagFamily
-	Fields      []Field
-	SID         common.SeriesID
+	Timestamps []int64
+	// EndTimestamps holds the timestamp of the last point of every coalesced run.
+	// It's only set if the query coalesces with CoalesceOptions.EmitEnd.
+	EndTimestamps []int64
+	TagFamilies   []TagFamily
+	Fields        []Field
+	SID           common.SeriesID
 }
 
 // StreamResult is the result of a query.
 type StreamResult struct {
+	// Series is the series of the elements, carrying its decoded entity values.
+	// It's nil if the query doesn't resolve the series.
+	Series      *Series
 	Timestamps  []int64
 	ElementIDs  []string
 	TagFamilies []TagFamily
@@ -137,8 +150,118 @@ type StreamQueryOptions struct {
 	Entities       [][]*modelv1.TagValue
 	Filter         index.Filter
 	Order          *OrderBy
+	ElementIDRange *ElementIDRange
 	TagProjection  []TagProjection
+	// ThenBy breaks the ties of Order, which has to sort by an index.
+	ThenBy []*OrderBy
+	// ShardIDs pins the query to the shards. Empty means all the shards.
+	// A group whose number of shards has changed rejects it.
+	ShardIDs       []common.ShardID
 	MaxElementSize int
+	MaxStaleness   time.Duration
+	SampleInterval uint32
+	// FlushedOnly skips the in-memory parts, so that the query only reads the immutable parts on disk.
+	FlushedOnly bool
+	// ElementIDOrder sorts the elements of every pulled result by their IDs, see StreamResult.OrderByElementID.
+	// SORT_UNSPECIFIED keeps the storage order.
+	ElementIDOrder modelv1.Sort
+}
+
+// Sampled reports whether the element is kept by a query that samples one in every interval elements.
+// The choice only depends on the element ID, so repeated queries return the same sample.
+func Sampled(elementID string, interval uint32) bool {
+	return interval <= 1 || convert.HashStr(elementID)%uint64(interval) == 0
+}
+
+// ElementIDRange selects the elements of a series whose IDs fall in [From, To].
+// An empty endpoint leaves that side of the range open.
+// Endpoints don't have to match an existing element.
+type ElementIDRange struct {
+	From string
+	To   string
+}
+
+// Contains reports whether the element ID falls in the range.
+// The integer IDs are ordered numerically and precede the others, which are ordered lexically.
+func (r *ElementIDRange) Contains(id string) bool {
+	if r.From != "" && CompareElementID(id, r.From) < 0 {
+		return false
+	}
+	if r.To != "" && CompareElementID(id, r.To) > 0 {
+		return false
+	}
+	return true
+}
+
+// CompareElementID orders the integer IDs numerically before the others, which are ordered lexically,
+// so that the order stays transitive when the integer and the non-integer IDs are mixed.
+func CompareElementID(a, b string) int {
+	ai, errA := strconv.ParseInt(a, 10, 64)
+	bi, errB := strconv.ParseInt(b, 10, 64)
+	switch {
+	case errA == nil && errB == nil:
+		return cmp.Compare(ai, bi)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// ElementIDSortKey encodes the element ID as a sort key, whose bytes compare as CompareElementID does.
+func ElementIDSortKey(id string) []byte {
+	if i, err := strconv.ParseInt(id, 10, 64); err == nil {
+		return append([]byte{0}, convert.Uint64ToBytes(uint64(i)^(1<<63))...)
+	}
+	return append([]byte{1}, id...)
+}
+
+// OrderByElementID sorts the elements of the result by their IDs in the order of CompareElementID,
+// keeping the storage order of the elements sharing an ID. SORT_DESC sorts them descending.
+func (sr *StreamResult) OrderByElementID(sort modelv1.Sort) {
+	idx := make([]int, len(sr.ElementIDs))
+	for i := range idx {
+		idx[i] = i
+	}
+	slices.SortStableFunc(idx, func(a, b int) int {
+		c := CompareElementID(sr.ElementIDs[a], sr.ElementIDs[b])
+		if sort == modelv1.Sort_SORT_DESC {
+			return -c
+		}
+		return c
+	})
+	sr.Timestamps = permute(sr.Timestamps, idx)
+	sr.ElementIDs = permute(sr.ElementIDs, idx)
+	for i := range sr.TagFamilies {
+		for j := range sr.TagFamilies[i].Tags {
+			sr.TagFamilies[i].Tags[j].Values = permute(sr.TagFamilies[i].Tags[j].Values, idx)
+		}
+	}
+}
+
+func permute[T any](values []T, idx []int) []T {
+	if len(values) != len(idx) {
+		return values
+	}
+	result := make([]T, len(values))
+	for i, j := range idx {
+		result[i] = values[j]
+	}
+	return result
+}
+
+// SeriesTimeExtent is the timestamps of the first and the last element of a series within a time range.
+type SeriesTimeExtent struct {
+	Series       *Series
+	MinTimestamp int64
+	MaxTimestamp int64
+}
+
+// SeriesCount is the number of the elements of a series matching a query.
+type SeriesCount struct {
+	Series *Series
+	Count  int64
 }
 
 // StreamQueryResult is the result of a stream query.
@@ -148,8 +271,10 @@ type StreamQueryResult interface {
 }
 
 // StreamSortResult is the result of a stream sort.
+// Pull returns the next batch of the sorted elements, and nil once they run out.
 type StreamSortResult interface {
 	Pull() *StreamColumnResult
+	Release()
 }
 
 // OrderByType is the type of order by.
@@ -173,7 +298,18 @@ type MeasureQueryOptions struct {
 	Entities        [][]*modelv1.TagValue
 	TagProjection   []TagProjection
 	FieldProjection []string
-	OrderByType     OrderByType
+	// Coalesce merges the consecutive points of a series having equal values. Nil keeps all the points.
+	Coalesce    *CoalesceOptions
+	OrderByType OrderByType
+	// FlushedOnly skips the in-memory parts, so that the query only reads the immutable parts on disk.
+	FlushedOnly bool
+}
+
+// CoalesceOptions coalesces every run of consecutive equal-value points of a series into its first point.
+// The result is lossless for a step interpretation of the values.
+type CoalesceOptions struct {
+	// EmitEnd also returns the timestamp of the last point of every run.
+	EmitEnd bool
 }
 
 // MeasureQueryResult is the result of a measure query.
@@ -0,0 +1,80 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package v1
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+)
+
+func TestCompareElementID(t *testing.T) {
+	// Comparing "1a" with the integers lexically made a cycle: "9" < "10" < "1a" < "9".
+	ids := []string{"9a", "10", "abc", "1a", "9", "-1", "10a"}
+	slices.SortFunc(ids, CompareElementID)
+	assert.Equal(t, []string{"-1", "9", "10", "10a", "1a", "9a", "abc"}, ids)
+	for _, a := range ids {
+		for _, b := range ids {
+			for _, c := range ids {
+				if CompareElementID(a, b) < 0 && CompareElementID(b, c) < 0 {
+					assert.Negative(t, CompareElementID(a, c), "%s < %s < %s", a, b, c)
+				}
+			}
+		}
+	}
+
+	for _, a := range ids {
+		for _, b := range ids {
+			assert.Equal(t, CompareElementID(a, b), bytes.Compare(ElementIDSortKey(a), ElementIDSortKey(b)), "%s <=> %s", a, b)
+		}
+	}
+
+	r := &ElementIDRange{From: "9", To: "9a"}
+	assert.True(t, r.Contains("10"))
+	assert.True(t, r.Contains("10a"))
+	assert.False(t, r.Contains("8"))
+	assert.False(t, r.Contains("abc"))
+}
+
+func TestStreamResultOrderByElementID(t *testing.T) {
+	sr := &StreamResult{
+		Timestamps: []int64{1, 2, 3, 4},
+		ElementIDs: []string{"10", "a", "9", "10"},
+		TagFamilies: []TagFamily{{Name: "default", Tags: []Tag{{Name: "id", Values: []*modelv1.TagValue{
+			{Value: &modelv1.TagValue_Int{Int: &modelv1.Int{Value: 1}}},
+			{Value: &modelv1.TagValue_Int{Int: &modelv1.Int{Value: 2}}},
+			{Value: &modelv1.TagValue_Int{Int: &modelv1.Int{Value: 3}}},
+			{Value: &modelv1.TagValue_Int{Int: &modelv1.Int{Value: 4}}},
+		}}}}},
+	}
+	sr.OrderByElementID(modelv1.Sort_SORT_ASC)
+	assert.Equal(t, []string{"9", "10", "10", "a"}, sr.ElementIDs)
+	assert.Equal(t, []int64{3, 1, 4, 2}, sr.Timestamps, "the elements sharing an ID should keep their order")
+	values := sr.TagFamilies[0].Tags[0].Values
+	assert.Equal(t, []int64{3, 1, 4, 2}, []int64{
+		values[0].GetInt().GetValue(), values[1].GetInt().GetValue(), values[2].GetInt().GetValue(), values[3].GetInt().GetValue(),
+	})
+
+	sr.OrderByElementID(modelv1.Sort_SORT_DESC)
+	assert.Equal(t, []string{"a", "10", "10", "9"}, sr.ElementIDs)
+	assert.Equal(t, []int64{2, 1, 4, 3}, sr.Timestamps)
+}
@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 
 	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
@@ -174,6 +175,64 @@ func EncodeFamily(familySpec *databasev1.TagFamilySpec, family *modelv1.TagFamil
 	return proto.Marshal(data)
 }
 
+// ParseTagFamilyTags decodes only the named tags from a tag family encoded by EncodeFamily.
+// The tags are returned in the requested order. Tags that are absent from the element are omitted.
+func ParseTagFamilyTags(familySpec *databasev1.TagFamilySpec, data []byte, tags ...string) ([]*modelv1.Tag, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	wanted := make(map[int]int, len(tags))
+	maxIndex := -1
+	for i, name := range tags {
+		for ti, tagSpec := range familySpec.GetTags() {
+			if tagSpec.GetName() == name {
+				wanted[ti] = i
+				if ti > maxIndex {
+					maxIndex = ti
+				}
+				break
+			}
+		}
+	}
+	values := make([]*modelv1.TagValue, len(tags))
+	for ti := 0; len(data) > 0 && ti <= maxIndex; {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, errors.Wrap(errMalformedElement, protowire.ParseError(n).Error())
+		}
+		data = data[n:]
+		if num != 1 || typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return nil, errors.Wrap(errMalformedElement, protowire.ParseError(n).Error())
+			}
+			data = data[n:]
+			continue
+		}
+		raw, n := protowire.ConsumeBytes(data)
+		if n < 0 {
+			return nil, errors.Wrap(errMalformedElement, protowire.ParseError(n).Error())
+		}
+		data = data[n:]
+		if i, ok := wanted[ti]; ok {
+			tv := &modelv1.TagValue{}
+			if err := proto.Unmarshal(raw, tv); err != nil {
+				return nil, errors.Wrapf(errMalformedElement, "tag %s: %v", tags[i], err)
+			}
+			values[i] = tv
+		}
+		ti++
+	}
+	result := make([]*modelv1.Tag, 0, len(tags))
+	for i, tv := range values {
+		if tv == nil {
+			continue
+		}
+		result = append(result, &modelv1.Tag{Key: tags[i], Value: tv})
+	}
+	return result, nil
+}
+
 // DecodeFieldValue decodes bytes to field value based on its specification.
 func DecodeFieldValue(fieldValue []byte, fieldSpec *databasev1.FieldSpec) (*modelv1.FieldValue, error) {
 	switch fieldSpec.GetFieldType() {
@@ -0,0 +1,140 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package v1
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+)
+
+func strTagValue(v string) *modelv1.TagValue {
+	return &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: v}}}
+}
+
+func wideFamily(width int) (*databasev1.TagFamilySpec, *modelv1.TagFamilyForWrite) {
+	spec := &databasev1.TagFamilySpec{Name: "searchable"}
+	family := &modelv1.TagFamilyForWrite{}
+	for i := 0; i < width; i++ {
+		name := fmt.Sprintf("tag_%d", i)
+		if i == width/2 {
+			name = "trace_id"
+		}
+		spec.Tags = append(spec.Tags, &databasev1.TagSpec{Name: name, Type: databasev1.TagType_TAG_TYPE_STRING})
+		family.Tags = append(family.Tags, strTagValue(fmt.Sprintf("value_%d", i)))
+	}
+	return spec, family
+}
+
+func TestParseTagFamilyTags(t *testing.T) {
+	spec := &databasev1.TagFamilySpec{
+		Name: "searchable",
+		Tags: []*databasev1.TagSpec{
+			{Name: "trace_id", Type: databasev1.TagType_TAG_TYPE_STRING},
+			{Name: "state", Type: databasev1.TagType_TAG_TYPE_INT},
+			{Name: "service_id", Type: databasev1.TagType_TAG_TYPE_STRING},
+			{Name: "endpoint_id", Type: databasev1.TagType_TAG_TYPE_STRING},
+		},
+	}
+	family := &modelv1.TagFamilyForWrite{Tags: []*modelv1.TagValue{
+		strTagValue("trace-1"),
+		{Value: &modelv1.TagValue_Int{Int: &modelv1.Int{Value: 1}}},
+		strTagValue("svc-1"),
+	}}
+	data, err := EncodeFamily(spec, family)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		tags []string
+		want []*modelv1.Tag
+	}{
+		{
+			name: "single tag",
+			tags: []string{"trace_id"},
+			want: []*modelv1.Tag{{Key: "trace_id", Value: family.Tags[0]}},
+		},
+		{
+			name: "requested order",
+			tags: []string{"service_id", "trace_id"},
+			want: []*modelv1.Tag{{Key: "service_id", Value: family.Tags[2]}, {Key: "trace_id", Value: family.Tags[0]}},
+		},
+		{
+			name: "absent from the element",
+			tags: []string{"endpoint_id", "state"},
+			want: []*modelv1.Tag{{Key: "state", Value: family.Tags[1]}},
+		},
+		{
+			name: "unknown tag",
+			tags: []string{"unknown"},
+			want: []*modelv1.Tag{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTagFamilyTags(spec, data, tt.tags...)
+			require.NoError(t, err)
+			require.Len(t, got, len(tt.want))
+			for i := range tt.want {
+				assert.Equal(t, tt.want[i].Key, got[i].Key)
+				assert.True(t, proto.Equal(tt.want[i].Value, got[i].Value))
+			}
+		})
+	}
+}
+
+func TestParseTagFamilyTagsMalformed(t *testing.T) {
+	spec, family := wideFamily(4)
+	data, err := EncodeFamily(spec, family)
+	require.NoError(t, err)
+	_, err = ParseTagFamilyTags(spec, data[:len(data)-3], "tag_3")
+	assert.ErrorIs(t, err, errMalformedElement)
+}
+
+func BenchmarkParseTagFamily(b *testing.B) {
+	for _, width := range []int{20, 64} {
+		spec, family := wideFamily(width)
+		data, err := EncodeFamily(spec, family)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(fmt.Sprintf("full-%d", width), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				f := &modelv1.TagFamilyForWrite{}
+				if err := proto.Unmarshal(data, f); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("single-tag-%d", width), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := ParseTagFamilyTags(spec, data, "trace_id"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
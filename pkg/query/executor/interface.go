@@ -93,6 +93,8 @@ type MeasureExecutable interface {
 type DistributedExecutionContext interface {
 	bus.Broadcaster
 	TimeRange() *modelv1.TimeRange
+	// AddBytesRead accumulates the bytes the data nodes read from disk for the query.
+	AddBytesRead(n uint64)
 }
 
 // DistributedExecutionContextKey is the key of distributed execution context in context.Context.
@@ -0,0 +1,447 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package logical
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+)
+
+const (
+	maxDerivedExprLen   = 1024
+	maxDerivedExprDepth = 32
+)
+
+var (
+	errInvalidDerivedExpr = errors.New("invalid derived expression")
+	errDerivedExprType    = errors.New("mismatched type in derived expression")
+)
+
+// DerivedExpr computes a tag from the other tags of a row, e.g. "status / 100".
+// An expression is made of the integer and string literals, the projected tags, the integer arithmetic
+// (+, -, *, /, %) and the functions substr(s, start, length) and concat(s1, s2, ...).
+// There is nothing else to call, so evaluating an expression is bounded by its size.
+type DerivedExpr interface {
+	// Type is the type of the computed tag, either TAG_TYPE_INT or TAG_TYPE_STRING.
+	Type() databasev1.TagType
+	// Eval computes the tag from the tag families of a row laid out by the projection.
+	// The tag is null if any tag it's computed from is null, or it's divided by zero.
+	Eval(tagFamilies []*modelv1.TagFamily) *modelv1.TagValue
+}
+
+// ParseDerivedExpr parses an expression and checks its types against the projected tags.
+func ParseDerivedExpr(expr string, tags TagSpecRegistry) (DerivedExpr, error) {
+	if len(expr) > maxDerivedExprLen {
+		return nil, errors.WithMessagef(errInvalidDerivedExpr, "the expression exceeds %d characters", maxDerivedExprLen)
+	}
+	tokens, err := lexDerivedExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &derivedExprParser{tokens: tokens, tags: tags}
+	n, err := p.parseSum(0)
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, errors.WithMessagef(errInvalidDerivedExpr, "unexpected %q in %q", p.peek().text, expr)
+	}
+	return &derivedExpr{root: n}, nil
+}
+
+type derivedExpr struct {
+	root derivedNode
+}
+
+func (e *derivedExpr) Type() databasev1.TagType {
+	return e.root.typ()
+}
+
+func (e *derivedExpr) Eval(tagFamilies []*modelv1.TagFamily) *modelv1.TagValue {
+	v := e.root.eval(tagFamilies)
+	switch {
+	case v.null:
+		return pbv1.NullTagValue
+	case e.root.typ() == databasev1.TagType_TAG_TYPE_INT:
+		return &modelv1.TagValue{Value: &modelv1.TagValue_Int{Int: &modelv1.Int{Value: v.i}}}
+	default:
+		return &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: v.s}}}
+	}
+}
+
+type derivedValue struct {
+	s    string
+	i    int64
+	null bool
+}
+
+type derivedNode interface {
+	typ() databasev1.TagType
+	eval(tagFamilies []*modelv1.TagFamily) derivedValue
+}
+
+type derivedLiteral struct {
+	v derivedValue
+	t databasev1.TagType
+}
+
+func (l *derivedLiteral) typ() databasev1.TagType { return l.t }
+
+func (l *derivedLiteral) eval(_ []*modelv1.TagFamily) derivedValue { return l.v }
+
+type derivedTagRef struct {
+	spec *TagSpec
+}
+
+func (r *derivedTagRef) typ() databasev1.TagType { return r.spec.Spec.GetType() }
+
+func (r *derivedTagRef) eval(tagFamilies []*modelv1.TagFamily) derivedValue {
+	if r.spec.TagFamilyIdx >= len(tagFamilies) || r.spec.TagIdx >= len(tagFamilies[r.spec.TagFamilyIdx].GetTags()) {
+		return derivedValue{null: true}
+	}
+	switch v := tagFamilies[r.spec.TagFamilyIdx].GetTags()[r.spec.TagIdx].GetValue().GetValue().(type) {
+	case *modelv1.TagValue_Int:
+		return derivedValue{i: v.Int.GetValue()}
+	case *modelv1.TagValue_Str:
+		return derivedValue{s: v.Str.GetValue()}
+	default:
+		return derivedValue{null: true}
+	}
+}
+
+type derivedArithmetic struct {
+	left  derivedNode
+	right derivedNode
+	op    byte
+}
+
+func (a *derivedArithmetic) typ() databasev1.TagType { return databasev1.TagType_TAG_TYPE_INT }
+
+func (a *derivedArithmetic) eval(tagFamilies []*modelv1.TagFamily) derivedValue {
+	l, r := a.left.eval(tagFamilies), a.right.eval(tagFamilies)
+	if l.null || r.null {
+		return derivedValue{null: true}
+	}
+	switch a.op {
+	case '+':
+		return derivedValue{i: l.i + r.i}
+	case '-':
+		return derivedValue{i: l.i - r.i}
+	case '*':
+		return derivedValue{i: l.i * r.i}
+	case '/':
+		if r.i == 0 {
+			return derivedValue{null: true}
+		}
+		return derivedValue{i: l.i / r.i}
+	default:
+		if r.i == 0 {
+			return derivedValue{null: true}
+		}
+		return derivedValue{i: l.i % r.i}
+	}
+}
+
+type derivedSubstr struct {
+	s      derivedNode
+	start  derivedNode
+	length derivedNode
+}
+
+func (f *derivedSubstr) typ() databasev1.TagType { return databasev1.TagType_TAG_TYPE_STRING }
+
+// eval counts the start and the length in characters, clamping them to the string.
+func (f *derivedSubstr) eval(tagFamilies []*modelv1.TagFamily) derivedValue {
+	s, start, length := f.s.eval(tagFamilies), f.start.eval(tagFamilies), f.length.eval(tagFamilies)
+	if s.null || start.null || length.null {
+		return derivedValue{null: true}
+	}
+	runes := []rune(s.s)
+	begin := min(max(start.i, 0), int64(len(runes)))
+	// The length is clamped before it's added, so a huge one doesn't overflow.
+	end := begin + min(max(length.i, 0), int64(len(runes))-begin)
+	return derivedValue{s: string(runes[begin:end])}
+}
+
+type derivedConcat struct {
+	args []derivedNode
+}
+
+func (f *derivedConcat) typ() databasev1.TagType { return databasev1.TagType_TAG_TYPE_STRING }
+
+func (f *derivedConcat) eval(tagFamilies []*modelv1.TagFamily) derivedValue {
+	var sb strings.Builder
+	for _, arg := range f.args {
+		v := arg.eval(tagFamilies)
+		if v.null {
+			return derivedValue{null: true}
+		}
+		sb.WriteString(v.s)
+	}
+	return derivedValue{s: sb.String()}
+}
+
+type derivedTokenKind int
+
+const (
+	derivedTokenInt derivedTokenKind = iota
+	derivedTokenStr
+	derivedTokenIdent
+	derivedTokenPunct
+)
+
+type derivedToken struct {
+	text string
+	kind derivedTokenKind
+}
+
+func lexDerivedExpr(expr string) ([]derivedToken, error) {
+	var tokens []derivedToken
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(expr) && expr[j] >= '0' && expr[j] <= '9' {
+				j++
+			}
+			tokens = append(tokens, derivedToken{kind: derivedTokenInt, text: expr[i:j]})
+			i = j
+		case isDerivedIdentStart(expr[i]):
+			j := i
+			for j < len(expr) && (isDerivedIdentStart(expr[j]) || expr[j] >= '0' && expr[j] <= '9') {
+				j++
+			}
+			tokens = append(tokens, derivedToken{kind: derivedTokenIdent, text: expr[i:j]})
+			i = j
+		case c == '\'' || c == '"':
+			j := strings.IndexByte(expr[i+1:], expr[i])
+			if j < 0 {
+				return nil, errors.WithMessagef(errInvalidDerivedExpr, "unterminated string in %q", expr)
+			}
+			tokens = append(tokens, derivedToken{kind: derivedTokenStr, text: expr[i+1 : i+1+j]})
+			i += j + 2
+		case strings.ContainsRune("+-*/%(),", c):
+			tokens = append(tokens, derivedToken{kind: derivedTokenPunct, text: expr[i : i+1]})
+			i++
+		default:
+			return nil, errors.WithMessagef(errInvalidDerivedExpr, "unexpected character %q in %q", c, expr)
+		}
+	}
+	return tokens, nil
+}
+
+func isDerivedIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// derivedExprParser is a recursive descent parser of the grammar:
+//
+//	sum     = product { ("+" | "-") product }
+//	product = unary { ("*" | "/" | "%") unary }
+//	unary   = "-" unary | primary
+//	primary = int | string | tag | function "(" [ sum { "," sum } ] ")" | "(" sum ")"
+type derivedExprParser struct {
+	tags   TagSpecRegistry
+	tokens []derivedToken
+	pos    int
+}
+
+func (p *derivedExprParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *derivedExprParser) peek() derivedToken {
+	if p.done() {
+		return derivedToken{}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *derivedExprParser) accept(punct string) bool {
+	if t := p.peek(); t.kind == derivedTokenPunct && t.text == punct {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *derivedExprParser) parseSum(depth int) (derivedNode, error) {
+	left, err := p.parseProduct(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek().text
+		if !p.accept("+") && !p.accept("-") {
+			return left, nil
+		}
+		right, err := p.parseProduct(depth)
+		if err != nil {
+			return nil, err
+		}
+		if left, err = newDerivedArithmetic(op[0], left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *derivedExprParser) parseProduct(depth int) (derivedNode, error) {
+	left, err := p.parseUnary(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek().text
+		if !p.accept("*") && !p.accept("/") && !p.accept("%") {
+			return left, nil
+		}
+		right, err := p.parseUnary(depth)
+		if err != nil {
+			return nil, err
+		}
+		if left, err = newDerivedArithmetic(op[0], left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *derivedExprParser) parseUnary(depth int) (derivedNode, error) {
+	if depth > maxDerivedExprDepth {
+		return nil, errors.WithMessagef(errInvalidDerivedExpr, "the expression nests deeper than %d levels", maxDerivedExprDepth)
+	}
+	if p.accept("-") {
+		operand, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return newDerivedArithmetic('-', &derivedLiteral{t: databasev1.TagType_TAG_TYPE_INT}, operand)
+	}
+	return p.parsePrimary(depth)
+}
+
+func (p *derivedExprParser) parsePrimary(depth int) (derivedNode, error) {
+	if p.done() {
+		return nil, errors.WithMessage(errInvalidDerivedExpr, "unexpected end of the expression")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	switch t.kind {
+	case derivedTokenInt:
+		i, err := strconv.ParseInt(t.text, 10, 64)
+		if err != nil {
+			return nil, errors.WithMessagef(errInvalidDerivedExpr, "invalid integer %s", t.text)
+		}
+		return &derivedLiteral{t: databasev1.TagType_TAG_TYPE_INT, v: derivedValue{i: i}}, nil
+	case derivedTokenStr:
+		return &derivedLiteral{t: databasev1.TagType_TAG_TYPE_STRING, v: derivedValue{s: t.text}}, nil
+	case derivedTokenIdent:
+		if p.accept("(") {
+			return p.parseCall(t.text, depth)
+		}
+		spec := p.tags.FindTagSpecByName(t.text)
+		if spec == nil {
+			return nil, errors.WithMessagef(errInvalidDerivedExpr, "tag %s is not in the projection", t.text)
+		}
+		switch spec.Spec.GetType() {
+		case databasev1.TagType_TAG_TYPE_INT, databasev1.TagType_TAG_TYPE_STRING:
+		default:
+			return nil, errors.WithMessagef(errDerivedExprType, "tag %s is a %s, only the integer and string tags are supported",
+				t.text, spec.Spec.GetType())
+		}
+		return &derivedTagRef{spec: spec}, nil
+	default:
+		if t.text != "(" {
+			return nil, errors.WithMessagef(errInvalidDerivedExpr, "unexpected %q", t.text)
+		}
+		n, err := p.parseSum(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, errors.WithMessage(errInvalidDerivedExpr, "missing )")
+		}
+		return n, nil
+	}
+}
+
+func (p *derivedExprParser) parseCall(name string, depth int) (derivedNode, error) {
+	var args []derivedNode
+	if !p.accept(")") {
+		for {
+			arg, err := p.parseSum(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.accept(")") {
+				break
+			}
+			if !p.accept(",") {
+				return nil, errors.WithMessagef(errInvalidDerivedExpr, "missing ) after the arguments of %s", name)
+			}
+		}
+	}
+	switch name {
+	case "substr":
+		if err := checkDerivedArgs(name, args, databasev1.TagType_TAG_TYPE_STRING,
+			databasev1.TagType_TAG_TYPE_INT, databasev1.TagType_TAG_TYPE_INT); err != nil {
+			return nil, err
+		}
+		return &derivedSubstr{s: args[0], start: args[1], length: args[2]}, nil
+	case "concat":
+		if len(args) < 2 {
+			return nil, errors.WithMessagef(errInvalidDerivedExpr, "concat takes at least 2 arguments, got %d", len(args))
+		}
+		for i, arg := range args {
+			if arg.typ() != databasev1.TagType_TAG_TYPE_STRING {
+				return nil, errors.WithMessagef(errDerivedExprType, "argument %d of concat is a %s, want a string", i+1, arg.typ())
+			}
+		}
+		return &derivedConcat{args: args}, nil
+	default:
+		return nil, errors.WithMessagef(errInvalidDerivedExpr, "unknown function %s", name)
+	}
+}
+
+func checkDerivedArgs(name string, args []derivedNode, types ...databasev1.TagType) error {
+	if len(args) != len(types) {
+		return errors.WithMessagef(errInvalidDerivedExpr, "%s takes %d arguments, got %d", name, len(types), len(args))
+	}
+	for i, arg := range args {
+		if arg.typ() != types[i] {
+			return errors.WithMessagef(errDerivedExprType, "argument %d of %s is a %s, want a %s", i+1, name, arg.typ(), types[i])
+		}
+	}
+	return nil
+}
+
+func newDerivedArithmetic(op byte, left, right derivedNode) (derivedNode, error) {
+	if left.typ() != databasev1.TagType_TAG_TYPE_INT || right.typ() != databasev1.TagType_TAG_TYPE_INT {
+		return nil, errors.WithMessagef(errDerivedExprType, "%c takes integers, got a %s and a %s", op, left.typ(), right.typ())
+	}
+	return &derivedArithmetic{op: op, left: left, right: right}, nil
+}
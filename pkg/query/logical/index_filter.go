@@ -21,6 +21,7 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"sort"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -167,6 +168,18 @@ func parseCondition(cond *modelv1.Condition, indexRule *databasev1.IndexRule, ex
 			or.append(newEq(indexRule, newBytesLiteral(b)))
 		}
 		return newNot(indexRule, or), [][]*modelv1.TagValue{entity}, nil
+	case modelv1.Condition_BINARY_OP_EXISTS:
+		return newExists(indexRule), [][]*modelv1.TagValue{entity}, nil
+	case modelv1.Condition_BINARY_OP_NOT_EXISTS:
+		return newNot(indexRule, newExists(indexRule)), [][]*modelv1.TagValue{entity}, nil
+	case modelv1.Condition_BINARY_OP_REGEX:
+		if cond.GetValue().GetStr() == nil {
+			return nil, nil, errors.WithMessagef(errUnsupportedConditionValue, "regex takes a string in condition(%v)", cond)
+		}
+		if err := index.ValidateRegexp(cond.GetValue().GetStr().GetValue(), cond.AllowFullScan); err != nil {
+			return nil, nil, err
+		}
+		return newRegex(indexRule, expr, cond.AllowFullScan), [][]*modelv1.TagValue{entity}, nil
 	}
 	return nil, nil, errors.WithMessagef(errUnsupportedConditionOp, "index filter parses %v", cond)
 }
@@ -176,6 +189,9 @@ func parseExprOrEntity(entityDict map[string]int, entity []*modelv1.TagValue, co
 	if ok && cond.Op != modelv1.Condition_BINARY_OP_EQ && cond.Op != modelv1.Condition_BINARY_OP_IN {
 		return nil, nil, errors.WithMessagef(errUnsupportedConditionOp, "tag belongs to the entity only supports EQ or IN operation in condition(%v)", cond)
 	}
+	if isExistenceOp(cond.Op) {
+		return nullLiteralExpr, nil, nil
+	}
 	switch v := cond.Value.Value.(type) {
 	case *modelv1.TagValue_Str:
 		if ok {
@@ -376,16 +392,32 @@ func newAnd(size int) *andNode {
 	}
 }
 
+// merge intersects the lists from the most selective one, which is the shortest list,
+// so that the intermediate result is as small as possible. The lists having the same length
+// are intersected in the declaration order. The intersection stops once the result is empty.
 func (an *andNode) merge(list ...posting.List) (posting.List, error) {
-	var result posting.List
+	lists := make([]posting.List, 0, len(list))
 	for _, l := range list {
+		if l == nil {
+			continue
+		}
 		if _, ok := l.(*bypassList); ok {
 			continue
 		}
+		lists = append(lists, l)
+	}
+	sort.SliceStable(lists, func(i, j int) bool {
+		return lists[i].Len() < lists[j].Len()
+	})
+	var result posting.List
+	for _, l := range lists {
 		if result == nil {
 			result = l
 			continue
 		}
+		if result.IsEmpty() {
+			break
+		}
 		if err := result.Intersect(l); err != nil {
 			return nil, err
 		}
@@ -393,8 +425,24 @@ func (an *andNode) merge(list ...posting.List) (posting.List, error) {
 	return result, nil
 }
 
+// Execute runs all the sub nodes before intersecting their results,
+// so that the order of the intersection depends on the sizes of the results.
 func (an *andNode) Execute(searcher index.GetSearcher, seriesID common.SeriesID) (posting.List, error) {
-	return execute(searcher, seriesID, an.node, an)
+	if len(an.SubNodes) < 1 {
+		return bList, nil
+	}
+	lists := make([]posting.List, 0, len(an.SubNodes))
+	for _, sn := range an.SubNodes {
+		l, err := sn.Execute(searcher, seriesID)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, l)
+	}
+	if len(lists) == 1 {
+		return lists[0], nil
+	}
+	return an.merge(lists...)
 }
 
 func (an *andNode) MarshalJSON() ([]byte, error) {
@@ -539,6 +587,45 @@ func (eq *eq) String() string {
 	return jsonToString(eq)
 }
 
+// exists matches the elements having any term of the indexed tag.
+// A not node wrapping it matches the rest of the elements of the series.
+type exists struct {
+	Key fieldKey
+}
+
+func newExists(indexRule *databasev1.IndexRule) *exists {
+	return &exists{Key: newFieldKey(indexRule)}
+}
+
+func (e *exists) Execute(searcher index.GetSearcher, seriesID common.SeriesID) (posting.List, error) {
+	s, err := searcher(e.Key.Type)
+	if err != nil {
+		return nil, err
+	}
+	// The empty lower bound restricts the range to the elements having the tag, while
+	// the range without any bound matches every element of the series.
+	return s.Range(e.Key.toIndex(seriesID), index.RangeOpts{
+		Lower:         []byte{},
+		IncludesLower: true,
+		IncludesUpper: true,
+	})
+}
+
+func (e *exists) MarshalJSON() ([]byte, error) {
+	data := make(map[string]interface{}, 1)
+	data["exists"] = e.Key.IndexRule.Metadata.Name + ":" + e.Key.IndexRule.Metadata.Group
+	return json.Marshal(data)
+}
+
+func (e *exists) String() string {
+	return jsonToString(e)
+}
+
+// isExistenceOp reports whether the operation checks the presence of a tag, which doesn't take a value.
+func isExistenceOp(op modelv1.Condition_BinaryOp) bool {
+	return op == modelv1.Condition_BINARY_OP_EXISTS || op == modelv1.Condition_BINARY_OP_NOT_EXISTS
+}
+
 type match struct {
 	*leaf
 }
@@ -578,6 +665,40 @@ func (match *match) String() string {
 	return jsonToString(match)
 }
 
+// regex matches the terms of the indexed tag by a regular expression.
+type regex struct {
+	*leaf
+	AllowFullScan bool
+}
+
+func newRegex(indexRule *databasev1.IndexRule, pattern LiteralExpr, allowFullScan bool) *regex {
+	return &regex{
+		leaf: &leaf{
+			Key:  newFieldKey(indexRule),
+			Expr: pattern,
+		},
+		AllowFullScan: allowFullScan,
+	}
+}
+
+func (r *regex) Execute(searcher index.GetSearcher, seriesID common.SeriesID) (posting.List, error) {
+	s, err := searcher(r.Key.Type)
+	if err != nil {
+		return nil, err
+	}
+	return s.Regexp(r.Key.toIndex(seriesID), string(bytes.Join(r.Expr.Bytes(), nil)), r.AllowFullScan)
+}
+
+func (r *regex) MarshalJSON() ([]byte, error) {
+	data := make(map[string]interface{}, 1)
+	data["regex"] = r.leaf
+	return json.Marshal(data)
+}
+
+func (r *regex) String() string {
+	return jsonToString(r)
+}
+
 type rangeOp struct {
 	*leaf
 	Opts index.RangeOpts
@@ -0,0 +1,215 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package logical
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/pkg/convert"
+	"github.com/apache/skywalking-banyandb/pkg/index"
+	"github.com/apache/skywalking-banyandb/pkg/index/inverted"
+	"github.com/apache/skywalking-banyandb/pkg/index/posting"
+	"github.com/apache/skywalking-banyandb/pkg/index/posting/roaring"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/test"
+)
+
+const broadPredicateSize = 1 << 20
+
+// listFilter is a predicate returning a clone of its posting list.
+type listFilter struct {
+	list posting.List
+}
+
+func (lf listFilter) Execute(_ index.GetSearcher, _ common.SeriesID) (posting.List, error) {
+	return lf.list.Clone(), nil
+}
+
+func (lf listFilter) String() string {
+	return "list"
+}
+
+func TestAndNodeIntersectsTheMostSelectiveFirst(t *testing.T) {
+	broad := roaring.NewRange(0, broadPredicateSize)
+	selective := roaring.NewPostingListWithInitialData(7, 42, broadPredicateSize+1)
+
+	result, err := newAnd(2).merge(broad, selective)
+	require.NoError(t, err)
+	assert.Same(t, selective, result, "the selective list is the base of the intersection")
+	assert.Equal(t, []uint64{7, 42}, result.ToSlice())
+	assert.Equal(t, broadPredicateSize, broad.Len(), "the broad list is only read")
+
+	t.Run("the declaration order is kept for the same selectivity", func(t *testing.T) {
+		first := roaring.NewPostingListWithInitialData(1, 2)
+		second := roaring.NewPostingListWithInitialData(2, 3)
+		result, err := newAnd(2).merge(first, bList, second)
+		require.NoError(t, err)
+		assert.Same(t, first, result)
+		assert.Equal(t, []uint64{2}, result.ToSlice())
+	})
+
+	t.Run("the result of the sub nodes is the intersection", func(t *testing.T) {
+		an := newAnd(3)
+		an.append(listFilter{list: roaring.NewRange(0, broadPredicateSize)}).
+			append(listFilter{list: roaring.NewPostingListWithInitialData(3, 5, broadPredicateSize+5)}).
+			append(listFilter{list: roaring.NewRange(4, 10)})
+		result, err := an.Execute(nil, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{5}, result.ToSlice())
+	})
+}
+
+func TestExistenceFilters(t *testing.T) {
+	path, defFn := test.Space(require.New(t))
+	defer defFn()
+	store, err := inverted.NewStore(inverted.StoreOpts{Path: path, Logger: logger.GetLogger("test")})
+	require.NoError(t, err)
+	defer store.Close()
+
+	newRule := func(id uint32, name string) *databasev1.IndexRule {
+		return &databasev1.IndexRule{
+			Metadata: &commonv1.Metadata{Id: id, Name: name, Group: "default"},
+			Tags:     []string{name},
+			Type:     databasev1.IndexRule_TYPE_INVERTED,
+		}
+	}
+	endpointID, traceID := newRule(1, "endpoint_id"), newRule(2, "trace_id")
+	const seriesID = common.SeriesID(7)
+	field := func(rule *databasev1.IndexRule, term string) index.Field {
+		return index.Field{Key: newFieldKey(rule).toIndex(seriesID), Term: []byte(term)}
+	}
+	// Every element has a trace_id, while only the odd ones have an endpoint_id.
+	var docs index.Documents
+	for docID := uint64(1); docID <= 6; docID++ {
+		fields := []index.Field{field(traceID, "trace")}
+		if docID%2 == 1 {
+			fields = append(fields, field(endpointID, "/api"))
+		}
+		docs = append(docs, index.Document{DocID: docID, Fields: fields})
+	}
+	applied := make(chan struct{})
+	require.NoError(t, store.Batch(index.Batch{Documents: docs, Applied: applied}))
+	<-applied
+
+	searcher := func(databasev1.IndexRule_Type) (index.Searcher, error) {
+		return store, nil
+	}
+	execute := func(op modelv1.Condition_BinaryOp) []uint64 {
+		filter, _, err := parseCondition(&modelv1.Condition{Name: "endpoint_id", Op: op}, endpointID, nullLiteralExpr, nil)
+		require.NoError(t, err)
+		list, err := filter.Execute(searcher, seriesID)
+		require.NoError(t, err)
+		return list.ToSlice()
+	}
+	assert.Equal(t, []uint64{1, 3, 5}, execute(modelv1.Condition_BINARY_OP_EXISTS))
+	assert.Equal(t, []uint64{2, 4, 6}, execute(modelv1.Condition_BINARY_OP_NOT_EXISTS))
+}
+
+// indexedSchema only answers which index rule a tag is indexed by.
+type indexedSchema struct {
+	Schema
+	rules map[string]*databasev1.IndexRule
+}
+
+func (is indexedSchema) IndexDefined(tagName string) (bool, *databasev1.IndexRule) {
+	rule, ok := is.rules[tagName]
+	return ok, rule
+}
+
+func TestLogicalExpressionAcrossIndexRules(t *testing.T) {
+	path, defFn := test.Space(require.New(t))
+	defer defFn()
+	store, err := inverted.NewStore(inverted.StoreOpts{Path: path, Logger: logger.GetLogger("test")})
+	require.NoError(t, err)
+	defer store.Close()
+
+	newRule := func(id uint32, name string) *databasev1.IndexRule {
+		return &databasev1.IndexRule{
+			Metadata: &commonv1.Metadata{Id: id, Name: name, Group: "default"},
+			Tags:     []string{name},
+			Type:     databasev1.IndexRule_TYPE_INVERTED,
+		}
+	}
+	endpointID, statusCode := newRule(1, "endpoint_id"), newRule(2, "status_code")
+	const seriesID = common.SeriesID(7)
+	var docs index.Documents
+	for docID, element := range []struct {
+		endpoint string
+		status   int64
+	}{{"/home_id", 200}, {"/list", 500}, {"/home_id", 500}, {"/about", 200}} {
+		docs = append(docs, index.Document{DocID: uint64(docID + 1), Fields: []index.Field{
+			{Key: newFieldKey(endpointID).toIndex(seriesID), Term: []byte(element.endpoint)},
+			{Key: newFieldKey(statusCode).toIndex(seriesID), Term: convert.Int64ToBytes(element.status)},
+		}})
+	}
+	applied := make(chan struct{})
+	require.NoError(t, store.Batch(index.Batch{Documents: docs, Applied: applied}))
+	<-applied
+
+	schema := indexedSchema{rules: map[string]*databasev1.IndexRule{"endpoint_id": endpointID, "status_code": statusCode}}
+	searcher := func(databasev1.IndexRule_Type) (index.Searcher, error) {
+		return store, nil
+	}
+	execute := func(op modelv1.LogicalExpression_LogicalOp) []uint64 {
+		criteria := &modelv1.Criteria{Exp: &modelv1.Criteria_Le{Le: &modelv1.LogicalExpression{
+			Op: op,
+			Left: &modelv1.Criteria{Exp: &modelv1.Criteria_Condition{Condition: &modelv1.Condition{
+				Name:  "endpoint_id",
+				Op:    modelv1.Condition_BINARY_OP_EQ,
+				Value: &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "/home_id"}}},
+			}}},
+			Right: &modelv1.Criteria{Exp: &modelv1.Criteria_Condition{Condition: &modelv1.Condition{
+				Name:  "status_code",
+				Op:    modelv1.Condition_BINARY_OP_EQ,
+				Value: &modelv1.TagValue{Value: &modelv1.TagValue_Int{Int: &modelv1.Int{Value: 500}}},
+			}}},
+		}}}
+		filter, _, err := BuildLocalFilter(criteria, schema, map[string]int{}, nil, false)
+		require.NoError(t, err)
+		list, err := filter.Execute(searcher, seriesID)
+		require.NoError(t, err)
+		return list.ToSlice()
+	}
+	// Every condition is pushed down to the index of its tag, the lists are combined in memory.
+	assert.Equal(t, []uint64{1, 2, 3}, execute(modelv1.LogicalExpression_LOGICAL_OP_OR))
+	assert.Equal(t, []uint64{3}, execute(modelv1.LogicalExpression_LOGICAL_OP_AND))
+}
+
+func BenchmarkAndNodeIntersection(b *testing.B) {
+	broad := roaring.NewRange(0, broadPredicateSize)
+	selective := roaring.NewPostingListWithInitialData(7, 42, broadPredicateSize+1)
+	an := newAnd(2)
+	an.append(listFilter{list: broad}).append(listFilter{list: selective})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		result, err := an.Execute(nil, 0)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if result.Len() != 2 {
+			b.Fatalf("unexpected intersection size %d", result.Len())
+		}
+	}
+}
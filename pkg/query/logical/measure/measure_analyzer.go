@@ -79,6 +79,16 @@ func Analyze(_ context.Context, criteria *measurev1.QueryRequest, metadata *comm
 	}
 	pushedLimit := int(limitParameter + criteria.GetOffset())
 
+	if criteria.GetInterpolation() != nil {
+		plan = interpolation(plan, criteria)
+		pushedLimit = math.MaxInt
+	}
+
+	if criteria.GetSeriesMerge() != nil {
+		plan = seriesMerge(plan, criteria)
+		pushedLimit = math.MaxInt
+	}
+
 	if criteria.GetGroupBy() != nil {
 		plan = newUnresolvedGroupBy(plan, groupByTags, groupByEntity)
 		pushedLimit = math.MaxInt
@@ -133,6 +143,16 @@ func DistributedAnalyze(criteria *measurev1.QueryRequest, s logical.Schema) (log
 	}
 	pushedLimit := int(limitParameter + criteria.GetOffset())
 
+	if criteria.GetInterpolation() != nil {
+		plan = interpolation(plan, criteria)
+		pushedLimit = math.MaxInt
+	}
+
+	if criteria.GetSeriesMerge() != nil {
+		plan = seriesMerge(plan, criteria)
+		pushedLimit = math.MaxInt
+	}
+
 	if criteria.GetGroupBy() != nil {
 		plan = newUnresolvedGroupBy(plan, groupByTags, false)
 		pushedLimit = math.MaxInt
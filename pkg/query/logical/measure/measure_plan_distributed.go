@@ -85,6 +85,7 @@ func (ud *unresolvedDistributed) Analyze(s logical.Schema) (logical.Plan, error)
 		Criteria:        ud.originalQuery.Criteria,
 		Limit:           limit,
 		OrderBy:         ud.originalQuery.OrderBy,
+		Checksum:        ud.originalQuery.Checksum,
 	}
 	if ud.groupByEntity {
 		e := s.EntityList()[0]
@@ -174,8 +175,15 @@ func (t *distributedPlan) Execute(ctx context.Context) (executor.MIterator, erro
 			if d == nil {
 				continue
 			}
+			resp := d.(*measurev1.QueryResponse)
+			if query.Checksum {
+				if verifyErr := pbv1.VerifyMeasureQueryChecksum(resp); verifyErr != nil {
+					allErr = multierr.Append(allErr, verifyErr)
+					continue
+				}
+			}
 			see = append(see,
-				newSortableElements(d.(*measurev1.QueryResponse).DataPoints,
+				newSortableElements(resp.DataPoints,
 					t.sortByTime, t.sortTagSpec))
 		}
 	}
@@ -0,0 +1,283 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/query/executor"
+	"github.com/apache/skywalking-banyandb/pkg/query/logical"
+)
+
+// maxInterpolatedPoints bounds the number of points in an interpolated series.
+const maxInterpolatedPoints = 10000
+
+var (
+	_ logical.UnresolvedPlan = (*unresolvedInterpolation)(nil)
+	_ logical.Plan           = (*interpolationPlan)(nil)
+
+	errUnsupportedInterpolation = errors.New("unsupported interpolation")
+)
+
+type unresolvedInterpolation struct {
+	begin           time.Time
+	end             time.Time
+	unresolvedInput logical.UnresolvedPlan
+	interpolation   *measurev1.QueryRequest_Interpolation
+	fields          []*logical.Field
+}
+
+func interpolation(input logical.UnresolvedPlan, criteria *measurev1.QueryRequest) logical.UnresolvedPlan {
+	fields := make([]*logical.Field, len(criteria.GetFieldProjection().GetNames()))
+	for i, name := range criteria.GetFieldProjection().GetNames() {
+		fields[i] = logical.NewField(name)
+	}
+	return &unresolvedInterpolation{
+		unresolvedInput: input,
+		interpolation:   criteria.GetInterpolation(),
+		fields:          fields,
+		begin:           criteria.GetTimeRange().GetBegin().AsTime(),
+		end:             criteria.GetTimeRange().GetEnd().AsTime(),
+	}
+}
+
+func (ui *unresolvedInterpolation) Analyze(measureSchema logical.Schema) (logical.Plan, error) {
+	prevPlan, err := ui.unresolvedInput.Analyze(measureSchema)
+	if err != nil {
+		return nil, err
+	}
+	interval := ui.interpolation.GetInterval().AsDuration()
+	if interval <= 0 {
+		return nil, errors.WithMessagef(errUnsupportedInterpolation, "interval %s should be positive", interval)
+	}
+	if ui.end.Sub(ui.begin)/interval > maxInterpolatedPoints {
+		return nil, errors.WithMessagef(errUnsupportedInterpolation,
+			"interval %s is too small for the time range, it generates more than %d points", interval, maxInterpolatedPoints)
+	}
+	fieldRefs, err := prevPlan.Schema().CreateFieldRef(ui.fields...)
+	if err != nil {
+		return nil, err
+	}
+	if len(fieldRefs) == 0 {
+		return nil, errors.Wrap(errFieldNotDefined, "interpolation schema")
+	}
+	step := ui.interpolation.GetMethod() == measurev1.QueryRequest_Interpolation_METHOD_STEP
+	if !step {
+		for _, ref := range fieldRefs {
+			switch ref.Spec.Spec.FieldType {
+			case databasev1.FieldType_FIELD_TYPE_INT, databasev1.FieldType_FIELD_TYPE_FLOAT:
+			default:
+				return nil, errors.WithMessagef(errUnsupportedInterpolation, "linear interpolation on field: %s", ref.Spec.Spec)
+			}
+		}
+	}
+	return &interpolationPlan{
+		Parent: &logical.Parent{
+			UnresolvedInput: ui.unresolvedInput,
+			Input:           prevPlan,
+		},
+		fieldRefs: fieldRefs,
+		step:      step,
+		begin:     ui.begin.UnixNano(),
+		end:       ui.end.UnixNano(),
+		interval:  interval.Nanoseconds(),
+		maxGap:    ui.interpolation.GetMaxGap().AsDuration().Nanoseconds(),
+	}, nil
+}
+
+type interpolationPlan struct {
+	*logical.Parent
+	fieldRefs []*logical.FieldRef
+	begin     int64
+	end       int64
+	interval  int64
+	maxGap    int64
+	step      bool
+}
+
+func (ip *interpolationPlan) String() string {
+	method := "linear"
+	if ip.step {
+		method = "step"
+	}
+	return fmt.Sprintf("%s interpolation: method=%s,interval=%s,maxGap=%s", ip.Input, method,
+		time.Duration(ip.interval), time.Duration(ip.maxGap))
+}
+
+func (ip *interpolationPlan) Children() []logical.Plan {
+	return []logical.Plan{ip.Input}
+}
+
+func (ip *interpolationPlan) Schema() logical.Schema {
+	return ip.Input.Schema()
+}
+
+func (ip *interpolationPlan) Execute(ec context.Context) (mit executor.MIterator, err error) {
+	iter, err := ip.Parent.Input.(executor.MeasureExecutable).Execute(ec)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = multierr.Append(err, iter.Close())
+	}()
+	var seriesList []*rawSeries
+	seriesMap := make(map[string]*rawSeries)
+	for iter.Next() {
+		for _, dp := range iter.Current() {
+			key, err := seriesKey(dp)
+			if err != nil {
+				return nil, err
+			}
+			s, ok := seriesMap[key]
+			if !ok {
+				s = &rawSeries{tagFamilies: dp.GetTagFamilies()}
+				seriesMap[key] = s
+				seriesList = append(seriesList, s)
+			}
+			s.points = append(s.points, dp)
+		}
+	}
+	var result []*measurev1.DataPoint
+	for _, s := range seriesList {
+		result = append(result, ip.interpolate(s)...)
+	}
+	return &interpolationIterator{dataPoints: result, index: -1}, nil
+}
+
+type rawSeries struct {
+	tagFamilies []*modelv1.TagFamily
+	points      []*measurev1.DataPoint
+}
+
+func seriesKey(dp *measurev1.DataPoint) (string, error) {
+	var key []byte
+	for _, tf := range dp.GetTagFamilies() {
+		b, err := proto.MarshalOptions{Deterministic: true}.Marshal(tf)
+		if err != nil {
+			return "", err
+		}
+		key = append(key, b...)
+	}
+	return string(key), nil
+}
+
+type rawPoint struct {
+	value *modelv1.FieldValue
+	ts    int64
+}
+
+func (ip *interpolationPlan) interpolate(s *rawSeries) []*measurev1.DataPoint {
+	sort.SliceStable(s.points, func(i, j int) bool {
+		return s.points[i].GetTimestamp().AsTime().Before(s.points[j].GetTimestamp().AsTime())
+	})
+	var result []*measurev1.DataPoint
+	for t := ip.begin; t < ip.end; t += ip.interval {
+		result = append(result, &measurev1.DataPoint{
+			Timestamp:   timestamppb.New(time.Unix(0, t)),
+			TagFamilies: s.tagFamilies,
+			Fields:      make([]*measurev1.DataPoint_Field, len(ip.fieldRefs)),
+		})
+	}
+	points := make([]rawPoint, 0, len(s.points))
+	for i, ref := range ip.fieldRefs {
+		points = points[:0]
+		for _, dp := range s.points {
+			v := dp.GetFields()[ref.Spec.FieldIdx].GetValue()
+			if _, isNull := v.GetValue().(*modelv1.FieldValue_Null); v == nil || isNull {
+				continue
+			}
+			ts := dp.GetTimestamp().AsTime().UnixNano()
+			if n := len(points); n > 0 && points[n-1].ts == ts {
+				points[n-1].value = v
+				continue
+			}
+			points = append(points, rawPoint{ts: ts, value: v})
+		}
+		// next is the index of the first raw point after or at the current timestamp
+		next := 0
+		for _, dp := range result {
+			t := dp.GetTimestamp().AsTime().UnixNano()
+			for next < len(points) && points[next].ts < t {
+				next++
+			}
+			dp.Fields[i] = &measurev1.DataPoint_Field{
+				Name:  ref.Field.Name,
+				Value: ip.valueAt(points, next, t),
+			}
+		}
+	}
+	return result
+}
+
+func (ip *interpolationPlan) valueAt(points []rawPoint, next int, t int64) *modelv1.FieldValue {
+	if next < len(points) && points[next].ts == t {
+		return points[next].value
+	}
+	if next == 0 || next == len(points) {
+		return pbv1.NullFieldValue
+	}
+	prev, after := points[next-1], points[next]
+	if ip.maxGap > 0 && after.ts-prev.ts > ip.maxGap {
+		return pbv1.NullFieldValue
+	}
+	if ip.step {
+		return prev.value
+	}
+	ratio := float64(t-prev.ts) / float64(after.ts-prev.ts)
+	if prev.value.GetFloat() != nil {
+		v := prev.value.GetFloat().GetValue()
+		return &modelv1.FieldValue{Value: &modelv1.FieldValue_Float{
+			Float: &modelv1.Float{Value: v + (after.value.GetFloat().GetValue()-v)*ratio},
+		}}
+	}
+	v := prev.value.GetInt().GetValue()
+	return &modelv1.FieldValue{Value: &modelv1.FieldValue_Int{
+		Int: &modelv1.Int{Value: v + int64(math.Round(float64(after.value.GetInt().GetValue()-v)*ratio))},
+	}}
+}
+
+type interpolationIterator struct {
+	dataPoints []*measurev1.DataPoint
+	index      int
+}
+
+func (ii *interpolationIterator) Next() bool {
+	ii.index++
+	return ii.index < len(ii.dataPoints)
+}
+
+func (ii *interpolationIterator) Current() []*measurev1.DataPoint {
+	return []*measurev1.DataPoint{ii.dataPoints[ii.index]}
+}
+
+func (ii *interpolationIterator) Close() error {
+	return nil
+}
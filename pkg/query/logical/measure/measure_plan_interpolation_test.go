@@ -0,0 +1,190 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/pkg/query/executor"
+	"github.com/apache/skywalking-banyandb/pkg/query/logical"
+)
+
+type rawPlan struct {
+	s          logical.Schema
+	dataPoints []*measurev1.DataPoint
+}
+
+func (r *rawPlan) Analyze(logical.Schema) (logical.Plan, error) { return r, nil }
+
+func (r *rawPlan) String() string { return "raw" }
+
+func (r *rawPlan) Children() []logical.Plan { return nil }
+
+func (r *rawPlan) Schema() logical.Schema { return r.s }
+
+func (r *rawPlan) Execute(context.Context) (executor.MIterator, error) {
+	return &interpolationIterator{dataPoints: r.dataPoints, index: -1}, nil
+}
+
+func TestInterpolation(t *testing.T) {
+	s, err := BuildSchema(&databasev1.Measure{
+		Metadata: &commonv1.Metadata{Group: "sw_metric", Name: "gauge"},
+		Entity:   &databasev1.Entity{TagNames: []string{"id"}},
+		TagFamilies: []*databasev1.TagFamilySpec{{
+			Name: "default",
+			Tags: []*databasev1.TagSpec{{Name: "id", Type: databasev1.TagType_TAG_TYPE_STRING}},
+		}},
+		Fields: []*databasev1.FieldSpec{
+			{Name: "value", FieldType: databasev1.FieldType_FIELD_TYPE_INT},
+			{Name: "ratio", FieldType: databasev1.FieldType_FIELD_TYPE_FLOAT},
+		},
+	}, nil)
+	require.NoError(t, err)
+	fieldRefs, err := s.CreateFieldRef(logical.NewField("value"), logical.NewField("ratio"))
+	require.NoError(t, err)
+	s = s.ProjFields(fieldRefs...)
+
+	begin := time.Unix(1700000000, 0)
+	point := func(id string, offset time.Duration, value int64, ratio float64) *measurev1.DataPoint {
+		return &measurev1.DataPoint{
+			Timestamp: timestamppb.New(begin.Add(offset)),
+			TagFamilies: []*modelv1.TagFamily{{
+				Name: "default",
+				Tags: []*modelv1.Tag{{Key: "id", Value: &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: id}}}}},
+			}},
+			Fields: []*measurev1.DataPoint_Field{
+				{Name: "value", Value: &modelv1.FieldValue{Value: &modelv1.FieldValue_Int{Int: &modelv1.Int{Value: value}}}},
+				{Name: "ratio", Value: &modelv1.FieldValue{Value: &modelv1.FieldValue_Float{Float: &modelv1.Float{Value: ratio}}}},
+			},
+		}
+	}
+	// the sparse series "a" has a 50s gap between 10s and 60s, and the raw points arrive out of order.
+	raw := []*measurev1.DataPoint{
+		point("a", 10*time.Second, 20, 0.2),
+		point("b", 10*time.Second, 7, 0.7),
+		point("a", 0, 10, 0.1),
+		point("a", 60*time.Second, 80, 0.8),
+	}
+
+	// null marks a missing point in the expected series.
+	const null = -1
+	tests := []struct {
+		name        string
+		method      measurev1.QueryRequest_Interpolation_Method
+		maxGap      time.Duration
+		wantA       []int64
+		wantARatios []float64
+		wantB       []int64
+	}{
+		{
+			name:        "linear",
+			method:      measurev1.QueryRequest_Interpolation_METHOD_LINEAR,
+			wantA:       []int64{10, 15, 20, 26, 32, 38, 44, 50, 56, 62, 68, 74, 80, null},
+			wantARatios: []float64{0.1, 0.15, 0.2, 0.26, 0.32, 0.38, 0.44, 0.5, 0.56, 0.62, 0.68, 0.74, 0.8, null},
+			wantB:       []int64{null, null, 7, null, null, null, null, null, null, null, null, null, null, null},
+		},
+		{
+			name:        "step",
+			method:      measurev1.QueryRequest_Interpolation_METHOD_STEP,
+			wantA:       []int64{10, 10, 20, 20, 20, 20, 20, 20, 20, 20, 20, 20, 80, null},
+			wantARatios: []float64{0.1, 0.1, 0.2, 0.2, 0.2, 0.2, 0.2, 0.2, 0.2, 0.2, 0.2, 0.2, 0.8, null},
+			wantB:       []int64{null, null, 7, null, null, null, null, null, null, null, null, null, null, null},
+		},
+		{
+			name:        "gap threshold",
+			method:      measurev1.QueryRequest_Interpolation_METHOD_LINEAR,
+			maxGap:      20 * time.Second,
+			wantA:       []int64{10, 15, 20, null, null, null, null, null, null, null, null, null, 80, null},
+			wantARatios: []float64{0.1, 0.15, 0.2, null, null, null, null, null, null, null, null, null, 0.8, null},
+			wantB:       []int64{null, null, 7, null, null, null, null, null, null, null, null, null, null, null},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unresolved := interpolation(&rawPlan{s: s, dataPoints: raw}, &measurev1.QueryRequest{
+				TimeRange:       &modelv1.TimeRange{Begin: timestamppb.New(begin), End: timestamppb.New(begin.Add(70 * time.Second))},
+				FieldProjection: &measurev1.QueryRequest_FieldProjection{Names: []string{"value", "ratio"}},
+				Interpolation: &measurev1.QueryRequest_Interpolation{
+					Method:   tt.method,
+					Interval: durationpb.New(5 * time.Second),
+					MaxGap:   durationpb.New(tt.maxGap),
+				},
+			})
+			plan, err := unresolved.Analyze(s)
+			require.NoError(t, err)
+			iter, err := plan.(executor.MeasureExecutable).Execute(context.Background())
+			require.NoError(t, err)
+			defer iter.Close()
+			got := map[string][]int64{}
+			var gotRatios []float64
+			for iter.Next() {
+				for _, dp := range iter.Current() {
+					id := dp.GetTagFamilies()[0].GetTags()[0].GetValue().GetStr().GetValue()
+					assert.Equal(t, begin.Add(time.Duration(len(got[id]))*5*time.Second).UnixNano(), dp.GetTimestamp().AsTime().UnixNano())
+					value, ratio := int64(null), float64(null)
+					if v := dp.GetFields()[0].GetValue().GetInt(); v != nil {
+						value = v.GetValue()
+					}
+					if v := dp.GetFields()[1].GetValue().GetFloat(); v != nil {
+						ratio = v.GetValue()
+					}
+					got[id] = append(got[id], value)
+					if id == "a" {
+						gotRatios = append(gotRatios, ratio)
+					}
+				}
+			}
+			assert.Equal(t, tt.wantA, got["a"])
+			assert.InDeltaSlice(t, tt.wantARatios, gotRatios, 1e-9)
+			assert.Equal(t, tt.wantB, got["b"])
+		})
+	}
+}
+
+func TestInterpolationValidation(t *testing.T) {
+	s, err := BuildSchema(&databasev1.Measure{
+		Metadata: &commonv1.Metadata{Group: "sw_metric", Name: "gauge"},
+		Entity:   &databasev1.Entity{TagNames: []string{"id"}},
+		Fields:   []*databasev1.FieldSpec{{Name: "name", FieldType: databasev1.FieldType_FIELD_TYPE_STRING}},
+	}, nil)
+	require.NoError(t, err)
+	begin := time.Unix(1700000000, 0)
+	analyze := func(method measurev1.QueryRequest_Interpolation_Method, interval time.Duration) error {
+		_, err := interpolation(&rawPlan{s: s}, &measurev1.QueryRequest{
+			TimeRange:       &modelv1.TimeRange{Begin: timestamppb.New(begin), End: timestamppb.New(begin.Add(time.Hour))},
+			FieldProjection: &measurev1.QueryRequest_FieldProjection{Names: []string{"name"}},
+			Interpolation:   &measurev1.QueryRequest_Interpolation{Method: method, Interval: durationpb.New(interval)},
+		}).Analyze(s)
+		return err
+	}
+	assert.ErrorIs(t, analyze(measurev1.QueryRequest_Interpolation_METHOD_LINEAR, time.Minute), errUnsupportedInterpolation)
+	assert.NoError(t, analyze(measurev1.QueryRequest_Interpolation_METHOD_STEP, time.Minute))
+	assert.ErrorIs(t, analyze(measurev1.QueryRequest_Interpolation_METHOD_STEP, 0), errUnsupportedInterpolation)
+	assert.ErrorIs(t, analyze(measurev1.QueryRequest_Interpolation_METHOD_STEP, time.Millisecond), errUnsupportedInterpolation)
+}
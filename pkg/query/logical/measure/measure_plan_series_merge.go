@@ -0,0 +1,255 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"google.golang.org/protobuf/proto"
+
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/query/aggregation"
+	"github.com/apache/skywalking-banyandb/pkg/query/executor"
+	"github.com/apache/skywalking-banyandb/pkg/query/logical"
+)
+
+var (
+	_ logical.UnresolvedPlan = (*unresolvedSeriesMerge)(nil)
+	_ logical.Plan           = (*seriesMergePlan)(nil)
+
+	errUnsupportedSeriesMerge = errors.New("unsupported series merge")
+)
+
+type unresolvedSeriesMerge struct {
+	unresolvedInput logical.UnresolvedPlan
+	merge           *measurev1.QueryRequest_SeriesMerge
+	fields          []*logical.Field
+}
+
+func seriesMerge(input logical.UnresolvedPlan, criteria *measurev1.QueryRequest) logical.UnresolvedPlan {
+	fields := make([]*logical.Field, len(criteria.GetFieldProjection().GetNames()))
+	for i, name := range criteria.GetFieldProjection().GetNames() {
+		fields[i] = logical.NewField(name)
+	}
+	return &unresolvedSeriesMerge{
+		unresolvedInput: input,
+		merge:           criteria.GetSeriesMerge(),
+		fields:          fields,
+	}
+}
+
+func (um *unresolvedSeriesMerge) Analyze(measureSchema logical.Schema) (logical.Plan, error) {
+	prevPlan, err := um.unresolvedInput.Analyze(measureSchema)
+	if err != nil {
+		return nil, err
+	}
+	entity := measureSchema.EntityList()
+	prefixLength := int(um.merge.GetEntityPrefixLength())
+	if prefixLength < 1 || prefixLength > len(entity) {
+		return nil, errors.WithMessagef(errUnsupportedSeriesMerge,
+			"entity prefix length %d should be between 1 and the number of entity tags %d", prefixLength, len(entity))
+	}
+	if _, err = aggregation.NewFunc[int64](um.merge.GetFunction()); err != nil {
+		return nil, err
+	}
+	prefixRefs, err := prevPlan.Schema().CreateTagRef(logical.NewTags("", entity[:prefixLength]...))
+	if err != nil {
+		return nil, errors.WithMessage(err, "the entity prefix of the series merge should be projected")
+	}
+	fieldRefs, err := prevPlan.Schema().CreateFieldRef(um.fields...)
+	if err != nil {
+		return nil, err
+	}
+	if len(fieldRefs) == 0 {
+		return nil, errors.Wrap(errFieldNotDefined, "series merge schema")
+	}
+	for _, ref := range fieldRefs {
+		switch ref.Spec.Spec.FieldType {
+		case databasev1.FieldType_FIELD_TYPE_INT, databasev1.FieldType_FIELD_TYPE_FLOAT:
+		default:
+			return nil, errors.WithMessagef(errUnsupportedSeriesMerge, "merge on field: %s", ref.Spec.Spec)
+		}
+	}
+	return &seriesMergePlan{
+		Parent: &logical.Parent{
+			UnresolvedInput: um.unresolvedInput,
+			Input:           prevPlan,
+		},
+		prefixRefs: prefixRefs[0],
+		fieldRefs:  fieldRefs,
+		function:   um.merge.GetFunction(),
+	}, nil
+}
+
+type seriesMergePlan struct {
+	*logical.Parent
+	prefixRefs []*logical.TagRef
+	fieldRefs  []*logical.FieldRef
+	function   modelv1.AggregationFunction
+}
+
+func (mp *seriesMergePlan) String() string {
+	return fmt.Sprintf("%s series merge: function=%s,entityPrefixLength=%d", mp.Input,
+		modelv1.AggregationFunction_name[int32(mp.function)], len(mp.prefixRefs))
+}
+
+func (mp *seriesMergePlan) Children() []logical.Plan {
+	return []logical.Plan{mp.Input}
+}
+
+func (mp *seriesMergePlan) Schema() logical.Schema {
+	return mp.Input.Schema()
+}
+
+func (mp *seriesMergePlan) Execute(ec context.Context) (mit executor.MIterator, err error) {
+	iter, err := mp.Parent.Input.(executor.MeasureExecutable).Execute(ec)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = multierr.Append(err, iter.Close())
+	}()
+	var seriesList []*mergedSeries
+	seriesMap := make(map[string]*mergedSeries)
+	for iter.Next() {
+		for _, dp := range iter.Current() {
+			key, err := mp.prefixKey(dp)
+			if err != nil {
+				return nil, err
+			}
+			s, ok := seriesMap[key]
+			if !ok {
+				s = &mergedSeries{tagFamilies: mp.maskTags(dp), points: make(map[int64][]*measurev1.DataPoint)}
+				seriesMap[key] = s
+				seriesList = append(seriesList, s)
+			}
+			ts := dp.GetTimestamp().AsTime().UnixNano()
+			if _, ok := s.points[ts]; !ok {
+				s.timestamps = append(s.timestamps, ts)
+			}
+			s.points[ts] = append(s.points[ts], dp)
+		}
+	}
+	var result []*measurev1.DataPoint
+	for _, s := range seriesList {
+		sort.Slice(s.timestamps, func(i, j int) bool { return s.timestamps[i] < s.timestamps[j] })
+		for _, ts := range s.timestamps {
+			dp, err := mp.merge(s.tagFamilies, s.points[ts])
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, dp)
+		}
+	}
+	return &interpolationIterator{dataPoints: result, index: -1}, nil
+}
+
+// mergedSeries is the series sharing an entity prefix, whose points are grouped by their timestamps.
+type mergedSeries struct {
+	points      map[int64][]*measurev1.DataPoint
+	tagFamilies []*modelv1.TagFamily
+	timestamps  []int64
+}
+
+func (mp *seriesMergePlan) prefixKey(dp *measurev1.DataPoint) (string, error) {
+	var key []byte
+	for _, ref := range mp.prefixRefs {
+		b, err := proto.MarshalOptions{Deterministic: true}.Marshal(
+			dp.GetTagFamilies()[ref.Spec.TagFamilyIdx].GetTags()[ref.Spec.TagIdx].GetValue())
+		if err != nil {
+			return "", err
+		}
+		key = append(key, b...)
+		// the separator tells apart the prefixes whose values are split differently
+		key = append(key, 0)
+	}
+	return string(key), nil
+}
+
+// maskTags copies the tags of a point, replacing the ones out of the entity prefix with nulls.
+func (mp *seriesMergePlan) maskTags(dp *measurev1.DataPoint) []*modelv1.TagFamily {
+	tagFamilies := make([]*modelv1.TagFamily, len(dp.GetTagFamilies()))
+	for i, tf := range dp.GetTagFamilies() {
+		tags := make([]*modelv1.Tag, len(tf.GetTags()))
+		for j, t := range tf.GetTags() {
+			tags[j] = &modelv1.Tag{Key: t.GetKey(), Value: pbv1.NullTagValue}
+		}
+		tagFamilies[i] = &modelv1.TagFamily{Name: tf.GetName(), Tags: tags}
+	}
+	for _, ref := range mp.prefixRefs {
+		tagFamilies[ref.Spec.TagFamilyIdx].Tags[ref.Spec.TagIdx].Value =
+			dp.GetTagFamilies()[ref.Spec.TagFamilyIdx].GetTags()[ref.Spec.TagIdx].GetValue()
+	}
+	return tagFamilies
+}
+
+func (mp *seriesMergePlan) merge(tagFamilies []*modelv1.TagFamily, points []*measurev1.DataPoint) (*measurev1.DataPoint, error) {
+	result := &measurev1.DataPoint{
+		Timestamp:   points[0].GetTimestamp(),
+		TagFamilies: tagFamilies,
+		Fields:      make([]*measurev1.DataPoint_Field, len(mp.fieldRefs)),
+	}
+	for i, ref := range mp.fieldRefs {
+		var value *modelv1.FieldValue
+		var err error
+		if ref.Spec.Spec.FieldType == databasev1.FieldType_FIELD_TYPE_FLOAT {
+			value, err = mergeField[float64](mp.function, ref, points)
+		} else {
+			value, err = mergeField[int64](mp.function, ref, points)
+		}
+		if err != nil {
+			return nil, err
+		}
+		result.Fields[i] = &measurev1.DataPoint_Field{Name: ref.Field.Name, Value: value}
+	}
+	return result, nil
+}
+
+// mergeField aggregates the non-null values of a field. It returns a null if all of them are nulls.
+func mergeField[N aggregation.Number](function modelv1.AggregationFunction, ref *logical.FieldRef,
+	points []*measurev1.DataPoint,
+) (*modelv1.FieldValue, error) {
+	fn, err := aggregation.NewFunc[N](function)
+	if err != nil {
+		return nil, err
+	}
+	var merged bool
+	for _, dp := range points {
+		v := dp.GetFields()[ref.Spec.FieldIdx].GetValue()
+		if _, isNull := v.GetValue().(*modelv1.FieldValue_Null); v == nil || isNull {
+			continue
+		}
+		n, err := aggregation.FromFieldValue[N](v)
+		if err != nil {
+			return nil, err
+		}
+		fn.In(n)
+		merged = true
+	}
+	if !merged {
+		return pbv1.NullFieldValue, nil
+	}
+	return aggregation.ToFieldValue(fn.Val())
+}
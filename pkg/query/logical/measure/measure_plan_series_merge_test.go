@@ -0,0 +1,193 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/query/executor"
+	"github.com/apache/skywalking-banyandb/pkg/query/logical"
+)
+
+func instanceSchema(t *testing.T) logical.Schema {
+	s, err := BuildSchema(&databasev1.Measure{
+		Metadata: &commonv1.Metadata{Group: "sw_metric", Name: "instance_cpm"},
+		Entity:   &databasev1.Entity{TagNames: []string{"service", "instance"}},
+		TagFamilies: []*databasev1.TagFamilySpec{{
+			Name: "default",
+			Tags: []*databasev1.TagSpec{
+				{Name: "service", Type: databasev1.TagType_TAG_TYPE_STRING},
+				{Name: "instance", Type: databasev1.TagType_TAG_TYPE_STRING},
+			},
+		}},
+		Fields: []*databasev1.FieldSpec{
+			{Name: "value", FieldType: databasev1.FieldType_FIELD_TYPE_INT},
+			{Name: "ratio", FieldType: databasev1.FieldType_FIELD_TYPE_FLOAT},
+			{Name: "name", FieldType: databasev1.FieldType_FIELD_TYPE_STRING},
+		},
+	}, nil)
+	require.NoError(t, err)
+	return s
+}
+
+func TestSeriesMerge(t *testing.T) {
+	s := instanceSchema(t)
+	tagRefs, err := s.CreateTagRef(logical.NewTags("default", "service", "instance"))
+	require.NoError(t, err)
+	fieldRefs, err := s.CreateFieldRef(logical.NewField("value"), logical.NewField("ratio"))
+	require.NoError(t, err)
+	s = s.ProjTags(tagRefs...).ProjFields(fieldRefs...)
+
+	begin := time.Unix(1700000000, 0)
+	str := func(v string) *modelv1.TagValue {
+		return &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: v}}}
+	}
+	point := func(service, instance string, minute int, value int64, ratio float64) *measurev1.DataPoint {
+		return &measurev1.DataPoint{
+			Timestamp: timestamppb.New(begin.Add(time.Duration(minute) * time.Minute)),
+			TagFamilies: []*modelv1.TagFamily{{
+				Name: "default",
+				Tags: []*modelv1.Tag{{Key: "service", Value: str(service)}, {Key: "instance", Value: str(instance)}},
+			}},
+			Fields: []*measurev1.DataPoint_Field{
+				{Name: "value", Value: &modelv1.FieldValue{Value: &modelv1.FieldValue_Int{Int: &modelv1.Int{Value: value}}}},
+				{Name: "ratio", Value: &modelv1.FieldValue{Value: &modelv1.FieldValue_Float{Float: &modelv1.Float{Value: ratio}}}},
+			},
+		}
+	}
+	var raw []*measurev1.DataPoint
+	// expected holds the per-minute values of every instance of the service "svc".
+	expected := map[int][]int64{}
+	expectedRatios := map[int][]float64{}
+	for i := 0; i < 4; i++ {
+		instance := fmt.Sprintf("instance-%d", i)
+		for minute := 2; minute >= 0; minute-- {
+			// the last instance misses the second minute
+			if i == 3 && minute == 1 {
+				continue
+			}
+			value, ratio := int64(10*i+minute), float64(i)/10+float64(minute)+1
+			raw = append(raw, point("svc", instance, minute, value, ratio))
+			expected[minute] = append(expected[minute], value)
+			expectedRatios[minute] = append(expectedRatios[minute], ratio)
+		}
+	}
+	raw = append(raw, point("other", "instance-0", 0, 1000, 1000))
+
+	tests := []struct {
+		sumInt   func([]int64) int64
+		sumFloat func([]float64) float64
+		name     string
+		function modelv1.AggregationFunction
+	}{
+		{
+			name:     "sum",
+			function: modelv1.AggregationFunction_AGGREGATION_FUNCTION_SUM,
+			sumInt: func(values []int64) (r int64) {
+				for _, v := range values {
+					r += v
+				}
+				return r
+			},
+			sumFloat: func(values []float64) (r float64) {
+				for _, v := range values {
+					r += v
+				}
+				return r
+			},
+		},
+		{
+			name:     "mean",
+			function: modelv1.AggregationFunction_AGGREGATION_FUNCTION_MEAN,
+			sumInt: func(values []int64) (r int64) {
+				for _, v := range values {
+					r += v
+				}
+				return r / int64(len(values))
+			},
+			sumFloat: func(values []float64) (r float64) {
+				for _, v := range values {
+					r += v
+				}
+				return r / float64(len(values))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := seriesMerge(&rawPlan{s: s, dataPoints: raw}, &measurev1.QueryRequest{
+				FieldProjection: &measurev1.QueryRequest_FieldProjection{Names: []string{"value", "ratio"}},
+				SeriesMerge:     &measurev1.QueryRequest_SeriesMerge{Function: tt.function, EntityPrefixLength: 1},
+			}).Analyze(s)
+			require.NoError(t, err)
+			iter, err := plan.(executor.MeasureExecutable).Execute(context.Background())
+			require.NoError(t, err)
+			defer iter.Close()
+			var minutes []int
+			for iter.Next() {
+				for _, dp := range iter.Current() {
+					tags := dp.GetTagFamilies()[0].GetTags()
+					assert.Equal(t, pbv1.NullTagValue, tags[1].GetValue(), "the instances are merged")
+					minute := int(dp.GetTimestamp().AsTime().Sub(begin) / time.Minute)
+					if tags[0].GetValue().GetStr().GetValue() == "other" {
+						assert.Equal(t, int64(1000), dp.GetFields()[0].GetValue().GetInt().GetValue())
+						continue
+					}
+					minutes = append(minutes, minute)
+					assert.Equal(t, tt.sumInt(expected[minute]), dp.GetFields()[0].GetValue().GetInt().GetValue(), "minute %d", minute)
+					assert.InDelta(t, tt.sumFloat(expectedRatios[minute]), dp.GetFields()[1].GetValue().GetFloat().GetValue(), 1e-9, "minute %d", minute)
+				}
+			}
+			assert.Equal(t, []int{0, 1, 2}, minutes, "one point per minute in the time order")
+		})
+	}
+}
+
+func TestSeriesMergeValidation(t *testing.T) {
+	s := instanceSchema(t)
+	tagRefs, err := s.CreateTagRef(logical.NewTags("default", "service"))
+	require.NoError(t, err)
+	s = s.ProjTags(tagRefs...)
+	analyze := func(prefixLength uint32, fields ...string) error {
+		_, err := seriesMerge(&rawPlan{s: s}, &measurev1.QueryRequest{
+			FieldProjection: &measurev1.QueryRequest_FieldProjection{Names: fields},
+			SeriesMerge: &measurev1.QueryRequest_SeriesMerge{
+				Function:           modelv1.AggregationFunction_AGGREGATION_FUNCTION_SUM,
+				EntityPrefixLength: prefixLength,
+			},
+		}).Analyze(s)
+		return err
+	}
+	assert.NoError(t, analyze(1, "value"))
+	assert.ErrorIs(t, analyze(0, "value"), errUnsupportedSeriesMerge)
+	assert.ErrorIs(t, analyze(3, "value"), errUnsupportedSeriesMerge)
+	assert.Error(t, analyze(2, "value"), "the instance tag isn't projected")
+	assert.ErrorIs(t, analyze(1, "name"), errUnsupportedSeriesMerge)
+}
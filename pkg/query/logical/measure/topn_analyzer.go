@@ -68,6 +68,10 @@ func TopNAnalyze(_ context.Context, criteria *measurev1.TopNRequest, schema *dat
 	projectionFields[0] = logical.NewField(fieldName)
 	// parse fields
 	plan := parse(criteria, schema.GetMetadata(), projectionFields, groupByTags)
+	// The entries to roll up are ranked by their buckets after the plan.
+	if criteria.GetRollupInterval() != nil {
+		return plan.Analyze(s)
+	}
 
 	if criteria.GetAgg() != 0 {
 		plan = newUnresolvedGroupBy(plan, groupByTags, false)
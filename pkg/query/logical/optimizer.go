@@ -63,22 +63,26 @@ var _ OptimizeRule = (*PushDownOrder)(nil)
 
 // PushDownOrder pushes down the order to a Plan.
 type PushDownOrder struct {
-	order *modelv1.QueryOrder
+	order  *modelv1.QueryOrder
+	thenBy []*modelv1.QueryOrder
 }
 
-// NewPushDownOrder returns a new PushDownOrder.
-func NewPushDownOrder(order *modelv1.QueryOrder) PushDownOrder {
-	return PushDownOrder{order: order}
+// NewPushDownOrder returns a new PushDownOrder. The thenBy orders break the ties of the order.
+func NewPushDownOrder(order *modelv1.QueryOrder, thenBy ...*modelv1.QueryOrder) PushDownOrder {
+	return PushDownOrder{order: order, thenBy: thenBy}
 }
 
 // Optimize a Plan by pushing down the query order.
 func (pdo PushDownOrder) Optimize(plan Plan) (Plan, error) {
 	if v, ok := plan.(Sorter); ok {
-		if order, err := ParseOrderBy(v.Schema(), pdo.order.GetIndexRuleName(), pdo.order.GetSort()); err == nil {
-			v.Sort(order)
-		} else {
+		order, err := ParseOrderBy(v.Schema(), pdo.order.GetIndexRuleName(), pdo.order.GetSort())
+		if err != nil {
 			return nil, err
 		}
+		if err = ParseThenBy(v.Schema(), order, pdo.thenBy); err != nil {
+			return nil, err
+		}
+		v.Sort(order)
 	}
 	return plan, nil
 }
@@ -19,6 +19,7 @@ package logical
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/pkg/errors"
 
@@ -36,7 +37,9 @@ type Parent struct {
 type OrderBy struct {
 	Index     *databasev1.IndexRule
 	fieldRefs []*TagRef
-	Sort      modelv1.Sort
+	// ThenBy breaks the ties of the index in order.
+	ThenBy []*OrderBy
+	Sort   modelv1.Sort
 }
 
 // Equal reports whether o and other has the same sorting order and name.
@@ -48,8 +51,17 @@ func (o *OrderBy) Equal(other interface{}) bool {
 		if o != nil && otherOrderBy == nil || o == nil && otherOrderBy != nil {
 			return false
 		}
-		return o.Sort == otherOrderBy.Sort &&
-			o.Index.GetMetadata().GetName() == otherOrderBy.Index.GetMetadata().GetName()
+		if o.Sort != otherOrderBy.Sort ||
+			o.Index.GetMetadata().GetName() != otherOrderBy.Index.GetMetadata().GetName() ||
+			len(o.ThenBy) != len(otherOrderBy.ThenBy) {
+			return false
+		}
+		for i := range o.ThenBy {
+			if !o.ThenBy[i].Equal(otherOrderBy.ThenBy[i]) {
+				return false
+			}
+		}
+		return true
 	}
 
 	return false
@@ -57,7 +69,14 @@ func (o *OrderBy) Equal(other interface{}) bool {
 
 // Strings shows the string represent.
 func (o *OrderBy) String() string {
-	return fmt.Sprintf("OrderBy: %v, sort=%s", o.Index.GetTags(), o.Sort.String())
+	if len(o.ThenBy) == 0 {
+		return fmt.Sprintf("OrderBy: %v, sort=%s", o.Index.GetTags(), o.Sort.String())
+	}
+	thenBy := make([]string, len(o.ThenBy))
+	for i := range o.ThenBy {
+		thenBy[i] = fmt.Sprintf("%v %s", o.ThenBy[i].Index.GetTags(), o.ThenBy[i].Sort.String())
+	}
+	return fmt.Sprintf("OrderBy: %v, sort=%s, thenBy=%s", o.Index.GetTags(), o.Sort.String(), strings.Join(thenBy, ","))
 }
 
 // ParseOrderBy parses an OrderBy from a Schema.
@@ -84,3 +103,24 @@ func ParseOrderBy(s Schema, indexRuleName string, sort modelv1.Sort) (*OrderBy,
 		fieldRefs: projFieldSpecs[0],
 	}, nil
 }
+
+// ParseThenBy parses the keys breaking the ties of the order, which has to sort by an index.
+func ParseThenBy(s Schema, order *OrderBy, thenBy []*modelv1.QueryOrder) error {
+	if len(thenBy) == 0 {
+		return nil
+	}
+	if order.Index == nil {
+		return errors.Wrap(errInvalidThenBy, "the order doesn't sort by an index")
+	}
+	for _, o := range thenBy {
+		if o.GetIndexRuleName() == "" {
+			return errors.Wrap(errInvalidThenBy, "the index rule is absent")
+		}
+		parsed, err := ParseOrderBy(s, o.GetIndexRuleName(), o.GetSort())
+		if err != nil {
+			return err
+		}
+		order.ThenBy = append(order.ThenBy, parsed)
+	}
+	return nil
+}
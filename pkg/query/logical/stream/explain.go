@@ -0,0 +1,107 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"github.com/apache/skywalking-banyandb/pkg/query/logical"
+)
+
+// Order strategies of a scan.
+const (
+	OrderByIndex     = "index"
+	OrderByTimestamp = "timestamp"
+)
+
+// Explanation describes how a stream query is planned, without executing it.
+// It's serialized to JSON for logging.
+type Explanation struct {
+	Order *OrderExplanation `json:"order"`
+	Group string            `json:"group"`
+	Name  string            `json:"name"`
+	// IndexFilter is the condition pushed down into the index, empty if there is none.
+	IndexFilter string `json:"index_filter,omitempty"`
+	// TagFilter is the condition checked against the scanned elements, empty if the index filter covers all the conditions.
+	TagFilter  string `json:"tag_filter,omitempty"`
+	Projection string `json:"projection,omitempty"`
+	Plan       string `json:"plan"`
+	// Entities is the number of the entity patterns looked up in the series index.
+	Entities int `json:"entities"`
+	// Limit is the number of elements the scan stops at, 0 means it scans all the matched elements.
+	Limit int `json:"limit,omitempty"`
+}
+
+// OrderExplanation describes how the scanned elements are sorted.
+type OrderExplanation struct {
+	// IndexRule is the index rule sorting the elements, empty if they're sorted by their timestamps.
+	IndexRule string   `json:"index_rule,omitempty"`
+	IndexType string   `json:"index_type,omitempty"`
+	Strategy  string   `json:"strategy"`
+	Sort      string   `json:"sort"`
+	ThenBy    []string `json:"then_by,omitempty"`
+}
+
+// Explain describes the plan built by Analyze. It returns nil if the plan doesn't scan locally,
+// such as the plan built by DistributedAnalyze, whose scans happen on the data nodes.
+func Explain(plan logical.Plan) *Explanation {
+	var scan *localIndexScan
+	var tagFilters []string
+	for p := plan; p != nil && scan == nil; {
+		switch t := p.(type) {
+		case *localIndexScan:
+			scan = t
+			continue
+		case *tagFilterPlan:
+			tagFilters = append(tagFilters, t.tagFilter.String())
+		}
+		children := p.Children()
+		if len(children) == 0 {
+			return nil
+		}
+		p = children[0]
+	}
+	if scan == nil {
+		return nil
+	}
+	e := &Explanation{
+		Group:      scan.metadata.GetGroup(),
+		Name:       scan.metadata.GetName(),
+		Projection: logical.FormatTagRefs(", ", scan.projectionTagRefs...),
+		Plan:       plan.String(),
+		Entities:   len(scan.entities),
+		Limit:      scan.maxElementSize,
+		Order:      &OrderExplanation{Strategy: OrderByTimestamp},
+	}
+	if len(tagFilters) > 0 {
+		e.TagFilter = tagFilters[0]
+	}
+	if scan.filter != nil && scan.filter != logical.ENode {
+		e.IndexFilter = scan.filter.String()
+	}
+	if o := scan.order; o != nil {
+		e.Order.Sort = o.Sort.String()
+		if o.Index != nil {
+			e.Order.Strategy = OrderByIndex
+			e.Order.IndexRule = o.Index.GetMetadata().GetName()
+			e.Order.IndexType = o.Index.GetType().String()
+		}
+		for _, t := range o.ThenBy {
+			e.Order.ThenBy = append(e.Order.ThenBy, t.Index.GetMetadata().GetName()+" "+t.Sort.String())
+		}
+	}
+	return e
+}
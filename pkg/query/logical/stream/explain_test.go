@@ -0,0 +1,93 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/pkg/query/logical"
+)
+
+// swSchema is a trimmed schema of the stream sw in pkg/test/stream/testdata.
+func swSchema(t *testing.T) logical.Schema {
+	s, err := BuildSchema(&databasev1.Stream{
+		Metadata: &commonv1.Metadata{Group: "default", Name: "sw"},
+		Entity:   &databasev1.Entity{TagNames: []string{"service_id"}},
+		TagFamilies: []*databasev1.TagFamilySpec{
+			{Name: "data", Tags: []*databasev1.TagSpec{{Name: "data_binary", Type: databasev1.TagType_TAG_TYPE_DATA_BINARY}}},
+			{Name: "searchable", Tags: []*databasev1.TagSpec{
+				{Name: "trace_id", Type: databasev1.TagType_TAG_TYPE_STRING},
+				{Name: "service_id", Type: databasev1.TagType_TAG_TYPE_STRING},
+				{Name: "duration", Type: databasev1.TagType_TAG_TYPE_INT},
+			}},
+		},
+	}, []*databasev1.IndexRule{{
+		Metadata: &commonv1.Metadata{Group: "default", Name: "duration", Id: 3},
+		Tags:     []string{"duration"},
+		Type:     databasev1.IndexRule_TYPE_INVERTED,
+	}})
+	require.NoError(t, err)
+	return s
+}
+
+func TestExplainOrderByDuration(t *testing.T) {
+	metadata := &commonv1.Metadata{Group: "default", Name: "sw"}
+	s := swSchema(t)
+
+	// It mirrors the "order by duration" case in test/cases/stream/data/input/sort_desc.yaml.
+	now := time.Now()
+	plan, err := Analyze(context.Background(), &streamv1.QueryRequest{
+		Groups:    []string{"default"},
+		Name:      "sw",
+		TimeRange: &modelv1.TimeRange{Begin: timestamppb.New(now.Add(-time.Hour)), End: timestamppb.New(now)},
+		Projection: &modelv1.TagProjection{TagFamilies: []*modelv1.TagProjection_TagFamily{
+			{Name: "searchable", Tags: []string{"trace_id", "duration"}},
+			{Name: "data", Tags: []string{"data_binary"}},
+		}},
+		OrderBy: &modelv1.QueryOrder{IndexRuleName: "duration", Sort: modelv1.Sort_SORT_DESC},
+	}, metadata, s)
+	require.NoError(t, err)
+
+	e := Explain(plan)
+	require.NotNil(t, e)
+	assert.Equal(t, "default", e.Group)
+	assert.Equal(t, "sw", e.Name)
+	assert.Equal(t, OrderByIndex, e.Order.Strategy)
+	assert.Equal(t, "duration", e.Order.IndexRule)
+	assert.Equal(t, databasev1.IndexRule_TYPE_INVERTED.String(), e.Order.IndexType)
+	assert.Equal(t, modelv1.Sort_SORT_DESC.String(), e.Order.Sort)
+	assert.Empty(t, e.IndexFilter)
+	assert.Empty(t, e.TagFilter)
+
+	b, err := json.Marshal(e)
+	require.NoError(t, err)
+	var decoded Explanation
+	require.NoError(t, json.Unmarshal(b, &decoded))
+	assert.Equal(t, *e.Order, *decoded.Order)
+}
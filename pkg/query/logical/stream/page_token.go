@@ -0,0 +1,169 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/query/logical"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+var errInvalidPageToken = errors.New("invalid page token")
+
+// pageToken is the position of the last element of a page. The elements are sorted by their timestamps,
+// then by their IDs ascending in the order of pbv1.CompareElementID, so the position doesn't move when new elements are written before it.
+type pageToken struct {
+	ElementID string `json:"id"`
+	Begin     int64  `json:"begin"`
+	End       int64  `json:"end"`
+	Timestamp int64  `json:"ts"`
+	// Ties is the number of the returned elements sharing the timestamp of the position.
+	Ties int  `json:"ties"`
+	Desc bool `json:"desc"`
+}
+
+func (pt *pageToken) encode() (string, error) {
+	b, err := json.Marshal(pt)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// parsePageToken returns the page token of the query, nil if the query starts from the first page.
+func parsePageToken(criteria *streamv1.QueryRequest) (*pageToken, error) {
+	if criteria.GetPageToken() == "" {
+		return nil, nil
+	}
+	if !pageable(criteria) {
+		return nil, fmt.Errorf("%w: only the queries sorted by timestamps are paged", errInvalidPageToken)
+	}
+	b, err := base64.RawURLEncoding.DecodeString(criteria.GetPageToken())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidPageToken, err)
+	}
+	pt := &pageToken{}
+	if err = json.Unmarshal(b, pt); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidPageToken, err)
+	}
+	tr := criteria.GetTimeRange()
+	if pt.Begin != tr.GetBegin().AsTime().UnixNano() || pt.End != tr.GetEnd().AsTime().UnixNano() || pt.Desc != sortDesc(criteria) {
+		return nil, fmt.Errorf("%w: the time range or the order differs from the previous page", errInvalidPageToken)
+	}
+	return pt, nil
+}
+
+func pageable(criteria *streamv1.QueryRequest) bool {
+	return criteria.GetOrderBy().GetIndexRuleName() == "" && criteria.GetTopKPerGroup() == nil
+}
+
+func sortDesc(criteria *streamv1.QueryRequest) bool {
+	return criteria.GetOrderBy().GetSort() == modelv1.Sort_SORT_DESC
+}
+
+// NextPageToken returns the token resuming the query after the elements it returns.
+// It's empty if the elements don't fill the limit, or the query isn't sorted by timestamps.
+func NextPageToken(criteria *streamv1.QueryRequest, elements []*streamv1.Element) (string, error) {
+	limit := criteria.GetLimit()
+	if limit == 0 {
+		limit = defaultLimit
+	}
+	if len(elements) == 0 || len(elements) < int(limit) || !pageable(criteria) {
+		return "", nil
+	}
+	prev, err := parsePageToken(criteria)
+	if err != nil {
+		return "", err
+	}
+	last := elements[len(elements)-1]
+	tr := criteria.GetTimeRange()
+	pt := &pageToken{
+		Begin:     tr.GetBegin().AsTime().UnixNano(),
+		End:       tr.GetEnd().AsTime().UnixNano(),
+		Desc:      sortDesc(criteria),
+		Timestamp: last.GetTimestamp().AsTime().UnixNano(),
+		ElementID: last.GetElementId(),
+	}
+	for i := len(elements) - 1; i >= 0 && elements[i].GetTimestamp().AsTime().UnixNano() == pt.Timestamp; i-- {
+		pt.Ties++
+	}
+	if prev != nil && pt.Ties == len(elements) && prev.Timestamp == pt.Timestamp {
+		pt.Ties += prev.Ties
+	}
+	return pt.encode()
+}
+
+// resume narrows the time range to the elements from the position on.
+func (pt *pageToken) resume(tr timestamp.TimeRange) timestamp.TimeRange {
+	if pt == nil {
+		return tr
+	}
+	position := time.Unix(0, pt.Timestamp)
+	if pt.Desc {
+		return timestamp.NewTimeRange(tr.Start, position, tr.IncludeStart, true)
+	}
+	return timestamp.NewTimeRange(position, tr.End, true, tr.IncludeEnd)
+}
+
+// skip drops the elements up to the position.
+func (pt *pageToken) skip(elements []*streamv1.Element) []*streamv1.Element {
+	if pt == nil {
+		return elements
+	}
+	result := elements[:0]
+	for _, e := range elements {
+		ts := e.GetTimestamp().AsTime().UnixNano()
+		if ts == pt.Timestamp && pbv1.CompareElementID(e.GetElementId(), pt.ElementID) <= 0 {
+			continue
+		}
+		if pt.Desc && ts > pt.Timestamp || !pt.Desc && ts < pt.Timestamp {
+			continue
+		}
+		result = append(result, e)
+	}
+	return result
+}
+
+func (pt *pageToken) ties() int {
+	if pt == nil {
+		return 0
+	}
+	return pt.Ties
+}
+
+var _ logical.OptimizeRule = pushDownPageToken{}
+
+// pushDownPageToken resumes the local scans from the position of the page token.
+type pushDownPageToken struct {
+	token *pageToken
+}
+
+func (pdp pushDownPageToken) Optimize(plan logical.Plan) (logical.Plan, error) {
+	if v, ok := plan.(*localIndexScan); ok {
+		v.after = pdp.token
+	}
+	return plan, nil
+}
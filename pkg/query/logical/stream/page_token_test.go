@@ -0,0 +1,178 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/query/executor"
+)
+
+type storedElement struct {
+	id string
+	ts int64
+}
+
+// elementStore serves the queries sorted by timestamps from the stored elements.
+type elementStore struct {
+	elements []storedElement
+}
+
+func (es *elementStore) Query(_ context.Context, opts pbv1.StreamQueryOptions) (pbv1.StreamQueryResult, error) {
+	r := &pbv1.StreamResult{}
+	for _, e := range es.elements {
+		if opts.TimeRange.Contains(e.ts) {
+			r.Timestamps = append(r.Timestamps, e.ts)
+			r.ElementIDs = append(r.ElementIDs, e.id)
+		}
+	}
+	desc := opts.Order != nil && opts.Order.Sort == modelv1.Sort_SORT_DESC
+	idx := make([]int, len(r.Timestamps))
+	for i := range idx {
+		idx[i] = i
+	}
+	slices.SortStableFunc(idx, func(a, b int) int {
+		if desc {
+			return int(r.Timestamps[b] - r.Timestamps[a])
+		}
+		return int(r.Timestamps[a] - r.Timestamps[b])
+	})
+	sorted := &pbv1.StreamResult{}
+	for _, i := range idx {
+		sorted.Timestamps = append(sorted.Timestamps, r.Timestamps[i])
+		sorted.ElementIDs = append(sorted.ElementIDs, r.ElementIDs[i])
+	}
+	return &storedResult{r: sorted}, nil
+}
+
+func (es *elementStore) Sort(context.Context, pbv1.StreamQueryOptions) (pbv1.StreamSortResult, error) {
+	return nil, errors.New("unsupported")
+}
+
+func (es *elementStore) Filter(context.Context, pbv1.StreamQueryOptions) (pbv1.StreamQueryResult, error) {
+	return nil, errors.New("unsupported")
+}
+
+type storedResult struct {
+	r *pbv1.StreamResult
+}
+
+func (sr *storedResult) Pull() *pbv1.StreamResult {
+	r := sr.r
+	sr.r = nil
+	return r
+}
+
+func (sr *storedResult) Release() {}
+
+func TestPageToken(t *testing.T) {
+	base := time.Now().Truncate(time.Second)
+	at := func(seconds int64) int64 {
+		return base.Add(time.Duration(seconds) * time.Second).UnixNano()
+	}
+	store := &elementStore{elements: []storedElement{
+		{id: "e", ts: at(1)}, {id: "a", ts: at(2)}, {id: "c", ts: at(2)}, {id: "b", ts: at(2)},
+		{id: "d", ts: at(3)}, {id: "f", ts: at(4)}, {id: "g", ts: at(4)},
+	}}
+	ctx := executor.WithStreamExecutionContext(context.Background(), store)
+	s := swSchema(t)
+	metadata := &commonv1.Metadata{Group: "default", Name: "sw"}
+	query := func(sort modelv1.Sort, limit uint32, token string) ([]string, string) {
+		req := &streamv1.QueryRequest{
+			Groups:     []string{"default"},
+			Name:       "sw",
+			TimeRange:  &modelv1.TimeRange{Begin: timestamppb.New(base), End: timestamppb.New(base.Add(time.Minute))},
+			Projection: &modelv1.TagProjection{TagFamilies: []*modelv1.TagProjection_TagFamily{{Name: "searchable", Tags: []string{"trace_id"}}}},
+			OrderBy:    &modelv1.QueryOrder{Sort: sort},
+			Limit:      limit,
+			PageToken:  token,
+		}
+		plan, err := Analyze(context.Background(), req, metadata, s)
+		require.NoError(t, err)
+		elements, err := plan.(executor.StreamExecutable).Execute(ctx)
+		require.NoError(t, err)
+		next, err := NextPageToken(req, elements)
+		require.NoError(t, err)
+		var ids []string
+		for _, e := range elements {
+			ids = append(ids, e.GetElementId())
+		}
+		return ids, next
+	}
+
+	for _, sort := range []modelv1.Sort{modelv1.Sort_SORT_ASC, modelv1.Sort_SORT_DESC} {
+		t.Run(sort.String(), func(t *testing.T) {
+			want, next := query(sort, 100, "")
+			require.Empty(t, next, "the page doesn't fill the limit")
+			var got []string
+			var token string
+			for page := 0; page == 0 || token != ""; page++ {
+				require.Less(t, page, len(want), "the pages don't end")
+				var ids []string
+				ids, token = query(sort, 2, token)
+				got = append(got, ids...)
+			}
+			assert.Equal(t, want, got)
+		})
+	}
+
+	t.Run("new data", func(t *testing.T) {
+		first, token := query(modelv1.Sort_SORT_ASC, 2, "")
+		assert.Equal(t, []string{"e", "a"}, first)
+		// The elements written before the position aren't returned by the following pages.
+		store.elements = append(store.elements, storedElement{id: "0", ts: at(1)}, storedElement{id: "1", ts: at(2)})
+		defer func() {
+			store.elements = store.elements[:len(store.elements)-2]
+		}()
+		second, _ := query(modelv1.Sort_SORT_ASC, 2, token)
+		assert.Equal(t, []string{"b", "c"}, second)
+	})
+
+	t.Run("mismatched query", func(t *testing.T) {
+		_, token := query(modelv1.Sort_SORT_ASC, 2, "")
+		_, err := Analyze(context.Background(), &streamv1.QueryRequest{
+			Groups:     []string{"default"},
+			Name:       "sw",
+			TimeRange:  &modelv1.TimeRange{Begin: timestamppb.New(base), End: timestamppb.New(base.Add(time.Hour))},
+			Projection: &modelv1.TagProjection{TagFamilies: []*modelv1.TagProjection_TagFamily{{Name: "searchable", Tags: []string{"trace_id"}}}},
+			PageToken:  token,
+		}, metadata, s)
+		assert.ErrorIs(t, err, errInvalidPageToken)
+		_, err = Analyze(context.Background(), &streamv1.QueryRequest{
+			Groups:     []string{"default"},
+			Name:       "sw",
+			TimeRange:  &modelv1.TimeRange{Begin: timestamppb.New(base), End: timestamppb.New(base.Add(time.Minute))},
+			Projection: &modelv1.TagProjection{TagFamilies: []*modelv1.TagProjection_TagFamily{{Name: "searchable", Tags: []string{"trace_id"}}}},
+			OrderBy:    &modelv1.QueryOrder{IndexRuleName: "duration"},
+			PageToken:  token,
+		}, metadata, s)
+		assert.ErrorIs(t, err, errInvalidPageToken)
+	})
+}
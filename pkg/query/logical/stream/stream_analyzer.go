@@ -48,9 +48,18 @@ func BuildSchema(sm *databasev1.Stream, indexRules []*databasev1.IndexRule) (log
 
 // Analyze converts logical expressions to executable operation tree represented by Plan.
 func Analyze(_ context.Context, criteria *streamv1.QueryRequest, metadata *commonv1.Metadata, s logical.Schema) (logical.Plan, error) {
+	token, err := parsePageToken(criteria)
+	if err != nil {
+		return nil, err
+	}
 	// parse fields
 	plan := parseTags(criteria, metadata)
 
+	// parse the ranking of groups
+	if criteria.GetTopKPerGroup() != nil {
+		plan = newTopKPerGroup(plan, criteria.GetTopKPerGroup())
+	}
+
 	// parse offset
 	plan = newOffset(plan, criteria.GetOffset())
 
@@ -61,13 +70,27 @@ func Analyze(_ context.Context, criteria *streamv1.QueryRequest, metadata *commo
 	}
 	plan = newLimit(plan, limitParameter)
 
+	// derive the tags of the returned elements
+	if len(criteria.GetDerivedTags()) > 0 {
+		plan = newDerivedTags(plan, criteria.GetDerivedTags())
+	}
+
 	p, err := plan.Analyze(s)
 	if err != nil {
 		return nil, err
 	}
+	order := logical.NewPushDownOrder(criteria.OrderBy, criteria.ThenBy...)
+	// The groups are ranked by their own order, scanning in it lets the ranking stop once the leading groups are complete.
+	if criteria.GetTopKPerGroup() != nil {
+		order = logical.NewPushDownOrder(rankOrder(criteria.GetTopKPerGroup()))
+	}
 	rules := []logical.OptimizeRule{
-		logical.NewPushDownOrder(criteria.OrderBy),
-		logical.NewPushDownMaxSize(int(limitParameter + criteria.GetOffset())),
+		order,
+		// The scan resumed from a page token returns the elements sharing the timestamp of the position again.
+		logical.NewPushDownMaxSize(int(limitParameter+criteria.GetOffset()) + token.ties()),
+	}
+	if token != nil {
+		rules = append(rules, pushDownPageToken{token: token})
 	}
 	if err := logical.ApplyRules(p, rules...); err != nil {
 		return nil, err
@@ -77,8 +100,16 @@ func Analyze(_ context.Context, criteria *streamv1.QueryRequest, metadata *commo
 
 // DistributedAnalyze converts logical expressions to executable operation tree represented by Plan.
 func DistributedAnalyze(criteria *streamv1.QueryRequest, s logical.Schema) (logical.Plan, error) {
+	// the data nodes resume from the page token
+	if _, err := parsePageToken(criteria); err != nil {
+		return nil, err
+	}
 	// parse fields
 	plan := newUnresolvedDistributed(criteria)
+	// rank the groups again after merging the top elements from the data nodes
+	if criteria.GetTopKPerGroup() != nil {
+		plan = newTopKPerGroup(plan, criteria.GetTopKPerGroup())
+	}
 	// parse offset
 	plan = newOffset(plan, criteria.GetOffset())
 
@@ -88,6 +119,10 @@ func DistributedAnalyze(criteria *streamv1.QueryRequest, s logical.Schema) (logi
 		limitParameter = defaultLimit
 	}
 	plan = newLimit(plan, limitParameter)
+	// derive the tags of the merged elements, the data nodes return the projected tags only
+	if len(criteria.GetDerivedTags()) > 0 {
+		plan = newDerivedTags(plan, criteria.GetDerivedTags())
+	}
 	return plan.Analyze(s)
 }
 
@@ -213,5 +248,5 @@ func newOffset(input logical.UnresolvedPlan, num uint32) logical.UnresolvedPlan
 func parseTags(criteria *streamv1.QueryRequest, metadata *commonv1.Metadata) logical.UnresolvedPlan {
 	timeRange := criteria.GetTimeRange()
 	return tagFilter(timeRange.GetBegin().AsTime(), timeRange.GetEnd().AsTime(), metadata,
-		criteria.Criteria, logical.ToTags(criteria.GetProjection()))
+		criteria.Criteria, logical.ToTags(criteria.GetProjection()), criteria.GetSampleInterval(), criteria.GetMaxStaleness().AsDuration())
 }
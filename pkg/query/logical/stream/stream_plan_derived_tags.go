@@ -0,0 +1,107 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/pkg/query/executor"
+	"github.com/apache/skywalking-banyandb/pkg/query/logical"
+)
+
+// DerivedTagFamily is the tag family holding the derived tags of an element.
+const DerivedTagFamily = "derived"
+
+var (
+	_ logical.Plan           = (*derivedTags)(nil)
+	_ logical.UnresolvedPlan = (*derivedTags)(nil)
+)
+
+// derivedTags computes the derived tags of the returned elements from their projected tags.
+type derivedTags struct {
+	*Parent
+	exprs []logical.DerivedExpr
+	specs []*streamv1.DerivedTag
+}
+
+func newDerivedTags(input logical.UnresolvedPlan, specs []*streamv1.DerivedTag) logical.UnresolvedPlan {
+	return &derivedTags{
+		Parent: &Parent{
+			UnresolvedInput: input,
+		},
+		specs: specs,
+	}
+}
+
+func (d *derivedTags) Analyze(s logical.Schema) (logical.Plan, error) {
+	var err error
+	d.Input, err = d.UnresolvedInput.Analyze(s)
+	if err != nil {
+		return nil, err
+	}
+	projected := d.Input.Schema()
+	names := make(map[string]struct{}, len(d.specs))
+	d.exprs = make([]logical.DerivedExpr, 0, len(d.specs))
+	for _, spec := range d.specs {
+		if _, ok := names[spec.GetName()]; ok || projected.FindTagSpecByName(spec.GetName()) != nil {
+			return nil, fmt.Errorf("derived tag %s conflicts with another tag", spec.GetName())
+		}
+		names[spec.GetName()] = struct{}{}
+		expr, errExpr := logical.ParseDerivedExpr(spec.GetExpression(), projected)
+		if errExpr != nil {
+			return nil, fmt.Errorf("derived tag %s: %w", spec.GetName(), errExpr)
+		}
+		d.exprs = append(d.exprs, expr)
+	}
+	return d, nil
+}
+
+func (d *derivedTags) Execute(ec context.Context) ([]*streamv1.Element, error) {
+	elements, err := d.Parent.Input.(executor.StreamExecutable).Execute(ec)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range elements {
+		tf := &modelv1.TagFamily{Name: DerivedTagFamily, Tags: make([]*modelv1.Tag, len(d.exprs))}
+		for i, expr := range d.exprs {
+			tf.Tags[i] = &modelv1.Tag{Key: d.specs[i].GetName(), Value: expr.Eval(e.TagFamilies)}
+		}
+		e.TagFamilies = append(e.TagFamilies, tf)
+	}
+	return elements, nil
+}
+
+func (d *derivedTags) Schema() logical.Schema {
+	return d.Input.Schema()
+}
+
+func (d *derivedTags) String() string {
+	exprs := make([]string, len(d.specs))
+	for i, spec := range d.specs {
+		exprs[i] = fmt.Sprintf("%s=%s", spec.GetName(), spec.GetExpression())
+	}
+	return fmt.Sprintf("%s DerivedTags: %s", d.Input.String(), strings.Join(exprs, "; "))
+}
+
+func (d *derivedTags) Children() []logical.Plan {
+	return []logical.Plan{d.Input}
+}
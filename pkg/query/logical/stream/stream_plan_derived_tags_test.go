@@ -0,0 +1,178 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/query/logical"
+)
+
+type rawPlan struct {
+	s        logical.Schema
+	elements []*streamv1.Element
+}
+
+func (r *rawPlan) Analyze(logical.Schema) (logical.Plan, error) { return r, nil }
+
+func (r *rawPlan) String() string { return "raw" }
+
+func (r *rawPlan) Children() []logical.Plan { return nil }
+
+func (r *rawPlan) Schema() logical.Schema { return r.s }
+
+func (r *rawPlan) Execute(context.Context) ([]*streamv1.Element, error) { return r.elements, nil }
+
+func segmentSchema(t *testing.T) logical.Schema {
+	s, err := BuildSchema(&databasev1.Stream{
+		Metadata: &commonv1.Metadata{Group: "default", Name: "sw"},
+		Entity:   &databasev1.Entity{TagNames: []string{"service"}},
+		TagFamilies: []*databasev1.TagFamilySpec{{
+			Name: "searchable",
+			Tags: []*databasev1.TagSpec{
+				{Name: "service", Type: databasev1.TagType_TAG_TYPE_STRING},
+				{Name: "endpoint", Type: databasev1.TagType_TAG_TYPE_STRING},
+				{Name: "status", Type: databasev1.TagType_TAG_TYPE_INT},
+				{Name: "ids", Type: databasev1.TagType_TAG_TYPE_INT_ARRAY},
+			},
+		}},
+	}, nil)
+	require.NoError(t, err)
+	tagRefs, err := s.CreateTagRef(logical.NewTags("searchable", "service", "endpoint", "status", "ids"))
+	require.NoError(t, err)
+	return s.ProjTags(tagRefs...)
+}
+
+func TestDerivedTags(t *testing.T) {
+	s := segmentSchema(t)
+	element := func(service, endpoint string, status *modelv1.TagValue) *streamv1.Element {
+		return &streamv1.Element{TagFamilies: []*modelv1.TagFamily{{
+			Name: "searchable",
+			Tags: []*modelv1.Tag{
+				{Key: "service", Value: &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: service}}}},
+				{Key: "endpoint", Value: &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: endpoint}}}},
+				{Key: "status", Value: status},
+				{Key: "ids", Value: pbv1.NullTagValue},
+			},
+		}}}
+	}
+	status := func(v int64) *modelv1.TagValue {
+		return &modelv1.TagValue{Value: &modelv1.TagValue_Int{Int: &modelv1.Int{Value: v}}}
+	}
+	input := &rawPlan{s: s, elements: []*streamv1.Element{
+		element("svc", "/api/users", status(404)),
+		element("gateway", "/health", status(200)),
+		element("svc", "/", pbv1.NullTagValue),
+	}}
+	plan, err := newDerivedTags(input, []*streamv1.DerivedTag{
+		{Name: "status_class", Expression: "status / 100"},
+		{Name: "route", Expression: "concat(service, ':', substr(endpoint, 0, 4))"},
+		{Name: "retry_after", Expression: "-(status % 100 - 3) * 2"},
+	}).Analyze(s)
+	require.NoError(t, err)
+	elements, err := plan.(*derivedTags).Execute(context.Background())
+	require.NoError(t, err)
+
+	derived := func(e *streamv1.Element) []*modelv1.TagValue {
+		tf := e.TagFamilies[len(e.TagFamilies)-1]
+		require.Equal(t, DerivedTagFamily, tf.Name)
+		values := make([]*modelv1.TagValue, len(tf.Tags))
+		for i, tag := range tf.Tags {
+			values[i] = tag.Value
+		}
+		return values
+	}
+	str := func(v string) *modelv1.TagValue {
+		return &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: v}}}
+	}
+	assert.Equal(t, []*modelv1.TagValue{status(4), str("svc:/api"), status(-2)}, derived(elements[0]))
+	assert.Equal(t, []*modelv1.TagValue{status(2), str("gateway:/hea"), status(6)}, derived(elements[1]))
+	assert.Equal(t, []*modelv1.TagValue{pbv1.NullTagValue, str("svc:/"), pbv1.NullTagValue}, derived(elements[2]),
+		"a null tag derives null values, and substr is clamped to the string")
+}
+
+func TestDerivedSubstrBounds(t *testing.T) {
+	s := segmentSchema(t)
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{expression: "substr(endpoint, 1, 9223372036854775807)", want: "api/users"},
+		{expression: "substr(endpoint, 9223372036854775807, 9223372036854775807)", want: ""},
+		{expression: "substr(endpoint, -9223372036854775807, 4)", want: "/api"},
+		{expression: "substr(endpoint, 1, -3)", want: ""},
+		{expression: "substr(endpoint, -5, -5)", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			input := &rawPlan{s: s, elements: []*streamv1.Element{{TagFamilies: []*modelv1.TagFamily{{
+				Name: "searchable",
+				Tags: []*modelv1.Tag{
+					{Key: "service", Value: pbv1.NullTagValue},
+					{Key: "endpoint", Value: &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "/api/users"}}}},
+					{Key: "status", Value: pbv1.NullTagValue},
+					{Key: "ids", Value: pbv1.NullTagValue},
+				},
+			}}}}}
+			plan, err := newDerivedTags(input, []*streamv1.DerivedTag{{Name: "d", Expression: tt.expression}}).Analyze(s)
+			require.NoError(t, err)
+			elements, err := plan.(*derivedTags).Execute(context.Background())
+			require.NoError(t, err)
+			tf := elements[0].TagFamilies[len(elements[0].TagFamilies)-1]
+			assert.Equal(t, tt.want, tf.Tags[0].Value.GetStr().GetValue())
+		})
+	}
+}
+
+func TestDerivedTagsRejection(t *testing.T) {
+	s := segmentSchema(t)
+	tests := []struct {
+		name       string
+		expression string
+		wantErr    string
+	}{
+		{name: "string arithmetic", expression: "endpoint / 100", wantErr: "mismatched type"},
+		{name: "concat an integer", expression: "concat(service, status)", wantErr: "mismatched type"},
+		{name: "substr of an integer", expression: "substr(status, 0, 1)", wantErr: "mismatched type"},
+		{name: "array tag", expression: "ids + 1", wantErr: "mismatched type"},
+		{name: "unknown tag", expression: "duration * 2", wantErr: "not in the projection"},
+		{name: "unknown function", expression: "exec('rm -rf /')", wantErr: "unknown function"},
+		{name: "wrong arity", expression: "substr(endpoint, 1)", wantErr: "takes 3 arguments"},
+		{name: "unbalanced", expression: "(status + 1", wantErr: "missing )"},
+		{name: "trailing token", expression: "status 1", wantErr: "unexpected"},
+		{name: "invalid character", expression: "status; 1", wantErr: "unexpected character"},
+		{name: "too deep", expression: "-----------------------------------1", wantErr: "nests deeper"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newDerivedTags(&rawPlan{s: s}, []*streamv1.DerivedTag{{Name: "d", Expression: tt.expression}}).Analyze(s)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+	_, err := newDerivedTags(&rawPlan{s: s}, []*streamv1.DerivedTag{{Name: "status", Expression: "status + 1"}}).Analyze(s)
+	assert.ErrorContains(t, err, "conflicts", "a derived tag can't shadow a projected tag")
+}
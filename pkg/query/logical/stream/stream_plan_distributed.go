@@ -68,12 +68,19 @@ func (ud *unresolvedDistributed) Analyze(s logical.Schema) (logical.Plan, error)
 		limit = defaultLimit
 	}
 	temp := &streamv1.QueryRequest{
-		Projection: ud.originalQuery.Projection,
-		Name:       ud.originalQuery.Name,
-		Groups:     ud.originalQuery.Groups,
-		Criteria:   ud.originalQuery.Criteria,
-		Limit:      limit,
-		OrderBy:    ud.originalQuery.OrderBy,
+		Projection:   ud.originalQuery.Projection,
+		Name:         ud.originalQuery.Name,
+		Groups:       ud.originalQuery.Groups,
+		Criteria:     ud.originalQuery.Criteria,
+		Limit:        limit,
+		OrderBy:      ud.originalQuery.OrderBy,
+		ThenBy:       ud.originalQuery.ThenBy,
+		TopKPerGroup: ud.originalQuery.TopKPerGroup,
+		PageToken:    ud.originalQuery.PageToken,
+		Checksum:     ud.originalQuery.Checksum,
+	}
+	if ud.originalQuery.OrderBy.GetIndexRuleName() == "" && len(ud.originalQuery.ThenBy) > 0 {
+		return nil, fmt.Errorf("then_by requires order_by to sort by an index rule")
 	}
 	if ud.originalQuery.OrderBy == nil {
 		return &distributedPlan{
@@ -113,6 +120,21 @@ func (ud *unresolvedDistributed) Analyze(s logical.Schema) (logical.Plan, error)
 	if ud.originalQuery.OrderBy.Sort == modelv1.Sort_SORT_DESC {
 		result.desc = true
 	}
+	for _, o := range ud.originalQuery.ThenBy {
+		ok, indexRule = s.IndexRuleDefined(o.GetIndexRuleName())
+		if !ok {
+			return nil, fmt.Errorf("index rule %s not found", o.GetIndexRuleName())
+		}
+		if len(indexRule.Tags) != 1 {
+			return nil, fmt.Errorf("index rule %s should have only one tag", o.GetIndexRuleName())
+		}
+		tagSpec := s.FindTagSpecByName(indexRule.Tags[0])
+		if tagSpec == nil {
+			return nil, fmt.Errorf("tag %s not found", indexRule.Tags[0])
+		}
+		result.thenByTagSpecs = append(result.thenByTagSpecs, *tagSpec)
+		result.thenByDesc = append(result.thenByDesc, o.GetSort() == modelv1.Sort_SORT_DESC)
+	}
 	return result, nil
 }
 
@@ -120,6 +142,8 @@ type distributedPlan struct {
 	s              logical.Schema
 	queryTemplate  *streamv1.QueryRequest
 	sortTagSpec    logical.TagSpec
+	thenByTagSpecs []logical.TagSpec
+	thenByDesc     []bool
 	sortByTime     bool
 	desc           bool
 	maxElementSize uint32
@@ -132,7 +156,7 @@ func (t *distributedPlan) Execute(ctx context.Context) ([]*streamv1.Element, err
 	if t.maxElementSize > 0 {
 		query.Limit = t.maxElementSize
 	}
-	ff, err := dctx.Broadcast(defaultQueryTimeout, data.TopicStreamQuery, bus.NewMessage(bus.MessageID(dctx.TimeRange().Begin.Nanos), query))
+	ff, err := dctx.Broadcast(defaultQueryTimeout, data.TopicStreamQuery, bus.NewMessage(bus.MessageID(dctx.TimeRange().Begin.Nanos), query).WithContext(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -147,11 +171,18 @@ func (t *distributedPlan) Execute(ctx context.Context) ([]*streamv1.Element, err
 				continue
 			}
 			resp := d.(*streamv1.QueryResponse)
+			if query.Checksum {
+				if verifyErr := pbv1.VerifyStreamQueryChecksum(resp); verifyErr != nil {
+					allErr = multierr.Append(allErr, verifyErr)
+					continue
+				}
+			}
+			dctx.AddBytesRead(resp.BytesRead)
 			see = append(see,
-				newSortableElements(resp.Elements, t.sortByTime, t.sortTagSpec))
+				newSortableElements(resp.Elements, t.sortByTime, t.sortTagSpec, t.thenByTagSpecs...))
 		}
 	}
-	iter := sort.NewItemIter[*comparableElement](see, t.desc)
+	iter := sort.NewOrderedItemIter[*comparableElement](see, t.orderSpec())
 	var result []*streamv1.Element
 	for iter.Next() {
 		result = append(result, iter.Val().Element)
@@ -159,6 +190,14 @@ func (t *distributedPlan) Execute(ctx context.Context) ([]*streamv1.Element, err
 	return result, allErr
 }
 
+// orderSpec sorts by the sorted field, then by the then-by tags in their own directions, then by the element ID.
+func (t *distributedPlan) orderSpec() sort.OrderSpec {
+	desc := make([]bool, 0, len(t.thenByDesc)+2)
+	desc = append(desc, t.desc)
+	desc = append(desc, t.thenByDesc...)
+	return sort.NewOrderSpec(append(desc, false)...)
+}
+
 func (t *distributedPlan) String() string {
 	return fmt.Sprintf("distributed:%s", t.queryTemplate.String())
 }
@@ -179,10 +218,11 @@ var _ sort.Comparable = (*comparableElement)(nil)
 
 type comparableElement struct {
 	*streamv1.Element
-	sortField []byte
+	sortField    []byte
+	thenByFields [][]byte
 }
 
-func newComparableElement(e *streamv1.Element, sortByTime bool, sortTagSpec logical.TagSpec) (*comparableElement, error) {
+func newComparableElement(e *streamv1.Element, sortByTime bool, sortTagSpec logical.TagSpec, thenBy ...logical.TagSpec) (*comparableElement, error) {
 	var sortField []byte
 	if sortByTime {
 		sortField = convert.Uint64ToBytes(uint64(e.Timestamp.AsTime().UnixNano()))
@@ -194,9 +234,21 @@ func newComparableElement(e *streamv1.Element, sortByTime bool, sortTagSpec logi
 		}
 	}
 
+	var thenByFields [][]byte
+	if len(thenBy) > 0 {
+		thenByFields = make([][]byte, len(thenBy))
+		for i, ts := range thenBy {
+			var err error
+			if thenByFields[i], err = pbv1.MarshalTagValue(e.TagFamilies[ts.TagFamilyIdx].Tags[ts.TagIdx].Value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	return &comparableElement{
-		Element:   e,
-		sortField: sortField,
+		Element:      e,
+		sortField:    sortField,
+		thenByFields: thenByFields,
 	}, nil
 }
 
@@ -204,21 +256,31 @@ func (e *comparableElement) SortedField() []byte {
 	return e.sortField
 }
 
+// SortKeys breaks the ties of the sorted field by the then-by tags, then by the element ID, as the data nodes do.
+func (e *comparableElement) SortKeys() [][]byte {
+	keys := make([][]byte, 0, len(e.thenByFields)+2)
+	keys = append(keys, e.sortField)
+	keys = append(keys, e.thenByFields...)
+	return append(keys, pbv1.ElementIDSortKey(e.ElementId))
+}
+
 var _ sort.Iterator[*comparableElement] = (*sortableElements)(nil)
 
 type sortableElements struct {
 	cur          *comparableElement
 	elements     []*streamv1.Element
+	thenBy       []logical.TagSpec
 	sortTagSpec  logical.TagSpec
 	index        int
 	isSortByTime bool
 }
 
-func newSortableElements(elements []*streamv1.Element, isSortByTime bool, sortTagSpec logical.TagSpec) *sortableElements {
+func newSortableElements(elements []*streamv1.Element, isSortByTime bool, sortTagSpec logical.TagSpec, thenBy ...logical.TagSpec) *sortableElements {
 	return &sortableElements{
 		elements:     elements,
 		isSortByTime: isSortByTime,
 		sortTagSpec:  sortTagSpec,
+		thenBy:       thenBy,
 	}
 }
 
@@ -228,7 +290,7 @@ func (*sortableElements) Close() error {
 
 func (s *sortableElements) Next() bool {
 	return s.iter(func(e *streamv1.Element) (*comparableElement, error) {
-		return newComparableElement(e, s.isSortByTime, s.sortTagSpec)
+		return newComparableElement(e, s.isSortByTime, s.sortTagSpec, s.thenBy...)
 	})
 }
 
@@ -0,0 +1,135 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/iter/sort"
+	"github.com/apache/skywalking-banyandb/pkg/query/executor"
+	"github.com/apache/skywalking-banyandb/pkg/query/logical"
+)
+
+func durationElement(id string, duration, startTime int64) *streamv1.Element {
+	intValue := func(v int64) *modelv1.TagValue {
+		return &modelv1.TagValue{Value: &modelv1.TagValue_Int{Int: &modelv1.Int{Value: v}}}
+	}
+	return &streamv1.Element{
+		ElementId: id,
+		TagFamilies: []*modelv1.TagFamily{{
+			Name: "searchable",
+			Tags: []*modelv1.Tag{{Key: "duration", Value: intValue(duration)}, {Key: "start_time", Value: intValue(startTime)}},
+		}},
+	}
+}
+
+func TestDistributedPlanThenBy(t *testing.T) {
+	// The nodes only sort their elements by the duration descending.
+	nodes := [][]*streamv1.Element{
+		{durationElement("a", 300, 20), durationElement("b", 100, 30), durationElement("c", 100, -10)},
+		{durationElement("d", 300, 10), durationElement("e", 300, 20), durationElement("f", 100, 30)},
+	}
+	tests := []struct {
+		name       string
+		want       []string
+		thenByDesc bool
+	}{
+		{name: "then by start_time ascending", want: []string{"d", "a", "e", "c", "b", "f"}},
+		{name: "then by start_time descending", thenByDesc: true, want: []string{"a", "e", "d", "b", "f", "c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := &distributedPlan{
+				sortTagSpec:    logical.TagSpec{TagFamilyIdx: 0, TagIdx: 0},
+				thenByTagSpecs: []logical.TagSpec{{TagFamilyIdx: 0, TagIdx: 1}},
+				thenByDesc:     []bool{tt.thenByDesc},
+				desc:           true,
+			}
+			var see []sort.Iterator[*comparableElement]
+			for _, elements := range nodes {
+				see = append(see, newSortableElements(elements, false, plan.sortTagSpec, plan.thenByTagSpecs...))
+			}
+			iter := sort.NewOrderedItemIter[*comparableElement](see, plan.orderSpec())
+			var got []string
+			for iter.Next() {
+				got = append(got, iter.Val().ElementId)
+			}
+			assert.NoError(t, iter.Close())
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+type respondedFuture struct {
+	resp *streamv1.QueryResponse
+}
+
+func (f respondedFuture) Get() (bus.Message, error) {
+	return bus.NewMessage(0, f.resp), nil
+}
+
+func (f respondedFuture) GetAll() ([]bus.Message, error) {
+	return []bus.Message{bus.NewMessage(0, f.resp)}, nil
+}
+
+type nodesContext struct {
+	responses []*streamv1.QueryResponse
+	bytesRead uint64
+}
+
+func (c *nodesContext) Broadcast(_ time.Duration, _ bus.Topic, _ bus.Message) ([]bus.Future, error) {
+	var ff []bus.Future
+	for _, resp := range c.responses {
+		ff = append(ff, respondedFuture{resp: resp})
+	}
+	return ff, nil
+}
+
+func (c *nodesContext) TimeRange() *modelv1.TimeRange {
+	return &modelv1.TimeRange{Begin: timestamppb.Now(), End: timestamppb.Now()}
+}
+
+func (c *nodesContext) AddBytesRead(n uint64) {
+	c.bytesRead += n
+}
+
+func TestDistributedPlanBytesRead(t *testing.T) {
+	dc := &nodesContext{responses: []*streamv1.QueryResponse{
+		{Elements: []*streamv1.Element{durationElement("a", 300, 20)}, BytesRead: 100},
+		{Elements: []*streamv1.Element{durationElement("b", 100, 30)}, BytesRead: 20},
+		{BytesRead: 3},
+	}}
+	plan := &distributedPlan{
+		queryTemplate: &streamv1.QueryRequest{},
+		sortTagSpec:   logical.TagSpec{TagFamilyIdx: 0, TagIdx: 0},
+		desc:          true,
+	}
+	elements, err := plan.Execute(executor.WithDistributedExecutionContext(context.Background(), dc))
+	require.NoError(t, err)
+	assert.Len(t, elements, 2)
+	assert.Equal(t, uint64(123), dc.bytesRead)
+}
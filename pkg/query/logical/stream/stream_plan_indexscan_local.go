@@ -28,6 +28,7 @@ import (
 	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
 	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
 	"github.com/apache/skywalking-banyandb/pkg/index"
+	"github.com/apache/skywalking-banyandb/pkg/iter/sort"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
 	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
 	"github.com/apache/skywalking-banyandb/pkg/query/executor"
@@ -46,12 +47,15 @@ type localIndexScan struct {
 	filter            index.Filter
 	order             *logical.OrderBy
 	metadata          *commonv1.Metadata
+	after             *pageToken
 	l                 *logger.Logger
 	timeRange         timestamp.TimeRange
 	projectionTagRefs [][]*logical.TagRef
 	projectionTags    []pbv1.TagProjection
 	entities          [][]*modelv1.TagValue
 	maxElementSize    int
+	maxStaleness      time.Duration
+	sampleInterval    uint32
 }
 
 func (i *localIndexScan) Limit(max int) {
@@ -64,23 +68,31 @@ func (i *localIndexScan) Sort(order *logical.OrderBy) {
 
 func (i *localIndexScan) Execute(ctx context.Context) ([]*streamv1.Element, error) {
 	var orderBy *pbv1.OrderBy
+	var thenBy []*pbv1.OrderBy
 	if i.order != nil {
 		orderBy = &pbv1.OrderBy{
 			Index: i.order.Index,
 			Sort:  i.order.Sort,
 		}
+		for _, o := range i.order.ThenBy {
+			thenBy = append(thenBy, &pbv1.OrderBy{Index: o.Index, Sort: o.Sort})
+		}
 	}
 	ec := executor.FromStreamExecutionContext(ctx)
+	timeRange := i.after.resume(i.timeRange)
 
 	if i.order != nil && i.order.Index != nil {
 		ssr, err := ec.Sort(ctx, pbv1.StreamQueryOptions{
 			Name:           i.metadata.GetName(),
-			TimeRange:      &i.timeRange,
+			TimeRange:      &timeRange,
 			Entities:       i.entities,
 			Filter:         i.filter,
 			Order:          orderBy,
+			ThenBy:         thenBy,
 			TagProjection:  i.projectionTags,
 			MaxElementSize: i.maxElementSize,
+			MaxStaleness:   i.maxStaleness,
+			SampleInterval: i.sampleInterval,
 		})
 		if err != nil {
 			return nil, err
@@ -88,19 +100,25 @@ func (i *localIndexScan) Execute(ctx context.Context) ([]*streamv1.Element, erro
 		if ssr == nil {
 			return nil, nil
 		}
-		r := ssr.Pull()
-		return buildElementsFromColumnResult(r), nil
+		defer ssr.Release()
+		var elements []*streamv1.Element
+		for r := ssr.Pull(); r != nil; r = ssr.Pull() {
+			elements = append(elements, buildElementsFromColumnResult(r)...)
+		}
+		return elements, nil
 	}
 
 	if i.filter != nil && i.filter != logical.ENode {
 		result, err := ec.Filter(ctx, pbv1.StreamQueryOptions{
 			Name:           i.metadata.GetName(),
-			TimeRange:      &i.timeRange,
+			TimeRange:      &timeRange,
 			Entities:       i.entities,
 			Filter:         i.filter,
 			Order:          orderBy,
 			TagProjection:  i.projectionTags,
 			MaxElementSize: i.maxElementSize,
+			MaxStaleness:   i.maxStaleness,
+			SampleInterval: i.sampleInterval,
 		})
 		if err != nil {
 			return nil, err
@@ -108,27 +126,98 @@ func (i *localIndexScan) Execute(ctx context.Context) ([]*streamv1.Element, erro
 		if result == nil {
 			return nil, nil
 		}
-		return BuildElementsFromStreamResult(result), nil
+		defer result.Release()
+		return i.after.skip(sortTiesByElementID(BuildElementsFromStreamResult(result))), nil
 	}
 
 	result, err := ec.Query(ctx, pbv1.StreamQueryOptions{
-		Name:          i.metadata.GetName(),
-		TimeRange:     &i.timeRange,
-		Entities:      i.entities,
-		Filter:        i.filter,
-		Order:         orderBy,
-		TagProjection: i.projectionTags,
+		Name:           i.metadata.GetName(),
+		TimeRange:      &timeRange,
+		Entities:       i.entities,
+		Filter:         i.filter,
+		Order:          orderBy,
+		TagProjection:  i.projectionTags,
+		MaxStaleness:   i.maxStaleness,
+		SampleInterval: i.sampleInterval,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to query stream: %w", err)
 	}
-	return BuildElementsFromStreamResult(result), nil
+	defer result.Release()
+	return i.after.skip(sortTiesByElementID(BuildElementsFromStreamResult(result))), nil
+}
+
+// iterate walks the elements sorted by the index one batch at a time, until fn returns false.
+// The scan isn't bounded by the max element size, the caller decides when to stop.
+// The scans not sorted by an index are executed at once.
+func (i *localIndexScan) iterate(ctx context.Context, fn func(*streamv1.Element) (bool, error)) error {
+	if i.order == nil || i.order.Index == nil {
+		elements, err := i.Execute(ctx)
+		if err != nil {
+			return err
+		}
+		for _, e := range elements {
+			if more, errFn := fn(e); errFn != nil || !more {
+				return errFn
+			}
+		}
+		return nil
+	}
+	orderBy := &pbv1.OrderBy{
+		Index: i.order.Index,
+		Sort:  i.order.Sort,
+	}
+	var thenBy []*pbv1.OrderBy
+	for _, o := range i.order.ThenBy {
+		thenBy = append(thenBy, &pbv1.OrderBy{Index: o.Index, Sort: o.Sort})
+	}
+	timeRange := i.after.resume(i.timeRange)
+	ssr, err := executor.FromStreamExecutionContext(ctx).Sort(ctx, pbv1.StreamQueryOptions{
+		Name:           i.metadata.GetName(),
+		TimeRange:      &timeRange,
+		Entities:       i.entities,
+		Filter:         i.filter,
+		Order:          orderBy,
+		ThenBy:         thenBy,
+		TagProjection:  i.projectionTags,
+		MaxStaleness:   i.maxStaleness,
+		SampleInterval: i.sampleInterval,
+	})
+	if err != nil || ssr == nil {
+		return err
+	}
+	defer ssr.Release()
+	for r := ssr.Pull(); r != nil; r = ssr.Pull() {
+		for _, e := range buildElementsFromColumnResult(r) {
+			if more, errFn := fn(e); errFn != nil || !more {
+				return errFn
+			}
+		}
+	}
+	return nil
+}
+
+// sortTiesByElementID orders the elements sharing a timestamp by their IDs, as the coordinator of a distributed query does.
+func sortTiesByElementID(elements []*streamv1.Element) []*streamv1.Element {
+	ces := make([]*comparableElement, 0, len(elements))
+	for _, e := range elements {
+		ce, err := newComparableElement(e, true, logical.TagSpec{})
+		if err != nil {
+			return elements
+		}
+		ces = append(ces, ce)
+	}
+	sort.SortTies(ces, sort.TieBrokenOrder(false))
+	for i := range ces {
+		elements[i] = ces[i].Element
+	}
+	return elements
 }
 
 func (i *localIndexScan) String() string {
-	return fmt.Sprintf("IndexScan: startTime=%d,endTime=%d,Metadata{group=%s,name=%s},conditions=%s; projection=%s; orderBy=%s; limit=%d",
+	return fmt.Sprintf("IndexScan: startTime=%d,endTime=%d,Metadata{group=%s,name=%s},conditions=%s; projection=%s; orderBy=%s; limit=%d; sampleInterval=%d; maxStaleness=%s",
 		i.timeRange.Start.Unix(), i.timeRange.End.Unix(), i.metadata.GetGroup(), i.metadata.GetName(),
-		i.filter, logical.FormatTagRefs(", ", i.projectionTagRefs...), i.order, i.maxElementSize)
+		i.filter, logical.FormatTagRefs(", ", i.projectionTagRefs...), i.order, i.maxElementSize, i.sampleInterval, i.maxStaleness)
 }
 
 func (i *localIndexScan) Children() []logical.Plan {
@@ -41,6 +41,8 @@ type unresolvedTagFilter struct {
 	metadata       *commonv1.Metadata
 	criteria       *modelv1.Criteria
 	projectionTags [][]*logical.Tag
+	maxStaleness   time.Duration
+	sampleInterval uint32
 }
 
 func (uis *unresolvedTagFilter) Analyze(s logical.Schema) (logical.Plan, error) {
@@ -97,11 +99,14 @@ func (uis *unresolvedTagFilter) selectIndexScanner(ctx *analyzeContext) logical.
 		metadata:          uis.metadata,
 		filter:            ctx.filter,
 		entities:          ctx.entities,
+		maxStaleness:      uis.maxStaleness,
+		sampleInterval:    uis.sampleInterval,
 		l:                 logger.GetLogger("query", "stream", "local-index"),
 	}
 }
 
 func tagFilter(startTime, endTime time.Time, metadata *commonv1.Metadata, criteria *modelv1.Criteria, projection [][]*logical.Tag,
+	sampleInterval uint32, maxStaleness time.Duration,
 ) logical.UnresolvedPlan {
 	return &unresolvedTagFilter{
 		startTime:      startTime,
@@ -109,6 +114,8 @@ func tagFilter(startTime, endTime time.Time, metadata *commonv1.Metadata, criter
 		metadata:       metadata,
 		criteria:       criteria,
 		projectionTags: projection,
+		sampleInterval: sampleInterval,
+		maxStaleness:   maxStaleness,
 	}
 }
 
@@ -165,6 +172,30 @@ func (t *tagFilterPlan) Execute(ec context.Context) ([]*streamv1.Element, error)
 	return filteredElements, nil
 }
 
+// iterate matches the elements one at a time if the parent is able to stream them.
+func (t *tagFilterPlan) iterate(ec context.Context, fn func(*streamv1.Element) (bool, error)) error {
+	it, ok := t.parent.(elementIterator)
+	if !ok {
+		elements, err := t.Execute(ec)
+		if err != nil {
+			return err
+		}
+		for _, e := range elements {
+			if more, errFn := fn(e); errFn != nil || !more {
+				return errFn
+			}
+		}
+		return nil
+	}
+	return it.iterate(ec, func(e *streamv1.Element) (bool, error) {
+		ok, err := t.tagFilter.Match(logical.TagFamilies(e.TagFamilies), t.s)
+		if err != nil || !ok {
+			return err == nil, err
+		}
+		return fn(e)
+	})
+}
+
 func (t *tagFilterPlan) String() string {
 	return fmt.Sprintf("%s tag-filter:%s", t.parent, t.tagFilter.String())
 }
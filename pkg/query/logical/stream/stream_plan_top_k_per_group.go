@@ -0,0 +1,257 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"bytes"
+	"container/heap"
+	"context"
+	"fmt"
+	"sort"
+
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/query/executor"
+	"github.com/apache/skywalking-banyandb/pkg/query/logical"
+)
+
+var (
+	_ logical.Plan           = (*topKPerGroup)(nil)
+	_ logical.UnresolvedPlan = (*topKPerGroup)(nil)
+	_ logical.Sorter         = (*topKPerGroup)(nil)
+	_ logical.VolumeLimiter  = (*topKPerGroup)(nil)
+)
+
+// elementIterator is a plan able to stream its elements, so that its parent doesn't hold them all at once.
+type elementIterator interface {
+	iterate(ec context.Context, fn func(*streamv1.Element) (bool, error)) error
+}
+
+// topKPerGroup keeps the top k elements of each group in a bounded heap while it walks its input,
+// so the memory is bounded by the number of groups times k.
+// The groups are emitted in the order of their best elements, each of them ranked from the best.
+//
+// If the input is sorted by the rank, the groups show up in the order they are emitted.
+// The ranking stops once the leading groups are complete and cover the max size,
+// and ignores the groups after the first max size ones.
+type topKPerGroup struct {
+	*Parent
+	criteria *streamv1.TopKPerGroup
+	groupBy  *logical.TagSpec
+	rankBy   *logical.TagSpec
+	maxSize  int
+	ordered  bool
+}
+
+func newTopKPerGroup(input logical.UnresolvedPlan, criteria *streamv1.TopKPerGroup) logical.UnresolvedPlan {
+	return &topKPerGroup{
+		Parent: &Parent{
+			UnresolvedInput: input,
+		},
+		criteria: criteria,
+	}
+}
+
+// rankOrder is the order of the input that lets the ranking stop early.
+func rankOrder(criteria *streamv1.TopKPerGroup) *modelv1.QueryOrder {
+	return &modelv1.QueryOrder{
+		IndexRuleName: criteria.GetIndexRuleName(),
+		Sort:          criteria.GetSort(),
+	}
+}
+
+func (t *topKPerGroup) Sort(order *logical.OrderBy) {
+	t.ordered = order != nil && order.Index != nil &&
+		order.Index.GetMetadata().GetName() == t.criteria.GetIndexRuleName() &&
+		(order.Sort == modelv1.Sort_SORT_DESC) == (t.criteria.GetSort() == modelv1.Sort_SORT_DESC)
+}
+
+func (t *topKPerGroup) Limit(max int) {
+	t.maxSize = max
+}
+
+func (t *topKPerGroup) Analyze(s logical.Schema) (logical.Plan, error) {
+	var err error
+	t.Input, err = t.UnresolvedInput.Analyze(s)
+	if err != nil {
+		return nil, err
+	}
+	projected := t.Input.Schema()
+	if t.groupBy = projected.FindTagSpecByName(t.criteria.GetGroupByTagName()); t.groupBy == nil {
+		return nil, fmt.Errorf("group by tag %s is not in the projection", t.criteria.GetGroupByTagName())
+	}
+	ok, indexRule := projected.IndexRuleDefined(t.criteria.GetIndexRuleName())
+	if !ok {
+		return nil, fmt.Errorf("index rule %s not found", t.criteria.GetIndexRuleName())
+	}
+	if len(indexRule.Tags) != 1 {
+		return nil, fmt.Errorf("index rule %s should have only one tag", t.criteria.GetIndexRuleName())
+	}
+	if t.rankBy = projected.FindTagSpecByName(indexRule.Tags[0]); t.rankBy == nil {
+		return nil, fmt.Errorf("rank tag %s is not in the projection", indexRule.Tags[0])
+	}
+	return t, nil
+}
+
+func (t *topKPerGroup) Execute(ec context.Context) ([]*streamv1.Element, error) {
+	r := &groupRanking{
+		t:      t,
+		groups: make(map[string]*rankedElements),
+		k:      int(t.criteria.GetK()),
+		desc:   t.criteria.GetSort() == modelv1.Sort_SORT_DESC,
+	}
+	if it, ok := t.Parent.Input.(elementIterator); ok {
+		if err := it.iterate(ec, r.add); err != nil {
+			return nil, err
+		}
+		return r.result(), nil
+	}
+	elements, err := t.Parent.Input.(executor.StreamExecutable).Execute(ec)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range elements {
+		if more, errAdd := r.add(e); errAdd != nil || !more {
+			if errAdd != nil {
+				return nil, errAdd
+			}
+			break
+		}
+	}
+	return r.result(), nil
+}
+
+// groupRanking collects the top k elements of the groups one element at a time.
+type groupRanking struct {
+	t        *topKPerGroup
+	groups   map[string]*rankedElements
+	order    []*rankedElements
+	k        int
+	seq      int
+	complete int
+	covered  int
+	desc     bool
+}
+
+// add ranks an element, it returns false once the rest of the input can't change the result.
+func (r *groupRanking) add(e *streamv1.Element) (bool, error) {
+	groupKey, err := r.t.tagValue(e, r.t.groupBy)
+	if err != nil {
+		return false, err
+	}
+	rank, err := r.t.tagValue(e, r.t.rankBy)
+	if err != nil {
+		return false, err
+	}
+	seq := r.seq
+	r.seq++
+	bounded := r.t.ordered && r.t.maxSize > 0
+	g, ok := r.groups[string(groupKey)]
+	if !ok {
+		if bounded && len(r.order) >= r.t.maxSize {
+			return true, nil
+		}
+		g = &rankedElements{desc: r.desc}
+		r.groups[string(groupKey)] = g
+		r.order = append(r.order, g)
+	}
+	heap.Push(g, rankedElement{Element: e, rank: rank, seq: seq})
+	if g.Len() > r.k {
+		heap.Pop(g)
+	}
+	if !bounded {
+		return true, nil
+	}
+	for r.complete < len(r.order) && r.order[r.complete].Len() >= r.k {
+		r.covered += r.k
+		r.complete++
+	}
+	return r.covered < r.t.maxSize, nil
+}
+
+func (r *groupRanking) result() []*streamv1.Element {
+	order := r.order
+	for _, g := range order {
+		sort.Slice(g.items, func(i, j int) bool { return g.worse(g.items[j], g.items[i]) })
+	}
+	sort.SliceStable(order, func(i, j int) bool { return order[i].worse(order[j].items[0], order[i].items[0]) })
+	result := make([]*streamv1.Element, 0, len(order)*r.k)
+	for _, g := range order {
+		for _, item := range g.items {
+			result = append(result, item.Element)
+		}
+	}
+	return result
+}
+
+func (t *topKPerGroup) tagValue(e *streamv1.Element, spec *logical.TagSpec) ([]byte, error) {
+	if spec.TagFamilyIdx >= len(e.TagFamilies) || spec.TagIdx >= len(e.TagFamilies[spec.TagFamilyIdx].Tags) {
+		return nil, nil
+	}
+	return pbv1.MarshalTagValue(e.TagFamilies[spec.TagFamilyIdx].Tags[spec.TagIdx].Value)
+}
+
+func (t *topKPerGroup) Schema() logical.Schema {
+	return t.Input.Schema()
+}
+
+func (t *topKPerGroup) String() string {
+	return fmt.Sprintf("%s TopKPerGroup: groupBy=%s; k=%d; rankBy=%s; sort=%s; limit=%d", t.Input.String(),
+		t.criteria.GetGroupByTagName(), t.criteria.GetK(), t.criteria.GetIndexRuleName(), t.criteria.GetSort(), t.maxSize)
+}
+
+func (t *topKPerGroup) Children() []logical.Plan {
+	return []logical.Plan{t.Input}
+}
+
+type rankedElement struct {
+	*streamv1.Element
+	rank []byte
+	seq  int
+}
+
+// rankedElements is a heap whose root is the worst element of a group.
+type rankedElements struct {
+	items []rankedElement
+	desc  bool
+}
+
+// worse reports whether a ranks behind b. Ties are broken by the arrival order.
+func (r *rankedElements) worse(a, b rankedElement) bool {
+	if c := bytes.Compare(a.rank, b.rank); c != 0 {
+		return (c < 0) == r.desc
+	}
+	return a.seq > b.seq
+}
+
+func (r *rankedElements) Len() int { return len(r.items) }
+
+func (r *rankedElements) Less(i, j int) bool { return r.worse(r.items[i], r.items[j]) }
+
+func (r *rankedElements) Swap(i, j int) { r.items[i], r.items[j] = r.items[j], r.items[i] }
+
+func (r *rankedElements) Push(x any) { r.items = append(r.items, x.(rankedElement)) }
+
+func (r *rankedElements) Pop() any {
+	old := r.items
+	n := len(old)
+	x := old[n-1]
+	r.items = old[:n-1]
+	return x
+}
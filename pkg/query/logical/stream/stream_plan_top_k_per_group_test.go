@@ -0,0 +1,96 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stream
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/pkg/query/logical"
+)
+
+type iteratedPlan struct {
+	*rawPlan
+	visited int
+}
+
+func (p *iteratedPlan) iterate(_ context.Context, fn func(*streamv1.Element) (bool, error)) error {
+	for _, e := range p.elements {
+		p.visited++
+		if more, err := fn(e); err != nil || !more {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestTopKPerGroup(t *testing.T) {
+	element := func(endpoint string, status int64) *streamv1.Element {
+		return &streamv1.Element{TagFamilies: []*modelv1.TagFamily{{
+			Name: "searchable",
+			Tags: []*modelv1.Tag{
+				{Key: "endpoint", Value: &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: endpoint}}}},
+				{Key: "status", Value: &modelv1.TagValue{Value: &modelv1.TagValue_Int{Int: &modelv1.Int{Value: status}}}},
+			},
+		}}}
+	}
+	// sorted by the status in the descending order
+	elements := []*streamv1.Element{
+		element("a", 9), element("b", 8), element("a", 7), element("c", 6),
+		element("b", 5), element("a", 4), element("c", 3), element("b", 2),
+	}
+	rankBy := &logical.TagSpec{TagFamilyIdx: 0, TagIdx: 1}
+	newPlan := func(ordered bool) (*topKPerGroup, *iteratedPlan) {
+		input := &iteratedPlan{rawPlan: &rawPlan{elements: elements}}
+		return &topKPerGroup{
+			Parent:   &Parent{Input: input},
+			criteria: &streamv1.TopKPerGroup{K: 2, Sort: modelv1.Sort_SORT_DESC},
+			groupBy:  &logical.TagSpec{TagFamilyIdx: 0, TagIdx: 0},
+			rankBy:   rankBy,
+			maxSize:  3,
+			ordered:  ordered,
+		}, input
+	}
+	statuses := func(elements []*streamv1.Element) (result []int64) {
+		for _, e := range elements {
+			result = append(result, e.TagFamilies[0].Tags[1].Value.GetInt().GetValue())
+		}
+		return result
+	}
+
+	t.Run("ranked input stops at the complete groups", func(t *testing.T) {
+		plan, input := newPlan(true)
+		result, err := plan.Execute(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []int64{9, 7, 8, 5, 6}, statuses(result))
+		assert.Equal(t, 5, input.visited)
+	})
+
+	t.Run("unranked input is walked to the end", func(t *testing.T) {
+		plan, input := newPlan(false)
+		result, err := plan.Execute(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []int64{9, 7, 8, 5, 6, 3}, statuses(result))
+		assert.Equal(t, len(elements), input.visited)
+	})
+}
@@ -84,9 +84,12 @@ func BuildTagFilter(criteria *modelv1.Criteria, entityDict map[string]int, index
 	switch criteria.GetExp().(type) {
 	case *modelv1.Criteria_Condition:
 		cond := criteria.GetCondition()
-		expr, err := parseExpr(cond.Value)
-		if err != nil {
-			return nil, err
+		var expr ComparableExpr
+		if !isExistenceOp(cond.Op) {
+			var err error
+			if expr, err = parseExpr(cond.Value); err != nil {
+				return nil, err
+			}
 		}
 		if ok, _ := indexChecker.IndexDefined(cond.Name); ok {
 			return DummyFilter, nil
@@ -157,6 +160,10 @@ func parseFilter(cond *modelv1.Condition, expr ComparableExpr) (TagFilter, error
 		return newInTag(cond.Name, expr), nil
 	case modelv1.Condition_BINARY_OP_NOT_IN:
 		return newNotTag(newInTag(cond.Name, expr)), nil
+	case modelv1.Condition_BINARY_OP_EXISTS:
+		return newExistsTag(cond.Name), nil
+	case modelv1.Condition_BINARY_OP_NOT_EXISTS:
+		return newNotTag(newExistsTag(cond.Name)), nil
 	default:
 		return nil, errors.WithMessagef(errUnsupportedConditionOp, "tag filter parses %v", cond)
 	}
@@ -341,6 +348,39 @@ func (n *notTag) String() string {
 	return jsonToString(n)
 }
 
+// existsTag matches the elements having a non-null value of the tag.
+type existsTag struct {
+	TagFilter
+	Name string
+}
+
+func newExistsTag(tagName string) *existsTag {
+	return &existsTag{Name: tagName}
+}
+
+func (e *existsTag) Match(accessor TagValueIndexAccessor, registry TagSpecRegistry) (bool, error) {
+	tagSpec := registry.FindTagSpecByName(e.Name)
+	if tagSpec == nil {
+		return false, errTagNotDefined
+	}
+	tagVal := accessor.GetTagValue(tagSpec.TagFamilyIdx, tagSpec.TagIdx)
+	if tagVal == nil || tagVal.GetValue() == nil {
+		return false, nil
+	}
+	_, isNull := tagVal.GetValue().(*modelv1.TagValue_Null)
+	return !isNull, nil
+}
+
+func (e *existsTag) MarshalJSON() ([]byte, error) {
+	data := make(map[string]interface{}, 1)
+	data["exists"] = e.Name
+	return json.Marshal(data)
+}
+
+func (e *existsTag) String() string {
+	return jsonToString(e)
+}
+
 type inTag struct {
 	*tagLeaf
 }
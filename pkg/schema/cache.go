@@ -28,6 +28,7 @@ import (
 
 	"github.com/pkg/errors"
 	"go.uber.org/multierr"
+	"google.golang.org/protobuf/proto"
 
 	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
 	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
@@ -108,13 +109,22 @@ type schemaRepo struct {
 	l                      *logger.Logger
 	closer                 *run.ChannelCloser
 	eventCh                chan MetadataEvent
+	droppedGroups          map[string]*droppedGroup
 	groupMap               sync.Map
+	groupAliases           sync.Map
 	resourceMap            sync.Map
 	workerNum              int
+	groupGracePeriod       time.Duration
 	resourceMutex          sync.Mutex
 	groupMux               sync.Mutex
 }
 
+// droppedGroup is a deleted group whose data is retained until the grace period expires.
+type droppedGroup struct {
+	group *group
+	timer *time.Timer
+}
+
 func (sr *schemaRepo) SendMetadataEvent(event MetadataEvent) {
 	if !sr.closer.AddSender() {
 		return
@@ -132,10 +142,12 @@ func (sr *schemaRepo) StopCh() <-chan struct{} {
 }
 
 // NewRepository return a new Repository.
+// The data of a deleted group is retained for groupGracePeriod, during which the group could be restored.
 func NewRepository(
 	metadata metadata.Repo,
 	l *logger.Logger,
 	resourceSupplier ResourceSupplier,
+	groupGracePeriod time.Duration,
 ) Repository {
 	workNum := getWorkerNum()
 	return &schemaRepo{
@@ -146,6 +158,8 @@ func NewRepository(
 		eventCh:                make(chan MetadataEvent, workNum),
 		workerNum:              workNum,
 		closer:                 run.NewChannelCloser(),
+		droppedGroups:          make(map[string]*droppedGroup),
+		groupGracePeriod:       groupGracePeriod,
 	}
 }
 
@@ -163,6 +177,7 @@ func NewPortableRepository(
 		eventCh:                make(chan MetadataEvent, workNum),
 		workerNum:              workNum,
 		closer:                 run.NewChannelCloser(),
+		droppedGroups:          make(map[string]*droppedGroup),
 	}
 }
 
@@ -237,11 +252,25 @@ func (sr *schemaRepo) Watcher() {
 	}
 }
 
-func (sr *schemaRepo) storeGroup(groupMeta *commonv1.Metadata) (*group, error) {
+func (sr *schemaRepo) storeGroup(groupMeta *commonv1.Metadata) (g *group, err error) {
 	name := groupMeta.GetName()
 	sr.groupMux.Lock()
 	defer sr.groupMux.Unlock()
+	defer func() {
+		if err == nil {
+			sr.indexAliases(name, g.GetSchema().GetAliases())
+		}
+	}()
 	g, ok := sr.getGroup(name)
+	if !ok || !g.isInit() {
+		relinked, isRelinked, relinkErr := sr.relinkDroppedGroup(name)
+		if relinkErr != nil {
+			return nil, relinkErr
+		}
+		if isRelinked {
+			return relinked, nil
+		}
+	}
 	if !ok {
 		sr.l.Info().Str("group", name).Msg("creating a tsdb")
 		g = sr.createGroup(name)
@@ -266,6 +295,9 @@ func (sr *schemaRepo) storeGroup(groupMeta *commonv1.Metadata) (*group, error) {
 	if groupSchema.GetMetadata().GetModRevision() <= prevGroupSchema.Metadata.ModRevision {
 		return g, nil
 	}
+	if resharded, err := g.reshard(prevGroupSchema, groupSchema); resharded || err != nil {
+		return g, err
+	}
 	sr.l.Info().Str("group", name).Msg("closing the previous tsdb")
 	db := g.SupplyTSDB()
 	if db != nil {
@@ -286,11 +318,152 @@ func (sr *schemaRepo) createGroup(name string) (g *group) {
 
 func (sr *schemaRepo) deleteGroup(groupMeta *commonv1.Metadata) error {
 	name := groupMeta.GetName()
-	g, loaded := sr.groupMap.LoadAndDelete(name)
+	sr.groupMux.Lock()
+	defer sr.groupMux.Unlock()
+	v, loaded := sr.groupMap.LoadAndDelete(name)
 	if !loaded {
 		return nil
 	}
-	return g.(*group).close()
+	sr.indexAliases(name, nil)
+	g := v.(*group)
+	if !g.isInit() || g.isPortable() {
+		return nil
+	}
+	// The tsdb is closed before the group is marked as dropped, which hides it from SupplyTSDB.
+	if err := g.close(); err != nil {
+		return err
+	}
+	g.dropped.Store(true)
+	sr.l.Info().Str("group", name).Dur("grace_period", sr.groupGracePeriod).Msg("the data of the dropped group will be deleted after the grace period")
+	dg := &droppedGroup{group: g}
+	dg.timer = time.AfterFunc(sr.groupGracePeriod, func() {
+		sr.dropGroupData(name, dg)
+	})
+	sr.droppedGroups[name] = dg
+	return nil
+}
+
+func (sr *schemaRepo) dropGroupData(name string, dg *droppedGroup) {
+	sr.groupMux.Lock()
+	defer sr.groupMux.Unlock()
+	if sr.droppedGroups[name] != dg {
+		return
+	}
+	delete(sr.droppedGroups, name)
+	if err := sr.resourceSupplier.DropDB(dg.group.GetSchema()); err != nil {
+		sr.l.Error().Err(err).Str("group", name).Msg("failed to delete the data of the dropped group")
+		return
+	}
+	sr.l.Info().Str("group", name).Msg("deleted the data of the dropped group")
+}
+
+// takeDroppedGroup cancels the deletion of a dropped group's data. The caller has to hold groupMux.
+func (sr *schemaRepo) takeDroppedGroup(name string) (*group, bool) {
+	dg, ok := sr.droppedGroups[name]
+	if !ok {
+		return nil, false
+	}
+	delete(sr.droppedGroups, name)
+	dg.timer.Stop()
+	dg.group.dropped.Store(false)
+	return dg.group, true
+}
+
+// relinkDroppedGroup re-links the data of a dropped group once the group is created again.
+// A late event of the dropped group's resources doesn't re-link it. The caller has to hold groupMux.
+func (sr *schemaRepo) relinkDroppedGroup(name string) (*group, bool, error) {
+	if _, ok := sr.droppedGroups[name]; !ok {
+		return nil, false, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	groupSchema, err := sr.metadata.GroupRegistry().GetGroup(ctx, name)
+	if errors.Is(err, schema.ErrGRPCResourceNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	g, _ := sr.takeDroppedGroup(name)
+	sr.l.Info().Str("group", name).Msg("re-linking the data of a dropped group")
+	sr.groupMap.Store(name, g)
+	if err = g.initBySchema(groupSchema); err != nil {
+		return nil, false, err
+	}
+	return g, true, nil
+}
+
+// RestoreGroup re-links the data of a dropped group with the schema of the group in the registry,
+// which the group has to be restored in first.
+func (sr *schemaRepo) RestoreGroup(name string) error {
+	sr.groupMux.Lock()
+	defer sr.groupMux.Unlock()
+	if _, ok := sr.droppedGroups[name]; !ok {
+		return errors.WithMessagef(ErrGroupNotDropped, "group %s", name)
+	}
+	_, ok, err := sr.relinkDroppedGroup(name)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.WithMessagef(schema.ErrGRPCResourceNotFound, "group %s isn't restored in the registry", name)
+	}
+	return nil
+}
+
+// ReopenGroups closes the databases of all groups, calls relocate, and opens them again
+// from wherever the ResourceSupplier points to afterwards. Group changes wait until it returns.
+// If a database fails to close, relocate is skipped and the groups are reopened in place.
+func (sr *schemaRepo) ReopenGroups(relocate func() error) error {
+	sr.groupMux.Lock()
+	defer sr.groupMux.Unlock()
+	var groups []*group
+	sr.groupMap.Range(func(_, value any) bool {
+		if g, ok := value.(*group); ok && g.isInit() && !g.isPortable() {
+			groups = append(groups, g)
+		}
+		return true
+	})
+	var err error
+	for _, g := range groups {
+		err = multierr.Append(err, g.close())
+	}
+	if err == nil {
+		err = relocate()
+	}
+	for _, g := range groups {
+		err = multierr.Append(err, g.initBySchema(g.GetSchema()))
+	}
+	return err
+}
+
+// indexAliases points the aliases to the group and drops the ones the group no longer has.
+// The caller has to hold groupMux.
+func (sr *schemaRepo) indexAliases(name string, aliases []string) {
+	sr.groupAliases.Range(func(key, value any) bool {
+		if value.(string) == name {
+			sr.groupAliases.Delete(key)
+		}
+		return true
+	})
+	for _, alias := range aliases {
+		if alias == "" || alias == name {
+			continue
+		}
+		if target, ok := sr.groupAliases.Load(alias); ok && target.(string) != name {
+			sr.l.Warn().Str("alias", alias).Str("group", name).Str("previous_group", target.(string)).Msg("the alias is moved to another group")
+		}
+		sr.groupAliases.Store(alias, name)
+	}
+}
+
+// resolveAlias returns the group the alias points to.
+func (sr *schemaRepo) resolveAlias(alias string) (string, bool) {
+	v, ok := sr.groupAliases.Load(alias)
+	if !ok {
+		return "", false
+	}
+	return v.(string), true
 }
 
 func (sr *schemaRepo) getGroup(name string) (*group, bool) {
@@ -304,16 +477,44 @@ func (sr *schemaRepo) getGroup(name string) (*group, bool) {
 func (sr *schemaRepo) LoadGroup(name string) (Group, bool) {
 	g, ok := sr.getGroup(name)
 	if !ok {
-		return nil, false
+		target, isAlias := sr.resolveAlias(name)
+		if !isAlias {
+			return nil, false
+		}
+		if g, ok = sr.getGroup(target); !ok {
+			return nil, false
+		}
 	}
 	return g, g.isInit()
 }
 
+func (sr *schemaRepo) LoadAllGroups() []Group {
+	var groups []Group
+	sr.groupMap.Range(func(_, value any) bool {
+		g, ok := value.(*group)
+		if !ok || !g.isInit() {
+			return true
+		}
+		groups = append(groups, g)
+		return true
+	})
+	return groups
+}
+
 func (sr *schemaRepo) LoadResource(metadata *commonv1.Metadata) (Resource, bool) {
 	k := getKey(metadata)
 	s, ok := sr.resourceMap.Load(k)
 	if !ok {
-		return nil, false
+		if _, isGroup := sr.getGroup(metadata.GetGroup()); isGroup {
+			return nil, false
+		}
+		target, isAlias := sr.resolveAlias(metadata.GetGroup())
+		if !isAlias {
+			return nil, false
+		}
+		if s, ok = sr.resourceMap.Load(path.Join(target, metadata.GetName())); !ok {
+			return nil, false
+		}
 	}
 	return s.(Resource), true
 }
@@ -413,6 +614,13 @@ func (sr *schemaRepo) Close() {
 	sr.closer.CloseThenWait()
 	close(sr.eventCh)
 
+	sr.groupMux.Lock()
+	for name, dg := range sr.droppedGroups {
+		dg.timer.Stop()
+		delete(sr.droppedGroups, name)
+	}
+	sr.groupMux.Unlock()
+
 	sr.resourceMutex.Lock()
 	sr.resourceMap.Range(func(_, value any) bool {
 		if value == nil {
@@ -447,6 +655,7 @@ func (sr *schemaRepo) Close() {
 		return true
 	})
 	sr.groupMap = sync.Map{}
+	sr.groupAliases = sync.Map{}
 }
 
 var _ Group = (*group)(nil)
@@ -457,6 +666,7 @@ type group struct {
 	db               atomic.Value
 	groupSchema      atomic.Pointer[commonv1.Group]
 	l                *logger.Logger
+	dropped          atomic.Bool
 }
 
 func newGroup(
@@ -477,7 +687,7 @@ func (g *group) init(name string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	groupSchema, err := g.metadata.GroupRegistry().GetGroup(ctx, name)
-	if errors.As(err, schema.ErrGRPCResourceNotFound) {
+	if errors.Is(err, schema.ErrGRPCResourceNotFound) {
 		return nil
 	}
 	if err != nil {
@@ -499,6 +709,32 @@ func (g *group) initBySchema(groupSchema *commonv1.Group) error {
 	return nil
 }
 
+// reshard grows the shards of the open tsdb if the group only adds shards, which keeps the tsdb serving.
+// It returns false if the tsdb has to be reopened for the new schema.
+func (g *group) reshard(prev, curr *commonv1.Group) (bool, error) {
+	shardNum := curr.GetResourceOpts().GetShardNum()
+	if shardNum <= prev.GetResourceOpts().GetShardNum() {
+		return false, nil
+	}
+	r, ok := g.SupplyTSDB().(Resharder)
+	if !ok {
+		return false, nil
+	}
+	p, c := proto.Clone(prev).(*commonv1.Group), proto.Clone(curr).(*commonv1.Group)
+	p.Metadata, c.Metadata = nil, nil
+	c.UpdatedAt = p.UpdatedAt
+	c.ResourceOpts.ShardNum = p.GetResourceOpts().GetShardNum()
+	if !proto.Equal(p, c) {
+		return false, nil
+	}
+	if err := r.Reshard(shardNum); err != nil {
+		return false, err
+	}
+	g.groupSchema.Store(curr)
+	g.l.Info().Str("group", curr.GetMetadata().GetName()).Uint32("shard_num", shardNum).Msg("resharded the tsdb")
+	return true, nil
+}
+
 func (g *group) isInit() bool {
 	return g.GetSchema() != nil
 }
@@ -508,6 +744,9 @@ func (g *group) GetSchema() *commonv1.Group {
 }
 
 func (g *group) SupplyTSDB() io.Closer {
+	if g.dropped.Load() {
+		return nil
+	}
 	if v := g.db.Load(); v != nil {
 		return v.(io.Closer)
 	}
@@ -522,9 +761,8 @@ func (g *group) close() (err error) {
 	if !g.isInit() || g.isPortable() {
 		return nil
 	}
-	db := g.SupplyTSDB()
-	if db != nil {
-		err = multierr.Append(err, db.Close())
+	if v := g.db.Load(); v != nil {
+		err = multierr.Append(err, v.(io.Closer).Close())
 	}
 	return err
 }
@@ -0,0 +1,224 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package schema
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	"github.com/apache/skywalking-banyandb/banyand/metadata"
+	"github.com/apache/skywalking-banyandb/banyand/metadata/schema"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/test"
+	"github.com/apache/skywalking-banyandb/pkg/test/flags"
+)
+
+type mockGroupRegistry struct {
+	schema.Group
+	groups map[string]*commonv1.Group
+}
+
+func (m *mockGroupRegistry) GetGroup(_ context.Context, name string) (*commonv1.Group, error) {
+	if g, ok := m.groups[name]; ok {
+		return g, nil
+	}
+	return nil, schema.ErrGRPCResourceNotFound
+}
+
+type mockMetadata struct {
+	metadata.Repo
+	groupRegistry *mockGroupRegistry
+}
+
+func (m *mockMetadata) GroupRegistry() schema.Group {
+	return m.groupRegistry
+}
+
+type mockDB struct {
+	closed atomic.Int32
+}
+
+func (m *mockDB) Close() error {
+	m.closed.Add(1)
+	return nil
+}
+
+type mockResourceSupplier struct {
+	ResourceSchemaSupplier
+	root string
+	dbs  []*mockDB
+}
+
+func (m *mockResourceSupplier) OpenDB(groupSchema *commonv1.Group) (io.Closer, error) {
+	db := &mockDB{}
+	m.dbs = append(m.dbs, db)
+	return db, os.MkdirAll(filepath.Join(m.root, groupSchema.Metadata.Name), 0o755)
+}
+
+func (m *mockResourceSupplier) DropDB(groupSchema *commonv1.Group) error {
+	return os.RemoveAll(filepath.Join(m.root, groupSchema.Metadata.Name))
+}
+
+func TestDroppedGroupGracePeriod(t *testing.T) {
+	root, defFn := test.Space(require.New(t))
+	defer defFn()
+	groupMeta := &commonv1.Metadata{Name: "sw_metric"}
+	md := &mockMetadata{groupRegistry: &mockGroupRegistry{groups: map[string]*commonv1.Group{
+		"sw_metric": {Metadata: groupMeta, ResourceOpts: &commonv1.ResourceOpts{ShardNum: 1}},
+	}}}
+	supplier := &mockResourceSupplier{root: root}
+	sr := NewRepository(md, logger.GetLogger("test"), supplier, time.Hour).(*schemaRepo)
+	defer sr.Close()
+
+	_, err := sr.storeGroup(groupMeta)
+	require.NoError(t, err)
+	g, ok := sr.LoadGroup("sw_metric")
+	require.True(t, ok)
+	require.NotNil(t, g.SupplyTSDB())
+	dataPath := filepath.Join(root, "sw_metric", "data")
+	require.NoError(t, os.WriteFile(dataPath, []byte("data points"), 0o600))
+
+	t.Run("restore within the grace period", func(t *testing.T) {
+		groupSchema := md.groupRegistry.groups["sw_metric"]
+		delete(md.groupRegistry.groups, "sw_metric")
+		require.NoError(t, sr.deleteGroup(groupMeta))
+		_, ok = sr.LoadGroup("sw_metric")
+		assert.False(t, ok)
+		assert.Nil(t, g.SupplyTSDB(), "queries on the dropped group should be blocked")
+		assert.FileExists(t, dataPath)
+		require.Len(t, supplier.dbs, 1)
+		assert.Equal(t, int32(1), supplier.dbs[0].closed.Load(), "the tsdb of the dropped group should be closed once")
+
+		assert.ErrorIs(t, sr.RestoreGroup("sw_metric"), schema.ErrGRPCResourceNotFound, "the group should be restored in the registry first")
+		md.groupRegistry.groups["sw_metric"] = groupSchema
+		require.NoError(t, sr.RestoreGroup("sw_metric"))
+		restored, restoredOK := sr.LoadGroup("sw_metric")
+		require.True(t, restoredOK)
+		assert.NotNil(t, restored.SupplyTSDB())
+		assert.NotNil(t, g.SupplyTSDB(), "resources holding the group should see the restored data")
+		data, errRead := os.ReadFile(dataPath)
+		require.NoError(t, errRead)
+		assert.Equal(t, "data points", string(data))
+
+		assert.ErrorIs(t, sr.RestoreGroup("sw_metric"), ErrGroupNotDropped)
+	})
+
+	t.Run("re-create within the grace period", func(t *testing.T) {
+		require.NoError(t, sr.deleteGroup(groupMeta))
+		require.Len(t, supplier.dbs, 2)
+		assert.Equal(t, int32(1), supplier.dbs[1].closed.Load(), "the restored tsdb should be closed once")
+		_, err = sr.storeGroup(groupMeta)
+		require.NoError(t, err)
+		_, ok = sr.LoadGroup("sw_metric")
+		require.True(t, ok)
+		assert.FileExists(t, dataPath)
+		assert.ErrorIs(t, sr.RestoreGroup("sw_metric"), ErrGroupNotDropped)
+	})
+
+	t.Run("delete after the grace period", func(t *testing.T) {
+		sr.groupGracePeriod = 10 * time.Millisecond
+		require.NoError(t, sr.deleteGroup(groupMeta))
+		require.Len(t, supplier.dbs, 3)
+		assert.Equal(t, int32(1), supplier.dbs[2].closed.Load(), "the tsdb should be closed before its data is deleted")
+		assert.Eventually(t, func() bool {
+			_, errStat := os.Stat(filepath.Join(root, "sw_metric"))
+			return os.IsNotExist(errStat)
+		}, flags.EventuallyTimeout, 10*time.Millisecond)
+		assert.ErrorIs(t, sr.RestoreGroup("sw_metric"), ErrGroupNotDropped)
+	})
+}
+
+type mockResource struct{}
+
+func (mockResource) Close() error {
+	return nil
+}
+
+func TestGroupAlias(t *testing.T) {
+	root, defFn := test.Space(require.New(t))
+	defer defFn()
+	groupMeta := &commonv1.Metadata{Name: "sw_metric_v2", ModRevision: 1}
+	groupRegistry := &mockGroupRegistry{groups: map[string]*commonv1.Group{
+		"sw_metric_v2": {
+			Metadata:     groupMeta,
+			ResourceOpts: &commonv1.ResourceOpts{ShardNum: 1},
+			Aliases:      []string{"sw_metric", "sw_metric_old"},
+		},
+	}}
+	sr := NewRepository(&mockMetadata{groupRegistry: groupRegistry}, logger.GetLogger("test"), &mockResourceSupplier{root: root}, time.Hour).(*schemaRepo)
+	defer sr.Close()
+
+	target, err := sr.storeGroup(groupMeta)
+	require.NoError(t, err)
+	measure := &resourceSpec{delegated: mockResource{}}
+	sr.resourceMap.Store(getKey(&commonv1.Metadata{Group: "sw_metric_v2", Name: "service_cpm"}), measure)
+
+	for _, alias := range []string{"sw_metric", "sw_metric_old"} {
+		g, ok := sr.LoadGroup(alias)
+		require.True(t, ok, alias)
+		assert.Same(t, target, g, "writes to %s should land in the target group", alias)
+		r, ok := sr.LoadResource(&commonv1.Metadata{Group: alias, Name: "service_cpm"})
+		require.True(t, ok, alias)
+		assert.Same(t, measure, r, "queries on %s should read the target group", alias)
+	}
+	_, ok := sr.LoadResource(&commonv1.Metadata{Group: "sw_metric", Name: "unknown"})
+	assert.False(t, ok)
+
+	t.Run("a group takes precedence over an alias", func(t *testing.T) {
+		legacyMeta := &commonv1.Metadata{Name: "sw_metric"}
+		groupRegistry.groups["sw_metric"] = &commonv1.Group{Metadata: legacyMeta, ResourceOpts: &commonv1.ResourceOpts{ShardNum: 1}}
+		legacy, errStore := sr.storeGroup(legacyMeta)
+		require.NoError(t, errStore)
+		g, loaded := sr.LoadGroup("sw_metric")
+		require.True(t, loaded)
+		assert.Same(t, legacy, g)
+		_, loaded = sr.LoadResource(&commonv1.Metadata{Group: "sw_metric", Name: "service_cpm"})
+		assert.False(t, loaded)
+		require.NoError(t, sr.deleteGroup(legacyMeta))
+		delete(groupRegistry.groups, "sw_metric")
+	})
+
+	t.Run("drop an alias", func(t *testing.T) {
+		groupRegistry.groups["sw_metric_v2"] = &commonv1.Group{
+			Metadata:     &commonv1.Metadata{Name: "sw_metric_v2", ModRevision: 2},
+			ResourceOpts: &commonv1.ResourceOpts{ShardNum: 1},
+			Aliases:      []string{"sw_metric"},
+		}
+		_, errStore := sr.storeGroup(groupMeta)
+		require.NoError(t, errStore)
+		_, loaded := sr.LoadGroup("sw_metric_old")
+		assert.False(t, loaded)
+		_, loaded = sr.LoadGroup("sw_metric")
+		assert.True(t, loaded)
+	})
+
+	t.Run("delete the target group", func(t *testing.T) {
+		require.NoError(t, sr.deleteGroup(groupMeta))
+		_, loaded := sr.LoadGroup("sw_metric")
+		assert.False(t, loaded)
+	})
+}
@@ -20,6 +20,7 @@ package schema
 import (
 	"context"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
@@ -32,7 +33,10 @@ import (
 	"github.com/apache/skywalking-banyandb/pkg/logger"
 )
 
-const topNTagFamily = "__topN__"
+const (
+	topNTagFamily      = "__topN__"
+	topNAggregationTag = "aggregation"
+)
 
 var (
 	initTimeout        = 10 * time.Second
@@ -281,6 +285,9 @@ func (sr *schemaRepo) initGroup(groupSchema *commonv1.Group) (*group, error) {
 	if err := g.initBySchema(groupSchema); err != nil {
 		return nil, err
 	}
+	sr.groupMux.Lock()
+	sr.indexAliases(groupSchema.Metadata.Name, groupSchema.GetAliases())
+	sr.groupMux.Unlock()
 	return g, nil
 }
 
@@ -361,6 +368,15 @@ func buildTopNSourceMeasure(topNSchema *databasev1.TopNAggregation, sourceMeasur
 			return nil, fmt.Errorf("fail to find tag spec %s", tagName)
 		}
 	}
+	entityTagNames := slices.Clone(topNSchema.GetGroupByTagNames())
+	// The result set of every pre-aggregation function is tagged with the name of the function.
+	if len(topNSchema.GetAggregationFunctions()) > 0 {
+		seriesSpecs = append(seriesSpecs, &databasev1.TagSpec{
+			Name: topNAggregationTag,
+			Type: databasev1.TagType_TAG_TYPE_STRING,
+		})
+		entityTagNames = append(entityTagNames, topNAggregationTag)
+	}
 	// create a new "derived" measure for TopN result
 	return &databasev1.Measure{
 		Metadata: topNSchema.Metadata,
@@ -382,7 +398,7 @@ func buildTopNSourceMeasure(topNSchema *databasev1.TopNAggregation, sourceMeasur
 		},
 		Fields: []*databasev1.FieldSpec{topNValueFieldSpec},
 		Entity: &databasev1.Entity{
-			TagNames: append(topNSchema.GetGroupByTagNames(),
+			TagNames: append(entityTagNames,
 				"sortDirection",
 				"rankNumber"),
 		},
@@ -19,6 +19,7 @@
 package schema
 
 import (
+	"errors"
 	"io"
 
 	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
@@ -26,6 +27,9 @@ import (
 	"github.com/apache/skywalking-banyandb/banyand/metadata/schema"
 )
 
+// ErrGroupNotDropped is returned when restoring a group which isn't in the grace period of deletion.
+var ErrGroupNotDropped = errors.New("group is not dropped or its data is deleted")
+
 // EventType defines actions of events.
 type EventType uint8
 
@@ -78,6 +82,11 @@ type Supplier interface {
 	SupplyTSDB() io.Closer
 }
 
+// Resharder grows the shards of an open tsdb.
+type Resharder interface {
+	Reshard(shardNum uint32) error
+}
+
 // ResourceSchemaSupplier allows get a ResourceSchema from the metadata.
 type ResourceSchemaSupplier interface {
 	ResourceSchema(metadata *commonv1.Metadata) (ResourceSchema, error)
@@ -88,6 +97,7 @@ type ResourceSchemaSupplier interface {
 type ResourceSupplier interface {
 	ResourceSchemaSupplier
 	OpenDB(groupSchema *commonv1.Group) (io.Closer, error)
+	DropDB(groupSchema *commonv1.Group) error
 }
 
 // Repository is the collection of several hierarchies groups by a "Group".
@@ -96,6 +106,9 @@ type Repository interface {
 	Init(schema.Kind) []int64
 	SendMetadataEvent(MetadataEvent)
 	LoadGroup(name string) (Group, bool)
+	LoadAllGroups() []Group
+	RestoreGroup(name string) error
+	ReopenGroups(relocate func() error) error
 	LoadResource(metadata *commonv1.Metadata) (Resource, bool)
 	Close()
 	StopCh() <-chan struct{}
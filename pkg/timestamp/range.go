@@ -60,6 +60,19 @@ func (t TimeRange) Overlapping(other TimeRange) bool {
 	return !t.Start.After(other.End) && !other.Start.After(t.End)
 }
 
+// UnixNanoBounds returns the smallest and the largest unix nanoseconds in the TimeRange.
+// The scans filter the timestamps between the bounds inclusively, so that an excluded start or end is left out.
+func (t TimeRange) UnixNanoBounds() (minTimestamp, maxTimestamp int64) {
+	minTimestamp, maxTimestamp = t.Start.UnixNano(), t.End.UnixNano()
+	if !t.IncludeStart {
+		minTimestamp++
+	}
+	if !t.IncludeEnd {
+		maxTimestamp--
+	}
+	return minTimestamp, maxTimestamp
+}
+
 // Duration converts TimeRange to time.Duration.
 func (t TimeRange) Duration() time.Duration {
 	return t.End.Sub(t.Start)
@@ -84,6 +97,9 @@ func (t TimeRange) String() string {
 	return string(buf)
 }
 
+// AllTime is the time range including all the times that can be represented.
+var AllTime = NewInclusiveTimeRange(time.Unix(0, MinNanoTime), time.Unix(0, MaxNanoTime))
+
 // NewInclusiveTimeRange returns TimeRange includes start and end time.
 func NewInclusiveTimeRange(start, end time.Time) TimeRange {
 	return TimeRange{
@@ -17,7 +17,10 @@
 
 package timestamp
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func Test_findRange(t *testing.T) {
 	type args struct {
@@ -114,3 +117,33 @@ func Test_findRange(t *testing.T) {
 		})
 	}
 }
+
+func TestUnixNanoBounds(t *testing.T) {
+	start, end := time.Unix(0, 100), time.Unix(0, 200)
+	tests := []struct {
+		name         string
+		includeStart bool
+		includeEnd   bool
+		wantMin      int64
+		wantMax      int64
+	}{
+		{name: "inclusive", includeStart: true, includeEnd: true, wantMin: 100, wantMax: 200},
+		{name: "section", includeStart: true, includeEnd: false, wantMin: 100, wantMax: 199},
+		{name: "exclusive start", includeStart: false, includeEnd: true, wantMin: 101, wantMax: 200},
+		{name: "exclusive", includeStart: false, includeEnd: false, wantMin: 101, wantMax: 199},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := NewTimeRange(start, end, tt.includeStart, tt.includeEnd)
+			gotMin, gotMax := tr.UnixNanoBounds()
+			if gotMin != tt.wantMin || gotMax != tt.wantMax {
+				t.Errorf("UnixNanoBounds() = (%d, %d), want (%d, %d)", gotMin, gotMax, tt.wantMin, tt.wantMax)
+			}
+			for _, ts := range []int64{99, 100, 101, 199, 200, 201} {
+				if got, want := tr.Contains(ts), ts >= gotMin && ts <= gotMax; got != want {
+					t.Errorf("Contains(%d) = %v, want %v as the bounds", ts, got, want)
+				}
+			}
+		})
+	}
+}
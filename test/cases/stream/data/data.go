@@ -84,6 +84,7 @@ var VerifyFn = func(innerGm gm.Gomega, sharedContext helpers.SharedContext, args
 	innerGm.Expect(cmp.Equal(resp, want,
 		protocmp.IgnoreUnknown(),
 		protocmp.IgnoreFields(&streamv1.Element{}, "timestamp"),
+		protocmp.IgnoreFields(&streamv1.QueryResponse{}, "bytes_read", "next_page_token"),
 		protocmp.Transform())).
 		To(gm.BeTrue(), func() string {
 			j, err := protojson.Marshal(resp)
@@ -98,6 +99,37 @@ var VerifyFn = func(innerGm gm.Gomega, sharedContext helpers.SharedContext, args
 		})
 }
 
+// VerifyPagesFn verifies that paging through the query response in pages of pageSize returns the whole response.
+var VerifyPagesFn = func(innerGm gm.Gomega, sharedContext helpers.SharedContext, args helpers.Args, pageSize uint32) {
+	i, err := inputFS.ReadFile("input/" + args.Input + ".yaml")
+	innerGm.Expect(err).NotTo(gm.HaveOccurred())
+	query := &streamv1.QueryRequest{}
+	helpers.UnmarshalYAML(i, query)
+	query.TimeRange = helpers.TimeRange(args, sharedContext)
+	c := streamv1.NewStreamServiceClient(sharedContext.Connection)
+	ctx := context.Background()
+	resp, err := c.Query(ctx, query)
+	innerGm.Expect(err).NotTo(gm.HaveOccurred(), query.String())
+	innerGm.Expect(resp.GetNextPageToken()).To(gm.BeEmpty())
+	var want []string
+	for _, e := range resp.GetElements() {
+		want = append(want, e.GetElementId())
+	}
+	innerGm.Expect(len(want)).To(gm.BeNumerically(">", int(pageSize)))
+	query.Limit = pageSize
+	var got []string
+	for page := 0; page == 0 || query.PageToken != ""; page++ {
+		innerGm.Expect(page).To(gm.BeNumerically("<", len(want)), "the pages don't end")
+		resp, err = c.Query(ctx, query)
+		innerGm.Expect(err).NotTo(gm.HaveOccurred(), query.String())
+		for _, e := range resp.GetElements() {
+			got = append(got, e.GetElementId())
+		}
+		query.PageToken = resp.GetNextPageToken()
+	}
+	innerGm.Expect(got).To(gm.Equal(want))
+}
+
 func loadData(stream streamv1.StreamService_WriteClient, metadata *commonv1.Metadata, dataFile string, baseTime time.Time, interval time.Duration) {
 	var templates []interface{}
 	content, err := dataFS.ReadFile("testdata/" + dataFile)
@@ -64,6 +64,7 @@ var _ = g.DescribeTable("Scanning Streams", func(args helpers.Args) {
 	}),
 	g.Entry("sort desc", helpers.Args{Input: "sort_desc", Duration: 1 * time.Hour}),
 	g.Entry("sort with filter", helpers.Args{Input: "sort_filter", Duration: 1 * time.Hour}),
+	g.Entry("sort with then by", helpers.Args{Input: "sort_then_by", Duration: 1 * time.Hour}),
 	g.Entry("global index", helpers.Args{Input: "global_index", Duration: 1 * time.Hour}),
 	g.Entry("multi-global index", helpers.Args{Input: "global_indices", Duration: 1 * time.Hour}),
 	g.Entry("filter by non-indexed tag", helpers.Args{Input: "filter_tag", Duration: 1 * time.Hour}),
@@ -72,10 +73,24 @@ var _ = g.DescribeTable("Scanning Streams", func(args helpers.Args) {
 	g.Entry("numeric local index: less", helpers.Args{Input: "less", Duration: 1 * time.Hour}),
 	g.Entry("numeric local index: less and eq", helpers.Args{Input: "less_eq", Duration: 1 * time.Hour}),
 	g.Entry("logical expression", helpers.Args{Input: "logical", Duration: 1 * time.Hour}),
+	g.Entry("not in", helpers.Args{Input: "not_in", Duration: 1 * time.Hour}),
+	g.Entry("not equal with a positive condition", helpers.Args{Input: "not_eq", Duration: 1 * time.Hour}),
+	g.Entry("not in excluding every element", helpers.Args{Input: "not_in_all", Duration: 1 * time.Hour, WantEmpty: true}),
 	g.Entry("having", helpers.Args{Input: "having", Duration: 1 * time.Hour}),
 	g.Entry("having non indexed", helpers.Args{Input: "having_non_indexed", Duration: 1 * time.Hour}),
 	g.Entry("having non indexed array", helpers.Args{Input: "having_non_indexed_arr", Duration: 1 * time.Hour}),
 	g.Entry("full text searching", helpers.Args{Input: "search", Duration: 1 * time.Hour}),
 	g.Entry("indexed only tags", helpers.Args{Input: "indexed_only", Duration: 1 * time.Hour}),
 	g.Entry("filter by non-indexed tag with or", helpers.Args{Input: "filter_no_indexed_or", Duration: 1 * time.Hour}),
+	g.Entry("top k per group", helpers.Args{Input: "top_k_per_group", Duration: 1 * time.Hour}),
+)
+
+var _ = g.DescribeTable("Paging Streams", func(args helpers.Args) {
+	gm.Eventually(func(innerGm gm.Gomega) {
+		stream_test_data.VerifyPagesFn(innerGm, SharedContext, args, 2)
+	}, flags.EventuallyTimeout).Should(gm.Succeed())
+},
+	g.Entry("order asc", helpers.Args{Input: "order_asc", Duration: 1 * time.Hour}),
+	g.Entry("order desc", helpers.Args{Input: "order_desc", Duration: 1 * time.Hour}),
+	g.Entry("filter by an index", helpers.Args{Input: "less", Duration: 1 * time.Hour}),
 )
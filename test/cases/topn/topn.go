@@ -43,4 +43,5 @@ var _ = g.DescribeTable("TopN Tests", verify,
 	g.Entry("max top3 order by desc", helpers.Args{Input: "aggr_desc", Duration: 25 * time.Minute, Offset: -20 * time.Minute}),
 	g.Entry("max top3 with condition order by desc", helpers.Args{Input: "condition_aggr_desc", Duration: 25 * time.Minute, Offset: -20 * time.Minute}),
 	g.Entry("max top3 for null group order by desc", helpers.Args{Input: "null_group", Duration: 25 * time.Minute, Offset: -20 * time.Minute}),
+	g.Entry("min bottom3 order by asc", helpers.Args{Input: "aggr_asc", Duration: 25 * time.Minute, Offset: -20 * time.Minute}),
 )
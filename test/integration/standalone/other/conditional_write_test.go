@@ -0,0 +1,97 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package integration_other_test
+
+import (
+	"context"
+	"time"
+
+	g "github.com/onsi/ginkgo/v2"
+	gm "github.com/onsi/gomega"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/pkg/grpchelper"
+	"github.com/apache/skywalking-banyandb/pkg/test/flags"
+	"github.com/apache/skywalking-banyandb/pkg/test/setup"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+var _ = g.Describe("Conditional writes", func() {
+	var deferFn func()
+	var conn *grpclib.ClientConn
+	var client measurev1.MeasureServiceClient
+
+	g.BeforeEach(func() {
+		var addr string
+		addr, _, deferFn = setup.Standalone()
+		var err error
+		conn, err = grpchelper.Conn(addr, 10*time.Second, grpclib.WithTransportCredentials(insecure.NewCredentials()))
+		gm.Expect(err).NotTo(gm.HaveOccurred())
+		client = measurev1.NewMeasureServiceClient(conn)
+	})
+	g.AfterEach(func() {
+		gm.Expect(conn.Close()).To(gm.Succeed())
+		deferFn()
+	})
+
+	str := func(v string) *modelv1.TagValue {
+		return &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: v}}}
+	}
+	write := func(ts time.Time, id string, cond *measurev1.WriteCondition) modelv1.Status {
+		wc, err := client.Write(context.Background())
+		gm.Expect(err).NotTo(gm.HaveOccurred())
+		gm.Expect(wc.Send(&measurev1.WriteRequest{
+			Metadata: &commonv1.Metadata{Name: "service_cpm_minute", Group: "sw_metric"},
+			DataPoint: &measurev1.DataPointValue{
+				Timestamp: timestamppb.New(ts),
+				TagFamilies: []*modelv1.TagFamilyForWrite{{
+					Tags: []*modelv1.TagValue{str(id), str("entity_1")},
+				}},
+				Fields: []*modelv1.FieldValue{
+					{Value: &modelv1.FieldValue_Int{Int: &modelv1.Int{Value: 1}}},
+					{Value: &modelv1.FieldValue_Int{Int: &modelv1.Int{Value: 1}}},
+				},
+			},
+			MessageId: uint64(time.Now().UnixNano()),
+			Condition: cond,
+		})).To(gm.Succeed())
+		resp, err := wc.Recv()
+		gm.Expect(err).NotTo(gm.HaveOccurred())
+		gm.Expect(wc.CloseSend()).To(gm.Succeed())
+		return resp.GetStatus()
+	}
+	expect := func(id string) *measurev1.WriteCondition {
+		return &measurev1.WriteCondition{TagFamily: "default", TagName: "id", Value: str(id)}
+	}
+
+	g.It("replies whether the condition holds", func() {
+		ns := timestamp.NowMilli().UnixNano()
+		baseTime := time.Unix(0, ns-ns%int64(time.Minute))
+		gm.Expect(write(baseTime, "a", nil)).To(gm.Equal(modelv1.Status_STATUS_SUCCEED))
+		gm.Eventually(func() modelv1.Status {
+			return write(baseTime.Add(time.Minute), "b", expect("a"))
+		}, flags.EventuallyTimeout).Should(gm.Equal(modelv1.Status_STATUS_SUCCEED))
+		gm.Expect(write(baseTime.Add(2*time.Minute), "c", expect("a"))).To(gm.Equal(modelv1.Status_STATUS_CONDITION_FAILED))
+		gm.Expect(write(baseTime.Add(2*time.Minute), "c", expect("b"))).To(gm.Equal(modelv1.Status_STATUS_SUCCEED))
+	})
+})
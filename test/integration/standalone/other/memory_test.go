@@ -0,0 +1,79 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package integration_other_test
+
+import (
+	"context"
+	"time"
+
+	g "github.com/onsi/ginkgo/v2"
+	gm "github.com/onsi/gomega"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/pkg/grpchelper"
+	"github.com/apache/skywalking-banyandb/pkg/test/setup"
+)
+
+var _ = g.Describe("Memory exhausted writes", func() {
+	var deferFn func()
+	var conn *grpclib.ClientConn
+
+	g.BeforeEach(func() {
+		var addr string
+		// The live heap always exceeds a single byte.
+		addr, _, deferFn = setup.Standalone("--measure-write-memory-limit=1")
+		var err error
+		conn, err = grpchelper.Conn(addr, 10*time.Second, grpclib.WithTransportCredentials(insecure.NewCredentials()))
+		gm.Expect(err).NotTo(gm.HaveOccurred())
+	})
+	g.AfterEach(func() {
+		gm.Expect(conn.Close()).To(gm.Succeed())
+		deferFn()
+	})
+
+	g.It("replies the rejected writes", func() {
+		wc, err := measurev1.NewMeasureServiceClient(conn).Write(context.Background())
+		gm.Expect(err).NotTo(gm.HaveOccurred())
+		gm.Expect(wc.Send(&measurev1.WriteRequest{
+			Metadata: &commonv1.Metadata{Name: "service_cpm_minute", Group: "sw_metric"},
+			DataPoint: &measurev1.DataPointValue{
+				Timestamp: timestamppb.New(time.Now().Truncate(time.Millisecond)),
+				TagFamilies: []*modelv1.TagFamilyForWrite{{
+					Tags: []*modelv1.TagValue{
+						{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "1"}}},
+						{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "entity_1"}}},
+					},
+				}},
+				Fields: []*modelv1.FieldValue{
+					{Value: &modelv1.FieldValue_Int{Int: &modelv1.Int{Value: 1}}},
+					{Value: &modelv1.FieldValue_Int{Int: &modelv1.Int{Value: 1}}},
+				},
+			},
+			MessageId: uint64(time.Now().UnixNano()),
+		})).To(gm.Succeed())
+		resp, err := wc.Recv()
+		gm.Expect(err).NotTo(gm.HaveOccurred())
+		gm.Expect(wc.CloseSend()).To(gm.Succeed())
+		gm.Expect(resp.GetStatus()).To(gm.Equal(modelv1.Status_STATUS_MEMORY_EXHAUSTED))
+	})
+})
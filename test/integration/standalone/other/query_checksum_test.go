@@ -0,0 +1,84 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package integration_other_test
+
+import (
+	"context"
+	"time"
+
+	g "github.com/onsi/ginkgo/v2"
+	gm "github.com/onsi/gomega"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/pkg/grpchelper"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/test/flags"
+	"github.com/apache/skywalking-banyandb/pkg/test/setup"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+	casesStreamData "github.com/apache/skywalking-banyandb/test/cases/stream/data"
+)
+
+var _ = g.Describe("Query checksum", func() {
+	var deferFn func()
+	var conn *grpclib.ClientConn
+	var baseTime time.Time
+
+	g.BeforeEach(func() {
+		var addr string
+		addr, _, deferFn = setup.Standalone()
+		var err error
+		conn, err = grpchelper.Conn(addr, 10*time.Second, grpclib.WithTransportCredentials(insecure.NewCredentials()))
+		gm.Expect(err).NotTo(gm.HaveOccurred())
+		ns := timestamp.NowMilli().UnixNano()
+		baseTime = time.Unix(0, ns-ns%int64(time.Minute))
+		casesStreamData.Write(conn, "data.json", baseTime, 500*time.Millisecond)
+	})
+	g.AfterEach(func() {
+		gm.Expect(conn.Close()).To(gm.Succeed())
+		deferFn()
+	})
+
+	g.It("returns the checksum computed by the data node in the trailer", func() {
+		client := streamv1.NewStreamServiceClient(conn)
+		gm.Eventually(func(innerGm gm.Gomega) {
+			var trailer metadata.MD
+			resp, err := client.Query(context.Background(), &streamv1.QueryRequest{
+				Groups: []string{"default"},
+				Name:   "sw",
+				TimeRange: &modelv1.TimeRange{
+					Begin: timestamppb.New(baseTime.Add(-time.Hour)),
+					End:   timestamppb.New(baseTime.Add(time.Hour)),
+				},
+				Projection: &modelv1.TagProjection{TagFamilies: []*modelv1.TagProjection_TagFamily{
+					{Name: "searchable", Tags: []string{"trace_id"}},
+				}},
+				Limit:    100,
+				Checksum: true,
+			}, grpclib.Trailer(&trailer))
+			innerGm.Expect(err).NotTo(gm.HaveOccurred())
+			innerGm.Expect(resp.GetElements()).To(gm.HaveLen(5))
+			innerGm.Expect(resp.GetChecksum()).To(gm.Equal(pbv1.StreamQueryChecksum(resp.GetElements())))
+			innerGm.Expect(trailer.Get(pbv1.ChecksumTrailerKey)).To(gm.Equal([]string{pbv1.FormatChecksum(resp.GetChecksum())}))
+		}, flags.EventuallyTimeout).Should(gm.Succeed())
+	})
+})
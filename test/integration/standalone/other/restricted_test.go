@@ -0,0 +1,140 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package integration_other_test
+
+import (
+	"context"
+	"time"
+
+	g "github.com/onsi/ginkgo/v2"
+	gm "github.com/onsi/gomega"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/pkg/grpchelper"
+	"github.com/apache/skywalking-banyandb/pkg/test/flags"
+	"github.com/apache/skywalking-banyandb/pkg/test/setup"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+	casesStreamData "github.com/apache/skywalking-banyandb/test/cases/stream/data"
+)
+
+var _ = g.Describe("Restricted tags", func() {
+	var deferFn func()
+	var conn *grpclib.ClientConn
+	var baseTime time.Time
+	var client streamv1.StreamServiceClient
+
+	g.BeforeEach(func() {
+		var addr string
+		addr, _, deferFn = setup.Standalone("--stream-restricted-tag-token=secret")
+		var err error
+		conn, err = grpchelper.Conn(addr, 10*time.Second, grpclib.WithTransportCredentials(insecure.NewCredentials()))
+		gm.Expect(err).NotTo(gm.HaveOccurred())
+		registry := databasev1.NewStreamRegistryServiceClient(conn)
+		resp, err := registry.Get(context.Background(), &databasev1.StreamRegistryServiceGetRequest{
+			Metadata: &commonv1.Metadata{Name: "sw", Group: "default"},
+		})
+		gm.Expect(err).NotTo(gm.HaveOccurred())
+		s := resp.GetStream()
+		for _, tf := range s.GetTagFamilies() {
+			for _, ts := range tf.GetTags() {
+				if ts.GetName() == "trace_id" || ts.GetName() == "duration" {
+					ts.Restricted = true
+				}
+			}
+		}
+		_, err = registry.Update(context.Background(), &databasev1.StreamRegistryServiceUpdateRequest{Stream: s})
+		gm.Expect(err).NotTo(gm.HaveOccurred())
+		ns := timestamp.NowMilli().UnixNano()
+		baseTime = time.Unix(0, ns-ns%int64(time.Minute))
+		casesStreamData.Write(conn, "data.json", baseTime, 500*time.Millisecond)
+		client = streamv1.NewStreamServiceClient(conn)
+	})
+	g.AfterEach(func() {
+		gm.Expect(conn.Close()).To(gm.Succeed())
+		deferFn()
+	})
+
+	query := func(ctx context.Context, criteria *modelv1.Criteria, orderBy *modelv1.QueryOrder) (*streamv1.QueryResponse, error) {
+		return client.Query(ctx, &streamv1.QueryRequest{
+			Groups: []string{"default"},
+			Name:   "sw",
+			TimeRange: &modelv1.TimeRange{
+				Begin: timestamppb.New(baseTime.Add(-time.Hour)),
+				End:   timestamppb.New(baseTime.Add(time.Hour)),
+			},
+			Projection: &modelv1.TagProjection{TagFamilies: []*modelv1.TagProjection_TagFamily{
+				{Name: "searchable", Tags: []string{"trace_id", "service_id", "duration"}},
+			}},
+			Criteria: criteria,
+			OrderBy:  orderBy,
+			Limit:    100,
+		})
+	}
+	traceIDs := func(resp *streamv1.QueryResponse) []string {
+		var ids []string
+		for _, e := range resp.GetElements() {
+			gm.Expect(e.GetTagFamilies()[0].GetTags()[1].GetValue().GetStr().GetValue()).To(gm.Equal("webapp_id"))
+			ids = append(ids, e.GetTagFamilies()[0].GetTags()[0].GetValue().GetStr().GetValue())
+		}
+		return ids
+	}
+	authorized := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+	byTraceID := &modelv1.Criteria{Exp: &modelv1.Criteria_Condition{Condition: &modelv1.Condition{
+		Name:  "trace_id",
+		Op:    modelv1.Condition_BINARY_OP_EQ,
+		Value: &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "1"}}},
+	}}}
+	byDuration := &modelv1.QueryOrder{IndexRuleName: "duration", Sort: modelv1.Sort_SORT_DESC}
+
+	g.It("redacts the restricted tags unless the caller presents the token", func() {
+		gm.Eventually(func(innerGm gm.Gomega) {
+			resp, err := query(authorized, nil, nil)
+			innerGm.Expect(err).NotTo(gm.HaveOccurred())
+			innerGm.Expect(resp.GetElements()).To(gm.HaveLen(5))
+			innerGm.Expect(traceIDs(resp)).NotTo(gm.ContainElement(""))
+		}, flags.EventuallyTimeout).Should(gm.Succeed())
+		for _, ctx := range []context.Context{
+			context.Background(),
+			metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer wrong"),
+		} {
+			resp, err := query(ctx, nil, nil)
+			gm.Expect(err).NotTo(gm.HaveOccurred())
+			gm.Expect(resp.GetElements()).To(gm.HaveLen(5))
+			gm.Expect(traceIDs(resp)).To(gm.HaveEach(""))
+		}
+	})
+
+	g.It("rejects the criteria and the order referring to the restricted tags", func() {
+		gm.Eventually(func(innerGm gm.Gomega) {
+			resp, err := query(authorized, byTraceID, byDuration)
+			innerGm.Expect(err).NotTo(gm.HaveOccurred())
+			innerGm.Expect(traceIDs(resp)).To(gm.Equal([]string{"1"}))
+		}, flags.EventuallyTimeout).Should(gm.Succeed())
+		_, err := query(context.Background(), byTraceID, nil)
+		gm.Expect(err).To(gm.MatchError(gm.ContainSubstring("restricted tag")))
+		_, err = query(context.Background(), nil, byDuration)
+		gm.Expect(err).To(gm.MatchError(gm.ContainSubstring("restricted tag")))
+	})
+})
@@ -0,0 +1,96 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package integration_other_test
+
+import (
+	"context"
+	"time"
+
+	g "github.com/onsi/ginkgo/v2"
+	gm "github.com/onsi/gomega"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/pkg/grpchelper"
+	"github.com/apache/skywalking-banyandb/pkg/test/flags"
+	"github.com/apache/skywalking-banyandb/pkg/test/setup"
+)
+
+var _ = g.Describe("Out of retention writes", func() {
+	var deferFn func()
+	var conn *grpclib.ClientConn
+	var client measurev1.MeasureServiceClient
+
+	g.BeforeEach(func() {
+		var addr string
+		addr, _, deferFn = setup.Standalone()
+		var err error
+		conn, err = grpchelper.Conn(addr, 10*time.Second, grpclib.WithTransportCredentials(insecure.NewCredentials()))
+		gm.Expect(err).NotTo(gm.HaveOccurred())
+		registry := databasev1.NewGroupRegistryServiceClient(conn)
+		resp, err := registry.Get(context.Background(), &databasev1.GroupRegistryServiceGetRequest{Group: "sw_metric"})
+		gm.Expect(err).NotTo(gm.HaveOccurred())
+		group := resp.GetGroup()
+		group.ResourceOpts.OutOfRetentionPolicy = commonv1.OutOfRetentionPolicy_OUT_OF_RETENTION_POLICY_REJECT
+		_, err = registry.Update(context.Background(), &databasev1.GroupRegistryServiceUpdateRequest{Group: group})
+		gm.Expect(err).NotTo(gm.HaveOccurred())
+		client = measurev1.NewMeasureServiceClient(conn)
+	})
+	g.AfterEach(func() {
+		gm.Expect(conn.Close()).To(gm.Succeed())
+		deferFn()
+	})
+
+	write := func(ts time.Time) modelv1.Status {
+		wc, err := client.Write(context.Background())
+		gm.Expect(err).NotTo(gm.HaveOccurred())
+		gm.Expect(wc.Send(&measurev1.WriteRequest{
+			Metadata: &commonv1.Metadata{Name: "service_cpm_minute", Group: "sw_metric"},
+			DataPoint: &measurev1.DataPointValue{
+				Timestamp: timestamppb.New(ts.Truncate(time.Millisecond)),
+				TagFamilies: []*modelv1.TagFamilyForWrite{{
+					Tags: []*modelv1.TagValue{
+						{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "1"}}},
+						{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "entity_1"}}},
+					},
+				}},
+				Fields: []*modelv1.FieldValue{
+					{Value: &modelv1.FieldValue_Int{Int: &modelv1.Int{Value: 1}}},
+					{Value: &modelv1.FieldValue_Int{Int: &modelv1.Int{Value: 1}}},
+				},
+			},
+			MessageId: uint64(time.Now().UnixNano()),
+		})).To(gm.Succeed())
+		resp, err := wc.Recv()
+		gm.Expect(err).NotTo(gm.HaveOccurred())
+		gm.Expect(wc.CloseSend()).To(gm.Succeed())
+		return resp.GetStatus()
+	}
+
+	g.It("replies the rejected writes", func() {
+		gm.Eventually(func() modelv1.Status {
+			return write(time.Now().AddDate(0, -1, 0))
+		}, flags.EventuallyTimeout).Should(gm.Equal(modelv1.Status_STATUS_OUT_OF_RETENTION))
+		gm.Expect(write(time.Now())).To(gm.Equal(modelv1.Status_STATUS_SUCCEED))
+	})
+})
@@ -0,0 +1,225 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+package integration_other_test
+
+import (
+	"context"
+	"time"
+
+	g "github.com/onsi/ginkgo/v2"
+	gm "github.com/onsi/gomega"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/pkg/convert"
+	"github.com/apache/skywalking-banyandb/pkg/grpchelper"
+	"github.com/apache/skywalking-banyandb/pkg/partition"
+	"github.com/apache/skywalking-banyandb/pkg/test/flags"
+	"github.com/apache/skywalking-banyandb/pkg/test/setup"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+var _ = g.Describe("Salted sharding", func() {
+	const (
+		group    = "sw_salted"
+		shardNum = 2
+	)
+	var deferFn func()
+	var conn *grpclib.ClientConn
+	var client measurev1.MeasureServiceClient
+	var measureRegistry databasev1.MeasureRegistryServiceClient
+	var source *databasev1.Measure
+	var baseTime time.Time
+
+	str := func(v string) *modelv1.TagValue {
+		return &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: v}}}
+	}
+	tagFamilies := func(id string) []*modelv1.TagFamilyForWrite {
+		return []*modelv1.TagFamilyForWrite{{Tags: []*modelv1.TagValue{str(id), str("entity_1")}}}
+	}
+	// shardOf is the shard the salted strategy picks for the data point of entity_1 at ts.
+	shardOf := func(ts time.Time) uint {
+		locator := partition.NewEntityLocator(source.GetTagFamilies(), source.GetEntity(), 0)
+		_, _, shardID, err := locator.LocateWithOpts(source.GetMetadata().GetName(), tagFamilies("any"), shardNum, partition.ShardingOpts{
+			Salt:     convert.Int64ToBytes(ts.UnixNano()),
+			Strategy: commonv1.ShardingStrategy_SHARDING_STRATEGY_SALTED_ENTITY,
+		})
+		gm.Expect(err).NotTo(gm.HaveOccurred())
+		return uint(shardID)
+	}
+	write := func(ts time.Time, id string, cond *measurev1.WriteCondition) modelv1.Status {
+		wc, err := client.Write(context.Background())
+		gm.Expect(err).NotTo(gm.HaveOccurred())
+		gm.Expect(wc.Send(&measurev1.WriteRequest{
+			Metadata: source.GetMetadata(),
+			DataPoint: &measurev1.DataPointValue{
+				Timestamp:   timestamppb.New(ts),
+				TagFamilies: tagFamilies(id),
+				Fields: []*modelv1.FieldValue{
+					{Value: &modelv1.FieldValue_Int{Int: &modelv1.Int{Value: 1}}},
+					{Value: &modelv1.FieldValue_Int{Int: &modelv1.Int{Value: 1}}},
+				},
+			},
+			MessageId: uint64(time.Now().UnixNano()),
+			Condition: cond,
+		})).To(gm.Succeed())
+		resp, err := wc.Recv()
+		gm.Expect(err).NotTo(gm.HaveOccurred())
+		gm.Expect(wc.CloseSend()).To(gm.Succeed())
+		return resp.GetStatus()
+	}
+	query := func(name string, begin, end time.Time) ([]*measurev1.DataPoint, error) {
+		resp, err := client.Query(context.Background(), &measurev1.QueryRequest{
+			Groups:    []string{group},
+			Name:      name,
+			TimeRange: &modelv1.TimeRange{Begin: timestamppb.New(begin), End: timestamppb.New(end)},
+			TagProjection: &modelv1.TagProjection{
+				TagFamilies: []*modelv1.TagProjection_TagFamily{{Name: "default", Tags: []string{"entity_id"}}},
+			},
+			FieldProjection: &measurev1.QueryRequest_FieldProjection{Names: []string{"total"}},
+		})
+		return resp.GetDataPoints(), err
+	}
+	// waitForMeasure waits until the data node opens the measure, otherwise the data points written to it are dropped.
+	waitForMeasure := func(name string) {
+		gm.Eventually(func() error {
+			_, err := query(name, baseTime, baseTime.Add(time.Minute))
+			return err
+		}, flags.EventuallyTimeout).Should(gm.Succeed())
+	}
+
+	g.BeforeEach(func() {
+		var addr string
+		addr, _, deferFn = setup.Standalone()
+		var err error
+		conn, err = grpchelper.Conn(addr, 10*time.Second, grpclib.WithTransportCredentials(insecure.NewCredentials()))
+		gm.Expect(err).NotTo(gm.HaveOccurred())
+		client = measurev1.NewMeasureServiceClient(conn)
+		measureRegistry = databasev1.NewMeasureRegistryServiceClient(conn)
+		ctx := context.Background()
+		_, err = databasev1.NewGroupRegistryServiceClient(conn).Create(ctx, &databasev1.GroupRegistryServiceCreateRequest{
+			Group: &commonv1.Group{
+				Metadata: &commonv1.Metadata{Name: group},
+				Catalog:  commonv1.Catalog_CATALOG_MEASURE,
+				ResourceOpts: &commonv1.ResourceOpts{
+					ShardNum:         shardNum,
+					SegmentInterval:  &commonv1.IntervalRule{Unit: commonv1.IntervalRule_UNIT_DAY, Num: 1},
+					Ttl:              &commonv1.IntervalRule{Unit: commonv1.IntervalRule_UNIT_DAY, Num: 7},
+					ShardingStrategy: commonv1.ShardingStrategy_SHARDING_STRATEGY_SALTED_ENTITY,
+				},
+			},
+		})
+		gm.Expect(err).NotTo(gm.HaveOccurred())
+		resp, err := measureRegistry.Get(ctx, &databasev1.MeasureRegistryServiceGetRequest{
+			Metadata: &commonv1.Metadata{Name: "service_cpm_minute", Group: "sw_metric"},
+		})
+		gm.Expect(err).NotTo(gm.HaveOccurred())
+		source = proto.Clone(resp.GetMeasure()).(*databasev1.Measure)
+		source.Metadata = &commonv1.Metadata{Name: "service_cpm_minute", Group: group}
+		_, err = measureRegistry.Create(ctx, &databasev1.MeasureRegistryServiceCreateRequest{Measure: source})
+		gm.Expect(err).NotTo(gm.HaveOccurred())
+		ns := timestamp.NowMilli().UnixNano()
+		baseTime = time.Unix(0, ns-ns%int64(time.Hour)).Add(-time.Hour)
+		waitForMeasure(source.GetMetadata().GetName())
+	})
+	g.AfterEach(func() {
+		gm.Expect(conn.Close()).To(gm.Succeed())
+		deferFn()
+	})
+
+	g.It("reads a series spread over the shards", func() {
+		const num = 20
+		shards := make(map[uint]struct{})
+		for i := 0; i < num; i++ {
+			shards[shardOf(baseTime.Add(time.Duration(i)*time.Minute))] = struct{}{}
+		}
+		gm.Expect(shards).To(gm.HaveLen(shardNum), "the data points of the series should be spread over all the shards")
+		for i := 0; i < num; i++ {
+			gm.Expect(write(baseTime.Add(time.Duration(i)*time.Minute), "0", nil)).To(gm.Equal(modelv1.Status_STATUS_SUCCEED))
+		}
+		gm.Eventually(func() ([]*measurev1.DataPoint, error) {
+			return query(source.GetMetadata().GetName(), baseTime, baseTime.Add(num*time.Minute))
+		}, flags.EventuallyTimeout).Should(gm.HaveLen(num))
+	})
+
+	g.It("evaluates the write conditions against the data points in the other shards", func() {
+		// The condition of the second write is held by the first data point, which is in the other shard.
+		next := baseTime.Add(time.Minute)
+		for shardOf(next) == shardOf(baseTime) {
+			next = next.Add(time.Minute)
+		}
+		expect := func(id string) *measurev1.WriteCondition {
+			return &measurev1.WriteCondition{TagFamily: "default", TagName: "id", Value: str(id)}
+		}
+		gm.Expect(write(baseTime, "a", nil)).To(gm.Equal(modelv1.Status_STATUS_SUCCEED))
+		gm.Eventually(func() modelv1.Status {
+			return write(next, "b", expect("a"))
+		}, flags.EventuallyTimeout).Should(gm.Equal(modelv1.Status_STATUS_SUCCEED))
+		last := next.Add(time.Minute)
+		gm.Expect(write(last, "c", expect("a"))).To(gm.Equal(modelv1.Status_STATUS_CONDITION_FAILED))
+		gm.Expect(write(last, "c", expect("b"))).To(gm.Equal(modelv1.Status_STATUS_SUCCEED))
+	})
+
+	g.It("rolls up the data points of a salted group", func() {
+		_, err := measureRegistry.Create(context.Background(), &databasev1.MeasureRegistryServiceCreateRequest{
+			Measure: &databasev1.Measure{
+				Metadata: &commonv1.Metadata{Name: "service_cpm_5minute", Group: group},
+				TagFamilies: []*databasev1.TagFamilySpec{{
+					Name: "default",
+					Tags: []*databasev1.TagSpec{{Name: "entity_id", Type: databasev1.TagType_TAG_TYPE_STRING}},
+				}},
+				Fields: []*databasev1.FieldSpec{{
+					Name:              "total",
+					FieldType:         databasev1.FieldType_FIELD_TYPE_INT,
+					EncodingMethod:    databasev1.EncodingMethod_ENCODING_METHOD_GORILLA,
+					CompressionMethod: databasev1.CompressionMethod_COMPRESSION_METHOD_ZSTD,
+				}},
+				Entity:   source.GetEntity(),
+				Interval: "5m",
+				Rollup: &databasev1.Rollup{
+					SourceMeasure: &commonv1.Metadata{Name: source.GetMetadata().GetName()},
+					Fields: []*databasev1.RollupField{
+						{Name: "total", Function: modelv1.AggregationFunction_AGGREGATION_FUNCTION_SUM},
+					},
+				},
+			},
+		})
+		gm.Expect(err).NotTo(gm.HaveOccurred())
+		waitForMeasure("service_cpm_5minute")
+		// The data points of the first bucket would be spread over the shards if the source were salted.
+		// The last one closes the bucket, as its end has passed by the allowed lateness.
+		offsets := []time.Duration{0, time.Minute, 2 * time.Minute, 3 * time.Minute, 4 * time.Minute, 10 * time.Minute}
+		gm.Eventually(func() ([]int64, error) {
+			// A data point written again is rolled up once, so the points are written until the rollup is registered.
+			for _, offset := range offsets {
+				gm.Expect(write(baseTime.Add(offset), "0", nil)).To(gm.Equal(modelv1.Status_STATUS_SUCCEED))
+			}
+			dps, err := query("service_cpm_5minute", baseTime, baseTime.Add(5*time.Minute))
+			var totals []int64
+			for _, dp := range dps {
+				totals = append(totals, dp.GetFields()[0].GetValue().GetInt().GetValue())
+			}
+			return totals, err
+		}, flags.EventuallyTimeout).Should(gm.Equal([]int64{5}))
+	})
+})